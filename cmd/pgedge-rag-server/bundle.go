@@ -0,0 +1,214 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/server"
+)
+
+// supportBundleHealthTimeout bounds the provider health checks run
+// while building a support bundle.
+const supportBundleHealthTimeout = 10 * time.Second
+
+// bundleFile is one file in a support bundle.
+type bundleFile struct {
+	name string
+	data []byte
+}
+
+// pipelineStatus describes a pipeline in a support bundle.
+type pipelineStatus struct {
+	Name               string                   `json:"name"`
+	Description        string                   `json:"description,omitempty"`
+	EmbeddingProvider  string                   `json:"embedding_provider"`
+	EmbeddingModel     string                   `json:"embedding_model"`
+	CompletionProvider string                   `json:"completion_provider"`
+	CompletionModel    string                   `json:"completion_model"`
+	Tables             []string                 `json:"tables"`
+	SystemPrompt       string                   `json:"system_prompt,omitempty"`
+	Health             *pipeline.PipelineHealth `json:"health,omitempty"`
+}
+
+// writeSupportBundle writes a gzipped tar archive to path for attaching
+// to a support ticket. It holds the server's versions, the effective
+// configuration with secrets redacted, each pipeline's providers,
+// system prompt and health, the OpenAPI specification, and the
+// warnings and errors logged while building the bundle, in bundle.log.
+// A configuration that fails to load or start is recorded there rather
+// than returned, since that is often what the ticket is about. The
+// bundle is built by its own process, so the running server's logs are
+// not part of it.
+func writeSupportBundle(path, configPath string) error {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+		Level: slog.LevelWarn,
+	}))
+
+	files := []bundleFile{{name: "versions.txt", data: versionReport()}}
+	files = append(files, configFiles(configPath, logger)...)
+
+	spec, err := json.MarshalIndent(server.BuildOpenAPISpec(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode OpenAPI spec: %w", err)
+	}
+	files = append(files, bundleFile{name: "openapi.json", data: spec})
+	files = append(files, bundleFile{name: "bundle.log", data: logs.Bytes()})
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	dir := strings.TrimSuffix(strings.TrimSuffix(filepath.Base(path), ".gz"), ".tar")
+	if err := writeTarGz(f, dir, files); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// configFiles loads the configuration and returns the redacted config
+// and pipeline status files, logging whatever prevents either.
+func configFiles(configPath string, logger *slog.Logger) []bundleFile {
+	resolved, err := config.FindConfigFile(configPath)
+	if err != nil {
+		logger.Error("failed to locate configuration file", "error", err)
+		return nil
+	}
+	cfg, err := config.Load(resolved)
+	if err != nil {
+		logger.Error("failed to load configuration", "path", resolved, "error", err)
+		return nil
+	}
+
+	var files []bundleFile
+	if data, err := config.MarshalRedacted(cfg); err != nil {
+		logger.Error("failed to encode configuration", "error", err)
+	} else {
+		files = append(files, bundleFile{name: "config.yaml", data: data})
+	}
+
+	// Without a manager, the pipelines are still described from the
+	// configuration, only without their prompts and health.
+	prompts := make(map[string]string)
+	health := make(map[string]pipeline.PipelineHealth)
+	pm, err := pipeline.NewManagerWithLogger(pipeline.ManagerConfig{
+		Config:   cfg,
+		Logger:   logger,
+		ReadOnly: true,
+	})
+	if err != nil {
+		logger.Error("failed to create pipeline manager", "error", err)
+	} else {
+		defer func() {
+			if err := pm.Close(); err != nil {
+				logger.Warn("failed to close pipeline manager", "error", err)
+			}
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), supportBundleHealthTimeout)
+		defer cancel()
+		for _, h := range pm.Health(ctx) {
+			health[h.Name] = h
+		}
+		for _, info := range pm.List() {
+			if p, err := pm.Get(info.Name); err == nil {
+				prompts[info.Name] = p.SystemPrompt()
+			}
+		}
+	}
+
+	statuses := make([]pipelineStatus, 0, len(cfg.Pipelines))
+	for _, pc := range cfg.Pipelines {
+		status := pipelineStatus{
+			Name:               pc.Name,
+			Description:        pc.Description,
+			EmbeddingProvider:  pc.EmbeddingLLM.Provider,
+			EmbeddingModel:     pc.EmbeddingLLM.Model,
+			CompletionProvider: pc.RAGLLM.Provider,
+			CompletionModel:    pc.RAGLLM.Model,
+			SystemPrompt:       prompts[pc.Name],
+		}
+		for _, t := range pc.Tables {
+			status.Tables = append(status.Tables, t.Table)
+		}
+		if h, ok := health[pc.Name]; ok {
+			status.Health = &h
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	data, err := json.MarshalIndent(statuses, "", "  ")
+	if err != nil {
+		logger.Error("failed to encode pipeline status", "error", err)
+		return files
+	}
+	return append(files, bundleFile{name: "pipelines.json", data: data})
+}
+
+// versionReport describes the server build, the Go runtime, and the
+// versions of the modules built into the binary.
+func versionReport() []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Version:    %s\n", version)
+	fmt.Fprintf(&b, "Build Time: %s\n", buildTime)
+	fmt.Fprintf(&b, "Git Commit: %s\n", gitCommit)
+	fmt.Fprintf(&b, "Go:         %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		b.WriteString("\nModules:\n")
+		for _, dep := range info.Deps {
+			fmt.Fprintf(&b, "  %s %s\n", dep.Path, dep.Version)
+		}
+	}
+	return b.Bytes()
+}
+
+// writeTarGz writes files to w as a gzipped tar archive, inside dir.
+func writeTarGz(w io.Writer, dir string, files []bundleFile) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    dir + "/" + file.name,
+			Mode:    0o600,
+			Size:    int64(len(file.data)),
+			ModTime: now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(file.data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
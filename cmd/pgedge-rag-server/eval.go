@@ -0,0 +1,117 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// runEval runs the eval subcommand: it evaluates pipelines against the
+// eval dataset named by its argument, or read from stdin, and writes
+// each pipeline's report as JSON or CSV.
+func runEval(args []string) error {
+	fs := flag.NewFlagSet("eval", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	pipelineList := fs.String("pipeline", "", "Comma-separated pipelines to evaluate; all when omitted")
+	k := fs.Int("k", 0, "Chunks to retrieve per question; 0 uses each pipeline's top_n")
+	judge := fs.Bool("judge", false, "Answer questions with reference answers and have the completion LLM grade the answers")
+	format := fs.String("format", "json", "Report format: json or csv")
+	output := fs.String("output", "", "File to write the report to; stdout when omitted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *k < 0 {
+		return fmt.Errorf("-k must not be negative")
+	}
+	if *format != "json" && *format != "csv" {
+		return fmt.Errorf("-format must be json or csv")
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("expected at most one dataset file")
+	}
+
+	in := io.Reader(os.Stdin)
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return fmt.Errorf("failed to open dataset: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+	cases, err := pipeline.ReadEvalDataset(in)
+	if err != nil {
+		return err
+	}
+
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	cfg, pm, closeManager, err := openManager(*configPath, level)
+	if err != nil {
+		return err
+	}
+	defer closeManager()
+
+	names := pipelineNames(cfg)
+	if *pipelineList != "" {
+		names = strings.Split(*pipelineList, ",")
+	}
+	pipelines := make([]*pipeline.Pipeline, len(names))
+	for i, name := range names {
+		if pipelines[i], err = pm.Get(strings.TrimSpace(name)); err != nil {
+			return err
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts := pipeline.EvalOptions{K: *k, Judge: *judge}
+	reports := make([]pipeline.PipelineEvalReport, len(pipelines))
+	for i, p := range pipelines {
+		report, err := p.Evaluate(ctx, cases, opts)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate pipeline %q: %w", p.Name(), err)
+		}
+		reports[i] = pipeline.PipelineEvalReport{Pipeline: p.Name(), EvalReport: report}
+	}
+
+	out := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	if *format == "csv" {
+		err = pipeline.WriteEvalCSV(out, reports)
+	} else {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			Pipelines []pipeline.PipelineEvalReport `json:"pipelines"`
+		}{reports})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return nil
+}
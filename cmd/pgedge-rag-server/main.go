@@ -17,12 +17,21 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/pgEdge/pgedge-rag-server/internal/audit"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/integration"
+	"github.com/pgEdge/pgedge-rag-server/internal/jobs"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
 	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/reports"
 	"github.com/pgEdge/pgedge-rag-server/internal/server"
+	"github.com/pgEdge/pgedge-rag-server/internal/session"
 	"github.com/pgEdge/pgedge-rag-server/internal/watch"
 )
 
@@ -39,6 +48,11 @@ func main() {
 		showHelp    = flag.Bool("help", false, "Show help message")
 		showOpenAPI = flag.Bool("openapi", false, "Output OpenAPI specification and exit")
 		configPath  = flag.String("config", "", "Path to configuration file")
+
+		supportBundle = flag.String("support-bundle", "", "Write a support bundle to the given path and exit")
+
+		validate         = flag.Bool("validate", false, "Validate the configuration and exit")
+		checkConnections = flag.Bool("check-connections", false, "With -validate, also connect to each pipeline's database and providers")
 	)
 
 	flag.Usage = func() {
@@ -46,6 +60,9 @@ func main() {
 
 Usage:
     pgedge-rag-server [options]
+    pgedge-rag-server repl [-config string] [-pipeline string]
+    pgedge-rag-server query [-config string] [-pipeline string] [question]
+    pgedge-rag-server eval [-config string] [-pipeline string] [dataset]
 
 Options:
     -config string
@@ -56,16 +73,73 @@ Options:
     -openapi
         Output OpenAPI v3 specification as JSON and exit
 
+    -support-bundle string
+        Write a support bundle (.tar.gz) to the given path and exit.
+        The bundle holds version information, the configuration with
+        secrets redacted, pipeline status, the OpenAPI specification
+        and any errors found, for attaching to a support ticket.
+
+    -validate
+        Load and validate the configuration without starting the
+        server, print each error and the effective configuration with
+        defaults applied and secrets redacted, and exit with status 1
+        if it is invalid
+
+    -check-connections
+        With -validate, also connect to each pipeline's database and
+        send a request to each of its providers, reporting any that
+        cannot be reached or reject their API key
+
     -version
         Show version information and exit
 
     -help
         Show this help message and exit
 
+Commands:
+    repl
+        Start an interactive prompt that answers questions with a
+        pipeline, shows the chunks it retrieved, and adjusts top_n and
+        min_similarity between queries, for tuning relevance. Takes
+        -config, and -pipeline naming the pipeline when more than one
+        is configured.
+
+    query
+        Answer the question given as the arguments with a pipeline
+        and print the answer, the chunks it was written from, and the
+        tokens used, for debugging retrieval without an HTTP client.
+        With no question, start the repl. Takes -config, -pipeline,
+        -top-n overriding the pipeline's top_n, and -debug to log at
+        debug level and print the query's timings. Flags come before
+        the question.
+
+    eval
+        Evaluate pipelines against an eval dataset of newline-delimited
+        JSON questions with expected source IDs or reference answers,
+        read from the file given as the argument or from stdin, and
+        print each pipeline's recall@k, MRR and nDCG. Takes -config,
+        -pipeline with a comma-separated list of pipelines (all by
+        default), -k overriding each pipeline's top_n, -judge to have
+        the completion LLM grade answers against the reference
+        answers, -format json or csv, and -output naming a file to
+        write the report to.
+
 For more information, visit: https://github.com/pgEdge/pgedge-rag-server
 `)
 	}
 
+	// Subcommands take their own flags.
+	if len(os.Args) > 1 {
+		subcommands := map[string]func([]string) error{"repl": runREPL, "query": runQuery, "eval": runEval}
+		if runSubcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := runSubcommand(os.Args[2:]); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[1], err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+	}
+
 	flag.Parse()
 
 	if *showHelp {
@@ -78,6 +152,7 @@ For more information, visit: https://github.com/pgEdge/pgedge-rag-server
 		fmt.Printf("  Version:    %s\n", version)
 		fmt.Printf("  Build Time: %s\n", buildTime)
 		fmt.Printf("  Git Commit: %s\n", gitCommit)
+		fmt.Printf("  Providers:  %s\n", strings.Join(ragllm.BuiltInProviders(), ", "))
 		os.Exit(0)
 	}
 
@@ -92,6 +167,22 @@ For more information, visit: https://github.com/pgEdge/pgedge-rag-server
 		os.Exit(0)
 	}
 
+	if *validate {
+		if !validateConfig(os.Stdout, *configPath, *checkConnections) {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	if *supportBundle != "" {
+		if err := writeSupportBundle(*supportBundle, *configPath); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to write support bundle: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Support bundle written to %s\n", *supportBundle)
+		os.Exit(0)
+	}
+
 	// Set up logger
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
@@ -131,17 +222,145 @@ func run(configPath string, logger *slog.Logger) error {
 	logger.Info("configuration loaded",
 		"pipelines", len(cfg.Pipelines))
 
+	// A single metrics registry outlives every pipeline manager, so
+	// counters keep accumulating across hot-reloads. Listener settings
+	// are read once at startup; changing them requires a restart.
+	var reg *metrics.Registry
+	if cfg.Server.Metrics.Enabled {
+		reg = metrics.NewRegistry()
+	}
+
+	// The provider payload log is likewise opened once.
+	var payloadLog *ragllm.PayloadLog
+	if pl := cfg.Server.ProviderLog; pl.Enabled {
+		f, err := os.OpenFile(pl.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to open provider log: %w", err)
+		}
+		defer f.Close()
+		maxString := pl.MaxStringLength
+		if maxString == 0 {
+			maxString = config.DefaultProviderLogMaxString
+		}
+		payloadLog = ragllm.NewPayloadLog(f, maxString)
+		logger.Warn("provider payload logging enabled; prompts and answers are written to the log",
+			"path", pl.Path)
+	}
+
+	// So is the key answers are signed with.
+	var signer *server.Signer
+	if cfg.Server.Signing.Enabled {
+		signer, err = server.LoadSigner(cfg.Server.Signing.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load signing key: %w", err)
+		}
+		logger.Info("answer signing enabled", "key_file", cfg.Server.Signing.KeyFile)
+	}
+
+	// The cost ledger, too, outlives every pipeline manager, so a
+	// reload does not reset the spending budgets are enforced against.
+	costs := pipeline.NewCostLedger()
+
+	// So are the provider limiters, so the queries of the old and new
+	// pipelines share provider_limits while a reload drains.
+	limiters := ragllm.NewLimiters()
+
 	// Create pipeline manager
 	pm, err := pipeline.NewManagerWithLogger(pipeline.ManagerConfig{
-		Config: cfg,
-		Logger: logger,
+		Config:     cfg,
+		Logger:     logger,
+		Metrics:    reg,
+		PayloadLog: payloadLog,
+		Costs:      costs,
+		Limiters:   limiters,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline manager: %w", err)
 	}
 
-	// Create and start server
-	srv := server.New(cfg, pm, logger)
+	// Like the metrics registry, the session store is created once and
+	// survives hot-reloads; sessions settings require a restart.
+	var sessions session.Store
+	if cfg.Sessions.Enabled {
+		sessions, err = session.NewStore(context.Background(), cfg.Sessions)
+		if err != nil {
+			if closeErr := pm.Close(); closeErr != nil {
+				logger.Error("failed to close pipeline manager", "error", closeErr)
+			}
+			return fmt.Errorf("failed to create session store: %w", err)
+		}
+		defer func() {
+			if err := sessions.Close(); err != nil {
+				logger.Error("failed to close session store", "error", err)
+			}
+		}()
+		logger.Info("conversation sessions enabled", "store", cfg.Sessions.Store)
+	}
+
+	// Usage reports count the queries the server is asked, and read
+	// token usage from whatever pipelines are current, so they follow
+	// reloads; their own settings take effect on restart.
+	var scheduler *reports.Scheduler
+	if cfg.Reports.Enabled {
+		scheduler, err = reports.New(cfg.Reports, logger)
+		if err != nil {
+			if closeErr := pm.Close(); closeErr != nil {
+				logger.Error("failed to close pipeline manager", "error", closeErr)
+			}
+			return fmt.Errorf("failed to start usage reports: %w", err)
+		}
+	}
+
+	// The job store is likewise created once. The queue is closed below,
+	// once the pipeline manager's deferred close is registered, so jobs
+	// are stopped before the pipelines they use are closed.
+	jobStore, err := jobs.NewStore(context.Background(), cfg.Jobs)
+	if err != nil {
+		if closeErr := pm.Close(); closeErr != nil {
+			logger.Error("failed to close pipeline manager", "error", closeErr)
+		}
+		return fmt.Errorf("failed to create job store: %w", err)
+	}
+	defer func() {
+		if err := jobStore.Close(); err != nil {
+			logger.Error("failed to close job store", "error", err)
+		}
+	}()
+	jobQueue := jobs.NewQueue(jobStore, cfg.Jobs, logger)
+
+	// The audit log is likewise created once; audit settings require a
+	// restart. It is closed below, after the server, so the entries of
+	// the last queries are written.
+	var auditLog *audit.Log
+	if cfg.Audit.Enabled {
+		auditStore, err := audit.NewPostgresStore(context.Background(), cfg.Audit.Database, cfg.Audit.Table)
+		if err != nil {
+			jobQueue.Close()
+			if closeErr := pm.Close(); closeErr != nil {
+				logger.Error("failed to close pipeline manager", "error", closeErr)
+			}
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+		defer func() {
+			if err := auditStore.Close(); err != nil {
+				logger.Error("failed to close audit store", "error", err)
+			}
+		}()
+		auditLog = audit.New(auditStore, cfg.Audit, logger)
+		defer auditLog.Close()
+		logger.Info("audit log enabled", "table", cfg.Audit.Table)
+	}
+
+	// Create and start server. The admin reload endpoint calls reload,
+	// set up below with the configuration watcher.
+	var reload func() error
+	opts := []server.Option{server.WithMetrics(reg), server.WithSessions(sessions),
+		server.WithJobs(jobQueue), server.WithReload(func() error { return reload() }),
+		server.WithSigner(signer), server.WithAudit(auditLog)}
+	if scheduler != nil {
+		opts = append(opts, server.WithQueryRecorder(scheduler))
+	}
+	srv := server.New(cfg, pm, logger, opts...)
 
 	// Close whatever pipeline manager is active at shutdown time, not
 	// necessarily the one created above — a reload may have swapped it
@@ -153,31 +372,81 @@ func run(configPath string, logger *slog.Logger) error {
 			}
 		}
 	}()
+	defer jobQueue.Close()
+
+	// Integrations answer with whatever pipelines are current, so they
+	// follow reloads; their own settings take effect on restart.
+	if cfg.Integrations.Slack.Enabled || cfg.Integrations.Mattermost.Enabled ||
+		cfg.Integrations.Email.Enabled {
+		integrations, err := integration.New(cfg.Integrations, srv, logger)
+		if err != nil {
+			return fmt.Errorf("failed to start integrations: %w", err)
+		}
+		for pattern, handler := range integrations.Handlers() {
+			srv.Handle(pattern, handler)
+		}
+		integrationsCtx, cancelIntegrations := context.WithCancel(context.Background())
+		defer cancelIntegrations()
+		go integrations.Run(integrationsCtx)
+		logger.Info("integrations enabled",
+			"slack", cfg.Integrations.Slack.Enabled,
+			"mattermost", cfg.Integrations.Mattermost.Enabled,
+			"email", cfg.Integrations.Email.Enabled)
+	}
+
+	if scheduler != nil {
+		reportsCtx, cancelReports := context.WithCancel(context.Background())
+		defer cancelReports()
+		go scheduler.Run(reportsCtx, srv)
+		logger.Info("usage reports enabled", "schedule", cfg.Reports.Schedule,
+			"time", cfg.Reports.Time, "timezone", cfg.Reports.Timezone)
+	}
 
 	// Watch the config file and any file-based API keys it uses (e.g. a
 	// mounted secret) for changes, and reload without a restart when
-	// they change — see issue #30.
-	watchPaths := append([]string{resolvedConfigPath}, config.APIKeyFilePaths(cfg)...)
-	reload := func() {
-		logger.Info("configuration change detected, reloading")
+	// they change — see issue #30. SIGHUP reloads too. Reloads are
+	// serialized, so one from the admin endpoint, the watcher or a
+	// signal cannot interleave with another.
+	watchPaths := func(c *config.Config) []string {
+		return append([]string{resolvedConfigPath}, config.APIKeyFilePaths(c)...)
+	}
+	var fileWatcher *watch.Watcher // nil when watching is disabled or failed
+	var reloadMu sync.Mutex
+	reload = func() error {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
 
 		newCfg, err := config.Load(resolvedConfigPath)
 		if err != nil {
 			logger.Error("config reload failed; keeping previous configuration", "error", err)
-			return
+			return fmt.Errorf("failed to load configuration: %w", err)
 		}
 
 		newPM, err := pipeline.NewManagerWithLogger(pipeline.ManagerConfig{
-			Config: newCfg,
-			Logger: logger,
+			Config:     newCfg,
+			Logger:     logger,
+			Metrics:    reg,
+			PayloadLog: payloadLog,
+			Costs:      costs,
+			Limiters:   limiters,
 		})
 		if err != nil {
 			logger.Error("pipeline reload failed; keeping previous configuration", "error", err)
-			return
+			return fmt.Errorf("failed to create pipelines: %w", err)
 		}
 
 		oldPM := srv.SwapPipelineManager(newPM)
 		logger.Info("configuration reloaded", "pipelines", len(newCfg.Pipelines))
+		if changed := config.RestartRequired(cfg, newCfg); len(changed) > 0 {
+			logger.Warn("configuration changes not applied until restart", "sections", changed)
+		}
+
+		// Follow the key files the new configuration uses.
+		if fileWatcher != nil {
+			if err := fileWatcher.SetPaths(watchPaths(newCfg)); err != nil {
+				logger.Warn("failed to update watched configuration files", "error", err)
+			}
+		}
 
 		if oldPM != nil {
 			// Give in-flight requests using the old manager time to finish
@@ -193,19 +462,39 @@ func run(configPath string, logger *slog.Logger) error {
 				}
 			})
 		}
+		return nil
 	}
 
-	fileWatcher, err := watch.New(watchPaths, watch.DefaultDebounce, reload, logger)
-	if err != nil {
-		logger.Warn("failed to start configuration watcher; hot-reload disabled", "error", err)
+	if cw := cfg.Server.ConfigWatch; cw.Enabled {
+		paths := watchPaths(cfg)
+		w, err := watch.New(paths, cw.Debounce.Std(), func() {
+			logger.Info("configuration change detected, reloading")
+			_ = reload()
+		}, logger)
+		if err != nil {
+			logger.Warn("failed to start configuration watcher; reload with SIGHUP", "error", err)
+		} else {
+			fileWatcher = w
+			watchCtx, cancelWatch := context.WithCancel(context.Background())
+			defer cancelWatch()
+			go fileWatcher.Start(watchCtx)
+			defer fileWatcher.Close()
+			logger.Info("watching for configuration changes", "paths", paths)
+		}
 	} else {
-		watchCtx, cancelWatch := context.WithCancel(context.Background())
-		defer cancelWatch()
-		go fileWatcher.Start(watchCtx)
-		defer fileWatcher.Close()
-		logger.Info("watching for configuration changes", "paths", watchPaths)
+		logger.Info("configuration watching disabled; reload with SIGHUP")
 	}
 
+	reloadCh := make(chan os.Signal, 1)
+	signal.Notify(reloadCh, syscall.SIGHUP)
+	defer signal.Stop(reloadCh)
+	go func() {
+		for range reloadCh {
+			logger.Info("received SIGHUP, reloading configuration")
+			_ = reload()
+		}
+	}()
+
 	// Handle graceful shutdown
 	shutdownCh := make(chan os.Signal, 1)
 	signal.Notify(shutdownCh, os.Interrupt, syscall.SIGTERM)
@@ -222,8 +511,9 @@ func run(configPath string, logger *slog.Logger) error {
 	case sig := <-shutdownCh:
 		logger.Info("received shutdown signal", "signal", sig)
 
-		// Give 30 seconds for graceful shutdown
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		// Let in-flight requests, streams included, finish until the
+		// shutdown timeout.
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
 		defer cancel()
 
 		return srv.Shutdown(ctx)
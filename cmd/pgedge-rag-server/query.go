@@ -0,0 +1,91 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// runQuery runs the query subcommand: it answers the question given as
+// its arguments with a pipeline, printing the answer, the chunks it was
+// written from and the tokens used, or starts the REPL when no
+// question is given.
+func runQuery(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	pipelineName := fs.String("pipeline", "", "Pipeline to query; may be omitted when only one is configured")
+	topN := fs.Int("top-n", 0, "Chunks to retrieve; 0 uses the pipeline's top_n")
+	debug := fs.Bool("debug", false, "Log at debug level and print the query's timings")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *topN < 0 {
+		return fmt.Errorf("-top-n must not be negative")
+	}
+
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	if *debug {
+		level.Set(slog.LevelDebug)
+	}
+	p, closePipeline, err := openPipeline(*configPath, *pipelineName, level)
+	if err != nil {
+		return err
+	}
+	defer closePipeline()
+
+	question := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if question == "" {
+		return startREPL(p, level)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return answerQuestion(ctx, os.Stdout, p, pipeline.QueryRequest{
+		Query:          question,
+		TopN:           *topN,
+		IncludeSources: true,
+		IncludeTimings: *debug,
+	})
+}
+
+// answerQuestion answers req with p and prints the answer, its
+// sources and the tokens it used to w.
+func answerQuestion(ctx context.Context, w io.Writer, p *pipeline.Pipeline, req pipeline.QueryRequest) error {
+	resp, err := p.ExecuteWithOptions(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "%s\n\n", strings.TrimSpace(resp.Answer))
+	fmt.Fprintf(w, "Sources (%d):\n", len(resp.Sources))
+	printChunks(w, resp.Sources)
+	fmt.Fprintln(w)
+	for _, warning := range resp.FormatWarnings {
+		fmt.Fprintf(w, "Format warning: %s\n", warning)
+	}
+	if len(resp.Guardrails) > 0 {
+		fmt.Fprintf(w, "Guardrails: %s\n", strings.Join(resp.Guardrails, ", "))
+	}
+	if t := resp.Timings; t != nil {
+		fmt.Fprintf(w, "Timings: embed %d ms, search %d ms, total %d ms\n", t.EmbedMS, t.SearchMS, t.TotalMS)
+	}
+	printUsage(w, resp.Usage)
+	return nil
+}
@@ -0,0 +1,363 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// replVariant names the retrieval settings adjusted in the REPL.
+const replVariant = "repl"
+
+// replPreviewLength bounds, in runes, the content shown of a chunk.
+const replPreviewLength = 300
+
+const replHelp = `Type a question to answer it with the pipeline, or a command:
+  \retrieve <query>          Show the chunks a query retrieves, without answering it
+  \sources                   Show the chunks the last answer was written from
+  \debug                     Toggle debug logging, timings and token usage
+  \set top_n <n>             Retrieve n chunks; "default" uses the pipeline's top_n
+  \set min_similarity <x>    Drop chunks scoring below x; "default" uses the pipeline's
+  \show                      Show the current settings
+  \help                      Show this help
+  \quit                      Exit (as does Ctrl-D)
+Ctrl-C cancels a running query.
+`
+
+// runREPL runs the repl subcommand: an interactive prompt that answers
+// questions with one pipeline and shows what it retrieved, with the
+// retrieval settings adjustable between queries.
+func runREPL(args []string) error {
+	fs := flag.NewFlagSet("repl", flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to configuration file")
+	pipelineName := fs.String("pipeline", "", "Pipeline to query; may be omitted when only one is configured")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// Only warnings are logged, to stderr, until debug is turned on.
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	p, closePipeline, err := openPipeline(*configPath, *pipelineName, level)
+	if err != nil {
+		return err
+	}
+	defer closePipeline()
+
+	return startREPL(p, level)
+}
+
+// startREPL runs the REPL on a pipeline opened by openPipeline.
+func startREPL(p *pipeline.Pipeline, level *slog.LevelVar) error {
+	r := &repl{base: p, pipeline: p, level: level, out: os.Stdout}
+	fmt.Fprintf(r.out, "pgEdge RAG Server REPL, pipeline %q. Type \\help for help.\n", p.Name())
+	return r.run(os.Stdin)
+}
+
+// openPipeline loads the configuration at configPath and creates the
+// pipeline named name, which may be empty when only one is configured.
+// It logs to stderr at level. The returned function closes the
+// pipeline's connections.
+func openPipeline(configPath, name string, level *slog.LevelVar) (*pipeline.Pipeline, func(), error) {
+	cfg, pm, closeManager, err := openManager(configPath, level)
+	if err != nil {
+		return nil, nil, err
+	}
+	if name == "" {
+		if len(cfg.Pipelines) != 1 {
+			closeManager()
+			return nil, nil, fmt.Errorf("-pipeline is required; configured pipelines: %s",
+				strings.Join(pipelineNames(cfg), ", "))
+		}
+		name = cfg.Pipelines[0].Name
+	}
+	p, err := pm.Get(name)
+	if err != nil {
+		closeManager()
+		return nil, nil, err
+	}
+	return p, closeManager, nil
+}
+
+// openManager loads the configuration at configPath and creates its
+// pipelines, logging to stderr at level. The returned function closes
+// the pipelines' connections.
+func openManager(configPath string, level *slog.LevelVar) (*config.Config, *pipeline.Manager, func(), error) {
+	resolved, err := config.FindConfigFile(configPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to locate configuration file: %w", err)
+	}
+	cfg, err := config.Load(resolved)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+	pm, err := pipeline.NewManagerWithLogger(pipeline.ManagerConfig{Config: cfg, Logger: logger})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create pipeline manager: %w", err)
+	}
+	closeManager := func() {
+		if err := pm.Close(); err != nil {
+			logger.Error("failed to close pipeline manager", "error", err)
+		}
+	}
+	return cfg, pm, closeManager, nil
+}
+
+// pipelineNames returns the names of the pipelines cfg configures, in
+// the order they are configured.
+func pipelineNames(cfg *config.Config) []string {
+	names := make([]string, len(cfg.Pipelines))
+	for i, p := range cfg.Pipelines {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// repl is the state of an interactive session with a pipeline.
+type repl struct {
+	base     *pipeline.Pipeline // the pipeline as configured
+	pipeline *pipeline.Pipeline // base with the adjusted settings
+	level    *slog.LevelVar
+	out      io.Writer
+
+	topN          int      // zero uses the pipeline's top_n
+	minSimilarity *float64 // nil uses the pipeline's min_similarity
+	debug         bool
+	last          []pipeline.Source // the last answer's sources
+}
+
+// run reads questions and commands from in until \quit or the end of
+// the input.
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for {
+		fmt.Fprintf(r.out, "%s> ", r.pipeline.Name())
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, `\`):
+			if quit := r.command(line); quit {
+				return nil
+			}
+		default:
+			r.query(line)
+		}
+	}
+}
+
+// command runs a backslash command, reporting whether it ends the
+// session.
+func (r *repl) command(line string) bool {
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	case `\q`, `\quit`:
+		return true
+	case `\h`, `\help`, `\?`:
+		fmt.Fprint(r.out, replHelp)
+	case `\retrieve`:
+		if arg == "" {
+			fmt.Fprintln(r.out, `usage: \retrieve <query>`)
+			break
+		}
+		r.retrieve(arg)
+	case `\sources`:
+		if r.last == nil {
+			fmt.Fprintln(r.out, "No answer yet.")
+			break
+		}
+		printChunks(r.out, r.last)
+	case `\debug`:
+		r.debug = !r.debug
+		if r.debug {
+			r.level.Set(slog.LevelDebug)
+			fmt.Fprintln(r.out, "Debug is on.")
+		} else {
+			r.level.Set(slog.LevelWarn)
+			fmt.Fprintln(r.out, "Debug is off.")
+		}
+	case `\set`:
+		setting, value, _ := strings.Cut(arg, " ")
+		if err := r.set(setting, strings.TrimSpace(value)); err != nil {
+			fmt.Fprintf(r.out, "Error: %v\n", err)
+			break
+		}
+		r.show()
+	case `\show`:
+		r.show()
+	default:
+		fmt.Fprintf(r.out, "Unknown command %s; type \\help for help.\n", cmd)
+	}
+	return false
+}
+
+// set adjusts a retrieval setting.
+func (r *repl) set(setting, value string) error {
+	switch setting {
+	case "top_n":
+		if value == "default" {
+			r.topN = 0
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return errors.New("top_n must be a positive integer or default")
+		}
+		r.topN = n
+		return nil
+	case "min_similarity":
+		var minSimilarity *float64
+		if value != "default" {
+			x, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return errors.New("min_similarity must be a number between 0.0 and 1.0 or default")
+			}
+			minSimilarity = &x
+		}
+		p, err := r.base.WithVariant(pipeline.TuningVariant{Name: replVariant, MinSimilarity: minSimilarity})
+		if err != nil {
+			return err
+		}
+		r.pipeline, r.minSimilarity = p, minSimilarity
+		return nil
+	default:
+		return fmt.Errorf("unknown setting %q; top_n and min_similarity can be set", setting)
+	}
+}
+
+// show prints the current settings.
+func (r *repl) show() {
+	topN, minSimilarity := "default", "default"
+	if r.topN > 0 {
+		topN = strconv.Itoa(r.topN)
+	}
+	if r.minSimilarity != nil {
+		minSimilarity = strconv.FormatFloat(*r.minSimilarity, 'f', -1, 64)
+	}
+	debug := "off"
+	if r.debug {
+		debug = "on"
+	}
+	fmt.Fprintf(r.out, "top_n: %s, min_similarity: %s, debug: %s\n", topN, minSimilarity, debug)
+}
+
+// query answers a question, keeping its sources for \sources.
+func (r *repl) query(q string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	resp, err := r.pipeline.ExecuteWithOptions(ctx, pipeline.QueryRequest{
+		Query:          q,
+		TopN:           r.topN,
+		IncludeSources: true,
+		IncludeTimings: r.debug,
+	})
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %v\n", err)
+		return
+	}
+	r.last = resp.Sources
+	if r.last == nil {
+		r.last = []pipeline.Source{}
+	}
+
+	fmt.Fprintf(r.out, "\n%s\n\n", strings.TrimSpace(resp.Answer))
+	fmt.Fprintf(r.out, "(%d sources; \\sources to show them)\n", len(resp.Sources))
+	for _, w := range resp.FormatWarnings {
+		fmt.Fprintf(r.out, "Format warning: %s\n", w)
+	}
+	if len(resp.Guardrails) > 0 {
+		fmt.Fprintf(r.out, "Guardrails: %s\n", strings.Join(resp.Guardrails, ", "))
+	}
+	if r.debug {
+		if t := resp.Timings; t != nil {
+			fmt.Fprintf(r.out, "Timings: embed %d ms, search %d ms, total %d ms\n", t.EmbedMS, t.SearchMS, t.TotalMS)
+		}
+		printUsage(r.out, resp.Usage)
+	}
+}
+
+// printUsage prints the tokens a query used, if known.
+func printUsage(w io.Writer, u *pipeline.StageUsage) {
+	if u == nil {
+		return
+	}
+	fmt.Fprintf(w, "Tokens: %d embedding, %d prompt, %d completion, %d total\n",
+		u.Embedding.TotalTokens, u.Completion.PromptTokens, u.Completion.CompletionTokens, u.TotalTokens())
+}
+
+// retrieve shows the chunks a query retrieves.
+func (r *repl) retrieve(q string) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	resp, err := r.pipeline.Retrieve(ctx, pipeline.RetrieveRequest{Query: q, K: r.topN})
+	if err != nil {
+		fmt.Fprintf(r.out, "Error: %v\n", err)
+		return
+	}
+	chunks := make([]pipeline.Source, len(resp.Documents))
+	for i, d := range resp.Documents {
+		chunks[i] = pipeline.Source{ID: d.ID, Content: d.PageContent, Score: d.Score, Metadata: d.Metadata}
+	}
+	printChunks(r.out, chunks)
+}
+
+// printChunks prints chunks, best first, with their scores, metadata
+// and the start of their content.
+func printChunks(w io.Writer, chunks []pipeline.Source) {
+	if len(chunks) == 0 {
+		fmt.Fprintln(w, "No chunks.")
+		return
+	}
+	for i, c := range chunks {
+		fmt.Fprintf(w, "[%d] score %.4f", i+1, c.Score)
+		if c.ID != "" {
+			fmt.Fprintf(w, "  id %s", c.ID)
+		}
+		fmt.Fprintln(w)
+		if len(c.Metadata) > 0 {
+			keys := make([]string, 0, len(c.Metadata))
+			for k := range c.Metadata {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(w, "    %s: %v\n", k, c.Metadata[k])
+			}
+		}
+		content := strings.Join(strings.Fields(c.Content), " ")
+		if runes := []rune(content); len(runes) > replPreviewLength {
+			content = string(runes[:replPreviewLength]) + "…"
+		}
+		fmt.Fprintf(w, "    %s\n", content)
+	}
+}
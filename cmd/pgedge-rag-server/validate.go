@@ -0,0 +1,148 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// validateConnectionsTimeout bounds the connectivity checks run by
+// -validate -check-connections, once the pipelines have started.
+const validateConnectionsTimeout = 30 * time.Second
+
+// validateConfig loads the configuration as the server would, without
+// starting it, and writes a summary to w: each validation error, then,
+// when checkConnections is set, whether every pipeline's database and
+// providers could be reached, any warnings logged while checking, and
+// the effective configuration with defaults applied and secrets
+// redacted. It reports whether the configuration is usable.
+func validateConfig(w io.Writer, configPath string, checkConnections bool) bool {
+	resolved, err := config.FindConfigFile(configPath)
+	if err != nil {
+		fmt.Fprintf(w, "Error: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(w, "Configuration: %s\n", resolved)
+
+	cfg, err := config.Load(resolved)
+	if err != nil {
+		var verrs config.ValidationErrors
+		if !errors.As(err, &verrs) {
+			fmt.Fprintf(w, "\nError: %v\n", err)
+			return false
+		}
+		fmt.Fprintf(w, "\nErrors (%d):\n", len(verrs))
+		for _, e := range verrs {
+			fmt.Fprintf(w, "  - %s: %s\n", e.Field, e.Message)
+		}
+		return false
+	}
+
+	ok := true
+	if checkConnections {
+		var logs bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{
+			Level: slog.LevelWarn,
+		}))
+		ok = checkPipelineConnections(w, cfg, logger)
+		if logs.Len() > 0 {
+			fmt.Fprintf(w, "\nWarnings:\n")
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				fmt.Fprintf(w, "  - %s\n", line)
+			}
+		}
+	}
+
+	data, err := config.MarshalRedacted(cfg)
+	if err != nil {
+		fmt.Fprintf(w, "\nError: failed to encode configuration: %v\n", err)
+		return false
+	}
+	fmt.Fprintf(w, "\nEffective configuration:\n\n%s", data)
+
+	if ok {
+		fmt.Fprintf(w, "\nConfiguration is valid: %d pipeline(s)\n", len(cfg.Pipelines))
+	} else {
+		fmt.Fprintf(w, "\nConfiguration is valid, but not every connection succeeded\n")
+	}
+	return ok
+}
+
+// checkPipelineConnections starts the configured pipelines, which
+// connects to their databases and checks their embedding dimensions,
+// then pings each pipeline's database and providers with a real
+// request, so a missing or wrong API key is found. It writes each
+// result to w and reports whether all succeeded.
+func checkPipelineConnections(w io.Writer, cfg *config.Config, logger *slog.Logger) bool {
+	fmt.Fprintf(w, "\nConnections:\n")
+	pm, err := pipeline.NewManagerWithLogger(pipeline.ManagerConfig{
+		Config:   cfg,
+		Logger:   logger,
+		ReadOnly: true,
+	})
+	if err != nil {
+		fmt.Fprintf(w, "  - %v\n", err)
+		var initErr *pipeline.InitError
+		if errors.As(err, &initErr) {
+			for _, stage := range initErr.Stages {
+				result := "ok"
+				if stage.Error != "" {
+					result = stage.Error
+				}
+				fmt.Fprintf(w, "    %s (%dms): %s\n", stage.Name, stage.DurationMS, result)
+			}
+		}
+		return false
+	}
+	defer func() {
+		if err := pm.Close(); err != nil {
+			logger.Warn("failed to close pipeline manager", "error", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), validateConnectionsTimeout)
+	defer cancel()
+	results := pm.Ready(ctx, true)
+	sort.Slice(results, func(i, j int) bool { return results[i].Name < results[j].Name })
+
+	ok := true
+	for _, r := range results {
+		for _, check := range []struct {
+			name   string
+			health *pipeline.ProviderHealth
+		}{
+			{"database", &r.Database},
+			{"embedding", r.Embedding},
+			{"completion", r.Completion},
+		} {
+			if check.health == nil {
+				continue
+			}
+			if check.health.Reachable {
+				fmt.Fprintf(w, "  %s %s: ok\n", r.Name, check.name)
+				continue
+			}
+			ok = false
+			fmt.Fprintf(w, "  %s %s: %s\n", r.Name, check.name, check.health.Error)
+		}
+	}
+	return ok
+}
@@ -0,0 +1,191 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package audit records the queries the server answers, with their
+// answers, the documents they were answered from, their token usage and
+// latency, and the clients that asked them, for compliance and offline
+// evaluation. Entries are written to a Store in the background, so
+// recording one never holds up a query.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+const (
+	// PruneInterval is how often entries older than the retention
+	// period are deleted.
+	PruneInterval = time.Hour
+
+	// writeTimeout bounds each write to the store.
+	writeTimeout = 10 * time.Second
+)
+
+// Entry is the record of one query.
+type Entry struct {
+	Time      time.Time // When the query was received
+	RequestID string
+	Pipeline  string
+	Client    string // The rate limit key's value, or the client's address
+	Query     string
+	Filter    *config.Filter
+	Sources   []Source // The documents the answer was written from
+	Answer    string
+	Status    string // The query's outcome: ok, error, timeout or disconnected
+	Error     string
+	Cached    bool // The answer came from the answer cache
+	Provider  string
+	Model     string
+	Usage     *pipeline.StageUsage
+	LatencyMS int64
+}
+
+// Source is a document an answer was written from, with its similarity
+// score.
+type Source struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+// Store keeps audit entries.
+type Store interface {
+	// Insert records an entry.
+	Insert(ctx context.Context, e Entry) error
+
+	// Prune deletes the entries recorded before the given time.
+	Prune(ctx context.Context, before time.Time) error
+
+	// Close releases the store's resources.
+	Close() error
+}
+
+// Log writes entries to a Store in the background, and deletes entries
+// older than its retention period once an hour. A nil Log records
+// nothing.
+type Log struct {
+	store     Store
+	logger    *slog.Logger
+	retention time.Duration
+	now       func() time.Time // overridden in tests
+
+	mu      sync.RWMutex
+	closed  bool
+	entries chan Entry
+	done    chan struct{}
+}
+
+// New starts a Log writing to store. Unset settings take their
+// defaults.
+func New(store Store, cfg config.AuditConfig, logger *slog.Logger) *Log {
+	return newLog(store, cfg, logger, time.Now)
+}
+
+func newLog(store Store, cfg config.AuditConfig, logger *slog.Logger, now func() time.Time) *Log {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = config.DefaultAuditQueueSize
+	}
+
+	l := &Log{
+		store:     store,
+		logger:    logger,
+		retention: cfg.Retention.Std(),
+		now:       now,
+		entries:   make(chan Entry, cfg.QueueSize),
+		done:      make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Record queues e to be written. When the queue is full the entry is
+// dropped and a warning logged.
+func (l *Log) Record(e Entry) {
+	if l == nil {
+		return
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.entries <- e:
+	default:
+		l.logger.Warn("audit log queue is full; entry dropped",
+			"pipeline", e.Pipeline, "request_id", e.RequestID)
+	}
+}
+
+// Close stops the log once the entries already queued are written. The
+// store is left open.
+func (l *Log) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.entries)
+	}
+	l.mu.Unlock()
+	<-l.done
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+
+	var prune <-chan time.Time
+	if l.retention > 0 {
+		l.prune()
+		ticker := time.NewTicker(PruneInterval)
+		defer ticker.Stop()
+		prune = ticker.C
+	}
+
+	for {
+		select {
+		case e, ok := <-l.entries:
+			if !ok {
+				return
+			}
+			l.write(e)
+		case <-prune:
+			l.prune()
+		}
+	}
+}
+
+// write records an entry. Failures are logged rather than returned:
+// the query the entry describes has already been answered.
+func (l *Log) write(e Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := l.store.Insert(ctx, e); err != nil {
+		l.logger.Error("failed to write audit entry",
+			"pipeline", e.Pipeline, "request_id", e.RequestID, "error", err)
+	}
+}
+
+// prune deletes the entries older than the retention period.
+func (l *Log) prune() {
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+	if err := l.store.Prune(ctx, l.now().Add(-l.retention)); err != nil {
+		l.logger.Error("failed to prune audit log", "error", err)
+	}
+}
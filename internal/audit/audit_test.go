@@ -0,0 +1,102 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// memoryStore keeps the entries it is given, and the times it was
+// asked to prune before.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries []Entry
+	pruned  []time.Time
+	block   chan struct{} // when set, Insert waits for it to be closed
+}
+
+func (s *memoryStore) Insert(_ context.Context, e Entry) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memoryStore) Prune(_ context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruned = append(s.pruned, before)
+	return nil
+}
+
+func (s *memoryStore) Close() error { return nil }
+
+func TestLog(t *testing.T) {
+	store := &memoryStore{}
+	l := New(store, config.AuditConfig{}, nil)
+
+	l.Record(Entry{RequestID: "r1", Pipeline: "docs", Query: "How does replication work?"})
+	l.Record(Entry{RequestID: "r2", Pipeline: "docs", Query: "What is vacuum?"})
+	l.Close()
+	l.Record(Entry{RequestID: "r3", Pipeline: "docs"})
+
+	if len(store.entries) != 2 || store.entries[0].RequestID != "r1" || store.entries[1].RequestID != "r2" {
+		t.Errorf("expected the entries recorded before Close written in order, got %+v", store.entries)
+	}
+	if len(store.pruned) != 0 {
+		t.Errorf("expected nothing pruned without a retention period, got %v", store.pruned)
+	}
+
+	var disabled *Log
+	disabled.Record(Entry{RequestID: "r4"})
+	disabled.Close()
+}
+
+func TestLog_Retention(t *testing.T) {
+	store := &memoryStore{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	l := newLog(store, config.AuditConfig{Retention: config.Duration(90 * 24 * time.Hour)}, nil,
+		func() time.Time { return now })
+	l.Close()
+
+	want := now.Add(-90 * 24 * time.Hour)
+	if len(store.pruned) != 1 || !store.pruned[0].Equal(want) {
+		t.Errorf("expected entries before %v pruned on start, got %v", want, store.pruned)
+	}
+}
+
+func TestLog_QueueFull(t *testing.T) {
+	store := &memoryStore{block: make(chan struct{})}
+	l := New(store, config.AuditConfig{QueueSize: 1}, nil)
+
+	// The first entry is taken by the writer, which blocks on it; the
+	// second fills the queue and the third is dropped.
+	l.Record(Entry{RequestID: "r1"})
+	deadline := time.Now().Add(5 * time.Second)
+	for len(l.entries) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	l.Record(Entry{RequestID: "r2"})
+	l.Record(Entry{RequestID: "r3"})
+	close(store.block)
+	l.Close()
+
+	if len(store.entries) != 2 || store.entries[1].RequestID != "r2" {
+		t.Errorf("expected the entry recorded while the queue was full dropped, got %+v", store.entries)
+	}
+}
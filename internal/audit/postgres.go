@@ -0,0 +1,142 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// PostgresStore keeps audit entries in a Postgres table, one row per
+// query.
+type PostgresStore struct {
+	db    *database.Pool
+	table string // sanitized identifier
+}
+
+// NewPostgresStore connects to the database and creates the audit table
+// if it does not already exist. table may be schema-qualified.
+func NewPostgresStore(ctx context.Context, dbCfg config.DatabaseConfig, table string) (*PostgresStore, error) {
+	db, err := database.NewPool(ctx, dbCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(table, ".")
+	s := &PostgresStore{
+		db:    db,
+		table: pgx.Identifier(parts).Sanitize(),
+	}
+	index := pgx.Identifier{parts[len(parts)-1] + "_created_at_idx"}.Sanitize()
+
+	_, err = s.pool().Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		request_id text NOT NULL DEFAULT '',
+		pipeline text NOT NULL,
+		client text NOT NULL DEFAULT '',
+		query text NOT NULL,
+		filter jsonb,
+		sources jsonb NOT NULL DEFAULT '[]',
+		answer text NOT NULL DEFAULT '',
+		status text NOT NULL,
+		error text NOT NULL DEFAULT '',
+		cached boolean NOT NULL DEFAULT false,
+		provider text NOT NULL DEFAULT '',
+		model text NOT NULL DEFAULT '',
+		tokens integer NOT NULL DEFAULT 0,
+		usage jsonb,
+		latency_ms bigint NOT NULL,
+		created_at timestamptz NOT NULL
+	)`, s.table))
+	if err == nil {
+		_, err = s.pool().Exec(ctx, fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %s ON %s (created_at)`, index, s.table))
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create audit table: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *PostgresStore) pool() *pgxpool.Pool {
+	return s.db.Pool()
+}
+
+// Insert implements Store.
+func (s *PostgresStore) Insert(ctx context.Context, e Entry) error {
+	var filter, usage []byte
+	var err error
+	if e.Filter != nil {
+		if filter, err = json.Marshal(e.Filter); err != nil {
+			return fmt.Errorf("failed to encode audit filter: %w", err)
+		}
+	}
+	if e.Usage != nil {
+		if usage, err = json.Marshal(e.Usage); err != nil {
+			return fmt.Errorf("failed to encode audit usage: %w", err)
+		}
+	}
+	sources := e.Sources
+	if sources == nil {
+		sources = []Source{}
+	}
+	encodedSources, err := json.Marshal(sources)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit sources: %w", err)
+	}
+
+	_, err = s.pool().Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (request_id, pipeline, client, query, filter, sources, answer, status,
+		 error, cached, provider, model, tokens, usage, latency_ms, created_at)
+		 VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7, $8, $9, $10, $11, $12, $13, $14::jsonb, $15, $16)`,
+		s.table), e.RequestID, e.Pipeline, e.Client, e.Query, nullJSON(filter),
+		string(encodedSources), e.Answer, e.Status, e.Error, e.Cached, e.Provider, e.Model,
+		e.Usage.TotalTokens(), nullJSON(usage), e.LatencyMS, e.Time)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// nullJSON returns encoded JSON as a query argument, or nil for NULL
+// when there is none.
+func nullJSON(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return string(b)
+}
+
+// Prune implements Store.
+func (s *PostgresStore) Prune(ctx context.Context, before time.Time) error {
+	_, err := s.pool().Exec(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE created_at < $1`, s.table), before)
+	if err != nil {
+		return fmt.Errorf("failed to prune audit log: %w", err)
+	}
+	return nil
+}
+
+// Close implements Store.
+func (s *PostgresStore) Close() error {
+	s.db.Close()
+	return nil
+}
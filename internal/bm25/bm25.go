@@ -132,3 +132,38 @@ func (bm *BM25) ScoreDocument(
 
 	return score
 }
+
+// FieldTF describes a term's occurrences in one field of a document,
+// for BM25F scoring.
+type FieldTF struct {
+	TF     int     // Term frequency in the field
+	Len    int     // Length of the field (in terms)
+	AvgLen float64 // Average length of the field across the corpus
+	Boost  float64 // Weight of the field relative to the others
+}
+
+// ScoreFields calculates the BM25F score for a term in a document
+// with several fields. Each field's term frequency is normalized by
+// that field's length and weighted by its boost, and the weighted sum
+// is saturated once, so a term repeated in several fields is not
+// scored as independent evidence. With a single field of boost 1 this
+// equals Score.
+func (bm *BM25) ScoreFields(fields []FieldTF, docFreq int) float64 {
+	if docFreq == 0 || bm.DocCount == 0 {
+		return 0
+	}
+
+	var tf float64
+	for _, f := range fields {
+		if f.TF == 0 || f.AvgLen == 0 {
+			continue
+		}
+		lengthNorm := 1 - bm.B + bm.B*(float64(f.Len)/f.AvgLen)
+		tf += f.Boost * float64(f.TF) / lengthNorm
+	}
+	if tf == 0 {
+		return 0
+	}
+
+	return bm.IDF(docFreq) * (tf * (bm.K1 + 1)) / (tf + bm.K1)
+}
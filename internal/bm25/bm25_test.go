@@ -10,6 +10,7 @@
 package bm25
 
 import (
+	"math"
 	"testing"
 )
 
@@ -143,3 +144,35 @@ func TestBM25_ScoreDocument_NoMatch(t *testing.T) {
 		t.Errorf("expected 0 for no matching terms, got %f", score)
 	}
 }
+
+func TestBM25_ScoreFields(t *testing.T) {
+	bm := New()
+	bm.SetCorpusStats(100, 50)
+
+	// A single field with boost 1 is plain BM25.
+	single := bm.ScoreFields([]FieldTF{{TF: 3, Len: 40, AvgLen: 50, Boost: 1}}, 10)
+	if want := bm.Score(3, 10, 40); math.Abs(single-want) > 1e-12 {
+		t.Errorf("single field score = %f, want %f", single, want)
+	}
+
+	// A boosted match in a short title adds to the body's score, but
+	// less than scoring the two fields independently would.
+	body := FieldTF{TF: 1, Len: 50, AvgLen: 50, Boost: 1}
+	title := FieldTF{TF: 1, Len: 5, AvgLen: 6, Boost: 3}
+	combined := bm.ScoreFields([]FieldTF{body, title}, 10)
+	bodyOnly := bm.ScoreFields([]FieldTF{body}, 10)
+	titleOnly := bm.ScoreFields([]FieldTF{title}, 10)
+	if combined <= bodyOnly || combined <= titleOnly {
+		t.Errorf("combined score %f should exceed either field alone (%f, %f)", combined, bodyOnly, titleOnly)
+	}
+	if combined >= bodyOnly+titleOnly {
+		t.Errorf("combined score %f should saturate below %f", combined, bodyOnly+titleOnly)
+	}
+
+	if score := bm.ScoreFields([]FieldTF{{Len: 10, AvgLen: 10, Boost: 2}}, 10); score != 0 {
+		t.Errorf("expected 0 without a match, got %f", score)
+	}
+	if score := bm.ScoreFields([]FieldTF{body}, 0); score != 0 {
+		t.Errorf("expected 0 for zero doc freq, got %f", score)
+	}
+}
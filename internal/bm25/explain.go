@@ -0,0 +1,103 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package bm25
+
+// ContentField is the name Explain gives a document's main content in
+// TermScore.TF, alongside the names of its other fields.
+const ContentField = "content"
+
+// TermScore is one query term's contribution to a document's score.
+type TermScore struct {
+	Term    string         `json:"term"`
+	DocFreq int            `json:"doc_freq"` // Documents containing the term
+	IDF     float64        `json:"idf"`
+	TF      map[string]int `json:"tf,omitempty"` // Occurrences by field; omitted if none
+	Score   float64        `json:"score"`
+}
+
+// Explanation breaks a document's BM25 score for a query down into its
+// parts. Score is Terms' scores plus Proximity, or zero when the
+// document does not match.
+type Explanation struct {
+	Terms       []TermScore       `json:"terms"`
+	Corrections map[string]string `json:"corrections,omitempty"` // Misspelled term -> replacement
+	PhraseMatch bool              `json:"phrase_match"`          // Every quoted phrase appears
+	Proximity   float64           `json:"proximity"`             // Weighted proximity bonus
+	Score       float64           `json:"score"`
+}
+
+// Explain explains the score Search gives document id for query,
+// including any spelling correction Search would apply. It returns
+// false if the document is not in the index.
+func (idx *Index) Explain(query, id string) (Explanation, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	doc, ok := idx.docs[id]
+	if !ok {
+		return Explanation{}, false
+	}
+
+	q := idx.tokenizer.ParseQuery(query)
+	var e Explanation
+	if len(q.Terms) > 0 && idx.fuzzySimilarity > 0 && len(idx.search(q, 1)) == 0 {
+		if corrected, ok := idx.correctQuery(q, idx.fuzzySimilarity); ok {
+			e.Corrections = make(map[string]string)
+			for i, term := range q.Order {
+				if corrected.Order[i] != term {
+					e.Corrections[term] = corrected.Order[i]
+				}
+			}
+			q = corrected
+		}
+	}
+
+	seen := make(map[string]bool, len(q.Terms))
+	var sum float64
+	for _, term := range q.Order {
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+
+		ts := TermScore{
+			Term:    term,
+			DocFreq: idx.docFreqs[term],
+			IDF:     idx.scorer.IDF(idx.docFreqs[term]),
+		}
+		if len(doc.fields) == 0 {
+			ts.Score = idx.scorer.Score(doc.TermFreqs[term], idx.docFreqs[term], doc.Length)
+		} else {
+			ts.Score = idx.scoreFields(map[string]int{term: 1}, doc)
+		}
+		if tf := doc.TermFreqs[term]; tf > 0 {
+			ts.TF = map[string]int{ContentField: tf}
+		}
+		for _, f := range doc.fields {
+			if tf := f.termFreqs[term]; tf > 0 {
+				if ts.TF == nil {
+					ts.TF = make(map[string]int)
+				}
+				ts.TF[f.name] += tf
+			}
+		}
+		e.Terms = append(e.Terms, ts)
+		sum += ts.Score
+	}
+
+	e.PhraseMatch = doc.containsPhrases(q.Phrases)
+	if sum > 0 && idx.proximityWeight > 0 {
+		e.Proximity = idx.proximityWeight * idx.proximityScore(q.Order, doc)
+	}
+	if e.PhraseMatch && sum > 0 {
+		e.Score = sum + e.Proximity
+	}
+	return e, true
+}
@@ -0,0 +1,119 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package bm25
+
+import "unicode/utf8"
+
+// DefaultFuzzySimilarity is the default minimum trigram similarity a
+// vocabulary term needs to stand in for a misspelled query term.
+const DefaultFuzzySimilarity = 0.4
+
+// trigrams returns the set of trigrams in term, padded the way
+// PostgreSQL's pg_trgm pads words: two spaces before and one after, so
+// a word's first letters weigh more than its middle.
+func trigrams(term string) map[string]struct{} {
+	runes := []rune("  " + term + " ")
+	set := make(map[string]struct{}, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = struct{}{}
+	}
+	return set
+}
+
+// trigramSimilarity returns the number of trigrams a and b share
+// divided by the number of distinct trigrams in either, from 0 (none
+// shared) to 1 (identical sets).
+func trigramSimilarity(a, b map[string]struct{}) float64 {
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
+
+// correctTerm returns the indexed term most similar to term, or false
+// if none reaches minSimilarity. Ties go to the term found in more
+// documents, then to the alphabetically first, so corrections are
+// deterministic. The caller must hold idx.mu.
+func (idx *Index) correctTerm(term string, minSimilarity float64) (string, bool) {
+	want := trigrams(term)
+	wantLen := utf8.RuneCountInString(term)
+
+	var best string
+	var bestSim float64
+	for candidate, df := range idx.docFreqs {
+		// A padded word of n runes has at most n+1 trigrams, so terms
+		// whose lengths differ too much can't reach minSimilarity.
+		n := utf8.RuneCountInString(candidate)
+		if float64(min(n, wantLen)+1) < minSimilarity*float64(max(n, wantLen)+1) {
+			continue
+		}
+
+		sim := trigramSimilarity(want, trigrams(candidate))
+		if sim < minSimilarity || sim < bestSim {
+			continue
+		}
+		if sim == bestSim {
+			bestDF := idx.docFreqs[best]
+			if df < bestDF || (df == bestDF && candidate > best) {
+				continue
+			}
+		}
+		best, bestSim = candidate, sim
+	}
+	return best, best != ""
+}
+
+// correctQuery replaces each query term missing from the index with
+// its closest indexed term, reporting whether any term was replaced.
+// The caller must hold idx.mu.
+func (idx *Index) correctQuery(q Query, minSimilarity float64) (Query, bool) {
+	replacements := make(map[string]string)
+	for term := range q.Terms {
+		if idx.docFreqs[term] > 0 {
+			continue
+		}
+		if corrected, ok := idx.correctTerm(term, minSimilarity); ok {
+			replacements[term] = corrected
+		}
+	}
+	if len(replacements) == 0 {
+		return q, false
+	}
+
+	replace := func(term string) string {
+		if corrected, ok := replacements[term]; ok {
+			return corrected
+		}
+		return term
+	}
+
+	corrected := Query{Terms: make(map[string]int, len(q.Terms))}
+	for term, freq := range q.Terms {
+		corrected.Terms[replace(term)] += freq
+	}
+	for _, term := range q.Order {
+		corrected.Order = append(corrected.Order, replace(term))
+	}
+	for _, phrase := range q.Phrases {
+		fixed := make([]string, len(phrase))
+		for i, term := range phrase {
+			fixed[i] = replace(term)
+		}
+		corrected.Phrases = append(corrected.Phrases, fixed)
+	}
+	return corrected, true
+}
@@ -20,6 +20,26 @@ type Document struct {
 	Content   string
 	Length    int            // Number of tokens
 	TermFreqs map[string]int // Term frequencies
+
+	positions map[string][]int // term -> positions in Content
+	fields    []indexedField   // Additional fields; nil for content-only documents
+}
+
+// Field is an additional text field of a document, such as a title,
+// indexed alongside its content.
+type Field struct {
+	Name  string
+	Text  string
+	Boost float64 // Weight relative to the content, which has boost 1
+}
+
+// indexedField is the tokenized form of a Field.
+type indexedField struct {
+	name      string
+	boost     float64
+	length    int
+	termFreqs map[string]int
+	positions map[string][]int
 }
 
 // SearchResult represents a BM25 search result.
@@ -37,7 +57,11 @@ type Index struct {
 	docs      map[string]*Document // docID -> Document
 	docFreqs  map[string]int       // term -> document frequency
 	totalDocs int
-	totalLen  int // Total length of all documents (for avg calculation)
+	totalLen  int            // Total length of all documents (for avg calculation)
+	fieldLens map[string]int // field name -> total length across documents
+
+	proximityWeight float64 // Weight of the proximity bonus; 0 disables it
+	fuzzySimilarity float64 // Minimum similarity for spelling correction; 0 disables it
 }
 
 // NewIndex creates a new BM25 index.
@@ -47,6 +71,9 @@ func NewIndex() *Index {
 		scorer:    New(),
 		docs:      make(map[string]*Document),
 		docFreqs:  make(map[string]int),
+		fieldLens: make(map[string]int),
+
+		proximityWeight: DefaultProximityWeight,
 	}
 }
 
@@ -57,30 +84,70 @@ func NewIndexWithParams(k1, b float64) *Index {
 		scorer:    NewWithParams(k1, b),
 		docs:      make(map[string]*Document),
 		docFreqs:  make(map[string]int),
+		fieldLens: make(map[string]int),
+
+		proximityWeight: DefaultProximityWeight,
 	}
 }
 
 // AddDocument adds a document to the index.
 func (idx *Index) AddDocument(id, content string) {
+	idx.AddDocumentFields(id, content, nil)
+}
+
+// AddDocumentFields adds a document with additional fields, such as a
+// title, to the index. Documents with fields are scored with BM25F:
+// each field is length-normalized against its own average and weighted
+// by its boost, so a match in a short, boosted title can outrank
+// several in a long body.
+func (idx *Index) AddDocumentFields(id, content string, fields []Field) {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	// Tokenize and get term frequencies
-	termFreqs := idx.tokenizer.TokenFrequencies(content)
-	docLen := 0
-	for _, freq := range termFreqs {
-		docLen += freq
+	// Tokenize and get term frequencies and positions
+	tokens := idx.tokenizer.Tokenize(content)
+	positions := termPositions(tokens)
+	termFreqs := make(map[string]int, len(positions))
+	for term, pos := range positions {
+		termFreqs[term] = len(pos)
 	}
+	docLen := len(tokens)
 
 	doc := &Document{
 		ID:        id,
 		Content:   content,
 		Length:    docLen,
 		TermFreqs: termFreqs,
+		positions: positions,
 	}
 
-	// Update document frequencies
+	// A term counts once towards document frequency however many of
+	// the document's fields contain it.
+	terms := make(map[string]bool, len(termFreqs))
 	for term := range termFreqs {
+		terms[term] = true
+	}
+	for _, f := range fields {
+		fieldTokens := idx.tokenizer.Tokenize(f.Text)
+		fieldPositions := termPositions(fieldTokens)
+		fieldFreqs := make(map[string]int, len(fieldPositions))
+		for term, pos := range fieldPositions {
+			fieldFreqs[term] = len(pos)
+			terms[term] = true
+		}
+		fieldLen := len(fieldTokens)
+		doc.fields = append(doc.fields, indexedField{
+			name:      f.Name,
+			boost:     f.Boost,
+			length:    fieldLen,
+			termFreqs: fieldFreqs,
+			positions: fieldPositions,
+		})
+		idx.fieldLens[f.Name] += fieldLen
+	}
+
+	// Update document frequencies
+	for term := range terms {
 		idx.docFreqs[term]++
 	}
 
@@ -108,7 +175,30 @@ func (idx *Index) updateScorerStats() {
 	idx.scorer.SetCorpusStats(idx.totalDocs, avgDL)
 }
 
-// Search performs a BM25 search and returns the top-N results.
+// SetProximityWeight sets the weight of the bonus for query terms that
+// appear near each other in a document; 0 disables it.
+func (idx *Index) SetProximityWeight(weight float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.proximityWeight = weight
+}
+
+// SetFuzzySimilarity enables spelling correction for searches that
+// match nothing: query terms missing from the index are replaced by the
+// indexed term with the most trigrams in common, provided its trigram
+// similarity is at least minSimilarity (0 to 1). 0 disables it.
+func (idx *Index) SetFuzzySimilarity(minSimilarity float64) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.fuzzySimilarity = minSimilarity
+}
+
+// Search performs a BM25 search and returns the top-N results. Quoted
+// phrases in query must appear in a document's content or one of its
+// fields for it to match, and documents where neighboring query terms
+// appear close together score higher. If nothing matches and spelling
+// correction is enabled, misspelled terms are corrected and the search
+// is retried.
 func (idx *Index) Search(query string, topN int) []SearchResult {
 	idx.mu.RLock()
 	defer idx.mu.RUnlock()
@@ -118,11 +208,25 @@ func (idx *Index) Search(query string, topN int) []SearchResult {
 	}
 
 	// Tokenize query
-	queryTermFreqs := idx.tokenizer.TokenFrequencies(query)
-	if len(queryTermFreqs) == 0 {
+	q := idx.tokenizer.ParseQuery(query)
+	if len(q.Terms) == 0 {
 		return nil
 	}
 
+	results := idx.search(q, topN)
+	if len(results) == 0 && idx.fuzzySimilarity > 0 {
+		if corrected, ok := idx.correctQuery(q, idx.fuzzySimilarity); ok {
+			results = idx.search(corrected, topN)
+		}
+	}
+	return results
+}
+
+// search scores every document against a parsed query. The caller must
+// hold idx.mu.
+func (idx *Index) search(q Query, topN int) []SearchResult {
+	queryTermFreqs := q.Terms
+
 	// Score each document
 	type scoredDoc struct {
 		id      string
@@ -132,12 +236,24 @@ func (idx *Index) Search(query string, topN int) []SearchResult {
 
 	var scored []scoredDoc
 	for id, doc := range idx.docs {
-		score := idx.scorer.ScoreDocument(
-			queryTermFreqs,
-			doc.TermFreqs,
-			idx.docFreqs,
-			doc.Length,
-		)
+		if !doc.containsPhrases(q.Phrases) {
+			continue
+		}
+
+		var score float64
+		if len(doc.fields) == 0 {
+			score = idx.scorer.ScoreDocument(
+				queryTermFreqs,
+				doc.TermFreqs,
+				idx.docFreqs,
+				doc.Length,
+			)
+		} else {
+			score = idx.scoreFields(queryTermFreqs, doc)
+		}
+		if score > 0 && idx.proximityWeight > 0 {
+			score += idx.proximityWeight * idx.proximityScore(q.Order, doc)
+		}
 		if score > 0 {
 			scored = append(scored, scoredDoc{
 				id:      id,
@@ -165,6 +281,57 @@ func (idx *Index) Search(query string, topN int) []SearchResult {
 	return results
 }
 
+// scoreFields calculates the BM25F score of a document with fields,
+// treating its content as a field with boost 1. Must be called with
+// idx.mu held.
+func (idx *Index) scoreFields(queryTerms map[string]int, doc *Document) float64 {
+	fields := make([]FieldTF, len(doc.fields)+1)
+	var score float64
+	for term := range queryTerms {
+		fields[0] = FieldTF{
+			TF:     doc.TermFreqs[term],
+			Len:    doc.Length,
+			AvgLen: idx.scorer.AvgDL,
+			Boost:  1,
+		}
+		for i, f := range doc.fields {
+			fields[i+1] = FieldTF{
+				TF:     f.termFreqs[term],
+				Len:    f.length,
+				AvgLen: float64(idx.fieldLens[f.name]) / float64(idx.totalDocs),
+				Boost:  f.boost,
+			}
+		}
+		score += idx.scorer.ScoreFields(fields, idx.docFreqs[term])
+	}
+	return score
+}
+
+// containsPhrases reports whether every phrase occurs in the document's
+// content or in one of its fields.
+func (doc *Document) containsPhrases(phrases [][]string) bool {
+	for _, phrase := range phrases {
+		found := containsPhrase(doc.positions, phrase)
+		for i := 0; !found && i < len(doc.fields); i++ {
+			found = containsPhrase(doc.fields[i].positions, phrase)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// proximityScore returns the document's proximity score: the best of
+// its content's and its fields' scores, weighted by field boost.
+func (idx *Index) proximityScore(order []string, doc *Document) float64 {
+	best := idx.scorer.proximityScore(order, doc.positions, idx.docFreqs)
+	for _, f := range doc.fields {
+		best = max(best, f.boost*idx.scorer.proximityScore(order, f.positions, idx.docFreqs))
+	}
+	return best
+}
+
 // Clear removes all documents from the index.
 func (idx *Index) Clear() {
 	idx.mu.Lock()
@@ -174,6 +341,7 @@ func (idx *Index) Clear() {
 	idx.docFreqs = make(map[string]int)
 	idx.totalDocs = 0
 	idx.totalLen = 0
+	idx.fieldLens = make(map[string]int)
 }
 
 // Size returns the number of documents in the index.
@@ -10,6 +10,7 @@
 package bm25
 
 import (
+	"math"
 	"testing"
 )
 
@@ -193,3 +194,205 @@ func TestIndex_NewIndexWithParams(t *testing.T) {
 		t.Errorf("expected B 0.5, got %f", idx.scorer.B)
 	}
 }
+
+func TestIndex_AddDocumentFields_BoostsTitleMatches(t *testing.T) {
+	idx := NewIndex()
+
+	idx.AddDocumentFields("title", "Steps to add a node to a running cluster safely.",
+		[]Field{{Name: "title", Text: "Replication setup", Boost: 3}})
+	idx.AddDocumentFields("body", "Replication copies changes; replication lag is monitored.",
+		[]Field{{Name: "title", Text: "Monitoring", Boost: 3}})
+	idx.AddDocumentFields("other", "Backups are taken nightly.",
+		[]Field{{Name: "title", Text: "Backups", Boost: 3}})
+
+	results := idx.Search("replication", 3)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].ID != "title" {
+		t.Errorf("expected the title match first, got %+v", results)
+	}
+	if results[0].Content != "Steps to add a node to a running cluster safely." {
+		t.Errorf("expected results to carry the content, got %q", results[0].Content)
+	}
+
+	// Without the boost, the body with two matches wins.
+	idx.Clear()
+	idx.AddDocumentFields("title", "Steps to add a node to a running cluster safely.",
+		[]Field{{Name: "title", Text: "Replication setup", Boost: 0.1}})
+	idx.AddDocumentFields("body", "Replication copies changes; replication lag is monitored.",
+		[]Field{{Name: "title", Text: "Monitoring", Boost: 0.1}})
+	idx.AddDocumentFields("other", "Backups are taken nightly.",
+		[]Field{{Name: "title", Text: "Backups", Boost: 0.1}})
+
+	results = idx.Search("replication", 3)
+	if len(results) != 2 || results[0].ID != "body" {
+		t.Errorf("expected the body match first with a low boost, got %+v", results)
+	}
+}
+
+func TestIndex_Search_Phrase(t *testing.T) {
+	idx := NewIndex()
+	idx.AddDocuments(map[string]string{
+		"exact":    "ERROR: could not connect to server on port 5432",
+		"scramble": "the server could not find the port to connect",
+		"other":    "vacuum reclaims storage from dead tuples",
+	})
+
+	results := idx.Search(`"could not connect to server"`, 10)
+	if len(results) != 1 || results[0].ID != "exact" {
+		t.Errorf("expected only the exact phrase to match, got %+v", results)
+	}
+
+	// Terms outside the quotes still score as usual.
+	results = idx.Search(`"connect to server" port`, 10)
+	if len(results) != 1 || results[0].ID != "exact" {
+		t.Errorf("expected the phrase to filter results, got %+v", results)
+	}
+
+	// An unterminated quote is ignored.
+	results = idx.Search(`"could not connect to server`, 10)
+	if len(results) != 2 {
+		t.Errorf("expected an unterminated quote to match terms, got %+v", results)
+	}
+}
+
+func TestIndex_Search_PhraseInField(t *testing.T) {
+	idx := NewIndex()
+	idx.AddDocumentFields("field", "How to tune autovacuum.",
+		[]Field{{Name: "title", Text: "pg_stat_activity view", Boost: 1}})
+	idx.AddDocumentFields("split", "The activity of pg_stat shows up elsewhere.", nil)
+	idx.AddDocument("other", "Backups are taken nightly.")
+
+	results := idx.Search(`"pg_stat_activity"`, 10)
+	if len(results) != 1 || results[0].ID != "field" {
+		t.Errorf("expected the phrase to match the field, got %+v", results)
+	}
+}
+
+func TestIndex_Search_Proximity(t *testing.T) {
+	docs := map[string]string{
+		"near":  "set the shared buffers parameter then restart the database server",
+		"far":   "buffers are used for caching while shared memory is set at server start",
+		"other": "vacuum reclaims storage from dead tuples",
+	}
+
+	idx := NewIndex()
+	idx.AddDocuments(docs)
+	results := idx.Search("shared buffers", 10)
+	if len(results) != 2 || results[0].ID != "near" {
+		t.Fatalf("expected adjacent terms to rank first, got %+v", results)
+	}
+	withBonus := results[0].Score
+
+	idx.SetProximityWeight(0)
+	results = idx.Search("shared buffers", 10)
+	if len(results) != 2 || results[0].Score >= withBonus {
+		t.Errorf("expected a lower score without the proximity bonus, got %+v", results)
+	}
+}
+
+func TestIndex_Search_Fuzzy(t *testing.T) {
+	docs := map[string]string{
+		"repl":   "streaming replication keeps a standby server in sync",
+		"vacuum": "vacuum reclaims storage from dead tuples",
+		"backup": "take a base backup before upgrading",
+	}
+
+	idx := NewIndex()
+	idx.AddDocuments(docs)
+	if results := idx.Search("replicaiton standby", 10); len(results) != 1 {
+		t.Fatalf("expected the other term to match, got %+v", results)
+	}
+	if results := idx.Search("replicaiton", 10); len(results) != 0 {
+		t.Fatalf("expected no matches with correction disabled, got %+v", results)
+	}
+
+	idx.SetFuzzySimilarity(DefaultFuzzySimilarity)
+	results := idx.Search("replicaiton", 10)
+	if len(results) != 1 || results[0].ID != "repl" {
+		t.Errorf("expected the misspelling to be corrected, got %+v", results)
+	}
+	if results := idx.Search(`"streaming replicaton"`, 10); len(results) != 1 || results[0].ID != "repl" {
+		t.Errorf("expected phrase terms to be corrected, got %+v", results)
+	}
+	if results := idx.Search("zzqx", 10); len(results) != 0 {
+		t.Errorf("expected no match for a term unlike any indexed term, got %+v", results)
+	}
+}
+
+func TestIndex_Explain(t *testing.T) {
+	idx := NewIndex()
+	idx.AddDocumentFields("title", "Configure the standby server.",
+		[]Field{{Name: "title", Text: "Streaming replication", Boost: 2}})
+	idx.AddDocument("body", "Streaming replication sends WAL to the standby.")
+	idx.AddDocument("other", "Vacuum reclaims storage from dead tuples.")
+
+	results := idx.Search("streaming replication", 10)
+	for _, r := range results {
+		e, ok := idx.Explain("streaming replication", r.ID)
+		if !ok {
+			t.Fatalf("%s: expected an explanation", r.ID)
+		}
+		if math.Abs(e.Score-r.Score) > 1e-9 {
+			t.Errorf("%s: explained score %v, search score %v", r.ID, e.Score, r.Score)
+		}
+		var sum float64
+		for _, ts := range e.Terms {
+			sum += ts.Score
+		}
+		if math.Abs(sum+e.Proximity-e.Score) > 1e-9 {
+			t.Errorf("%s: term scores %v and proximity %v do not add up to %v", r.ID, sum, e.Proximity, e.Score)
+		}
+	}
+
+	e, _ := idx.Explain("streaming replication", "title")
+	if len(e.Terms) != 2 || e.Terms[0].Term != "streaming" || e.Terms[0].TF["title"] != 1 ||
+		e.Terms[0].DocFreq != 2 {
+		t.Errorf("unexpected term breakdown: %+v", e.Terms)
+	}
+	if e, _ := idx.Explain(`"replication streaming"`, "body"); e.PhraseMatch || e.Score != 0 {
+		t.Errorf("expected a failed phrase to zero the score, got %+v", e)
+	}
+
+	idx.SetFuzzySimilarity(DefaultFuzzySimilarity)
+	if e, _ := idx.Explain("replicaiton", "body"); e.Corrections["replicaiton"] != "replication" || e.Score == 0 {
+		t.Errorf("expected the correction to be reported, got %+v", e)
+	}
+	if _, ok := idx.Explain("replication", "missing"); ok {
+		t.Error("expected no explanation for a document not in the index")
+	}
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	if s := trigramSimilarity(trigrams("vacuum"), trigrams("vacuum")); s != 1 {
+		t.Errorf("expected identical terms to have similarity 1, got %v", s)
+	}
+	typo := trigramSimilarity(trigrams("replication"), trigrams("replicaiton"))
+	other := trigramSimilarity(trigrams("replication"), trigrams("relation"))
+	if typo < DefaultFuzzySimilarity || other >= typo {
+		t.Errorf("expected the typo (%v) to be closer than another word (%v)", typo, other)
+	}
+}
+
+func TestTokenizer_ParseQuery(t *testing.T) {
+	q := NewTokenizer().ParseQuery(`replication "logical decoding slot" lag`)
+
+	wantOrder := []string{"replication", "logical", "decoding", "slot", "lag"}
+	if len(q.Order) != len(wantOrder) {
+		t.Fatalf("expected order %v, got %v", wantOrder, q.Order)
+	}
+	for i, term := range wantOrder {
+		if q.Order[i] != term || q.Terms[term] != 1 {
+			t.Errorf("expected term %q at %d, got %v (terms %v)", term, i, q.Order, q.Terms)
+		}
+	}
+	if len(q.Phrases) != 1 || len(q.Phrases[0]) != 3 {
+		t.Errorf("expected one three-term phrase, got %v", q.Phrases)
+	}
+
+	// Single-term quotes are plain terms.
+	if q := NewTokenizer().ParseQuery(`"vacuum"`); len(q.Phrases) != 0 || q.Terms["vacuum"] != 1 {
+		t.Errorf("expected a single quoted term to be a plain term, got %+v", q)
+	}
+}
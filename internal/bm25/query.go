@@ -0,0 +1,136 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package bm25
+
+import (
+	"sort"
+	"strings"
+)
+
+// DefaultProximityWeight is the default weight of the proximity bonus
+// added for query terms that appear near each other in a document.
+const DefaultProximityWeight = 0.5
+
+// ProximityWindow is the largest distance, in terms, at which two
+// query terms still earn a proximity bonus.
+const ProximityWindow = 5
+
+// Query is a parsed lexical search query.
+type Query struct {
+	Terms   map[string]int // Every term and its frequency, including phrase terms
+	Order   []string       // Terms in the order they appear in the query
+	Phrases [][]string     // Quoted phrases of two or more terms
+}
+
+// ParseQuery tokenizes a search query. Text in double quotes is a
+// phrase whose terms must appear consecutively in a matching document;
+// stop words are dropped from phrases as from the rest of the query.
+// An unterminated quote is ignored.
+func (t *Tokenizer) ParseQuery(text string) Query {
+	q := Query{Terms: make(map[string]int)}
+
+	parts := strings.Split(text, `"`)
+	for i, part := range parts {
+		tokens := t.Tokenize(part)
+		for _, token := range tokens {
+			q.Terms[token]++
+		}
+		q.Order = append(q.Order, tokens...)
+
+		quoted := i%2 == 1 && i < len(parts)-1
+		if quoted && len(tokens) > 1 {
+			q.Phrases = append(q.Phrases, tokens)
+		}
+	}
+	return q
+}
+
+// termPositions returns the positions at which each token occurs, in
+// ascending order.
+func termPositions(tokens []string) map[string][]int {
+	positions := make(map[string][]int)
+	for i, token := range tokens {
+		positions[token] = append(positions[token], i)
+	}
+	return positions
+}
+
+// containsPhrase reports whether the terms of phrase occur
+// consecutively in a field with the given term positions.
+func containsPhrase(positions map[string][]int, phrase []string) bool {
+	for _, start := range positions[phrase[0]] {
+		matched := true
+		for i := 1; i < len(phrase); i++ {
+			if !hasPosition(positions[phrase[i]], start+i) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPosition(positions []int, pos int) bool {
+	i := sort.SearchInts(positions, pos)
+	return i < len(positions) && positions[i] == pos
+}
+
+// minDistance returns the smallest distance between a position in a
+// and one in b, both sorted ascending, or -1 if either is empty.
+func minDistance(a, b []int) int {
+	if len(a) == 0 || len(b) == 0 {
+		return -1
+	}
+	best := -1
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		d := a[i] - b[j]
+		if d < 0 {
+			d = -d
+		}
+		if best < 0 || d < best {
+			best = d
+		}
+		if a[i] < b[j] {
+			i++
+		} else {
+			j++
+		}
+	}
+	return best
+}
+
+// proximityScore scores how closely neighboring query terms appear in
+// a field: each pair of consecutive, distinct query terms found within
+// ProximityWindow of each other adds their mean IDF divided by the
+// distance between them, so adjacent terms earn the most.
+func (bm *BM25) proximityScore(
+	order []string,
+	positions map[string][]int,
+	docFreqs map[string]int,
+) float64 {
+	var score float64
+	for i := 1; i < len(order); i++ {
+		a, b := order[i-1], order[i]
+		if a == b {
+			continue
+		}
+		d := minDistance(positions[a], positions[b])
+		if d <= 0 || d > ProximityWindow {
+			continue
+		}
+		idf := (bm.IDF(docFreqs[a]) + bm.IDF(docFreqs[b])) / 2
+		score += idf / float64(d)
+	}
+	return score
+}
@@ -22,6 +22,11 @@ const (
 	EnvOpenAIAPIKey    = "OPENAI_API_KEY"
 	EnvVoyageAPIKey    = "VOYAGE_API_KEY"
 	EnvGeminiAPIKey    = "GEMINI_API_KEY"
+	EnvCohereAPIKey    = "COHERE_API_KEY"
+
+	// EnvOpenAICompatibleAPIKey is the optional key of the server an
+	// openai-compatible provider's base_url points at.
+	EnvOpenAICompatibleAPIKey = "OPENAI_COMPATIBLE_API_KEY"
 )
 
 // Default API key file paths (relative to home directory).
@@ -30,6 +35,7 @@ const (
 	DefaultOpenAIKeyFile    = ".openai-api-key"
 	DefaultVoyageKeyFile    = ".voyage-api-key"
 	DefaultGeminiKeyFile    = ".gemini-api-key"
+	DefaultCohereKeyFile    = ".cohere-api-key"
 )
 
 // LoadedKeys holds all loaded API keys.
@@ -38,6 +44,11 @@ type LoadedKeys struct {
 	OpenAI    string
 	Voyage    string
 	Gemini    string
+	Cohere    string
+	AWS       AWSCredentials
+
+	// OpenAICompatible is empty when the server needs no key.
+	OpenAICompatible string
 }
 
 // APIKeyLoader handles loading API keys from configured paths, environment
@@ -91,6 +102,28 @@ func (l *APIKeyLoader) LoadGeminiKey() (string, error) {
 	)
 }
 
+// LoadCohereKey loads the Cohere API key.
+func (l *APIKeyLoader) LoadCohereKey() (string, error) {
+	return l.loadKey(
+		l.config.Cohere,
+		EnvCohereAPIKey,
+		DefaultCohereKeyFile,
+		"Cohere",
+	)
+}
+
+// LoadOpenAICompatibleKey loads the optional key of an
+// openai-compatible server: from the configured file, or else the
+// OPENAI_COMPATIBLE_API_KEY environment variable. Self-hosted servers
+// often need no key, so there is no default file, and finding none is
+// not an error.
+func (l *APIKeyLoader) LoadOpenAICompatibleKey() (string, error) {
+	if l.config.OpenAICompatible != "" {
+		return readKeyFile(ExpandPath(l.config.OpenAICompatible), "OpenAI-compatible")
+	}
+	return os.Getenv(EnvOpenAICompatibleAPIKey), nil
+}
+
 // loadKey loads an API key with the following priority:
 // 1. Configured file path (if specified in config)
 // 2. Environment variable
@@ -100,7 +133,7 @@ func (l *APIKeyLoader) loadKey(
 ) (string, error) {
 	// Priority 1: Configured file path
 	if configPath != "" {
-		path := expandKeyPath(configPath)
+		path := ExpandPath(configPath)
 		return readKeyFile(path, providerName)
 	}
 
@@ -147,24 +180,30 @@ func readKeyFile(path, providerName string) (string, error) {
 	return key, nil
 }
 
-// expandKeyPath expands ~ to the user's home directory.
-func expandKeyPath(path string) string {
-	if strings.HasPrefix(path, "~/") {
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return path
-		}
-		return filepath.Join(homeDir, path[2:])
+// ReadSecretFile reads a credential other than an API key, such as a
+// bot token or webhook secret, from a file as API keys are read: a
+// leading ~/ is expanded, surrounding whitespace is trimmed, and an
+// empty file is an error. what names the credential in errors.
+func ReadSecretFile(path, what string) (string, error) {
+	path = ExpandPath(path)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", what, err)
+	}
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("%s file is empty: %s", what, path)
 	}
-	return path
+	return secret, nil
 }
 
 // APIKeyFilePaths returns the resolved paths of every API key file the
 // given config actually reads from — explicitly configured paths, or the
 // default file locations (~/.provider-api-key) when they exist on disk.
 // Keys sourced from environment variables have no backing file and are
-// not included. Used to watch these files for changes (e.g. a mounted
-// secret being rotated) alongside the main config file — see issue #30.
+// not included. Pipelines' database password files are included too.
+// Used to watch these files for changes (e.g. a mounted secret being
+// rotated) alongside the main config file — see issue #30.
 func APIKeyFilePaths(cfg *Config) []string {
 	seen := make(map[string]bool)
 	var paths []string
@@ -178,7 +217,7 @@ func APIKeyFilePaths(cfg *Config) []string {
 			}
 			path = filepath.Join(homeDir, defaultFile)
 		} else {
-			path = expandKeyPath(path)
+			path = ExpandPath(path)
 		}
 
 		if seen[path] {
@@ -195,12 +234,25 @@ func APIKeyFilePaths(cfg *Config) []string {
 	addIfFile(cfg.APIKeys.OpenAI, DefaultOpenAIKeyFile)
 	addIfFile(cfg.APIKeys.Voyage, DefaultVoyageKeyFile)
 	addIfFile(cfg.APIKeys.Gemini, DefaultGeminiKeyFile)
+	addIfFile(cfg.APIKeys.Cohere, DefaultCohereKeyFile)
+	if cfg.APIKeys.OpenAICompatible != "" {
+		addIfFile(cfg.APIKeys.OpenAICompatible, "")
+	}
+	addIfFile(cfg.APIKeys.AWS, DefaultAWSCredentialsFile)
 
 	for _, p := range cfg.Pipelines {
+		if p.Database.PasswordFile != "" {
+			addIfFile(p.Database.PasswordFile, "")
+		}
 		addIfFile(p.APIKeys.Anthropic, DefaultAnthropicKeyFile)
 		addIfFile(p.APIKeys.OpenAI, DefaultOpenAIKeyFile)
 		addIfFile(p.APIKeys.Voyage, DefaultVoyageKeyFile)
 		addIfFile(p.APIKeys.Gemini, DefaultGeminiKeyFile)
+		addIfFile(p.APIKeys.Cohere, DefaultCohereKeyFile)
+		if p.APIKeys.OpenAICompatible != "" {
+			addIfFile(p.APIKeys.OpenAICompatible, "")
+		}
+		addIfFile(p.APIKeys.AWS, DefaultAWSCredentialsFile)
 	}
 
 	return paths
@@ -251,6 +303,30 @@ func (l *APIKeyLoader) LoadRequiredKeys(pipelines []Pipeline) (*LoadedKeys, erro
 		keys.Gemini = key
 	}
 
+	if needed["cohere"] {
+		key, err := l.LoadCohereKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.Cohere = key
+	}
+
+	if needed["openai-compatible"] {
+		key, err := l.LoadOpenAICompatibleKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.OpenAICompatible = key
+	}
+
+	if needed["bedrock"] {
+		creds, err := l.LoadAWSCredentials()
+		if err != nil {
+			return nil, err
+		}
+		keys.AWS = creds
+	}
+
 	// Ollama doesn't require an API key
 
 	return keys, nil
@@ -266,6 +342,9 @@ func (l *APIKeyLoader) LoadKeysForPipeline(pipeline Pipeline) (*LoadedKeys, erro
 	// Determine which providers are needed for this pipeline
 	needed[strings.ToLower(pipeline.EmbeddingLLM.Provider)] = true
 	needed[strings.ToLower(pipeline.RAGLLM.Provider)] = true
+	for _, fb := range pipeline.RAGLLMFallbacks {
+		needed[strings.ToLower(fb.Provider)] = true
+	}
 	if pipeline.Rerank.Provider != "" {
 		needed[strings.ToLower(pipeline.Rerank.Provider)] = true
 	}
@@ -303,6 +382,30 @@ func (l *APIKeyLoader) LoadKeysForPipeline(pipeline Pipeline) (*LoadedKeys, erro
 		keys.Gemini = key
 	}
 
+	if needed["cohere"] {
+		key, err := l.LoadCohereKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.Cohere = key
+	}
+
+	if needed["openai-compatible"] {
+		key, err := l.LoadOpenAICompatibleKey()
+		if err != nil {
+			return nil, err
+		}
+		keys.OpenAICompatible = key
+	}
+
+	if needed["bedrock"] {
+		creds, err := l.LoadAWSCredentials()
+		if err != nil {
+			return nil, err
+		}
+		keys.AWS = creds
+	}
+
 	// Ollama doesn't require an API key
 
 	return keys, nil
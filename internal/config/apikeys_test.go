@@ -9,7 +9,11 @@
 
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 // TestLoadKeysForPipeline_RerankProviderKeyLoaded is a regression test
 // for a live end-to-end bug found while verifying issue #22: a
@@ -57,3 +61,176 @@ func TestLoadKeysForPipeline_NoRerankDoesNotRequireVoyageKey(t *testing.T) {
 		t.Errorf("expected no Voyage key without a rerank stage, got %q", keys.Voyage)
 	}
 }
+
+// TestLoadKeysForPipeline_CohereKeyLoaded verifies the Cohere key is
+// loaded from a configured file in preference to COHERE_API_KEY.
+func TestLoadKeysForPipeline_CohereKeyLoaded(t *testing.T) {
+	t.Setenv(EnvCohereAPIKey, "co-env")
+	path := filepath.Join(t.TempDir(), "cohere.key")
+	if err := os.WriteFile(path, []byte("co-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := Pipeline{
+		EmbeddingLLM: LLMConfig{Provider: "ollama"},
+		RAGLLM:       LLMConfig{Provider: "ollama"},
+		Rerank:       RerankConfig{Provider: "cohere", Model: "rerank-v3.5"},
+	}
+	keys, err := NewAPIKeyLoader(APIKeysConfig{}).LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.Cohere != "co-env" {
+		t.Errorf("expected the Cohere key from the environment, got %q", keys.Cohere)
+	}
+
+	keys, err = NewAPIKeyLoader(APIKeysConfig{Cohere: path}).LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.Cohere != "co-file" {
+		t.Errorf("expected the Cohere key from the configured file, got %q", keys.Cohere)
+	}
+}
+
+// TestLoadKeysForPipeline_OpenAICompatibleKeyOptional verifies an
+// openai-compatible pipeline loads without a key, and with the one
+// OPENAI_COMPATIBLE_API_KEY names when set.
+func TestLoadKeysForPipeline_OpenAICompatibleKeyOptional(t *testing.T) {
+	t.Setenv(EnvOpenAICompatibleAPIKey, "")
+	p := Pipeline{
+		EmbeddingLLM: LLMConfig{Provider: "openai-compatible"},
+		RAGLLM:       LLMConfig{Provider: "openai-compatible"},
+	}
+
+	keys, err := NewAPIKeyLoader(APIKeysConfig{}).LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.OpenAICompatible != "" || keys.OpenAI != "" {
+		t.Errorf("expected no keys, got %+v", keys)
+	}
+
+	t.Setenv(EnvOpenAICompatibleAPIKey, "sk-local")
+	keys, err = NewAPIKeyLoader(APIKeysConfig{}).LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.OpenAICompatible != "sk-local" {
+		t.Errorf("expected the key from the environment, got %q", keys.OpenAICompatible)
+	}
+}
+
+// TestLoadKeysForPipeline_FallbackProviderKeyLoaded verifies the keys
+// of rag_llm_fallbacks providers are loaded alongside rag_llm's.
+func TestLoadKeysForPipeline_FallbackProviderKeyLoaded(t *testing.T) {
+	t.Setenv(EnvAnthropicAPIKey, "sk-ant-test")
+
+	loader := NewAPIKeyLoader(APIKeysConfig{})
+	p := Pipeline{
+		EmbeddingLLM:    LLMConfig{Provider: "ollama"},
+		RAGLLM:          LLMConfig{Provider: "ollama"},
+		RAGLLMFallbacks: []LLMConfig{{Provider: "Anthropic", Model: "claude-sonnet-4-20250514"}},
+	}
+
+	keys, err := loader.LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.Anthropic != "sk-ant-test" {
+		t.Errorf("expected the fallback's Anthropic key to be loaded, got %q", keys.Anthropic)
+	}
+}
+
+// TestLoadAWSCredentials_ProfileFromFile verifies the configured shared
+// credentials file is read for the configured profile, ahead of the
+// environment variables.
+func TestLoadAWSCredentials_ProfileFromFile(t *testing.T) {
+	t.Setenv(EnvAWSAccessKeyID, "AKIDENV")
+	t.Setenv(EnvAWSSecretAccessKey, "env-secret")
+
+	path := filepath.Join(t.TempDir(), "credentials")
+	data := `[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+# temporary credentials
+[bedrock]
+aws_access_key_id = AKIDBEDROCK
+aws_secret_access_key = bedrock-secret
+aws_session_token = bedrock-token
+`
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewAPIKeyLoader(APIKeysConfig{AWS: path, AWSProfile: "bedrock"})
+	creds, err := loader.LoadAWSCredentials()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := AWSCredentials{
+		AccessKeyID:     "AKIDBEDROCK",
+		SecretAccessKey: "bedrock-secret",
+		SessionToken:    "bedrock-token",
+	}
+	if creds != want {
+		t.Errorf("got %+v, want %+v", creds, want)
+	}
+
+	loader = NewAPIKeyLoader(APIKeysConfig{AWS: path, AWSProfile: "missing"})
+	if _, err := loader.LoadAWSCredentials(); err == nil {
+		t.Error("expected an error for a profile missing from the file")
+	}
+}
+
+// TestLoadKeysForPipeline_BedrockUsesEnvironment verifies a bedrock
+// pipeline picks up credentials from the standard AWS environment
+// variables.
+func TestLoadKeysForPipeline_BedrockUsesEnvironment(t *testing.T) {
+	t.Setenv(EnvAWSAccessKeyID, "AKIDENV")
+	t.Setenv(EnvAWSSecretAccessKey, "env-secret")
+	t.Setenv(EnvAWSSessionToken, "")
+
+	loader := NewAPIKeyLoader(APIKeysConfig{})
+	p := Pipeline{
+		EmbeddingLLM: LLMConfig{Provider: "bedrock"},
+		RAGLLM:       LLMConfig{Provider: "ollama"},
+	}
+
+	keys, err := loader.LoadKeysForPipeline(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if keys.AWS.AccessKeyID != "AKIDENV" || keys.AWS.SecretAccessKey != "env-secret" {
+		t.Errorf("unexpected credentials: %+v", keys.AWS)
+	}
+}
+
+func TestReadSecretFile(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(secretFile, []byte("  xoxb-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	emptyFile := filepath.Join(dir, "empty")
+	if err := os.WriteFile(emptyFile, []byte("\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ReadSecretFile(secretFile, "bot token"); err != nil || got != "xoxb-secret" {
+		t.Errorf("expected the trimmed secret, got %q, %v", got, err)
+	}
+	if _, err := ReadSecretFile(emptyFile, "bot token"); err == nil || !contains(err.Error(), "bot token file is empty") {
+		t.Errorf("expected an empty file error, got %v", err)
+	}
+	if _, err := ReadSecretFile(filepath.Join(dir, "missing"), "bot token"); err == nil ||
+		!contains(err.Error(), "failed to read bot token") {
+		t.Errorf("expected a read error, got %v", err)
+	}
+
+	t.Setenv("HOME", dir)
+	if got, err := ReadSecretFile("~/token", "bot token"); err != nil || got != "xoxb-secret" {
+		t.Errorf("expected ~/ to be expanded, got %q, %v", got, err)
+	}
+}
@@ -0,0 +1,166 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Environment variables read for the bedrock provider. They are the
+// ones the AWS CLI and SDKs use.
+const (
+	EnvAWSAccessKeyID     = "AWS_ACCESS_KEY_ID"
+	EnvAWSSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	EnvAWSSessionToken    = "AWS_SESSION_TOKEN"
+	EnvAWSProfile         = "AWS_PROFILE"
+	EnvAWSRegion          = "AWS_REGION"
+	EnvAWSDefaultRegion   = "AWS_DEFAULT_REGION"
+)
+
+// DefaultAWSCredentialsFile is the shared credentials file (relative to
+// the home directory) read when no other AWS credentials are found.
+const DefaultAWSCredentialsFile = ".aws/credentials"
+
+// DefaultAWSProfile is the profile read from a shared credentials file
+// when none is configured.
+const DefaultAWSProfile = "default"
+
+// AWSCredentials are the credentials the bedrock provider signs
+// requests with. SessionToken is only set for temporary credentials.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// LoadAWSCredentials loads AWS credentials with the same priority as
+// API keys:
+// 1. Configured shared credentials file (api_keys.aws)
+// 2. AWS_ACCESS_KEY_ID / AWS_SECRET_ACCESS_KEY (and AWS_SESSION_TOKEN)
+// 3. Default shared credentials file (~/.aws/credentials)
+//
+// Files are read for the profile named by api_keys.aws_profile, then
+// AWS_PROFILE, then "default".
+func (l *APIKeyLoader) LoadAWSCredentials() (AWSCredentials, error) {
+	profile := l.config.AWSProfile
+	if profile == "" {
+		profile = os.Getenv(EnvAWSProfile)
+	}
+	if profile == "" {
+		profile = DefaultAWSProfile
+	}
+
+	// Priority 1: Configured file path
+	if l.config.AWS != "" {
+		return readAWSCredentialsFile(ExpandPath(l.config.AWS), profile)
+	}
+
+	// Priority 2: Environment variables
+	if id := os.Getenv(EnvAWSAccessKeyID); id != "" {
+		secret := os.Getenv(EnvAWSSecretAccessKey)
+		if secret == "" {
+			return AWSCredentials{}, fmt.Errorf(
+				"%s is set but %s is not", EnvAWSAccessKeyID, EnvAWSSecretAccessKey)
+		}
+		return AWSCredentials{
+			AccessKeyID:     id,
+			SecretAccessKey: secret,
+			SessionToken:    os.Getenv(EnvAWSSessionToken),
+		}, nil
+	}
+
+	// Priority 3: Default file location
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	path := filepath.Join(homeDir, DefaultAWSCredentialsFile)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return AWSCredentials{}, fmt.Errorf(
+			"AWS credentials not found: set %s and %s environment variables or create %s",
+			EnvAWSAccessKeyID, EnvAWSSecretAccessKey, path)
+	}
+	return readAWSCredentialsFile(path, profile)
+}
+
+// readAWSCredentialsFile reads one profile from a shared credentials
+// file: an INI file with a [profile] section per profile holding
+// aws_access_key_id, aws_secret_access_key and, optionally,
+// aws_session_token.
+func readAWSCredentialsFile(path, profile string) (AWSCredentials, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return AWSCredentials{}, fmt.Errorf("AWS credentials file not found: %s", path)
+		}
+		return AWSCredentials{}, fmt.Errorf("failed to read AWS credentials: %w", err)
+	}
+	defer f.Close()
+
+	var creds AWSCredentials
+	found := false
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			found = found || section == profile
+			continue
+		}
+		if section != profile {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.SessionToken = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return AWSCredentials{}, fmt.Errorf("failed to read AWS credentials: %w", err)
+	}
+
+	if !found {
+		return AWSCredentials{}, fmt.Errorf("AWS profile %q not found in %s", profile, path)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf(
+			"AWS profile %q in %s must set aws_access_key_id and aws_secret_access_key",
+			profile, path)
+	}
+	return creds, nil
+}
+
+// AWSRegion returns the configured region, falling back to the
+// AWS_REGION and AWS_DEFAULT_REGION environment variables.
+func AWSRegion(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	if region := os.Getenv(EnvAWSRegion); region != "" {
+		return region
+	}
+	return os.Getenv(EnvAWSDefaultRegion)
+}
@@ -13,6 +13,8 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"time"
 )
 
@@ -47,31 +49,519 @@ func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// MarshalYAML renders a Duration as a duration string, the form
+// UnmarshalYAML reads, or an empty string when it is zero.
+func (d Duration) MarshalYAML() (interface{}, error) {
+	if d == 0 {
+		return "", nil
+	}
+	return time.Duration(d).String(), nil
+}
+
 // Config is the root configuration structure for the server.
 type Config struct {
-	Server    ServerConfig  `yaml:"server"`
-	APIKeys   APIKeysConfig `yaml:"api_keys"`
-	Defaults  Defaults      `yaml:"defaults"`
-	Pipelines []Pipeline    `yaml:"pipelines"`
+	Server    ServerConfig   `yaml:"server"`
+	APIKeys   APIKeysConfig  `yaml:"api_keys"`
+	Defaults  Defaults       `yaml:"defaults"`
+	Sessions  SessionsConfig `yaml:"sessions"`
+	Jobs      JobsConfig     `yaml:"jobs"`
+	Audit     AuditConfig    `yaml:"audit"`
+	Pipelines []Pipeline     `yaml:"pipelines"`
+
+	// Experiments split the queries asked of pipelines between variant
+	// pipelines, for comparing configurations in production.
+	Experiments []ExperimentConfig `yaml:"experiments"`
+
+	// Integrations connect pipelines to chat platforms.
+	Integrations IntegrationsConfig `yaml:"integrations"`
+
+	// Reports deliver scheduled summaries of the pipelines' usage.
+	Reports ReportsConfig `yaml:"reports"`
+
+	// ProviderLimits bound the requests in flight to each provider, by
+	// provider name, across every pipeline using the same API key and
+	// base URL.
+	ProviderLimits map[string]ProviderLimitConfig `yaml:"provider_limits"`
 }
 
 // APIKeysConfig contains paths to files containing API keys for LLM providers.
 // If not specified, keys are loaded from environment variables or default
 // file locations (~/.anthropic-api-key, ~/.openai-api-key, ~/.voyage-api-key,
-// ~/.gemini-api-key).
+// ~/.gemini-api-key, ~/.cohere-api-key).
 type APIKeysConfig struct {
 	Anthropic string `yaml:"anthropic"` // Path to file containing Anthropic API key
 	OpenAI    string `yaml:"openai"`    // Path to file containing OpenAI API key
 	Voyage    string `yaml:"voyage"`    // Path to file containing Voyage API key
 	Gemini    string `yaml:"gemini"`    // Path to file containing Gemini API key
+	Cohere    string `yaml:"cohere"`    // Path to file containing Cohere API key
+
+	// OpenAICompatible is the path to a file containing the key of the
+	// server an openai-compatible provider's base_url points at, for
+	// servers that need one.
+	OpenAICompatible string `yaml:"openai_compatible"`
+
+	// AWS is the path to a shared credentials file (the format of
+	// ~/.aws/credentials) used by the bedrock provider, and AWSProfile
+	// the profile to read from it.
+	AWS        string `yaml:"aws"`
+	AWSProfile string `yaml:"aws_profile"`
 }
 
 // ServerConfig contains HTTP server settings.
 type ServerConfig struct {
-	ListenAddress string     `yaml:"listen_address"`
-	Port          int        `yaml:"port"`
-	TLS           TLSConfig  `yaml:"tls"`
-	CORS          CORSConfig `yaml:"cors"`
+	ListenAddress string        `yaml:"listen_address"`
+	Port          int           `yaml:"port"`
+	TLS           TLSConfig     `yaml:"tls"`
+	CORS          CORSConfig    `yaml:"cors"`
+	Metrics       MetricsConfig `yaml:"metrics"`
+
+	StreamResume StreamResumeConfig `yaml:"stream_resume"`
+	HTTP2        HTTP2Config        `yaml:"http2"`
+	Explain      ExplainConfig      `yaml:"explain"`
+
+	// RequestValidation selects how request body fields outside the
+	// published API schema are handled: RequestValidationWarn (the
+	// default) or RequestValidationStrict. Fields of the wrong type
+	// are rejected either way.
+	RequestValidation string `yaml:"request_validation"`
+
+	// ClaimsHeader names the request header an authenticating proxy
+	// puts the caller's verified identity claims in, as a JSON object,
+	// optionally base64url-encoded. Config filters refer to the claims
+	// as {{claim:name}}. Empty disables claim variables.
+	ClaimsHeader string `yaml:"claims_header"`
+
+	// ProviderLog writes every provider request and response to a file,
+	// for diagnosing malformed prompts and provider errors.
+	ProviderLog ProviderLogConfig `yaml:"provider_log"`
+
+	// RateLimit bounds how fast each caller may use the pipelines.
+	RateLimit ServerRateLimitConfig `yaml:"rate_limit"`
+
+	// Admin serves the administrative endpoints under /v1/admin.
+	Admin AdminConfig `yaml:"admin"`
+
+	// Replay records the inputs of recent queries, so the admin
+	// endpoints can look an answer up by request ID and replay it.
+	Replay ReplayConfig `yaml:"replay"`
+
+	// GRPC serves the gRPC API alongside the HTTP API.
+	GRPC GRPCConfig `yaml:"grpc"`
+
+	// ConfigWatch reloads the configuration when its files change.
+	ConfigWatch ConfigWatchConfig `yaml:"config_watch"`
+
+	// ShutdownTimeout bounds how long the server waits on shutdown for
+	// in-flight requests to finish. Streams still running as it runs
+	// out are sent a final server_shutting_down event and ended; zero
+	// ends them straight away.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout"`
+
+	// TraceHeaders names the request headers, such as traceparent or
+	// X-Request-ID, copied onto every provider call a request makes, so
+	// a gateway's logs can be correlated with the server's. A header
+	// the provider's configuration already sets is not overridden.
+	TraceHeaders []string `yaml:"trace_headers"`
+
+	// Signing signs answers with the server's key, so downstream
+	// systems can verify they came from the server unmodified.
+	Signing SigningConfig `yaml:"signing"`
+}
+
+// SigningConfig enables signing answers. KeyFile holds a PEM-encoded
+// PKCS #8 Ed25519 private key, as written by
+// "openssl genpkey -algorithm ed25519". The key is read at startup;
+// changing it requires a restart.
+type SigningConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	KeyFile string `yaml:"key_file"` // Ed25519 private key the answers are signed with
+}
+
+// AdminConfig enables the administrative endpoints under /v1/admin:
+// reloading the configuration, and listing the pipelines and their
+// usage. With Port set they are served only on their own listener,
+// never on the API listener; sharing the API listener requires a
+// TokenFile.
+type AdminConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	ListenAddress string `yaml:"listen_address"` // Separate listener address (default: server.listen_address)
+	Port          int    `yaml:"port"`           // Separate listener port (0: share the API listener)
+	TokenFile     string `yaml:"token_file"`     // Bearer token admin requests must send
+}
+
+// ReplayConfig contains settings for recording queries to replay. When
+// enabled, the server keeps the MaxRequests most recent answered
+// queries in memory with the inputs that decide their answers: the
+// request, the model and its sampling settings, the seed, the documents
+// retrieved and a hash of the prompt. Each is kept under the request's
+// X-Request-ID header, or an ID the server generates and returns in
+// that header, for the admin endpoints to look up and replay.
+type ReplayConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	MaxRequests int  `yaml:"max_requests"` // Queries kept (default: 1000)
+}
+
+// DefaultReplayMaxRequests is how many queries are kept for replay by
+// default.
+const DefaultReplayMaxRequests = 1000
+
+// DefaultConfigWatchDebounce is how long, by default, the
+// configuration files must be left unchanged before a change to them is
+// reloaded.
+const DefaultConfigWatchDebounce = 500 * time.Millisecond
+
+// ConfigWatchConfig controls reloading the configuration when its file,
+// or an API key file it uses, changes, such as when Kubernetes updates
+// a mounted ConfigMap or Secret. A reload is applied only once the new
+// configuration validates and its pipelines start; otherwise the
+// running configuration is kept. SIGHUP reloads the configuration
+// whether or not watching is enabled.
+type ConfigWatchConfig struct {
+	Enabled  bool     `yaml:"enabled"`  // Watch the configuration files (default: true)
+	Debounce Duration `yaml:"debounce"` // Quiet period before a change is reloaded (default: 500ms)
+}
+
+// DefaultGRPCPort is the port the gRPC API listens on by default.
+const DefaultGRPCPort = 50051
+
+// GRPCConfig serves the gRPC API, which answers queries, streamed or
+// not, and lists and health-checks the pipelines, on a listener of its
+// own. It uses the server's TLS certificate when TLS is enabled.
+type GRPCConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	ListenAddress string `yaml:"listen_address"` // Listener address (default: server.listen_address)
+	Port          int    `yaml:"port"`           // Listener port (default: 50051)
+}
+
+// RateLimitConfig bounds the requests, and the LLM tokens, a caller may
+// use per minute; zero leaves either unbounded. Each is a token bucket
+// holding a minute's allowance, which refills continuously, so a caller
+// may spend it in a burst. Tokens are counted once a request finishes,
+// so the request that exhausts the allowance is not cut short; later
+// ones wait until it has refilled.
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
+}
+
+// Enabled reports whether either limit is set.
+func (r RateLimitConfig) Enabled() bool {
+	return r.RequestsPerMinute > 0 || r.TokensPerMinute > 0
+}
+
+// ServerRateLimitConfig bounds each caller's use of all pipelines
+// together. Every client address is limited and, when Key is set, so is
+// every caller it identifies, built from filter variables such as
+// "{{header:X-API-Key}}" or "{{claim:sub}}". The key comes from request
+// headers, which an authenticating proxy should set; limiting the
+// address as well keeps a client from escaping by varying its key.
+type ServerRateLimitConfig struct {
+	RateLimitConfig `yaml:",inline"`
+
+	Key string `yaml:"key"`
+
+	// Keys replace the limits above for particular callers, by the
+	// value of Key. They can only lower them: the caller's address is
+	// still held to the limits above.
+	Keys map[string]RateLimitConfig `yaml:"keys"`
+}
+
+// ProviderLogConfig enables the provider payload log: one JSON line per
+// request to an LLM provider, with its headers and bodies. API keys and
+// other credentials are redacted, and strings longer than
+// MaxStringLength, such as retrieved context, are truncated. It is
+// meant for debugging, not production: prompts and answers are written
+// in full otherwise. It is opened at startup, so changing it requires
+// a restart.
+type ProviderLogConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	Path            string `yaml:"path"`              // File the log is appended to
+	MaxStringLength int    `yaml:"max_string_length"` // Longest string logged in full (default: 2000)
+}
+
+// DefaultProviderLogMaxString is the default max_string_length of the
+// provider payload log.
+const DefaultProviderLogMaxString = 2000
+
+// Request validation modes accepted by server.request_validation. Warn
+// accepts a request with unknown fields, logging them and naming them
+// in Warning response headers; Strict rejects it.
+const (
+	RequestValidationWarn   = "warn"
+	RequestValidationStrict = "strict"
+)
+
+// ExplainConfig enables the retrieval explanation endpoint, which shows
+// operators why a document ranked where it did for a query. It is off
+// by default, and served only among the admin endpoints, since it
+// returns document text.
+type ExplainConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// HTTP2Config controls the HTTP protocols the API listener accepts.
+// HTTP/2 is negotiated over TLS by default; H2C additionally accepts
+// HTTP/2 over plaintext ("prior knowledge" h2c), for internal
+// deployments where TLS is terminated elsewhere but concurrent SSE
+// streams should still share a connection.
+type HTTP2Config struct {
+	Enabled bool `yaml:"enabled"` // Offer HTTP/2 over TLS (default: true)
+	H2C     bool `yaml:"h2c"`     // Accept HTTP/2 over plaintext (default: false)
+}
+
+// StreamResumeConfig contains settings for resuming interrupted
+// streaming responses. When enabled, every SSE event carries an ID and
+// each answer is buffered in memory until Window after it finishes, so
+// a client that reconnects with a Last-Event-ID header receives the
+// events it missed instead of a new answer. Buffers are local to the
+// server instance, so replicas behind a load balancer need sticky
+// routing for resumption to work.
+type StreamResumeConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Window  Duration `yaml:"window"` // How long a finished stream stays resumable (default: 1m)
+}
+
+// MetricsConfig contains settings for the Prometheus metrics endpoint.
+// When Port is zero the endpoint is served on the main API listener;
+// otherwise it gets its own listener so it can be bound to an internal
+// interface (or left unexposed by a load balancer) independently of
+// the API.
+type MetricsConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Path          string `yaml:"path"`           // URL path (default: /metrics)
+	ListenAddress string `yaml:"listen_address"` // Separate listener address (default: server.listen_address)
+	Port          int    `yaml:"port"`           // Separate listener port (0: share the API listener)
+}
+
+// Session store backends.
+const (
+	SessionStoreMemory   = "memory"
+	SessionStorePostgres = "postgres"
+)
+
+// DefaultMaxSessions bounds the sessions the memory session store keeps
+// when sessions.max_sessions is not set.
+const DefaultMaxSessions = 10000
+
+// SessionsConfig contains settings for server-side conversation
+// sessions. The memory store is lost on restart and is not shared
+// between replicas; the postgres store keeps sessions in a table it
+// creates on startup.
+type SessionsConfig struct {
+	Enabled          bool           `yaml:"enabled"`
+	Store            string         `yaml:"store"`              // "memory" (default) or "postgres"
+	TTL              Duration       `yaml:"ttl"`                // Idle time before a session expires (default: 24h)
+	MaxHistoryTokens int            `yaml:"max_history_tokens"` // History sent per query (default: 2000; 0: unlimited)
+	MaxSessions      int            `yaml:"max_sessions"`       // Memory store only: sessions kept, least recently active evicted first (default: 10000; 0: unlimited)
+	Database         DatabaseConfig `yaml:"database"`           // Postgres store only
+	Table            string         `yaml:"table"`              // Postgres store only (default: rag_sessions)
+}
+
+// Job store backends.
+const (
+	JobStoreMemory   = "memory"
+	JobStorePostgres = "postgres"
+)
+
+// Background job defaults, used when the jobs section leaves them unset.
+const (
+	DefaultJobWorkers   = 2
+	DefaultJobQueueSize = 100
+	DefaultJobRetention = Duration(24 * time.Hour)
+)
+
+// JobsConfig contains settings for background jobs, such as ingesting
+// uploaded documents. The memory store is lost on restart; the postgres
+// store keeps job state in a table it creates on startup, so progress
+// can still be looked up after a restart and from any replica.
+type JobsConfig struct {
+	Workers   int            `yaml:"workers"`    // Jobs run at once (default: 2)
+	QueueSize int            `yaml:"queue_size"` // Jobs waiting for a worker before submissions are refused (default: 100)
+	Retention Duration       `yaml:"retention"`  // How long a job is kept after its last update (default: 24h)
+	Store     string         `yaml:"store"`      // "memory" (default) or "postgres"
+	Database  DatabaseConfig `yaml:"database"`   // Postgres store only
+	Table     string         `yaml:"table"`      // Postgres store only (default: rag_jobs)
+}
+
+// Audit log defaults, used when the audit section leaves them unset.
+const (
+	DefaultAuditTable     = "rag_audit"
+	DefaultAuditQueueSize = 1000
+)
+
+// AuditConfig contains settings for the audit log, which records every
+// query the server answers, with its answer, the documents it was
+// answered from, its token usage and latency, and the client that
+// asked it, in a Postgres table the server creates on startup. Entries
+// are written in the background; when more than QueueSize are waiting
+// to be written, new ones are dropped and logged rather than slowing
+// queries down.
+type AuditConfig struct {
+	Enabled   bool           `yaml:"enabled"`
+	Database  DatabaseConfig `yaml:"database"`
+	Table     string         `yaml:"table"`      // Default: rag_audit
+	Retention Duration       `yaml:"retention"`  // How long entries are kept (default: 0, forever)
+	QueueSize int            `yaml:"queue_size"` // Entries waiting to be written (default: 1000)
+}
+
+// ProviderLimitConfig bounds the requests sent to a provider at once,
+// so a burst of queries waits its turn rather than tripping the
+// provider's rate limits. A request over MaxConcurrentRequests waits
+// in a queue of at most MaxQueue requests, for at most QueueTimeout;
+// one that finds the queue full, or times out in it, fails as busy.
+type ProviderLimitConfig struct {
+	MaxConcurrentRequests int      `yaml:"max_concurrent_requests"` // Required, above 0
+	MaxQueue              int      `yaml:"max_queue"`               // Default: 0, no limit
+	QueueTimeout          Duration `yaml:"queue_timeout"`           // Default: 0, the request's own deadline
+}
+
+// ProviderLimit returns the limits configured for provider, whose name
+// is matched regardless of case.
+func (c *Config) ProviderLimit(provider string) (ProviderLimitConfig, bool) {
+	for name, lc := range c.ProviderLimits {
+		if strings.EqualFold(name, provider) {
+			return lc, true
+		}
+	}
+	return ProviderLimitConfig{}, false
+}
+
+// ExperimentConfig is an A/B experiment on a pipeline: each variant,
+// another pipeline, usually one that extends it with a different
+// model, prompt or search settings, answers its traffic percentage of
+// the queries asked of the pipeline, and the pipeline answers the
+// rest. Queries in the same session are answered by the same variant.
+type ExperimentConfig struct {
+	Pipeline string              `yaml:"pipeline"` // Pipeline whose queries are split
+	Variants []ExperimentVariant `yaml:"variants"`
+}
+
+// ExperimentVariant is a pipeline answering part of an experiment's
+// queries.
+type ExperimentVariant struct {
+	Pipeline string  `yaml:"pipeline"`
+	Traffic  float64 `yaml:"traffic"` // Percentage of queries, above 0; the variants' total is at most 100
+}
+
+// IntegrationsConfig contains the integrations that answer questions
+// asked over chat or email with a pipeline. Credentials are read from
+// files, like API keys, so they stay out of the configuration.
+type IntegrationsConfig struct {
+	Slack      SlackConfig      `yaml:"slack"`
+	Mattermost MattermostConfig `yaml:"mattermost"`
+	Email      EmailConfig      `yaml:"email"`
+}
+
+// SlackConfig connects a pipeline to a Slack app. With AppTokenFile set
+// the app receives events over Socket Mode, needing no public URL;
+// otherwise Slack sends them to EventsPath on the API listener, signed
+// with the signing secret.
+type SlackConfig struct {
+	Enabled           bool              `yaml:"enabled"`
+	Pipeline          string            `yaml:"pipeline"`            // Pipeline that answers questions
+	BotTokenFile      string            `yaml:"bot_token_file"`      // Bot token (xoxb-...), for posting answers
+	AppTokenFile      string            `yaml:"app_token_file"`      // App-level token (xapp-...); enables Socket Mode
+	SigningSecretFile string            `yaml:"signing_secret_file"` // Verifies Events API requests
+	EventsPath        string            `yaml:"events_path"`         // Events API request URL path (default: /integrations/slack/events)
+	SourceLinks       SourceLinksConfig `yaml:"source_links"`
+}
+
+// MattermostConfig connects a pipeline to a Mattermost bot account,
+// which receives posts over the server's WebSocket API.
+type MattermostConfig struct {
+	Enabled     bool              `yaml:"enabled"`
+	Pipeline    string            `yaml:"pipeline"`   // Pipeline that answers questions
+	URL         string            `yaml:"url"`        // Mattermost server URL, e.g. https://chat.example.com
+	TokenFile   string            `yaml:"token_file"` // Bot access token
+	SourceLinks SourceLinksConfig `yaml:"source_links"`
+}
+
+// EmailConfig answers questions sent to a mailbox. The mail provider's
+// inbound webhook (SendGrid Inbound Parse) posts each message to
+// InboundPath on the API listener, authenticated with HTTP basic auth,
+// and the answer is sent back to the sender over SMTP.
+type EmailConfig struct {
+	Enabled           bool              `yaml:"enabled"`
+	Pipeline          string            `yaml:"pipeline"`            // Pipeline that answers questions
+	InboundPath       string            `yaml:"inbound_path"`        // Webhook path (default: /integrations/email/inbound)
+	WebhookSecretFile string            `yaml:"webhook_secret_file"` // Basic auth password the webhook sends
+	From              string            `yaml:"from"`                // Address replies are sent from
+	AllowedSenders    []string          `yaml:"allowed_senders"`     // Addresses or @domains answered; empty answers anyone
+	SMTP              SMTPConfig        `yaml:"smtp"`
+	SourceLinks       SourceLinksConfig `yaml:"source_links"`
+}
+
+// SMTPConfig is the mail server replies are sent through. The
+// connection is upgraded with STARTTLS when the server offers it.
+type SMTPConfig struct {
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`          // Default: 587
+	Username     string `yaml:"username"`      // Optional; enables authentication
+	PasswordFile string `yaml:"password_file"` // Required with username
+}
+
+// Usage report schedules.
+const (
+	ReportScheduleDaily  = "daily"
+	ReportScheduleWeekly = "weekly"
+)
+
+// ReportsConfig schedules a report of each pipeline's queries, failure
+// rate, token usage, cost and most frequent queries over the last day
+// or week, delivered to a webhook, by email, or both.
+type ReportsConfig struct {
+	Enabled    bool                `yaml:"enabled"`
+	Schedule   string              `yaml:"schedule"`    // "daily" (default) or "weekly"
+	Time       string              `yaml:"time"`        // Time of day reports are sent, as HH:MM (default: 08:00)
+	Weekday    string              `yaml:"weekday"`     // Day weekly reports are sent (default: monday)
+	Timezone   string              `yaml:"timezone"`    // IANA time zone of time and weekday (default: UTC)
+	Pipelines  []string            `yaml:"pipelines"`   // Pipelines reported; empty reports all
+	TopQueries int                 `yaml:"top_queries"` // Most frequent queries listed per pipeline (default: 10)
+	Webhook    ReportWebhookConfig `yaml:"webhook"`
+	Email      ReportEmailConfig   `yaml:"email"`
+}
+
+// ParseTimeOfDay parses a time of day given as HH:MM, on a 24-hour
+// clock.
+func ParseTimeOfDay(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// ParseWeekday parses the English name of a day of the week, in any
+// case.
+func ParseWeekday(s string) (time.Weekday, bool) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// ReportWebhookConfig posts each report as JSON to a URL. With a
+// secret, the body is signed with HMAC-SHA256 so the receiver can
+// check where it came from.
+type ReportWebhookConfig struct {
+	URL        string `yaml:"url"`
+	SecretFile string `yaml:"secret_file"` // Optional; signs the body in the X-Signature-256 header
+}
+
+// ReportEmailConfig mails each report, as plain text, over SMTP.
+type ReportEmailConfig struct {
+	From string     `yaml:"from"`
+	To   []string   `yaml:"to"`
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SourceLinksConfig names the metadata_columns an integration links an
+// answer's sources with. A source without a URL is listed by title.
+type SourceLinksConfig struct {
+	URLColumn   string `yaml:"url_column"`   // Default: url
+	TitleColumn string `yaml:"title_column"` // Default: title
 }
 
 // CORSConfig contains CORS (Cross-Origin Resource Sharing) settings.
@@ -89,29 +579,425 @@ type TLSConfig struct {
 
 // Defaults contains default values that can be overridden per-pipeline.
 type Defaults struct {
-	TokenBudget  int               `yaml:"token_budget"`
-	TopN         int               `yaml:"top_n"`
-	EmbeddingLLM LLMConfig         `yaml:"embedding_llm"` // Default embedding provider
-	RAGLLM       LLMConfig         `yaml:"rag_llm"`       // Default completion provider
-	APIKeys      APIKeysConfig     `yaml:"api_keys"`      // Default API key paths
-	LLMHeaders   map[string]string `yaml:"llm_headers"`   // Default headers for LLM calls
+	TokenBudget  int                `yaml:"token_budget"`
+	TopN         int                `yaml:"top_n"`
+	EmbeddingLLM LLMConfig          `yaml:"embedding_llm"` // Default embedding provider
+	RAGLLM       LLMConfig          `yaml:"rag_llm"`       // Default completion provider
+	APIKeys      APIKeysConfig      `yaml:"api_keys"`      // Default API key paths
+	LLMHeaders   map[string]string  `yaml:"llm_headers"`   // Default headers for LLM calls
+	ProviderPool ProviderPoolConfig `yaml:"provider_pool"` // Default provider keep-alive pool
+	Formatting   FormattingConfig   `yaml:"formatting"`    // Default answer formatting conventions
+	AnswerLength string             `yaml:"answer_length"` // Default answer length preset
+	Citations    *bool              `yaml:"citations"`     // Default citations mode
 }
 
 // Pipeline defines a single RAG pipeline configuration.
 type Pipeline struct {
-	Name         string            `yaml:"name"`
-	Description  string            `yaml:"description"`
-	Database     DatabaseConfig    `yaml:"database"`
-	Tables       []TableSource     `yaml:"tables"`
-	EmbeddingLLM LLMConfig         `yaml:"embedding_llm"`
-	RAGLLM       LLMConfig         `yaml:"rag_llm"`
-	APIKeys      APIKeysConfig     `yaml:"api_keys"` // Pipeline-specific API key paths
-	TokenBudget  int               `yaml:"token_budget"`
-	TopN         int               `yaml:"top_n"`
-	SystemPrompt string            `yaml:"system_prompt"` // Custom system prompt for LLM
-	Search       SearchConfig      `yaml:"search"`        // Search behavior settings
-	Rerank       RerankConfig      `yaml:"rerank"`        // Optional reranking stage
-	LLMHeaders   map[string]string `yaml:"llm_headers"`   // Pipeline-level headers for LLM calls
+	Name         string             `yaml:"name"`
+	Extends      string             `yaml:"extends"` // Pipeline or template this one is merged with
+	Description  string             `yaml:"description"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Tables       []TableSource      `yaml:"tables"`
+	EmbeddingLLM LLMConfig          `yaml:"embedding_llm"`
+	RAGLLM       LLMConfig          `yaml:"rag_llm"`
+	APIKeys      APIKeysConfig      `yaml:"api_keys"` // Pipeline-specific API key paths
+	TokenBudget  int                `yaml:"token_budget"`
+	TopN         int                `yaml:"top_n"`
+	SystemPrompt string             `yaml:"system_prompt"` // Custom system prompt for LLM
+	Search       SearchConfig       `yaml:"search"`        // Search behavior settings
+	Retrieval    RetrievalConfig    `yaml:"retrieval"`     // How queries are turned into searches
+	BM25         BM25Config         `yaml:"bm25"`          // Lexical ranking parameters
+	Rerank       RerankConfig       `yaml:"rerank"`        // Optional reranking stage
+	LLMHeaders   map[string]string  `yaml:"llm_headers"`   // Pipeline-level headers for LLM calls
+	ProviderPool ProviderPoolConfig `yaml:"provider_pool"` // Keep-alive pool for provider connections
+	Formatting   FormattingConfig   `yaml:"formatting"`    // Regional conventions for answers
+	AnswerLength string             `yaml:"answer_length"` // Answer length preset; see AnswerLengthMaxTokens
+	Citations    *bool              `yaml:"citations"`     // Cite context documents as [n] (default: false)
+
+	// ContextFormat selects how retrieved documents are laid out in the
+	// prompt.
+	ContextFormat ContextFormatConfig `yaml:"context_format"`
+
+	// Tokenizer counts the tokens of the context and history against
+	// their budgets. Empty selects the one rag_llm's model uses.
+	Tokenizer string `yaml:"tokenizer"`
+
+	// MaxHistoryTokens bounds the conversation history a query sends to
+	// the completion provider, estimated as the context is. Older
+	// messages beyond it are dropped or summarized, as HistoryOverflow
+	// selects. Zero sends the whole history.
+	MaxHistoryTokens int    `yaml:"max_history_tokens"`
+	HistoryOverflow  string `yaml:"history_overflow"` // HistoryOverflowDrop (default) or HistoryOverflowSummarize
+
+	// BudgetOverflow selects what happens when the top retrieved
+	// document alone exceeds the token budget. Whichever it is, the
+	// top document is never silently left out of the context.
+	BudgetOverflow string `yaml:"budget_overflow"` // BudgetOverflowTruncate (default), BudgetOverflowSummarize or BudgetOverflowError
+
+	// DropLowestScoring leaves the lowest-scoring retrieved documents
+	// out of the context, whole, until the rest fit in the token budget,
+	// rather than truncating the document the budget runs out in. The
+	// top document is never dropped.
+	DropLowestScoring bool `yaml:"drop_lowest_scoring"`
+
+	// ChunkMerge joins retrieved chunks that are adjacent parts of the
+	// same document into one context document.
+	ChunkMerge ChunkMergeConfig `yaml:"chunk_merge"`
+
+	// AnswerCache reuses the answers to repeated queries.
+	AnswerCache AnswerCacheConfig `yaml:"answer_cache"`
+
+	// EvalCapture samples anonymized queries, with the sources they
+	// retrieved and the feedback they received, into an eval dataset.
+	EvalCapture EvalCaptureConfig `yaml:"eval_capture"`
+
+	// Feedback records ratings of the pipeline's answers in a table of
+	// its database.
+	Feedback FeedbackConfig `yaml:"feedback"`
+
+	// FilterColumns, when set, are the only columns a request's filter
+	// may reference. They are published as an enum in the pipeline's
+	// OpenAPI document.
+	FilterColumns []string `yaml:"filter_columns"`
+
+	// FilterPresets are named filters a request selects with
+	// filter_preset. A preset is combined with the request's own
+	// filter, so documents must match both, and may use columns outside
+	// FilterColumns. Preset names are published as an enum in the
+	// pipeline's OpenAPI document.
+	FilterPresets map[string]Filter `yaml:"filter_presets"`
+
+	// Ingest lets clients upload documents to the pipeline.
+	Ingest IngestConfig `yaml:"ingest"`
+
+	// Tenant limits every search to the rows of the tenant a request
+	// names.
+	Tenant TenantConfig `yaml:"tenant"`
+
+	// RateLimit bounds each caller's use of this pipeline, in addition
+	// to server.rate_limit.
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+
+	// Budget caps what the pipeline's queries may cost per day and per
+	// month, as priced by its models' pricing.
+	Budget BudgetConfig `yaml:"budget"`
+
+	// AccessControl asks an authorization hook which retrieved
+	// documents the caller may see before they reach the prompt.
+	AccessControl AccessControlConfig `yaml:"access_control"`
+
+	// Guardrails check and clean up the pipeline's answers before they
+	// are returned.
+	Guardrails GuardrailsConfig `yaml:"guardrails"`
+
+	// Hooks run the functions of a sandboxed script at fixed points of
+	// each query, to customize it without changing the server.
+	Hooks HooksConfig `yaml:"hooks"`
+
+	// Tools are the built-in tools the completion model may call while
+	// it answers, such as a follow-up search of the pipeline's tables.
+	Tools ToolsConfig `yaml:"tools"`
+
+	// RAGLLMFallbacks are completion providers tried in order when
+	// rag_llm fails or its circuit breaker is open.
+	RAGLLMFallbacks []LLMConfig          `yaml:"rag_llm_fallbacks"`
+	CircuitBreaker  CircuitBreakerConfig `yaml:"circuit_breaker"` // When to stop sending to a failing provider
+
+	// Stage timeouts bound each part of a query, and TotalTimeout the
+	// whole query. Zero leaves a stage bounded only by the server's
+	// request timeout.
+	EmbeddingTimeout  Duration `yaml:"embedding_timeout"`
+	SearchTimeout     Duration `yaml:"search_timeout"`
+	CompletionTimeout Duration `yaml:"completion_timeout"`
+	TotalTimeout      Duration `yaml:"total_timeout"`
+}
+
+// Columns chunk_merge reads a chunk's document and position from when
+// none are configured, as the pgEdge vectorizer names them in its
+// chunks tables.
+const (
+	DefaultChunkDocumentColumn = "source_id"
+	DefaultChunkIndexColumn    = "chunk_index"
+)
+
+// ChunkMergeConfig merges retrieved chunks of the same document with
+// consecutive indices into a single context document, leaving out the
+// text the later chunk repeats from the earlier, to save tokens. Both
+// columns must be among the metadata_columns of each of the pipeline's
+// tables.
+type ChunkMergeConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	DocumentColumn string `yaml:"document_column"` // Identifies a chunk's document (default: DefaultChunkDocumentColumn)
+	IndexColumn    string `yaml:"index_column"`    // A chunk's position in its document (default: DefaultChunkIndexColumn)
+}
+
+// Eval capture defaults, used when eval_capture leaves them unset.
+const (
+	DefaultEvalSampleRate = 0.1
+	DefaultEvalMaxEntries = 1000
+)
+
+// EvalCaptureConfig keeps a sample of a pipeline's queries in memory
+// as an eval dataset, which the admin API exports and evaluates the
+// pipeline's retrieval against. Each captured query is anonymized:
+// email addresses, long numbers and matches of RedactPatterns are
+// replaced before it is kept. Queries with conversation history are
+// never captured.
+type EvalCaptureConfig struct {
+	Enabled        bool     `yaml:"enabled"`
+	SampleRate     float64  `yaml:"sample_rate"`     // Fraction of queries captured (default: 0.1)
+	MaxEntries     int      `yaml:"max_entries"`     // Queries kept, oldest dropped first (default: 1000)
+	RedactPatterns []string `yaml:"redact_patterns"` // Further regular expressions (RE2) of text to remove
+}
+
+// Feedback defaults, used when feedback leaves them unset.
+const (
+	DefaultFeedbackTable        = "rag_feedback"
+	DefaultFeedbackMaxResponses = 10000
+)
+
+// FeedbackConfig gives each of a pipeline's answers a response ID that
+// feedback rates it by, and records each rating, with the anonymized
+// query, the IDs of the documents the answer was written from and the
+// model that wrote it, in Table of the pipeline's database, which is
+// created on startup if it does not exist. What feedback needs of an
+// answer is kept in memory for the MaxResponses most recent answers, so
+// older answers, and answers given before a restart, cannot be rated.
+type FeedbackConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	Table        string `yaml:"table"`         // May be schema-qualified (default: rag_feedback)
+	MaxResponses int    `yaml:"max_responses"` // Answers that can be rated, oldest dropped first (default: 10000)
+}
+
+// Answer cache defaults, used when answer_cache leaves them unset.
+const (
+	DefaultAnswerCacheTTL        = Duration(time.Hour)
+	DefaultAnswerCacheMaxEntries = 1000
+)
+
+// AnswerCacheConfig caches a pipeline's answers in memory, keyed on the
+// query's text, with case, spacing and trailing punctuation normalized,
+// and on the request's filter and other settings that change the
+// answer. Queries with conversation history are never cached. Uploading
+// documents to the pipeline clears its cache; other changes to its
+// tables are only seen once an answer expires.
+type AnswerCacheConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	TTL        Duration `yaml:"ttl"`         // How long an answer is reused (default: 1h)
+	MaxEntries int      `yaml:"max_entries"` // Answers kept, least recently used evicted first (default: 1000)
+}
+
+// TenantConfig isolates the tenants of a pipeline whose tables hold
+// several tenants' documents. Every search of its tables, vector or
+// lexical, is limited to the rows whose Column equals the tenant the
+// request names in Header or, through server.claims_header, in Claim,
+// as is every fetch of their parent tables, and a request that names
+// none is rejected. Setting Column enables it.
+type TenantConfig struct {
+	Column string `yaml:"column"` // Column holding each row's tenant, such as tenant_id
+	Header string `yaml:"header"` // Request header naming the tenant, such as X-Tenant-ID
+	Claim  string `yaml:"claim"`  // Identity claim naming the tenant, such as org_id
+}
+
+// Enabled reports whether the pipeline isolates tenants.
+func (t TenantConfig) Enabled() bool {
+	return t.Column != ""
+}
+
+// Variable returns the filter variable the tenant is read from.
+func (t TenantConfig) Variable() FilterVariable {
+	if t.Claim != "" {
+		return FilterVariable{Source: FilterVarClaim, Name: t.Claim}
+	}
+	return FilterVariable{Source: FilterVarHeader, Name: t.Header}
+}
+
+// TenantTables returns the pipeline's tables with the tenant condition
+// added to each one's filter, and to its parent table's, or the tables
+// as they are when tenants are not isolated. A structured filter
+// becomes a group beside the condition; a raw SQL filter is kept, with
+// the condition as the structured part.
+func (p Pipeline) TenantTables() []TableSource {
+	if !p.Tenant.Enabled() {
+		return p.Tables
+	}
+	condition := FilterCondition{
+		Column:   p.Tenant.Column,
+		Operator: "=",
+		Value:    "{{" + p.Tenant.Variable().String() + "}}",
+	}
+	tables := make([]TableSource, len(p.Tables))
+	for i, t := range p.Tables {
+		filter := &ConfigFilter{Structured: &Filter{Conditions: []FilterCondition{condition}}}
+		if t.Filter != nil {
+			filter.RawSQL = t.Filter.RawSQL
+			if t.Filter.Structured != nil {
+				filter.Structured.Groups = []Filter{*t.Filter.Structured}
+			}
+		}
+		t.Filter = filter
+		if t.Parent.Table != "" {
+			t.Parent.Filter = &ConfigFilter{Structured: &Filter{Conditions: []FilterCondition{condition}}}
+		}
+		tables[i] = t
+	}
+	return tables
+}
+
+// DefaultAccessControlTimeout bounds each authorization call when
+// access_control.timeout is not set.
+const DefaultAccessControlTimeout = 5 * time.Second
+
+// AccessControlConfig enables result-level access control. For each
+// table searched, the IDs of the retrieved documents are passed, with
+// the principal making the request, to either a SQL function or an
+// HTTP callback, and only the documents it returns are kept. Leaving
+// both Function and URL empty (the default) disables the hook. Every
+// table needs an id_column, so documents have stable IDs.
+type AccessControlConfig struct {
+	// Principal identifies the caller, built from filter variables such
+	// as "{{claim:sub}}" or "{{header:X-User}}".
+	Principal string `yaml:"principal"`
+
+	// Function is a SQL function, optionally schema-qualified, called as
+	// function(principal text, table_name text, ids text[]) and
+	// returning the visible IDs as SETOF text.
+	Function string `yaml:"function"`
+
+	// URL receives a JSON POST per table and answers with the visible
+	// IDs. Headers are added to each request.
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+
+	Timeout Duration `yaml:"timeout"` // Bound on each call (default: DefaultAccessControlTimeout)
+}
+
+// Enabled reports whether the pipeline has an authorization hook.
+func (a AccessControlConfig) Enabled() bool {
+	return a.Function != "" || a.URL != ""
+}
+
+// DefaultRedaction replaces the text the guardrails redact from an
+// answer when guardrails.redaction is not set.
+const DefaultRedaction = "[REDACTED]"
+
+// DefaultUngroundedAnswer is returned in place of an answer the
+// groundedness check finds unsupported, when
+// guardrails.groundedness.fallback_message is not set.
+const DefaultUngroundedAnswer = "I could not find an answer supported by the available documents."
+
+// GuardrailsConfig post-processes a pipeline's answers. Text matching a
+// redaction pattern or a denylisted term is replaced, an answer longer
+// than MaxAnswerChars is cut short, and, with the groundedness check
+// enabled, an answer the completion model judges unsupported by the
+// retrieved documents is replaced by a fallback message. The zero value
+// disables them all.
+type GuardrailsConfig struct {
+	RedactPatterns []string `yaml:"redact_patterns"`  // Regular expressions (RE2) of text to redact
+	Denylist       []string `yaml:"denylist"`         // Terms to redact, matched case-insensitively
+	Redaction      string   `yaml:"redaction"`        // Replacement for redacted text (default: DefaultRedaction)
+	MaxAnswerChars int      `yaml:"max_answer_chars"` // Longest answer returned, in characters (0: unlimited)
+
+	Groundedness GroundednessConfig `yaml:"groundedness"`
+}
+
+// GroundednessConfig enables a second completion call that checks an
+// answer against the documents it was written from.
+type GroundednessConfig struct {
+	Enabled         bool   `yaml:"enabled"`
+	FallbackMessage string `yaml:"fallback_message"` // Returned instead of an unsupported answer (default: DefaultUngroundedAnswer)
+}
+
+// Enabled reports whether any guardrail is configured.
+func (g GuardrailsConfig) Enabled() bool {
+	return len(g.RedactPatterns) > 0 || len(g.Denylist) > 0 || g.MaxAnswerChars > 0 ||
+		g.Groundedness.Enabled
+}
+
+// Defaults for a pipeline's script hooks.
+const (
+	DefaultHookTimeout  = time.Second
+	DefaultHookMaxSteps = 1000000
+)
+
+// HooksConfig runs functions of a Starlark script at fixed points of a
+// query. rewrite_query(query) returns the text to search for,
+// filter_results(query, documents) returns the retrieved documents to
+// keep, in order, and transform_answer(query, answer) returns the
+// answer to send; a script defines any of them. Scripts are sandboxed:
+// they cannot read files, reach the network or load other scripts, and
+// each call is bounded by Timeout and MaxSteps. Leaving Script empty
+// (the default) disables the hooks.
+type HooksConfig struct {
+	Script   string   `yaml:"script"`    // Path to the Starlark script
+	Timeout  Duration `yaml:"timeout"`   // Bound on each call (default: DefaultHookTimeout)
+	MaxSteps int      `yaml:"max_steps"` // Bound on the steps each call runs (default: DefaultHookMaxSteps)
+}
+
+// ScriptPath returns the path of the hook script, with a leading ~
+// expanded to the user's home directory.
+func (h HooksConfig) ScriptPath() string {
+	return ExpandPath(h.Script)
+}
+
+// DefaultMaxUploadBytes is the largest document upload a pipeline
+// accepts when ingest.max_upload_bytes is not set.
+const DefaultMaxUploadBytes = 32 << 20
+
+// IngestConfig enables uploading documents to a pipeline. Uploaded
+// PDF, HTML and Markdown files have their text extracted, are split
+// into chunks and embedded with the pipeline's embedding LLM, and each
+// chunk is inserted into Table as a new row. The table's id column must
+// have a default, such as an identity column.
+type IngestConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Table          string `yaml:"table"`            // One of the pipeline's tables (default: the first)
+	ChunkTokens    int    `yaml:"chunk_tokens"`     // Estimated tokens per chunk (default: 400)
+	ChunkOverlap   int    `yaml:"chunk_overlap"`    // Estimated tokens repeated from the previous chunk (default: 0)
+	FilenameColumn string `yaml:"filename_column"`  // Column receiving the uploaded file's name (optional)
+	PageColumn     string `yaml:"page_column"`      // Column receiving a PDF chunk's page number (optional)
+	MaxUploadBytes int64  `yaml:"max_upload_bytes"` // Largest accepted upload (default: DefaultMaxUploadBytes)
+}
+
+// IngestTable returns the table ingested documents are stored in, and
+// false if ingest.table names none of the pipeline's tables.
+func (p Pipeline) IngestTable() (TableSource, bool) {
+	if p.Ingest.Table == "" {
+		if len(p.Tables) == 0 {
+			return TableSource{}, false
+		}
+		return p.Tables[0], true
+	}
+	for _, t := range p.Tables {
+		if t.Table == p.Ingest.Table {
+			return t, true
+		}
+	}
+	return TableSource{}, false
+}
+
+// CircuitBreakerConfig controls the circuit breaker in front of each
+// completion provider of a pipeline with rag_llm_fallbacks. After
+// FailureThreshold consecutive failures the breaker opens and requests
+// skip the provider; once Cooldown has passed, a single request probes
+// it, closing the breaker if it succeeds.
+type CircuitBreakerConfig struct {
+	FailureThreshold int      `yaml:"failure_threshold"` // Consecutive failures that open the breaker (default: 5)
+	Cooldown         Duration `yaml:"cooldown"`          // How long the breaker stays open (default: 30s)
+}
+
+// ProviderPoolConfig tunes the keep-alive connections a pipeline holds
+// open to its LLM providers. Reusing an idle connection skips the TCP
+// and TLS handshakes that would otherwise precede every provider call.
+type ProviderPoolConfig struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept per
+	// provider host; Go's default of 2 forces concurrent queries to
+	// dial fresh connections. Zero inherits the default (16).
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+
+	// IdleConnTimeout is how long an idle connection is kept before it
+	// is closed. Zero inherits the default (90s).
+	IdleConnTimeout Duration `yaml:"idle_conn_timeout"`
 }
 
 // HostEntry represents a single host in a multi-host database configuration.
@@ -122,6 +1008,12 @@ type HostEntry struct {
 
 // DatabaseConfig contains PostgreSQL connection settings.
 type DatabaseConfig struct {
+	// URL is a connection URL (postgres://user@host:port/database?...)
+	// or keyword/value connection string used instead of the
+	// connection fields below. Only a password may be set alongside
+	// it, to keep it out of the URL.
+	URL string `yaml:"url"`
+
 	// Single-host connection fields
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
@@ -135,12 +1027,38 @@ type DatabaseConfig struct {
 	Password string `yaml:"password"`
 	SSLMode  string `yaml:"ssl_mode"`
 
+	// PasswordEnv names an environment variable, and PasswordFile a
+	// file, holding the password, so it need not be written in the
+	// configuration. At most one of Password, PasswordEnv and
+	// PasswordFile may be set.
+	PasswordEnv  string `yaml:"password_env"`
+	PasswordFile string `yaml:"password_file"`
+
 	// Certificate-based authentication
 	SSLCert   string `yaml:"ssl_cert"`
 	SSLKey    string `yaml:"ssl_key"`
 	SSLRootCA string `yaml:"ssl_root_ca"`
 }
 
+// ResolvePassword returns the database password: Password, the value
+// of the PasswordEnv environment variable, or the contents of
+// PasswordFile, read as ReadSecretFile reads it, so an empty file is an
+// error. The file is read on each call, so a rotated password is used
+// by new connection pools. It returns "" when none is set.
+func (d DatabaseConfig) ResolvePassword() (string, error) {
+	switch {
+	case d.PasswordEnv != "":
+		password, ok := os.LookupEnv(d.PasswordEnv)
+		if !ok {
+			return "", fmt.Errorf("database password environment variable %s is not set", d.PasswordEnv)
+		}
+		return password, nil
+	case d.PasswordFile != "":
+		return ReadSecretFile(d.PasswordFile, "database password")
+	}
+	return d.Password, nil
+}
+
 // TableSource defines a table with text and vector columns for hybrid search.
 type TableSource struct {
 	Table        string        `yaml:"table"`
@@ -148,35 +1066,459 @@ type TableSource struct {
 	VectorColumn string        `yaml:"vector_column"`
 	IDColumn     string        `yaml:"id_column"` // Optional ID column (required for views)
 	Filter       *ConfigFilter `yaml:"filter"`    // Optional filter (raw SQL or structured)
+
+	// MetadataColumns are returned with each source, keyed by column
+	// name, so clients can link back to the original document.
+	MetadataColumns []string `yaml:"metadata_columns"`
+
+	// LexicalColumns are additional text columns, such as a title,
+	// indexed for BM25 alongside TextColumn. They do not affect
+	// vector search.
+	LexicalColumns []LexicalColumn `yaml:"lexical_columns"`
+
+	// LexicalSearch selects how the lexical arm of hybrid search ranks
+	// the table: LexicalSearchBM25 (the default) or
+	// LexicalSearchPostgresFTS.
+	LexicalSearch string `yaml:"lexical_search"`
+
+	// TSVectorColumn is a tsvector column ranked by postgres_fts. When
+	// empty, TextColumn is converted with to_tsvector at query time.
+	TSVectorColumn string `yaml:"tsvector_column"`
+
+	// TextSearchConfig is the PostgreSQL text search configuration
+	// postgres_fts parses queries, and TextColumn, with (default
+	// "english").
+	TextSearchConfig string `yaml:"text_search_config"`
+
+	// Rescore makes vector search two-stage when VectorColumn is
+	// quantized: candidates are found with it, then re-scored and
+	// ordered with a full-precision column.
+	Rescore RescoreConfig `yaml:"rescore"`
+
+	// Distance is the metric vector search compares VectorColumn with
+	// the query by: DistanceCosine (the default), DistanceL2 or
+	// DistanceInnerProduct. It should match the operator class of the
+	// column's index.
+	Distance string `yaml:"distance"`
+
+	// VectorType is the pgvector type of VectorColumn:
+	// VectorTypeVector (the default), VectorTypeHalfvec or
+	// VectorTypeSparsevec. The query embedding is cast to it, so an
+	// index on the column can be used.
+	VectorType string `yaml:"vector_type"`
+
+	// VectorSearch tunes the approximate nearest neighbour index
+	// VectorColumn's search uses, trading recall for latency.
+	VectorSearch VectorSearchOptions `yaml:"vector_search"`
+
+	// Parent builds the context from the documents the table's rows
+	// are chunks of, or from the chunks around each match, rather than
+	// from the matching chunks alone.
+	Parent ParentConfig `yaml:"parent"`
+}
+
+// Quantizations accepted by rescore.quantization: a halfvec column, or
+// a bit column of binary_quantize'd embeddings searched by Hamming
+// distance.
+const (
+	QuantizationHalfvec = "halfvec"
+	QuantizationBinary  = "binary"
+)
+
+// DefaultRescoreFactor is how many times top_n candidates the quantized
+// search passes on to be re-scored when rescore.candidates is not set.
+const DefaultRescoreFactor = 4
+
+// RescoreConfig configures two-stage vector search of a table whose
+// vector_column is quantized. Setting Column enables it.
+type RescoreConfig struct {
+	Column       string `yaml:"column"`       // Full-precision vector column the candidates are scored with
+	Quantization string `yaml:"quantization"` // QuantizationHalfvec or QuantizationBinary
+	Candidates   int    `yaml:"candidates"`   // Rows the quantized search passes on (default: DefaultRescoreFactor × top_n)
+}
+
+// Enabled reports whether the table is searched in two stages.
+func (r RescoreConfig) Enabled() bool {
+	return r.Column != ""
+}
+
+// Distance metrics accepted by distance, each searched with its
+// pgvector operator: <=> for cosine distance, <-> for Euclidean (L2)
+// distance and <#> for negative inner product.
+const (
+	DistanceCosine       = "cosine"
+	DistanceL2           = "l2"
+	DistanceInnerProduct = "inner_product"
+)
+
+// Vector column types accepted by vector_type.
+const (
+	VectorTypeVector    = "vector"
+	VectorTypeHalfvec   = "halfvec"
+	VectorTypeSparsevec = "sparsevec"
+)
+
+// Upper bounds of the pgvector settings VectorSearchOptions sets.
+const (
+	MaxIVFFlatProbes = 32768
+	MaxHNSWEfSearch  = 1000
+)
+
+// VectorSearchOptions are the pgvector settings vector search of a
+// table runs with, set with SET LOCAL in the search's transaction so
+// they apply to nothing else. Zero leaves a setting at the server's
+// value.
+type VectorSearchOptions struct {
+	Probes   int `yaml:"probes"`    // ivfflat.probes: lists an IVFFlat index scan visits
+	EfSearch int `yaml:"ef_search"` // hnsw.ef_search: candidate list size of an HNSW index scan
+}
+
+// DefaultParentIDColumn is the ID column of a parent table when
+// parent.id_column is not set.
+const DefaultParentIDColumn = "id"
+
+// ParentConfig expands the chunks a table's search matches into larger
+// context. With Table set, each match is replaced by the row of Table
+// whose IDColumn equals the chunk's ParentIDColumn, and matches of the
+// same parent become one result. Otherwise, each match is widened to
+// the Siblings chunks either side of it with the same parent, ordered
+// by IndexColumn. ParentIDColumn, and IndexColumn for siblings, must be
+// among the table's metadata_columns. Setting ParentIDColumn enables
+// it.
+type ParentConfig struct {
+	ParentIDColumn string `yaml:"parent_id_column"` // Column of the chunk holding its parent's ID
+	Table          string `yaml:"table"`            // Table of parent documents
+	IDColumn       string `yaml:"id_column"`        // The parent table's ID column (default: DefaultParentIDColumn)
+	TextColumn     string `yaml:"text_column"`      // The parent table's text column
+	Siblings       int    `yaml:"siblings"`         // Chunks either side of a match, when Table is not set
+	IndexColumn    string `yaml:"index_column"`     // A chunk's position in its parent (default: DefaultChunkIndexColumn)
+
+	// Filter limits the parent rows fetched; TenantTables sets it to
+	// the tenant condition.
+	Filter *ConfigFilter `yaml:"-"`
 }
 
+// Enabled reports whether matches are expanded.
+func (p ParentConfig) Enabled() bool {
+	return p.ParentIDColumn != ""
+}
+
+// Lexical search backends accepted by lexical_search. BM25 fetches the
+// table's documents and ranks them in memory; PostgresFTS ranks them in
+// the database with ts_rank, so large tables never leave it.
+const (
+	LexicalSearchBM25        = "bm25"
+	LexicalSearchPostgresFTS = "postgres_fts"
+)
+
+// LexicalColumn is an additional text column indexed for BM25. Boost
+// weights its matches relative to TextColumn's; zero means 1.
+type LexicalColumn struct {
+	Column string  `yaml:"column"`
+	Boost  float64 `yaml:"boost"`
+}
+
+// MaxLexicalBoost bounds LexicalColumn.Boost.
+const MaxLexicalBoost = 10.0
+
 // SearchConfig contains settings for search behavior.
 type SearchConfig struct {
 	HybridEnabled *bool    `yaml:"hybrid_enabled"` // Enable hybrid search (default: true)
 	VectorWeight  *float64 `yaml:"vector_weight"`  // Weight for vector vs BM25 (default: 0.5)
-	MinSimilarity *float64 `yaml:"min_similarity"` // Minimum cosine similarity threshold (0.0-1.0)
+	LexicalWeight *float64 `yaml:"lexical_weight"` // Weight for BM25 (default: 1 - vector_weight)
+	Fusion        string   `yaml:"fusion"`         // "rrf" (default) or "score_fusion"
+	RRFK          *int     `yaml:"rrf_k"`          // RRF constant k (default: 60)
+	MinSimilarity *float64 `yaml:"min_similarity"` // Minimum vector similarity score (0.0-1.0)
+}
+
+// Methods of combining vector and lexical results accepted by
+// search.fusion. Reciprocal rank fusion combines the results' ranks;
+// score fusion normalizes their scores and combines those.
+const (
+	FusionRRF   = "rrf"
+	FusionScore = "score_fusion"
+)
+
+// DefaultRRFK is the default search.rrf_k. Larger values flatten the
+// difference between adjacent ranks.
+const DefaultRRFK = 60
+
+// VectorShare returns the share of the vector ranking in hybrid search,
+// 0-1, with the lexical ranking getting the rest. With lexical_weight
+// set, the two weights are relative; otherwise the lexical weight is
+// 1 - vector_weight.
+func (s SearchConfig) VectorShare() float64 {
+	vector := 0.5
+	if s.VectorWeight != nil {
+		vector = *s.VectorWeight
+	}
+	if s.LexicalWeight == nil {
+		return vector
+	}
+	if total := vector + *s.LexicalWeight; total > 0 {
+		return vector / total
+	}
+	return 0.5
+}
+
+// Retrieval strategies accepted by retrieval.strategy.
+const (
+	RetrievalStrategySingle     = "single"
+	RetrievalStrategyMultiQuery = "multi_query"
+	RetrievalStrategyHyDE       = "hyde"
+	RetrievalStrategyIterative  = "iterative"
+)
+
+// MaxNumQueries bounds retrieval.num_queries. Each paraphrase costs an
+// embedding call and a search, so large values slow every query.
+const MaxNumQueries = 10
+
+// MaxIterations bounds retrieval.max_iterations. Each iteration after
+// the first costs a completion call, an embedding call and a search.
+const MaxIterations = 5
+
+// RetrievalConfig selects how a query is searched. The single strategy
+// (the default) searches the query as written; multi_query has the
+// completion LLM paraphrase it NumQueries times, searches every
+// variant, and fuses the results with reciprocal rank fusion; hyde has
+// the completion LLM draft a hypothetical answer, and searches for
+// documents similar to the draft's embedding, blended with the query's
+// by HyDEWeight; iterative shows the completion LLM what was found and,
+// unless it judges that enough to answer, searches the query it writes
+// for what is missing, up to MaxIterations searches in all, fusing the
+// results as multi_query does. Keyword search always uses the query as
+// written.
+type RetrievalConfig struct {
+	Strategy   string   `yaml:"strategy"`    // "single" (default), "multi_query", "hyde" or "iterative"
+	NumQueries int      `yaml:"num_queries"` // Paraphrases for multi_query, 1 to MaxNumQueries (default: 3)
+	HyDEWeight *float64 `yaml:"hyde_weight"` // Weight of the draft for hyde, 0 to 1 (default: 1, the draft alone)
+
+	// MaxIterations bounds the searches of the iterative strategy,
+	// including the first, 1 to config.MaxIterations (default: 3), and
+	// MaxIterationTokens the tokens its checks of the results may use
+	// per query. A check that would exceed them is not made. Zero
+	// MaxIterationTokens leaves the checks unbounded.
+	MaxIterations      int `yaml:"max_iterations"`
+	MaxIterationTokens int `yaml:"max_iteration_tokens"`
+}
+
+// Built-in tools accepted by tools.builtin.
+const (
+	// ToolSearch searches the pipeline's tables again, for a query the
+	// model writes, and adds what it finds to the context.
+	ToolSearch = "search"
+)
+
+// MaxToolRounds bounds tools.max_rounds. Each round is another
+// completion call, so large values slow every query that uses tools.
+const MaxToolRounds = 5
+
+// ToolsConfig lets the completion model call the named built-in tools
+// while it answers. After each round of calls the model is asked again
+// with their results; after MaxRounds rounds it must answer with what
+// it has. Tools are not supported by the bedrock provider.
+type ToolsConfig struct {
+	Builtin   []string `yaml:"builtin"`    // Tools the model may call, e.g. ToolSearch
+	MaxRounds int      `yaml:"max_rounds"` // Rounds of tool calls per query, 1 to MaxToolRounds (default: 2)
+}
+
+// MaxBM25K1 bounds bm25.k1. Useful values lie between 0.5 and 2; far
+// larger ones make BM25 rank by raw term counts.
+const MaxBM25K1 = 3.0
+
+// MaxProximityWeight bounds bm25.proximity_weight.
+const MaxProximityWeight = 5.0
+
+// Default limits on how much of a table the BM25 arm of hybrid search
+// loads into memory to rank it.
+const (
+	DefaultBM25MaxRows  = 100000
+	DefaultBM25MaxBytes = 256 << 20
+)
+
+// BM25Config tunes the BM25 ranking used by the lexical arm of hybrid
+// search. Nil fields use the standard values (k1 1.2, b 0.75,
+// proximity_weight 0.5, fuzzy_similarity 0.4).
+type BM25Config struct {
+	K1              *float64 `yaml:"k1"`               // Term frequency saturation, 0 to MaxBM25K1; 0 ignores repeats
+	B               *float64 `yaml:"b"`                // Document length normalization, 0 (none) to 1 (full)
+	ProximityWeight *float64 `yaml:"proximity_weight"` // Bonus for query terms close together, 0 (off) to MaxProximityWeight
+	Fuzzy           bool     `yaml:"fuzzy"`            // Correct misspelled query terms when nothing matches
+	FuzzySimilarity *float64 `yaml:"fuzzy_similarity"` // Minimum trigram similarity for a correction, above 0 to 1
+
+	// MaxRows and MaxBytes bound the rows, and the bytes of text, a
+	// table's search loads to rank; a table over either is not
+	// searched by keyword. Zero uses DefaultBM25MaxRows and
+	// DefaultBM25MaxBytes.
+	MaxRows  int   `yaml:"max_rows"`
+	MaxBytes int64 `yaml:"max_bytes"`
+
+	// Cache keeps the documents fetched to rank a table, so searches
+	// reuse them rather than loading the table each time.
+	Cache DocumentCacheConfig `yaml:"cache"`
+}
+
+// Document cache defaults, used when bm25.cache leaves them unset.
+const (
+	DefaultDocumentCacheTTL        = Duration(time.Minute)
+	DefaultDocumentCacheMaxStale   = Duration(10 * time.Minute)
+	DefaultDocumentCacheMaxEntries = 100
+)
+
+// DocumentCacheConfig caches the documents BM25 ranks, per table, filter
+// and caller, in memory. Documents younger than TTL are reused as they
+// are. Older ones are still served, up to MaxStale past TTL, while they
+// are fetched again in the background, so searches do not wait on the
+// table being loaded; documents older than that are fetched before the
+// search. Uploading documents to the pipeline clears the cache.
+type DocumentCacheConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	TTL        Duration `yaml:"ttl"`         // How long documents are reused before being refreshed (default: 1m)
+	MaxStale   Duration `yaml:"max_stale"`   // How long past ttl documents are served while refreshed (default: 10m)
+	MaxEntries int      `yaml:"max_entries"` // Document sets kept, least recently used evicted first (default: 100)
+}
+
+// Limits returns the most rows, and bytes of text, a table's search
+// loads to rank, with defaults applied.
+func (b BM25Config) Limits() (maxRows int, maxBytes int64) {
+	maxRows, maxBytes = b.MaxRows, b.MaxBytes
+	if maxRows == 0 {
+		maxRows = DefaultBM25MaxRows
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultBM25MaxBytes
+	}
+	return maxRows, maxBytes
 }
 
 // RerankConfig contains settings for an optional reranking stage that
 // reorders search results by relevance to the query immediately before
 // context building. Leaving Provider empty (the default) disables the
 // stage entirely. Only providers whose llm.Client.Rerank is actually
-// implemented may be configured here (currently Voyage).
+// implemented may be configured here (currently Voyage and Cohere).
 type RerankConfig struct {
 	Provider string            `yaml:"provider"`
 	Model    string            `yaml:"model"`
 	BaseURL  string            `yaml:"base_url"` // Optional custom base URL
 	Headers  map[string]string `yaml:"headers"`  // Per-rerank-call custom headers
+	CAFile   string            `yaml:"ca_file"`  // Extra trusted CA certificates (PEM)
 
-	// RequestTimeout / PerAttemptTimeout behave as documented on
-	// LLMConfig's fields of the same name.
-	RequestTimeout    Duration `yaml:"request_timeout"`
-	PerAttemptTimeout Duration `yaml:"per_attempt_timeout"`
+	// RequestTimeout / PerAttemptTimeout / Retry behave as documented
+	// on LLMConfig's fields of the same name.
+	RequestTimeout    Duration    `yaml:"request_timeout"`
+	PerAttemptTimeout Duration    `yaml:"per_attempt_timeout"`
+	Retry             RetryConfig `yaml:"retry"`
 
 	// TopK, when > 0, keeps only the top-K reranked results and
 	// discards the rest before context building. Zero (the default)
 	// reorders all retrieved results without dropping any.
 	TopK int `yaml:"top_k"`
+
+	// Pricing is what the provider charges for the model; only
+	// InputPerMillion applies to reranking.
+	Pricing PricingConfig `yaml:"pricing"`
+}
+
+// CAPath returns the path of the CA bundle, with a leading ~ expanded
+// to the user's home directory.
+func (r RerankConfig) CAPath() string {
+	return ExpandPath(r.CAFile)
+}
+
+// Answer length presets accepted by answer_length.
+const (
+	AnswerLengthShort  = "short"
+	AnswerLengthMedium = "medium"
+	AnswerLengthLong   = "long"
+)
+
+// AnswerLengthMaxTokens maps each answer length preset to the
+// completion token limit sent to the provider. The same limits apply
+// to every provider, so a preset means the same thing whichever model
+// a pipeline uses.
+var AnswerLengthMaxTokens = map[string]int{
+	AnswerLengthShort:  256,
+	AnswerLengthMedium: 1024,
+	AnswerLengthLong:   4096,
+}
+
+// History overflow modes accepted by history_overflow. Drop discards
+// the messages beyond max_history_tokens; Summarize asks the completion
+// provider to summarize them, and adds the summary to the system prompt.
+const (
+	HistoryOverflowDrop      = "drop"
+	HistoryOverflowSummarize = "summarize"
+)
+
+// Budget overflow modes accepted by budget_overflow. Truncate includes
+// as much of the top document as fits in the token budget; Summarize
+// asks the completion provider to summarize it to fit, truncating it
+// if that fails; Error rejects the query.
+const (
+	BudgetOverflowTruncate  = "always_include_first_truncated"
+	BudgetOverflowSummarize = "summarize_first"
+	BudgetOverflowError     = "error"
+)
+
+// Values accepted by FormattingConfig.
+const (
+	DateFormatISO = "iso" // 2025-12-31
+	DateFormatDMY = "dmy" // 31.12.2025, 31/12/2025
+	DateFormatMDY = "mdy" // 12/31/2025
+
+	UnitsMetric   = "metric"
+	UnitsImperial = "imperial"
+)
+
+// FormattingConfig describes the regional conventions a pipeline's
+// answers should follow. The settings are added to the system prompt,
+// and answers are checked against the date, number and unit settings
+// afterwards. Empty fields impose no convention.
+type FormattingConfig struct {
+	Locale           string `yaml:"locale"`            // BCP 47 tag, e.g. "de-DE"; a hint for the model
+	DateFormat       string `yaml:"date_format"`       // DateFormatISO, DateFormatDMY or DateFormatMDY
+	DecimalSeparator string `yaml:"decimal_separator"` // "." or ","
+	Units            string `yaml:"units"`             // UnitsMetric or UnitsImperial
+}
+
+// IsZero reports whether no formatting convention is configured.
+func (f FormattingConfig) IsZero() bool {
+	return f == FormattingConfig{}
+}
+
+// Context styles accepted by context_format.style. Text gives each
+// document a heading line; XML wraps each in a <document> tag, as
+// Anthropic recommends for Claude; JSON lists them as an array of
+// objects.
+const (
+	ContextStyleText = "text"
+	ContextStyleXML  = "xml"
+	ContextStyleJSON = "json"
+)
+
+// Tokenizers accepted by a pipeline's tokenizer. Anthropic does not
+// publish Claude's tokenizer, so TokenizerAnthropic approximates it;
+// TokenizerEstimate counts a token per four bytes of text.
+const (
+	TokenizerO200kBase  = "o200k_base"
+	TokenizerCl100kBase = "cl100k_base"
+	TokenizerAnthropic  = "anthropic"
+	TokenizerEstimate   = "estimate"
+)
+
+// DefaultContextHeading is the heading of each document in the text
+// context style. {n} is replaced by the document number, followed by
+// the document's source and score in parentheses when they are shown.
+const DefaultContextHeading = "--- Document {n} ---"
+
+// ContextFormatConfig describes how a pipeline lays out retrieved
+// documents in the prompt. The zero value is the text style with the
+// default heading, without sources or scores.
+type ContextFormatConfig struct {
+	Style         string `yaml:"style"`          // ContextStyleText (default), ContextStyleXML or ContextStyleJSON
+	Heading       string `yaml:"heading"`        // Text style document heading (default: DefaultContextHeading)
+	SourceColumn  string `yaml:"source_column"`  // Metadata column shown as each document's source
+	IncludeScores bool   `yaml:"include_scores"` // Show each document's relevance score
 }
 
 // FilterCondition represents a single filter condition.
@@ -190,11 +1532,13 @@ type FilterCondition struct {
 // Used for API request filters which must be parameterized for security.
 type Filter struct {
 	Conditions []FilterCondition `json:"conditions" yaml:"conditions"`
-	Logic      string            `json:"logic,omitempty" yaml:"logic,omitempty"` // "AND" or "OR", default "AND"
+	Groups     []Filter          `json:"groups,omitempty" yaml:"groups,omitempty"` // Nested filters, each in parentheses
+	Logic      string            `json:"logic,omitempty" yaml:"logic,omitempty"`   // "AND" or "OR", default "AND"
 }
 
 // ConfigFilter represents a filter in pipeline configuration.
 // It can be either a raw SQL string (for admin use) or a structured Filter.
+// When both are set, as for a tenant's tables, rows must match both.
 type ConfigFilter struct {
 	RawSQL     string  // Raw SQL WHERE clause fragment (admin-only)
 	Structured *Filter // Structured filter with conditions
@@ -220,6 +1564,23 @@ func (cf *ConfigFilter) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return fmt.Errorf("filter must be a string or structured filter object")
 }
 
+// MarshalYAML renders a ConfigFilter in whichever form it was written.
+func (cf ConfigFilter) MarshalYAML() (interface{}, error) {
+	if cf.Structured != nil {
+		return cf.Structured, nil
+	}
+	return cf.RawSQL, nil
+}
+
+// Default request timeouts of a provider's embedding and completion
+// requests. Embedding a query takes well under a second, so one that
+// takes longer than DefaultEmbeddingRequestTimeout has hung;
+// DefaultCompletionRequestTimeout matches pgedge-go-llm-lib's default.
+const (
+	DefaultEmbeddingRequestTimeout  = Duration(30 * time.Second)
+	DefaultCompletionRequestTimeout = Duration(120 * time.Second)
+)
+
 // LLMConfig contains settings for an LLM provider.
 type LLMConfig struct {
 	Provider string            `yaml:"provider"`
@@ -227,17 +1588,131 @@ type LLMConfig struct {
 	BaseURL  string            `yaml:"base_url"` // Optional custom base URL (e.g. for API gateways)
 	Headers  map[string]string `yaml:"headers"`  // Per-LLM custom headers
 
+	// CAFile is a PEM bundle of CA certificates trusted, in addition to
+	// the system roots, for connections to the provider, so traffic can
+	// go through a gateway or egress proxy with a private certificate.
+	CAFile string `yaml:"ca_file"`
+
+	// Region is the AWS region of the bedrock provider. Empty falls
+	// back to the AWS_REGION and AWS_DEFAULT_REGION environment
+	// variables.
+	Region string `yaml:"region"`
+
 	// RequestTimeout caps the wall-clock time of a single request to
-	// this provider, spanning every retry. Zero uses the library
-	// default (120s). Specified as a duration string, e.g. "120s".
+	// this provider, spanning every retry. Zero uses the default of the
+	// request's kind (see RequestTimeouts). Specified as a duration
+	// string, e.g. "120s".
 	RequestTimeout Duration `yaml:"request_timeout"`
 
+	// EmbeddingRequestTimeout and CompletionRequestTimeout override
+	// RequestTimeout for embedding and for completion requests,
+	// streamed or not, so hung embedding calls fail fast while answers
+	// are given long enough to generate.
+	EmbeddingRequestTimeout  Duration `yaml:"embedding_request_timeout"`
+	CompletionRequestTimeout Duration `yaml:"completion_request_timeout"`
+
 	// PerAttemptTimeout, when greater than zero, bounds each individual
-	// HTTP attempt so a single slow upstream (e.g. a heavy embedding
-	// batch) is retried rather than burning the whole RequestTimeout
-	// budget in one go. Set it below RequestTimeout to leave room for
-	// retries. Zero disables per-attempt timeouts.
+	// HTTP attempt. An attempt that times out before its request is
+	// sent, e.g. while connecting, is retried, as is a timed-out
+	// embedding attempt; a completion attempt that times out waiting
+	// for the response fails, since the provider may already be
+	// processing it. Zero disables per-attempt timeouts.
 	PerAttemptTimeout Duration `yaml:"per_attempt_timeout"`
+
+	// Retry controls how requests that fail transiently are retried.
+	Retry RetryConfig `yaml:"retry"`
+
+	// StopSequences end generation as soon as the model emits any of
+	// them. Only meaningful for rag_llm; at most MaxStopSequences.
+	StopSequences []string `yaml:"stop_sequences"`
+
+	// LogitBias maps OpenAI token IDs to a bias between -MaxLogitBias
+	// and MaxLogitBias; -100 effectively bans a token. Only supported
+	// by the openai rag_llm provider.
+	LogitBias map[string]int `yaml:"logit_bias"`
+
+	// Pricing is what the provider charges for the model, used to
+	// project the cost of a query and to track what queries cost
+	// against the pipeline's budget. Unset prices are not counted.
+	Pricing PricingConfig `yaml:"pricing"`
+}
+
+// PricingConfig holds a model's prices per million tokens, in whatever
+// currency the operator bills in.
+type PricingConfig struct {
+	InputPerMillion  float64 `yaml:"input_per_million"`  // Price of a million prompt tokens
+	OutputPerMillion float64 `yaml:"output_per_million"` // Price of a million completion tokens
+}
+
+// Priced reports whether either price is set.
+func (p PricingConfig) Priced() bool {
+	return p.InputPerMillion > 0 || p.OutputPerMillion > 0
+}
+
+// BudgetConfig caps the estimated cost of a pipeline's queries per
+// calendar day and per calendar month, in UTC, in the currency of its
+// models' pricing; zero leaves either uncapped. Once a cap is reached,
+// queries are refused until the period ends.
+type BudgetConfig struct {
+	Daily   float64 `yaml:"daily"`
+	Monthly float64 `yaml:"monthly"`
+}
+
+// Enabled reports whether either cap is set.
+func (b BudgetConfig) Enabled() bool {
+	return b.Daily > 0 || b.Monthly > 0
+}
+
+// CAPath returns the path of the CA bundle, with a leading ~ expanded
+// to the user's home directory.
+func (l LLMConfig) CAPath() string {
+	return ExpandPath(l.CAFile)
+}
+
+// RequestTimeouts returns the timeouts of the provider's embedding and
+// completion requests: the kind's own timeout, else RequestTimeout,
+// else the kind's default.
+func (l LLMConfig) RequestTimeouts() (embedding, completion time.Duration) {
+	embedding, completion = l.EmbeddingRequestTimeout.Std(), l.CompletionRequestTimeout.Std()
+	if embedding == 0 {
+		embedding = l.RequestTimeout.Std()
+	}
+	if embedding == 0 {
+		embedding = DefaultEmbeddingRequestTimeout.Std()
+	}
+	if completion == 0 {
+		completion = l.RequestTimeout.Std()
+	}
+	if completion == 0 {
+		completion = DefaultCompletionRequestTimeout.Std()
+	}
+	return embedding, completion
+}
+
+// Limits on the generation controls in LLMConfig. They match the
+// strictest provider (OpenAI) so a config stays portable.
+const (
+	MaxStopSequences = 4
+	MaxLogitBias     = 100
+)
+
+// MaxRetries bounds retry.max_retries.
+const MaxRetries = 10
+
+// RetryConfig controls how provider requests are retried when they are
+// rate limited (429), the provider is overloaded (503 or 529), or they
+// fail before being sent, e.g. on a refused connection. Embedding
+// requests, which are safe to repeat, are also retried after network
+// errors, timed-out attempts and server errors (500, 502 or 504);
+// other requests that may have reached the provider are not. Waits
+// double from InitialBackoff up to MaxBackoff, except that a
+// Retry-After header sets the wait for that retry. Unset fields use
+// the defaults: 5 retries, 2s, 60s and 0.2 jitter.
+type RetryConfig struct {
+	MaxRetries     *int     `yaml:"max_retries"`     // Retries after the first attempt, 0 to MaxRetries; 0 disables
+	InitialBackoff Duration `yaml:"initial_backoff"` // Wait before the first retry
+	MaxBackoff     Duration `yaml:"max_backoff"`     // Longest wait between retries
+	Jitter         *float64 `yaml:"jitter"`          // Fraction (0 to 1) by which waits are randomized
 }
 
 // DefaultConfig returns a Config with sensible default values.
@@ -249,10 +1724,75 @@ func DefaultConfig() *Config {
 			TLS: TLSConfig{
 				Enabled: false,
 			},
+			Metrics: MetricsConfig{
+				Path: "/metrics",
+			},
+			StreamResume: StreamResumeConfig{
+				Window: Duration(time.Minute),
+			},
+			Replay: ReplayConfig{
+				MaxRequests: DefaultReplayMaxRequests,
+			},
+			HTTP2: HTTP2Config{
+				Enabled: true,
+			},
+			RequestValidation: RequestValidationWarn,
+			ShutdownTimeout:   Duration(30 * time.Second),
+			ConfigWatch: ConfigWatchConfig{
+				Enabled:  true,
+				Debounce: Duration(DefaultConfigWatchDebounce),
+			},
+			GRPC: GRPCConfig{
+				Port: DefaultGRPCPort,
+			},
 		},
 		Defaults: Defaults{
 			TokenBudget: 1000,
 			TopN:        10,
+			ProviderPool: ProviderPoolConfig{
+				MaxIdleConnsPerHost: 16,
+				IdleConnTimeout:     Duration(90 * time.Second),
+			},
+		},
+		Sessions: SessionsConfig{
+			Store:            SessionStoreMemory,
+			TTL:              Duration(24 * time.Hour),
+			MaxHistoryTokens: 2000,
+			MaxSessions:      DefaultMaxSessions,
+			Table:            "rag_sessions",
+		},
+		Jobs: JobsConfig{
+			Workers:   DefaultJobWorkers,
+			QueueSize: DefaultJobQueueSize,
+			Retention: DefaultJobRetention,
+			Store:     JobStoreMemory,
+			Table:     "rag_jobs",
+		},
+		Audit: AuditConfig{
+			Table:     DefaultAuditTable,
+			QueueSize: DefaultAuditQueueSize,
+		},
+		Integrations: IntegrationsConfig{
+			Slack: SlackConfig{
+				EventsPath:  "/integrations/slack/events",
+				SourceLinks: SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+			},
+			Mattermost: MattermostConfig{
+				SourceLinks: SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+			},
+			Email: EmailConfig{
+				InboundPath: "/integrations/email/inbound",
+				SMTP:        SMTPConfig{Port: 587},
+				SourceLinks: SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+			},
+		},
+		Reports: ReportsConfig{
+			Schedule:   ReportScheduleDaily,
+			Time:       "08:00",
+			Weekday:    "monday",
+			Timezone:   "UTC",
+			TopQueries: 10,
+			Email:      ReportEmailConfig{SMTP: SMTPConfig{Port: 587}},
 		},
 	}
 }
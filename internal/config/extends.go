@@ -0,0 +1,242 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveExtends merges each pipeline that names another in extends
+// with it, in the parsed configuration file, before the file is decoded.
+// A pipeline may extend another pipeline or one of the file's
+// pipeline_templates, which are only ever extended, and templates may
+// themselves extend others. The pipeline's own settings win: mappings
+// are merged key by key, other values, including lists, are replaced,
+// and a setting given as null is removed. A base's name is not
+// inherited. The templates are removed from the document.
+func resolveExtends(doc *yaml.Node) error {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	r := &extendsResolver{
+		bases:    make(map[string]extendsBase),
+		resolved: make(map[*yaml.Node]*yaml.Node),
+		visiting: make(map[*yaml.Node]bool),
+	}
+	var errs ValidationErrors
+
+	templates := mappingValue(root, "pipeline_templates")
+	if templates != nil && templates.Kind == yaml.SequenceNode {
+		for i, t := range templates.Content {
+			field := fmt.Sprintf("pipeline_templates[%d]", i)
+			name := scalarValue(t, "name")
+			switch {
+			case t.Kind != yaml.MappingNode || name == "":
+				errs = append(errs, ValidationError{Field: field + ".name", Message: "required"})
+			case r.bases[name].node != nil:
+				errs = append(errs, ValidationError{
+					Field:   field + ".name",
+					Message: fmt.Sprintf("duplicate template name: %s", name),
+				})
+			default:
+				r.bases[name] = extendsBase{node: t, field: field}
+			}
+		}
+	}
+
+	pipelines := mappingValue(root, "pipelines")
+	if pipelines == nil || pipelines.Kind != yaml.SequenceNode {
+		removeMappingKey(root, "pipeline_templates")
+		if len(errs) > 0 {
+			return errs
+		}
+		return nil
+	}
+	for i, p := range pipelines.Content {
+		name := scalarValue(p, "name")
+		if name == "" {
+			continue
+		}
+		field := fmt.Sprintf("pipelines[%d]", i)
+		if base, ok := r.bases[name]; ok {
+			// Duplicate pipeline names are reported by Validate.
+			if !base.pipeline {
+				errs = append(errs, ValidationError{
+					Field:   field + ".name",
+					Message: fmt.Sprintf("%s is also the name of a pipeline template", name),
+				})
+			}
+			continue
+		}
+		r.bases[name] = extendsBase{node: p, field: field, pipeline: true}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for i, p := range pipelines.Content {
+		merged, err := r.resolve(p, fmt.Sprintf("pipelines[%d]", i))
+		if err != nil {
+			errs = append(errs, *err)
+			continue
+		}
+		pipelines.Content[i] = merged
+	}
+	removeMappingKey(root, "pipeline_templates")
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// extendsBase is a pipeline or template that others may extend.
+type extendsBase struct {
+	node     *yaml.Node
+	field    string
+	pipeline bool
+}
+
+// extendsResolver merges pipelines with their bases, resolving each
+// base once.
+type extendsResolver struct {
+	bases    map[string]extendsBase
+	resolved map[*yaml.Node]*yaml.Node
+	visiting map[*yaml.Node]bool
+}
+
+// resolve returns node merged with the chain of bases it extends.
+func (r *extendsResolver) resolve(node *yaml.Node, field string) (*yaml.Node, *ValidationError) {
+	if merged, ok := r.resolved[node]; ok {
+		return merged, nil
+	}
+	extends := scalarValue(node, "extends")
+	if extends == "" {
+		return node, nil
+	}
+	base, ok := r.bases[extends]
+	if !ok {
+		return nil, &ValidationError{
+			Field:   field + ".extends",
+			Message: fmt.Sprintf("unknown pipeline or template: %s", extends),
+		}
+	}
+	if r.visiting[node] {
+		return nil, &ValidationError{
+			Field:   field + ".extends",
+			Message: fmt.Sprintf("forms a cycle through %s", extends),
+		}
+	}
+
+	r.visiting[node] = true
+	resolvedBase, err := r.resolve(base.node, base.field)
+	delete(r.visiting, node)
+	if err != nil {
+		return nil, err
+	}
+
+	inherited := copyNode(resolvedBase)
+	removeMappingKey(inherited, "name")
+	removeMappingKey(inherited, "extends")
+	merged := mergeNodes(inherited, node)
+	r.resolved[node] = merged
+	return merged, nil
+}
+
+// mergeNodes returns base overridden by override: mappings are merged
+// key by key, a null value removes the key, and any other value
+// replaces base's.
+func mergeNodes(base, override *yaml.Node) *yaml.Node {
+	base, override = unalias(base), unalias(override)
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+	merged := copyNode(base)
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		if unalias(value).Tag == "!!null" {
+			removeMappingKey(merged, key.Value)
+			continue
+		}
+		if existing := mappingValue(merged, key.Value); existing != nil {
+			setMappingValue(merged, key.Value, mergeNodes(existing, value))
+			continue
+		}
+		merged.Content = append(merged.Content, key, value)
+	}
+	return merged
+}
+
+// unalias returns the node an alias refers to.
+func unalias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// copyNode returns a copy of a mapping, so merging into it leaves the
+// original as it was. Values are shared, since merging replaces rather
+// than changes them.
+func copyNode(n *yaml.Node) *yaml.Node {
+	n = unalias(n)
+	c := *n
+	c.Content = append([]*yaml.Node(nil), n.Content...)
+	return &c
+}
+
+// mappingValue returns the value of key in a mapping, or nil.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	m = unalias(m)
+	if m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return unalias(m.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the text of key's scalar value in a mapping, or
+// "".
+func scalarValue(m *yaml.Node, key string) string {
+	if v := mappingValue(m, key); v != nil && v.Kind == yaml.ScalarNode && v.Tag != "!!null" {
+		return v.Value
+	}
+	return ""
+}
+
+// setMappingValue replaces the value of key in a mapping.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+}
+
+// removeMappingKey removes key and its value from a mapping.
+func removeMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
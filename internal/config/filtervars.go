@@ -0,0 +1,124 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Sources of the variables a config filter can refer to.
+const (
+	// FilterVarHeader is a request header, as in {{header:X-Tenant}}.
+	FilterVarHeader = "header"
+
+	// FilterVarClaim is a claim of the caller's identity, as in
+	// {{claim:org_id}}, read from server.claims_header.
+	FilterVarClaim = "claim"
+)
+
+// filterVarRe matches a {{source:name}} placeholder. Spaces inside the
+// braces are allowed.
+var filterVarRe = regexp.MustCompile(`\{\{\s*([A-Za-z_]+)\s*:\s*([^{}\s]+)\s*\}\}`)
+
+// headerNameRe matches a valid HTTP header field name.
+var headerNameRe = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// FilterVariable is a {{source:name}} placeholder in a config filter,
+// resolved for each request to a value passed to the database as a
+// query parameter.
+type FilterVariable struct {
+	Source string // FilterVarHeader or FilterVarClaim
+	Name   string // The header or claim name
+}
+
+// String returns the variable as written in a filter, without braces.
+func (v FilterVariable) String() string {
+	return v.Source + ":" + v.Name
+}
+
+// ExpandFilterVariables replaces each placeholder in s with what expand
+// returns for it.
+func ExpandFilterVariables(s string, expand func(FilterVariable) string) string {
+	return filterVarRe.ReplaceAllStringFunc(s, func(match string) string {
+		m := filterVarRe.FindStringSubmatch(match)
+		return expand(FilterVariable{Source: m[1], Name: m[2]})
+	})
+}
+
+// Variables returns the variables a config filter refers to, in raw SQL
+// or in the string values of its conditions and groups, in the order
+// they appear.
+func (cf *ConfigFilter) Variables() []FilterVariable {
+	if cf == nil {
+		return nil
+	}
+	var vars []FilterVariable
+	collect := func(s string) {
+		for _, m := range filterVarRe.FindAllStringSubmatch(s, -1) {
+			vars = append(vars, FilterVariable{Source: m[1], Name: m[2]})
+		}
+	}
+	var collectFilter func(f *Filter)
+	collectFilter = func(f *Filter) {
+		for _, cond := range f.Conditions {
+			switch v := cond.Value.(type) {
+			case string:
+				collect(v)
+			case []interface{}:
+				for _, item := range v {
+					if s, ok := item.(string); ok {
+						collect(s)
+					}
+				}
+			}
+		}
+		for i := range f.Groups {
+			collectFilter(&f.Groups[i])
+		}
+	}
+	collect(cf.RawSQL)
+	if cf.Structured != nil {
+		collectFilter(cf.Structured)
+	}
+	return vars
+}
+
+// validateFilterVariables checks the variables a table's filter refers
+// to: each must name a known source, headers must be valid header names,
+// and claims need server.claims_header to read them from.
+func (c *Config) validateFilterVariables(field string, cf *ConfigFilter) ValidationErrors {
+	var errs ValidationErrors
+	for _, v := range cf.Variables() {
+		switch v.Source {
+		case FilterVarHeader:
+			if !headerNameRe.MatchString(v.Name) {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("{{%s}}: %q is not a valid header name", v, v.Name),
+				})
+			}
+		case FilterVarClaim:
+			if c.Server.ClaimsHeader == "" {
+				errs = append(errs, ValidationError{
+					Field:   field,
+					Message: fmt.Sprintf("{{%s}} requires server.claims_header", v),
+				})
+			}
+		default:
+			errs = append(errs, ValidationError{
+				Field: field,
+				Message: fmt.Sprintf("{{%s}}: unknown variable source %q (must be %q or %q)",
+					v, v.Source, FilterVarHeader, FilterVarClaim),
+			})
+		}
+	}
+	return errs
+}
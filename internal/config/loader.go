@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 
 	"gopkg.in/yaml.v3"
 )
@@ -41,6 +42,31 @@ func Load(path string) (*Config, error) {
 	return loadFromFile(configPath)
 }
 
+// RestartRequired returns the sections of next, a reloaded
+// configuration, that differ from cur but that a reload does not apply.
+// Only the pipelines, the defaults they inherit and the API keys they
+// use are reloaded; the other sections are read once at startup.
+func RestartRequired(cur, next *Config) []string {
+	sections := []struct {
+		name      string
+		cur, next any
+	}{
+		{"server", cur.Server, next.Server},
+		{"sessions", cur.Sessions, next.Sessions},
+		{"jobs", cur.Jobs, next.Jobs},
+		{"audit", cur.Audit, next.Audit},
+		{"integrations", cur.Integrations, next.Integrations},
+		{"reports", cur.Reports, next.Reports},
+	}
+	var changed []string
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.cur, s.next) {
+			changed = append(changed, s.name)
+		}
+	}
+	return changed
+}
+
 // FindConfigFile finds the configuration file using the search order.
 func FindConfigFile(explicitPath string) (string, error) {
 	// If explicit path provided, use it
@@ -96,10 +122,22 @@ func loadFromFile(path string) (*Config, error) {
 	// Start with defaults
 	cfg := DefaultConfig()
 
-	if err := yaml.Unmarshal(data, cfg); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
+	// Merge pipelines with the pipelines and templates they extend
+	if err := resolveExtends(&doc); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if doc.Kind != 0 {
+		if err := doc.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
 	// Apply defaults to pipelines
 	applyDefaults(cfg)
 
@@ -113,6 +151,43 @@ func loadFromFile(path string) (*Config, error) {
 
 // applyDefaults applies default values to pipelines where not specified.
 func applyDefaults(cfg *Config) {
+	// Dedicated metrics and admin listeners bind to the API's address unless
+	// told otherwise.
+	if cfg.Server.Metrics.Port != 0 && cfg.Server.Metrics.ListenAddress == "" {
+		cfg.Server.Metrics.ListenAddress = cfg.Server.ListenAddress
+	}
+	if cfg.Server.Admin.Port != 0 && cfg.Server.Admin.ListenAddress == "" {
+		cfg.Server.Admin.ListenAddress = cfg.Server.ListenAddress
+	}
+	if cfg.Server.GRPC.ListenAddress == "" {
+		cfg.Server.GRPC.ListenAddress = cfg.Server.ListenAddress
+	}
+
+	if cfg.Sessions.Store == SessionStorePostgres {
+		applyDatabaseDefaults(&cfg.Sessions.Database)
+	}
+	if cfg.Jobs.Workers == 0 {
+		cfg.Jobs.Workers = DefaultJobWorkers
+	}
+	if cfg.Jobs.QueueSize == 0 {
+		cfg.Jobs.QueueSize = DefaultJobQueueSize
+	}
+	if cfg.Jobs.Retention == 0 {
+		cfg.Jobs.Retention = DefaultJobRetention
+	}
+	if cfg.Jobs.Store == JobStorePostgres {
+		applyDatabaseDefaults(&cfg.Jobs.Database)
+	}
+	if cfg.Audit.Enabled {
+		applyDatabaseDefaults(&cfg.Audit.Database)
+		if cfg.Audit.Table == "" {
+			cfg.Audit.Table = DefaultAuditTable
+		}
+		if cfg.Audit.QueueSize == 0 {
+			cfg.Audit.QueueSize = DefaultAuditQueueSize
+		}
+	}
+
 	for i := range cfg.Pipelines {
 		p := &cfg.Pipelines[i]
 
@@ -136,6 +211,12 @@ func applyDefaults(cfg *Config) {
 		if p.EmbeddingLLM.BaseURL == "" {
 			p.EmbeddingLLM.BaseURL = cfg.Defaults.EmbeddingLLM.BaseURL
 		}
+		if p.EmbeddingLLM.Region == "" {
+			p.EmbeddingLLM.Region = cfg.Defaults.EmbeddingLLM.Region
+		}
+		if p.EmbeddingLLM.CAFile == "" {
+			p.EmbeddingLLM.CAFile = cfg.Defaults.EmbeddingLLM.CAFile
+		}
 
 		// Apply RAG LLM defaults
 		if p.RAGLLM.Provider == "" {
@@ -147,6 +228,18 @@ func applyDefaults(cfg *Config) {
 		if p.RAGLLM.BaseURL == "" {
 			p.RAGLLM.BaseURL = cfg.Defaults.RAGLLM.BaseURL
 		}
+		if p.RAGLLM.Region == "" {
+			p.RAGLLM.Region = cfg.Defaults.RAGLLM.Region
+		}
+		if p.RAGLLM.CAFile == "" {
+			p.RAGLLM.CAFile = cfg.Defaults.RAGLLM.CAFile
+		}
+		if p.RAGLLM.StopSequences == nil {
+			p.RAGLLM.StopSequences = cfg.Defaults.RAGLLM.StopSequences
+		}
+		if p.RAGLLM.LogitBias == nil {
+			p.RAGLLM.LogitBias = cfg.Defaults.RAGLLM.LogitBias
+		}
 
 		// Apply API key defaults (cascade: pipeline -> defaults -> global)
 		if p.APIKeys.Anthropic == "" {
@@ -177,6 +270,64 @@ func applyDefaults(cfg *Config) {
 				p.APIKeys.Gemini = cfg.APIKeys.Gemini
 			}
 		}
+		if p.APIKeys.Cohere == "" {
+			if cfg.Defaults.APIKeys.Cohere != "" {
+				p.APIKeys.Cohere = cfg.Defaults.APIKeys.Cohere
+			} else {
+				p.APIKeys.Cohere = cfg.APIKeys.Cohere
+			}
+		}
+		if p.APIKeys.OpenAICompatible == "" {
+			if cfg.Defaults.APIKeys.OpenAICompatible != "" {
+				p.APIKeys.OpenAICompatible = cfg.Defaults.APIKeys.OpenAICompatible
+			} else {
+				p.APIKeys.OpenAICompatible = cfg.APIKeys.OpenAICompatible
+			}
+		}
+		if p.APIKeys.AWS == "" {
+			if cfg.Defaults.APIKeys.AWS != "" {
+				p.APIKeys.AWS = cfg.Defaults.APIKeys.AWS
+			} else {
+				p.APIKeys.AWS = cfg.APIKeys.AWS
+			}
+		}
+		if p.APIKeys.AWSProfile == "" {
+			if cfg.Defaults.APIKeys.AWSProfile != "" {
+				p.APIKeys.AWSProfile = cfg.Defaults.APIKeys.AWSProfile
+			} else {
+				p.APIKeys.AWSProfile = cfg.APIKeys.AWSProfile
+			}
+		}
+
+		// Apply provider pool defaults
+		if p.ProviderPool.MaxIdleConnsPerHost == 0 {
+			p.ProviderPool.MaxIdleConnsPerHost = cfg.Defaults.ProviderPool.MaxIdleConnsPerHost
+		}
+		if p.ProviderPool.IdleConnTimeout == 0 {
+			p.ProviderPool.IdleConnTimeout = cfg.Defaults.ProviderPool.IdleConnTimeout
+		}
+
+		// Apply formatting defaults field by field, so a pipeline can
+		// change one convention and inherit the rest.
+		if p.Formatting.Locale == "" {
+			p.Formatting.Locale = cfg.Defaults.Formatting.Locale
+		}
+		if p.Formatting.DateFormat == "" {
+			p.Formatting.DateFormat = cfg.Defaults.Formatting.DateFormat
+		}
+		if p.Formatting.DecimalSeparator == "" {
+			p.Formatting.DecimalSeparator = cfg.Defaults.Formatting.DecimalSeparator
+		}
+		if p.Formatting.Units == "" {
+			p.Formatting.Units = cfg.Defaults.Formatting.Units
+		}
+
+		if p.AnswerLength == "" {
+			p.AnswerLength = cfg.Defaults.AnswerLength
+		}
+		if p.Citations == nil {
+			p.Citations = cfg.Defaults.Citations
+		}
 
 		// Apply LLM header defaults (cascade: defaults -> pipeline).
 		// Default headers are merged in first, then pipeline-specific
@@ -192,27 +343,7 @@ func applyDefaults(cfg *Config) {
 			p.LLMHeaders = merged
 		}
 
-		// Apply database port default
-		if len(p.Database.Hosts) == 0 && p.Database.Port == 0 {
-			p.Database.Port = 5432
-		}
-
-		// Apply database ssl_mode default
-		if p.Database.SSLMode == "" {
-			p.Database.SSLMode = "prefer"
-		}
-
-		// Apply per-host port defaults
-		for j := range p.Database.Hosts {
-			if p.Database.Hosts[j].Port == 0 {
-				p.Database.Hosts[j].Port = 5432
-			}
-		}
-
-		// Default target_session_attrs for multi-host configs only
-		if len(p.Database.Hosts) > 0 && p.Database.TargetSessionAttrs == "" {
-			p.Database.TargetSessionAttrs = "prefer-standby"
-		}
+		applyDatabaseDefaults(&p.Database)
 
 		// Apply search config defaults
 		if p.Search.HybridEnabled == nil {
@@ -223,5 +354,71 @@ func applyDefaults(cfg *Config) {
 			defaultWeight := 0.5
 			p.Search.VectorWeight = &defaultWeight
 		}
+		if p.Search.Fusion == "" {
+			p.Search.Fusion = FusionRRF
+		}
+		if p.Search.RRFK == nil {
+			defaultK := DefaultRRFK
+			p.Search.RRFK = &defaultK
+		}
+
+		if p.HistoryOverflow == "" {
+			p.HistoryOverflow = HistoryOverflowDrop
+		}
+
+		if p.Ingest.MaxUploadBytes == 0 {
+			p.Ingest.MaxUploadBytes = DefaultMaxUploadBytes
+		}
+
+		if p.ChunkMerge.DocumentColumn == "" {
+			p.ChunkMerge.DocumentColumn = DefaultChunkDocumentColumn
+		}
+		if p.ChunkMerge.IndexColumn == "" {
+			p.ChunkMerge.IndexColumn = DefaultChunkIndexColumn
+		}
+
+		for j := range p.Tables {
+			parent := &p.Tables[j].Parent
+			if !parent.Enabled() {
+				continue
+			}
+			if parent.Table != "" && parent.IDColumn == "" {
+				parent.IDColumn = DefaultParentIDColumn
+			}
+			if parent.Table == "" && parent.IndexColumn == "" {
+				parent.IndexColumn = DefaultChunkIndexColumn
+			}
+		}
+	}
+}
+
+// applyDatabaseDefaults fills in connection defaults for a database
+// configuration.
+func applyDatabaseDefaults(db *DatabaseConfig) {
+	// A connection URL carries its own settings.
+	if db.URL != "" {
+		return
+	}
+
+	// Apply database port default
+	if len(db.Hosts) == 0 && db.Port == 0 {
+		db.Port = 5432
+	}
+
+	// Apply database ssl_mode default
+	if db.SSLMode == "" {
+		db.SSLMode = "prefer"
+	}
+
+	// Apply per-host port defaults
+	for j := range db.Hosts {
+		if db.Hosts[j].Port == 0 {
+			db.Hosts[j].Port = 5432
+		}
+	}
+
+	// Default target_session_attrs for multi-host configs only
+	if len(db.Hosts) > 0 && db.TargetSessionAttrs == "" {
+		db.TargetSessionAttrs = "prefer-standby"
 	}
 }
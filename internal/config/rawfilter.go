@@ -0,0 +1,154 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// rawFilterKeywords are SQL keywords that have no place in a WHERE
+// clause expression and would only appear in a raw filter that tries
+// to do more than filter rows.
+var rawFilterKeywords = map[string]bool{
+	"ALTER": true, "ANALYZE": true, "CALL": true, "COMMIT": true,
+	"COPY": true, "CREATE": true, "DELETE": true, "DO": true,
+	"DROP": true, "EXECUTE": true, "GRANT": true, "INSERT": true,
+	"INTO": true, "LOCK": true, "MERGE": true, "REVOKE": true,
+	"ROLLBACK": true, "TRUNCATE": true, "UPDATE": true, "VACUUM": true,
+}
+
+// rawFilterFunctions are server functions a raw filter must not call:
+// they sleep, read files, signal backends or change settings.
+var rawFilterFunctions = map[string]bool{
+	"dblink": true, "dblink_exec": true, "lo_export": true,
+	"lo_import": true, "pg_cancel_backend": true, "pg_ls_dir": true,
+	"pg_read_binary_file": true, "pg_read_file": true,
+	"pg_reload_conf": true, "pg_sleep": true, "pg_sleep_for": true,
+	"pg_sleep_until": true, "pg_terminate_backend": true,
+	"set_config": true,
+}
+
+// validateRawFilter checks a raw SQL filter without a database: it must
+// be a single expression, with balanced parentheses and quotes, no
+// comments, and none of the keywords and functions above outside quoted
+// strings. A quoted identifier is checked as the name it quotes when it
+// is called, so "pg_sleep"(1) is refused as pg_sleep(1) is, and
+// Unicode-escaped identifiers, whose names cannot be read without
+// decoding them, are refused. Whether the filter refers to real columns
+// is checked against the database when the pipeline starts.
+func validateRawFilter(field, sql string) ValidationErrors {
+	fail := func(format string, args ...interface{}) ValidationErrors {
+		return ValidationErrors{{Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+	checkWord := func(word string) ValidationErrors {
+		if rawFilterKeywords[strings.ToUpper(word)] {
+			return fail("must not contain %s", strings.ToUpper(word))
+		}
+		if rawFilterFunctions[strings.ToLower(word)] {
+			return fail("must not call %s", strings.ToLower(word))
+		}
+		return nil
+	}
+
+	depth := 0
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'' || c == '"':
+			// E'...' strings escape with backslashes; all quotes also
+			// escape by doubling.
+			escapes := c == '\'' && i > 0 && (sql[i-1] == 'E' || sql[i-1] == 'e') &&
+				(i == 1 || !isWordByte(sql[i-2]))
+			j := i + 1
+			for {
+				for j < len(sql) && sql[j] != c {
+					if escapes && sql[j] == '\\' {
+						j++
+					}
+					j++
+				}
+				if j >= len(sql) {
+					return fail("unterminated quoted string or identifier")
+				}
+				j++
+				if j < len(sql) && sql[j] == c {
+					j++
+					continue
+				}
+				break
+			}
+			if c == '"' {
+				if i >= 2 && sql[i-1] == '&' && (sql[i-2] == 'U' || sql[i-2] == 'u') {
+					return fail("must not contain Unicode-escaped identifiers")
+				}
+				name := strings.ReplaceAll(sql[i+1:j-1], `""`, `"`)
+				if strings.HasPrefix(strings.TrimLeft(sql[j:], " \t\r\n"), "(") {
+					if errs := checkWord(name); errs != nil {
+						return errs
+					}
+				}
+			}
+			i = j
+		case c == '$' && (i == 0 || !isWordByte(sql[i-1])) && dollarTag(sql[i:]) != "":
+			tag := dollarTag(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				return fail("unterminated dollar-quoted string")
+			}
+			i += len(tag) + end + len(tag)
+		case strings.HasPrefix(sql[i:], "--") || strings.HasPrefix(sql[i:], "/*"):
+			return fail("must not contain comments")
+		case c == ';':
+			return fail("must be a single expression, without ';'")
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return fail("unbalanced parentheses")
+			}
+			i++
+		case isWordByte(c):
+			start := i
+			for i < len(sql) && isWordByte(sql[i]) {
+				i++
+			}
+			if errs := checkWord(sql[start:i]); errs != nil {
+				return errs
+			}
+		default:
+			i++
+		}
+	}
+	if depth != 0 {
+		return fail("unbalanced parentheses")
+	}
+	return nil
+}
+
+// dollarTag returns the opening tag of a dollar-quoted string at the
+// start of s, such as "$$" or "$body$", or "" if s does not start one.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '$':
+			return s[:i+1]
+		case !isWordByte(s[i]) || (i == 1 && s[i] >= '0' && s[i] <= '9'):
+			return "" // $1 is a parameter, not a tag
+		}
+	}
+	return ""
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
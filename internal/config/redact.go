@@ -0,0 +1,112 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package config
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces secret values in MarshalRedacted's output.
+const Redacted = "[REDACTED]"
+
+// secretKeys are the YAML keys whose values are secrets.
+var secretKeys = map[string]bool{
+	"password": true,
+}
+
+// connectionKeys are the YAML keys of connection URLs, whose
+// passwords are redacted while the rest is kept.
+var connectionKeys = map[string]bool{
+	"url": true,
+}
+
+// dsnPassword matches the password in a keyword/value connection
+// string, quoted or not.
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// headerKeys are the YAML keys of custom HTTP header maps. Headers
+// often carry credentials such as gateway tokens, so their values are
+// redacted while the header names are kept.
+var headerKeys = map[string]bool{
+	"headers":     true,
+	"llm_headers": true,
+}
+
+// MarshalRedacted renders cfg as YAML with its secrets replaced by
+// Redacted, so it can be shared, e.g. in a support bundle. Database
+// passwords, including those in connection URLs, and custom header
+// values are redacted. API keys are never
+// part of the configuration, only the paths of the files holding
+// them, so those are kept.
+func MarshalRedacted(cfg *Config) ([]byte, error) {
+	var doc yaml.Node
+	if err := doc.Encode(cfg); err != nil {
+		return nil, err
+	}
+	redactNode(&doc)
+	return yaml.Marshal(&doc)
+}
+
+// redactNode redacts the secrets in a YAML node tree in place.
+func redactNode(n *yaml.Node) {
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i].Value, n.Content[i+1]
+			switch {
+			case secretKeys[key]:
+				redactScalar(value)
+			case connectionKeys[key] && value.Kind == yaml.ScalarNode:
+				value.Value = redactConnectionString(value.Value)
+			case headerKeys[key] && value.Kind == yaml.MappingNode:
+				for j := 1; j < len(value.Content); j += 2 {
+					redactScalar(value.Content[j])
+				}
+			}
+		}
+	}
+	for _, child := range n.Content {
+		redactNode(child)
+	}
+}
+
+// redactScalar replaces a non-empty scalar's value with Redacted, so an
+// unset secret still reads as unset.
+func redactScalar(n *yaml.Node) {
+	if n.Kind == yaml.ScalarNode && n.Value != "" {
+		n.Value = Redacted
+		n.Tag = "!!str"
+		n.Style = 0
+	}
+}
+
+// redactConnectionString redacts the password in a connection URL or
+// keyword/value connection string.
+func redactConnectionString(s string) string {
+	if !strings.Contains(s, "://") {
+		return dsnPassword.ReplaceAllString(s, "${1}"+Redacted)
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return Redacted
+	}
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", Redacted)
+		u.RawQuery = q.Encode()
+	}
+	// Keep the marker readable rather than escaped.
+	return strings.ReplaceAll(u.String(), url.PathEscape(Redacted), Redacted)
+}
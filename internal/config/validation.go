@@ -10,12 +10,20 @@
 package config
 
 import (
+	"crypto/x509"
 	"fmt"
+	"maps"
 	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // maxPipelineNameLen is the maximum allowed length for a pipeline name.
@@ -25,8 +33,9 @@ const maxPipelineNameLen = 63
 // Only lowercase letters, digits, hyphens, and underscores are permitted.
 var pipelineNameRe = regexp.MustCompile(`^[a-z0-9_-]+$`)
 
-// expandPath expands ~ to the user's home directory.
-func expandPath(path string) string {
+// ExpandPath expands a leading ~/ to the user's home directory, as
+// every file path in the configuration is expanded.
+func ExpandPath(path string) string {
 	if strings.HasPrefix(path, "~/") {
 		homeDir, err := os.UserHomeDir()
 		if err != nil {
@@ -73,9 +82,36 @@ func (c *Config) Validate() error {
 	// Validate defaults
 	errs = append(errs, c.validateDefaults()...)
 
+	// Validate sessions
+	if c.Sessions.Enabled {
+		errs = append(errs, c.validateSessions()...)
+	}
+
+	// Validate jobs
+	errs = append(errs, c.validateJobs()...)
+
+	// Validate the audit log
+	if c.Audit.Enabled {
+		errs = append(errs, c.validateAudit()...)
+	}
+
+	// Validate provider limits
+	errs = append(errs, c.validateProviderLimits()...)
+
 	// Validate pipelines
 	errs = append(errs, c.validatePipelines()...)
 
+	// Validate experiments
+	errs = append(errs, c.validateExperiments()...)
+
+	// Validate integrations
+	errs = append(errs, c.validateIntegrations()...)
+
+	// Validate reports
+	if c.Reports.Enabled {
+		errs = append(errs, c.validateReports()...)
+	}
+
 	if len(errs) > 0 {
 		return errs
 	}
@@ -93,13 +129,113 @@ func (c *Config) validateServer() ValidationErrors {
 		})
 	}
 
+	if c.Server.Metrics.Enabled {
+		errs = append(errs, c.validateMetrics()...)
+	}
+
+	if c.Server.Admin.Enabled {
+		errs = append(errs, c.validateAdmin()...)
+	}
+
+	if c.Server.GRPC.Enabled {
+		errs = append(errs, c.validateGRPC()...)
+	}
+
+	if c.Server.Replay.Enabled {
+		if !c.Server.Admin.Enabled {
+			errs = append(errs, ValidationError{
+				Field:   "server.replay.enabled",
+				Message: "requires server.admin.enabled, whose endpoints replay the queries",
+			})
+		}
+		if c.Server.Replay.MaxRequests <= 0 {
+			errs = append(errs, ValidationError{
+				Field:   "server.replay.max_requests",
+				Message: "must be positive when replay is enabled",
+			})
+		}
+	}
+
+	if c.Server.Signing.Enabled {
+		errs = append(errs, validateSecretFile("server.signing.key_file", c.Server.Signing.KeyFile, true)...)
+	}
+
+	if c.Server.StreamResume.Enabled && c.Server.StreamResume.Window <= 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.stream_resume.window",
+			Message: "must be positive when stream resumption is enabled",
+		})
+	}
+
+	if c.Server.ShutdownTimeout < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.shutdown_timeout",
+			Message: "must be non-negative",
+		})
+	}
+
+	if c.Server.ConfigWatch.Debounce < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.config_watch.debounce",
+			Message: "must be non-negative",
+		})
+	}
+
+	if c.Server.HTTP2.H2C {
+		if c.Server.TLS.Enabled {
+			errs = append(errs, ValidationError{
+				Field:   "server.http2.h2c",
+				Message: "cannot be used with TLS; HTTP/2 is negotiated over TLS automatically",
+			})
+		} else if !c.Server.HTTP2.Enabled {
+			errs = append(errs, ValidationError{
+				Field:   "server.http2.h2c",
+				Message: "requires server.http2.enabled",
+			})
+		}
+	}
+
+	switch c.Server.RequestValidation {
+	case "", RequestValidationWarn, RequestValidationStrict:
+	default:
+		errs = append(errs, ValidationError{
+			Field: "server.request_validation",
+			Message: fmt.Sprintf("must be %q or %q", RequestValidationWarn,
+				RequestValidationStrict),
+		})
+	}
+
+	if c.Server.ClaimsHeader != "" && !headerNameRe.MatchString(c.Server.ClaimsHeader) {
+		errs = append(errs, ValidationError{
+			Field:   "server.claims_header",
+			Message: "must be a valid header name",
+		})
+	}
+
+	errs = append(errs, c.validateTraceHeaders()...)
+
+	if pl := c.Server.ProviderLog; pl.Enabled && pl.Path == "" {
+		errs = append(errs, ValidationError{
+			Field:   "server.provider_log.path",
+			Message: "required when the provider log is enabled",
+		})
+	}
+	if c.Server.ProviderLog.MaxStringLength < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.provider_log.max_string_length",
+			Message: "must not be negative",
+		})
+	}
+
+	errs = append(errs, c.validateServerRateLimit()...)
+
 	if c.Server.TLS.Enabled {
 		if c.Server.TLS.CertFile == "" {
 			errs = append(errs, ValidationError{
 				Field:   "server.tls.cert_file",
 				Message: "required when TLS is enabled",
 			})
-		} else if _, err := os.Stat(expandPath(c.Server.TLS.CertFile)); err != nil {
+		} else if _, err := os.Stat(ExpandPath(c.Server.TLS.CertFile)); err != nil {
 			errs = append(errs, ValidationError{
 				Field:   "server.tls.cert_file",
 				Message: fmt.Sprintf("file not found: %s", c.Server.TLS.CertFile),
@@ -111,7 +247,7 @@ func (c *Config) validateServer() ValidationErrors {
 				Field:   "server.tls.key_file",
 				Message: "required when TLS is enabled",
 			})
-		} else if _, err := os.Stat(expandPath(c.Server.TLS.KeyFile)); err != nil {
+		} else if _, err := os.Stat(ExpandPath(c.Server.TLS.KeyFile)); err != nil {
 			errs = append(errs, ValidationError{
 				Field:   "server.tls.key_file",
 				Message: fmt.Sprintf("file not found: %s", c.Server.TLS.KeyFile),
@@ -122,6 +258,40 @@ func (c *Config) validateServer() ValidationErrors {
 	return errs
 }
 
+// validateMetrics validates the metrics endpoint configuration. The path
+// must be absolute and must not shadow the versioned API routes, and a
+// dedicated listener must not collide with the API listener.
+func (c *Config) validateMetrics() ValidationErrors {
+	var errs ValidationErrors
+	m := c.Server.Metrics
+
+	if !strings.HasPrefix(m.Path, "/") {
+		errs = append(errs, ValidationError{
+			Field:   "server.metrics.path",
+			Message: "must start with /",
+		})
+	} else if m.Path == "/v1" || strings.HasPrefix(m.Path, "/v1/") {
+		errs = append(errs, ValidationError{
+			Field:   "server.metrics.path",
+			Message: "must not be under /v1",
+		})
+	}
+
+	if m.Port < 0 || m.Port > 65535 {
+		errs = append(errs, ValidationError{
+			Field:   "server.metrics.port",
+			Message: "must be between 1 and 65535, or 0 to share the API listener",
+		})
+	} else if m.Port == c.Server.Port && m.ListenAddress == c.Server.ListenAddress {
+		errs = append(errs, ValidationError{
+			Field:   "server.metrics.port",
+			Message: "must differ from server.port; omit it to share the API listener",
+		})
+	}
+
+	return errs
+}
+
 // validateDefaults validates the defaults configuration.
 func (c *Config) validateDefaults() ValidationErrors {
 	var errs ValidationErrors
@@ -129,360 +299,2133 @@ func (c *Config) validateDefaults() ValidationErrors {
 	// Validate embedding LLM if provider is specified
 	if c.Defaults.EmbeddingLLM.Provider != "" {
 		errs = append(errs, c.validateLLMOptional("defaults.embedding_llm",
-			c.Defaults.EmbeddingLLM, []string{"openai", "voyage", "ollama", "gemini"})...)
+			c.Defaults.EmbeddingLLM, []string{"openai", "voyage", "ollama", "gemini", "bedrock", "cohere",
+				"openai-compatible"})...)
 	}
 
 	// Validate RAG LLM if provider is specified
 	if c.Defaults.RAGLLM.Provider != "" {
 		errs = append(errs, c.validateLLMOptional("defaults.rag_llm",
-			c.Defaults.RAGLLM, []string{"anthropic", "openai", "ollama", "gemini"})...)
+			c.Defaults.RAGLLM, []string{"anthropic", "openai", "ollama", "gemini", "bedrock", "cohere",
+				"openai-compatible"})...)
+	}
+	errs = append(errs, validateGenerationControls("defaults.rag_llm", c.Defaults.RAGLLM)...)
+	errs = append(errs, validateProviderPool("defaults.provider_pool", c.Defaults.ProviderPool)...)
+	errs = append(errs, validateFormatting("defaults.formatting", c.Defaults.Formatting)...)
+	if msg := CheckAnswerLength(c.Defaults.AnswerLength); msg != "" {
+		errs = append(errs, ValidationError{Field: "defaults.answer_length", Message: msg})
 	}
 
 	return errs
 }
 
-// validatePipelines validates all pipeline configurations.
-func (c *Config) validatePipelines() ValidationErrors {
+// validateSessions validates the conversation session configuration.
+func (c *Config) validateSessions() ValidationErrors {
 	var errs ValidationErrors
+	sc := c.Sessions
 
-	if len(c.Pipelines) == 0 {
+	switch sc.Store {
+	case SessionStoreMemory:
+	case SessionStorePostgres:
+		errs = append(errs, c.validateDatabase("sessions.database", sc.Database)...)
+		if sc.Table == "" {
+			errs = append(errs, ValidationError{
+				Field:   "sessions.table",
+				Message: "required when store is postgres",
+			})
+		}
+	default:
 		errs = append(errs, ValidationError{
-			Field:   "pipelines",
-			Message: "at least one pipeline must be configured",
+			Field: "sessions.store",
+			Message: fmt.Sprintf("must be one of: %s, %s",
+				SessionStoreMemory, SessionStorePostgres),
 		})
-		return errs
 	}
 
-	// Check for duplicate pipeline names
-	names := make(map[string]bool)
-	for i, p := range c.Pipelines {
-		if names[p.Name] {
+	if sc.TTL < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "sessions.ttl",
+			Message: "must not be negative",
+		})
+	}
+	if sc.MaxHistoryTokens < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "sessions.max_history_tokens",
+			Message: "must be non-negative",
+		})
+	}
+	if sc.MaxSessions < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "sessions.max_sessions",
+			Message: "must be non-negative",
+		})
+	}
+
+	return errs
+}
+
+// validateJobs validates the background job configuration.
+func (c *Config) validateJobs() ValidationErrors {
+	var errs ValidationErrors
+	jc := c.Jobs
+
+	switch jc.Store {
+	case JobStoreMemory, "":
+	case JobStorePostgres:
+		errs = append(errs, c.validateDatabase("jobs.database", jc.Database)...)
+		if jc.Table == "" {
 			errs = append(errs, ValidationError{
-				Field:   fmt.Sprintf("pipelines[%d].name", i),
-				Message: fmt.Sprintf("duplicate pipeline name: %s", p.Name),
+				Field:   "jobs.table",
+				Message: "required when store is postgres",
 			})
 		}
-		names[p.Name] = true
+	default:
+		errs = append(errs, ValidationError{
+			Field: "jobs.store",
+			Message: fmt.Sprintf("must be one of: %s, %s",
+				JobStoreMemory, JobStorePostgres),
+		})
+	}
 
-		errs = append(errs, c.validatePipeline(i, p)...)
+	if jc.Workers < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "jobs.workers",
+			Message: "must be non-negative",
+		})
+	}
+	if jc.QueueSize < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "jobs.queue_size",
+			Message: "must be non-negative",
+		})
+	}
+	if jc.Retention < 0 {
+		errs = append(errs, ValidationError{
+			Field:   "jobs.retention",
+			Message: "must not be negative",
+		})
 	}
 
 	return errs
 }
 
-// validatePipeline validates a single pipeline configuration.
-func (c *Config) validatePipeline(index int, p Pipeline) ValidationErrors {
+// validateAudit validates the audit log configuration.
+func (c *Config) validateAudit() ValidationErrors {
 	var errs ValidationErrors
-	prefix := fmt.Sprintf("pipelines[%d]", index)
+	ac := c.Audit
 
-	// Required fields
-	if p.Name == "" {
+	errs = append(errs, c.validateDatabase("audit.database", ac.Database)...)
+	if ac.Retention < 0 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".name",
-			Message: "required",
+			Field:   "audit.retention",
+			Message: "must not be negative",
 		})
-	} else if len(p.Name) > maxPipelineNameLen {
+	}
+	if ac.QueueSize < 0 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".name",
-			Message: fmt.Sprintf("must be %d characters or fewer", maxPipelineNameLen),
+			Field:   "audit.queue_size",
+			Message: "must be non-negative",
 		})
-	} else if !pipelineNameRe.MatchString(p.Name) {
+	}
+
+	return errs
+}
+
+// validateProviderLimits validates the provider concurrency limits.
+func (c *Config) validateProviderLimits() ValidationErrors {
+	var errs ValidationErrors
+
+	providers := []string{"anthropic", "openai", "ollama", "gemini", "voyage", "bedrock", "cohere",
+		"openai-compatible"}
+	for _, name := range slices.Sorted(maps.Keys(c.ProviderLimits)) {
+		lc := c.ProviderLimits[name]
+		prefix := "provider_limits." + name
+		if !slices.Contains(providers, strings.ToLower(name)) {
+			errs = append(errs, ValidationError{
+				Field:   prefix,
+				Message: fmt.Sprintf("unknown provider; must be one of: %s", strings.Join(providers, ", ")),
+			})
+		}
+		if lc.MaxConcurrentRequests < 1 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".max_concurrent_requests",
+				Message: "must be at least 1",
+			})
+		}
+		if lc.MaxQueue < 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".max_queue",
+				Message: "must be non-negative",
+			})
+		}
+		if lc.QueueTimeout < 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".queue_timeout",
+				Message: "must not be negative",
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateExperiments validates the experiments. A pipeline may run one
+// experiment, and a variant may not run one of its own.
+func (c *Config) validateExperiments() ValidationErrors {
+	var errs ValidationErrors
+
+	experimented := make(map[string]bool)
+	for _, ec := range c.Experiments {
+		experimented[ec.Pipeline] = true
+	}
+	seen := make(map[string]bool)
+	for i, ec := range c.Experiments {
+		prefix := fmt.Sprintf("experiments[%d]", i)
+		errs = append(errs, c.validateIntegrationPipeline(prefix, ec.Pipeline)...)
+		if ec.Pipeline != "" && seen[ec.Pipeline] {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".pipeline",
+				Message: fmt.Sprintf("pipeline %s already runs an experiment", ec.Pipeline),
+			})
+		}
+		seen[ec.Pipeline] = true
+
+		if len(ec.Variants) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".variants",
+				Message: "at least one variant is required",
+			})
+		}
+		variants := make(map[string]bool)
+		total := 0.0
+		for j, v := range ec.Variants {
+			field := fmt.Sprintf("%s.variants[%d]", prefix, j)
+			errs = append(errs, c.validateIntegrationPipeline(field, v.Pipeline)...)
+			switch {
+			case v.Pipeline == "":
+			case v.Pipeline == ec.Pipeline:
+				errs = append(errs, ValidationError{
+					Field:   field + ".pipeline",
+					Message: "must not be the experiment's own pipeline",
+				})
+			case variants[v.Pipeline]:
+				errs = append(errs, ValidationError{
+					Field:   field + ".pipeline",
+					Message: fmt.Sprintf("duplicate variant: %s", v.Pipeline),
+				})
+			case experimented[v.Pipeline]:
+				errs = append(errs, ValidationError{
+					Field:   field + ".pipeline",
+					Message: fmt.Sprintf("pipeline %s runs an experiment of its own", v.Pipeline),
+				})
+			}
+			variants[v.Pipeline] = true
+
+			if v.Traffic <= 0 || v.Traffic > 100 {
+				errs = append(errs, ValidationError{
+					Field:   field + ".traffic",
+					Message: "must be above 0 and at most 100",
+				})
+			}
+			total += v.Traffic
+		}
+		if total > 100 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".variants",
+				Message: fmt.Sprintf("traffic totals %g%%, over 100%%", total),
+			})
+		}
+	}
+
+	return errs
+}
+
+// validateIntegrations validates the enabled integrations.
+func (c *Config) validateIntegrations() ValidationErrors {
+	var errs ValidationErrors
+
+	if sc := c.Integrations.Slack; sc.Enabled {
+		prefix := "integrations.slack"
+		errs = append(errs, c.validateIntegrationPipeline(prefix, sc.Pipeline)...)
+		errs = append(errs, validateSecretFile(prefix+".bot_token_file", sc.BotTokenFile, true)...)
+		errs = append(errs, validateSecretFile(prefix+".app_token_file", sc.AppTokenFile, false)...)
+		errs = append(errs, validateSecretFile(prefix+".signing_secret_file", sc.SigningSecretFile,
+			sc.AppTokenFile == "")...)
+		if sc.AppTokenFile == "" {
+			errs = append(errs, validateWebhookPath(prefix+".events_path", sc.EventsPath)...)
+		}
+	}
+
+	if mc := c.Integrations.Mattermost; mc.Enabled {
+		prefix := "integrations.mattermost"
+		errs = append(errs, c.validateIntegrationPipeline(prefix, mc.Pipeline)...)
+		errs = append(errs, validateSecretFile(prefix+".token_file", mc.TokenFile, true)...)
+		if u, err := url.Parse(mc.URL); mc.URL == "" || err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".url",
+				Message: "must be an http or https URL",
+			})
+		}
+	}
+
+	if ec := c.Integrations.Email; ec.Enabled {
+		prefix := "integrations.email"
+		errs = append(errs, c.validateIntegrationPipeline(prefix, ec.Pipeline)...)
+		errs = append(errs, validateWebhookPath(prefix+".inbound_path", ec.InboundPath)...)
+		errs = append(errs, validateSecretFile(prefix+".webhook_secret_file", ec.WebhookSecretFile, true)...)
+		if _, err := mail.ParseAddress(ec.From); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".from",
+				Message: "must be an email address",
+			})
+		}
+		for i, sender := range ec.AllowedSenders {
+			if strings.TrimPrefix(strings.TrimSpace(sender), "@") == "" {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("%s.allowed_senders[%d]", prefix, i),
+					Message: "must be an address or @domain",
+				})
+			}
+		}
+		errs = append(errs, validateSMTP(prefix+".smtp", ec.SMTP)...)
+	}
+
+	return errs
+}
+
+// validateSMTP validates the mail server email is sent through.
+func validateSMTP(prefix string, sc SMTPConfig) ValidationErrors {
+	var errs ValidationErrors
+	if sc.Host == "" {
+		errs = append(errs, ValidationError{Field: prefix + ".host", Message: "required"})
+	}
+	if sc.Port < 1 || sc.Port > 65535 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".name",
-			Message: "must contain only lowercase letters, digits, hyphens, and underscores (^[a-z0-9_-]+$)",
+			Field:   prefix + ".port",
+			Message: "must be between 1 and 65535",
 		})
 	}
+	errs = append(errs, validateSecretFile(prefix+".password_file", sc.PasswordFile, sc.Username != "")...)
+	return errs
+}
 
-	// Database validation
-	errs = append(errs, c.validateDatabase(prefix+".database", p.Database)...)
+// validateReports validates the usage report schedule and where the
+// reports are delivered.
+func (c *Config) validateReports() ValidationErrors {
+	var errs ValidationErrors
+	rc := c.Reports
 
-	// Tables validation
-	if len(p.Tables) == 0 {
+	if rc.Schedule != ReportScheduleDaily && rc.Schedule != ReportScheduleWeekly {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".tables",
-			Message: "at least one table must be configured",
+			Field: "reports.schedule",
+			Message: fmt.Sprintf("must be one of: %s, %s",
+				ReportScheduleDaily, ReportScheduleWeekly),
 		})
-	} else {
-		for j, ts := range p.Tables {
-			errs = append(errs, c.validateTable(
-				fmt.Sprintf("%s.tables[%d]", prefix, j), ts)...)
+	}
+	if _, _, err := ParseTimeOfDay(rc.Time); err != nil {
+		errs = append(errs, ValidationError{Field: "reports.time", Message: "must be a time of day as HH:MM"})
+	}
+	if _, ok := ParseWeekday(rc.Weekday); rc.Schedule == ReportScheduleWeekly && !ok {
+		errs = append(errs, ValidationError{Field: "reports.weekday", Message: "must be a day of the week"})
+	}
+	if _, err := time.LoadLocation(rc.Timezone); err != nil {
+		errs = append(errs, ValidationError{Field: "reports.timezone", Message: "must be an IANA time zone"})
+	}
+	for i, name := range rc.Pipelines {
+		if !slices.ContainsFunc(c.Pipelines, func(p Pipeline) bool { return p.Name == name }) {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("reports.pipelines[%d]", i),
+				Message: fmt.Sprintf("unknown pipeline %q", name),
+			})
 		}
 	}
+	if rc.TopQueries < 0 {
+		errs = append(errs, ValidationError{Field: "reports.top_queries", Message: "must be non-negative"})
+	}
 
-	// LLM validation
-	errs = append(errs, c.validateLLM(prefix+".embedding_llm", p.EmbeddingLLM,
-		[]string{"openai", "voyage", "ollama", "gemini"})...)
-	errs = append(errs, c.validateLLM(prefix+".rag_llm", p.RAGLLM,
-		[]string{"anthropic", "openai", "ollama", "gemini"})...)
+	if rc.Webhook.URL == "" && len(rc.Email.To) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "reports",
+			Message: "webhook.url or email.to is required",
+		})
+	}
+	if rc.Webhook.URL != "" {
+		if u, err := url.Parse(rc.Webhook.URL); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   "reports.webhook.url",
+				Message: "must be an http or https URL",
+			})
+		}
+		errs = append(errs, validateSecretFile("reports.webhook.secret_file", rc.Webhook.SecretFile, false)...)
+	}
+	if len(rc.Email.To) > 0 {
+		if _, err := mail.ParseAddress(rc.Email.From); err != nil {
+			errs = append(errs, ValidationError{
+				Field:   "reports.email.from",
+				Message: "must be an email address",
+			})
+		}
+		for i, to := range rc.Email.To {
+			if _, err := mail.ParseAddress(to); err != nil {
+				errs = append(errs, ValidationError{
+					Field:   fmt.Sprintf("reports.email.to[%d]", i),
+					Message: "must be an email address",
+				})
+			}
+		}
+		errs = append(errs, validateSMTP("reports.email.smtp", rc.Email.SMTP)...)
+	}
+
+	return errs
+}
+
+// validateWebhookPath checks the path an integration's webhook is
+// served at on the API listener, which must stay clear of the API.
+func validateWebhookPath(field, path string) ValidationErrors {
+	if !strings.HasPrefix(path, "/") {
+		return ValidationErrors{{Field: field, Message: "must start with /"}}
+	}
+	if path == "/v1" || strings.HasPrefix(path, "/v1/") {
+		return ValidationErrors{{Field: field, Message: "must not be under /v1"}}
+	}
+	return nil
+}
+
+// validateIntegrationPipeline checks that an integration names one of
+// the configured pipelines.
+func (c *Config) validateIntegrationPipeline(prefix, name string) ValidationErrors {
+	if name == "" {
+		return ValidationErrors{{Field: prefix + ".pipeline", Message: "required"}}
+	}
+	for _, p := range c.Pipelines {
+		if p.Name == name {
+			return nil
+		}
+	}
+	return ValidationErrors{{
+		Field:   prefix + ".pipeline",
+		Message: fmt.Sprintf("unknown pipeline %q", name),
+	}}
+}
+
+// validateSecretFile checks that a credential file, if set or required,
+// exists.
+func validateSecretFile(field, path string, required bool) ValidationErrors {
+	if path == "" {
+		if required {
+			return ValidationErrors{{Field: field, Message: "required"}}
+		}
+		return nil
+	}
+	if _, err := os.Stat(ExpandPath(path)); err != nil {
+		return ValidationErrors{{Field: field, Message: fmt.Sprintf("file not found: %s", path)}}
+	}
+	return nil
+}
+
+// validateCAFile checks that an optional CA bundle can be read and
+// holds at least one PEM certificate.
+func validateCAFile(field, path string) ValidationErrors {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(ExpandPath(path))
+	if err != nil {
+		return ValidationErrors{{Field: field, Message: fmt.Sprintf("cannot read %s: %v", path, err)}}
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(data) {
+		return ValidationErrors{{Field: field, Message: fmt.Sprintf("no PEM certificates found in %s", path)}}
+	}
+	return nil
+}
+
+// validatePipelines validates all pipeline configurations.
+func (c *Config) validatePipelines() ValidationErrors {
+	var errs ValidationErrors
+
+	if len(c.Pipelines) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   "pipelines",
+			Message: "at least one pipeline must be configured",
+		})
+		return errs
+	}
+
+	// Check for duplicate pipeline names
+	names := make(map[string]bool)
+	for i, p := range c.Pipelines {
+		if names[p.Name] {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("pipelines[%d].name", i),
+				Message: fmt.Sprintf("duplicate pipeline name: %s", p.Name),
+			})
+		}
+		names[p.Name] = true
+
+		errs = append(errs, c.validatePipeline(i, p)...)
+	}
+
+	return errs
+}
+
+// validatePipeline validates a single pipeline configuration.
+func (c *Config) validatePipeline(index int, p Pipeline) ValidationErrors {
+	var errs ValidationErrors
+	prefix := fmt.Sprintf("pipelines[%d]", index)
+
+	// Required fields
+	if p.Name == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".name",
+			Message: "required",
+		})
+	} else if len(p.Name) > maxPipelineNameLen {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".name",
+			Message: fmt.Sprintf("must be %d characters or fewer", maxPipelineNameLen),
+		})
+	} else if !pipelineNameRe.MatchString(p.Name) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".name",
+			Message: "must contain only lowercase letters, digits, hyphens, and underscores (^[a-z0-9_-]+$)",
+		})
+	}
+
+	// Database validation
+	errs = append(errs, c.validateDatabase(prefix+".database", p.Database)...)
+
+	// Tables validation
+	if len(p.Tables) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".tables",
+			Message: "at least one table must be configured",
+		})
+	} else {
+		for j, ts := range p.Tables {
+			errs = append(errs, c.validateTable(
+				fmt.Sprintf("%s.tables[%d]", prefix, j), ts)...)
+		}
+	}
+
+	// LLM validation
+	errs = append(errs, c.validateLLM(prefix+".embedding_llm", p.EmbeddingLLM,
+		[]string{"openai", "voyage", "ollama", "gemini", "bedrock", "cohere", "openai-compatible"})...)
+	errs = append(errs, c.validateLLM(prefix+".rag_llm", p.RAGLLM,
+		[]string{"anthropic", "openai", "ollama", "gemini", "bedrock", "cohere", "openai-compatible"})...)
+	errs = append(errs, validateGenerationControls(prefix+".rag_llm", p.RAGLLM)...)
+	for j, fb := range p.RAGLLMFallbacks {
+		fbPrefix := fmt.Sprintf("%s.rag_llm_fallbacks[%d]", prefix, j)
+		errs = append(errs, c.validateLLM(fbPrefix, fb,
+			[]string{"anthropic", "openai", "ollama", "gemini", "bedrock", "cohere", "openai-compatible"})...)
+		errs = append(errs, validateGenerationControls(fbPrefix, fb)...)
+	}
+	errs = append(errs, validateCircuitBreaker(prefix+".circuit_breaker", p.CircuitBreaker)...)
+	errs = append(errs, validateStageTimeouts(prefix, p)...)
+	errs = append(errs, validateProviderPool(prefix+".provider_pool", p.ProviderPool)...)
+	errs = append(errs, validateFormatting(prefix+".formatting", p.Formatting)...)
+	errs = append(errs, validateContextFormat(prefix+".context_format", p)...)
+	switch p.Tokenizer {
+	case "", TokenizerO200kBase, TokenizerCl100kBase, TokenizerAnthropic, TokenizerEstimate:
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".tokenizer",
+			Message: fmt.Sprintf("must be one of: %s, %s, %s, %s", TokenizerO200kBase,
+				TokenizerCl100kBase, TokenizerAnthropic, TokenizerEstimate),
+		})
+	}
+	if msg := CheckAnswerLength(p.AnswerLength); msg != "" {
+		errs = append(errs, ValidationError{Field: prefix + ".answer_length", Message: msg})
+	}
+
+	seenFilter := make(map[string]bool, len(p.FilterColumns))
+	for j, col := range p.FilterColumns {
+		field := fmt.Sprintf("%s.filter_columns[%d]", prefix, j)
+		switch {
+		case col == "":
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must not be empty",
+			})
+		case seenFilter[col]:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("duplicate column %q", col),
+			})
+		}
+		seenFilter[col] = true
+	}
+
+	presets := make([]string, 0, len(p.FilterPresets))
+	for name := range p.FilterPresets {
+		presets = append(presets, name)
+	}
+	sort.Strings(presets)
+	for _, name := range presets {
+		preset := p.FilterPresets[name]
+		field := fmt.Sprintf("%s.filter_presets.%s", prefix, name)
+		switch {
+		case strings.TrimSpace(name) == "":
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".filter_presets",
+				Message: "preset names must not be empty",
+			})
+		case len(preset.Conditions) == 0 && len(preset.Groups) == 0:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must have at least one condition or group",
+			})
+		}
+	}
+
+	// Token budget validation
+	if p.TokenBudget < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".token_budget",
+			Message: "must be non-negative",
+		})
+	}
+
+	if p.MaxHistoryTokens < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_history_tokens",
+			Message: "must be non-negative",
+		})
+	}
+	switch p.HistoryOverflow {
+	case "", HistoryOverflowDrop, HistoryOverflowSummarize:
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".history_overflow",
+			Message: fmt.Sprintf("must be %q or %q", HistoryOverflowDrop,
+				HistoryOverflowSummarize),
+		})
+	}
+
+	switch p.BudgetOverflow {
+	case "", BudgetOverflowTruncate, BudgetOverflowSummarize, BudgetOverflowError:
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".budget_overflow",
+			Message: fmt.Sprintf("must be %q, %q or %q", BudgetOverflowTruncate,
+				BudgetOverflowSummarize, BudgetOverflowError),
+		})
+	}
+
+	// Top N validation
+	if p.TopN < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".top_n",
+			Message: "must be non-negative",
+		})
+	}
+
+	// Search config validation
+	if p.Search.VectorWeight != nil {
+		w := *p.Search.VectorWeight
+		if w < 0.0 || w > 1.0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".search.vector_weight",
+				Message: "must be between 0.0 and 1.0",
+			})
+		}
+	}
+
+	if p.Search.LexicalWeight != nil {
+		w := *p.Search.LexicalWeight
+		if w < 0.0 || w > 1.0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".search.lexical_weight",
+				Message: "must be between 0.0 and 1.0",
+			})
+		} else if w == 0 && p.Search.VectorWeight != nil && *p.Search.VectorWeight == 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".search.lexical_weight",
+				Message: "must be positive when vector_weight is 0",
+			})
+		}
+	}
+
+	switch p.Search.Fusion {
+	case "", FusionRRF, FusionScore:
+	default:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".search.fusion",
+			Message: fmt.Sprintf("must be %q or %q", FusionRRF, FusionScore),
+		})
+	}
+
+	if p.Search.RRFK != nil && *p.Search.RRFK < 1 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".search.rrf_k",
+			Message: "must be at least 1",
+		})
+	}
+
+	if p.Search.MinSimilarity != nil {
+		ms := *p.Search.MinSimilarity
+		if ms < 0.0 || ms > 1.0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".search.min_similarity",
+				Message: "must be between 0.0 and 1.0",
+			})
+		}
+	}
+
+	errs = append(errs, validateRetrieval(prefix+".retrieval", p.Retrieval)...)
+	errs = append(errs, validateIngest(prefix+".ingest", p)...)
+	errs = append(errs, validateChunkMerge(prefix+".chunk_merge", p)...)
+	errs = append(errs, c.validateAccessControl(prefix, p)...)
+	errs = append(errs, c.validateTenant(prefix+".tenant", p.Tenant)...)
+	errs = append(errs, validateRateLimit(prefix+".rate_limit", p.RateLimit)...)
+	errs = append(errs, validateBudget(prefix+".budget", p)...)
+	errs = append(errs, validateGuardrails(prefix+".guardrails", p.Guardrails)...)
+	errs = append(errs, validateHooks(prefix+".hooks", p.Hooks)...)
+	errs = append(errs, validateTools(prefix, p)...)
+	errs = append(errs, validateAnswerCache(prefix+".answer_cache", p.AnswerCache)...)
+	errs = append(errs, validateEvalCapture(prefix+".eval_capture", p.EvalCapture)...)
+	errs = append(errs, validateFeedback(prefix+".feedback", p.Feedback)...)
+
+	if p.BM25.K1 != nil {
+		k1 := *p.BM25.K1
+		if k1 < 0.0 || k1 > MaxBM25K1 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".bm25.k1",
+				Message: fmt.Sprintf("must be between 0.0 and %.1f", MaxBM25K1),
+			})
+		}
+	}
+
+	if p.BM25.B != nil {
+		b := *p.BM25.B
+		if b < 0.0 || b > 1.0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".bm25.b",
+				Message: "must be between 0.0 and 1.0",
+			})
+		}
+	}
+
+	if p.BM25.ProximityWeight != nil {
+		w := *p.BM25.ProximityWeight
+		if w < 0.0 || w > MaxProximityWeight {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".bm25.proximity_weight",
+				Message: fmt.Sprintf("must be between 0.0 and %.1f", MaxProximityWeight),
+			})
+		}
+	}
+
+	if p.BM25.FuzzySimilarity != nil {
+		s := *p.BM25.FuzzySimilarity
+		if s <= 0.0 || s > 1.0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".bm25.fuzzy_similarity",
+				Message: "must be greater than 0.0 and at most 1.0",
+			})
+		}
+	}
+
+	if p.BM25.MaxRows < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".bm25.max_rows",
+			Message: "must be non-negative",
+		})
+	}
+
+	if p.BM25.MaxBytes < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".bm25.max_bytes",
+			Message: "must be non-negative",
+		})
+	}
+
+	errs = append(errs, validateDocumentCache(prefix+".bm25.cache", p.BM25.Cache)...)
+
+	// Rerank config validation (optional; disabled unless provider is set)
+	errs = append(errs, c.validateRerank(prefix+".rerank", p.Rerank)...)
+
+	return errs
+}
+
+// validateRerank validates the optional rerank configuration. Leaving
+// Provider empty disables the stage, so no fields are required in that
+// case. When Provider is set, it reuses validateLLMOptional's
+// provider/model/timeout checks restricted to the providers that
+// actually implement Client.Rerank (currently Voyage and Cohere).
+func (c *Config) validateRerank(prefix string, r RerankConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	errs = append(errs, c.validateLLMOptional(prefix, LLMConfig{
+		Provider:          r.Provider,
+		Model:             r.Model,
+		BaseURL:           r.BaseURL,
+		Headers:           r.Headers,
+		CAFile:            r.CAFile,
+		RequestTimeout:    r.RequestTimeout,
+		PerAttemptTimeout: r.PerAttemptTimeout,
+		Retry:             r.Retry,
+		Pricing:           r.Pricing,
+	}, []string{"voyage", "cohere"})...)
+
+	if r.TopK < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".top_k",
+			Message: "must be non-negative",
+		})
+	}
+
+	return errs
+}
+
+// validateDatabasePassword checks that at most one source of the
+// database password is set, and that the one set can be read.
+func validateDatabasePassword(prefix string, db DatabaseConfig) ValidationErrors {
+	set := 0
+	for _, v := range []string{db.Password, db.PasswordEnv, db.PasswordFile} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return ValidationErrors{{
+			Field:   prefix,
+			Message: "set at most one of 'password', 'password_env' and 'password_file'",
+		}}
+	}
+	if db.PasswordEnv != "" {
+		if _, ok := os.LookupEnv(db.PasswordEnv); !ok {
+			return ValidationErrors{{
+				Field:   prefix + ".password_env",
+				Message: fmt.Sprintf("environment variable %s is not set", db.PasswordEnv),
+			}}
+		}
+	}
+	return validateSecretFile(prefix+".password_file", db.PasswordFile, false)
+}
+
+// validateDatabaseURL validates a database configured by a connection
+// URL, which may not be combined with the connection fields it
+// replaces.
+func validateDatabaseURL(prefix string, db DatabaseConfig) ValidationErrors {
+	var errs ValidationErrors
+	fields := []struct {
+		name string
+		set  bool
+	}{
+		{"host", db.Host != ""},
+		{"hosts", len(db.Hosts) > 0},
+		{"port", db.Port != 0},
+		{"database", db.Database != ""},
+		{"username", db.Username != ""},
+		{"ssl_mode", db.SSLMode != ""},
+		{"ssl_cert", db.SSLCert != ""},
+		{"ssl_key", db.SSLKey != ""},
+		{"ssl_root_ca", db.SSLRootCA != ""},
+		{"target_session_attrs", db.TargetSessionAttrs != ""},
+	}
+	for _, f := range fields {
+		if f.set {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + f.name,
+				Message: "cannot be combined with 'url'; set it in the URL instead",
+			})
+		}
+	}
+	// Keyword/value connection strings are checked when connecting.
+	if strings.Contains(db.URL, "://") {
+		// url.Parse's errors quote the URL, password and all.
+		if u, err := url.Parse(db.URL); err != nil {
+			errs = append(errs, ValidationError{Field: prefix + ".url", Message: "invalid connection URL"})
+		} else if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".url",
+				Message: "must be a postgres:// or postgresql:// URL",
+			})
+		}
+	}
+	return errs
+}
+
+// validateDatabase validates database configuration.
+func (c *Config) validateDatabase(prefix string, db DatabaseConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	errs = append(errs, validateDatabasePassword(prefix, db)...)
+	if db.URL != "" {
+		return append(errs, validateDatabaseURL(prefix, db)...)
+	}
+
+	// Mutual exclusion: hosts and host cannot both be set
+	if len(db.Hosts) > 0 && db.Host != "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix,
+			Message: "cannot specify both 'hosts' and 'host'; use one or the other",
+		})
+		return errs
+	}
+
+	if len(db.Hosts) > 0 {
+		// Multi-host validation
+		for i, h := range db.Hosts {
+			entryPrefix := fmt.Sprintf("%s.hosts[%d]", prefix, i)
+			if h.Host == "" {
+				errs = append(errs, ValidationError{
+					Field:   entryPrefix + ".host",
+					Message: "required",
+				})
+			} else {
+				errs = append(errs, validateHostValue(entryPrefix+".host", h.Host)...)
+			}
+			if h.Port < 1 || h.Port > 65535 {
+				errs = append(errs, ValidationError{
+					Field:   entryPrefix + ".port",
+					Message: "must be between 1 and 65535",
+				})
+			}
+		}
+	} else {
+		// Legacy single-host validation
+		if db.Host == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".host",
+				Message: "required",
+			})
+		} else {
+			errs = append(errs, validateHostValue(prefix+".host", db.Host)...)
+		}
+
+		if db.Port < 1 || db.Port > 65535 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".port",
+				Message: "must be between 1 and 65535",
+			})
+		}
+	}
+
+	if db.Database == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".database",
+			Message: "required",
+		})
+	}
+
+	// Validate target_session_attrs
+	if db.TargetSessionAttrs != "" {
+		if len(db.Hosts) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".target_session_attrs",
+				Message: "only supported with multi-host 'hosts' configuration",
+			})
+		} else {
+			validTSA := map[string]bool{
+				"any":            true,
+				"read-write":     true,
+				"read-only":      true,
+				"primary":        true,
+				"standby":        true,
+				"prefer-standby": true,
+			}
+			if !validTSA[db.TargetSessionAttrs] {
+				errs = append(errs, ValidationError{
+					Field:   prefix + ".target_session_attrs",
+					Message: "must be one of: any, read-write, read-only, primary, standby, prefer-standby",
+				})
+			}
+		}
+	}
+
+	// Validate SSL mode
+	validSSLModes := map[string]bool{
+		"disable":     true,
+		"allow":       true,
+		"prefer":      true,
+		"require":     true,
+		"verify-ca":   true,
+		"verify-full": true,
+	}
+	if db.SSLMode != "" && !validSSLModes[db.SSLMode] {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".ssl_mode",
+			Message: "must be one of: disable, allow, prefer, require, verify-ca, verify-full",
+		})
+	}
+
+	return errs
+}
+
+// validateTable validates a table source configuration.
+func (c *Config) validateTable(prefix string, ts TableSource) ValidationErrors {
+	var errs ValidationErrors
+
+	if ts.Table == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".table",
+			Message: "required",
+		})
+	}
+
+	if ts.TextColumn == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".text_column",
+			Message: "required",
+		})
+	}
+
+	if ts.VectorColumn == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".vector_column",
+			Message: "required",
+		})
+	}
+
+	seen := make(map[string]bool, len(ts.MetadataColumns))
+	for i, col := range ts.MetadataColumns {
+		field := fmt.Sprintf("%s.metadata_columns[%d]", prefix, i)
+		switch {
+		case col == "":
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must not be empty",
+			})
+		case seen[col]:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("duplicate column %q", col),
+			})
+		}
+		seen[col] = true
+	}
+
+	lexical := make(map[string]bool, len(ts.LexicalColumns))
+	for i, lc := range ts.LexicalColumns {
+		field := fmt.Sprintf("%s.lexical_columns[%d]", prefix, i)
+		switch {
+		case lc.Column == "":
+			errs = append(errs, ValidationError{
+				Field:   field + ".column",
+				Message: "required",
+			})
+		case lc.Column == ts.TextColumn || lexical[lc.Column]:
+			errs = append(errs, ValidationError{
+				Field:   field + ".column",
+				Message: fmt.Sprintf("column %q is already indexed", lc.Column),
+			})
+		}
+		lexical[lc.Column] = true
+		if lc.Boost < 0 || lc.Boost > MaxLexicalBoost {
+			errs = append(errs, ValidationError{
+				Field:   field + ".boost",
+				Message: fmt.Sprintf("must be between 0.0 and %.1f", MaxLexicalBoost),
+			})
+		}
+	}
+
+	if ts.Filter != nil && ts.Filter.RawSQL != "" {
+		// Variables become query parameters, so they are checked as one.
+		sql := ExpandFilterVariables(ts.Filter.RawSQL, func(FilterVariable) string { return "NULL" })
+		errs = append(errs, validateRawFilter(prefix+".filter", sql)...)
+	}
+	errs = append(errs, c.validateFilterVariables(prefix+".filter", ts.Filter)...)
+
+	errs = append(errs, validateLexicalSearch(prefix, ts)...)
+	errs = append(errs, validateRescore(prefix+".rescore", ts)...)
+	errs = append(errs, validateVectorColumn(prefix, ts)...)
+	errs = append(errs, validateVectorSearch(prefix+".vector_search", ts.VectorSearch)...)
+	errs = append(errs, validateParent(prefix+".parent", ts)...)
+
+	return errs
+}
+
+// validateParent validates a table's parent expansion settings: a
+// parent table with its text column, or a number of siblings, and the
+// chunk columns the expansion reads, which the table must return as
+// metadata.
+func validateParent(prefix string, ts TableSource) ValidationErrors {
+	p := ts.Parent
+	if p == (ParentConfig{}) {
+		return nil
+	}
+
+	if !p.Enabled() {
+		return ValidationErrors{{
+			Field:   prefix + ".parent_id_column",
+			Message: "required",
+		}}
+	}
+
+	var errs ValidationErrors
+	columns := []struct{ field, column string }{{"parent_id_column", p.ParentIDColumn}}
+	switch {
+	case p.Table != "" && p.Siblings != 0:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".siblings",
+			Message: "cannot be combined with table",
+		})
+	case p.Table != "":
+		if p.TextColumn == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".text_column",
+				Message: "required",
+			})
+		}
+	case p.Siblings < 0:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".siblings",
+			Message: "must be non-negative",
+		})
+	case p.Siblings == 0:
+		errs = append(errs, ValidationError{
+			Field:   prefix,
+			Message: "requires table or siblings",
+		})
+	default:
+		if p.IndexColumn == p.ParentIDColumn {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".index_column",
+				Message: "must differ from parent_id_column",
+			})
+		}
+		columns = append(columns, struct{ field, column string }{"index_column", p.IndexColumn})
+	}
+	for _, c := range columns {
+		if c.column != "" && !slices.Contains(ts.MetadataColumns, c.column) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + c.field,
+				Message: fmt.Sprintf("column %q must be one of the table's metadata_columns", c.column),
+			})
+		}
+	}
+	return errs
+}
+
+// validateRescore validates a table's two-stage vector search settings.
+func validateRescore(prefix string, ts TableSource) ValidationErrors {
+	r := ts.Rescore
+	if r == (RescoreConfig{}) {
+		return nil
+	}
+
+	var errs ValidationErrors
+	switch r.Column {
+	case "":
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".column",
+			Message: "required",
+		})
+	case ts.VectorColumn:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".column",
+			Message: "must differ from vector_column, which holds the quantized embeddings",
+		})
+	}
+	if r.Quantization != QuantizationHalfvec && r.Quantization != QuantizationBinary {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".quantization",
+			Message: fmt.Sprintf("must be %q or %q", QuantizationHalfvec, QuantizationBinary),
+		})
+	}
+	if r.Candidates < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".candidates",
+			Message: "must be non-negative",
+		})
+	}
+	return errs
+}
+
+// validateVectorColumn validates the distance metric and type of a
+// table's vector column.
+func validateVectorColumn(prefix string, ts TableSource) ValidationErrors {
+	var errs ValidationErrors
+	switch ts.Distance {
+	case "", DistanceCosine, DistanceL2, DistanceInnerProduct:
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".distance",
+			Message: fmt.Sprintf("must be %q, %q or %q",
+				DistanceCosine, DistanceL2, DistanceInnerProduct),
+		})
+	}
+	switch ts.VectorType {
+	case "", VectorTypeVector:
+	case VectorTypeHalfvec, VectorTypeSparsevec:
+		if ts.Rescore.Enabled() {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".vector_type",
+				Message: "cannot be combined with rescore, whose quantization sets the type of vector_column",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".vector_type",
+			Message: fmt.Sprintf("must be %q, %q or %q",
+				VectorTypeVector, VectorTypeHalfvec, VectorTypeSparsevec),
+		})
+	}
+	return errs
+}
+
+// validateVectorSearch validates a table's pgvector index settings
+// against the ranges pgvector accepts.
+func validateVectorSearch(prefix string, o VectorSearchOptions) ValidationErrors {
+	var errs ValidationErrors
+	settings := []struct {
+		field      string
+		value, max int
+	}{
+		{"probes", o.Probes, MaxIVFFlatProbes},
+		{"ef_search", o.EfSearch, MaxHNSWEfSearch},
+	}
+	for _, s := range settings {
+		if s.value < 0 || s.value > s.max {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + s.field,
+				Message: fmt.Sprintf("must be between 0 and %d", s.max),
+			})
+		}
+	}
+	return errs
+}
+
+// textSearchConfigRe matches a text search configuration name,
+// optionally schema-qualified.
+var textSearchConfigRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// validateLexicalSearch validates a table's lexical search backend and
+// the settings that only apply to postgres_fts.
+func validateLexicalSearch(prefix string, ts TableSource) ValidationErrors {
+	var errs ValidationErrors
+
+	switch ts.LexicalSearch {
+	case "", LexicalSearchBM25:
+		if ts.TSVectorColumn != "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".tsvector_column",
+				Message: `only used with lexical_search "postgres_fts"`,
+			})
+		}
+		if ts.TextSearchConfig != "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".text_search_config",
+				Message: `only used with lexical_search "postgres_fts"`,
+			})
+		}
+	case LexicalSearchPostgresFTS:
+		if len(ts.LexicalColumns) > 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".lexical_columns",
+				Message: `only indexed by lexical_search "bm25"; weight extra columns in a tsvector_column instead`,
+			})
+		}
+		if ts.TextSearchConfig != "" && !textSearchConfigRe.MatchString(ts.TextSearchConfig) {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".text_search_config",
+				Message: "must be a text search configuration name, such as english or public.my_config",
+			})
+		}
+	default:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".lexical_search",
+			Message: `must be "bm25" or "postgres_fts"`,
+		})
+	}
+
+	return errs
+}
+
+// validateLLM validates LLM configuration (required fields).
+func (c *Config) validateLLM(prefix string, llm LLMConfig, validProviders []string) ValidationErrors {
+	var errs ValidationErrors
+
+	if llm.Provider == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".provider",
+			Message: "required",
+		})
+	} else {
+		provider := strings.ToLower(llm.Provider)
+		valid := false
+		for _, vp := range validProviders {
+			if provider == vp {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".provider",
+				Message: fmt.Sprintf("must be one of: %s", strings.Join(validProviders, ", ")),
+			})
+		}
+	}
+
+	if llm.Model == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".model",
+			Message: "required",
+		})
+	}
+	if strings.ToLower(llm.Provider) == "openai-compatible" && llm.BaseURL == "" {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".base_url",
+			Message: "required by the openai-compatible provider",
+		})
+	}
+
+	errs = append(errs, validateLLMTimeouts(prefix, llm)...)
+	errs = append(errs, validateCAFile(prefix+".ca_file", llm.CAFile)...)
+
+	if llm.Pricing.InputPerMillion < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".pricing.input_per_million",
+			Message: "must not be negative",
+		})
+	}
+	if llm.Pricing.OutputPerMillion < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".pricing.output_per_million",
+			Message: "must not be negative",
+		})
+	}
+
+	return errs
+}
+
+// validateLLMTimeouts checks the relationship between the optional
+// timeout fields: a per-attempt timeout only makes sense when it leaves
+// room for retries within the overall request budget, so it must not
+// exceed a request timeout set alongside it. Negative values are
+// rejected.
+func validateLLMTimeouts(prefix string, llm LLMConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	for _, t := range []struct {
+		field   string
+		timeout Duration
+	}{
+		{"request_timeout", llm.RequestTimeout},
+		{"embedding_request_timeout", llm.EmbeddingRequestTimeout},
+		{"completion_request_timeout", llm.CompletionRequestTimeout},
+		{"per_attempt_timeout", llm.PerAttemptTimeout},
+	} {
+		if t.timeout < 0 {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + t.field,
+				Message: "must not be negative",
+			})
+			continue
+		}
+		if t.field != "per_attempt_timeout" && t.timeout > 0 && llm.PerAttemptTimeout > t.timeout {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".per_attempt_timeout",
+				Message: "must not exceed " + t.field,
+			})
+		}
+	}
+
+	errs = append(errs, validateRetry(prefix+".retry", llm.Retry)...)
+
+	return errs
+}
+
+// validateRetry checks the retry settings' ranges and that the initial
+// backoff does not exceed the maximum when both are set.
+func validateRetry(prefix string, r RetryConfig) ValidationErrors {
+	var errs ValidationErrors
+
+	if r.MaxRetries != nil && (*r.MaxRetries < 0 || *r.MaxRetries > MaxRetries) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_retries",
+			Message: fmt.Sprintf("must be between 0 and %d", MaxRetries),
+		})
+	}
+	if r.InitialBackoff < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".initial_backoff",
+			Message: "must not be negative",
+		})
+	}
+	if r.MaxBackoff < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_backoff",
+			Message: "must not be negative",
+		})
+	}
+	if r.InitialBackoff > 0 && r.MaxBackoff > 0 && r.InitialBackoff > r.MaxBackoff {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".initial_backoff",
+			Message: "must not exceed max_backoff",
+		})
+	}
+	if r.Jitter != nil && (*r.Jitter < 0 || *r.Jitter > 1) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".jitter",
+			Message: "must be between 0.0 and 1.0",
+		})
+	}
+
+	return errs
+}
+
+// validateCircuitBreaker rejects negative circuit breaker settings;
+// zero values select the defaults.
+func validateCircuitBreaker(prefix string, cb CircuitBreakerConfig) ValidationErrors {
+	var errs ValidationErrors
+	if cb.FailureThreshold < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".failure_threshold",
+			Message: "must be non-negative",
+		})
+	}
+	if cb.Cooldown < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".cooldown",
+			Message: "must be non-negative",
+		})
+	}
+	return errs
+}
+
+// validateStageTimeouts rejects negative pipeline timeouts, and stage
+// timeouts longer than total_timeout, which could never fire.
+func validateStageTimeouts(prefix string, p Pipeline) ValidationErrors {
+	var errs ValidationErrors
+	for _, t := range []struct {
+		name    string
+		timeout Duration
+	}{
+		{"embedding_timeout", p.EmbeddingTimeout},
+		{"search_timeout", p.SearchTimeout},
+		{"completion_timeout", p.CompletionTimeout},
+		{"total_timeout", p.TotalTimeout},
+	} {
+		switch {
+		case t.timeout < 0:
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + t.name,
+				Message: "must be non-negative",
+			})
+		case p.TotalTimeout > 0 && t.timeout > p.TotalTimeout:
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + t.name,
+				Message: "must not exceed total_timeout",
+			})
+		}
+	}
+	return errs
+}
+
+// validateRetrieval checks the retrieval strategy and its settings;
+// zero num_queries and max_iterations take the defaults.
+func validateRetrieval(prefix string, r RetrievalConfig) ValidationErrors {
+	var errs ValidationErrors
+	switch r.Strategy {
+	case "", RetrievalStrategySingle, RetrievalStrategyMultiQuery, RetrievalStrategyHyDE,
+		RetrievalStrategyIterative:
+	default:
+		errs = append(errs, ValidationError{
+			Field: prefix + ".strategy",
+			Message: fmt.Sprintf("must be %q, %q, %q or %q", RetrievalStrategySingle,
+				RetrievalStrategyMultiQuery, RetrievalStrategyHyDE, RetrievalStrategyIterative),
+		})
+	}
+	if r.NumQueries < 0 || r.NumQueries > MaxNumQueries {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".num_queries",
+			Message: fmt.Sprintf("must be between 0 and %d", MaxNumQueries),
+		})
+	}
+	if r.HyDEWeight != nil && (*r.HyDEWeight < 0.0 || *r.HyDEWeight > 1.0) {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".hyde_weight",
+			Message: "must be between 0.0 and 1.0",
+		})
+	}
+	if r.MaxIterations < 0 || r.MaxIterations > MaxIterations {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_iterations",
+			Message: fmt.Sprintf("must be between 0 and %d", MaxIterations),
+		})
+	}
+	if r.MaxIterationTokens < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_iteration_tokens",
+			Message: "must not be negative",
+		})
+	}
+	return errs
+}
+
+// validateIngest checks the document ingestion settings of a pipeline
+// that enables it.
+func validateIngest(prefix string, p Pipeline) ValidationErrors {
+	in := p.Ingest
+	if !in.Enabled {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if _, ok := p.IngestTable(); !ok && len(p.Tables) > 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".table",
+			Message: fmt.Sprintf("must be one of the pipeline's tables, got %q", in.Table),
+		})
+	}
+	if in.ChunkTokens < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".chunk_tokens",
+			Message: "must be non-negative",
+		})
+	}
+	if in.ChunkOverlap < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".chunk_overlap",
+			Message: "must be non-negative",
+		})
+	} else if in.ChunkTokens > 0 && in.ChunkOverlap >= in.ChunkTokens {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".chunk_overlap",
+			Message: "must be less than chunk_tokens",
+		})
+	}
+	if in.FilenameColumn != "" && in.FilenameColumn == in.PageColumn {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".page_column",
+			Message: "must differ from filename_column",
+		})
+	}
+	if in.MaxUploadBytes < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_upload_bytes",
+			Message: "must be non-negative",
+		})
+	}
+	return errs
+}
+
+// validateAdmin validates the admin endpoints' configuration. A
+// dedicated listener must not collide with the API or metrics listener,
+// and sharing the API listener requires a token.
+func (c *Config) validateAdmin() ValidationErrors {
+	var errs ValidationErrors
+	a := c.Server.Admin
+	m := c.Server.Metrics
+
+	switch {
+	case a.Port < 0 || a.Port > 65535:
+		errs = append(errs, ValidationError{
+			Field:   "server.admin.port",
+			Message: "must be between 1 and 65535, or 0 to share the API listener",
+		})
+	case a.Port == 0:
+		if a.TokenFile == "" {
+			errs = append(errs, ValidationError{
+				Field:   "server.admin.token_file",
+				Message: "required when the admin endpoints share the API listener; set server.admin.port to serve them separately",
+			})
+		}
+	case a.Port == c.Server.Port && a.ListenAddress == c.Server.ListenAddress:
+		errs = append(errs, ValidationError{
+			Field:   "server.admin.port",
+			Message: "must differ from server.port; omit it to share the API listener",
+		})
+	case m.Enabled && a.Port == m.Port && a.ListenAddress == m.ListenAddress:
+		errs = append(errs, ValidationError{
+			Field:   "server.admin.port",
+			Message: "must differ from server.metrics.port",
+		})
+	}
+
+	errs = append(errs, validateSecretFile("server.admin.token_file", a.TokenFile, false)...)
+	return errs
+}
+
+// validateGRPC validates the gRPC listener, which must not collide with
+// the server's other listeners.
+func (c *Config) validateGRPC() ValidationErrors {
+	g := c.Server.GRPC
+	m := c.Server.Metrics
+	a := c.Server.Admin
+
+	var message string
+	switch {
+	case g.Port < 1 || g.Port > 65535:
+		message = "must be between 1 and 65535"
+	case g.Port == c.Server.Port && g.ListenAddress == c.Server.ListenAddress:
+		message = "must differ from server.port"
+	case m.Enabled && g.Port == m.Port && g.ListenAddress == m.ListenAddress:
+		message = "must differ from server.metrics.port"
+	case a.Enabled && g.Port == a.Port && g.ListenAddress == a.ListenAddress:
+		message = "must differ from server.admin.port"
+	default:
+		return nil
+	}
+	return ValidationErrors{{Field: "server.grpc.port", Message: message}}
+}
+
+// validateTraceHeaders checks the headers copied onto provider calls.
+// The caller's credentials, and the claims an authenticating proxy
+// vouches for, must not be passed on to a provider.
+func (c *Config) validateTraceHeaders() ValidationErrors {
+	var errs ValidationErrors
+	for i, name := range c.Server.TraceHeaders {
+		field := fmt.Sprintf("server.trace_headers[%d]", i)
+		switch {
+		case !headerNameRe.MatchString(name):
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must be a valid header name",
+			})
+		case slices.ContainsFunc([]string{"Authorization", "Proxy-Authorization", "Cookie", c.Server.ClaimsHeader},
+			func(h string) bool { return h != "" && strings.EqualFold(h, name) }):
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("must not forward the caller's credentials (%s)", name),
+			})
+		}
+	}
+	return errs
+}
+
+// validateServerRateLimit checks the server-wide rate limits, the key
+// callers are told apart by, and the limits of particular callers.
+func (c *Config) validateServerRateLimit() ValidationErrors {
+	rl := c.Server.RateLimit
+	errs := validateRateLimit("server.rate_limit", rl.RateLimitConfig)
+	if rl.Key != "" {
+		key := &ConfigFilter{RawSQL: rl.Key}
+		if len(key.Variables()) == 0 {
+			errs = append(errs, ValidationError{
+				Field:   "server.rate_limit.key",
+				Message: "must refer to the caller, as in \"{{header:X-API-Key}}\" or \"{{claim:sub}}\"",
+			})
+		}
+		errs = append(errs, c.validateFilterVariables("server.rate_limit.key", key)...)
+	} else if len(rl.Keys) > 0 {
+		errs = append(errs, ValidationError{
+			Field:   "server.rate_limit.keys",
+			Message: "requires server.rate_limit.key",
+		})
+	}
+
+	names := make([]string, 0, len(rl.Keys))
+	for name := range rl.Keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prefix := fmt.Sprintf("server.rate_limit.keys[%q]", name)
+		errs = append(errs, validateRateLimit(prefix, rl.Keys[name])...)
+		errs = append(errs, validateKeyLimit(prefix+".requests_per_minute",
+			rl.Keys[name].RequestsPerMinute, rl.RequestsPerMinute, "server.rate_limit.requests_per_minute")...)
+		errs = append(errs, validateKeyLimit(prefix+".tokens_per_minute",
+			rl.Keys[name].TokensPerMinute, rl.TokensPerMinute, "server.rate_limit.tokens_per_minute")...)
+	}
+	return errs
+}
+
+// validateKeyLimit checks that a caller's limit does not exceed the
+// server's: every request is held to the server's limits for its client
+// address as well, so a higher limit could never take effect.
+func validateKeyLimit(field string, limit, serverLimit int, serverField string) ValidationErrors {
+	if serverLimit > 0 && limit > serverLimit {
+		return ValidationErrors{{
+			Field: field,
+			Message: fmt.Sprintf("must not exceed %s (%d); keys can only lower the server's limits",
+				serverField, serverLimit),
+		}}
+	}
+	return nil
+}
+
+// validateGuardrails checks a pipeline's guardrails: each redaction
+// pattern must compile and match something, and each denylisted term
+// must be set.
+func validateGuardrails(prefix string, g GuardrailsConfig) ValidationErrors {
+	var errs ValidationErrors
+	for i, pattern := range g.RedactPatterns {
+		field := fmt.Sprintf("%s.redact_patterns[%d]", prefix, i)
+		re, err := regexp.Compile(pattern)
+		switch {
+		case err != nil:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("invalid regular expression: %v", err),
+			})
+		case re.MatchString(""):
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must not match empty text",
+			})
+		}
+	}
+	for i, term := range g.Denylist {
+		if strings.TrimSpace(term) == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.denylist[%d]", prefix, i),
+				Message: "must not be empty",
+			})
+		}
+	}
+	if g.MaxAnswerChars < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".max_answer_chars",
+			Message: "must be non-negative",
+		})
+	}
+	return errs
+}
+
+// validateTools checks a pipeline's built-in tools: each must be known
+// and named once, and every completion provider that may answer must
+// support tools. Zero max_rounds takes the default.
+func validateTools(prefix string, p Pipeline) ValidationErrors {
+	var errs ValidationErrors
+	seen := make(map[string]bool)
+	for i, name := range p.Tools.Builtin {
+		field := fmt.Sprintf("%s.tools.builtin[%d]", prefix, i)
+		switch {
+		case name != ToolSearch:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("must be %q", ToolSearch),
+			})
+		case seen[name]:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("duplicate tool: %s", name),
+			})
+		}
+		seen[name] = true
+	}
+	if p.Tools.MaxRounds < 0 || p.Tools.MaxRounds > MaxToolRounds {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".tools.max_rounds",
+			Message: fmt.Sprintf("must be between 0 and %d", MaxToolRounds),
+		})
+	}
+	if len(p.Tools.Builtin) == 0 {
+		return errs
+	}
+	providers := append([]LLMConfig{p.RAGLLM}, p.RAGLLMFallbacks...)
+	for i, llm := range providers {
+		provider := strings.ToLower(llm.Provider)
+		if provider != "bedrock" && provider != "cohere" {
+			continue
+		}
+		field := prefix + ".rag_llm"
+		if i > 0 {
+			field = fmt.Sprintf("%s.rag_llm_fallbacks[%d]", prefix, i-1)
+		}
+		errs = append(errs, ValidationError{
+			Field:   field + ".provider",
+			Message: fmt.Sprintf("tools are not supported by the %s provider", provider),
+		})
+	}
+	return errs
+}
+
+// validateBudget checks a pipeline's cost caps: they must not be
+// negative, and a pipeline with a cap needs a price to count against
+// it.
+func validateBudget(prefix string, p Pipeline) ValidationErrors {
+	var errs ValidationErrors
+	if p.Budget.Daily < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".daily",
+			Message: "must be non-negative",
+		})
+	}
+	if p.Budget.Monthly < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".monthly",
+			Message: "must be non-negative",
+		})
+	}
+	if p.Budget.Enabled() && !p.RAGLLM.Pricing.Priced() && !p.EmbeddingLLM.Pricing.Priced() &&
+		!p.Rerank.Pricing.Priced() {
+		errs = append(errs, ValidationError{
+			Field:   prefix,
+			Message: "requires pricing on rag_llm, embedding_llm or rerank",
+		})
+	}
+	return errs
+}
+
+// validateRateLimit checks a set of rate limits.
+func validateRateLimit(prefix string, rl RateLimitConfig) ValidationErrors {
+	var errs ValidationErrors
+	if rl.RequestsPerMinute < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".requests_per_minute",
+			Message: "must be non-negative",
+		})
+	}
+	if rl.TokensPerMinute < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".tokens_per_minute",
+			Message: "must be non-negative",
+		})
+	}
+	return errs
+}
+
+// validateChunkMerge checks the chunk merging settings of a pipeline
+// that enables it: both columns are required, and every table must
+// return them as metadata.
+func validateChunkMerge(prefix string, p Pipeline) ValidationErrors {
+	cm := p.ChunkMerge
+	if !cm.Enabled {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if cm.DocumentColumn != "" && cm.DocumentColumn == cm.IndexColumn {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".index_column",
+			Message: "must differ from document_column",
+		})
+	}
+	for _, c := range []struct{ field, column string }{
+		{"document_column", cm.DocumentColumn},
+		{"index_column", cm.IndexColumn},
+	} {
+		if c.column == "" {
+			errs = append(errs, ValidationError{
+				Field:   prefix + "." + c.field,
+				Message: "is required",
+			})
+			continue
+		}
+		for _, t := range p.Tables {
+			if !slices.Contains(t.MetadataColumns, c.column) {
+				errs = append(errs, ValidationError{
+					Field:   prefix + "." + c.field,
+					Message: fmt.Sprintf("column %q must be in the metadata_columns of table %s", c.column, t.Table),
+				})
+			}
+		}
+	}
+	return errs
+}
+
+// validateTenant checks the tenant isolation settings of a pipeline:
+// a column, and exactly one of a header or a claim to read the tenant
+// from.
+func (c *Config) validateTenant(field string, t TenantConfig) ValidationErrors {
+	if t == (TenantConfig{}) {
+		return nil
+	}
+
+	var errs ValidationErrors
+	if t.Column == "" {
+		errs = append(errs, ValidationError{
+			Field:   field + ".column",
+			Message: "is required",
+		})
+	}
+	switch {
+	case t.Header != "" && t.Claim != "":
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "header and claim are mutually exclusive",
+		})
+	case t.Header == "" && t.Claim == "":
+		errs = append(errs, ValidationError{
+			Field:   field,
+			Message: "requires a header or a claim naming the tenant",
+		})
+	case t.Header != "" && !headerNameRe.MatchString(t.Header):
+		errs = append(errs, ValidationError{
+			Field:   field + ".header",
+			Message: fmt.Sprintf("%q is not a valid header name", t.Header),
+		})
+	case t.Claim != "" && c.Server.ClaimsHeader == "":
+		errs = append(errs, ValidationError{
+			Field:   field + ".claim",
+			Message: "requires server.claims_header",
+		})
+	}
+	return errs
+}
+
+// sqlFunctionRe matches a function name, optionally schema-qualified.
+var sqlFunctionRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// validateAccessControl checks the authorization hook of a pipeline
+// that sets one. prefix is the pipeline's.
+func (c *Config) validateAccessControl(prefix string, p Pipeline) ValidationErrors {
+	ac := p.AccessControl
+	field := prefix + ".access_control"
+	if !ac.Enabled() {
+		if ac.Principal != "" {
+			return ValidationErrors{{
+				Field:   field,
+				Message: "requires function or url",
+			}}
+		}
+		return nil
+	}
 
-	// Token budget validation
-	if p.TokenBudget < 0 {
+	var errs ValidationErrors
+	if ac.Function != "" && ac.URL != "" {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".token_budget",
-			Message: "must be non-negative",
+			Field:   field,
+			Message: "function and url are mutually exclusive",
 		})
 	}
-
-	// Top N validation
-	if p.TopN < 0 {
+	if ac.Function != "" && !sqlFunctionRe.MatchString(ac.Function) {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".top_n",
-			Message: "must be non-negative",
+			Field:   field + ".function",
+			Message: "must be a function name, optionally schema-qualified",
 		})
 	}
-
-	// Search config validation
-	if p.Search.VectorWeight != nil {
-		w := *p.Search.VectorWeight
-		if w < 0.0 || w > 1.0 {
+	if ac.URL != "" {
+		if u, err := url.Parse(ac.URL); err != nil ||
+			(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
 			errs = append(errs, ValidationError{
-				Field:   prefix + ".search.vector_weight",
-				Message: "must be between 0.0 and 1.0",
+				Field:   field + ".url",
+				Message: "must be an http or https URL",
 			})
 		}
 	}
 
-	if p.Search.MinSimilarity != nil {
-		ms := *p.Search.MinSimilarity
-		if ms < 0.0 || ms > 1.0 {
+	principal := &ConfigFilter{RawSQL: ac.Principal}
+	if len(principal.Variables()) == 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".principal",
+			Message: "must refer to the caller, as in \"{{claim:sub}}\" or \"{{header:X-User}}\"",
+		})
+	}
+	errs = append(errs, c.validateFilterVariables(field+".principal", principal)...)
+
+	if ac.Timeout < 0 {
+		errs = append(errs, ValidationError{
+			Field:   field + ".timeout",
+			Message: "must be non-negative",
+		})
+	}
+	for i, t := range p.Tables {
+		if t.IDColumn == "" {
 			errs = append(errs, ValidationError{
-				Field:   prefix + ".search.min_similarity",
-				Message: "must be between 0.0 and 1.0",
+				Field:   fmt.Sprintf("%s.tables[%d].id_column", prefix, i),
+				Message: "required when access_control is set",
 			})
 		}
 	}
-
-	// Rerank config validation (optional; disabled unless provider is set)
-	errs = append(errs, c.validateRerank(prefix+".rerank", p.Rerank)...)
-
 	return errs
 }
 
-// validateRerank validates the optional rerank configuration. Leaving
-// Provider empty disables the stage, so no fields are required in that
-// case. When Provider is set, it reuses validateLLMOptional's
-// provider/model/timeout checks restricted to the providers that
-// actually implement Client.Rerank (currently only Voyage).
-func (c *Config) validateRerank(prefix string, r RerankConfig) ValidationErrors {
+// validateProviderPool rejects negative pool settings; zero values are
+// replaced by the defaults before validation runs.
+func validateProviderPool(prefix string, pp ProviderPoolConfig) ValidationErrors {
 	var errs ValidationErrors
-
-	errs = append(errs, c.validateLLMOptional(prefix, LLMConfig{
-		Provider:          r.Provider,
-		Model:             r.Model,
-		BaseURL:           r.BaseURL,
-		Headers:           r.Headers,
-		RequestTimeout:    r.RequestTimeout,
-		PerAttemptTimeout: r.PerAttemptTimeout,
-	}, []string{"voyage"})...)
-
-	if r.TopK < 0 {
+	if pp.MaxIdleConnsPerHost < 0 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".top_k",
+			Field:   prefix + ".max_idle_conns_per_host",
+			Message: "must be non-negative",
+		})
+	}
+	if pp.IdleConnTimeout < 0 {
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".idle_conn_timeout",
 			Message: "must be non-negative",
 		})
 	}
-
 	return errs
 }
 
-// validateDatabase validates database configuration.
-func (c *Config) validateDatabase(prefix string, db DatabaseConfig) ValidationErrors {
-	var errs ValidationErrors
+// localePattern loosely matches a BCP 47 language tag such as "en",
+// "de-DE" or "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
 
-	// Mutual exclusion: hosts and host cannot both be set
-	if len(db.Hosts) > 0 && db.Host != "" {
+// validateFormatting checks the formatting conventions against the
+// values the answer checks understand.
+func validateFormatting(prefix string, f FormattingConfig) ValidationErrors {
+	var errs ValidationErrors
+	if f.Locale != "" && !localePattern.MatchString(f.Locale) {
 		errs = append(errs, ValidationError{
-			Field:   prefix,
-			Message: "cannot specify both 'hosts' and 'host'; use one or the other",
+			Field:   prefix + ".locale",
+			Message: "must be a language tag such as en-GB or de-DE",
 		})
-		return errs
-	}
-
-	if len(db.Hosts) > 0 {
-		// Multi-host validation
-		for i, h := range db.Hosts {
-			entryPrefix := fmt.Sprintf("%s.hosts[%d]", prefix, i)
-			if h.Host == "" {
-				errs = append(errs, ValidationError{
-					Field:   entryPrefix + ".host",
-					Message: "required",
-				})
-			} else {
-				errs = append(errs, validateHostValue(entryPrefix+".host", h.Host)...)
-			}
-			if h.Port < 1 || h.Port > 65535 {
-				errs = append(errs, ValidationError{
-					Field:   entryPrefix + ".port",
-					Message: "must be between 1 and 65535",
-				})
-			}
-		}
-	} else {
-		// Legacy single-host validation
-		if db.Host == "" {
-			errs = append(errs, ValidationError{
-				Field:   prefix + ".host",
-				Message: "required",
-			})
-		} else {
-			errs = append(errs, validateHostValue(prefix+".host", db.Host)...)
-		}
-
-		if db.Port < 1 || db.Port > 65535 {
-			errs = append(errs, ValidationError{
-				Field:   prefix + ".port",
-				Message: "must be between 1 and 65535",
-			})
-		}
 	}
-
-	if db.Database == "" {
+	switch f.DateFormat {
+	case "", DateFormatISO, DateFormatDMY, DateFormatMDY:
+	default:
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".database",
-			Message: "required",
+			Field:   prefix + ".date_format",
+			Message: fmt.Sprintf("must be one of: %s, %s, %s", DateFormatISO, DateFormatDMY, DateFormatMDY),
 		})
 	}
-
-	// Validate target_session_attrs
-	if db.TargetSessionAttrs != "" {
-		if len(db.Hosts) == 0 {
-			errs = append(errs, ValidationError{
-				Field:   prefix + ".target_session_attrs",
-				Message: "only supported with multi-host 'hosts' configuration",
-			})
-		} else {
-			validTSA := map[string]bool{
-				"any":            true,
-				"read-write":     true,
-				"read-only":      true,
-				"primary":        true,
-				"standby":        true,
-				"prefer-standby": true,
-			}
-			if !validTSA[db.TargetSessionAttrs] {
-				errs = append(errs, ValidationError{
-					Field:   prefix + ".target_session_attrs",
-					Message: "must be one of: any, read-write, read-only, primary, standby, prefer-standby",
-				})
-			}
-		}
-	}
-
-	// Validate SSL mode
-	validSSLModes := map[string]bool{
-		"disable":     true,
-		"allow":       true,
-		"prefer":      true,
-		"require":     true,
-		"verify-ca":   true,
-		"verify-full": true,
+	switch f.DecimalSeparator {
+	case "", ".", ",":
+	default:
+		errs = append(errs, ValidationError{
+			Field:   prefix + ".decimal_separator",
+			Message: `must be "." or ","`,
+		})
 	}
-	if db.SSLMode != "" && !validSSLModes[db.SSLMode] {
+	switch f.Units {
+	case "", UnitsMetric, UnitsImperial:
+	default:
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".ssl_mode",
-			Message: "must be one of: disable, allow, prefer, require, verify-ca, verify-full",
+			Field:   prefix + ".units",
+			Message: fmt.Sprintf("must be one of: %s, %s", UnitsMetric, UnitsImperial),
 		})
 	}
-
 	return errs
 }
 
-// validateTable validates a table source configuration.
-func (c *Config) validateTable(prefix string, ts TableSource) ValidationErrors {
-	var errs ValidationErrors
+// validateHooks checks a pipeline's script hooks. The script itself is
+// compiled when the pipeline starts.
+func validateHooks(prefix string, h HooksConfig) ValidationErrors {
+	if h.Script == "" {
+		if h.Timeout != 0 || h.MaxSteps != 0 {
+			return ValidationErrors{{Field: prefix, Message: "requires script"}}
+		}
+		return nil
+	}
 
-	if ts.Table == "" {
+	var errs ValidationErrors
+	if _, err := os.Stat(ExpandPath(h.Script)); err != nil {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".table",
-			Message: "required",
+			Field:   prefix + ".script",
+			Message: fmt.Sprintf("file not found: %s", h.Script),
 		})
 	}
-
-	if ts.TextColumn == "" {
+	if h.Timeout < 0 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".text_column",
-			Message: "required",
+			Field:   prefix + ".timeout",
+			Message: "must be non-negative",
 		})
 	}
-
-	if ts.VectorColumn == "" {
+	if h.MaxSteps < 0 {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".vector_column",
-			Message: "required",
+			Field:   prefix + ".max_steps",
+			Message: "must be non-negative",
 		})
 	}
-
 	return errs
 }
 
-// validateLLM validates LLM configuration (required fields).
-func (c *Config) validateLLM(prefix string, llm LLMConfig, validProviders []string) ValidationErrors {
+// validateContextFormat checks a pipeline's context layout. The
+// heading must number the documents, which citations refer to, and the
+// source column must be returned with at least one table's documents.
+func validateContextFormat(prefix string, p Pipeline) ValidationErrors {
 	var errs ValidationErrors
-
-	if llm.Provider == "" {
+	f := p.ContextFormat
+	switch f.Style {
+	case "", ContextStyleText, ContextStyleXML, ContextStyleJSON:
+	default:
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".provider",
-			Message: "required",
+			Field:   prefix + ".style",
+			Message: fmt.Sprintf("must be one of: %s, %s, %s", ContextStyleText, ContextStyleXML, ContextStyleJSON),
 		})
-	} else {
-		provider := strings.ToLower(llm.Provider)
-		valid := false
-		for _, vp := range validProviders {
-			if provider == vp {
-				valid = true
-				break
-			}
-		}
-		if !valid {
+	}
+	if f.Heading != "" {
+		if f.Style != "" && f.Style != ContextStyleText {
 			errs = append(errs, ValidationError{
-				Field:   prefix + ".provider",
-				Message: fmt.Sprintf("must be one of: %s", strings.Join(validProviders, ", ")),
+				Field:   prefix + ".heading",
+				Message: "only applies to the text style",
+			})
+		} else if !strings.Contains(f.Heading, "{n}") || strings.Contains(f.Heading, "\n") {
+			errs = append(errs, ValidationError{
+				Field:   prefix + ".heading",
+				Message: "must be a single line containing {n}, the document number",
 			})
 		}
 	}
-
-	if llm.Model == "" {
+	if f.SourceColumn != "" && !slices.ContainsFunc(p.Tables, func(ts TableSource) bool {
+		return slices.Contains(ts.MetadataColumns, f.SourceColumn)
+	}) {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".model",
-			Message: "required",
+			Field:   prefix + ".source_column",
+			Message: "must be one of a table's metadata_columns",
 		})
 	}
-
-	errs = append(errs, validateLLMTimeouts(prefix, llm)...)
-
 	return errs
 }
 
-// validateLLMTimeouts checks the relationship between the two optional
-// timeout fields: a per-attempt timeout only makes sense when it leaves
-// room for retries within the overall request budget, so it must not
-// exceed RequestTimeout when both are set. Negative values are rejected.
-func validateLLMTimeouts(prefix string, llm LLMConfig) ValidationErrors {
+// validateGenerationControls checks stop_sequences and logit_bias on a
+// completion LLM. logit_bias is rejected for providers other than
+// OpenAI rather than silently dropped. An empty provider (possible in
+// defaults) defers the provider check to the pipelines that inherit it.
+func validateGenerationControls(prefix string, llm LLMConfig) ValidationErrors {
 	var errs ValidationErrors
 
-	if llm.RequestTimeout < 0 {
+	if len(llm.StopSequences) > MaxStopSequences {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".request_timeout",
-			Message: "must not be negative",
+			Field:   prefix + ".stop_sequences",
+			Message: fmt.Sprintf("must contain at most %d entries", MaxStopSequences),
 		})
 	}
-	if llm.PerAttemptTimeout < 0 {
-		errs = append(errs, ValidationError{
-			Field:   prefix + ".per_attempt_timeout",
-			Message: "must not be negative",
-		})
+	for i, seq := range llm.StopSequences {
+		if seq == "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.stop_sequences[%d]", prefix, i),
+				Message: "must not be empty",
+			})
+		}
 	}
 
-	if llm.RequestTimeout > 0 && llm.PerAttemptTimeout > llm.RequestTimeout {
+	if len(llm.LogitBias) == 0 {
+		return errs
+	}
+	if llm.Provider != "" && strings.ToLower(llm.Provider) != "openai" {
 		errs = append(errs, ValidationError{
-			Field:   prefix + ".per_attempt_timeout",
-			Message: "must not exceed request_timeout",
+			Field:   prefix + ".logit_bias",
+			Message: "only supported by the openai provider",
 		})
 	}
+	for _, token := range sortedMapKeys(llm.LogitBias) {
+		if msg := CheckLogitBiasEntry(token, llm.LogitBias[token]); msg != "" {
+			errs = append(errs, ValidationError{
+				Field:   fmt.Sprintf("%s.logit_bias[%s]", prefix, token),
+				Message: msg,
+			})
+		}
+	}
 
 	return errs
 }
 
+// CheckLogitBiasEntry validates a single logit_bias entry, returning a
+// description of the problem or "" if it is valid. Keys must be
+// non-negative integer token IDs.
+func CheckLogitBiasEntry(token string, bias int) string {
+	if n, err := strconv.Atoi(token); err != nil || n < 0 {
+		return "key must be a non-negative integer token ID"
+	}
+	if bias < -MaxLogitBias || bias > MaxLogitBias {
+		return fmt.Sprintf("must be between %d and %d", -MaxLogitBias, MaxLogitBias)
+	}
+	return ""
+}
+
+// CheckAnswerLength validates an answer_length preset, returning a
+// description of the problem or "" if it is valid. Empty means no
+// preset.
+func CheckAnswerLength(length string) string {
+	if _, ok := AnswerLengthMaxTokens[length]; length == "" || ok {
+		return ""
+	}
+	return fmt.Sprintf("must be one of: %s, %s, %s",
+		AnswerLengthShort, AnswerLengthMedium, AnswerLengthLong)
+}
+
+// sortedMapKeys returns m's keys in sorted order so validation errors
+// are reported deterministically.
+func sortedMapKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // validateLLMOptional validates LLM configuration when provider is set.
 // Unlike validateLLM, this doesn't require provider/model to be present,
 // but validates them if they are.
@@ -516,6 +2459,7 @@ func (c *Config) validateLLMOptional(prefix string, llm LLMConfig, validProvider
 	}
 
 	errs = append(errs, validateLLMTimeouts(prefix, llm)...)
+	errs = append(errs, validateCAFile(prefix+".ca_file", llm.CAFile)...)
 
 	return errs
 }
@@ -560,3 +2504,69 @@ func validateHostValue(field string, host string) ValidationErrors {
 
 	return errs
 }
+
+// validateEvalCapture checks a pipeline's eval capture settings: the
+// sample rate is a fraction, and each redaction pattern must compile
+// and match something.
+func validateEvalCapture(prefix string, ec EvalCaptureConfig) ValidationErrors {
+	var errs ValidationErrors
+	if ec.SampleRate < 0 || ec.SampleRate > 1 {
+		errs = append(errs, ValidationError{Field: prefix + ".sample_rate", Message: "must be between 0.0 and 1.0"})
+	}
+	if ec.MaxEntries < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".max_entries", Message: "must be non-negative"})
+	}
+	for i, pattern := range ec.RedactPatterns {
+		field := fmt.Sprintf("%s.redact_patterns[%d]", prefix, i)
+		re, err := regexp.Compile(pattern)
+		switch {
+		case err != nil:
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: fmt.Sprintf("invalid regular expression: %v", err),
+			})
+		case re.MatchString(""):
+			errs = append(errs, ValidationError{
+				Field:   field,
+				Message: "must not match empty text",
+			})
+		}
+	}
+	return errs
+}
+
+// validateFeedback checks a pipeline's feedback settings.
+func validateFeedback(prefix string, fb FeedbackConfig) ValidationErrors {
+	if fb.MaxResponses < 0 {
+		return ValidationErrors{{Field: prefix + ".max_responses", Message: "must be non-negative"}}
+	}
+	return nil
+}
+
+// validateDocumentCache checks a pipeline's BM25 document cache
+// settings.
+func validateDocumentCache(prefix string, dc DocumentCacheConfig) ValidationErrors {
+	var errs ValidationErrors
+	if dc.TTL < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".ttl", Message: "must be non-negative"})
+	}
+	if dc.MaxStale < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".max_stale", Message: "must be non-negative"})
+	}
+	if dc.MaxEntries < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".max_entries", Message: "must be non-negative"})
+	}
+	return errs
+}
+
+// validateAnswerCache checks a pipeline's answer cache settings.
+func validateAnswerCache(prefix string, ac AnswerCacheConfig) ValidationErrors {
+	var errs ValidationErrors
+	if ac.TTL < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".ttl", Message: "must be non-negative"})
+	}
+	if ac.MaxEntries < 0 {
+		errs = append(errs, ValidationError{Field: prefix + ".max_entries", Message: "must be non-negative"})
+	}
+	return errs
+}
@@ -0,0 +1,52 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// buildVisibleDocumentsQuery constructs the call of an access control
+// function. Extracted for testability.
+//
+// Arg ordering: $1=principal, $2=table name, $3=document IDs.
+func buildVisibleDocumentsQuery(function string) string {
+	return fmt.Sprintf("SELECT * FROM %s($1::text, $2::text, $3::text[])",
+		parseTableIdentifier(function).Sanitize())
+}
+
+// VisibleDocuments calls an access control function with a principal,
+// a table and the IDs of documents retrieved from it, and returns the
+// IDs the function reports the principal may see.
+func (p *Pool) VisibleDocuments(
+	ctx context.Context,
+	function, principal, table string,
+	ids []string,
+) ([]string, error) {
+	rows, err := p.pool.Query(ctx, buildVisibleDocumentsQuery(function), principal, table, ids)
+	if err != nil {
+		return nil, fmt.Errorf("access control function %s failed: %w", function, err)
+	}
+	defer rows.Close()
+
+	var visible []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		visible = append(visible, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return visible, nil
+}
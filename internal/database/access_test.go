@@ -0,0 +1,25 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import "testing"
+
+func TestBuildVisibleDocumentsQuery(t *testing.T) {
+	tests := map[string]string{
+		"visible_docs":      `SELECT * FROM "visible_docs"($1::text, $2::text, $3::text[])`,
+		"acl.visible_docs":  `SELECT * FROM "acl"."visible_docs"($1::text, $2::text, $3::text[])`,
+		`bad"); DROP x; --`: `SELECT * FROM "bad""); DROP x; --"($1::text, $2::text, $3::text[])`,
+	}
+	for function, want := range tests {
+		if got := buildVisibleDocumentsQuery(function); got != want {
+			t.Errorf("%s: got %q, want %q", function, got, want)
+		}
+	}
+}
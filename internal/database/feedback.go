@@ -0,0 +1,83 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Feedback is a rating of one of a pipeline's answers, with the query
+// it answered, the IDs of the documents it was written from and the
+// model that wrote it.
+type Feedback struct {
+	ResponseID string
+	Pipeline   string
+	Rating     string
+	Comment    string
+	Query      string
+	Sources    []string
+	Provider   string
+	Model      string
+	AnsweredAt time.Time
+}
+
+// buildCreateFeedbackTableQuery constructs the statement creating a
+// feedback table. Extracted from CreateFeedbackTable for testability.
+func buildCreateFeedbackTableQuery(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		response_id text NOT NULL,
+		pipeline text NOT NULL,
+		rating text NOT NULL,
+		comment text NOT NULL DEFAULT '',
+		query text NOT NULL,
+		sources text[] NOT NULL DEFAULT '{}',
+		provider text NOT NULL DEFAULT '',
+		model text NOT NULL DEFAULT '',
+		answered_at timestamptz NOT NULL,
+		created_at timestamptz NOT NULL DEFAULT now()
+	)`, parseTableIdentifier(table).Sanitize())
+}
+
+// CreateFeedbackTable creates the table feedback is recorded in if it
+// does not already exist. table may be schema-qualified.
+func (p *Pool) CreateFeedbackTable(ctx context.Context, table string) error {
+	if _, err := p.pool.Exec(ctx, buildCreateFeedbackTableQuery(table)); err != nil {
+		return fmt.Errorf("failed to create feedback table %s: %w", table, err)
+	}
+	return nil
+}
+
+// buildInsertFeedbackQuery constructs the INSERT statement and argument
+// list recording fb. Extracted from InsertFeedback for testability.
+func buildInsertFeedbackQuery(table string, fb Feedback) (string, []interface{}) {
+	sources := fb.Sources
+	if sources == nil {
+		sources = []string{}
+	}
+	query := fmt.Sprintf(`INSERT INTO %s
+		(response_id, pipeline, rating, comment, query, sources, provider, model, answered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`, parseTableIdentifier(table).Sanitize())
+	return query, []interface{}{
+		fb.ResponseID, fb.Pipeline, fb.Rating, fb.Comment, fb.Query,
+		sources, fb.Provider, fb.Model, fb.AnsweredAt,
+	}
+}
+
+// InsertFeedback records fb as a new row of table.
+func (p *Pool) InsertFeedback(ctx context.Context, table string, fb Feedback) error {
+	query, args := buildInsertFeedbackQuery(table, fb)
+	if _, err := p.pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record feedback: %w", err)
+	}
+	return nil
+}
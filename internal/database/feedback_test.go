@@ -0,0 +1,45 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildCreateFeedbackTableQuery(t *testing.T) {
+	query := buildCreateFeedbackTableQuery("ops.rag_feedback")
+	if !strings.HasPrefix(query, `CREATE TABLE IF NOT EXISTS "ops"."rag_feedback" (`) {
+		t.Errorf("expected the schema-qualified table created if missing, got\n%s", query)
+	}
+}
+
+func TestBuildInsertFeedbackQuery(t *testing.T) {
+	answeredAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	query, args := buildInsertFeedbackQuery("rag_feedback", Feedback{
+		ResponseID: "r1",
+		Pipeline:   "docs",
+		Rating:     "down",
+		Query:      "How does replication work?",
+		Model:      "gpt-4o",
+		AnsweredAt: answeredAt,
+	})
+
+	if !strings.HasPrefix(query, `INSERT INTO "rag_feedback"`) {
+		t.Errorf("unexpected query\n%s", query)
+	}
+	want := []interface{}{"r1", "docs", "down", "", "How does replication work?",
+		[]string{}, "", "gpt-4o", answeredAt}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected args %v, got %v", want, args)
+	}
+}
@@ -10,6 +10,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -34,13 +35,111 @@ var supportedOperators = map[string]bool{
 	"IS NOT NULL": true,
 }
 
+// FilterVars looks up the value of a config filter variable, such as
+// {{header:X-Tenant}}, for the current request. It reports false when
+// the request does not set the variable.
+type FilterVars func(v config.FilterVariable) (string, bool)
+
+type filterVarsKey struct{}
+
+// WithFilterVars returns a context whose searches resolve config filter
+// variables with vars.
+func WithFilterVars(ctx context.Context, vars FilterVars) context.Context {
+	return context.WithValue(ctx, filterVarsKey{}, vars)
+}
+
+// FilterVarsFrom returns the filter variables set on ctx by
+// WithFilterVars, or nil if there are none.
+func FilterVarsFrom(ctx context.Context) FilterVars {
+	vars, _ := ctx.Value(filterVarsKey{}).(FilterVars)
+	return vars
+}
+
+// Lookup returns the value of a variable; a nil FilterVars sets none.
+func (vars FilterVars) Lookup(v config.FilterVariable) (string, bool) {
+	if vars == nil {
+		return "", false
+	}
+	return vars(v)
+}
+
+// expandRawFilter replaces each variable in a raw SQL filter with a
+// text parameter numbered from *paramIndex, returning the SQL and the
+// parameter values. A variable the request does not set is an error,
+// so a filter never silently matches every tenant's rows.
+func expandRawFilter(sql string, vars FilterVars, paramIndex *int) (string, []interface{}, error) {
+	var args []interface{}
+	var missing error
+	expanded := config.ExpandFilterVariables(sql, func(v config.FilterVariable) string {
+		value, ok := vars.Lookup(v)
+		if !ok && missing == nil {
+			missing = fmt.Errorf("filter variable %s is not set", v)
+		}
+		args = append(args, value)
+		placeholder := fmt.Sprintf("$%d::text", *paramIndex)
+		*paramIndex++
+		return placeholder
+	})
+	if missing != nil {
+		return "", nil, missing
+	}
+	return expanded, args, nil
+}
+
+// expandStructuredFilter returns a copy of a structured config filter
+// with the variables in its string values replaced by their values.
+func expandStructuredFilter(filter *config.Filter, vars FilterVars) (*config.Filter, error) {
+	var missing error
+	expand := func(s string) string {
+		return config.ExpandFilterVariables(s, func(v config.FilterVariable) string {
+			value, ok := vars.Lookup(v)
+			if !ok && missing == nil {
+				missing = fmt.Errorf("filter variable %s is not set", v)
+			}
+			return value
+		})
+	}
+
+	var expandFilter func(f *config.Filter) *config.Filter
+	expandFilter = func(f *config.Filter) *config.Filter {
+		expanded := &config.Filter{Logic: f.Logic, Conditions: make([]config.FilterCondition, len(f.Conditions))}
+		for i, cond := range f.Conditions {
+			switch v := cond.Value.(type) {
+			case string:
+				cond.Value = expand(v)
+			case []interface{}:
+				values := make([]interface{}, len(v))
+				for j, item := range v {
+					if s, ok := item.(string); ok {
+						item = expand(s)
+					}
+					values[j] = item
+				}
+				cond.Value = values
+			}
+			expanded.Conditions[i] = cond
+		}
+		for i := range f.Groups {
+			expanded.Groups = append(expanded.Groups, *expandFilter(&f.Groups[i]))
+		}
+		return expanded
+	}
+	expanded := expandFilter(filter)
+	if missing != nil {
+		return nil, missing
+	}
+	return expanded, nil
+}
+
 // buildFilterClause constructs a parameterized WHERE clause from config and request filters.
 // Returns the WHERE clause string, parameter values, and any error.
 // The WHERE clause uses PostgreSQL parameter placeholders starting from startParamIndex.
 //
-// Config filters can be raw SQL strings (admin-controlled, trusted) or structured filters.
+// Config filters can be raw SQL strings (admin-controlled, trusted), structured filters, or both.
 // Request filters must be structured filters (user input, parameterized for security).
-func buildFilterClause(configFilter *config.ConfigFilter, requestFilter *config.Filter, startParamIndex int) (string, []interface{}, error) {
+// Variables in config filters are resolved with vars; request filters
+// are never expanded.
+func buildFilterClause(configFilter *config.ConfigFilter, requestFilter *config.Filter, vars FilterVars, startParamIndex int) (string, []interface{}, error) {
 	var conditions []string
 	var args []interface{}
 	paramIndex := startParamIndex
@@ -49,9 +148,19 @@ func buildFilterClause(configFilter *config.ConfigFilter, requestFilter *config.
 	if configFilter != nil {
 		if configFilter.RawSQL != "" {
 			// Raw SQL from config file - admin controlled, trusted
-			conditions = append(conditions, "("+configFilter.RawSQL+")")
-		} else if configFilter.Structured != nil {
-			clause, clauseArgs, err := buildFilterFromStruct(configFilter.Structured, &paramIndex)
+			clause, clauseArgs, err := expandRawFilter(configFilter.RawSQL, vars, &paramIndex)
+			if err != nil {
+				return "", nil, fmt.Errorf("config filter error: %w", err)
+			}
+			conditions = append(conditions, "("+clause+")")
+			args = append(args, clauseArgs...)
+		}
+		if configFilter.Structured != nil {
+			structured, err := expandStructuredFilter(configFilter.Structured, vars)
+			if err != nil {
+				return "", nil, fmt.Errorf("config filter error: %w", err)
+			}
+			clause, clauseArgs, err := buildFilterFromStruct(structured, &paramIndex)
 			if err != nil {
 				return "", nil, fmt.Errorf("config filter error: %w", err)
 			}
@@ -81,10 +190,37 @@ func buildFilterClause(configFilter *config.ConfigFilter, requestFilter *config.
 	return " WHERE " + strings.Join(conditions, " AND "), args, nil
 }
 
+// buildCheckFilterQuery constructs the EXPLAIN statement CheckFilter
+// plans a table's raw SQL filter with. Filter variables are planned as
+// the text parameters they become. Extracted for testability.
+func buildCheckFilterQuery(table config.TableSource) string {
+	sql := config.ExpandFilterVariables(table.Filter.RawSQL,
+		func(config.FilterVariable) string { return "NULL::text" })
+	return fmt.Sprintf("EXPLAIN SELECT 1 FROM %s WHERE (%s)",
+		parseTableIdentifier(table.Table).Sanitize(), sql)
+}
+
+// CheckFilter has the database plan, without running, a query using a
+// table's raw SQL filter, so syntax errors and references to missing
+// tables, columns or functions are reported when the pipeline starts
+// rather than by its first query. The plan is made in a read-only
+// transaction. Tables without a raw SQL filter are not checked.
+func (p *Pool) CheckFilter(ctx context.Context, table config.TableSource) error {
+	if table.Filter == nil || table.Filter.RawSQL == "" {
+		return nil
+	}
+	return pgx.BeginTxFunc(ctx, p.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, buildCheckFilterQuery(table))
+		return err
+	})
+}
+
 // buildFilterFromStruct converts a Filter struct to SQL WHERE conditions.
+// Each group is built the same way and parenthesized, then joined with
+// the conditions by the filter's logic.
 // Returns the SQL string (without WHERE keyword), parameter values, and any error.
 func buildFilterFromStruct(filter *config.Filter, paramIndex *int) (string, []interface{}, error) {
-	if filter == nil || len(filter.Conditions) == 0 {
+	if filter == nil || (len(filter.Conditions) == 0 && len(filter.Groups) == 0) {
 		return "", nil, nil
 	}
 
@@ -96,7 +232,7 @@ func buildFilterFromStruct(filter *config.Filter, paramIndex *int) (string, []in
 		}
 	}
 
-	conditions := make([]string, 0, len(filter.Conditions))
+	conditions := make([]string, 0, len(filter.Conditions)+len(filter.Groups))
 	var args []interface{}
 
 	for _, cond := range filter.Conditions {
@@ -107,6 +243,20 @@ func buildFilterFromStruct(filter *config.Filter, paramIndex *int) (string, []in
 		conditions = append(conditions, clause)
 		args = append(args, clauseArgs...)
 	}
+	for i := range filter.Groups {
+		clause, clauseArgs, err := buildFilterFromStruct(&filter.Groups[i], paramIndex)
+		if err != nil {
+			return "", nil, err
+		}
+		if clause == "" {
+			continue
+		}
+		conditions = append(conditions, "("+clause+")")
+		args = append(args, clauseArgs...)
+	}
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
 
 	return strings.Join(conditions, " "+logic+" "), args, nil
 }
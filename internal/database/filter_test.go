@@ -10,6 +10,7 @@
 package database
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -92,6 +93,7 @@ func TestBuildVectorSearchQuery(t *testing.T) {
 				tt.table,
 				tt.topN,
 				tt.filter,
+				nil,
 				tt.minSimilarity,
 			)
 			if err != nil {
@@ -166,6 +168,51 @@ func TestBuildFilterClause(t *testing.T) {
 			expectedSQL:  " WHERE (\"status\" = $1 OR \"status\" = $2)",
 			expectedArgs: []interface{}{"published", "draft"},
 		},
+		{
+			name: "nested groups",
+			requestFilter: &config.Filter{
+				Conditions: []config.FilterCondition{
+					{Column: "product", Operator: "=", Value: "pgEdge"},
+				},
+				Groups: []config.Filter{
+					{
+						Conditions: []config.FilterCondition{
+							{Column: "status", Operator: "=", Value: "published"},
+						},
+						Groups: []config.Filter{{
+							Conditions: []config.FilterCondition{
+								{Column: "status", Operator: "=", Value: "draft"},
+								{Column: "owner", Operator: "=", Value: "jo"},
+							},
+						}},
+						Logic: "OR",
+					},
+				},
+			},
+			expectedSQL:  " WHERE (\"product\" = $1 AND (\"status\" = $2 OR (\"status\" = $3 AND \"owner\" = $4)))",
+			expectedArgs: []interface{}{"pgEdge", "published", "draft", "jo"},
+		},
+		{
+			name: "groups only",
+			requestFilter: &config.Filter{
+				Groups: []config.Filter{
+					{Conditions: []config.FilterCondition{{Column: "a", Operator: "=", Value: "x"}}},
+					{Conditions: []config.FilterCondition{{Column: "b", Operator: "=", Value: "y"}}},
+				},
+				Logic: "OR",
+			},
+			expectedSQL:  " WHERE ((\"a\" = $1) OR (\"b\" = $2))",
+			expectedArgs: []interface{}{"x", "y"},
+		},
+		{
+			name: "invalid operator in a group",
+			requestFilter: &config.Filter{
+				Groups: []config.Filter{
+					{Conditions: []config.FilterCondition{{Column: "a", Operator: "; DROP", Value: "x"}}},
+				},
+			},
+			expectError: true,
+		},
 		{
 			name: "IN operator",
 			requestFilter: &config.Filter{
@@ -335,7 +382,7 @@ func TestBuildFilterClause(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			sql, args, err := buildFilterClause(tt.configFilter, tt.requestFilter, 1)
+			sql, args, err := buildFilterClause(tt.configFilter, tt.requestFilter, nil, 1)
 			if tt.expectError {
 				if err == nil {
 					t.Errorf("expected error but got none")
@@ -366,6 +413,128 @@ func TestBuildFilterClause(t *testing.T) {
 	}
 }
 
+func TestBuildCheckFilterQuery(t *testing.T) {
+	table := config.TableSource{
+		Table:  "public.chunks",
+		Filter: &config.ConfigFilter{RawSQL: "status = 'published'"},
+	}
+	want := `EXPLAIN SELECT 1 FROM "public"."chunks" WHERE (status = 'published')`
+	if got := buildCheckFilterQuery(table); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	table.Filter.RawSQL = "tenant_id = {{header:X-Tenant}}::int"
+	want = `EXPLAIN SELECT 1 FROM "public"."chunks" WHERE (tenant_id = NULL::text::int)`
+	if got := buildCheckFilterQuery(table); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestBuildFilterClause_Variables(t *testing.T) {
+	vars := FilterVars(func(v config.FilterVariable) (string, bool) {
+		if v.Source == config.FilterVarHeader && v.Name == "X-Tenant" {
+			return "acme'; DROP TABLE docs", true
+		}
+		return "", false
+	})
+	request := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "title", Operator: "=", Value: "{{header:X-Tenant}}"},
+	}}
+
+	raw := &config.ConfigFilter{RawSQL: "tenant_id = {{header:X-Tenant}} OR owner = {{ header:X-Tenant }}"}
+	sql, args, err := buildFilterClause(raw, request, vars, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := ` WHERE (tenant_id = $3::text OR owner = $4::text) AND ("title" = $5)`
+	if sql != want {
+		t.Errorf("SQL mismatch:\nexpected: %q\ngot:      %q", want, sql)
+	}
+	// Request filters are never expanded.
+	if len(args) != 3 || args[0] != "acme'; DROP TABLE docs" || args[2] != "{{header:X-Tenant}}" {
+		t.Errorf("unexpected args: %v", args)
+	}
+
+	structured := &config.ConfigFilter{Structured: &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "tenant_id", Operator: "IN", Value: []interface{}{"{{header:X-Tenant}}", "shared"}},
+		{Column: "path", Operator: "LIKE", Value: "/{{header:X-Tenant}}/%"},
+	}}}
+	sql, args, err = buildFilterClause(structured, nil, vars, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = ` WHERE ("tenant_id" IN ($1, $2) AND "path" LIKE $3)`
+	if sql != want {
+		t.Errorf("SQL mismatch:\nexpected: %q\ngot:      %q", want, sql)
+	}
+	if len(args) != 3 || args[0] != "acme'; DROP TABLE docs" || args[2] != "/acme'; DROP TABLE docs/%" {
+		t.Errorf("unexpected args: %v", args)
+	}
+	if structured.Structured.Conditions[0].Value.([]interface{})[0] != "{{header:X-Tenant}}" {
+		t.Error("expected the configured filter to be left unchanged")
+	}
+
+	for _, cf := range []*config.ConfigFilter{
+		{RawSQL: "org_id = {{claim:org_id}}"},
+		{Structured: &config.Filter{Conditions: []config.FilterCondition{
+			{Column: "org_id", Operator: "=", Value: "{{claim:org_id}}"},
+		}}},
+	} {
+		if _, _, err := buildFilterClause(cf, nil, vars, 1); err == nil ||
+			!strings.Contains(err.Error(), "claim:org_id is not set") {
+			t.Errorf("expected an unset variable to be an error, got %v", err)
+		}
+		if _, _, err := buildFilterClause(cf, nil, nil, 1); err == nil {
+			t.Error("expected an error without filter variables")
+		}
+	}
+}
+
+func TestBuildFilterClause_TenantTables(t *testing.T) {
+	vars := FilterVars(func(v config.FilterVariable) (string, bool) {
+		return "acme", v.String() == "header:X-Tenant-ID"
+	})
+	p := config.Pipeline{
+		Tenant: config.TenantConfig{Column: "tenant_id", Header: "X-Tenant-ID"},
+		Tables: []config.TableSource{
+			{Table: "plain"},
+			{Table: "raw", Filter: &config.ConfigFilter{RawSQL: "status = 'published'"}},
+			{Table: "structured", Filter: &config.ConfigFilter{Structured: &config.Filter{
+				Logic: "OR",
+				Conditions: []config.FilterCondition{
+					{Column: "product", Operator: "=", Value: "pgedge"},
+					{Column: "product", Operator: "=", Value: "shared"},
+				},
+			}}},
+		},
+	}
+
+	want := []struct {
+		sql  string
+		args []interface{}
+	}{
+		{` WHERE ("tenant_id" = $1)`, []interface{}{"acme"}},
+		{` WHERE (status = 'published') AND ("tenant_id" = $1)`, []interface{}{"acme"}},
+		{` WHERE ("tenant_id" = $1 AND ("product" = $2 OR "product" = $3))`, []interface{}{"acme", "pgedge", "shared"}},
+	}
+	for i, table := range p.TenantTables() {
+		sql, args, err := buildFilterClause(table.Filter, nil, vars, 1)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", table.Table, err)
+		}
+		if sql != want[i].sql || !reflect.DeepEqual(args, want[i].args) {
+			t.Errorf("%s: got %q %v, want %q %v", table.Table, sql, args, want[i].sql, want[i].args)
+		}
+	}
+	if p.Tables[0].Filter != nil || p.Tables[2].Filter.Structured.Logic != "OR" {
+		t.Error("expected the configured tables to be left unchanged")
+	}
+
+	if _, _, err := buildFilterClause(p.TenantTables()[0].Filter, nil, nil, 1); err == nil {
+		t.Error("expected an error without the tenant")
+	}
+}
+
 func TestSQLInjectionPrevention(t *testing.T) {
 	injectionAttempts := []struct {
 		name  string
@@ -388,7 +557,7 @@ func TestSQLInjectionPrevention(t *testing.T) {
 				},
 			}
 
-			sql, args, err := buildFilterClause(nil, filter, 1)
+			sql, args, err := buildFilterClause(nil, filter, nil, 1)
 			if err != nil {
 				t.Errorf("unexpected error for injection attempt: %v", err)
 				return
@@ -504,7 +673,7 @@ func TestBuildFilterClauseWithOffset(t *testing.T) {
 	}
 
 	// Start at index 3 (simulating VectorSearch where $1=vector, $2=limit)
-	sql, args, err := buildFilterClause(nil, filter, 3)
+	sql, args, err := buildFilterClause(nil, filter, nil, 3)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
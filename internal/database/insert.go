@@ -0,0 +1,85 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// Chunk is a piece of an ingested document to store as a new row.
+type Chunk struct {
+	Content   string
+	Embedding []float32
+	Metadata  map[string]interface{} // Further column values, keyed by column name
+}
+
+// buildInsertChunkQuery constructs the INSERT statement and argument
+// list for one chunk. Extracted from InsertChunks for testability.
+//
+// Arg ordering: $1=content, $2=embedding; metadata columns follow in
+// column name order. A table searched in two stages is given the
+// full-precision embedding; its quantized column is expected to be
+// generated from it. Otherwise the embedding is cast to the table's
+// vector_type.
+func buildInsertChunkQuery(table config.TableSource, chunk Chunk) (string, []interface{}) {
+	vectorColumn, vectorValue := table.VectorColumn, "$2::vector"
+	if table.Rescore.Enabled() {
+		vectorColumn = table.Rescore.Column
+	} else if table.VectorType != "" && table.VectorType != config.VectorTypeVector {
+		vectorValue += "::" + table.VectorType
+	}
+	columns := []string{
+		pgx.Identifier{table.TextColumn}.Sanitize(),
+		pgx.Identifier{vectorColumn}.Sanitize(),
+	}
+	values := []string{"$1", vectorValue}
+	args := []interface{}{chunk.Content, formatVector(chunk.Embedding)}
+
+	names := make([]string, 0, len(chunk.Metadata))
+	for name := range chunk.Metadata {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		columns = append(columns, pgx.Identifier{name}.Sanitize())
+		args = append(args, chunk.Metadata[name])
+		values = append(values, fmt.Sprintf("$%d", len(args)))
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		parseTableIdentifier(table.Table).Sanitize(),
+		strings.Join(columns, ", "),
+		strings.Join(values, ", "),
+	)
+	return query, args
+}
+
+// InsertChunks stores chunks as new rows of table, in one transaction,
+// so a document is stored completely or not at all.
+func (p *Pool) InsertChunks(ctx context.Context, table config.TableSource, chunks []Chunk) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, chunk := range chunks {
+			query, args := buildInsertChunkQuery(table, chunk)
+			batch.Queue(query, args...)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return fmt.Errorf("failed to insert chunks: %w", err)
+		}
+		return nil
+	})
+}
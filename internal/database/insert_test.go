@@ -0,0 +1,71 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestBuildInsertChunkQuery(t *testing.T) {
+	table := config.TableSource{
+		Table:        "docs.chunks",
+		TextColumn:   "content",
+		VectorColumn: "embedding",
+	}
+	chunk := Chunk{
+		Content:   "Streaming replication sends WAL to a standby.",
+		Embedding: []float32{0.5, -1},
+		Metadata:  map[string]interface{}{"source": "guide.pdf", "page": 3},
+	}
+
+	query, args := buildInsertChunkQuery(table, chunk)
+
+	want := `INSERT INTO "docs"."chunks" ("content", "embedding", "page", "source") VALUES ($1, $2::vector, $3, $4)`
+	if query != want {
+		t.Errorf("got query\n%s\nwant\n%s", query, want)
+	}
+	if len(args) != 4 || args[0] != chunk.Content || args[1] != "[0.5,-1]" || args[2] != 3 || args[3] != "guide.pdf" {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildInsertChunkQuery_Rescore(t *testing.T) {
+	table := config.TableSource{
+		Table:        "chunks",
+		TextColumn:   "content",
+		VectorColumn: "embedding_bits",
+		Rescore:      config.RescoreConfig{Column: "embedding", Quantization: config.QuantizationBinary},
+	}
+
+	query, _ := buildInsertChunkQuery(table, Chunk{Content: "WAL", Embedding: []float32{1}})
+
+	want := `INSERT INTO "chunks" ("content", "embedding") VALUES ($1, $2::vector)`
+	if query != want {
+		t.Errorf("expected the full-precision column to be filled in, got\n%s", query)
+	}
+}
+
+func TestBuildInsertChunkQuery_VectorType(t *testing.T) {
+	table := config.TableSource{
+		Table:        "chunks",
+		TextColumn:   "content",
+		VectorColumn: "embedding",
+		VectorType:   config.VectorTypeHalfvec,
+	}
+
+	query, _ := buildInsertChunkQuery(table, Chunk{Content: "WAL", Embedding: []float32{1}})
+
+	want := `INSERT INTO "chunks" ("content", "embedding") VALUES ($1, $2::vector::halfvec)`
+	if query != want {
+		t.Errorf("expected the embedding cast to halfvec, got\n%s", query)
+	}
+}
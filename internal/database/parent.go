@@ -0,0 +1,208 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// ChunkPosition locates a chunk within its parent document.
+type ChunkPosition struct {
+	ParentID string
+	Index    int64
+}
+
+// Sibling is a chunk fetched to widen a match: its ID, position and
+// text.
+type Sibling struct {
+	ChunkPosition
+	ID      string
+	Content string
+}
+
+// buildFetchParentsQuery constructs the query for the parent rows of a
+// table's chunks. The parent's filter, such as the tenant condition,
+// and the request's filter apply to the parent table, so a parent is
+// only given when it matches the filter its chunk did. Extracted for
+// testability.
+//
+// Arg ordering: $1=parent IDs, as text; parent and request filter
+// parameters follow.
+func buildFetchParentsQuery(table config.TableSource, filter *config.Filter,
+	vars FilterVars) (string, []interface{}, error) {
+	parent := table.Parent
+	condition := fmt.Sprintf("%s::text = ANY($1::text[]) AND %s IS NOT NULL",
+		pgx.Identifier{parent.IDColumn}.Sanitize(),
+		pgx.Identifier{parent.TextColumn}.Sanitize())
+	filterClause, filterArgs, err := buildFilterClause(parent.Filter, filter, vars, 2)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	if filterClause == "" {
+		filterClause = " WHERE " + condition
+	} else {
+		filterClause += " AND " + condition
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s::text AS id,
+			%s AS content
+		FROM %s%s`,
+		pgx.Identifier{parent.IDColumn}.Sanitize(),
+		pgx.Identifier{parent.TextColumn}.Sanitize(),
+		parseTableIdentifier(parent.Table).Sanitize(),
+		filterClause,
+	)
+	return query, filterArgs, nil
+}
+
+// FetchParents fetches the text of the parent documents with the given
+// IDs from table's parent table, keyed by ID, keeping those that match
+// filter. IDs without a matching row are left out.
+func (p *Pool) FetchParents(
+	ctx context.Context,
+	table config.TableSource,
+	ids []string,
+	filter *config.Filter,
+) (map[string]string, error) {
+	parents := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return parents, nil
+	}
+
+	query, filterArgs, err := buildFetchParentsQuery(table, filter, FilterVarsFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	rows, err := p.pool.Query(ctx, query, append([]interface{}{ids}, filterArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch parent documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, content string
+		if err := rows.Scan(&id, &content); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		parents[id] = content
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return parents, nil
+}
+
+// buildFetchSiblingsQuery constructs the query for the chunks within
+// window positions of the given matches, in their parents, of a table.
+// The table's configured filter and the request's filter apply to them
+// as they do to search. Extracted for testability.
+//
+// Arg ordering: $1=parent IDs, $2=chunk indices, $3=window; config
+// and request filter parameters follow.
+func buildFetchSiblingsQuery(table config.TableSource, filter *config.Filter,
+	vars FilterVars) (string, []interface{}, error) {
+	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, vars, 4)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	// Columns are qualified with the table, so they cannot be mistaken
+	// for the matches' columns.
+	qualified := func(column string) string {
+		return append(parseTableIdentifier(table.Table), column).Sanitize()
+	}
+	parentID := qualified(table.Parent.ParentIDColumn)
+	index := qualified(table.Parent.IndexColumn)
+	text := qualified(table.TextColumn)
+	id := "''"
+	if table.IDColumn != "" {
+		id = qualified(table.IDColumn) + "::text"
+	}
+
+	condition := fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM unnest($1::text[], $2::bigint[]) AS m(parent_id, idx)
+			WHERE %s::text = m.parent_id
+			  AND %s BETWEEN m.idx - $3 AND m.idx + $3
+		) AND %s IS NOT NULL`, parentID, index, text)
+	if filterClause == "" {
+		filterClause = " WHERE " + condition
+	} else {
+		filterClause += " AND " + condition
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			%s::text AS parent_id,
+			%s::bigint AS idx,
+			%s AS id,
+			%s AS content
+		FROM %s%s
+		ORDER BY 1, 2`,
+		parentID,
+		index,
+		id,
+		text,
+		parseTableIdentifier(table.Table).Sanitize(),
+		filterClause,
+	)
+	return query, filterArgs, nil
+}
+
+// FetchSiblings fetches the chunks of table within window positions of
+// each match, in the same parent, including the matches themselves,
+// that match filter. They are ordered by parent and position.
+func (p *Pool) FetchSiblings(
+	ctx context.Context,
+	table config.TableSource,
+	matches []ChunkPosition,
+	window int,
+	filter *config.Filter,
+) ([]Sibling, error) {
+	if len(matches) == 0 {
+		return nil, nil
+	}
+
+	query, filterArgs, err := buildFetchSiblingsQuery(table, filter, FilterVarsFrom(ctx))
+	if err != nil {
+		return nil, err
+	}
+	parentIDs := make([]string, len(matches))
+	indices := make([]int64, len(matches))
+	for i, m := range matches {
+		parentIDs[i], indices[i] = m.ParentID, m.Index
+	}
+	args := append([]interface{}{parentIDs, indices, window}, filterArgs...)
+
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sibling chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var siblings []Sibling
+	for rows.Next() {
+		var s Sibling
+		if err := rows.Scan(&s.ParentID, &s.Index, &s.ID, &s.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		siblings = append(siblings, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+	return siblings, nil
+}
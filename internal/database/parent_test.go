@@ -0,0 +1,129 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestBuildFetchParentsQuery(t *testing.T) {
+	table := config.TableSource{
+		Table:      "doc_chunks",
+		TextColumn: "chunk",
+		Parent: config.ParentConfig{
+			ParentIDColumn: "document_id",
+			Table:          "public.documents",
+			IDColumn:       "id",
+			TextColumn:     "body",
+		},
+	}
+
+	filter := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "product", Operator: "=", Value: "spock"},
+	}}
+	query, args, err := buildFetchParentsQuery(table, filter, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"id"::text AS id`,
+		`"body" AS content`,
+		`FROM "public"."documents"`,
+		`WHERE ("product" = $2) AND "id"::text = ANY($1::text[])`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query missing %q\nquery: %s", want, query)
+		}
+	}
+	if len(args) != 1 || args[0] != "spock" {
+		t.Errorf("expected the request filter's argument, got %v", args)
+	}
+
+	query, args, err = buildFetchParentsQuery(table, nil, nil)
+	if err != nil || !strings.Contains(query, `WHERE "id"::text = ANY($1::text[])`) || len(args) != 0 {
+		t.Errorf("unexpected unfiltered query %q, %v, %v", query, args, err)
+	}
+}
+
+// TestBuildFetchParentsQuery_Tenant verifies a tenant pipeline's parent
+// rows are fetched for the request's tenant only.
+func TestBuildFetchParentsQuery_Tenant(t *testing.T) {
+	p := config.Pipeline{
+		Tenant: config.TenantConfig{Column: "tenant_id", Header: "X-Tenant"},
+		Tables: []config.TableSource{{
+			Table:      "doc_chunks",
+			TextColumn: "chunk",
+			Parent: config.ParentConfig{
+				ParentIDColumn: "document_id",
+				Table:          "documents",
+				IDColumn:       "id",
+				TextColumn:     "body",
+			},
+		}},
+	}
+	vars := FilterVars(func(config.FilterVariable) (string, bool) { return "acme", true })
+	query, args, err := buildFetchParentsQuery(p.TenantTables()[0], nil, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(query, `WHERE ("tenant_id" = $2) AND "id"::text = ANY($1::text[])`) {
+		t.Errorf("expected the tenant condition on the parent table\nquery: %s", query)
+	}
+	if len(args) != 1 || args[0] != "acme" {
+		t.Errorf("expected the tenant as the argument, got %v", args)
+	}
+
+	if _, _, err := buildFetchParentsQuery(p.TenantTables()[0], nil, nil); err == nil {
+		t.Error("expected an error when the request names no tenant")
+	}
+}
+
+func TestBuildFetchSiblingsQuery(t *testing.T) {
+	table := config.TableSource{
+		Table:      "public.doc_chunks",
+		TextColumn: "chunk",
+		Filter: &config.ConfigFilter{
+			RawSQL: "tenant_id = {{header:X-Tenant}}",
+		},
+		Parent: config.ParentConfig{
+			ParentIDColumn: "source_id",
+			Siblings:       1,
+			IndexColumn:    "chunk_index",
+		},
+	}
+
+	vars := FilterVars(func(config.FilterVariable) (string, bool) { return "acme", true })
+	filter := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "product", Operator: "=", Value: "spock"},
+	}}
+	query, args, err := buildFetchSiblingsQuery(table, filter, vars)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`"public"."doc_chunks"."source_id"::text AS parent_id`,
+		`"public"."doc_chunks"."chunk_index"::bigint AS idx`,
+		`'' AS id`,
+		`WHERE (tenant_id = $4::text) AND ("product" = $5) AND EXISTS`,
+		`unnest($1::text[], $2::bigint[])`,
+		`BETWEEN m.idx - $3 AND m.idx + $3`,
+		"ORDER BY 1, 2",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query missing %q\nquery: %s", want, query)
+		}
+	}
+	if len(args) != 2 || args[0] != "acme" || args[1] != "spock" {
+		t.Errorf("expected the tenant and request filter arguments, got %v", args)
+	}
+}
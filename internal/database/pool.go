@@ -29,11 +29,9 @@ type Pool struct {
 
 // NewPool creates a new database connection pool.
 func NewPool(ctx context.Context, cfg config.DatabaseConfig) (*Pool, error) {
-	connStr := buildConnectionString(cfg)
-
-	poolCfg, err := pgxpool.ParseConfig(connStr)
+	poolCfg, err := poolConfig(cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+		return nil, err
 	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
@@ -53,6 +51,31 @@ func NewPool(ctx context.Context, cfg config.DatabaseConfig) (*Pool, error) {
 	}, nil
 }
 
+// poolConfig returns the pool configuration for cfg, from its URL when
+// it has one.
+func poolConfig(cfg config.DatabaseConfig) (*pgxpool.Config, error) {
+	connStr := cfg.URL
+	if connStr == "" {
+		connStr = buildConnectionString(cfg)
+	}
+
+	poolCfg, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+
+	// A password from the environment or a file is set directly, so it
+	// needs no quoting and overrides any in the URL.
+	if cfg.PasswordEnv != "" || cfg.PasswordFile != "" {
+		password, err := cfg.ResolvePassword()
+		if err != nil {
+			return nil, err
+		}
+		poolCfg.ConnConfig.Password = password
+	}
+	return poolCfg, nil
+}
+
 // buildConnectionString constructs a PostgreSQL connection string.
 func buildConnectionString(cfg config.DatabaseConfig) string {
 	var parts []string
@@ -11,19 +11,33 @@ package database
 
 import (
 	"sort"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
 )
 
 // DefaultRRFConstant is the default k constant for RRF ranking.
 // A value of 60 is commonly used in practice.
 const DefaultRRFConstant = 60
 
-// RRFResult represents a result after RRF fusion.
+// RRFResult represents a result after fusing the vector and lexical
+// rankings, by reciprocal rank fusion or by score fusion.
 type RRFResult struct {
-	ID       string
-	Content  string
-	Score    float64
-	VecRank  int // Rank in vector search results (0 if not present)
-	BM25Rank int // Rank in BM25 results (0 if not present)
+	ID           string
+	Content      string
+	Score        float64
+	VecRank      int     // Rank in vector search results (0 if not present)
+	BM25Rank     int     // Rank in BM25 results (0 if not present)
+	VectorScore  float64 // The vector ranking's contribution to Score
+	LexicalScore float64 // The lexical ranking's contribution to Score
+	SourceInfo   map[string]interface{}
+}
+
+// FusionOptions selects how FusedSearch combines vector and lexical
+// results.
+type FusionOptions struct {
+	Method       string  // config.FusionRRF (the default) or config.FusionScore
+	K            float64 // RRF constant; DefaultRRFConstant when not positive
+	VectorWeight float64 // The vector ranking's share, 0-1; the lexical ranking gets the rest
 }
 
 // ReciprocalRankFusion combines results from vector and BM25 searches
@@ -43,72 +57,117 @@ func ReciprocalRankFusion(
 	if k <= 0 {
 		k = DefaultRRFConstant
 	}
-	if vectorWeight < 0 || vectorWeight > 1 {
-		vectorWeight = 0.5
+	rrf := func(results []SearchResult) []float64 {
+		scores := make([]float64, len(results))
+		for i := range results {
+			scores[i] = 1 / (k + float64(i+1)) // Ranks are 1-indexed
+		}
+		return scores
 	}
-	bm25Weight := 1.0 - vectorWeight
-
-	// Map to accumulate scores and track ranks
-	resultMap := make(map[string]*RRFResult)
+	return fuseResults(vectorResults, rrf(vectorResults), bm25Results, rrf(bm25Results), vectorWeight)
+}
 
-	// Process vector results
-	if vectorWeight > 0 {
-		for i, r := range vectorResults {
-			rank := i + 1 // 1-indexed
-			key := r.Content
-			if r.ID != "" {
-				key = r.ID
-			}
+// ScoreFusion combines results from vector and lexical searches by
+// their scores rather than their ranks. Each list's scores are min-max
+// normalized to 0-1, since similarities and BM25 or ts_rank scores are
+// on different scales, and combined linearly:
+//
+//	score = vectorWeight * vector + (1 - vectorWeight) * lexical
+//
+// A result missing from one list scores 0 there. Unlike RRF, a result
+// far ahead of the rest in one list keeps that lead.
+//
+// The function returns results sorted by combined score (highest first).
+func ScoreFusion(vectorResults, lexicalResults []SearchResult, vectorWeight float64) []RRFResult {
+	return fuseResults(vectorResults, normalizeScores(vectorResults),
+		lexicalResults, normalizeScores(lexicalResults), vectorWeight)
+}
 
-			if existing, ok := resultMap[key]; ok {
-				existing.Score += vectorWeight / (k + float64(rank))
-				existing.VecRank = rank
-			} else {
-				resultMap[key] = &RRFResult{
-					ID:      r.ID,
-					Content: r.Content,
-					Score:   vectorWeight / (k + float64(rank)),
-					VecRank: rank,
-				}
-			}
+// normalizeScores min-max normalizes results' scores to 0-1. When every
+// score is the same they all normalize to 1.
+func normalizeScores(results []SearchResult) []float64 {
+	scores := make([]float64, len(results))
+	if len(results) == 0 {
+		return scores
+	}
+	lo, hi := results[0].Score, results[0].Score
+	for _, r := range results {
+		lo, hi = min(lo, r.Score), max(hi, r.Score)
+	}
+	for i, r := range results {
+		scores[i] = 1
+		if hi > lo {
+			scores[i] = (r.Score - lo) / (hi - lo)
 		}
 	}
+	return scores
+}
 
-	// Process BM25 results
-	if bm25Weight > 0 {
-		for i, r := range bm25Results {
-			rank := i + 1 // 1-indexed
-			key := r.Content
-			if r.ID != "" {
-				key = r.ID
-			}
-
-			if existing, ok := resultMap[key]; ok {
-				existing.Score += bm25Weight / (k + float64(rank))
-				existing.BM25Rank = rank
-			} else {
-				resultMap[key] = &RRFResult{
-					ID:       r.ID,
-					Content:  r.Content,
-					Score:    bm25Weight / (k + float64(rank)),
-					BM25Rank: rank,
-				}
+// fuseResults combines two ranked lists, given each result's score
+// within its list, weighting the vector list by vectorWeight and the
+// lexical list by the rest. Results are keyed by ID, or by content when
+// they have none; a list with no weight is left out entirely. Results
+// are sorted by combined score, ties in order of first appearance.
+func fuseResults(
+	vectorResults []SearchResult,
+	vectorScores []float64,
+	lexicalResults []SearchResult,
+	lexicalScores []float64,
+	vectorWeight float64,
+) []RRFResult {
+	if vectorWeight < 0 || vectorWeight > 1 {
+		vectorWeight = 0.5
+	}
+	lexicalWeight := 1.0 - vectorWeight
+
+	index := make(map[string]int)
+	var fused []RRFResult
+	entry := func(r SearchResult) *RRFResult {
+		key := r.Content
+		if r.ID != "" {
+			key = r.ID
+		}
+		if i, ok := index[key]; ok {
+			if fused[i].SourceInfo == nil {
+				fused[i].SourceInfo = r.SourceInfo
 			}
+			return &fused[i]
 		}
+		index[key] = len(fused)
+		fused = append(fused, RRFResult{ID: r.ID, Content: r.Content, SourceInfo: r.SourceInfo})
+		return &fused[len(fused)-1]
 	}
 
-	// Convert map to slice
-	results := make([]RRFResult, 0, len(resultMap))
-	for _, r := range resultMap {
-		results = append(results, *r)
+	if vectorWeight > 0 {
+		for i, r := range vectorResults {
+			e := entry(r)
+			e.VecRank = i + 1
+			e.VectorScore = vectorWeight * vectorScores[i]
+		}
+	}
+	if lexicalWeight > 0 {
+		for i, r := range lexicalResults {
+			e := entry(r)
+			e.BM25Rank = i + 1
+			e.LexicalScore = lexicalWeight * lexicalScores[i]
+		}
+	}
+	for i := range fused {
+		fused[i].Score = fused[i].VectorScore + fused[i].LexicalScore
 	}
 
-	// Sort by score (highest first)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
 	})
+	return fused
+}
 
-	return results
+// Fuse combines vector and lexical results as opts selects.
+func Fuse(vectorResults, lexicalResults []SearchResult, opts FusionOptions) []RRFResult {
+	if opts.Method == config.FusionScore {
+		return ScoreFusion(vectorResults, lexicalResults, opts.VectorWeight)
+	}
+	return ReciprocalRankFusion(vectorResults, lexicalResults, opts.K, opts.VectorWeight)
 }
 
 // HybridSearch combines vector and BM25 search results using RRF.
@@ -120,20 +179,68 @@ func HybridSearch(
 	topN int,
 	vectorWeight float64,
 ) []SearchResult {
-	rrfResults := ReciprocalRankFusion(vectorResults, bm25Results, DefaultRRFConstant, vectorWeight)
+	return FusedSearch(vectorResults, bm25Results, topN, FusionOptions{VectorWeight: vectorWeight})
+}
+
+// FusedSearch combines vector and lexical search results as opts
+// selects, and returns the top-N fused results.
+func FusedSearch(
+	vectorResults []SearchResult,
+	lexicalResults []SearchResult,
+	topN int,
+	opts FusionOptions,
+) []SearchResult {
+	fused := Fuse(vectorResults, lexicalResults, opts)
 
 	// Convert back to SearchResult and limit to topN
-	results := make([]SearchResult, 0, min(topN, len(rrfResults)))
-	for i, r := range rrfResults {
-		if i >= topN {
-			break
-		}
+	results := make([]SearchResult, 0, min(topN, len(fused)))
+	for _, r := range fused[:min(topN, len(fused))] {
 		results = append(results, SearchResult{
-			ID:      r.ID,
-			Content: r.Content,
-			Score:   r.Score,
+			ID:         r.ID,
+			Content:    r.Content,
+			Score:      r.Score,
+			SourceInfo: r.SourceInfo,
 		})
 	}
 
 	return results
 }
+
+// FuseRankings combines any number of ranked result lists using RRF,
+// weighting every list equally: a result scores sum(1 / (k + rank))
+// over the lists it appears in. Results are keyed by ID, or by content
+// when they have none. The top-N fused results are returned highest
+// score first, with ties in order of first appearance.
+func FuseRankings(lists [][]SearchResult, k float64, topN int) []SearchResult {
+	if k <= 0 {
+		k = DefaultRRFConstant
+	}
+
+	index := make(map[string]int)
+	var fused []SearchResult
+	for _, list := range lists {
+		for i, r := range list {
+			key := r.Content
+			if r.ID != "" {
+				key = r.ID
+			}
+			score := 1 / (k + float64(i+1))
+
+			if j, ok := index[key]; ok {
+				fused[j].Score += score
+				if fused[j].SourceInfo == nil {
+					fused[j].SourceInfo = r.SourceInfo
+				}
+				continue
+			}
+			index[key] = len(fused)
+			r.Score = score
+			fused = append(fused, r)
+		}
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused[:min(topN, len(fused))]
+}
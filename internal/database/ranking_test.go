@@ -11,7 +11,10 @@ package database
 
 import (
 	"math"
+	"strings"
 	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
 )
 
 // TestReciprocalRankFusion_EqualWeight verifies that equal vector and BM25
@@ -401,3 +404,113 @@ func TestReciprocalRankFusion_BothEmpty(t *testing.T) {
 		t.Errorf("expected 0 results, got %d", len(results))
 	}
 }
+
+// TestHybridSearch_CarriesSourceInfo verifies that fused results keep the
+// metadata from whichever arm found them, so BM25-only matches still link
+// back to their documents.
+func TestHybridSearch_CarriesSourceInfo(t *testing.T) {
+	vec := []SearchResult{
+		{ID: "a", Content: "doc-a", Score: 0.9, SourceInfo: map[string]interface{}{"title": "A"}},
+	}
+	bm25 := []SearchResult{
+		{ID: "a", Content: "doc-a", Score: 5.0},
+		{ID: "b", Content: "doc-b", Score: 3.0, SourceInfo: map[string]interface{}{"title": "B"}},
+	}
+
+	results := HybridSearch(vec, bm25, 2, 0.5)
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		want := strings.ToUpper(r.ID)
+		if r.SourceInfo["title"] != want {
+			t.Errorf("result %s: title = %v, want %s", r.ID, r.SourceInfo["title"], want)
+		}
+	}
+}
+
+// TestFuseRankings verifies that results found by several lists
+// outrank those found by one, and that the scores are the RRF sums.
+func TestFuseRankings(t *testing.T) {
+	lists := [][]SearchResult{
+		{{ID: "a", Content: "doc-a"}, {ID: "b", Content: "doc-b"}},
+		{{ID: "c", Content: "doc-c"}, {ID: "b", Content: "doc-b", SourceInfo: map[string]interface{}{"title": "B"}}},
+		{{Content: "no-id"}},
+	}
+
+	results := FuseRankings(lists, 60, 3)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	if results[0].ID != "b" || results[1].ID != "a" || results[2].ID != "c" {
+		t.Errorf("unexpected order: %v, %v, %v", results[0].ID, results[1].ID, results[2].ID)
+	}
+	if want := 2.0 / 62; math.Abs(results[0].Score-want) > 1e-12 {
+		t.Errorf("b score = %v, want %v", results[0].Score, want)
+	}
+	if results[0].SourceInfo["title"] != "B" {
+		t.Errorf("expected b to keep its metadata, got %v", results[0].SourceInfo)
+	}
+
+	if all := FuseRankings(lists, 0, 10); len(all) != 4 || all[3].Content != "no-id" {
+		t.Errorf("expected every result, keyed by content without an ID, got %+v", all)
+	}
+}
+
+// TestScoreFusion verifies that scores are min-max normalized per list
+// and blended by weight, so a clear lead in one list survives fusion
+// where RRF would reduce it to a rank.
+func TestScoreFusion(t *testing.T) {
+	vec := []SearchResult{
+		{ID: "a", Content: "doc-a", Score: 0.90},
+		{ID: "b", Content: "doc-b", Score: 0.89},
+		{ID: "c", Content: "doc-c", Score: 0.50},
+	}
+	bm25 := []SearchResult{
+		{ID: "b", Content: "doc-b", Score: 12.0},
+		{ID: "c", Content: "doc-c", Score: 2.0},
+		{ID: "a", Content: "doc-a", Score: 1.0},
+	}
+
+	results := ScoreFusion(vec, bm25, 0.5)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+	// b: 0.5*(0.39/0.40) + 0.5*1; a: 0.5*1 + 0; c: 0 + 0.5*(1/11)
+	if results[0].ID != "b" || results[1].ID != "a" || results[2].ID != "c" {
+		t.Errorf("unexpected order: %v, %v, %v", results[0].ID, results[1].ID, results[2].ID)
+	}
+	if want := 0.5*(0.39/0.40) + 0.5; math.Abs(results[0].Score-want) > 1e-9 {
+		t.Errorf("b score = %v, want %v", results[0].Score, want)
+	}
+	if results[0].VecRank != 2 || results[0].BM25Rank != 1 {
+		t.Errorf("b ranks = %d, %d, want 2, 1", results[0].VecRank, results[0].BM25Rank)
+	}
+	if math.Abs(results[0].VectorScore+results[0].LexicalScore-results[0].Score) > 1e-12 {
+		t.Errorf("contributions %v + %v do not sum to %v",
+			results[0].VectorScore, results[0].LexicalScore, results[0].Score)
+	}
+
+	// A single result, or equal scores, normalize to 1.
+	single := ScoreFusion(vec[:1], nil, 0.7)
+	if len(single) != 1 || math.Abs(single[0].Score-0.7) > 1e-12 {
+		t.Errorf("single result = %+v, want score 0.7", single)
+	}
+}
+
+// TestFusedSearch_Options verifies that FusedSearch applies the chosen
+// method and RRF constant.
+func TestFusedSearch_Options(t *testing.T) {
+	vec := []SearchResult{{ID: "a", Content: "doc-a", Score: 0.9}, {ID: "b", Content: "doc-b", Score: 0.1}}
+	bm25 := []SearchResult{{ID: "b", Content: "doc-b", Score: 9.0}}
+
+	results := FusedSearch(vec, bm25, 10, FusionOptions{K: 10, VectorWeight: 0.5})
+	if want := 0.5/12 + 0.5/11; math.Abs(results[0].Score-want) > 1e-12 || results[0].ID != "b" {
+		t.Errorf("rrf with k=10: got %+v, want b scoring %v", results[0], want)
+	}
+
+	results = FusedSearch(vec, bm25, 1, FusionOptions{Method: config.FusionScore, VectorWeight: 0.4})
+	if len(results) != 1 || results[0].ID != "b" || math.Abs(results[0].Score-0.6) > 1e-12 {
+		t.Errorf("score fusion: got %+v, want only b scoring 0.6", results)
+	}
+}
@@ -0,0 +1,247 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// vectorDimensionsQuery reads a column's type modifier, which for the
+// pgvector types is the number of dimensions the column was declared
+// with, or -1 when it was declared without one.
+const vectorDimensionsQuery = `SELECT atttypmod FROM pg_attribute
+	WHERE attrelid = $1::regclass AND attname = $2 AND NOT attisdropped`
+
+// VectorDimensions returns the number of dimensions a table's vector
+// column is declared with, or 0 when its type does not fix them, as a
+// plain vector column does not.
+func (p *Pool) VectorDimensions(ctx context.Context, table config.TableSource) (int, error) {
+	var typmod int
+	err := p.pool.QueryRow(ctx, vectorDimensionsQuery,
+		parseTableIdentifier(table.Table).Sanitize(), table.VectorColumn).Scan(&typmod)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("column %q does not exist on table %s", table.VectorColumn, table.Table)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up column %q of table %s: %w", table.VectorColumn, table.Table, err)
+	}
+	return max(typmod, 0), nil
+}
+
+// vectorIndexQuery reports whether a relation has an IVFFlat or HNSW
+// index on a column. Views and foreign tables cannot be indexed, and
+// their searches use the indexes of the tables beneath them, so they
+// count as indexed.
+const vectorIndexQuery = `SELECT c.relkind NOT IN ('r', 'm', 'p') OR EXISTS (
+		SELECT 1 FROM pg_index i
+		JOIN pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_am am ON am.oid = ic.relam
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY (i.indkey)
+		WHERE i.indrelid = c.oid AND a.attname = $2 AND am.amname IN ('ivfflat', 'hnsw'))
+	FROM pg_class c WHERE c.oid = $1::regclass`
+
+// HasVectorIndex reports whether a table's vector column has an
+// approximate nearest neighbour index. Without one, every vector
+// search scans the whole table.
+func (p *Pool) HasVectorIndex(ctx context.Context, table config.TableSource) (bool, error) {
+	var indexed bool
+	err := p.pool.QueryRow(ctx, vectorIndexQuery,
+		parseTableIdentifier(table.Table).Sanitize(), table.VectorColumn).Scan(&indexed)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up the indexes of table %s: %w", table.Table, err)
+	}
+	return indexed, nil
+}
+
+// relationKinds are the pg_class kinds a pipeline can search: tables,
+// views, materialized views, partitioned and foreign tables.
+const relationKinds = "('r', 'v', 'm', 'p', 'f')"
+
+// CheckSchema confirms that the pgvector extension is installed and
+// that each table, any parent table, and every column their
+// configuration names exist, so a mistake fails startup, or the
+// reload, with a hint at the likely fix rather than failing the first
+// query.
+func (p *Pool) CheckSchema(ctx context.Context, tables []config.TableSource) error {
+	var installed bool
+	err := p.pool.QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')").Scan(&installed)
+	if err != nil {
+		return fmt.Errorf("failed to check for the pgvector extension: %w", err)
+	}
+	if !installed {
+		return errors.New("the pgvector extension is not installed in the database; " +
+			"run CREATE EXTENSION vector")
+	}
+
+	for _, table := range tables {
+		if err := p.checkTable(ctx, table); err != nil {
+			return err
+		}
+		if parent := table.Parent; parent.Table != "" {
+			err := p.checkTable(ctx, config.TableSource{
+				Table:      parent.Table,
+				TextColumn: parent.TextColumn,
+				IDColumn:   parent.IDColumn,
+			})
+			if err != nil {
+				return fmt.Errorf("parent of table %s: %w", table.Table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkTable checks that a table and the columns it names exist.
+func (p *Pool) checkTable(ctx context.Context, table config.TableSource) error {
+	ident := parseTableIdentifier(table.Table)
+	relname := ident[len(ident)-1]
+
+	var exists bool
+	if err := p.pool.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL",
+		ident.Sanitize()).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to look up table %s: %w", table.Table, err)
+	}
+	if !exists {
+		// Suggest a similar relation from the same schema, or from the
+		// search path when the name is not qualified.
+		query := `SELECT c.relname FROM pg_class c
+			WHERE c.relkind IN ` + relationKinds + ` AND pg_table_is_visible(c.oid)`
+		args := []interface{}{}
+		if len(ident) > 1 {
+			query = `SELECT c.relname FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+				WHERE c.relkind IN ` + relationKinds + ` AND n.nspname = $1`
+			args = append(args, ident[0])
+		}
+		names, _ := p.queryNames(ctx, query, args...)
+		return fmt.Errorf("table %s does not exist%s", table.Table, didYouMean(closestName(relname, names)))
+	}
+
+	columns, err := p.queryNames(ctx, `SELECT attname FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped`, ident.Sanitize())
+	if err != nil {
+		return fmt.Errorf("failed to look up the columns of table %s: %w", table.Table, err)
+	}
+	for _, column := range missingColumns(table, columns) {
+		// The pgEdge vectorizer keeps the text and embedding of a table
+		// in a separate <table>_<column>_chunks table, which is the one
+		// to search.
+		related, _ := p.queryNames(ctx, `SELECT c.relname FROM pg_class c
+			JOIN pg_attribute a ON a.attrelid = c.oid
+			WHERE c.relkind IN `+relationKinds+` AND a.attname = $1 AND NOT a.attisdropped
+			AND c.relnamespace = (SELECT relnamespace FROM pg_class WHERE oid = $2::regclass)`,
+			column, ident.Sanitize())
+		hint := didYouMean(relatedTable(relname, related))
+		if hint == "" {
+			if similar := closestName(column, columns); similar != "" {
+				hint = didYouMean(fmt.Sprintf("column %q", similar))
+			}
+		}
+		return fmt.Errorf("column %q does not exist on table %s%s", column, table.Table, hint)
+	}
+	return nil
+}
+
+// queryNames runs a query returning a single text column.
+func (p *Pool) queryNames(ctx context.Context, query string, args ...interface{}) ([]string, error) {
+	rows, err := p.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// missingColumns returns the columns a table's configuration names that
+// are not among its columns, in the order the configuration lists them.
+func missingColumns(table config.TableSource, columns []string) []string {
+	have := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		have[c] = true
+	}
+	want := []string{table.TextColumn, table.VectorColumn, table.IDColumn, table.TSVectorColumn, table.Rescore.Column}
+	want = append(want, table.MetadataColumns...)
+	for _, lc := range table.LexicalColumns {
+		want = append(want, lc.Column)
+	}
+
+	var missing []string
+	for _, c := range want {
+		if c != "" && !have[c] {
+			missing = append(missing, c)
+		}
+	}
+	return missing
+}
+
+// relatedTable picks, from the tables that have a column the table
+// lacks, one named after the table, preferring a vectorizer chunks
+// table, or returns "".
+func relatedTable(relname string, candidates []string) string {
+	var related string
+	for _, c := range candidates {
+		if !strings.HasPrefix(c, relname+"_") {
+			continue
+		}
+		if strings.HasSuffix(c, "_chunks") {
+			return c
+		}
+		if related == "" {
+			related = c
+		}
+	}
+	return related
+}
+
+// closestName returns the candidate most similar to name, when it is
+// close enough to be a likely typo, or "".
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func didYouMean(suggestion string) string {
+	if suggestion == "" {
+		return ""
+	}
+	return " — did you mean " + suggestion + "?"
+}
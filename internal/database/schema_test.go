@@ -0,0 +1,62 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestMissingColumns(t *testing.T) {
+	table := config.TableSource{
+		Table:           "documents",
+		TextColumn:      "content",
+		VectorColumn:    "embedding",
+		IDColumn:        "id",
+		MetadataColumns: []string{"title", "url"},
+		LexicalColumns:  []config.LexicalColumn{{Column: "summary"}},
+	}
+	got := missingColumns(table, []string{"id", "content", "title"})
+	want := []string{"embedding", "url", "summary"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := missingColumns(table, []string{"id", "content", "embedding", "title", "url", "summary"}); got != nil {
+		t.Errorf("expected no missing columns, got %v", got)
+	}
+}
+
+func TestSchemaHints(t *testing.T) {
+	related := relatedTable("documents", []string{"notes", "documents_archive", "documents_content_chunks"})
+	if related != "documents_content_chunks" {
+		t.Errorf("expected the chunks table, got %q", related)
+	}
+	if got := relatedTable("documents", []string{"notes"}); got != "" {
+		t.Errorf("expected no related table, got %q", got)
+	}
+
+	tests := []struct {
+		name       string
+		candidates []string
+		want       string
+	}{
+		{"embeding", []string{"id", "content", "embedding"}, "embedding"},
+		{"documets", []string{"documents", "docs"}, "documents"},
+		{"Content", []string{"content"}, "content"},
+		{"vector", []string{"id", "content"}, ""},
+	}
+	for _, tt := range tests {
+		if got := closestName(tt.name, tt.candidates); got != tt.want {
+			t.Errorf("closestName(%q): got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -11,6 +11,7 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -41,6 +42,97 @@ type SearchResult struct {
 	Content    string                 `json:"content"`
 	Score      float64                `json:"score"`
 	SourceInfo map[string]interface{} `json:"source_info,omitempty"`
+	Table      string                 `json:"-"` // The table searched, set by the pipeline
+}
+
+// Document is a row fetched for BM25 indexing: its text, the text of
+// the table's lexical columns (in configured order; NULL is ""), and
+// the values of its metadata columns.
+type Document struct {
+	Content    string
+	Lexical    []string
+	SourceInfo map[string]interface{}
+}
+
+// ErrFetchLimitExceeded is returned (wrapped) by FetchDocuments when a
+// table has more matching rows, or more text, than its FetchLimits
+// allow.
+var ErrFetchLimitExceeded = errors.New("fetch limit exceeded")
+
+// FetchLimits bound how much of a table FetchDocuments loads into
+// memory. Zero leaves a limit off.
+type FetchLimits struct {
+	MaxRows  int   // Most rows loaded
+	MaxBytes int64 // Most bytes of content and lexical columns loaded
+}
+
+// fetchBudget tracks how much of its FetchLimits a FetchDocuments call
+// has used.
+type fetchBudget struct {
+	limits FetchLimits
+	table  string
+	rows   int
+	bytes  int64
+}
+
+// add counts a fetched document against the limits, and fails once
+// either is exceeded.
+func (b *fetchBudget) add(doc Document) error {
+	b.rows++
+	b.bytes += int64(len(doc.Content))
+	for _, text := range doc.Lexical {
+		b.bytes += int64(len(text))
+	}
+	if b.limits.MaxRows > 0 && b.rows > b.limits.MaxRows {
+		return fmt.Errorf("%w: table %s has more than %d matching rows (bm25.max_rows); "+
+			"raise the limit, or set lexical_search: postgres_fts to rank the table in the database",
+			ErrFetchLimitExceeded, b.table, b.limits.MaxRows)
+	}
+	if b.limits.MaxBytes > 0 && b.bytes > b.limits.MaxBytes {
+		return fmt.Errorf("%w: table %s has more than %d bytes of matching text (bm25.max_bytes); "+
+			"raise the limit, or set lexical_search: postgres_fts to rank the table in the database",
+			ErrFetchLimitExceeded, b.table, b.limits.MaxBytes)
+	}
+	return nil
+}
+
+// metadataSelect returns the select-list entries for a table's metadata
+// columns, each preceded by a comma so it can follow the fixed columns.
+func metadataSelect(table config.TableSource) string {
+	var sb strings.Builder
+	for _, col := range table.MetadataColumns {
+		sb.WriteString(",\n\t\t\t")
+		sb.WriteString(pgx.Identifier{col}.Sanitize())
+	}
+	return sb.String()
+}
+
+// scanWithMetadata scans a row whose select list is dest followed by
+// the table's metadata columns, returning the metadata keyed by column
+// name. Values keep the types pgx decodes them to, so timestamps and
+// numbers render naturally in JSON; NULLs are reported as nil.
+func scanWithMetadata(
+	rows pgx.Rows,
+	table config.TableSource,
+	dest ...interface{},
+) (map[string]interface{}, error) {
+	if len(table.MetadataColumns) == 0 {
+		return nil, rows.Scan(dest...)
+	}
+
+	values := make([]interface{}, len(table.MetadataColumns))
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	info := make(map[string]interface{}, len(values))
+	for i, col := range table.MetadataColumns {
+		info[col] = values[i]
+	}
+	return info, nil
 }
 
 // buildVectorSearchQuery constructs the SQL query and argument list for a
@@ -48,11 +140,16 @@ type SearchResult struct {
 //
 // Arg ordering: $1=vector, $2=limit. If minSimilarity is set it occupies $3
 // and filters start at $4; otherwise filters start at $3.
+//
+// A table with rescore set is searched in two stages: the quantized
+// vector column picks the candidates, which are then scored, filtered by
+// minSimilarity and ordered with the full-precision column.
 func buildVectorSearchQuery(
 	embedding []float32,
 	table config.TableSource,
 	topN int,
 	filter *config.Filter,
+	vars FilterVars,
 	minSimilarity *float64,
 ) (string, []interface{}, error) {
 	vectorCol := pgx.Identifier{table.VectorColumn}.Sanitize()
@@ -64,11 +161,14 @@ func buildVectorSearchQuery(
 		extraArgs = append(extraArgs, *minSimilarity)
 	}
 
-	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, nextParam)
+	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, vars, nextParam)
 	if err != nil {
 		return "", nil, fmt.Errorf("invalid filter: %w", err)
 	}
 
+	args := append([]interface{}{formatVector(embedding), topN}, extraArgs...)
+	args = append(args, filterArgs...)
+
 	// Exclude rows with NULL vector column — NULL embeddings produce NULL scores
 	// which cannot be scanned and are useless for similarity search.
 	nullGuard := vectorCol + " IS NOT NULL"
@@ -78,24 +178,276 @@ func buildVectorSearchQuery(
 		filterClause = filterClause + " AND " + nullGuard
 	}
 
+	if table.Rescore.Enabled() {
+		return buildRescoreQuery(table, topN, filterClause, minSimilarity != nil), args, nil
+	}
+
+	distance := vectorDistance(vectorCol, table.Distance, table.VectorType)
 	if minSimilarity != nil {
-		simCondition := fmt.Sprintf("1 - (%s <=> $1::vector) >= $3", vectorCol)
+		simCondition := vectorSimilarity(distance, table.Distance) + " >= $3"
 		filterClause = filterClause + " AND " + simCondition
 	}
 
-	// Determine the ID expression. When an id_column is configured we select
-	// it so vector results carry a stable id — this is what makes both search
-	// arms key on the same id in RRF (correct fusion) and what makes vector
-	// results usable for id-based source resolution (citations).
-	//
-	// When no id_column is configured there is no stable identifier. We must
-	// NOT emit a ROW_NUMBER() id here: the vector query (ORDER BY ... LIMIT)
-	// and the BM25 FetchDocuments query (full scan) number their rows
-	// independently, so a row number from one arm does not identify the same
-	// document as the same row number from the other. Using it as an RRF key
-	// would falsely fuse unrelated documents. Emitting an empty id makes RRF
-	// and deduplication fall back to keying on content, which is the only
-	// reliable cross-arm identity when no id_column exists.
+	query := fmt.Sprintf(`
+		SELECT
+			%s AS id,
+			%s AS content,
+			%s AS score%s
+		FROM %s%s
+		ORDER BY %s
+		LIMIT $2`,
+		vectorIDExpr(table),
+		pgx.Identifier{table.TextColumn}.Sanitize(),
+		vectorSimilarity(distance, table.Distance),
+		metadataSelect(table),
+		parseTableIdentifier(table.Table).Sanitize(),
+		filterClause,
+		distance,
+	)
+	return query, args, nil
+}
+
+// vectorDistance returns the expression for the distance of col, of
+// pgvector type typ, from the query vector under metric. Rows are
+// ordered by it, nearest first, so an index with the matching operator
+// class can serve the search.
+func vectorDistance(col, metric, typ string) string {
+	op := "<=>"
+	switch metric {
+	case config.DistanceL2:
+		op = "<->"
+	case config.DistanceInnerProduct:
+		op = "<#>"
+	}
+	query := "$1::vector"
+	if typ != "" && typ != config.VectorTypeVector {
+		query += "::" + typ
+	}
+	return col + " " + op + " " + query
+}
+
+// vectorSimilarity converts a distance under metric into a score that
+// is higher for closer rows: cosine similarity for cosine distance,
+// 1 / (1 + d) for L2 distance, which falls from 1 towards 0, and the
+// inner product itself, which <#> returns negated. For normalized
+// embeddings, the inner product equals cosine similarity.
+func vectorSimilarity(distance, metric string) string {
+	switch metric {
+	case config.DistanceL2:
+		return "1 / (1 + (" + distance + "))"
+	case config.DistanceInnerProduct:
+		return "-(" + distance + ")"
+	default:
+		return "1 - (" + distance + ")"
+	}
+}
+
+// vectorIDExpr returns the expression a vector search selects as a
+// result's id. When an id_column is configured we select it so vector
+// results carry a stable id — this is what makes both search arms key on
+// the same id in RRF (correct fusion) and what makes vector results
+// usable for id-based source resolution (citations).
+//
+// When no id_column is configured there is no stable identifier. We must
+// NOT emit a ROW_NUMBER() id here: the vector query (ORDER BY ... LIMIT)
+// and the BM25 FetchDocuments query (full scan) number their rows
+// independently, so a row number from one arm does not identify the same
+// document as the same row number from the other. Using it as an RRF key
+// would falsely fuse unrelated documents. Emitting an empty id makes RRF
+// and deduplication fall back to keying on content, which is the only
+// reliable cross-arm identity when no id_column exists.
+func vectorIDExpr(table config.TableSource) string {
+	if table.IDColumn != "" {
+		return pgx.Identifier{table.IDColumn}.Sanitize() + "::text"
+	}
+	return "''::text"
+}
+
+// quantizedDistance returns the expression ordering a table's rows by
+// the distance of its quantized vector column from the query vector.
+func quantizedDistance(table config.TableSource) string {
+	col := pgx.Identifier{table.VectorColumn}.Sanitize()
+	if table.Rescore.Quantization == config.QuantizationBinary {
+		return col + " <~> binary_quantize($1::vector)"
+	}
+	return vectorDistance(col, table.Distance, config.VectorTypeHalfvec)
+}
+
+// buildRescoreQuery constructs the two-stage search of a table with
+// rescore set, taking the same arguments as buildVectorSearchQuery's
+// query. filterClause already excludes rows without a quantized vector.
+func buildRescoreQuery(table config.TableSource, topN int, filterClause string, minSimilarity bool) string {
+	fullCol := pgx.Identifier{table.Rescore.Column}.Sanitize()
+	distance := vectorDistance(fullCol, table.Distance, config.VectorTypeVector)
+	candidates := table.Rescore.Candidates
+	if candidates == 0 {
+		candidates = topN * config.DefaultRescoreFactor
+	}
+	candidates = max(candidates, topN)
+
+	outerWhere := ""
+	if minSimilarity {
+		outerWhere = "\n\t\tWHERE " + vectorSimilarity(distance, table.Distance) + " >= $3"
+	}
+
+	return fmt.Sprintf(`
+		SELECT
+			%s AS id,
+			%s AS content,
+			%s AS score%s
+		FROM (
+			SELECT * FROM %s%s AND %s IS NOT NULL
+			ORDER BY %s
+			LIMIT %d
+		) AS candidate%s
+		ORDER BY %s
+		LIMIT $2`,
+		vectorIDExpr(table),
+		pgx.Identifier{table.TextColumn}.Sanitize(),
+		vectorSimilarity(distance, table.Distance),
+		metadataSelect(table),
+		parseTableIdentifier(table.Table).Sanitize(),
+		filterClause,
+		fullCol,
+		quantizedDistance(table),
+		candidates,
+		outerWhere,
+		distance,
+	)
+}
+
+// VectorSearch performs a vector similarity search using pgvector.
+// Returns results ordered by similarity (highest first).
+// The filter parameter allows additional WHERE conditions from the API request.
+// If minSimilarity is non-nil, results scoring below it are excluded;
+// see vectorSimilarity for how the table's distance metric is scored.
+func (p *Pool) VectorSearch(
+	ctx context.Context,
+	embedding []float32,
+	table config.TableSource,
+	topN int,
+	filter *config.Filter,
+	minSimilarity *float64,
+) ([]SearchResult, error) {
+	query, args, err := buildVectorSearchQuery(embedding, table, topN, filter, FilterVarsFrom(ctx), minSimilarity)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := vectorSearchSettings(table.VectorSearch)
+	if len(settings) == 0 {
+		rows, err := p.pool.Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("vector search failed: %w", err)
+		}
+		return scanVectorResults(rows, table)
+	}
+
+	// SET LOCAL only lasts until the transaction ends, so the settings
+	// never leak to other queries on the pooled connection.
+	var results []SearchResult
+	err = pgx.BeginTxFunc(ctx, p.pool, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		for _, stmt := range settings {
+			if _, err := tx.Exec(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to apply vector search settings: %w", err)
+			}
+		}
+		rows, err := tx.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("vector search failed: %w", err)
+		}
+		results, err = scanVectorResults(rows, table)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// vectorSearchSettings returns the SET LOCAL statements that apply a
+// table's pgvector index settings, or none when it sets none.
+func vectorSearchSettings(opts config.VectorSearchOptions) []string {
+	var stmts []string
+	if opts.Probes > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET LOCAL ivfflat.probes = %d", opts.Probes))
+	}
+	if opts.EfSearch > 0 {
+		stmts = append(stmts, fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", opts.EfSearch))
+	}
+	return stmts
+}
+
+// scanVectorResults reads, and closes, the rows of a vector search.
+func scanVectorResults(rows pgx.Rows, table config.TableSource) ([]SearchResult, error) {
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		info, err := scanWithMetadata(rows, table, &r.ID, &r.Content, &r.Score)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		r.SourceInfo = info
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return results, nil
+}
+
+// DefaultTextSearchConfig is the text search configuration full-text
+// search uses when a table sets none.
+const DefaultTextSearchConfig = "english"
+
+// buildTextSearchQuery constructs the SQL query and argument list for
+// a full-text search. Extracted from TextSearch for testability.
+//
+// The query's words are OR'd together, as BM25 matches them, rather
+// than all being required: plainto_tsquery joins them with &, which is
+// replaced with |. The text search configuration is a literal, not a
+// parameter, so an expression index on to_tsvector('english', ...)
+// matches the query.
+//
+// Arg ordering: $1=query text, $2=limit; filters start at $3.
+func buildTextSearchQuery(
+	queryText string,
+	table config.TableSource,
+	topN int,
+	filter *config.Filter,
+	vars FilterVars,
+) (string, []interface{}, error) {
+	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, vars, 3)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	tsConfig := table.TextSearchConfig
+	if tsConfig == "" {
+		tsConfig = DefaultTextSearchConfig
+	}
+	regconfig := "'" + strings.ReplaceAll(tsConfig, "'", "''") + "'::regconfig"
+
+	textCol := pgx.Identifier{table.TextColumn}.Sanitize()
+	document := fmt.Sprintf("to_tsvector(%s, %s)", regconfig, textCol)
+	if table.TSVectorColumn != "" {
+		document = pgx.Identifier{table.TSVectorColumn}.Sanitize()
+	}
+	tsquery := fmt.Sprintf("replace(plainto_tsquery(%s, $1)::text, ' & ', ' | ')::tsquery", regconfig)
+
+	match := fmt.Sprintf("%s IS NOT NULL AND %s @@ %s", textCol, document, tsquery)
+	if filterClause == "" {
+		filterClause = " WHERE " + match
+	} else {
+		filterClause = filterClause + " AND " + match
+	}
+
+	// As in vector search, an empty id without an id_column makes
+	// fusion key on content rather than an unstable row number.
 	var idExpr string
 	if table.IDColumn != "" {
 		idExpr = pgx.Identifier{table.IDColumn}.Sanitize() + "::text"
@@ -107,50 +459,50 @@ func buildVectorSearchQuery(
 		SELECT
 			%s AS id,
 			%s AS content,
-			1 - (%s <=> $1::vector) AS score
+			ts_rank(%s, %s)::float8 AS score%s
 		FROM %s%s
-		ORDER BY %s <=> $1::vector
+		ORDER BY score DESC
 		LIMIT $2`,
 		idExpr,
-		pgx.Identifier{table.TextColumn}.Sanitize(),
-		vectorCol,
+		textCol,
+		document,
+		tsquery,
+		metadataSelect(table),
 		parseTableIdentifier(table.Table).Sanitize(),
 		filterClause,
-		vectorCol,
 	)
 
-	args := append([]interface{}{formatVector(embedding), topN}, extraArgs...)
-	args = append(args, filterArgs...)
+	args := append([]interface{}{queryText, topN}, filterArgs...)
 	return query, args, nil
 }
 
-// VectorSearch performs a vector similarity search using pgvector.
-// Returns results ordered by similarity (highest first).
+// TextSearch ranks a table's documents against queryText with
+// PostgreSQL full-text search, for tables whose lexical_search is
+// postgres_fts. Returns results ordered by ts_rank (highest first).
 // The filter parameter allows additional WHERE conditions from the API request.
-// If minSimilarity is non-nil, results below that cosine similarity are excluded.
-func (p *Pool) VectorSearch(
+func (p *Pool) TextSearch(
 	ctx context.Context,
-	embedding []float32,
+	queryText string,
 	table config.TableSource,
 	topN int,
 	filter *config.Filter,
-	minSimilarity *float64,
 ) ([]SearchResult, error) {
-	query, args, err := buildVectorSearchQuery(embedding, table, topN, filter, minSimilarity)
+	query, args, err := buildTextSearchQuery(queryText, table, topN, filter, FilterVarsFrom(ctx))
 	if err != nil {
 		return nil, err
 	}
 
 	rows, err := p.pool.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("vector search failed: %w", err)
+		return nil, fmt.Errorf("full-text search failed: %w", err)
 	}
 	defer rows.Close()
 
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.ID, &r.Content, &r.Score); err != nil {
+		r.SourceInfo, err = scanWithMetadata(rows, table, &r.ID, &r.Content, &r.Score)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 		results = append(results, r)
@@ -163,19 +515,19 @@ func (p *Pool) VectorSearch(
 	return results, nil
 }
 
-// FetchDocuments fetches all documents from a table for BM25 indexing.
-// Returns a map of document ID to content.
-// The filter parameter allows additional WHERE conditions from the API request.
-func (p *Pool) FetchDocuments(
-	ctx context.Context,
+// buildFetchDocumentsQuery constructs the SQL query and argument list
+// for FetchDocuments. Extracted for testability.
+func buildFetchDocumentsQuery(
 	table config.TableSource,
 	filter *config.Filter,
-) (map[string]string, error) {
+	vars FilterVars,
+	maxRows int,
+) (string, []interface{}, error) {
 	// Build filter clause combining config and request filters
 	// Start at param index 1 (no initial params in this query)
-	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, 1)
+	filterClause, filterArgs, err := buildFilterClause(table.Filter, filter, vars, 1)
 	if err != nil {
-		return nil, fmt.Errorf("invalid filter: %w", err)
+		return "", nil, fmt.Errorf("invalid filter: %w", err)
 	}
 
 	// Build base WHERE clause for non-null content
@@ -190,45 +542,77 @@ func (p *Pool) FetchDocuments(
 	}
 
 	// Determine ID expression: use configured id_column, or ROW_NUMBER() fallback
-	var query string
+	var idExpr string
 	if table.IDColumn != "" {
-		// Use configured ID column
-		query = fmt.Sprintf(`
-		SELECT
-			%s::text AS id,
-			%s AS content
-		FROM %s%s`,
-			pgx.Identifier{table.IDColumn}.Sanitize(),
-			pgx.Identifier{table.TextColumn}.Sanitize(),
-			parseTableIdentifier(table.Table).Sanitize(),
-			filterClause,
-		)
+		idExpr = pgx.Identifier{table.IDColumn}.Sanitize() + "::text"
 	} else {
 		// Fallback to ROW_NUMBER() for views or tables without explicit ID
-		query = fmt.Sprintf(`
+		idExpr = "ROW_NUMBER() OVER()::text"
+	}
+
+	var lexical strings.Builder
+	for _, lc := range table.LexicalColumns {
+		fmt.Fprintf(&lexical, ",\n\t\t\tCOALESCE(%s::text, '')", pgx.Identifier{lc.Column}.Sanitize())
+	}
+
+	query := fmt.Sprintf(`
 		SELECT
-			ROW_NUMBER() OVER()::text AS id,
-			%s AS content
+			%s AS id,
+			%s AS content%s%s
 		FROM %s%s`,
-			pgx.Identifier{table.TextColumn}.Sanitize(),
-			parseTableIdentifier(table.Table).Sanitize(),
-			filterClause,
-		)
+		idExpr,
+		pgx.Identifier{table.TextColumn}.Sanitize(),
+		lexical.String(),
+		metadataSelect(table),
+		parseTableIdentifier(table.Table).Sanitize(),
+		filterClause,
+	)
+	// One row more than the limit is enough to tell it was exceeded.
+	if maxRows > 0 {
+		query += fmt.Sprintf("\n\t\tLIMIT %d", maxRows+1)
 	}
+	return query, filterArgs, nil
+}
 
-	rows, err := p.pool.Query(ctx, query, filterArgs...)
+// FetchDocuments fetches all documents from a table for BM25 indexing.
+// Returns a map of document ID to document.
+// The filter parameter allows additional WHERE conditions from the API request.
+// It fails with ErrFetchLimitExceeded, rather than loading the rest of
+// the table, once limits are exceeded.
+func (p *Pool) FetchDocuments(
+	ctx context.Context,
+	table config.TableSource,
+	filter *config.Filter,
+	limits FetchLimits,
+) (map[string]Document, error) {
+	query, args, err := buildFetchDocumentsQuery(table, filter, FilterVarsFrom(ctx), limits.MaxRows)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := p.pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch documents: %w", err)
 	}
 	defer rows.Close()
 
-	docs := make(map[string]string)
+	budget := fetchBudget{limits: limits, table: table.Table}
+	docs := make(map[string]Document)
 	for rows.Next() {
-		var id, content string
-		if err := rows.Scan(&id, &content); err != nil {
+		var id string
+		doc := Document{Lexical: make([]string, len(table.LexicalColumns))}
+		dest := []interface{}{&id, &doc.Content}
+		for i := range doc.Lexical {
+			dest = append(dest, &doc.Lexical[i])
+		}
+		doc.SourceInfo, err = scanWithMetadata(rows, table, dest...)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
-		docs[id] = content
+		if err := budget.add(doc); err != nil {
+			return nil, err
+		}
+		docs[id] = doc
 	}
 
 	if err := rows.Err(); err != nil {
@@ -10,6 +10,7 @@
 package database
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -32,7 +33,7 @@ func TestBuildVectorSearchQuery_SelectsIDColumn(t *testing.T) {
 	}
 
 	query, _, err := buildVectorSearchQuery(
-		[]float32{0.1, 0.2, 0.3}, table, 5, nil, nil,
+		[]float32{0.1, 0.2, 0.3}, table, 5, nil, nil, nil,
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -72,7 +73,7 @@ func TestBuildVectorSearchQuery_NoIDColumnEmitsEmptyID(t *testing.T) {
 	}
 
 	query, _, err := buildVectorSearchQuery(
-		[]float32{0.1, 0.2, 0.3}, table, 5, nil, nil,
+		[]float32{0.1, 0.2, 0.3}, table, 5, nil, nil, nil,
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -106,7 +107,7 @@ func TestBuildVectorSearchQuery_IDColumnWithFilterAndMinSimilarity(t *testing.T)
 	}
 
 	query, args, err := buildVectorSearchQuery(
-		[]float32{0.1, 0.2, 0.3}, table, 5, filter, &min,
+		[]float32{0.1, 0.2, 0.3}, table, 5, filter, nil, &min,
 	)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
@@ -130,3 +131,383 @@ func TestBuildVectorSearchQuery_IDColumnWithFilterAndMinSimilarity(t *testing.T)
 		t.Errorf("unexpected args: %v", args)
 	}
 }
+
+// TestBuildSearchQueries_SelectMetadataColumns verifies that the search
+// queries select the configured metadata columns after their fixed columns,
+// which is the order scanWithMetadata expects.
+// TestBuildVectorSearchQuery_Rescore verifies the two-stage search of a
+// quantized table: candidates are ordered by the quantized column with the
+// filter applied, then scored, thresholded and ordered by the
+// full-precision column.
+func TestBuildVectorSearchQuery_Rescore(t *testing.T) {
+	minSim := 0.7
+	filter := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "product", Operator: "=", Value: "pgedge"},
+	}}
+
+	tests := []struct {
+		name       string
+		rescore    config.RescoreConfig
+		wantOrder  string
+		wantLimit  string
+		wantFilter string
+	}{
+		{
+			name:      "halfvec",
+			rescore:   config.RescoreConfig{Column: "embedding", Quantization: config.QuantizationHalfvec},
+			wantOrder: `ORDER BY "embedding_half" <=> $1::vector::halfvec`,
+			wantLimit: "LIMIT 20",
+		},
+		{
+			name:      "binary",
+			rescore:   config.RescoreConfig{Column: "embedding", Quantization: config.QuantizationBinary, Candidates: 50},
+			wantOrder: `ORDER BY "embedding_half" <~> binary_quantize($1::vector)`,
+			wantLimit: "LIMIT 50",
+		},
+		{
+			name:      "candidates below top_n",
+			rescore:   config.RescoreConfig{Column: "embedding", Quantization: config.QuantizationHalfvec, Candidates: 2},
+			wantOrder: `ORDER BY "embedding_half" <=> $1::vector::halfvec`,
+			wantLimit: "LIMIT 5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := config.TableSource{
+				Table:        "public.chunks",
+				TextColumn:   "content",
+				VectorColumn: "embedding_half",
+				IDColumn:     "doc_id",
+				Rescore:      tt.rescore,
+			}
+			query, args, err := buildVectorSearchQuery([]float32{0.1, 0.2}, table, 5, filter, nil, &minSim)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for _, want := range []string{
+				`1 - ("embedding" <=> $1::vector) AS score`,
+				`FROM "public"."chunks" WHERE ("product" = $4) AND "embedding_half" IS NOT NULL AND "embedding" IS NOT NULL`,
+				tt.wantOrder,
+				tt.wantLimit,
+				`WHERE 1 - ("embedding" <=> $1::vector) >= $3`,
+				`ORDER BY "embedding" <=> $1::vector
+		LIMIT $2`,
+			} {
+				if !strings.Contains(query, want) {
+					t.Errorf("query missing %q\nquery: %s", want, query)
+				}
+			}
+			if len(args) != 4 || args[1] != 5 || args[2] != minSim || args[3] != "pgedge" {
+				t.Errorf("unexpected args: %v", args)
+			}
+		})
+	}
+}
+
+// TestBuildVectorSearchQuery_Distance verifies that each distance
+// metric orders rows by its own operator, so a matching index can be
+// used, and scores them higher for closer rows, and that the query
+// vector is cast to the column's type.
+func TestBuildVectorSearchQuery_Distance(t *testing.T) {
+	minSim := 0.5
+	tests := []struct {
+		name      string
+		distance  string
+		typ       string
+		wantScore string
+		wantOrder string
+	}{
+		{"cosine by default", "", "",
+			`1 - ("embedding" <=> $1::vector)`, `ORDER BY "embedding" <=> $1::vector`},
+		{"l2", config.DistanceL2, "",
+			`1 / (1 + ("embedding" <-> $1::vector))`, `ORDER BY "embedding" <-> $1::vector`},
+		{"inner product", config.DistanceInnerProduct, config.VectorTypeVector,
+			`-("embedding" <#> $1::vector)`, `ORDER BY "embedding" <#> $1::vector`},
+		{"halfvec", config.DistanceCosine, config.VectorTypeHalfvec,
+			`1 - ("embedding" <=> $1::vector::halfvec)`, `ORDER BY "embedding" <=> $1::vector::halfvec`},
+		{"sparsevec l2", config.DistanceL2, config.VectorTypeSparsevec,
+			`1 / (1 + ("embedding" <-> $1::vector::sparsevec))`, `ORDER BY "embedding" <-> $1::vector::sparsevec`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := config.TableSource{
+				Table:        "chunks",
+				TextColumn:   "content",
+				VectorColumn: "embedding",
+				Distance:     tt.distance,
+				VectorType:   tt.typ,
+			}
+			query, _, err := buildVectorSearchQuery([]float32{0.1}, table, 5, nil, nil, &minSim)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range []string{tt.wantScore + " AS score", tt.wantScore + " >= $3", tt.wantOrder} {
+				if !strings.Contains(query, want) {
+					t.Errorf("query missing %q\nquery: %s", want, query)
+				}
+			}
+		})
+	}
+}
+
+// TestBuildVectorSearchQuery_RescoreDistance verifies that both stages
+// of a two-stage search use the table's distance metric.
+func TestBuildVectorSearchQuery_RescoreDistance(t *testing.T) {
+	table := config.TableSource{
+		Table:        "chunks",
+		TextColumn:   "content",
+		VectorColumn: "embedding_half",
+		Distance:     config.DistanceInnerProduct,
+		Rescore:      config.RescoreConfig{Column: "embedding", Quantization: config.QuantizationHalfvec},
+	}
+	query, _, err := buildVectorSearchQuery([]float32{0.1}, table, 5, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		`-("embedding" <#> $1::vector) AS score`,
+		`ORDER BY "embedding_half" <#> $1::vector::halfvec`,
+		`ORDER BY "embedding" <#> $1::vector
+		LIMIT $2`,
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("query missing %q\nquery: %s", want, query)
+		}
+	}
+}
+
+// TestVectorSearchSettings verifies that only the pgvector settings a
+// table sets are applied, each scoped to the search's transaction.
+func TestVectorSearchSettings(t *testing.T) {
+	if got := vectorSearchSettings(config.VectorSearchOptions{}); len(got) != 0 {
+		t.Errorf("expected no statements without settings, got %v", got)
+	}
+
+	got := vectorSearchSettings(config.VectorSearchOptions{Probes: 10, EfSearch: 80})
+	want := []string{"SET LOCAL ivfflat.probes = 10", "SET LOCAL hnsw.ef_search = 80"}
+	if strings.Join(got, ";") != strings.Join(want, ";") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	got = vectorSearchSettings(config.VectorSearchOptions{EfSearch: 40})
+	if len(got) != 1 || got[0] != "SET LOCAL hnsw.ef_search = 40" {
+		t.Errorf("expected only ef_search set, got %v", got)
+	}
+}
+
+func TestBuildSearchQueries_SelectMetadataColumns(t *testing.T) {
+	table := config.TableSource{
+		Table:           "public.chunks",
+		TextColumn:      "content",
+		VectorColumn:    "embedding",
+		IDColumn:        "doc_id",
+		MetadataColumns: []string{"title", "source url"},
+	}
+
+	vectorQuery, _, err := buildVectorSearchQuery(
+		[]float32{0.1, 0.2, 0.3}, table, 5, nil, nil, nil,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fetchQuery, _, err := buildFetchDocumentsQuery(table, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	textQuery, _, err := buildTextSearchQuery("replication", table, 5, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, query := range map[string]string{"vector": vectorQuery, "fetch": fetchQuery, "text": textQuery} {
+		title := strings.Index(query, `"title"`)
+		url := strings.Index(query, `"source url"`)
+		from := strings.Index(query, "FROM")
+		if title < 0 || url < 0 {
+			t.Errorf("%s query missing metadata columns\nquery: %s", name, query)
+			continue
+		}
+		if !(title < url && url < from) || title < strings.Index(query, "AS content") {
+			t.Errorf("%s query selects metadata columns out of order\nquery: %s", name, query)
+		}
+	}
+}
+
+func TestBuildFetchDocumentsQuery_NoIDColumnUsesRowNumber(t *testing.T) {
+	table := config.TableSource{
+		Table:        "docs_view",
+		TextColumn:   "content",
+		VectorColumn: "embedding",
+	}
+
+	query, args, err := buildFetchDocumentsQuery(table, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(query, "ROW_NUMBER() OVER()::text AS id") {
+		t.Errorf("query missing ROW_NUMBER fallback\nquery: %s", query)
+	}
+	if !strings.Contains(query, `"content" IS NOT NULL`) {
+		t.Errorf("query missing content guard\nquery: %s", query)
+	}
+	if len(args) != 0 {
+		t.Errorf("unexpected args: %v", args)
+	}
+}
+
+func TestBuildFetchDocumentsQuery_LimitsRows(t *testing.T) {
+	table := config.TableSource{Table: "docs", TextColumn: "content", VectorColumn: "embedding", IDColumn: "id"}
+
+	query, _, err := buildFetchDocumentsQuery(table, nil, nil, 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(query, "LIMIT 1001") {
+		t.Errorf("expected one row more than the limit to be fetched\nquery: %s", query)
+	}
+
+	query, _, err = buildFetchDocumentsQuery(table, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(query, "LIMIT") {
+		t.Errorf("expected no limit\nquery: %s", query)
+	}
+}
+
+func TestFetchBudget(t *testing.T) {
+	doc := Document{Content: "streaming replication", Lexical: []string{"WAL"}}
+
+	b := fetchBudget{limits: FetchLimits{MaxRows: 2}, table: "docs"}
+	for i := 0; i < 2; i++ {
+		if err := b.add(doc); err != nil {
+			t.Fatalf("row %d: unexpected error: %v", i+1, err)
+		}
+	}
+	err := b.add(doc)
+	if !errors.Is(err, ErrFetchLimitExceeded) || !strings.Contains(err.Error(), "more than 2 matching rows") ||
+		!strings.Contains(err.Error(), "lexical_search: postgres_fts") {
+		t.Errorf("expected the row limit to be exceeded, got %v", err)
+	}
+
+	// Content and lexical columns both count towards the bytes.
+	b = fetchBudget{limits: FetchLimits{MaxBytes: 47}, table: "docs"}
+	if err := b.add(doc); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.add(doc); !errors.Is(err, ErrFetchLimitExceeded) || !strings.Contains(err.Error(), "47 bytes") {
+		t.Errorf("expected the byte limit to be exceeded, got %v", err)
+	}
+
+	b = fetchBudget{table: "docs"}
+	for i := 0; i < 1000; i++ {
+		if err := b.add(doc); err != nil {
+			t.Fatalf("expected no limits, got %v", err)
+		}
+	}
+}
+
+// TestBuildFetchDocumentsQuery_SelectsLexicalColumns verifies that lexical
+// columns are selected as text, with NULLs as "", between the content and
+// the metadata columns, matching the order FetchDocuments scans them in.
+func TestBuildFetchDocumentsQuery_SelectsLexicalColumns(t *testing.T) {
+	table := config.TableSource{
+		Table:           "docs",
+		TextColumn:      "content",
+		VectorColumn:    "embedding",
+		IDColumn:        "id",
+		LexicalColumns:  []config.LexicalColumn{{Column: "title", Boost: 3}, {Column: "headings"}},
+		MetadataColumns: []string{"url"},
+	}
+
+	query, _, err := buildFetchDocumentsQuery(table, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content := strings.Index(query, "AS content")
+	title := strings.Index(query, `COALESCE("title"::text, '')`)
+	headings := strings.Index(query, `COALESCE("headings"::text, '')`)
+	url := strings.Index(query, `"url"`)
+	if title < 0 || headings < 0 {
+		t.Fatalf("query missing lexical columns\nquery: %s", query)
+	}
+	if !(content < title && title < headings && headings < url) {
+		t.Errorf("query selects columns out of order\nquery: %s", query)
+	}
+
+	vectorQuery, _, err := buildVectorSearchQuery([]float32{0.1}, table, 5, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(vectorQuery, "title") {
+		t.Errorf("vector query should not select lexical columns\nquery: %s", vectorQuery)
+	}
+}
+
+// TestBuildTextSearchQuery verifies the full-text search query: it
+// ranks the tsvector column when one is configured, and otherwise the
+// text column converted with the table's text search configuration;
+// it ORs the query's words; and request filters follow the query text
+// and limit parameters.
+func TestBuildTextSearchQuery(t *testing.T) {
+	filter := &config.Filter{
+		Conditions: []config.FilterCondition{
+			{Column: "product", Operator: "=", Value: "pgEdge"},
+		},
+	}
+	tests := []struct {
+		name     string
+		table    config.TableSource
+		document string
+		id       string
+	}{
+		{
+			name: "tsvector column",
+			table: config.TableSource{
+				Table: "public.chunks", TextColumn: "content", IDColumn: "doc_id",
+				LexicalSearch: config.LexicalSearchPostgresFTS, TSVectorColumn: "content_tsv",
+			},
+			document: `"content_tsv"`,
+			id:       `"doc_id"::text AS id`,
+		},
+		{
+			name: "computed from the text column",
+			table: config.TableSource{
+				Table: "public.chunks", TextColumn: "content",
+				LexicalSearch: config.LexicalSearchPostgresFTS, TextSearchConfig: "simple",
+			},
+			document: `to_tsvector('simple'::regconfig, "content")`,
+			id:       `''::text AS id`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := buildTextSearchQuery("streaming replication", tt.table, 5, filter, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !strings.Contains(query, tt.id) {
+				t.Errorf("query missing %s\nquery: %s", tt.id, query)
+			}
+			if !strings.Contains(query, "ts_rank("+tt.document+", ") ||
+				!strings.Contains(query, tt.document+" @@ ") {
+				t.Errorf("query does not rank and match %s\nquery: %s", tt.document, query)
+			}
+			if !strings.Contains(query, "plainto_tsquery(") || !strings.Contains(query, `' & ', ' | '`) {
+				t.Errorf("query does not OR the query's words\nquery: %s", query)
+			}
+			if !strings.Contains(query, `"product" = $3`) {
+				t.Errorf("query missing filter at $3\nquery: %s", query)
+			}
+			if len(args) != 3 || args[0] != "streaming replication" || args[1] != 5 || args[2] != "pgEdge" {
+				t.Errorf("unexpected args: %v", args)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// DefaultChunkTokens is the estimated size of a chunk when
+// ingest.chunk_tokens is not set.
+const DefaultChunkTokens = 400
+
+// Chunk is a piece of a document small enough to embed.
+type Chunk struct {
+	Text string
+	Page int // The page the chunk was taken from; 0 for unpaged documents
+}
+
+// segment is a paragraph, sentence or run of words of a page, the
+// units chunks are assembled from.
+type segment struct {
+	text      string
+	paragraph bool // starts a paragraph, so is joined with a blank line
+}
+
+var (
+	paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+	sentenceEnd    = regexp.MustCompile(`[.!?]["')\]]*\s+`)
+)
+
+// Split cuts a document into chunks of about chunkTokens tokens,
+// estimating a token as four bytes; zero uses DefaultChunkTokens.
+// Chunks end at paragraph boundaries where possible, then at sentence
+// ends, then between words, and never span pages. Each chunk after the first of a page repeats about
+// overlapTokens tokens from the end of the one before it.
+func Split(doc *Document, chunkTokens, overlapTokens int) []Chunk {
+	if chunkTokens <= 0 {
+		chunkTokens = DefaultChunkTokens
+	}
+	maxChars := chunkTokens * 4
+	overlapChars := overlapTokens * 4
+
+	var chunks []Chunk
+	for _, page := range doc.Pages {
+		for _, text := range pack(segments(page.Text, maxChars), maxChars, overlapChars) {
+			chunks = append(chunks, Chunk{Text: text, Page: page.Number})
+		}
+	}
+	return chunks
+}
+
+// segments splits text into paragraphs, breaking those longer than
+// maxChars into sentences and overlong sentences into runs of words.
+// Whitespace within a paragraph is collapsed to single spaces.
+func segments(text string, maxChars int) []segment {
+	var segs []segment
+	for _, para := range paragraphBreak.Split(text, -1) {
+		para = strings.Join(strings.Fields(para), " ")
+		if para == "" {
+			continue
+		}
+		if len(para) <= maxChars {
+			segs = append(segs, segment{text: para, paragraph: true})
+			continue
+		}
+
+		first := true
+		for _, sentence := range splitSentences(para) {
+			for _, piece := range splitWords(sentence, maxChars) {
+				segs = append(segs, segment{text: piece, paragraph: first})
+				first = false
+			}
+		}
+	}
+	return segs
+}
+
+// splitSentences splits a paragraph after each sentence-ending
+// punctuation mark followed by whitespace.
+func splitSentences(para string) []string {
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(para, -1) {
+		sentences = append(sentences, strings.TrimSpace(para[start:loc[1]]))
+		start = loc[1]
+	}
+	if rest := strings.TrimSpace(para[start:]); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// splitWords splits text into runs of words of at most maxChars bytes.
+// A single word longer than that is cut, on a character boundary.
+func splitWords(text string, maxChars int) []string {
+	if len(text) <= maxChars {
+		return []string{text}
+	}
+
+	var pieces []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			pieces = append(pieces, cur.String())
+			cur.Reset()
+		}
+	}
+	for _, word := range strings.Fields(text) {
+		for len(word) > maxChars {
+			flush()
+			cut := maxChars
+			for cut > 0 && !isRuneStart(word[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				cut = maxChars
+			}
+			pieces = append(pieces, word[:cut])
+			word = word[cut:]
+		}
+		if cur.Len() > 0 && cur.Len()+1+len(word) > maxChars {
+			flush()
+		}
+		if cur.Len() > 0 {
+			cur.WriteByte(' ')
+		}
+		cur.WriteString(word)
+	}
+	flush()
+	return pieces
+}
+
+func isRuneStart(b byte) bool { return b&0xC0 != 0x80 }
+
+// TrimToBoundary shortens text that was cut off mid-way, such as at a
+// token limit, to end at the last paragraph break, provided that keeps
+// at least half of it, or else at the last sentence end or paragraph
+// break, or else between words. Text with none of these is returned
+// as it is.
+func TrimToBoundary(text string) string {
+	para := -1
+	if locs := paragraphBreak.FindAllStringIndex(text, -1); len(locs) > 0 {
+		para = locs[len(locs)-1][0]
+		if para >= len(text)/2 {
+			return text[:para]
+		}
+	}
+	end := para
+	if locs := sentenceEnd.FindAllStringIndex(text, -1); len(locs) > 0 {
+		end = max(end, locs[len(locs)-1][1])
+	}
+	if end > 0 {
+		return strings.TrimRightFunc(text[:end], unicode.IsSpace)
+	}
+	if i := strings.LastIndexFunc(text, unicode.IsSpace); i > 0 {
+		return strings.TrimRightFunc(text[:i], unicode.IsSpace)
+	}
+	return text
+}
+
+// pack joins consecutive segments into chunks of at most maxChars
+// bytes. A new chunk starts with the trailing segments of the previous
+// one that fit in overlapChars, but always with at least one new
+// segment, so packing makes progress.
+func pack(segs []segment, maxChars, overlapChars int) []string {
+	var chunks []string
+	var cur []segment
+	size := 0
+	fresh := 0 // segments of cur not carried over from the previous chunk
+
+	joined := func(segs []segment) string {
+		var sb strings.Builder
+		for i, s := range segs {
+			if i > 0 {
+				if s.paragraph {
+					sb.WriteString("\n\n")
+				} else {
+					sb.WriteByte(' ')
+				}
+			}
+			sb.WriteString(s.text)
+		}
+		return sb.String()
+	}
+
+	for _, seg := range segs {
+		if fresh > 0 && size+2+len(seg.text) > maxChars {
+			chunks = append(chunks, joined(cur))
+
+			// Carry over the tail of the chunk as the overlap.
+			keep := len(cur)
+			carried := 0
+			for keep > 0 && carried+len(cur[keep-1].text) <= overlapChars {
+				carried += len(cur[keep-1].text) + 2
+				keep--
+			}
+			cur = append([]segment(nil), cur[keep:]...)
+			size = 0
+			for _, s := range cur {
+				size += len(s.text) + 2
+			}
+			fresh = 0
+			// Drop carried segments that leave no room for the new one.
+			for len(cur) > 0 && size+len(seg.text) > maxChars {
+				size -= len(cur[0].text) + 2
+				cur = cur[1:]
+			}
+		}
+		cur = append(cur, seg)
+		size += len(seg.text) + 2
+		fresh++
+	}
+	if fresh > 0 {
+		chunks = append(chunks, joined(cur))
+	}
+	return chunks
+}
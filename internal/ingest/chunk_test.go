@@ -0,0 +1,116 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	para := func(word string, n int) string {
+		return strings.TrimSpace(strings.Repeat(word+" ", n))
+	}
+
+	tests := []struct {
+		name    string
+		doc     Document
+		tokens  int
+		overlap int
+		want    []Chunk
+	}{
+		{
+			name: "paragraphs packed",
+			doc:  Document{Pages: []Page{{Text: "First  paragraph\nwraps.\n\nSecond.\n\n\nThird."}}},
+			want: []Chunk{{Text: "First paragraph wraps.\n\nSecond.\n\nThird."}},
+		},
+		{
+			name:   "split at paragraphs",
+			doc:    Document{Pages: []Page{{Text: para("aaa", 5) + "\n\n" + para("bbb", 5) + "\n\n" + para("ccc", 5)}}},
+			tokens: 10, // 40 bytes: two 19-byte paragraphs do not fit with the blank line
+			want: []Chunk{
+				{Text: para("aaa", 5)},
+				{Text: para("bbb", 5)},
+				{Text: para("ccc", 5)},
+			},
+		},
+		{
+			name:   "long paragraph split at sentences",
+			doc:    Document{Pages: []Page{{Text: "One two three. Four five six! Seven eight nine?"}}},
+			tokens: 8,
+			want: []Chunk{
+				{Text: "One two three. Four five six!"},
+				{Text: "Seven eight nine?"},
+			},
+		},
+		{
+			name:   "long sentence split between words",
+			doc:    Document{Pages: []Page{{Text: para("word", 12)}}},
+			tokens: 5,
+			want: []Chunk{
+				{Text: para("word", 4)},
+				{Text: para("word", 4)},
+				{Text: para("word", 4)},
+			},
+		},
+		{
+			name:    "overlap",
+			doc:     Document{Pages: []Page{{Text: "Alpha beta. Gamma delta. Epsilon zeta. Eta theta."}}},
+			tokens:  7,
+			overlap: 4,
+			want: []Chunk{
+				{Text: "Alpha beta. Gamma delta."},
+				{Text: "Gamma delta. Epsilon zeta."},
+				{Text: "Epsilon zeta. Eta theta."},
+			},
+		},
+		{
+			name: "pages kept apart",
+			doc:  Document{Pages: []Page{{Number: 1, Text: "One."}, {Number: 2, Text: " "}, {Number: 3, Text: "Three."}}},
+			want: []Chunk{{Text: "One.", Page: 1}, {Text: "Three.", Page: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(&tt.doc, tt.tokens, tt.overlap)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d chunks %+v, want %d", len(got), got, len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("chunk %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTrimToBoundary(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"paragraph", "First paragraph.\n\nSecond paragraph, cut off mid-sent", "First paragraph."},
+		{"sentence", "Short.\n\nA paragraph long enough to be worth keeping. Its second sentence is cut", "Short.\n\nA paragraph long enough to be worth keeping."},
+		{"closing quote", `He said "stop." Then he`, `He said "stop."`},
+		{"words", "A single sentence cut off mid-wo", "A single sentence cut off"},
+		{"one word", "Unbroken", "Unbroken"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TrimToBoundary(tt.text); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package ingest turns uploaded files into chunks of text ready to be
+// embedded and stored, and tracks the jobs that do so.
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrUnsupportedFormat is returned for files that are not PDF, HTML or
+// Markdown.
+var ErrUnsupportedFormat = errors.New("unsupported file format")
+
+// Format is the format of an uploaded file.
+type Format string
+
+// Supported upload formats.
+const (
+	FormatPDF      Format = "pdf"
+	FormatHTML     Format = "html"
+	FormatMarkdown Format = "markdown"
+)
+
+// Document is the text extracted from an uploaded file.
+type Document struct {
+	Filename string
+	Pages    []Page
+}
+
+// Page is the text of one page of a document. PDF pages are numbered
+// from 1; HTML and Markdown documents have a single page numbered 0.
+type Page struct {
+	Number int
+	Text   string
+}
+
+// File is an uploaded file to ingest.
+type File struct {
+	Name   string
+	Format Format
+	Data   []byte
+}
+
+// DetectFormat returns the format of a file from its name's extension,
+// falling back to the content type it was uploaded with.
+func DetectFormat(filename, contentType string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return FormatPDF, nil
+	case ".html", ".htm", ".xhtml":
+		return FormatHTML, nil
+	case ".md", ".markdown":
+		return FormatMarkdown, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/pdf":
+		return FormatPDF, nil
+	case "text/html", "application/xhtml+xml":
+		return FormatHTML, nil
+	case "text/markdown", "text/x-markdown":
+		return FormatMarkdown, nil
+	}
+	return "", fmt.Errorf("%w: %s; upload PDF, HTML or Markdown files", ErrUnsupportedFormat, filename)
+}
+
+// Parse extracts the text of a file in the given format, dropping
+// boilerplate such as HTML navigation, Markdown front matter and the
+// running headers and footers of PDF pages.
+func Parse(format Format, filename string, data []byte) (*Document, error) {
+	doc := &Document{Filename: filename}
+	switch format {
+	case FormatPDF:
+		pages, err := parsePDF(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+		}
+		doc.Pages = pages
+	case FormatHTML:
+		doc.Pages = []Page{{Text: tidyText(parseHTML(string(data)))}}
+	case FormatMarkdown:
+		doc.Pages = []Page{{Text: tidyText(parseMarkdown(string(data)))}}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+	return doc, nil
+}
+
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// tidyText trims the lines of extracted text and leaves at most one
+// blank line between paragraphs.
+func tidyText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\u00a0", " "), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
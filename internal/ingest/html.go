@@ -0,0 +1,231 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlBoilerplate are elements whose content is dropped: page chrome
+// such as navigation and footers, and content that is not text.
+var htmlBoilerplate = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true,
+	"template": true, "svg": true, "canvas": true, "iframe": true,
+	"nav": true, "header": true, "footer": true, "aside": true,
+	"form": true, "button": true, "select": true, "dialog": true,
+}
+
+// htmlRawText are elements whose content is not markup, so must be
+// skipped up to their end tag without being scanned for tags.
+var htmlRawText = map[string]bool{"script": true, "style": true, "textarea": true}
+
+// htmlBlocks are elements that start a new paragraph of text.
+var htmlBlocks = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "dd": true,
+	"details": true, "div": true, "dl": true, "dt": true,
+	"figcaption": true, "figure": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"li": true, "main": true, "ol": true, "p": true, "pre": true,
+	"section": true, "summary": true, "table": true, "tr": true, "ul": true,
+}
+
+// htmlVoid are elements that have no end tag.
+var htmlVoid = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// parseHTML extracts the readable text of an HTML page, one paragraph
+// per block element. Boilerplate elements are dropped, and when the
+// page marks its content with <main> or <article>, only that content
+// is kept.
+func parseHTML(src string) string {
+	var all, content strings.Builder
+	var skip string // boilerplate element being skipped
+	skipDepth := 0
+	contentDepth := 0 // depth inside <main> or <article>
+	sawContent := false
+	pre := 0
+
+	write := func(s string) {
+		all.WriteString(s)
+		if contentDepth > 0 {
+			content.WriteString(s)
+		}
+	}
+
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt < 0 {
+			lt = len(src)
+		}
+		if text := src[:lt]; text != "" && skipDepth == 0 {
+			text = html.UnescapeString(text)
+			if pre == 0 {
+				text = collapseSpace(text)
+			}
+			write(text)
+		}
+		src = src[lt:]
+		if src == "" {
+			break
+		}
+
+		name, closing, selfClosing, rest := scanTag(src)
+		if name == "" {
+			if len(rest) == len(src)-1 && skipDepth == 0 {
+				write("<") // A "<" that does not start a tag
+			}
+			src = rest
+			continue
+		}
+		src = rest
+
+		if skipDepth > 0 {
+			switch {
+			case name != skip || selfClosing:
+			case closing:
+				skipDepth--
+			default:
+				skipDepth++
+			}
+			continue
+		}
+
+		switch {
+		case closing:
+			if name == "pre" && pre > 0 {
+				pre--
+			}
+			if (name == "main" || name == "article") && contentDepth > 0 {
+				contentDepth--
+			}
+		case htmlBoilerplate[name] && !selfClosing:
+			if htmlRawText[name] {
+				src = skipRawText(src, name)
+				continue
+			}
+			skip, skipDepth = name, 1
+			continue
+		case htmlRawText[name]:
+			src = skipRawText(src, name)
+			continue
+		case name == "pre":
+			pre++
+		case name == "main" || name == "article":
+			contentDepth++
+			sawContent = true
+		}
+
+		switch {
+		case name == "br":
+			write("\n")
+		case htmlBlocks[name]:
+			write("\n\n")
+		case name == "td" || name == "th":
+			write(" ")
+		}
+	}
+
+	if sawContent {
+		return strings.TrimSpace(content.String())
+	}
+	return strings.TrimSpace(all.String())
+}
+
+// scanTag reads the markup at the start of src, which begins with "<".
+// It returns the lowercased tag name, whether it is an end tag or
+// self-closing, and the input after the markup. The name is empty for
+// comments, doctypes and processing instructions, and for a "<" that
+// does not start a tag, in which case only it is consumed.
+func scanTag(src string) (name string, closing, selfClosing bool, rest string) {
+	if strings.HasPrefix(src, "<!--") {
+		if end := strings.Index(src[4:], "-->"); end >= 0 {
+			return "", false, false, src[4+end+3:]
+		}
+		return "", false, false, ""
+	}
+	if strings.HasPrefix(src, "<!") || strings.HasPrefix(src, "<?") {
+		if end := strings.IndexByte(src, '>'); end >= 0 {
+			return "", false, false, src[end+1:]
+		}
+		return "", false, false, ""
+	}
+
+	i := 1
+	if i < len(src) && src[i] == '/' {
+		closing = true
+		i++
+	}
+	start := i
+	for i < len(src) && isTagNameChar(src[i]) {
+		i++
+	}
+	if i == start {
+		return "", false, false, src[1:]
+	}
+	name = strings.ToLower(src[start:i])
+
+	// Skip attributes, honouring quotes, up to the closing ">".
+	var quote byte
+	for ; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '>':
+			selfClosing = htmlVoid[name] || src[i-1] == '/'
+			return name, closing, selfClosing, src[i+1:]
+		}
+	}
+	return name, closing, htmlVoid[name], ""
+}
+
+func isTagNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+// skipRawText returns src after the end tag of the named raw text
+// element, or "" if it has none.
+func skipRawText(src, name string) string {
+	lower := strings.ToLower(src)
+	if end := strings.Index(lower, "</"+name); end >= 0 {
+		if gt := strings.IndexByte(src[end:], '>'); gt >= 0 {
+			return src[end+gt+1:]
+		}
+	}
+	return ""
+}
+
+// collapseSpace replaces each run of whitespace with a single space, as
+// a browser renders text outside <pre>.
+func collapseSpace(s string) string {
+	var sb strings.Builder
+	space := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\r', '\f':
+			if !space {
+				sb.WriteByte(' ')
+			}
+			space = true
+		default:
+			sb.WriteRune(r)
+			space = false
+		}
+	}
+	return sb.String()
+}
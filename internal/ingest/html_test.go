@@ -0,0 +1,54 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import "testing"
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "boilerplate dropped",
+			src: `<!DOCTYPE html><html><head><title>Docs</title><style>p { color: red }</style></head>
+<body><nav><a href="/">Home</a></nav>
+<h1>Replication</h1><p>Streaming   replication sends
+WAL&nbsp;to a <b>standby</b>.</p><!-- hidden -->
+<script>if (a < b) { document.write("<p>no</p>") }</script>
+<footer>&copy; pgEdge</footer></body></html>`,
+			want: "Replication\n\nStreaming replication sends WAL to a standby.",
+		},
+		{
+			name: "main content preferred",
+			src:  `<body><div>Sign in</div><main><p>Only this.</p></main><div>Related posts</div></body>`,
+			want: "Only this.",
+		},
+		{
+			name: "line breaks and tables",
+			src:  `<p>a &lt; b<br>c</p><table><tr><td>x</td><td>y</td></tr></table>`,
+			want: "a < b\nc\n\nx  y",
+		},
+		{
+			name: "nested boilerplate",
+			src:  `<aside><aside>inner</aside>still aside</aside><p>kept</p>`,
+			want: "kept",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tidyText(parseHTML(tt.src)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
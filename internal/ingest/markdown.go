@@ -0,0 +1,115 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFrontMatter   = regexp.MustCompile(`(?s)\A(?:---|\+\+\+)[ \t]*\r?\n.*?\n(?:---|\+\+\+|\.\.\.)[ \t]*(?:\r?\n|\z)`)
+	mdComment       = regexp.MustCompile(`(?s)<!--.*?-->`)
+	mdFence         = regexp.MustCompile("^\\s*(```|~~~)")
+	mdHeading       = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.*?)(?:\s+#+)?\s*$`)
+	mdSetextRule    = regexp.MustCompile(`^\s{0,3}(?:=+|-+)\s*$`)
+	mdThematicBreak = regexp.MustCompile(`^\s{0,3}(?:(?:\*\s*){3,}|(?:-\s*){3,}|(?:_\s*){3,})$`)
+	mdRefDefinition = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	mdTableRule     = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdBlockquote    = regexp.MustCompile(`^\s{0,3}(?:>\s?)+`)
+	mdListMarker    = regexp.MustCompile(`^(\s*)(?:[-*+]|\d{1,9}[.)])\s+(?:\[[ xX]\]\s+)?`)
+	mdImage         = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink          = regexp.MustCompile(`\[([^\]]+)\](?:\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink      = regexp.MustCompile(`<((?:https?|mailto):[^>\s]+)>`)
+	mdInlineHTML    = regexp.MustCompile(`</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+	mdCodeSpan      = regexp.MustCompile("`+([^`]+)`+")
+	mdStrongStar    = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	mdStrongUnder   = regexp.MustCompile(`__(\S(?:.*?\S)?)__`)
+	mdEmphasisStar  = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdEmphasisUnder = regexp.MustCompile(`(^|[^\w])_(\S(?:[^_]*?\S)?)_([^\w]|$)`)
+	mdStrike        = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+)
+
+// parseMarkdown extracts the text of a Markdown document. Front matter,
+// comments, link targets, reference definitions and formatting markup
+// are dropped; headings, list items and code blocks are kept as text.
+func parseMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = mdFrontMatter.ReplaceAllString(src, "")
+	src = mdComment.ReplaceAllString(src, "")
+
+	var out []string
+	fence := ""
+	for _, line := range strings.Split(src, "\n") {
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "":
+				fence = m[1]
+				out = append(out, "")
+				continue
+			case m[1] == fence:
+				fence = ""
+				out = append(out, "")
+				continue
+			}
+		}
+		if fence != "" {
+			out = append(out, line) // Code is kept as written
+			continue
+		}
+
+		switch {
+		case mdRefDefinition.MatchString(line), mdTableRule.MatchString(line) && strings.Contains(line, "|"):
+			continue
+		case mdThematicBreak.MatchString(line):
+			out = append(out, "")
+			continue
+		case mdSetextRule.MatchString(line) && len(out) > 0 && strings.TrimSpace(out[len(out)-1]) != "":
+			out = append(out, "") // Underlined heading
+			continue
+		}
+
+		line = mdBlockquote.ReplaceAllString(line, "")
+		if m := mdHeading.FindStringSubmatch(line); m != nil {
+			// Headings are paragraphs of their own.
+			out = append(out, "", markdownInline(m[1]), "")
+			continue
+		}
+		if mdListMarker.MatchString(line) {
+			// List items are paragraphs of their own.
+			line = mdListMarker.ReplaceAllString(line, "$1")
+			out = append(out, "", markdownInline(line))
+			continue
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "|") {
+			line = strings.ReplaceAll(strings.Trim(strings.TrimSpace(line), "|"), "|", " ")
+		}
+		out = append(out, markdownInline(line))
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// markdownInline strips the inline markup of a line of Markdown,
+// keeping link and image text and decoding HTML entities.
+func markdownInline(line string) string {
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdAutolink.ReplaceAllString(line, "$1")
+	line = mdInlineHTML.ReplaceAllString(line, "")
+	line = mdCodeSpan.ReplaceAllString(line, "$1")
+	line = mdStrongStar.ReplaceAllString(line, "$1")
+	line = mdStrongUnder.ReplaceAllString(line, "$1")
+	line = mdEmphasisStar.ReplaceAllString(line, "$1")
+	line = mdEmphasisUnder.ReplaceAllString(line, "$1$2$3")
+	line = mdStrike.ReplaceAllString(line, "$1")
+	line = strings.TrimSuffix(line, "\\") // Hard line break
+	return html.UnescapeString(line)
+}
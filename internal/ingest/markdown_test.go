@@ -0,0 +1,38 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import "testing"
+
+func TestParseMarkdown(t *testing.T) {
+	src := "---\ntitle: Replication\ntags: [wal]\n---\n" +
+		"# Streaming *replication* #\n\n" +
+		"<!-- TODO: diagrams -->\n" +
+		"Send **WAL** to a [standby](https://example.com/standby) with `pg_basebackup`,\n" +
+		"using the snake_case setting ![diagram](wal.png).\n\n" +
+		"- one\n- [x] two\n\n" +
+		"```sql\nSELECT * FROM pg_stat_replication;\n```\n\n" +
+		"| Setting | Value |\n|---|---|\n| wal_level | replica |\n\n" +
+		"***\n" +
+		"[standby]: https://example.com/standby\n" +
+		"> Quoted &amp; kept\n"
+
+	want := "Streaming replication\n\n" +
+		"Send WAL to a standby with pg_basebackup,\n" +
+		"using the snake_case setting diagram.\n\n" +
+		"one\n\ntwo\n\n" +
+		"SELECT * FROM pg_stat_replication;\n\n" +
+		"Setting   Value\nwal_level   replica\n\n" +
+		"Quoted & kept"
+
+	if got := tidyText(parseMarkdown(src)); got != want {
+		t.Errorf("got:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,1291 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Only as much of PDF is implemented as extracting text needs. Objects
+// are located by scanning for "n g obj" rather than through the
+// cross-reference table, so damaged tables and incremental updates are
+// read alike; compressed streams and object streams are decoded; and
+// text is mapped to Unicode through each font's ToUnicode CMap.
+
+// Uploads are untrusted, so parsing one is bounded however it is
+// crafted: a document past one of these limits fails with
+// errPDFTooComplex rather than exhausting the stack, memory or time.
+const (
+	// maxPDFStreamBytes bounds the decoded size of a single stream; a
+	// longer one is truncated.
+	maxPDFStreamBytes = 64 << 20
+
+	// maxPDFDecodedBytes bounds the bytes decoded from all of a
+	// document's streams, counting a stream each time it is used, so
+	// neither small streams that decompress hugely nor one drawn many
+	// times can add up without limit.
+	maxPDFDecodedBytes = 256 << 20
+
+	// maxPDFScannedBytes bounds the bytes read to parse a document's
+	// objects. Objects can overlap, so a crafted file could otherwise
+	// have each one read the rest of the file.
+	maxPDFScannedBytes = 256 << 20
+
+	// maxPDFNesting bounds how deeply arrays and dictionaries may nest,
+	// and how long a chain of objects may refer to one another while
+	// being loaded, since each level is a recursive call.
+	maxPDFNesting = 64
+
+	// maxPDFCMapCodes bounds the character codes the fonts of a
+	// document may map, since a short range can map thousands.
+	maxPDFCMapCodes = 1 << 20
+
+	// maxPDFTextBytes bounds the text extracted from a document, since
+	// a font can map one character code to a long string.
+	maxPDFTextBytes = 64 << 20
+)
+
+// maxPDFFormDepth bounds how deeply form XObjects may nest.
+const maxPDFFormDepth = 8
+
+var (
+	errPDFEncrypted  = errors.New("encrypted PDFs are not supported")
+	errPDFNoPages    = errors.New("no pages found")
+	errPDFTooComplex = errors.New("PDF is too complex to parse")
+
+	errPDFTooDeep = fmt.Errorf("%w: objects nested more than %d deep",
+		errPDFTooComplex, maxPDFNesting)
+	errPDFTooLarge = fmt.Errorf("%w: more than %d MiB decoded",
+		errPDFTooComplex, maxPDFDecodedBytes>>20)
+	errPDFTooLong = fmt.Errorf("%w: more than %d MiB read to parse its objects",
+		errPDFTooComplex, maxPDFScannedBytes>>20)
+	errPDFTooManyCodes = fmt.Errorf("%w: fonts map more than %d character codes",
+		errPDFTooComplex, maxPDFCMapCodes)
+	errPDFTooMuchText = fmt.Errorf("%w: more than %d MiB of text",
+		errPDFTooComplex, maxPDFTextBytes>>20)
+)
+
+// PDF values. Strings are held as their raw bytes in a Go string.
+type (
+	pdfName     string
+	pdfRef      struct{ num, gen int }
+	pdfDict     map[pdfName]any
+	pdfArray    []any
+	pdfOperator string // A keyword, such as a content stream operator
+	pdfDelim    string // A closing "]", ">>" or a brace
+	pdfStream   struct {
+		dict pdfDict
+		data []byte // Still encoded
+	}
+)
+
+// pdfLexer reads PDF tokens and values from data.
+type pdfLexer struct {
+	data  []byte
+	pos   int
+	depth int // Arrays and dictionaries being read
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments.
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// value reads the next value. Keywords are returned as pdfOperator and
+// misplaced closing delimiters as pdfDelim; "n g R" is returned as a
+// pdfRef. It returns io.EOF at the end of the data, and errPDFTooDeep
+// for arrays and dictionaries nested more than maxPDFNesting deep.
+func (l *pdfLexer) value() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		return l.literalString(), nil
+	case c == '<' && l.peek(1) == '<':
+		l.pos += 2
+		return l.dict()
+	case c == '<':
+		return l.hexString(), nil
+	case c == '>' && l.peek(1) == '>':
+		l.pos += 2
+		return pdfDelim(">>"), nil
+	case c == '[':
+		l.pos++
+		return l.array()
+	case c == ']' || c == '{' || c == '}' || c == '>' || c == ')':
+		l.pos++
+		return pdfDelim([]byte{c}), nil
+	case c == '/':
+		return l.name(), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseInt(word, 10, 64); err == nil {
+		return l.maybeRef(n), nil
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil && strings.ContainsAny(word, "0123456789") {
+		return f, nil
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfOperator(word), nil
+}
+
+func (l *pdfLexer) peek(n int) byte {
+	if l.pos+n < len(l.data) {
+		return l.data[l.pos+n]
+	}
+	return 0
+}
+
+// maybeRef returns the reference "num gen R" if the integer num just
+// read starts one, and otherwise num, leaving the position unchanged.
+func (l *pdfLexer) maybeRef(num int64) any {
+	save := l.pos
+	l.skipSpace()
+	start := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	if l.pos > start {
+		gen, err := strconv.Atoi(string(l.data[start:l.pos]))
+		l.skipSpace()
+		if err == nil && l.peek(0) == 'R' {
+			if next := l.peek(1); next == 0 || isPDFSpace(next) || isPDFDelimiter(next) {
+				l.pos++
+				return pdfRef{num: int(num), gen: gen}
+			}
+		}
+	}
+	l.pos = save
+	return num
+}
+
+// nest enters an array or dictionary, returning the function that
+// leaves it, or errPDFTooDeep past maxPDFNesting.
+func (l *pdfLexer) nest() (func(), error) {
+	if l.depth >= maxPDFNesting {
+		return nil, errPDFTooDeep
+	}
+	l.depth++
+	return func() { l.depth-- }, nil
+}
+
+func (l *pdfLexer) array() (pdfArray, error) {
+	leave, err := l.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	var arr pdfArray
+	for {
+		v, err := l.value()
+		if err != nil {
+			return arr, err
+		}
+		if v == pdfDelim("]") {
+			return arr, nil
+		}
+		arr = append(arr, v)
+	}
+}
+
+func (l *pdfLexer) dict() (pdfDict, error) {
+	leave, err := l.nest()
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	d := pdfDict{}
+	for {
+		k, err := l.value()
+		if err != nil {
+			return d, err
+		}
+		if k == pdfDelim(">>") {
+			return d, nil
+		}
+		key, ok := k.(pdfName)
+		if !ok {
+			continue // Malformed; resynchronize on the next name
+		}
+		v, err := l.value()
+		if err != nil {
+			return d, err
+		}
+		if v == pdfDelim(">>") {
+			return d, nil
+		}
+		d[key] = v
+	}
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++ // "/"
+	var sb strings.Builder
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if isPDFSpace(c) || isPDFDelimiter(c) {
+			break
+		}
+		if c == '#' && l.pos+2 < len(l.data) {
+			if b, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				sb.WriteByte(b[0])
+				l.pos += 3
+				continue
+			}
+		}
+		sb.WriteByte(c)
+		l.pos++
+	}
+	return pdfName(sb.String())
+}
+
+func (l *pdfLexer) literalString() string {
+	l.pos++ // "("
+	var sb strings.Builder
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return sb.String()
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return sb.String()
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					sb.WriteByte(byte(n))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func (l *pdfLexer) hexString() string {
+	l.pos++ // "<"
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	if l.pos < len(l.data) {
+		l.pos++ // ">"
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	b, _ := hex.DecodeString(string(digits))
+	return string(b)
+}
+
+// pdfFile is a PDF document whose objects are parsed on demand.
+type pdfFile struct {
+	data    []byte
+	offsets map[int]int // object number to the offset after "n g obj"
+	objects map[int]any // parsed objects
+	loading map[int]bool
+	cmaps   map[pdfCMapKey]*pdfCMap
+
+	// What parsing has used of the limits on untrusted input, and the
+	// first limit it passed, which stops it.
+	decoded   int
+	scanned   int
+	cmapCodes int
+	text      int
+	err       error
+}
+
+// fail records err if it is the first limit the document passed.
+// Other errors are left to the caller, as parsing skips what it cannot
+// read.
+func (f *pdfFile) fail(err error) {
+	if f.err == nil && errors.Is(err, errPDFTooComplex) {
+		f.err = err
+	}
+}
+
+// scan counts n bytes read to parse objects against maxPDFScannedBytes.
+func (f *pdfFile) scan(n int) {
+	f.scanned += n
+	if f.scanned > maxPDFScannedBytes {
+		f.fail(errPDFTooLong)
+	}
+}
+
+func newPDFFile(data []byte) *pdfFile {
+	f := &pdfFile{
+		data:    data,
+		offsets: make(map[int]int),
+		objects: make(map[int]any),
+		loading: make(map[int]bool),
+		cmaps:   make(map[pdfCMapKey]*pdfCMap),
+	}
+	// Later definitions override earlier ones, as in incremental updates.
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("obj"))
+		if i < 0 {
+			break
+		}
+		at := pos + i
+		pos = at + len("obj")
+		if num, ok := pdfObjectHeader(data, at, pos); ok {
+			f.offsets[num] = pos
+		}
+	}
+	f.loadObjectStreams()
+	return f
+}
+
+// pdfObjectHeader reports whether the "obj" keyword from at to end in
+// data ends an "n g obj" header, preceded by whitespace, ">" or "]" or
+// starting the data, and returns n. The numbers are read backwards
+// from the keyword, so finding every header reads the data once.
+func pdfObjectHeader(data []byte, at, end int) (int, bool) {
+	isSpace := func(c byte) bool {
+		return c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+	}
+	isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+	if end < len(data) {
+		if c := data[end]; c == '_' || isDigit(c) || (c|0x20 >= 'a' && c|0x20 <= 'z') {
+			return 0, false
+		}
+	}
+	// Whitespace, the generation, whitespace and the number, backwards.
+	i := at
+	for _, class := range []func(byte) bool{isSpace, isDigit, isSpace, isDigit} {
+		from := i
+		for i > 0 && class(data[i-1]) {
+			i--
+		}
+		if i == from {
+			return 0, false
+		}
+	}
+	if i > 0 && !isSpace(data[i-1]) && data[i-1] != '>' && data[i-1] != ']' {
+		return 0, false
+	}
+	numEnd := i
+	for isDigit(data[numEnd]) {
+		numEnd++
+	}
+	num, err := strconv.Atoi(string(data[i:numEnd]))
+	return num, err == nil
+}
+
+// object returns the object with the given number, or nil.
+func (f *pdfFile) object(num int) any {
+	if v, ok := f.objects[num]; ok {
+		return v
+	}
+	off, ok := f.offsets[num]
+	if !ok || f.loading[num] || f.err != nil {
+		return nil
+	}
+	if len(f.loading) >= maxPDFNesting {
+		f.fail(errPDFTooDeep)
+		return nil
+	}
+	f.loading[num] = true
+	defer delete(f.loading, num)
+
+	v := f.parseAt(f.data, off)
+	f.objects[num] = v
+	return v
+}
+
+// parseAt parses the object at off in data, with the stream that
+// follows it, if any.
+func (f *pdfFile) parseAt(data []byte, off int) any {
+	l := &pdfLexer{data: data, pos: off}
+	v, err := l.value()
+	f.scan(l.pos - off)
+	if err != nil && err != io.EOF {
+		f.fail(err)
+		return nil
+	}
+	dict, ok := v.(pdfDict)
+	if !ok {
+		return v
+	}
+
+	l.skipSpace()
+	if !bytes.HasPrefix(data[l.pos:], []byte("stream")) {
+		return dict
+	}
+	start := l.pos + len("stream")
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	if n, ok := f.resolve(dict["Length"]).(int64); ok && n >= 0 && start+int(n) <= len(data) {
+		end := start + int(n)
+		after := &pdfLexer{data: data, pos: end}
+		after.skipSpace()
+		if bytes.HasPrefix(data[after.pos:], []byte("endstream")) {
+			return pdfStream{dict: dict, data: data[start:end]}
+		}
+	}
+	// A missing or wrong Length: the data runs up to "endstream".
+	end := bytes.Index(data[start:], []byte("endstream"))
+	if end < 0 {
+		f.scan(len(data) - start)
+		return pdfStream{dict: dict, data: data[start:]}
+	}
+	f.scan(end)
+	return pdfStream{dict: dict, data: bytes.TrimRight(data[start:start+end], "\r\n")}
+}
+
+// resolve follows v if it is a reference.
+func (f *pdfFile) resolve(v any) any {
+	for range 16 {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = f.object(ref.num)
+	}
+	return nil
+}
+
+func (f *pdfFile) dict(v any) pdfDict {
+	switch v := f.resolve(v).(type) {
+	case pdfDict:
+		return v
+	case pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// loadObjectStreams parses the objects held in object streams, which
+// PDF 1.5 and later files use to compress most of their objects.
+// Objects defined directly in the file take precedence.
+func (f *pdfFile) loadObjectStreams() {
+	nums := make([]int, 0, len(f.offsets))
+	for num := range f.offsets {
+		nums = append(nums, num)
+	}
+	slices.Sort(nums)
+
+	for _, num := range nums {
+		if f.err != nil {
+			return
+		}
+		s, ok := f.object(num).(pdfStream)
+		if !ok || s.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+		n, _ := f.resolve(s.dict["N"]).(int64)
+		first, _ := f.resolve(s.dict["First"]).(int64)
+		if first < 0 || int(first) > len(data) {
+			continue
+		}
+
+		header := &pdfLexer{data: data[:first]}
+		for range n {
+			objNum, err1 := header.value()
+			objOff, err2 := header.value()
+			if err1 != nil || err2 != nil {
+				break
+			}
+			on, ok1 := objNum.(int64)
+			oo, ok2 := objOff.(int64)
+			if !ok1 || !ok2 || int(first+oo) > len(data) {
+				break
+			}
+			if _, direct := f.offsets[int(on)]; direct {
+				continue
+			}
+			l := &pdfLexer{data: data, pos: int(first + oo)}
+			v, err := l.value()
+			f.scan(l.pos - int(first+oo))
+			if err != nil {
+				f.fail(err)
+				continue
+			}
+			f.objects[int(on)] = v
+		}
+	}
+}
+
+// decode returns the decoded data of a stream, counting it against
+// maxPDFDecodedBytes.
+func (f *pdfFile) decode(s pdfStream) ([]byte, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var filters []any
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{v}
+	case pdfArray:
+		filters = v
+	}
+	if p := f.dict(s.dict["DecodeParms"]); p != nil {
+		if pred, ok := f.resolve(p["Predictor"]).(int64); ok && pred > 1 {
+			return nil, fmt.Errorf("unsupported predictor %d", pred)
+		}
+	}
+
+	data := s.data
+	for _, filter := range filters {
+		var r io.Reader
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			r = zr
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			l := &pdfLexer{data: append([]byte("<"), data...)}
+			data = []byte(l.hexString())
+			continue
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			trimmed := bytes.TrimSpace(data)
+			trimmed = bytes.TrimPrefix(trimmed, []byte("<~"))
+			trimmed = bytes.TrimSuffix(trimmed, []byte("~>"))
+			r = ascii85.NewDecoder(bytes.NewReader(trimmed))
+		default:
+			return nil, fmt.Errorf("unsupported filter %v", filter)
+		}
+
+		// One byte past what the document has left tells running
+		// out from ending exactly at the limit.
+		limit := min(maxPDFStreamBytes, maxPDFDecodedBytes-f.decoded+1)
+		decoded, err := io.ReadAll(io.LimitReader(r, int64(limit)))
+		// Truncated streams are common; keep what could be decoded.
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && len(decoded) == 0 {
+			return nil, err
+		}
+		data = decoded
+	}
+	f.decoded += len(data)
+	if f.decoded > maxPDFDecodedBytes {
+		f.fail(errPDFTooLarge)
+		return nil, f.err
+	}
+	return data, nil
+}
+
+// trailer returns the document's trailer dictionary: the last trailer
+// in the file, or the last cross-reference stream's dictionary.
+func (f *pdfFile) trailer() pdfDict {
+	if i := bytes.LastIndex(f.data, []byte("trailer")); i >= 0 {
+		l := &pdfLexer{data: f.data, pos: i + len("trailer")}
+		v, err := l.value()
+		f.fail(err)
+		if v != nil {
+			if d, ok := v.(pdfDict); ok && d["Root"] != nil {
+				return d
+			}
+		}
+	}
+
+	var trailer pdfDict
+	best := -1
+	for num, off := range f.offsets {
+		if s, ok := f.object(num).(pdfStream); ok && s.dict["Type"] == pdfName("XRef") && off > best {
+			trailer, best = s.dict, off
+		}
+	}
+	return trailer
+}
+
+// catalog returns the document catalog.
+func (f *pdfFile) catalog(trailer pdfDict) pdfDict {
+	if root := f.dict(trailer["Root"]); root != nil {
+		return root
+	}
+	for num := range f.offsets {
+		if d, ok := f.object(num).(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+			return d
+		}
+	}
+	for _, v := range f.objects {
+		if d, ok := v.(pdfDict); ok && d["Type"] == pdfName("Catalog") {
+			return d
+		}
+	}
+	return nil
+}
+
+// pdfPage is a page with the resources it inherits.
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages walks the page tree in order.
+func (f *pdfFile) pages(root pdfDict) []pdfPage {
+	var pages []pdfPage
+	visits := 0 // Bounds the walk of a malformed tree with cycles
+	var walk func(node pdfDict, resources pdfDict, depth int)
+	walk = func(node pdfDict, resources pdfDict, depth int) {
+		visits++
+		if node == nil || depth > 64 || visits > 1<<16 {
+			return
+		}
+		if r := f.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+		kids, isTree := f.resolve(node["Kids"]).(pdfArray)
+		if !isTree || node["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: node, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(f.dict(kid), resources, depth+1)
+		}
+	}
+	walk(f.dict(root["Pages"]), nil, 0)
+	return pages
+}
+
+// parsePDF extracts the text of each page of a PDF document. Lines
+// repeated at the top or bottom of most pages, such as running headers
+// and page numbers, are dropped.
+func parsePDF(data []byte) ([]Page, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return nil, errors.New("not a PDF file")
+	}
+
+	f := newPDFFile(data)
+	trailer := f.trailer()
+	if f.err != nil {
+		return nil, f.err
+	}
+	if trailer["Encrypt"] != nil {
+		return nil, errPDFEncrypted
+	}
+	root := f.catalog(trailer)
+	if root == nil {
+		return nil, errPDFNoPages
+	}
+	pdfPages := f.pages(root)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if len(pdfPages) == 0 {
+		return nil, errPDFNoPages
+	}
+
+	pages := make([]Page, len(pdfPages))
+	for i, p := range pdfPages {
+		var text pdfText
+		f.runContent(f.contents(p.dict), p.resources, &text, 0)
+		if f.err != nil {
+			return nil, f.err
+		}
+		pages[i] = Page{Number: i + 1, Text: text.String()}
+	}
+	stripRunningLines(pages)
+	return pages, nil
+}
+
+// contents returns a page's content streams, decoded and joined.
+func (f *pdfFile) contents(page pdfDict) []byte {
+	var streams []any
+	switch v := f.resolve(page["Contents"]).(type) {
+	case pdfStream:
+		streams = []any{v}
+	case pdfArray:
+		streams = v
+	}
+
+	var buf bytes.Buffer
+	for _, s := range streams {
+		if s, ok := f.resolve(s).(pdfStream); ok {
+			if data, err := f.decode(s); err == nil {
+				buf.Write(data)
+				buf.WriteByte('\n')
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// runContent interprets a content stream, writing the text it shows.
+// Only the operators that show text or move between lines matter.
+func (f *pdfFile) runContent(data []byte, resources pdfDict, out *pdfText, depth int) {
+	fonts := make(map[pdfName]*pdfFont)
+	var font *pdfFont
+	var tm, tlm [6]float64 // Text matrix and text line matrix
+	leading := 0.0
+
+	moveTo := func(m [6]float64) {
+		if math.Abs(m[5]-tm[5]) < 0.01 && m[4] != tm[4] {
+			out.space()
+		}
+		tm, tlm = m, m
+	}
+	nextLine := func(tx, ty float64) {
+		m := tlm
+		m[4] = tx*tlm[0] + ty*tlm[2] + tlm[4]
+		m[5] = tx*tlm[1] + ty*tlm[3] + tlm[5]
+		moveTo(m)
+	}
+	show := func(s string) {
+		text := font.decode(s, maxPDFTextBytes-f.text)
+		if f.text += len(text); f.text > maxPDFTextBytes {
+			f.fail(errPDFTooMuchText)
+			return
+		}
+		out.show(text, tm[5])
+	}
+
+	l := &pdfLexer{data: data}
+	var operands []any
+	for f.err == nil {
+		v, err := l.value()
+		if err != nil {
+			f.fail(err)
+			return
+		}
+		op, ok := v.(pdfOperator)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+
+		switch op {
+		case "BT":
+			tm = [6]float64{1, 0, 0, 1, 0, 0}
+			tlm = tm
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = f.font(resources, name, fonts)
+				}
+			}
+		case "TL":
+			if len(operands) >= 1 {
+				leading = pdfNumber(operands[0])
+			}
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				ty := pdfNumber(operands[1])
+				if op == "TD" {
+					leading = -ty
+				}
+				nextLine(pdfNumber(operands[0]), ty)
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				var m [6]float64
+				for i := range m {
+					m[i] = pdfNumber(operands[i])
+				}
+				moveTo(m)
+			}
+		case "T*":
+			nextLine(0, -leading)
+		case "Tj":
+			if len(operands) >= 1 {
+				if s, ok := operands[0].(string); ok {
+					show(s)
+				}
+			}
+		case "'", "\"":
+			nextLine(0, -leading)
+			if len(operands) >= 1 {
+				if s, ok := operands[len(operands)-1].(string); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				arr, _ := operands[0].(pdfArray)
+				for _, item := range arr {
+					switch item := item.(type) {
+					case string:
+						show(item)
+					case int64, float64:
+						// A large negative adjustment is a gap between words.
+						if pdfNumber(item) < -200 {
+							out.space()
+						}
+					}
+				}
+			}
+		case "Do":
+			if len(operands) >= 1 && depth < maxPDFFormDepth {
+				if name, ok := operands[0].(pdfName); ok {
+					f.runForm(resources, name, out, depth)
+				}
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm interprets the named form XObject.
+func (f *pdfFile) runForm(resources pdfDict, name pdfName, out *pdfText, depth int) {
+	xobjects := f.dict(resources["XObject"])
+	form, ok := f.resolve(xobjects[name]).(pdfStream)
+	if !ok || form.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := f.decode(form)
+	if err != nil {
+		return
+	}
+	if r := f.dict(form.dict["Resources"]); r != nil {
+		resources = r
+	}
+	f.runContent(data, resources, out, depth+1)
+}
+
+// skipInlineImage skips the data of an inline image, which follows the
+// ID operator and ends with EI.
+func (l *pdfLexer) skipInlineImage() {
+	for i := l.pos + 1; i+2 <= len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isPDFSpace(l.data[i-1]) &&
+			(i+2 == len(l.data) || isPDFSpace(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+func pdfNumber(v any) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// pdfText assembles the text shown on a page into lines and
+// paragraphs, from the vertical position of each piece of text. A gap
+// noticeably larger than the usual line spacing starts a paragraph.
+type pdfText struct {
+	sb      strings.Builder
+	started bool
+	lastY   float64
+	lineGap float64 // Smallest gap seen between lines
+	spaced  bool    // A space is due before the next text
+}
+
+func (t *pdfText) space() { t.spaced = true }
+
+func (t *pdfText) show(s string, y float64) {
+	if s == "" {
+		return
+	}
+	if t.started {
+		dy := t.lastY - y
+		switch {
+		case math.Abs(dy) < 1:
+			if t.spaced && !strings.HasSuffix(t.sb.String(), " ") && !strings.HasPrefix(s, " ") {
+				t.sb.WriteByte(' ')
+			}
+		case dy < 0 || (t.lineGap > 0 && dy > t.lineGap*1.6):
+			t.sb.WriteString("\n\n")
+		default:
+			t.sb.WriteByte('\n')
+		}
+		if dy >= 1 && (t.lineGap == 0 || dy < t.lineGap) {
+			t.lineGap = dy
+		}
+	}
+	t.sb.WriteString(s)
+	t.started = true
+	t.spaced = false
+	t.lastY = y
+}
+
+var pdfHyphenation = regexp.MustCompile(`(\p{Ll})-\n(\p{Ll})`)
+
+// String returns the text, rejoining words hyphenated across lines.
+func (t *pdfText) String() string {
+	return strings.TrimSpace(pdfHyphenation.ReplaceAllString(t.sb.String(), "$1$2"))
+}
+
+// pdfFont maps the character codes of a font to text.
+type pdfFont struct {
+	toUnicode map[uint32]string
+	codeBytes int  // Bytes per character code
+	simple    bool // Unmapped codes are read as WinAnsi
+}
+
+// font returns the named font of resources, loading it on first use.
+func (f *pdfFile) font(resources pdfDict, name pdfName, cache map[pdfName]*pdfFont) *pdfFont {
+	if font, ok := cache[name]; ok {
+		return font
+	}
+	font := &pdfFont{codeBytes: 1, simple: true}
+	dict := f.dict(f.dict(resources["Font"])[name])
+	if dict["Subtype"] == pdfName("Type0") {
+		font.codeBytes, font.simple = 2, false
+	}
+	if cmap := f.cmap(dict["ToUnicode"], font.codeBytes); cmap != nil {
+		font.toUnicode, font.codeBytes = cmap.toUnicode, cmap.codeBytes
+	}
+	cache[name] = font
+	return font
+}
+
+// pdfCMapKey identifies a parsed ToUnicode CMap: its object, and the
+// code size of the font it was read for.
+type pdfCMapKey struct{ num, codeBytes int }
+
+// pdfCMap is a parsed ToUnicode CMap.
+type pdfCMap struct {
+	toUnicode map[uint32]string
+	codeBytes int
+}
+
+// cmap returns the ToUnicode CMap v refers to, parsed for a font with
+// codes of codeBytes, or nil. Each CMap is parsed once per document,
+// however many pages' fonts share it.
+func (f *pdfFile) cmap(v any, codeBytes int) *pdfCMap {
+	ref, ok := v.(pdfRef)
+	if !ok {
+		return nil
+	}
+	key := pdfCMapKey{num: ref.num, codeBytes: codeBytes}
+	if cmap, ok := f.cmaps[key]; ok {
+		return cmap
+	}
+	var cmap *pdfCMap
+	if s, ok := f.resolve(ref).(pdfStream); ok {
+		if data, err := f.decode(s); err == nil {
+			budget := maxPDFCMapCodes - f.cmapCodes
+			toUnicode, codeBytes, err := parseCMap(data, codeBytes, &budget)
+			f.cmapCodes = maxPDFCMapCodes - budget
+			f.fail(err)
+			if err == nil {
+				cmap = &pdfCMap{toUnicode: toUnicode, codeBytes: codeBytes}
+			}
+		}
+	}
+	f.cmaps[key] = cmap
+	return cmap
+}
+
+// decode maps a shown string to text. Codes a composite font cannot map
+// are dropped. It stops once the text is longer than limit bytes, since
+// one code can map to a long string.
+func (font *pdfFont) decode(s string, limit int) string {
+	if font == nil {
+		font = &pdfFont{codeBytes: 1, simple: true}
+	}
+	var sb strings.Builder
+	for i := 0; i+font.codeBytes <= len(s) && sb.Len() <= limit; i += font.codeBytes {
+		var code uint32
+		for j := range font.codeBytes {
+			code = code<<8 | uint32(s[i+j])
+		}
+		if text, ok := font.toUnicode[code]; ok {
+			sb.WriteString(text)
+		} else if font.simple {
+			sb.WriteRune(winAnsiRune(byte(code)))
+		}
+	}
+	return sb.String()
+}
+
+// winAnsiHigh are the WinAnsiEncoding characters of codes 0x80 to 0x9F,
+// where it differs from Latin-1.
+var winAnsiHigh = []rune("€�‚ƒ„…†‡ˆ‰Š‹Œ�Ž��‘’“”•–—˜™š›œ�žŸ")
+
+func winAnsiRune(b byte) rune {
+	if b >= 0x80 && b <= 0x9F {
+		return winAnsiHigh[b-0x80]
+	}
+	return rune(b)
+}
+
+// parseCMap reads the character code mappings of a ToUnicode CMap. It
+// returns them with the code size of the CMap's codespace, or
+// codeBytes if it declares none. Each code mapped is taken from
+// budget; once it is spent parsing fails with errPDFTooManyCodes.
+func parseCMap(data []byte, codeBytes int, budget *int) (map[uint32]string, int, error) {
+	m := make(map[uint32]string)
+	mapped := func() bool {
+		*budget--
+		return *budget >= 0
+	}
+	l := &pdfLexer{data: data}
+	var operands []any
+	for {
+		v, err := l.value()
+		if errors.Is(err, errPDFTooComplex) {
+			return nil, 0, err
+		}
+		if err != nil {
+			return m, codeBytes, nil
+		}
+		op, ok := v.(pdfOperator)
+		if !ok {
+			operands = append(operands, v)
+			continue
+		}
+
+		switch op {
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if lo, ok := operands[0].(string); ok && len(lo) > 0 && len(lo) <= 4 {
+					codeBytes = len(lo)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					if !mapped() {
+						return nil, 0, errPDFTooManyCodes
+					}
+					m[cmapCode(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 {
+					continue
+				}
+				first, last := cmapCode(lo), cmapCode(hi)
+				if last < first || last-first > 0xFFFF {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case string:
+					// Consecutive codes map to consecutive characters.
+					units := utf16Units(dst)
+					for code := first; code <= last && len(units) > 0; code++ {
+						if !mapped() {
+							return nil, 0, errPDFTooManyCodes
+						}
+						m[code] = string(utf16.Decode(units))
+						units = slices.Clone(units)
+						units[len(units)-1]++
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(string); ok && first+uint32(j) <= last {
+							if !mapped() {
+								return nil, 0, errPDFTooManyCodes
+							}
+							m[first+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+func cmapCode(s string) uint32 {
+	var code uint32
+	for i := 0; i < len(s) && i < 4; i++ {
+		code = code<<8 | uint32(s[i])
+	}
+	return code
+}
+
+func utf16Units(s string) []uint16 {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return units
+}
+
+// utf16BE decodes the UTF-16BE text of a CMap destination.
+func utf16BE(s string) string {
+	if len(s) == 1 {
+		return string(rune(s[0]))
+	}
+	return string(utf16.Decode(utf16Units(s)))
+}
+
+// runningLineEdge is how many lines at the top and at the bottom of a
+// page are checked for running headers and footers.
+const runningLineEdge = 2
+
+var digits = regexp.MustCompile(`\d+`)
+
+// stripRunningLines drops the lines at the top or bottom of a page that
+// recur on at least half the pages of a document of three or more:
+// running headers, footers and page numbers. Digits are ignored when
+// comparing lines, so "Page 3 of 10" recurs on every page.
+func stripRunningLines(pages []Page) {
+	if len(pages) < 3 {
+		return
+	}
+
+	key := func(line string) string {
+		return digits.ReplaceAllString(strings.ToLower(strings.TrimSpace(line)), "#")
+	}
+	edges := func(lines []string) []int {
+		var idx []int
+		for i := range lines {
+			if i < runningLineEdge || i >= len(lines)-runningLineEdge {
+				idx = append(idx, i)
+			}
+		}
+		return idx
+	}
+
+	counts := make(map[string]int)
+	pageLines := make([][]string, len(pages))
+	for i, p := range pages {
+		pageLines[i] = nonEmptyLines(p.Text)
+		seen := make(map[string]bool)
+		for _, j := range edges(pageLines[i]) {
+			if k := key(pageLines[i][j]); !seen[k] {
+				seen[k] = true
+				counts[k]++
+			}
+		}
+	}
+
+	for i := range pages {
+		lines := pageLines[i]
+		drop := make(map[int]bool)
+		for _, j := range edges(lines) {
+			if counts[key(lines[j])]*2 >= len(pages) {
+				drop[j] = true
+			}
+		}
+		if len(drop) == 0 {
+			continue
+		}
+		// Rebuild the text without the dropped lines, keeping its
+		// paragraph breaks.
+		var kept []string
+		j := 0
+		for _, line := range strings.Split(pages[i].Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				kept = append(kept, line)
+				continue
+			}
+			if !drop[j] {
+				kept = append(kept, line)
+			}
+			j++
+		}
+		pages[i].Text = strings.TrimSpace(strings.Join(kept, "\n"))
+	}
+}
+
+func nonEmptyLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
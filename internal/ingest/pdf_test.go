@@ -0,0 +1,272 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package ingest
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// buildPDF assembles a PDF whose pages show the given content streams
+// in a Helvetica font, F1, and an optional composite font, F2, with a
+// ToUnicode CMap. Streams are Flate-compressed when compress is set.
+// No cross-reference table is written; the parser does not need one.
+func buildPDF(pages []string, compress bool) []byte {
+	var objs []string
+	stream := func(dict, data string) string {
+		if compress {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			zw.Write([]byte(data))
+			zw.Close()
+			return fmt.Sprintf("<< %s /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+				dict, buf.Len(), buf.String())
+		}
+		return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+	}
+
+	// 1: catalog, 2: page tree, 3: F1, 4: F2, 5: F2's CMap, then pairs
+	// of page and content objects.
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	objs = append(objs,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> >>",
+			strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 5 0 R >>",
+		stream("", "/CIDInit /ProcSet findresource begin\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n"+
+			"1 beginbfchar <0001> <0048> endbfchar\n"+
+			"1 beginbfrange <0002> <0003> <0069> endbfrange\nendcmap"),
+	)
+	for i, content := range pages {
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", 7+2*i),
+			stream("", content))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objs {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestParsePDF(t *testing.T) {
+	content := `BT /F1 12 Tf 72 720 Td (Streaming replication) Tj 0 -14 Td [(sends ) -250 (WAL) 300 (.)] TJ
+0 -40 Td (A new \(second\) para-) Tj 0 -14 Td (graph.) Tj ET`
+
+	for _, compress := range []bool{false, true} {
+		t.Run(fmt.Sprintf("compressed=%v", compress), func(t *testing.T) {
+			pages, err := parsePDF(buildPDF([]string{content}, compress))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			want := "Streaming replication\nsends WAL.\n\nA new (second) paragraph."
+			if len(pages) != 1 || pages[0].Number != 1 || pages[0].Text != want {
+				t.Errorf("got %+v, want one page with %q", pages, want)
+			}
+		})
+	}
+}
+
+func TestParsePDF_ToUnicode(t *testing.T) {
+	pages, err := parsePDF(buildPDF([]string{`BT /F2 12 Tf 72 720 Td <000100020003> Tj ET`}, true))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pages) != 1 || pages[0].Text != "Hij" {
+		t.Errorf("expected the CMap to map the codes to \"Hij\", got %+v", pages)
+	}
+}
+
+func TestParsePDF_RunningLines(t *testing.T) {
+	bodies := []string{"Installing the server.", "Configuring pipelines.", "Monitoring queries."}
+	var contents []string
+	for i, body := range bodies {
+		contents = append(contents, fmt.Sprintf(
+			`BT /F1 10 Tf 72 760 Td (pgEdge Manual) Tj 0 -40 Td (%s) Tj 0 -700 Td (Page %d of 3) Tj ET`,
+			body, i+1))
+	}
+	pages, err := parsePDF(buildPDF(contents, false))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 pages, got %d", len(pages))
+	}
+	for i, p := range pages {
+		if p.Number != i+1 || p.Text != bodies[i] {
+			t.Errorf("page %d: got %d %q, want %q", i+1, p.Number, p.Text, bodies[i])
+		}
+	}
+}
+
+func TestParsePDF_Errors(t *testing.T) {
+	if _, err := parsePDF([]byte("<html></html>")); err == nil {
+		t.Error("expected an error for a file that is not a PDF")
+	}
+
+	encrypted := bytes.Replace(buildPDF([]string{"BT ET"}, false),
+		[]byte("<< /Root 1 0 R >>"), []byte("<< /Root 1 0 R /Encrypt 99 0 R >>"), 1)
+	if _, err := parsePDF(encrypted); !errors.Is(err, errPDFEncrypted) {
+		t.Errorf("expected errPDFEncrypted, got %v", err)
+	}
+}
+
+// formPDF returns a PDF whose page draws form XObject X, which draws
+// itself draws times and holds padding bytes of whitespace besides.
+func formPDF(draws, padding int) []byte {
+	form := strings.Repeat("/X Do\n", draws) + strings.Repeat(" ", padding)
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /XObject << /X 4 0 R >> >> /Contents 5 0 R >>",
+		fmt.Sprintf("<< /Type /XObject /Subtype /Form /Resources << /XObject << /X 4 0 R >> >> /Length %d >>\nstream\n%s\nendstream",
+			len(form), form),
+		"<< /Length 5 >>\nstream\n/X Do\nendstream",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.7\n")
+	for i, obj := range objs {
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+// withCMap returns a PDF from buildPDF, uncompressed, with old in the
+// ToUnicode CMap of font F2 replaced by new. The stream's Length is
+// left wrong; the parser reads up to "endstream".
+func withCMap(content, old, new string) []byte {
+	return bytes.Replace(buildPDF([]string{content}, false), []byte(old), []byte(new), 1)
+}
+
+func TestParsePDF_Limits(t *testing.T) {
+	lengthChain := []byte("%PDF-1.7\n")
+	for n := 1; n <= 2*maxPDFNesting; n++ {
+		lengthChain = fmt.Appendf(lengthChain, "%d 0 obj\n<< /Length %d 0 R >>\nstream\nx\nendstream\nendobj\n", n, n+1)
+	}
+
+	// Each object's string runs to the end of the file, so parsing them
+	// all would read it once per object.
+	overlapping := []byte("%PDF-1.4\n")
+	for n := 1; len(overlapping) < 1<<20; n++ {
+		overlapping = fmt.Appendf(overlapping, "%d 0 obj ( ", n)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{
+			name: "nested arrays",
+			data: append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("["), 30<<20)...),
+			want: errPDFTooDeep,
+		},
+		{
+			name: "nested dictionaries",
+			data: append([]byte("%PDF-1.4\n1 0 obj\n"), bytes.Repeat([]byte("<<"), 1<<20)...),
+			want: errPDFTooDeep,
+		},
+		{
+			name: "nested arrays in a content stream",
+			data: buildPDF([]string{"BT " + strings.Repeat("[", 1<<16) + " ET"}, true),
+			want: errPDFTooDeep,
+		},
+		{
+			name: "chained stream lengths",
+			data: lengthChain,
+			want: errPDFTooDeep,
+		},
+		{
+			name: "overlapping objects",
+			data: overlapping,
+			want: errPDFTooLong,
+		},
+		{
+			name: "form drawing itself",
+			data: formPDF(10, 100<<10),
+			want: errPDFTooLarge,
+		},
+		{
+			name: "character code ranges",
+			data: withCMap(`BT /F2 12 Tf <0001> Tj ET`, "1 beginbfrange <0002> <0003> <0069> endbfrange",
+				"20 beginbfrange "+strings.Repeat("<0000> <FFFF> <0041> ", 20)+"endbfrange"),
+			want: errPDFTooManyCodes,
+		},
+		{
+			name: "character code mapped to long text",
+			data: withCMap(`BT /F2 12 Tf <`+strings.Repeat("0001", 20000)+`> Tj ET`, "<0001> <0048>",
+				"<0001> <"+strings.Repeat("0048", 4000)+">"),
+			want: errPDFTooMuchText,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePDF(tt.data)
+			if !errors.Is(err, tt.want) || !errors.Is(err, errPDFTooComplex) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Within the limits, deep but reasonable nesting still parses.
+	nested := buildPDF([]string{"BT /F1 12 Tf (Nested) Tj " + strings.Repeat("[", 32) + strings.Repeat("]", 32) +
+		" ET"}, true)
+	if pages, err := parsePDF(nested); err != nil || len(pages) != 1 || pages[0].Text != "Nested" {
+		t.Errorf("expected nesting within the limit to parse, got %+v, %v", pages, err)
+	}
+	if pages, err := parsePDF(formPDF(2, 0)); err != nil || len(pages) != 1 {
+		t.Errorf("expected forms nested to the form depth limit to parse, got %+v, %v", pages, err)
+	}
+}
+
+func FuzzParsePDF(f *testing.F) {
+	f.Add(buildPDF([]string{`BT /F1 12 Tf 72 720 Td (Streaming replication) Tj ET`}, false))
+	f.Add(buildPDF([]string{`BT /F2 12 Tf 72 720 Td <000100020003> Tj ET`, `BT (Two) ' ET`}, true))
+	f.Add(formPDF(2, 16))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n[[[[[[[[<<<</A [1 0 R]>>"))
+	f.Add([]byte("%PDF-1.5\n1 0 obj\n<< /Type /ObjStm /N 2 /First 8 /Length 20 >>\nstream\n2 0 3 4 <<>> [2 0 R]\nendstream\n" +
+		"trailer\n<< /Root 2 0 R >>"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pages, err := parsePDF(data)
+		if err != nil {
+			if pages != nil {
+				t.Errorf("expected no pages with the error %v", err)
+			}
+			return
+		}
+		if len(pages) == 0 {
+			t.Fatal("expected errPDFNoPages rather than no pages")
+		}
+		text := 0
+		for i, p := range pages {
+			if p.Number != i+1 {
+				t.Errorf("page %d numbered %d", i+1, p.Number)
+			}
+			text += len(p.Text)
+		}
+		if text > maxPDFTextBytes {
+			t.Errorf("extracted %d bytes of text, past the %d limit", text, maxPDFTextBytes)
+		}
+	})
+}
@@ -0,0 +1,394 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// maxInboundEmailBytes bounds an inbound webhook request, attachments
+// included. SendGrid posts messages of up to 30 MB.
+const maxInboundEmailBytes = 32 << 20
+
+// maxInboundMemory is how much of an inbound request is held in memory
+// while parsing it; larger attachments are spooled to temporary files.
+const maxInboundMemory = 1 << 20
+
+// quoteHeaderRe matches the line a mail client puts above the message
+// it quotes in a reply, such as "On Mon, 2 Mar 2026, Jo wrote:".
+var quoteHeaderRe = regexp.MustCompile(`^On .+ wrote:$`)
+
+// sendMailFunc sends a message over SMTP; smtp.SendMail, replaced in
+// tests.
+type sendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// Email answers questions sent to a mailbox. The mail provider's
+// inbound webhook posts each message to the inbound handler, and the
+// answer, with its sources, is sent back to the sender over SMTP as a
+// reply in the same thread.
+type Email struct {
+	pipeline       string
+	executors      Executors
+	inboundPath    string
+	webhookSecret  string
+	from           *mail.Address
+	allowedSenders []string
+	smtpAddr       string
+	smtpAuth       smtp.Auth
+	sourceLinks    config.SourceLinksConfig
+	sendMail       sendMailFunc
+	logger         *slog.Logger
+}
+
+// NewEmail creates the email gateway, reading its webhook secret and
+// SMTP password from their files.
+func NewEmail(cfg config.EmailConfig, executors Executors, logger *slog.Logger) (*Email, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email from address: %w", err)
+	}
+	e := &Email{
+		pipeline:    cfg.Pipeline,
+		executors:   executors,
+		inboundPath: cfg.InboundPath,
+		from:        from,
+		smtpAddr:    net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(cfg.SMTP.Port)),
+		sourceLinks: cfg.SourceLinks,
+		sendMail:    smtp.SendMail,
+		logger:      logger,
+	}
+	for _, sender := range cfg.AllowedSenders {
+		e.allowedSenders = append(e.allowedSenders, strings.ToLower(strings.TrimSpace(sender)))
+	}
+	if e.webhookSecret, err = config.ReadSecretFile(cfg.WebhookSecretFile, "email webhook secret"); err != nil {
+		return nil, err
+	}
+	if cfg.SMTP.Username != "" {
+		password, err := config.ReadSecretFile(cfg.SMTP.PasswordFile, "SMTP password")
+		if err != nil {
+			return nil, err
+		}
+		// PlainAuth refuses to send the password over an unencrypted
+		// connection to anything but localhost.
+		e.smtpAuth = smtp.PlainAuth("", cfg.SMTP.Username, password, cfg.SMTP.Host)
+	}
+	return e, nil
+}
+
+// inboundEmail is the part of a received message the gateway reads.
+type inboundEmail struct {
+	sender     *mail.Address // Where the reply goes: Reply-To, or From
+	subject    string
+	text       string
+	messageID  string
+	references string
+	header     mail.Header
+}
+
+// InboundHandler returns the handler for the inbound webhook, which
+// takes messages in SendGrid Inbound Parse's default, parsed format. It
+// checks the webhook's basic auth password and acknowledges each
+// message at once, answering it in the background, so the provider
+// does not time out and post it again.
+func (e *Email) InboundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, password, _ := r.BasicAuth()
+		if subtle.ConstantTimeCompare([]byte(password), []byte(e.webhookSecret)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="email"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxInboundEmailBytes)
+		if err := r.ParseMultipartForm(maxInboundMemory); err != nil {
+			http.Error(w, "invalid inbound email", http.StatusBadRequest)
+			return
+		}
+		defer func() { _ = r.MultipartForm.RemoveAll() }()
+
+		msg, err := parseInboundEmail(r.PostFormValue("from"), r.PostFormValue("headers"),
+			r.PostFormValue("subject"), r.PostFormValue("text"))
+		if err != nil {
+			// A malformed message will not parse on a retry either, so
+			// it is acknowledged rather than rejected.
+			e.logger.Warn("ignoring inbound email", "error", err)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if reason := e.skipReason(msg); reason != "" {
+			e.logger.Info("not answering inbound email", "sender", msg.sender.Address, "reason", reason)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		go e.answer(context.Background(), msg)
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// parseInboundEmail builds an inboundEmail from the webhook's fields.
+func parseInboundEmail(from, headers, subject, text string) (*inboundEmail, error) {
+	header := mail.Header{}
+	if headers != "" {
+		m, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(headers, "\r\n") + "\r\n\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid headers: %w", err)
+		}
+		header = m.Header
+	}
+
+	sender, err := mail.ParseAddress(header.Get("Reply-To"))
+	if err != nil {
+		if from == "" {
+			from = header.Get("From")
+		}
+		if sender, err = mail.ParseAddress(from); err != nil {
+			return nil, fmt.Errorf("invalid sender %q: %w", from, err)
+		}
+	}
+
+	if subject == "" {
+		subject = header.Get("Subject")
+	}
+	if dec, err := new(mime.WordDecoder).DecodeHeader(subject); err == nil {
+		subject = dec
+	}
+	return &inboundEmail{
+		sender:     sender,
+		subject:    strings.TrimSpace(subject),
+		text:       stripQuoted(text),
+		messageID:  header.Get("Message-Id"),
+		references: header.Get("References"),
+		header:     header,
+	}, nil
+}
+
+// skipReason reports why msg should not be answered, or "" if it
+// should: it is an automatic message, such as a bounce, an
+// out-of-office reply or a mailing list post, which would otherwise
+// start a mail loop; it was sent by the gateway itself; or its sender
+// is not allowed.
+func (e *Email) skipReason(msg *inboundEmail) string {
+	header := msg.header
+	if as := strings.ToLower(header.Get("Auto-Submitted")); as != "" && as != "no" {
+		return "automatic message"
+	}
+	switch strings.ToLower(header.Get("Precedence")) {
+	case "bulk", "junk", "list":
+		return "automatic message"
+	}
+	if header.Get("List-Id") != "" {
+		return "mailing list message"
+	}
+
+	sender := strings.ToLower(msg.sender.Address)
+	if sender == strings.ToLower(e.from.Address) {
+		return "sent by the gateway"
+	}
+	if !e.senderAllowed(sender) {
+		return "sender not allowed"
+	}
+	if msg.text == "" && msg.subject == "" {
+		return "no question"
+	}
+	return ""
+}
+
+// senderAllowed reports whether address matches allowed_senders: an
+// exact address, or an "@domain" entry matching its domain. An empty
+// list allows anyone.
+func (e *Email) senderAllowed(address string) bool {
+	if len(e.allowedSenders) == 0 {
+		return true
+	}
+	_, domain, _ := strings.Cut(address, "@")
+	for _, allowed := range e.allowedSenders {
+		if allowed == address || allowed == "@"+domain {
+			return true
+		}
+	}
+	return false
+}
+
+// stripQuoted removes what a reply quotes of earlier messages, and the
+// sender's signature, leaving the new question.
+func stripQuoted(text string) string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if line == "-- " || quoteHeaderRe.MatchString(trimmed) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// answer runs msg through the pipeline and mails the answer back. The
+// body is the question, or the subject for a message without one. A
+// question that fails is answered with an apology.
+func (e *Email) answer(ctx context.Context, msg *inboundEmail) {
+	ctx, cancel := context.WithTimeout(ctx, answerTimeout)
+	defer cancel()
+	logger := e.logger.With("pipeline", e.pipeline, "sender", msg.sender.Address)
+
+	question := msg.text
+	if question == "" {
+		question = msg.subject
+	}
+	text, err := e.query(ctx, question)
+	if err != nil {
+		logger.Error("failed to answer email", "error", err)
+		text = failedText
+	}
+	if err := e.sendMail(e.smtpAddr, e.smtpAuth, e.from.Address, []string{msg.sender.Address},
+		e.buildReply(msg, text)); err != nil {
+		logger.Error("failed to send email reply", "error", err)
+	}
+}
+
+// query returns the pipeline's answer to question with its citations,
+// or its sources when the pipeline does not cite.
+func (e *Email) query(ctx context.Context, question string) (string, error) {
+	exec, err := e.executors.GetExecutor(e.pipeline)
+	if err != nil {
+		return "", err
+	}
+	resp, err := exec.ExecuteWithOptions(ctx, pipeline.QueryRequest{
+		Query:          question,
+		IncludeSources: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	answer := strings.TrimSpace(resp.Answer)
+	if answer == "" {
+		return "", errors.New("the pipeline returned an empty answer")
+	}
+	if len(resp.Citations) > 0 {
+		return answer + formatCitations(e.sourceLinks, resp.Citations), nil
+	}
+	return answer + formatSources(emailMarkup{}, e.sourceLinks, resp.Sources), nil
+}
+
+// formatCitations lists the sources an answer's [n] markers cite, in
+// marker order.
+func formatCitations(links config.SourceLinksConfig, citations []pipeline.Citation) string {
+	var lines []string
+	for _, c := range citations {
+		url := metadataString(c.Metadata, links.URLColumn)
+		title := metadataString(c.Metadata, links.TitleColumn)
+		ref := emailMarkup{}.link(url, title)
+		switch {
+		case url == "" && title == "":
+			ref = c.ID
+		case url == "":
+			ref = title
+		case title == "":
+			ref = url
+		}
+		if ref != "" {
+			lines = append(lines, fmt.Sprintf("[%d] %s", c.Marker, ref))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\nSources\n" + strings.Join(lines, "\n")
+}
+
+// buildReply builds the reply message: plain text, threaded under msg,
+// and marked as automatic so that other autoresponders leave it alone.
+func (e *Email) buildReply(msg *inboundEmail, text string) []byte {
+	subject := msg.subject
+	if subject == "" {
+		subject = "Your question"
+	}
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		// Header values from the inbound message are unfolded already;
+		// stripping line breaks keeps any that were not from injecting
+		// headers.
+		value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", e.from.String())
+	header("To", msg.sender.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", e.messageID())
+	if msg.messageID != "" {
+		header("In-Reply-To", msg.messageID)
+		header("References", strings.TrimSpace(msg.references+" "+msg.messageID))
+	}
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n") + "\r\n"))
+	_ = qp.Close()
+	return buf.Bytes()
+}
+
+// messageID returns a new, unique Message-ID in the from address's
+// domain.
+func (e *Email) messageID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	_, domain, _ := strings.Cut(e.from.Address, "@")
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// emailMarkup formats answers as plain text.
+type emailMarkup struct{}
+
+func (emailMarkup) link(url, title string) string {
+	if title == url {
+		return url
+	}
+	return title + " <" + url + ">"
+}
+
+func (emailMarkup) bold(text string) string {
+	return text
+}
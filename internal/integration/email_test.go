@@ -0,0 +1,236 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// sentMail is a message the gateway sent.
+type sentMail struct {
+	addr string
+	from string
+	to   []string
+	msg  *mail.Message
+	body string
+}
+
+// mailbox records the messages sent through it.
+type mailbox struct {
+	mu   sync.Mutex
+	sent []sentMail
+}
+
+func (m *mailbox) send(t *testing.T) sendMailFunc {
+	return func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		parsed, err := mail.ReadMessage(bytes.NewReader(msg))
+		if err != nil {
+			t.Errorf("sent an invalid message: %v\n%s", err, msg)
+			return err
+		}
+		body, _ := io.ReadAll(parsed.Body)
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.sent = append(m.sent, sentMail{addr: addr, from: from, to: to, msg: parsed, body: string(body)})
+		return nil
+	}
+}
+
+func (m *mailbox) messages() []sentMail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]sentMail(nil), m.sent...)
+}
+
+func newTestEmail(t *testing.T, exec *mockExecutor, box *mailbox, allowed ...string) *Email {
+	t.Helper()
+	e, err := NewEmail(config.EmailConfig{
+		Enabled:           true,
+		Pipeline:          "docs",
+		InboundPath:       "/integrations/email/inbound",
+		WebhookSecretFile: writeSecret(t, "webhook-secret", "hook-secret"),
+		From:              "Docs Bot <docs-bot@example.com>",
+		AllowedSenders:    allowed,
+		SMTP:              config.SMTPConfig{Host: "smtp.example.com", Port: 587},
+		SourceLinks:       config.SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+	}, mockExecutors{exec: exec}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewEmail failed: %v", err)
+	}
+	e.sendMail = box.send(t)
+	return e
+}
+
+// inboundRequest builds a SendGrid Inbound Parse request.
+func inboundRequest(t *testing.T, password string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := w.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/integrations/email/inbound", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.SetBasicAuth("sendgrid", password)
+	return req
+}
+
+const inboundHeaders = "Received: by mx.example.com\n" +
+	"From: Jo Smith <jo@customer.example>\n" +
+	"Subject: Replication question\n" +
+	"Message-ID: <q1@customer.example>\n" +
+	"References: <q0@customer.example>\n"
+
+func TestEmail_AnswersQuestion(t *testing.T) {
+	exec := &mockExecutor{response: &pipeline.QueryResponse{
+		Answer: "Use streaming replication [1].",
+		Citations: []pipeline.Citation{{Marker: 1, ID: "doc-1", Metadata: map[string]interface{}{
+			"url": "https://docs.example.com/replication", "title": "Replication"}}},
+	}}
+	box := &mailbox{}
+	e := newTestEmail(t, exec, box, "@customer.example")
+
+	rec := httptest.NewRecorder()
+	e.InboundHandler().ServeHTTP(rec, inboundRequest(t, "hook-secret", map[string]string{
+		"from":    "Jo Smith <jo@customer.example>",
+		"subject": "Replication question",
+		"headers": inboundHeaders,
+		"text": "How do I set up a standby?\n\n" +
+			"On Mon, 2 Mar 2026, Docs Bot wrote:\n> An earlier answer\n",
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	waitFor(t, "the reply", func() bool { return len(box.messages()) == 1 })
+	sent := box.messages()[0]
+	if got := exec.asked(); len(got) != 1 || got[0] != "How do I set up a standby?" {
+		t.Errorf("queries = %q, want the question without the quoted reply", got)
+	}
+	if sent.addr != "smtp.example.com:587" || sent.from != "docs-bot@example.com" ||
+		len(sent.to) != 1 || sent.to[0] != "jo@customer.example" {
+		t.Errorf("sent via %s from %s to %v", sent.addr, sent.from, sent.to)
+	}
+	for name, want := range map[string]string{
+		"Subject":        "Re: Replication question",
+		"In-Reply-To":    "<q1@customer.example>",
+		"References":     "<q0@customer.example> <q1@customer.example>",
+		"Auto-Submitted": "auto-replied",
+	} {
+		if got := sent.msg.Header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	wantBody := "Use streaming replication [1].\r\n\r\nSources\r\n" +
+		"[1] Replication <https://docs.example.com/replication>\r\n"
+	if sent.body != wantBody {
+		t.Errorf("body =\n%q\nwant\n%q", sent.body, wantBody)
+	}
+}
+
+func TestEmail_RejectsWrongSecret(t *testing.T) {
+	exec := &mockExecutor{response: &pipeline.QueryResponse{Answer: "Hello."}}
+	e := newTestEmail(t, exec, &mailbox{})
+
+	rec := httptest.NewRecorder()
+	e.InboundHandler().ServeHTTP(rec, inboundRequest(t, "guess", map[string]string{
+		"from": "jo@customer.example", "text": "Hi",
+	}))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestEmail_SkipsUnansweredMessages(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+	}{
+		{"auto reply", map[string]string{"from": "jo@customer.example", "text": "Out of office",
+			"headers": "Auto-Submitted: auto-replied\n"}},
+		{"mailing list", map[string]string{"from": "list@customer.example", "text": "Digest",
+			"headers": "List-Id: <users.customer.example>\n"}},
+		{"own reply", map[string]string{"from": "docs-bot@example.com", "text": "An answer"}},
+		{"disallowed sender", map[string]string{"from": "eve@elsewhere.example", "text": "Hi"}},
+		{"disallowed reply-to", map[string]string{"from": "jo@customer.example", "text": "Hi",
+			"headers": "Reply-To: eve@elsewhere.example\n"}},
+		{"empty", map[string]string{"from": "jo@customer.example", "text": "> quoted only"}},
+	}
+
+	exec := &mockExecutor{response: &pipeline.QueryResponse{Answer: "Hello."}}
+	box := &mailbox{}
+	e := newTestEmail(t, exec, box, "@customer.example", "docs-bot@example.com")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			e.InboundHandler().ServeHTTP(rec, inboundRequest(t, "hook-secret", tt.fields))
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200 so the provider does not retry", rec.Code)
+			}
+		})
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if got := exec.asked(); len(got) != 0 {
+		t.Errorf("expected no questions, got %q", got)
+	}
+	if n := len(box.messages()); n != 0 {
+		t.Errorf("expected no replies, sent %d", n)
+	}
+}
+
+func TestEmail_FailureSendsApology(t *testing.T) {
+	exec := &mockExecutor{err: pipeline.ErrNoCompletionProvider}
+	box := &mailbox{}
+	e := newTestEmail(t, exec, box)
+
+	rec := httptest.NewRecorder()
+	e.InboundHandler().ServeHTTP(rec, inboundRequest(t, "hook-secret", map[string]string{
+		"from": "jo@customer.example", "subject": "Re: How do I upgrade?",
+	}))
+
+	waitFor(t, "the reply", func() bool { return len(box.messages()) == 1 })
+	sent := box.messages()[0]
+	if got := exec.asked(); len(got) != 1 || got[0] != "Re: How do I upgrade?" {
+		t.Errorf("queries = %q, want the subject of a message without a body", got)
+	}
+	if got := sent.msg.Header.Get("Subject"); got != "Re: How do I upgrade?" {
+		t.Errorf("Subject = %q, want no second Re:", got)
+	}
+	if !strings.Contains(sent.body, failedText) {
+		t.Errorf("body = %q, want the apology", sent.body)
+	}
+}
+
+func TestStripQuoted(t *testing.T) {
+	text := "Is PITR supported?\r\n\r\nThanks,\r\nJo\r\n-- \r\nJo Smith, ACME\r\n"
+	if got, want := stripQuoted(text), "Is PITR supported?\n\nThanks,\nJo"; got != want {
+		t.Errorf("stripQuoted = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package integration connects pipelines to chat platforms and email: a
+// bot that is asked a question in Slack or Mattermost answers it in a
+// thread, editing its reply as the answer streams in and finishing with
+// links to the sources, and a question emailed to the gateway is
+// answered by reply.
+package integration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// Executors looks up pipelines by name. The server satisfies it, always
+// answering with the pipelines of the current configuration, so a
+// reload applies to the bots too.
+type Executors interface {
+	GetExecutor(name string) (pipeline.QueryExecutor, error)
+}
+
+// DefaultUpdateInterval is how often a reply is edited while its answer
+// streams in. Chat platforms rate-limit message edits, so the answer is
+// shown in steps rather than token by token.
+const DefaultUpdateInterval = time.Second
+
+// answerTimeout bounds answering one question, from the first search
+// to the last edit of the reply.
+const answerTimeout = 2 * time.Minute
+
+// maxConcurrentAnswers bounds the questions a bot answers at once, and
+// maxQueuedQuestions those waiting for an answer; a question asked
+// while the queue is full is dropped.
+const (
+	maxConcurrentAnswers = 8
+	maxQueuedQuestions   = 64
+)
+
+// maxSourceLinks bounds the sources listed under an answer.
+const maxSourceLinks = 5
+
+// Messages shown in a reply before and instead of an answer.
+const (
+	pendingText = "_Searching the documentation…_"
+	failedText  = "Sorry, I couldn't answer that question. Please try again later."
+	typingMark  = " …"
+)
+
+// Backoff between attempts to reconnect to a chat platform.
+const (
+	minReconnectDelay = time.Second
+	maxReconnectDelay = time.Minute
+)
+
+// Integrations runs the enabled integrations.
+type Integrations struct {
+	slack      *Slack
+	mattermost *Mattermost
+	email      *Email
+}
+
+// New creates the enabled integrations of cfg, answering with the
+// pipelines executors looks up.
+func New(cfg config.IntegrationsConfig, executors Executors, logger *slog.Logger) (*Integrations, error) {
+	in := &Integrations{}
+	var err error
+	if cfg.Slack.Enabled {
+		if in.slack, err = NewSlack(cfg.Slack, executors, logger); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Mattermost.Enabled {
+		if in.mattermost, err = NewMattermost(cfg.Mattermost, executors, logger); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Email.Enabled {
+		if in.email, err = NewEmail(cfg.Email, executors, logger); err != nil {
+			return nil, err
+		}
+	}
+	return in, nil
+}
+
+// Handlers returns the webhooks to serve on the API listener, keyed by
+// their route pattern: the Slack Events API request URL, unless Slack
+// events arrive over Socket Mode, and the email inbound webhook.
+func (in *Integrations) Handlers() map[string]http.Handler {
+	handlers := make(map[string]http.Handler)
+	if in.slack != nil && !in.slack.SocketMode() {
+		handlers["POST "+in.slack.eventsPath] = in.slack.EventsHandler()
+	}
+	if in.email != nil {
+		handlers["POST "+in.email.inboundPath] = in.email.InboundHandler()
+	}
+	return handlers
+}
+
+// Run runs the integrations that hold a connection open to their chat
+// platform until ctx is done.
+func (in *Integrations) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	if in.slack != nil {
+		wg.Go(func() { in.slack.Run(ctx) })
+	}
+	if in.mattermost != nil {
+		wg.Go(func() { in.mattermost.Run(ctx) })
+	}
+	wg.Wait()
+}
+
+// reconnect calls connect until ctx is done, waiting between attempts
+// with exponential backoff. The backoff starts over once a connection
+// is established, so a connection the platform closes after hours is
+// replaced at once.
+func reconnect(ctx context.Context, logger *slog.Logger, connect func(context.Context) (bool, error)) {
+	delay := minReconnectDelay
+	for {
+		connected, err := connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = minReconnectDelay
+		}
+		if err != nil {
+			logger.Warn("chat connection failed; reconnecting", "error", err, "delay", delay)
+		} else {
+			logger.Info("chat connection closed; reconnecting")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// markup formats text for where an answer is shown.
+type markup interface {
+	link(url, title string) string
+	bold(text string) string
+}
+
+// chat is a chat platform a bot answers on.
+type chat interface {
+	markup
+	// post replies in a thread, returning the new message's id.
+	post(ctx context.Context, channel, thread, text string) (string, error)
+	// update replaces the text of a message the bot posted.
+	update(ctx context.Context, channel, id, text string) error
+}
+
+// bot answers questions with a pipeline on a chat platform.
+type bot struct {
+	questions      chan question // Waiting for serve to answer them
+	pipeline       string
+	executors      Executors
+	chat           chat
+	sourceLinks    config.SourceLinksConfig
+	updateInterval time.Duration
+	logger         *slog.Logger
+}
+
+// question is a question asked of a bot.
+type question struct {
+	channel string
+	thread  string // Message id the reply is threaded under
+	text    string
+}
+
+// ask queues q for serve to answer. It does not wait, so events are
+// acknowledged at once; when the queue is full, q is dropped.
+func (b *bot) ask(q question) {
+	select {
+	case b.questions <- q:
+	default:
+		b.logger.Warn("too many questions waiting; dropping one", "pipeline", b.pipeline, "channel", q.channel)
+	}
+}
+
+// serve answers the questions asked until ctx is done, up to
+// maxConcurrentAnswers at once. Answers in progress are cancelled with
+// ctx.
+func (b *bot) serve(ctx context.Context) {
+	var wg sync.WaitGroup
+	for range maxConcurrentAnswers {
+		wg.Go(func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case q := <-b.questions:
+					b.answer(ctx, q)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// answer replies to q in its thread: a placeholder first, edited as the
+// answer streams in, and finally the whole answer and its sources.
+// Failures are logged; a reply already posted says the question could
+// not be answered.
+func (b *bot) answer(ctx context.Context, q question) {
+	ctx, cancel := context.WithTimeout(ctx, answerTimeout)
+	defer cancel()
+	logger := b.logger.With("pipeline", b.pipeline, "channel", q.channel)
+
+	replyID, err := b.chat.post(ctx, q.channel, q.thread, pendingText)
+	if err != nil {
+		logger.Error("failed to post reply", "error", err)
+		return
+	}
+
+	text, err := b.streamAnswer(ctx, q, replyID)
+	if err != nil {
+		logger.Error("failed to answer question", "error", err)
+		text = failedText
+	}
+	// The final edit gets its own deadline, so a question that ran out
+	// of time still has its placeholder replaced.
+	updateCtx, cancelUpdate := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancelUpdate()
+	if err := b.chat.update(updateCtx, q.channel, replyID, text); err != nil {
+		logger.Error("failed to update reply", "error", err)
+	}
+}
+
+// streamAnswer runs q through the pipeline, editing the reply at most
+// once per update interval as the answer grows, and returns the final
+// text with its sources.
+func (b *bot) streamAnswer(ctx context.Context, q question, replyID string) (string, error) {
+	exec, err := b.executors.GetExecutor(b.pipeline)
+	if err != nil {
+		return "", err
+	}
+	chunks, errs := exec.ExecuteStreamWithOptions(ctx, pipeline.QueryRequest{
+		Query:          q.text,
+		Stream:         true,
+		IncludeSources: true,
+	})
+
+	var answer strings.Builder
+	var sources []pipeline.Source
+	lastUpdate := time.Now()
+	for chunk := range chunks {
+		if chunk.Sources != nil {
+			sources = chunk.Sources
+		}
+		answer.WriteString(chunk.Content)
+		if chunk.Content != "" && time.Since(lastUpdate) >= b.updateInterval {
+			lastUpdate = time.Now()
+			if err := b.chat.update(ctx, q.channel, replyID, answer.String()+typingMark); err != nil {
+				b.logger.Warn("failed to update reply", "pipeline", b.pipeline, "error", err)
+			}
+		}
+	}
+	if err := <-errs; err != nil {
+		return "", err
+	}
+	if answer.Len() == 0 {
+		return "", errors.New("the pipeline returned an empty answer")
+	}
+	return strings.TrimSpace(answer.String()) + formatSources(b.chat, b.sourceLinks, sources), nil
+}
+
+// formatSources lists an answer's sources under it, linked by the
+// configured metadata columns. A source with neither a URL nor a title
+// is left out, as it gives the reader nothing to follow.
+func formatSources(m markup, links config.SourceLinksConfig, sources []pipeline.Source) string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		url := metadataString(src.Metadata, links.URLColumn)
+		title := metadataString(src.Metadata, links.TitleColumn)
+		key := url + "\x00" + title
+		if (url == "" && title == "") || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		switch {
+		case url == "":
+			lines = append(lines, "• "+title)
+		case title == "":
+			lines = append(lines, "• "+m.link(url, url))
+		default:
+			lines = append(lines, "• "+m.link(url, title))
+		}
+		if len(lines) == maxSourceLinks {
+			break
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\n\n" + m.bold("Sources") + "\n" + strings.Join(lines, "\n")
+}
+
+// metadataString returns a source's metadata column as a string, or ""
+// when it is absent or NULL.
+func metadataString(metadata map[string]interface{}, column string) string {
+	if column == "" {
+		return ""
+	}
+	switch v := metadata[column].(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,308 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// mockExecutor answers with a fixed response, or streams fixed chunks,
+// and records the queries it was asked.
+type mockExecutor struct {
+	response *pipeline.QueryResponse
+	chunks   []pipeline.StreamChunk
+	err      error
+
+	mu      sync.Mutex
+	queries []string
+}
+
+func (m *mockExecutor) ExecuteWithOptions(
+	ctx context.Context, req pipeline.QueryRequest,
+) (*pipeline.QueryResponse, error) {
+	m.mu.Lock()
+	m.queries = append(m.queries, req.Query)
+	m.mu.Unlock()
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.response, nil
+}
+
+func (m *mockExecutor) ExecuteStreamWithOptions(
+	ctx context.Context, req pipeline.QueryRequest,
+) (<-chan pipeline.StreamChunk, <-chan error) {
+	m.mu.Lock()
+	m.queries = append(m.queries, req.Query)
+	m.mu.Unlock()
+
+	chunkChan := make(chan pipeline.StreamChunk, len(m.chunks))
+	errChan := make(chan error, 1)
+	for _, c := range m.chunks {
+		chunkChan <- c
+	}
+	close(chunkChan)
+	errChan <- m.err
+	close(errChan)
+	return chunkChan, errChan
+}
+
+func (m *mockExecutor) asked() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.queries...)
+}
+
+// mockExecutors serves a single pipeline named "docs".
+type mockExecutors struct {
+	exec *mockExecutor
+}
+
+func (m mockExecutors) GetExecutor(name string) (pipeline.QueryExecutor, error) {
+	if name != "docs" {
+		return nil, pipeline.ErrPipelineNotFound
+	}
+	return m.exec, nil
+}
+
+// docsAnswer returns an executor that answers in two chunks, citing two
+// sources and one without link metadata.
+func docsAnswer() *mockExecutor {
+	return &mockExecutor{chunks: []pipeline.StreamChunk{
+		{Content: "Use streaming "},
+		{Content: "replication.", Sources: []pipeline.Source{
+			{ID: "1", Metadata: map[string]interface{}{
+				"url": "https://docs.example.com/replication", "title": "Replication"}},
+			{ID: "2", Metadata: map[string]interface{}{"title": "Standby servers"}},
+			{ID: "3", Metadata: map[string]interface{}{"section": "intro"}},
+		}},
+		{FinishReason: "stop"},
+	}}
+}
+
+// mockChat records the messages a bot posts and edits, formatting links
+// and bold text as Markdown.
+type mockChat struct {
+	mu       sync.Mutex
+	posts    []question
+	messages map[string]string
+	postErr  error
+}
+
+func (m *mockChat) post(ctx context.Context, channel, thread, text string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.postErr != nil {
+		return "", m.postErr
+	}
+	m.posts = append(m.posts, question{channel: channel, thread: thread, text: text})
+	if m.messages == nil {
+		m.messages = make(map[string]string)
+	}
+	id := "reply-" + thread
+	m.messages[id] = text
+	return id, nil
+}
+
+func (m *mockChat) update(ctx context.Context, channel, id, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages[id] = text
+	return nil
+}
+
+func (m *mockChat) link(url, title string) string { return "[" + title + "](" + url + ")" }
+
+func (m *mockChat) bold(text string) string { return "**" + text + "**" }
+
+func testBot(exec *mockExecutor, c chat) *bot {
+	return &bot{
+		questions:   make(chan question, maxQueuedQuestions),
+		pipeline:    "docs",
+		executors:   mockExecutors{exec: exec},
+		chat:        c,
+		sourceLinks: config.SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+		logger:      slog.New(slog.DiscardHandler),
+	}
+}
+
+// writeSecret writes a credential file for a test and returns its path.
+func writeSecret(t *testing.T, name, secret string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(secret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// waitFor polls cond until it holds, failing the test after a few
+// seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBot_AnswerInThread(t *testing.T) {
+	exec := docsAnswer()
+	c := &mockChat{}
+	b := testBot(exec, c)
+
+	b.answer(context.Background(), question{channel: "C1", thread: "100.1", text: "How do I replicate?"})
+
+	if got := exec.asked(); len(got) != 1 || got[0] != "How do I replicate?" {
+		t.Errorf("queries = %q", got)
+	}
+	if len(c.posts) != 1 || c.posts[0].thread != "100.1" || c.posts[0].text != pendingText {
+		t.Fatalf("posts = %+v, want one placeholder in thread 100.1", c.posts)
+	}
+	want := "Use streaming replication.\n\n**Sources**\n" +
+		"• [Replication](https://docs.example.com/replication)\n" +
+		"• Standby servers"
+	if got := c.messages["reply-100.1"]; got != want {
+		t.Errorf("reply =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestBot_AnswerFailure(t *testing.T) {
+	exec := &mockExecutor{err: errors.New("completion provider unavailable")}
+	c := &mockChat{}
+	b := testBot(exec, c)
+
+	b.answer(context.Background(), question{channel: "C1", thread: "100.1", text: "Anyone there?"})
+
+	if got := c.messages["reply-100.1"]; got != failedText {
+		t.Errorf("reply = %q, want %q", got, failedText)
+	}
+}
+
+// blockingChat is a mockChat whose posts wait for release, counting
+// how many wait at once.
+type blockingChat struct {
+	mockChat
+	release chan struct{}
+
+	countMu       sync.Mutex
+	waiting, most int
+}
+
+func (b *blockingChat) post(ctx context.Context, channel, thread, text string) (string, error) {
+	b.countMu.Lock()
+	b.waiting++
+	b.most = max(b.most, b.waiting)
+	b.countMu.Unlock()
+	defer func() {
+		b.countMu.Lock()
+		b.waiting--
+		b.countMu.Unlock()
+	}()
+
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return b.mockChat.post(ctx, channel, thread, text)
+}
+
+// counts returns how many posts are waiting, and the most that waited
+// at once.
+func (b *blockingChat) counts() (waiting, most int) {
+	b.countMu.Lock()
+	defer b.countMu.Unlock()
+	return b.waiting, b.most
+}
+
+func TestBot_Serve(t *testing.T) {
+	c := &blockingChat{release: make(chan struct{})}
+	b := testBot(docsAnswer(), c)
+	for i := range maxConcurrentAnswers + 2 {
+		b.ask(question{channel: "C1", thread: strconv.Itoa(i), text: "How do I replicate?"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		b.serve(ctx)
+		close(done)
+	}()
+
+	waitFor(t, "the answers to start", func() bool {
+		waiting, _ := c.counts()
+		return waiting == maxConcurrentAnswers
+	})
+	close(c.release)
+	waitFor(t, "every question to be answered", func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.posts) == maxConcurrentAnswers+2
+	})
+	if _, most := c.counts(); most != maxConcurrentAnswers {
+		t.Errorf("answered %d questions at once, want at most %d", most, maxConcurrentAnswers)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not return after cancellation")
+	}
+}
+
+func TestBot_AskDropsWhenQueueFull(t *testing.T) {
+	b := testBot(docsAnswer(), &mockChat{})
+	for i := range maxQueuedQuestions + 1 {
+		b.ask(question{channel: "C1", thread: strconv.Itoa(i), text: "How do I replicate?"})
+	}
+	if n := len(b.questions); n != maxQueuedQuestions {
+		t.Errorf("queued %d questions, want %d", n, maxQueuedQuestions)
+	}
+}
+
+func TestFormatSources(t *testing.T) {
+	links := config.SourceLinksConfig{URLColumn: "url", TitleColumn: "title"}
+
+	var sources []pipeline.Source
+	for i := range maxSourceLinks + 2 {
+		url := "https://docs.example.com/" + string(rune('a'+i))
+		sources = append(sources,
+			pipeline.Source{Metadata: map[string]interface{}{"url": url}},
+			pipeline.Source{Metadata: map[string]interface{}{"url": url}}) // Same chunk's neighbour
+	}
+
+	got := formatSources(&mockChat{}, links, sources)
+	if n := strings.Count(got, "• "); n != maxSourceLinks {
+		t.Errorf("listed %d sources, want %d:\n%s", n, maxSourceLinks, got)
+	}
+	if !strings.Contains(got, "[https://docs.example.com/a](https://docs.example.com/a)") {
+		t.Errorf("expected an untitled source to be linked by its URL:\n%s", got)
+	}
+	if got := formatSources(&mockChat{}, links, []pipeline.Source{{ID: "1"}}); got != "" {
+		t.Errorf("expected no sources section without link metadata, got %q", got)
+	}
+}
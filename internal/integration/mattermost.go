@@ -0,0 +1,248 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// Mattermost answers questions asked of a Mattermost bot account:
+// mentions of the bot, and direct messages to it. Posts arrive over the
+// server's WebSocket API, so no public URL is needed.
+type Mattermost struct {
+	bot        *bot
+	baseURL    string
+	token      string
+	httpClient *http.Client
+	logger     *slog.Logger
+
+	userID   string // The bot's own user, looked up on first connection
+	username string
+}
+
+// NewMattermost creates the Mattermost integration, reading its token
+// from its file.
+func NewMattermost(cfg config.MattermostConfig, executors Executors, logger *slog.Logger) (*Mattermost, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	token, err := config.ReadSecretFile(cfg.TokenFile, "Mattermost token")
+	if err != nil {
+		return nil, err
+	}
+	m := &Mattermost{
+		baseURL:    strings.TrimSuffix(cfg.URL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}
+	m.bot = &bot{
+		questions:      make(chan question, maxQueuedQuestions),
+		pipeline:       cfg.Pipeline,
+		executors:      executors,
+		chat:           m,
+		sourceLinks:    cfg.SourceLinks,
+		updateInterval: DefaultUpdateInterval,
+		logger:         logger,
+	}
+	return m, nil
+}
+
+// Run receives posts, and answers them, until ctx is done,
+// reconnecting whenever the connection fails.
+func (m *Mattermost) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Go(func() { m.bot.serve(ctx) })
+	reconnect(ctx, m.logger.With("integration", "mattermost"), m.runOnce)
+	wg.Wait()
+}
+
+// mattermostEvent is a WebSocket event. Data values are strings, with
+// the post itself JSON-encoded inside one.
+type mattermostEvent struct {
+	Event string `json:"event"`
+	Data  struct {
+		Post        string `json:"post"`
+		ChannelType string `json:"channel_type"`
+		Mentions    string `json:"mentions"`
+	} `json:"data"`
+}
+
+// mattermostPost is the part of a post the bot reads.
+type mattermostPost struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	RootID    string `json:"root_id"`
+	UserID    string `json:"user_id"`
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+}
+
+// runOnce runs a single WebSocket connection. It reports whether the
+// connection was established, so the caller can tell a dropped
+// connection from one that could not be made.
+func (m *Mattermost) runOnce(ctx context.Context) (bool, error) {
+	if m.userID == "" {
+		var me struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		}
+		if err := m.call(ctx, http.MethodGet, "/users/me", nil, &me); err != nil {
+			return false, err
+		}
+		m.userID, m.username = me.ID, me.Username
+	}
+
+	header := http.Header{"Authorization": {"Bearer " + m.token}}
+	conn, err := dialWebSocket(ctx, websocketURL(m.baseURL)+"/api/v4/websocket", header)
+	if err != nil {
+		return false, err
+	}
+	defer conn.CloseNow()
+
+	for {
+		_, msg, err := conn.Read(ctx)
+		if err != nil {
+			return true, err
+		}
+		var ev mattermostEvent
+		if err := json.Unmarshal(msg, &ev); err != nil || ev.Event != "posted" {
+			continue
+		}
+		m.handlePost(ev)
+	}
+}
+
+// handlePost answers a post that mentions the bot, or a direct message
+// to it, in the post's thread. System posts and the bot's own are
+// ignored.
+func (m *Mattermost) handlePost(ev mattermostEvent) {
+	var post mattermostPost
+	if err := json.Unmarshal([]byte(ev.Data.Post), &post); err != nil {
+		m.logger.Warn("ignoring malformed Mattermost post", "error", err)
+		return
+	}
+	if post.UserID == m.userID || post.Type != "" {
+		return
+	}
+	var mentions []string
+	_ = json.Unmarshal([]byte(ev.Data.Mentions), &mentions)
+	mentioned := ev.Data.ChannelType == "D"
+	for _, id := range mentions {
+		mentioned = mentioned || id == m.userID
+	}
+	if !mentioned {
+		return
+	}
+
+	text := post.Message
+	if m.username != "" {
+		mention := regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(m.username) + `\b`)
+		text = mention.ReplaceAllString(text, "")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	thread := post.RootID
+	if thread == "" {
+		thread = post.ID
+	}
+	m.bot.ask(question{channel: post.ChannelID, thread: thread, text: text})
+}
+
+// call calls a Mattermost API v4 endpoint, sending body as JSON when it
+// is non-nil and decoding the response into out.
+func (m *Mattermost) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.baseURL+"/api/v4"+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("mattermost %s %s failed: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEventBytes))
+	if err != nil {
+		return fmt.Errorf("mattermost %s %s failed: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+			return fmt.Errorf("mattermost %s %s failed: %s: %s", method, path, resp.Status, apiErr.Message)
+		}
+		return fmt.Errorf("mattermost %s %s failed: %s", method, path, resp.Status)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// post implements chat by creating a reply post.
+func (m *Mattermost) post(ctx context.Context, channel, thread, text string) (string, error) {
+	var created mattermostPost
+	err := m.call(ctx, http.MethodPost, "/posts", map[string]string{
+		"channel_id": channel,
+		"root_id":    thread,
+		"message":    text,
+	}, &created)
+	if err == nil && created.ID == "" {
+		err = errors.New("mattermost returned no post id")
+	}
+	return created.ID, err
+}
+
+// update implements chat by patching the post's message.
+func (m *Mattermost) update(ctx context.Context, channel, id, text string) error {
+	return m.call(ctx, http.MethodPut, "/posts/"+url.PathEscape(id)+"/patch",
+		map[string]string{"message": text}, nil)
+}
+
+// link implements chat with a Markdown link.
+func (m *Mattermost) link(url, title string) string {
+	title = strings.NewReplacer("[", "\\[", "]", "\\]").Replace(title)
+	return "[" + title + "](" + strings.ReplaceAll(url, ")", "%29") + ")"
+}
+
+// bold implements chat.
+func (m *Mattermost) bold(text string) string {
+	return "**" + text + "**"
+}
@@ -0,0 +1,148 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// postedEvent builds a Mattermost "posted" event, JSON-encoding the
+// post and mentions inside it as the server does.
+func postedEvent(t *testing.T, post mattermostPost, channelType string, mentions ...string) []byte {
+	t.Helper()
+	postJSON, _ := json.Marshal(post)
+	mentionsJSON, _ := json.Marshal(mentions)
+	ev, err := json.Marshal(map[string]interface{}{
+		"event": "posted",
+		"data": map[string]string{
+			"post":         string(postJSON),
+			"channel_type": channelType,
+			"mentions":     string(mentionsJSON),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestMattermost_AnswersMention(t *testing.T) {
+	exec := docsAnswer()
+
+	var mu sync.Mutex
+	var created map[string]string
+	var message string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v4/users/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mm-token" {
+			http.Error(w, `{"message":"invalid token"}`, http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":"bot1","username":"docsbot"}`))
+	})
+	mux.HandleFunc("POST /api/v4/posts", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		_ = json.NewDecoder(r.Body).Decode(&created)
+		message = created["message"]
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"id":"reply1"}`))
+	})
+	mux.HandleFunc("PUT /api/v4/posts/reply1/patch", func(w http.ResponseWriter, r *http.Request) {
+		var patch map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&patch)
+		mu.Lock()
+		message = patch["message"]
+		mu.Unlock()
+		_, _ = w.Write([]byte(`{"id":"reply1"}`))
+	})
+	mux.HandleFunc("GET /api/v4/websocket", func(w http.ResponseWriter, r *http.Request) {
+		conn := upgradeWebSocket(t, w, r)
+		if conn == nil {
+			return
+		}
+		defer conn.CloseNow()
+		for _, ev := range [][]byte{
+			// The bot's own post, and a channel post not mentioning it.
+			postedEvent(t, mattermostPost{ID: "p1", ChannelID: "ch1", UserID: "bot1",
+				Message: "@docsbot loop"}, "O", "bot1"),
+			postedEvent(t, mattermostPost{ID: "p2", ChannelID: "ch1", UserID: "u1",
+				Message: "lunch?"}, "O"),
+			postedEvent(t, mattermostPost{ID: "p3", ChannelID: "ch1", RootID: "p0", UserID: "u1",
+				Message: "@docsbot How do I replicate?"}, "O", "bot1"),
+		} {
+			_ = conn.Write(r.Context(), websocket.MessageText, ev)
+		}
+		_, _, _ = conn.Read(r.Context()) // Until the client closes
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	m, err := NewMattermost(config.MattermostConfig{
+		Enabled:     true,
+		Pipeline:    "docs",
+		URL:         srv.URL + "/",
+		TokenFile:   writeSecret(t, "token", "mm-token"),
+		SourceLinks: config.SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+	}, mockExecutors{exec: exec}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewMattermost failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+
+	want := "Use streaming replication.\n\n**Sources**\n" +
+		"• [Replication](https://docs.example.com/replication)\n" +
+		"• Standby servers"
+	waitFor(t, "the final answer", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return message == want
+	})
+	if got := exec.asked(); len(got) != 1 || got[0] != "How do I replicate?" {
+		t.Errorf("queries = %q, want only the mention, stripped", got)
+	}
+	mu.Lock()
+	if created["channel_id"] != "ch1" || created["root_id"] != "p0" {
+		t.Errorf("reply = %v, want one in thread p0", created)
+	}
+	mu.Unlock()
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func TestMattermost_Link(t *testing.T) {
+	m := &Mattermost{}
+	got := m.link("https://docs.example.com/a_(b)", "[Draft] Guide")
+	want := `[\[Draft\] Guide](https://docs.example.com/a_(b%29)`
+	if got != want {
+		t.Errorf("link = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,365 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// slackAPIURL is the base URL of the Slack Web API.
+const slackAPIURL = "https://slack.com/api"
+
+// slackMaxClockSkew is how far an Events API request's timestamp may be
+// from the current time. Older requests are rejected as possible
+// replays, as Slack recommends.
+const slackMaxClockSkew = 5 * time.Minute
+
+// maxEventBytes bounds an Events API request body.
+const maxEventBytes = 1 << 20
+
+// slackMentionRe matches a user mention, such as the bot's own
+// "<@U0123ABCD>", in a message's text.
+var slackMentionRe = regexp.MustCompile(`<@[A-Z0-9]+(\|[^>]*)?>`)
+
+// Slack answers questions asked of a Slack app: mentions of the bot in
+// channels, and direct messages to it. Events arrive over Socket Mode
+// when an app token is configured, and otherwise at the Events API
+// handler, which must be served at the app's request URL.
+type Slack struct {
+	bot           *bot
+	botToken      string
+	appToken      string
+	signingSecret string
+	eventsPath    string
+	apiURL        string
+	httpClient    *http.Client
+	logger        *slog.Logger
+}
+
+// NewSlack creates the Slack integration, reading its tokens from their
+// files.
+func NewSlack(cfg config.SlackConfig, executors Executors, logger *slog.Logger) (*Slack, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &Slack{
+		eventsPath: cfg.EventsPath,
+		apiURL:     slackAPIURL,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}
+	var err error
+	if s.botToken, err = config.ReadSecretFile(cfg.BotTokenFile, "Slack bot token"); err != nil {
+		return nil, err
+	}
+	if cfg.AppTokenFile != "" {
+		if s.appToken, err = config.ReadSecretFile(cfg.AppTokenFile, "Slack app token"); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.SigningSecretFile != "" {
+		if s.signingSecret, err = config.ReadSecretFile(cfg.SigningSecretFile, "Slack signing secret"); err != nil {
+			return nil, err
+		}
+	}
+	s.bot = &bot{
+		questions:      make(chan question, maxQueuedQuestions),
+		pipeline:       cfg.Pipeline,
+		executors:      executors,
+		chat:           s,
+		sourceLinks:    cfg.SourceLinks,
+		updateInterval: DefaultUpdateInterval,
+		logger:         logger,
+	}
+	return s, nil
+}
+
+// SocketMode reports whether events arrive over Socket Mode rather than
+// at the Events API handler.
+func (s *Slack) SocketMode() bool {
+	return s.appToken != ""
+}
+
+// slackEvent is the part of an Events API event the bot reads.
+type slackEvent struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+// slackCallback is an Events API request body, or the payload of a
+// Socket Mode events_api envelope.
+type slackCallback struct {
+	Type      string     `json:"type"`
+	Challenge string     `json:"challenge"`
+	Event     slackEvent `json:"event"`
+}
+
+// handleEvent answers a mention of the bot, or a direct message to it,
+// in the message's thread. Other events, and messages from bots
+// (including this one) or edits, are ignored.
+func (s *Slack) handleEvent(ev slackEvent) {
+	if ev.BotID != "" || ev.Subtype != "" {
+		return
+	}
+	if ev.Type != "app_mention" && (ev.Type != "message" || ev.ChannelType != "im") {
+		return
+	}
+	text := strings.TrimSpace(slackMentionRe.ReplaceAllString(ev.Text, ""))
+	if text == "" {
+		return
+	}
+	thread := ev.ThreadTS
+	if thread == "" {
+		thread = ev.TS
+	}
+	s.bot.ask(question{channel: ev.Channel, thread: thread, text: text})
+}
+
+// EventsHandler returns the handler for the Events API request URL. It
+// verifies each request's signature, answers Slack's URL verification
+// challenge, and acknowledges events at once, leaving Run to answer
+// them, since Slack expects a response within three seconds.
+func (s *Slack) EventsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxEventBytes))
+		if err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !verifySlackSignature(s.signingSecret, r.Header, body, time.Now()) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		// Slack retries an event it thinks went unacknowledged; the
+		// first delivery was already answered.
+		if r.Header.Get("X-Slack-Retry-Num") != "" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		var cb slackCallback
+		if err := json.Unmarshal(body, &cb); err != nil {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		switch cb.Type {
+		case "url_verification":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, cb.Challenge)
+		case "event_callback":
+			s.handleEvent(cb.Event)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// verifySlackSignature checks a request's X-Slack-Signature: an
+// HMAC-SHA256, keyed by the signing secret, of the version, the
+// request timestamp and the body.
+func verifySlackSignature(secret string, header http.Header, body []byte, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	ts := header.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(sec, 0)); skew > slackMaxClockSkew || skew < -slackMaxClockSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":"))
+	mac.Write(body)
+	want := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(want), []byte(header.Get("X-Slack-Signature")))
+}
+
+// Run answers events until ctx is done. With Socket Mode it also
+// receives them, reconnecting whenever Slack closes the connection, as
+// it does every few hours, or the connection fails; otherwise they
+// arrive at the Events API handler.
+func (s *Slack) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Go(func() { s.bot.serve(ctx) })
+	if s.SocketMode() {
+		reconnect(ctx, s.logger.With("integration", "slack"), s.runSocketMode)
+	}
+	wg.Wait()
+}
+
+// socketEnvelope is a Socket Mode message.
+type socketEnvelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// runSocketMode runs a single Socket Mode connection. It reports
+// whether the connection was established, so the caller can tell a
+// dropped connection from one that could not be made.
+func (s *Slack) runSocketMode(ctx context.Context) (bool, error) {
+	var opened struct {
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, "apps.connections.open", s.appToken, nil, &opened); err != nil {
+		return false, err
+	}
+	conn, err := dialWebSocket(ctx, opened.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	defer conn.CloseNow()
+
+	for {
+		_, msg, err := conn.Read(ctx)
+		if err != nil {
+			return true, err
+		}
+		var env socketEnvelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			s.logger.Warn("ignoring malformed Socket Mode message", "error", err)
+			continue
+		}
+		// Every envelope must be acknowledged, or Slack redelivers it.
+		if env.EnvelopeID != "" {
+			ack, _ := json.Marshal(map[string]string{"envelope_id": env.EnvelopeID})
+			if err := conn.Write(ctx, websocket.MessageText, ack); err != nil {
+				return true, err
+			}
+		}
+		switch env.Type {
+		case "disconnect":
+			return true, nil
+		case "events_api":
+			var cb slackCallback
+			if err := json.Unmarshal(env.Payload, &cb); err != nil {
+				s.logger.Warn("ignoring malformed Slack event", "error", err)
+				continue
+			}
+			s.handleEvent(cb.Event)
+		}
+	}
+}
+
+// call calls a Slack Web API method with token, sending body as JSON,
+// or no body when it is nil, and decodes the response into out.
+func (s *Slack) call(ctx context.Context, method, token string, body, out interface{}) error {
+	var reqBody io.Reader
+	contentType := "application/x-www-form-urlencoded"
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+		contentType = "application/json; charset=utf-8"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.apiURL+"/"+method, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEventBytes))
+	if err != nil {
+		return fmt.Errorf("slack %s failed: %w", method, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s failed: %s", method, resp.Status)
+	}
+
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return fmt.Errorf("slack %s returned an invalid response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("slack %s failed: %s", method, status.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(data, out)
+	}
+	return nil
+}
+
+// post implements chat with chat.postMessage.
+func (s *Slack) post(ctx context.Context, channel, thread, text string) (string, error) {
+	var resp struct {
+		TS string `json:"ts"`
+	}
+	err := s.call(ctx, "chat.postMessage", s.botToken, map[string]interface{}{
+		"channel":      channel,
+		"thread_ts":    thread,
+		"text":         text,
+		"unfurl_links": false,
+	}, &resp)
+	if err == nil && resp.TS == "" {
+		err = errors.New("slack chat.postMessage returned no message timestamp")
+	}
+	return resp.TS, err
+}
+
+// update implements chat with chat.update.
+func (s *Slack) update(ctx context.Context, channel, id, text string) error {
+	return s.call(ctx, "chat.update", s.botToken, map[string]interface{}{
+		"channel": channel,
+		"ts":      id,
+		"text":    text,
+	}, nil)
+}
+
+// slackEscaper escapes the characters Slack's mrkdwn reserves.
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// link implements chat with Slack's <url|title> syntax.
+func (s *Slack) link(url, title string) string {
+	return "<" + url + "|" + slackEscaper.Replace(strings.ReplaceAll(title, "|", "¦")) + ">"
+}
+
+// bold implements chat.
+func (s *Slack) bold(text string) string {
+	return "*" + text + "*"
+}
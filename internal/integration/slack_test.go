@@ -0,0 +1,281 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+const testSigningSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+// slackAPI is a fake Slack Web API that records the methods called.
+type slackAPI struct {
+	*httptest.Server
+	mu       sync.Mutex
+	calls    []slackCall
+	socketWS string // URL apps.connections.open returns
+}
+
+type slackCall struct {
+	method string
+	token  string
+	body   map[string]interface{}
+}
+
+func newSlackAPI(t *testing.T) *slackAPI {
+	api := &slackAPI{}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := slackCall{
+			method: strings.TrimPrefix(r.URL.Path, "/"),
+			token:  strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "),
+		}
+		_ = json.NewDecoder(r.Body).Decode(&call.body)
+		api.mu.Lock()
+		api.calls = append(api.calls, call)
+		api.mu.Unlock()
+
+		resp := map[string]interface{}{"ok": true}
+		switch call.method {
+		case "chat.postMessage":
+			resp["ts"] = "200.2"
+		case "apps.connections.open":
+			resp["url"] = api.socketWS
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// lastText returns the text of the last chat.postMessage or
+// chat.update call.
+func (api *slackAPI) lastText() string {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for i := len(api.calls) - 1; i >= 0; i-- {
+		if text, ok := api.calls[i].body["text"].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+func (api *slackAPI) call(method string) (slackCall, bool) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	for _, c := range api.calls {
+		if c.method == method {
+			return c, true
+		}
+	}
+	return slackCall{}, false
+}
+
+func newTestSlack(t *testing.T, api *slackAPI, exec *mockExecutor, socketMode bool) *Slack {
+	t.Helper()
+	cfg := config.SlackConfig{
+		Enabled:      true,
+		Pipeline:     "docs",
+		BotTokenFile: writeSecret(t, "bot-token", "xoxb-bot"),
+		EventsPath:   "/integrations/slack/events",
+		SourceLinks:  config.SourceLinksConfig{URLColumn: "url", TitleColumn: "title"},
+	}
+	if socketMode {
+		cfg.AppTokenFile = writeSecret(t, "app-token", "xapp-app")
+	} else {
+		cfg.SigningSecretFile = writeSecret(t, "signing-secret", testSigningSecret)
+	}
+	s, err := NewSlack(cfg, mockExecutors{exec: exec}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("NewSlack failed: %v", err)
+	}
+	s.apiURL = api.URL
+	return s
+}
+
+// signedEvent builds an Events API request signed with secret at ts.
+func signedEvent(body, secret string, ts time.Time) *http.Request {
+	stamp := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + stamp + ":" + body))
+
+	req := httptest.NewRequest(http.MethodPost, "/integrations/slack/events", strings.NewReader(body))
+	req.Header.Set("X-Slack-Request-Timestamp", stamp)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+const slackMention = `{"type":"event_callback","event":{"type":"app_mention",` +
+	`"channel":"C1","user":"U1","text":"<@U0BOT> How do I replicate?","ts":"100.1"}}`
+
+const wantSlackAnswer = "Use streaming replication.\n\n*Sources*\n" +
+	"• <https://docs.example.com/replication|Replication>\n" +
+	"• Standby servers"
+
+func TestSlack_EventsSignature(t *testing.T) {
+	s := newTestSlack(t, newSlackAPI(t), docsAnswer(), false)
+	handler := s.EventsHandler()
+	challenge := `{"type":"url_verification","challenge":"3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P"}`
+
+	tests := []struct {
+		name       string
+		req        *http.Request
+		wantStatus int
+	}{
+		{"valid", signedEvent(challenge, testSigningSecret, time.Now()), http.StatusOK},
+		{"wrong secret", signedEvent(challenge, "not-the-secret", time.Now()), http.StatusUnauthorized},
+		{"stale", signedEvent(challenge, testSigningSecret, time.Now().Add(-10*time.Minute)),
+			http.StatusUnauthorized},
+		{"unsigned", httptest.NewRequest(http.MethodPost, "/", strings.NewReader(challenge)),
+			http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, tt.req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK &&
+				rec.Body.String() != "3eZbrw1aBm2rZgRNFdxV2595E9CY3gmdALWMmHkvFXO7tYXAYM8P" {
+				t.Errorf("expected the challenge echoed, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSlack_EventsAPIAnswersMention(t *testing.T) {
+	api := newSlackAPI(t)
+	exec := docsAnswer()
+	s := newTestSlack(t, api, exec, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.Run(ctx)
+
+	rec := httptest.NewRecorder()
+	s.EventsHandler().ServeHTTP(rec, signedEvent(slackMention, testSigningSecret, time.Now()))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	waitFor(t, "the final answer", func() bool { return api.lastText() == wantSlackAnswer })
+	if got := exec.asked(); len(got) != 1 || got[0] != "How do I replicate?" {
+		t.Errorf("queries = %q, want the mention stripped", got)
+	}
+	post, _ := api.call("chat.postMessage")
+	if post.token != "xoxb-bot" || post.body["channel"] != "C1" || post.body["thread_ts"] != "100.1" {
+		t.Errorf("chat.postMessage = %+v, want a reply in thread 100.1", post)
+	}
+	update, _ := api.call("chat.update")
+	if update.body["ts"] != "200.2" {
+		t.Errorf("chat.update ts = %v, want the reply's 200.2", update.body["ts"])
+	}
+}
+
+func TestSlack_IgnoresBotsAndChannelMessages(t *testing.T) {
+	exec := docsAnswer()
+	s := newTestSlack(t, newSlackAPI(t), exec, false)
+
+	for _, ev := range []slackEvent{
+		{Type: "app_mention", BotID: "B1", Channel: "C1", Text: "<@U0BOT> hi", TS: "1.1"},
+		{Type: "message", Subtype: "message_changed", ChannelType: "im", Channel: "D1", Text: "hi", TS: "1.2"},
+		{Type: "message", ChannelType: "channel", Channel: "C1", Text: "hi", TS: "1.3"},
+		{Type: "app_mention", Channel: "C1", Text: "<@U0BOT>", TS: "1.4"},
+	} {
+		s.handleEvent(ev)
+	}
+	if n := len(s.bot.questions); n != 0 {
+		t.Errorf("expected no questions, got %d", n)
+	}
+}
+
+func TestSlack_SocketModeAnswersDirectMessage(t *testing.T) {
+	api := newSlackAPI(t)
+	exec := docsAnswer()
+	s := newTestSlack(t, api, exec, true)
+
+	acked := make(chan string, 1)
+	ws := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn := upgradeWebSocket(t, w, r)
+		if conn == nil {
+			return
+		}
+		defer conn.CloseNow()
+		event := `{"type":"event_callback","event":{"type":"message","channel_type":"im",` +
+			`"channel":"D1","text":"How do I replicate?","ts":"100.1","thread_ts":"90.0"}}`
+		_ = conn.Write(r.Context(), websocket.MessageText, []byte(`{"type":"hello"}`))
+		_ = conn.Write(r.Context(), websocket.MessageText, []byte(`{"type":"events_api","envelope_id":"env-1","payload":`+event+`}`))
+		_, msg, err := conn.Read(r.Context())
+		if err != nil {
+			return
+		}
+		var ack socketEnvelope
+		_ = json.Unmarshal(msg, &ack)
+		acked <- ack.EnvelopeID
+		_, _, _ = conn.Read(r.Context()) // Until the client closes
+	}))
+	defer ws.Close()
+	api.socketWS = websocketURL(ws.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case id := <-acked:
+		if id != "env-1" {
+			t.Errorf("acknowledged %q, want env-1", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the envelope to be acknowledged")
+	}
+	waitFor(t, "the final answer", func() bool { return api.lastText() == wantSlackAnswer })
+	if open, _ := api.call("apps.connections.open"); open.token != "xapp-app" {
+		t.Errorf("apps.connections.open used token %q, want the app token", open.token)
+	}
+	if post, _ := api.call("chat.postMessage"); post.body["thread_ts"] != "90.0" {
+		t.Errorf("reply thread_ts = %v, want the existing thread 90.0", post.body["thread_ts"])
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func TestSlack_Link(t *testing.T) {
+	s := &Slack{}
+	got := s.link("https://docs.example.com/a", "Backup & <Restore> | Guide")
+	want := "<https://docs.example.com/a|Backup &amp; &lt;Restore&gt; ¦ Guide>"
+	if got != want {
+		t.Errorf("link = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,47 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/coder/websocket"
+)
+
+// maxMessageBytes bounds a received WebSocket message. Chat events are
+// small; this only guards against a misbehaving server.
+const maxMessageBytes = 1 << 20
+
+// dialWebSocket opens a WebSocket connection to a ws:// or wss:// URL,
+// sending header with the handshake, for the Slack Socket Mode and
+// Mattermost event streams. ctx bounds the handshake only; pings are
+// answered as messages are read.
+func dialWebSocket(ctx context.Context, rawURL string, header http.Header) (*websocket.Conn, error) {
+	conn, _, err := websocket.Dial(ctx, rawURL, &websocket.DialOptions{HTTPHeader: header})
+	if err != nil {
+		return nil, fmt.Errorf("websocket handshake failed: %w", err)
+	}
+	conn.SetReadLimit(maxMessageBytes)
+	return conn, nil
+}
+
+// websocketURL converts an http(s) URL to the matching ws(s) URL.
+func websocketURL(httpURL string) string {
+	switch {
+	case strings.HasPrefix(httpURL, "https://"):
+		return "wss://" + strings.TrimPrefix(httpURL, "https://")
+	case strings.HasPrefix(httpURL, "http://"):
+		return "ws://" + strings.TrimPrefix(httpURL, "http://")
+	}
+	return httpURL
+}
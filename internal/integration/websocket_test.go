@@ -0,0 +1,120 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/coder/websocket"
+)
+
+// upgradeWebSocket accepts a WebSocket connection on the server side of
+// a test.
+func upgradeWebSocket(t *testing.T, w http.ResponseWriter, r *http.Request) *websocket.Conn {
+	t.Helper()
+	conn, err := websocket.Accept(w, r, nil)
+	if err != nil {
+		t.Errorf("accept failed: %v", err)
+		return nil
+	}
+	conn.SetReadLimit(-1)
+	return conn
+}
+
+func TestWebSocket_RoundTrip(t *testing.T) {
+	large := bytes.Repeat([]byte("x"), 70000) // Needs a 64-bit length
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		ws := upgradeWebSocket(t, w, r)
+		if ws == nil {
+			return
+		}
+		defer ws.CloseNow()
+		for {
+			typ, msg, err := ws.Read(r.Context())
+			if err != nil {
+				return
+			}
+			if string(msg) == "bye" {
+				_ = ws.Close(websocket.StatusNormalClosure, "")
+				return
+			}
+			if err := ws.Write(r.Context(), typ, msg); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	url := websocketURL(srv.URL)
+	if !strings.HasPrefix(url, "ws://") {
+		t.Fatalf("websocketURL(%q) = %q", srv.URL, url)
+	}
+	ctx := context.Background()
+	if _, err := dialWebSocket(ctx, url, nil); err == nil {
+		t.Fatal("expected the handshake to fail without a token")
+	}
+
+	conn, err := dialWebSocket(ctx, url, http.Header{"Authorization": {"Bearer token"}})
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.CloseNow()
+
+	for _, msg := range [][]byte{[]byte(`{"type":"hello"}`), large} {
+		if err := conn.Write(ctx, websocket.MessageText, msg); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		_, got, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("echoed %d bytes, want %d", len(got), len(msg))
+		}
+	}
+
+	if err := conn.Write(ctx, websocket.MessageText, []byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusNormalClosure {
+		t.Errorf("expected a normal closure, got %v", err)
+	}
+}
+
+func TestWebSocket_ReadLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws := upgradeWebSocket(t, w, r)
+		if ws == nil {
+			return
+		}
+		defer ws.CloseNow()
+		_ = ws.Write(r.Context(), websocket.MessageText, bytes.Repeat([]byte("x"), maxMessageBytes+1))
+		_, _, _ = ws.Read(r.Context()) // Until the client closes
+	}))
+	defer srv.Close()
+
+	conn, err := dialWebSocket(context.Background(), websocketURL(srv.URL), nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.CloseNow()
+	if _, _, err := conn.Read(context.Background()); err == nil {
+		t.Errorf("expected a message over %d bytes to be refused", maxMessageBytes)
+	}
+}
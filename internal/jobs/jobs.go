@@ -0,0 +1,120 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package jobs runs long operations, such as ingesting uploaded
+// documents, in the background on a bounded pool of workers, and keeps
+// their state in a Store so clients can poll for progress and errors.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// ErrNotFound is returned when a job does not exist or has expired.
+var ErrNotFound = errors.New("job not found")
+
+// ErrQueueFull is returned when every worker is busy and the queue of
+// waiting jobs is full.
+var ErrQueueFull = errors.New("job queue is full")
+
+// Status is the state of a job, or of one of its tasks.
+type Status string
+
+// Job and task states.
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed" // For a job, at least one of its tasks failed
+)
+
+// Job is a background operation made up of tasks that run in order.
+type Job struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"` // What the job does, e.g. "ingest"
+	Pipeline  string    `json:"pipeline"`
+	Status    Status    `json:"status"`
+	Tasks     []Task    `json:"tasks"`
+	Error     string    `json:"error,omitempty"` // Why the job as a whole failed, if it did
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Task reports the progress of one part of a job, such as one
+// uploaded file, as done out of total units of work, and what it
+// produced, if anything, once it completes.
+type Task struct {
+	Name   string          `json:"name"`
+	Status Status          `json:"status"`
+	Done   int             `json:"done"`
+	Total  int             `json:"total"`
+	Error  string          `json:"error,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// Finished reports whether the job has completed or failed.
+func (j *Job) Finished() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed
+}
+
+func (j *Job) clone() *Job {
+	c := *j
+	c.Tasks = slices.Clone(j.Tasks)
+	return &c
+}
+
+// Store persists job state. Implementations must be safe for
+// concurrent use.
+type Store interface {
+	// Save creates or replaces a job.
+	Save(ctx context.Context, job *Job) error
+
+	// Get returns the job with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// Touch sets the update time of the given jobs, so readers can
+	// tell they are still being worked on.
+	Touch(ctx context.Context, ids []string, at time.Time) error
+
+	// Prune removes jobs last updated before the given time.
+	Prune(ctx context.Context, before time.Time) error
+
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// NewStore creates the store selected by cfg.Store.
+func NewStore(ctx context.Context, cfg config.JobsConfig) (Store, error) {
+	switch cfg.Store {
+	case config.JobStoreMemory, "":
+		return NewMemoryStore(), nil
+	case config.JobStorePostgres:
+		return NewPostgresStore(ctx, cfg.Database, cfg.Table)
+	default:
+		return nil, fmt.Errorf("unknown job store: %s", cfg.Store)
+	}
+}
+
+// newID returns a random 128-bit job ID in hex.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
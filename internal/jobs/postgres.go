@@ -0,0 +1,136 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// PostgresStore keeps jobs in a Postgres table, so their state
+// survives restarts and can be looked up from every server instance
+// using the same database.
+type PostgresStore struct {
+	db    *database.Pool
+	table string // sanitized identifier
+}
+
+// NewPostgresStore connects to the database and creates the jobs table
+// if it does not already exist. table may be schema-qualified.
+func NewPostgresStore(ctx context.Context, dbCfg config.DatabaseConfig, table string) (*PostgresStore, error) {
+	db, err := database.NewPool(ctx, dbCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PostgresStore{
+		db:    db,
+		table: pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+	}
+
+	_, err = s.pool().Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id text PRIMARY KEY,
+		kind text NOT NULL,
+		pipeline text NOT NULL,
+		status text NOT NULL,
+		tasks jsonb NOT NULL DEFAULT '[]',
+		error text NOT NULL DEFAULT '',
+		created_at timestamptz NOT NULL,
+		updated_at timestamptz NOT NULL
+	)`, s.table))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create jobs table: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *PostgresStore) pool() *pgxpool.Pool {
+	return s.db.Pool()
+}
+
+// Save implements Store.
+func (s *PostgresStore) Save(ctx context.Context, job *Job) error {
+	tasks, err := json.Marshal(job.Tasks)
+	if err != nil {
+		return fmt.Errorf("failed to encode job tasks: %w", err)
+	}
+
+	_, err = s.pool().Exec(ctx, fmt.Sprintf(
+		`INSERT INTO %s (id, kind, pipeline, status, tasks, error, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7, $8)
+		 ON CONFLICT (id) DO UPDATE SET status = $4, tasks = $5::jsonb, error = $6, updated_at = $8`,
+		s.table), job.ID, job.Kind, job.Pipeline, string(job.Status), string(tasks),
+		job.Error, job.CreatedAt, job.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save job: %w", err)
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Job, error) {
+	job := &Job{ID: id}
+	var status string
+	var tasks []byte
+
+	err := s.pool().QueryRow(ctx, fmt.Sprintf(
+		`SELECT kind, pipeline, status, tasks, error, created_at, updated_at FROM %s WHERE id = $1`,
+		s.table), id).Scan(&job.Kind, &job.Pipeline, &status, &tasks, &job.Error,
+		&job.CreatedAt, &job.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+	job.Status = Status(status)
+	if err := json.Unmarshal(tasks, &job.Tasks); err != nil {
+		return nil, fmt.Errorf("failed to decode job tasks: %w", err)
+	}
+	return job, nil
+}
+
+// Touch implements Store.
+func (s *PostgresStore) Touch(ctx context.Context, ids []string, at time.Time) error {
+	_, err := s.pool().Exec(ctx, fmt.Sprintf(
+		`UPDATE %s SET updated_at = $2 WHERE id = ANY($1)`, s.table), ids, at)
+	if err != nil {
+		return fmt.Errorf("failed to touch jobs: %w", err)
+	}
+	return nil
+}
+
+// Prune implements Store.
+func (s *PostgresStore) Prune(ctx context.Context, before time.Time) error {
+	_, err := s.pool().Exec(ctx, fmt.Sprintf(
+		`DELETE FROM %s WHERE updated_at < $1`, s.table), before)
+	if err != nil {
+		return fmt.Errorf("failed to prune jobs: %w", err)
+	}
+	return nil
+}
+
+// Close implements Store.
+func (s *PostgresStore) Close() error {
+	s.db.Close()
+	return nil
+}
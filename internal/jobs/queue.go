@@ -0,0 +1,298 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+const (
+	// HeartbeatInterval is how often a queue refreshes the update time
+	// of the jobs it has queued or running.
+	HeartbeatInterval = 30 * time.Second
+
+	// StaleAfter is how long an unfinished job can go without an update
+	// before it is reported as failed: the server running it stopped.
+	StaleAfter = 3 * HeartbeatInterval
+
+	// progressInterval limits how often task progress is saved.
+	progressInterval = time.Second
+
+	// saveTimeout bounds each write to the store.
+	saveTimeout = 10 * time.Second
+)
+
+// Step is one part of a job, such as ingesting one uploaded file. Run
+// reports its progress as done out of total units of work. Result, if
+// set, is called once Run succeeds for what the step produced, such as
+// a report, which is saved with its task as JSON.
+type Step struct {
+	Name   string
+	Run    func(ctx context.Context, progress func(done, total int)) error
+	Result func() any
+}
+
+type queued struct {
+	job   *Job
+	steps []Step
+}
+
+// Queue runs jobs on a fixed number of workers, one job per worker at
+// a time, and records their state in a Store. A job's steps run in
+// order; the job fails if any of them fails.
+type Queue struct {
+	store     Store
+	logger    *slog.Logger
+	retention time.Duration
+
+	ctx    context.Context // cancelled by Close
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	pending chan queued
+	live    map[string]bool // jobs queued or running on this queue
+}
+
+// NewQueue starts cfg.Workers workers that run submitted jobs. Unset
+// settings take their defaults.
+func NewQueue(store Store, cfg config.JobsConfig, logger *slog.Logger) *Queue {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = config.DefaultJobWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = config.DefaultJobQueueSize
+	}
+	if cfg.Retention <= 0 {
+		cfg.Retention = config.DefaultJobRetention
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		store:     store,
+		logger:    logger,
+		retention: cfg.Retention.Std(),
+		ctx:       ctx,
+		cancel:    cancel,
+		pending:   make(chan queued, cfg.QueueSize),
+		live:      make(map[string]bool),
+	}
+	for range cfg.Workers {
+		q.wg.Add(1)
+		go q.worker()
+	}
+	q.wg.Add(1)
+	go q.heartbeat()
+	return q
+}
+
+// Submit queues a job of the given kind whose steps run in order, and
+// returns its initial state. It returns ErrQueueFull when too many jobs
+// are already waiting for a worker. Jobs last updated longer ago than
+// the retention period are removed at the same time.
+func (q *Queue) Submit(ctx context.Context, kind, pipeline string, steps []Step) (*Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	job := &Job{
+		ID:        id,
+		Kind:      kind,
+		Pipeline:  pipeline,
+		Status:    StatusPending,
+		Tasks:     make([]Task, len(steps)),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	for i, step := range steps {
+		job.Tasks[i] = Task{Name: step.Name, Status: StatusPending}
+	}
+
+	// Only Submit sends to pending, so a free slot found under the lock
+	// is still free when the job is sent.
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.ctx.Err() != nil {
+		return nil, errors.New("job queue is closed")
+	}
+	if len(q.pending) == cap(q.pending) {
+		return nil, ErrQueueFull
+	}
+	if err := q.store.Prune(ctx, now.Add(-q.retention)); err != nil {
+		return nil, err
+	}
+	if err := q.store.Save(ctx, job); err != nil {
+		return nil, err
+	}
+	q.live[id] = true
+	q.pending <- queued{job: job.clone(), steps: steps}
+	return job, nil
+}
+
+// Get returns the current state of a job, or ErrNotFound. An unfinished
+// job that has gone StaleAfter without an update is reported as failed,
+// since the server running it stopped before it finished.
+func (q *Queue) Get(ctx context.Context, id string) (*Job, error) {
+	job, err := q.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	age := time.Since(job.UpdatedAt)
+	if age > q.retention {
+		return nil, ErrNotFound
+	}
+	if !job.Finished() && age > StaleAfter {
+		job.Status = StatusFailed
+		job.Error = "interrupted: the server running the job stopped before it finished"
+	}
+	return job, nil
+}
+
+// Close stops the queue. Running steps are cancelled, and jobs still
+// waiting for a worker are marked failed. The store is left open.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.cancel()
+	q.mu.Unlock()
+	q.wg.Wait()
+
+	for {
+		select {
+		case item := <-q.pending:
+			q.abandon(item.job)
+		default:
+			return
+		}
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case item := <-q.pending:
+			q.run(item.job, item.steps)
+		}
+	}
+}
+
+func (q *Queue) run(job *Job, steps []Step) {
+	if q.ctx.Err() != nil {
+		q.abandon(job)
+		return
+	}
+	defer q.forget(job.ID)
+	logger := q.logger.With("job", job.ID, "kind", job.Kind, "pipeline", job.Pipeline)
+
+	job.Status = StatusRunning
+	q.save(job)
+
+	failed := 0
+	for i, step := range steps {
+		task := &job.Tasks[i]
+		task.Status = StatusRunning
+		q.save(job)
+
+		var saved time.Time
+		progress := func(done, total int) {
+			task.Done, task.Total = done, total
+			if time.Since(saved) >= progressInterval {
+				saved = time.Now()
+				q.save(job)
+			}
+		}
+		err := step.Run(q.ctx, progress)
+		if err == nil && step.Result != nil {
+			task.Result, err = json.Marshal(step.Result())
+		}
+		if err != nil {
+			failed++
+			logger.Warn("job task failed", "task", step.Name, "error", err)
+			task.Status, task.Error = StatusFailed, err.Error()
+		} else {
+			task.Status = StatusCompleted
+		}
+		q.save(job)
+	}
+
+	job.Status = StatusCompleted
+	if failed > 0 {
+		job.Status = StatusFailed
+	}
+	q.save(job)
+	logger.Info("job finished", "status", job.Status, "tasks", len(steps), "failed", failed)
+}
+
+// abandon marks a job that never ran as failed.
+func (q *Queue) abandon(job *Job) {
+	job.Status = StatusFailed
+	job.Error = "the server shut down before the job ran"
+	q.save(job)
+	q.forget(job.ID)
+}
+
+func (q *Queue) forget(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.live, id)
+}
+
+// save records a job's state. Failures are logged rather than returned:
+// a missed progress update is made good by the next save.
+func (q *Queue) save(job *Job) {
+	job.UpdatedAt = time.Now()
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(q.ctx), saveTimeout)
+	defer cancel()
+	if err := q.store.Save(ctx, job); err != nil {
+		q.logger.Error("failed to save job", "job", job.ID, "error", err)
+	}
+}
+
+// heartbeat refreshes the update time of the queue's unfinished jobs,
+// so Get can tell them from jobs a stopped server left unfinished.
+func (q *Queue) heartbeat() {
+	defer q.wg.Done()
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		q.mu.Lock()
+		ids := slices.Collect(maps.Keys(q.live))
+		q.mu.Unlock()
+		if len(ids) == 0 {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(q.ctx, saveTimeout)
+		if err := q.store.Touch(ctx, ids, time.Now()); err != nil {
+			q.logger.Error("failed to refresh jobs", "error", err)
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,180 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// waitForJob polls a job until it finishes.
+func waitForJob(t *testing.T, q *Queue, id string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := q.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get job %s: %v", id, err)
+		}
+		if job.Finished() {
+			return job
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	q := NewQueue(NewMemoryStore(), config.JobsConfig{}, nil)
+	defer q.Close()
+
+	var ran []string
+	steps := []Step{
+		{Name: "guide.md", Run: func(ctx context.Context, progress func(done, total int)) error {
+			ran = append(ran, "guide.md")
+			progress(1, 2)
+			progress(2, 2)
+			return nil
+		}, Result: func() any { return map[string]int{"chunks": 12} }},
+		{Name: "broken.pdf", Run: func(ctx context.Context, progress func(done, total int)) error {
+			ran = append(ran, "broken.pdf")
+			return errors.New("not a PDF file")
+		}},
+	}
+	job, err := q.Submit(context.Background(), "ingest", "docs", steps)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.ID == "" || job.Status != StatusPending || len(job.Tasks) != 2 || job.Tasks[1].Name != "broken.pdf" {
+		t.Fatalf("unexpected initial job: %+v", job)
+	}
+
+	job = waitForJob(t, q, job.ID)
+	if job.Status != StatusFailed || job.Kind != "ingest" || job.Pipeline != "docs" {
+		t.Errorf("expected the job to fail with a failed task, got %+v", job)
+	}
+	guide, broken := job.Tasks[0], job.Tasks[1]
+	if guide.Status != StatusCompleted || guide.Done != 2 || guide.Total != 2 || string(guide.Result) != `{"chunks":12}` {
+		t.Errorf("unexpected progress for the first task: %+v", guide)
+	}
+	if broken.Status != StatusFailed || broken.Error != "not a PDF file" {
+		t.Errorf("unexpected progress for the failed task: %+v", broken)
+	}
+	if len(ran) != 2 || ran[0] != "guide.md" {
+		t.Errorf("expected the steps to run in order, got %v", ran)
+	}
+
+	if _, err := q.Get(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	q := NewQueue(NewMemoryStore(), config.JobsConfig{Workers: 1, QueueSize: 1}, nil)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	block := []Step{{Name: "block", Run: func(ctx context.Context, progress func(done, total int)) error {
+		close(started)
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}}}
+	running, err := q.Submit(context.Background(), "ingest", "docs", block)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-started
+
+	waiting, err := q.Submit(context.Background(), "ingest", "docs", nil)
+	if err != nil {
+		t.Fatalf("expected the job to wait for the worker, got %v", err)
+	}
+	if _, err := q.Submit(context.Background(), "ingest", "docs", nil); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	// Closing cancels the running job and abandons the waiting one.
+	q.Close()
+	for _, id := range []string{running.ID, waiting.ID} {
+		job, err := q.store.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get job %s: %v", id, err)
+		}
+		if job.Status != StatusFailed {
+			t.Errorf("expected job %s to fail on close, got %+v", id, job)
+		}
+	}
+	if _, err := q.Submit(context.Background(), "ingest", "docs", nil); err == nil {
+		t.Error("expected an error submitting to a closed queue")
+	}
+}
+
+func TestQueue_StaleAndExpired(t *testing.T) {
+	store := NewMemoryStore()
+	q := NewQueue(store, config.JobsConfig{Retention: config.Duration(time.Hour)}, nil)
+	defer q.Close()
+	ctx := context.Background()
+
+	now := time.Now()
+	store.Save(ctx, &Job{ID: "stale", Status: StatusRunning, UpdatedAt: now.Add(-StaleAfter - time.Second)})
+	store.Save(ctx, &Job{ID: "running", Status: StatusRunning, UpdatedAt: now})
+	store.Save(ctx, &Job{ID: "expired", Status: StatusCompleted, UpdatedAt: now.Add(-2 * time.Hour)})
+
+	if job, err := q.Get(ctx, "stale"); err != nil || job.Status != StatusFailed || job.Error == "" {
+		t.Errorf("expected a stale job to be reported as failed, got %+v, %v", job, err)
+	}
+	if job, err := q.Get(ctx, "running"); err != nil || job.Status != StatusRunning {
+		t.Errorf("expected a recently updated job to be running, got %+v, %v", job, err)
+	}
+	if _, err := q.Get(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected an expired job to be gone, got %v", err)
+	}
+
+	if _, err := q.Submit(ctx, "ingest", "docs", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := store.Get(ctx, "expired"); !errors.Is(err, ErrNotFound) {
+		t.Error("expected submitting a job to prune expired jobs")
+	}
+}
+
+func TestMemoryStore_Copies(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	job := &Job{ID: "a", Status: StatusRunning, Tasks: []Task{{Name: "f", Status: StatusRunning}}}
+	store.Save(ctx, job)
+	job.Tasks[0].Done = 5
+
+	got, err := store.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Tasks[0].Done != 0 {
+		t.Error("expected the store to keep its own copy of a saved job")
+	}
+	got.Tasks[0].Done = 7
+	if again, _ := store.Get(ctx, "a"); again.Tasks[0].Done != 0 {
+		t.Error("expected Get to return a copy")
+	}
+
+	at := time.Now().Add(time.Minute)
+	store.Touch(ctx, []string{"a", "missing"}, at)
+	if touched, _ := store.Get(ctx, "a"); !touched.UpdatedAt.Equal(at) {
+		t.Errorf("expected Touch to set the update time, got %v", touched.UpdatedAt)
+	}
+}
@@ -0,0 +1,619 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package bedrock implements llm.Client for Amazon Bedrock, which
+// pgedge-go-llm-lib does not provide. Chat uses Bedrock's
+// model-agnostic Converse API, so it works with Anthropic Claude,
+// Amazon Titan Text and the other text models Bedrock hosts; Embed uses
+// the Amazon Titan Text Embeddings request format. Requests are signed
+// with AWS Signature Version 4.
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	llm "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+const (
+	providerName = "bedrock"
+
+	// signingService is the SigV4 service name for both the runtime
+	// and control-plane endpoints.
+	signingService = "bedrock"
+
+	// Defaults matching pgedge-go-llm-lib's Options.WithDefaults, so a
+	// Bedrock pipeline behaves like the other providers.
+	defaultMaxTokens      = 4096
+	defaultTemperature    = 0.7
+	defaultRequestTimeout = 120 * time.Second
+)
+
+// Options configure a Bedrock client.
+type Options struct {
+	Model       string
+	Region      string
+	Credentials Credentials
+
+	// BaseURL replaces both the runtime
+	// (https://bedrock-runtime.<region>.amazonaws.com) and control-plane
+	// (https://bedrock.<region>.amazonaws.com) endpoints, e.g. for a
+	// proxy. Requests are still signed for Region.
+	BaseURL string
+
+	CustomHeaders map[string]string
+	HTTPClient    *http.Client
+
+	// RequestTimeout caps each request; for streams it caps the time to
+	// receive the response headers. Zero uses the library default.
+	RequestTimeout time.Duration
+}
+
+type client struct {
+	model      string
+	region     string
+	creds      Credentials
+	runtimeURL string
+	controlURL string
+	headers    map[string]string
+	http       *http.Client
+	timeout    time.Duration
+	now        func() time.Time // overridden in tests
+
+	mu              sync.Mutex
+	cumulativeUsage llm.TokenUsage
+}
+
+// New creates a Bedrock client. Region and credentials are required.
+func New(opts Options) (llm.Client, error) {
+	if opts.Region == "" {
+		return nil, fmt.Errorf("AWS region not configured")
+	}
+	if opts.Credentials.AccessKeyID == "" || opts.Credentials.SecretAccessKey == "" {
+		return nil, fmt.Errorf("AWS credentials not configured")
+	}
+
+	c := &client{
+		model:      opts.Model,
+		region:     opts.Region,
+		creds:      opts.Credentials,
+		runtimeURL: "https://bedrock-runtime." + opts.Region + ".amazonaws.com",
+		controlURL: "https://bedrock." + opts.Region + ".amazonaws.com",
+		headers:    opts.CustomHeaders,
+		http:       opts.HTTPClient,
+		timeout:    opts.RequestTimeout,
+		now:        time.Now,
+	}
+	if opts.BaseURL != "" {
+		base := strings.TrimRight(opts.BaseURL, "/")
+		c.runtimeURL = base
+		c.controlURL = base
+	}
+	if c.http == nil {
+		c.http = &http.Client{}
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultRequestTimeout
+	}
+	return c, nil
+}
+
+// Provider implements llm.Client.
+func (c *client) Provider() string { return providerName }
+
+// Model implements llm.Client.
+func (c *client) Model() string { return c.model }
+
+// Usage implements llm.Client.
+func (c *client) Usage() llm.TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cumulativeUsage
+}
+
+// ResetUsage implements llm.Client.
+func (c *client) ResetUsage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cumulativeUsage = llm.TokenUsage{}
+}
+
+func (c *client) addUsage(u llm.TokenUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cumulativeUsage.Add(u)
+}
+
+// Ping calls ListModels as a lightweight liveness probe that also
+// verifies the credentials.
+func (c *client) Ping(ctx context.Context) error {
+	_, err := c.ListModels(ctx)
+	return err
+}
+
+// ---------- Chat ----------
+
+type converseText struct {
+	Text string `json:"text"`
+}
+
+type converseMessage struct {
+	Role    string         `json:"role"`
+	Content []converseText `json:"content"`
+}
+
+type inferenceConfig struct {
+	MaxTokens     *int     `json:"maxTokens,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+type converseRequest struct {
+	Messages        []converseMessage `json:"messages"`
+	System          []converseText    `json:"system,omitempty"`
+	InferenceConfig inferenceConfig   `json:"inferenceConfig"`
+}
+
+type converseUsage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+	TotalTokens  int `json:"totalTokens"`
+}
+
+func (u converseUsage) tokenUsage() llm.TokenUsage {
+	return llm.TokenUsage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+}
+
+type converseResponse struct {
+	Output struct {
+		Message converseMessage `json:"message"`
+	} `json:"output"`
+	StopReason string        `json:"stopReason"`
+	Usage      converseUsage `json:"usage"`
+}
+
+// buildConverseRequest maps a ChatRequest onto the Converse API. Only
+// text content is supported; system messages are folded into the
+// request's system prompt.
+func buildConverseRequest(req llm.ChatRequest) (converseRequest, error) {
+	out := converseRequest{}
+	if req.SystemPrompt != "" {
+		out.System = append(out.System, converseText{Text: req.SystemPrompt})
+	}
+	if len(req.Tools) > 0 {
+		return out, invalidRequest("tools are not supported")
+	}
+
+	for _, m := range req.Messages {
+		var content []converseText
+		for _, block := range m.Content {
+			if block.Type != llm.BlockText {
+				return out, invalidRequest(fmt.Sprintf("%s content is not supported", block.Type))
+			}
+			content = append(content, converseText{Text: block.Text})
+		}
+		switch m.Role {
+		case llm.RoleSystem:
+			out.System = append(out.System, content...)
+		case llm.RoleUser, llm.RoleAssistant:
+			out.Messages = append(out.Messages, converseMessage{Role: string(m.Role), Content: content})
+		default:
+			return out, invalidRequest(fmt.Sprintf("%s messages are not supported", m.Role))
+		}
+	}
+
+	out.InferenceConfig.MaxTokens = req.MaxTokens
+	if out.InferenceConfig.MaxTokens == nil {
+		out.InferenceConfig.MaxTokens = llm.Int(defaultMaxTokens)
+	}
+	out.InferenceConfig.Temperature = req.Temperature
+	if out.InferenceConfig.Temperature == nil {
+		out.InferenceConfig.Temperature = llm.Float(defaultTemperature)
+	}
+	out.InferenceConfig.StopSequences = req.StopSequences
+	return out, nil
+}
+
+func mapStopReason(reason string) llm.StopReason {
+	switch reason {
+	case "max_tokens":
+		return llm.StopReasonMaxTokens
+	case "stop_sequence":
+		return llm.StopReasonStopSequence
+	case "tool_use":
+		return llm.StopReasonToolUse
+	case "content_filtered", "guardrail_intervened":
+		return llm.StopReasonContentFilter
+	default:
+		return llm.StopReasonEndTurn
+	}
+}
+
+// Chat implements llm.Client using the Converse API.
+func (c *client) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	body, err := buildConverseRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var resp converseResponse
+	if err := c.doJSON(ctx, http.MethodPost, c.modelURL("converse"), body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &llm.ChatResponse{
+		StopReason: mapStopReason(resp.StopReason),
+		Usage:      resp.Usage.tokenUsage(),
+	}
+	for _, block := range resp.Output.Message.Content {
+		if block.Text != "" {
+			out.Content = append(out.Content, llm.TextBlock(block.Text))
+		}
+	}
+	c.addUsage(out.Usage)
+	return out, nil
+}
+
+// ChatStream implements llm.Client using the ConverseStream API.
+func (c *client) ChatStream(ctx context.Context, req llm.ChatRequest) (*llm.Stream, error) {
+	body, err := buildConverseRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timeout only bounds the wait for response headers; the
+	// stream itself may run longer.
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(c.timeout, cancel)
+	resp, err := c.do(ctx, http.MethodPost, c.modelURL("converse-stream"), body)
+	timer.Stop()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	chunks := make(chan llm.StreamChunk)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer cancel()
+		defer close(chunks)
+		defer close(errCh)
+		defer resp.Body.Close()
+
+		send := func(chunk llm.StreamChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return false
+			}
+		}
+
+		usage := &llm.TokenUsage{}
+		for {
+			msg, err := readEventMessage(resp.Body)
+			if errors.Is(err, io.EOF) {
+				send(llm.StreamChunk{Type: llm.ChunkDone, Usage: usage})
+				return
+			}
+			if err != nil {
+				if ctxErr := ctx.Err(); ctxErr != nil {
+					err = ctxErr
+				}
+				errCh <- err
+				return
+			}
+
+			if msg.headers[":message-type"] != "event" {
+				errCh <- streamException(msg)
+				return
+			}
+
+			switch msg.headers[":event-type"] {
+			case "contentBlockDelta":
+				var ev struct {
+					Delta struct {
+						Text string `json:"text"`
+					} `json:"delta"`
+				}
+				if err := json.Unmarshal(msg.payload, &ev); err != nil {
+					errCh <- fmt.Errorf("failed to decode Bedrock stream event: %w", err)
+					return
+				}
+				if ev.Delta.Text != "" && !send(llm.StreamChunk{Type: llm.ChunkText, Text: ev.Delta.Text}) {
+					return
+				}
+			case "metadata":
+				var ev struct {
+					Usage converseUsage `json:"usage"`
+				}
+				if err := json.Unmarshal(msg.payload, &ev); err == nil {
+					*usage = ev.Usage.tokenUsage()
+					c.addUsage(*usage)
+				}
+			}
+		}
+	}()
+
+	return &llm.Stream{Chunks: chunks, Err: errCh}, nil
+}
+
+// streamException converts an exception or error frame into a
+// ProviderError.
+func streamException(msg *eventMessage) error {
+	var ev struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(msg.payload, &ev) // best-effort; fall back to headers below
+
+	kind := msg.headers[":exception-type"]
+	if kind == "" {
+		kind = msg.headers[":error-code"]
+	}
+	text := ev.Message
+	if text == "" {
+		text = msg.headers[":error-message"]
+	}
+	if text == "" {
+		text = kind
+	}
+
+	var sentinel error
+	switch kind {
+	case "throttlingException", "serviceUnavailableException":
+		sentinel = llm.ErrRateLimit
+	case "validationException":
+		sentinel = llm.ErrInvalidRequest
+	default:
+		sentinel = llm.ErrProviderError
+	}
+	return &llm.ProviderError{Err: sentinel, Message: text, Provider: providerName}
+}
+
+// ---------- Embed ----------
+
+type titanEmbedRequest struct {
+	InputText string `json:"inputText"`
+}
+
+type titanEmbedResponse struct {
+	Embedding           []float64 `json:"embedding"`
+	InputTextTokenCount int       `json:"inputTextTokenCount"`
+}
+
+// Embed implements llm.Client using the Titan Text Embeddings format.
+func (c *client) Embed(ctx context.Context, text string) ([]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var resp titanEmbedResponse
+	if err := c.doJSON(ctx, http.MethodPost, c.modelURL("invoke"), titanEmbedRequest{InputText: text}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embedding) == 0 {
+		return nil, &llm.ProviderError{
+			Err:      llm.ErrProviderError,
+			Message:  "response contained no embedding",
+			Provider: providerName,
+		}
+	}
+	c.addUsage(llm.TokenUsage{
+		PromptTokens: resp.InputTextTokenCount,
+		TotalTokens:  resp.InputTextTokenCount,
+	})
+	return resp.Embedding, nil
+}
+
+// EmbedBatch implements llm.Client. Titan embeds one text per request,
+// so texts are embedded sequentially.
+func (c *client) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, 0, len(texts))
+	for _, text := range texts {
+		embedding, err := c.Embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, embedding)
+	}
+	return out, nil
+}
+
+// EmbedMultimodal implements llm.Client; it is not supported.
+func (c *client) EmbedMultimodal(ctx context.Context, req llm.MultimodalEmbedRequest) ([][]float64, error) {
+	return nil, notSupported("multimodal embeddings")
+}
+
+// Rerank implements llm.Client; it is not supported.
+func (c *client) Rerank(ctx context.Context, req llm.RerankRequest) (*llm.RerankResponse, error) {
+	return nil, notSupported("reranking")
+}
+
+// ---------- Models ----------
+
+type foundationModelsResponse struct {
+	ModelSummaries []struct {
+		ModelID                    string   `json:"modelId"`
+		InputModalities            []string `json:"inputModalities"`
+		OutputModalities           []string `json:"outputModalities"`
+		ResponseStreamingSupported bool     `json:"responseStreamingSupported"`
+	} `json:"modelSummaries"`
+}
+
+// ListModels implements llm.Client.
+func (c *client) ListModels(ctx context.Context, opts ...llm.ListModelsOption) ([]string, error) {
+	infos, err := c.ListModelsWithMetadata(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(infos))
+	for i, info := range infos {
+		ids[i] = info.ID
+	}
+	return ids, nil
+}
+
+// ListModelsWithMetadata implements llm.Client using the control-plane
+// ListFoundationModels API.
+func (c *client) ListModelsWithMetadata(ctx context.Context, opts ...llm.ListModelsOption) ([]llm.ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var resp foundationModelsResponse
+	if err := c.doJSON(ctx, http.MethodGet, c.controlURL+"/foundation-models", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	infos := make([]llm.ModelInfo, 0, len(resp.ModelSummaries))
+	for _, m := range resp.ModelSummaries {
+		info := llm.ModelInfo{ID: m.ModelID}
+		for _, out := range m.OutputModalities {
+			switch out {
+			case "TEXT":
+				info.Capabilities = append(info.Capabilities, llm.ModelCapabilityChat)
+				if m.ResponseStreamingSupported {
+					info.Capabilities = append(info.Capabilities, llm.ModelCapabilityStreaming)
+				}
+			case "EMBEDDING":
+				info.Capabilities = append(info.Capabilities, llm.ModelCapabilityEmbeddings)
+			}
+		}
+		for _, in := range m.InputModalities {
+			if in == "IMAGE" {
+				info.Capabilities = append(info.Capabilities, llm.ModelCapabilityVision)
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	var cfg llm.ListModelsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return llm.FilterModelInfos(infos, cfg), nil
+}
+
+// ---------- HTTP ----------
+
+// modelURL returns the runtime URL of an operation on the configured
+// model. Model IDs contain ":" (e.g. "anthropic.claude-3-haiku-20240307-v1:0"),
+// which Bedrock expects percent-encoded.
+func (c *client) modelURL(operation string) string {
+	id := strings.ReplaceAll(url.PathEscape(c.model), ":", "%3A")
+	return c.runtimeURL + "/model/" + id + "/" + operation
+}
+
+// do sends a signed request and returns the response if it succeeded.
+// A nil reqBody sends no body.
+func (c *client) do(ctx context.Context, method, rawURL string, reqBody any) (*http.Response, error) {
+	var payload []byte
+	if reqBody != nil {
+		var err error
+		if payload, err = json.Marshal(reqBody); err != nil {
+			return nil, fmt.Errorf("failed to encode Bedrock request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Bedrock request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	signRequest(req, payload, c.creds, c.region, signingService, c.now())
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &llm.ProviderError{Err: llm.ErrProviderError, Message: err.Error(), Provider: providerName}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, mapError(resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+func (c *client) doJSON(ctx context.Context, method, rawURL string, reqBody, dest any) error {
+	resp, err := c.do(ctx, method, rawURL, reqBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode Bedrock response: %w", err)
+	}
+	return nil
+}
+
+func mapError(status int, body []byte) error {
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &errResp) // best-effort; fall back to status-based message below
+
+	msg := errResp.Message
+	if msg == "" {
+		msg = fmt.Sprintf("HTTP %d", status)
+	}
+
+	var sentinel error
+	switch {
+	case status == 401 || status == 403:
+		sentinel = llm.ErrAuthentication
+	case status == 429:
+		sentinel = llm.ErrRateLimit
+	case status == 400:
+		sentinel = llm.ErrInvalidRequest
+	default:
+		sentinel = llm.ErrProviderError
+	}
+
+	return &llm.ProviderError{
+		Err:        sentinel,
+		StatusCode: status,
+		Message:    msg,
+		Provider:   providerName,
+	}
+}
+
+func invalidRequest(msg string) error {
+	return &llm.ProviderError{Err: llm.ErrInvalidRequest, Message: msg, Provider: providerName}
+}
+
+func notSupported(what string) error {
+	return &llm.ProviderError{
+		Err:      llm.ErrNotSupported,
+		Message:  what + " are not supported",
+		Provider: providerName,
+	}
+}
@@ -0,0 +1,325 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	llm "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+const testModel = "anthropic.claude-3-haiku-20240307-v1:0"
+
+// TestSignRequest_AWSTestSuite checks the signer against the
+// "get-vanilla" case of the AWS Signature Version 4 test suite.
+func TestSignRequest_AWSTestSuite(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := Credentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	signRequest(req, nil, creds, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, " +
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("X-Amz-Date = %q", got)
+	}
+}
+
+func TestCanonicalURI_DoubleEncodesModelID(t *testing.T) {
+	c := &client{runtimeURL: "https://bedrock-runtime.us-east-1.amazonaws.com", model: testModel}
+	req := httptest.NewRequest(http.MethodPost, c.modelURL("converse"), nil)
+
+	want := "/model/anthropic.claude-3-haiku-20240307-v1%253A0/converse"
+	if got := canonicalURI(req.URL); got != want {
+		t.Errorf("canonicalURI = %q, want %q", got, want)
+	}
+}
+
+// encodeEventMessage frames headers and payload as an event stream
+// message, the inverse of readEventMessage.
+func encodeEventMessage(headers map[string]string, payload []byte) []byte {
+	var hdr bytes.Buffer
+	for name, value := range headers {
+		hdr.WriteByte(byte(len(name)))
+		hdr.WriteString(name)
+		hdr.WriteByte(7)
+		_ = binary.Write(&hdr, binary.BigEndian, uint16(len(value)))
+		hdr.WriteString(value)
+	}
+
+	total := 12 + hdr.Len() + len(payload) + 4
+	var msg bytes.Buffer
+	_ = binary.Write(&msg, binary.BigEndian, uint32(total))
+	_ = binary.Write(&msg, binary.BigEndian, uint32(hdr.Len()))
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	msg.Write(hdr.Bytes())
+	msg.Write(payload)
+	_ = binary.Write(&msg, binary.BigEndian, crc32.ChecksumIEEE(msg.Bytes()))
+	return msg.Bytes()
+}
+
+func TestReadEventMessage_RoundTrip(t *testing.T) {
+	headers := map[string]string{":message-type": "event", ":event-type": "contentBlockDelta"}
+	frame := encodeEventMessage(headers, []byte(`{"delta":{"text":"hi"}}`))
+	r := bytes.NewReader(append(append([]byte{}, frame...), frame...))
+
+	for i := 0; i < 2; i++ {
+		msg, err := readEventMessage(r)
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if msg.headers[":event-type"] != "contentBlockDelta" || string(msg.payload) != `{"delta":{"text":"hi"}}` {
+			t.Errorf("message %d: got %v %q", i, msg.headers, msg.payload)
+		}
+	}
+	if _, err := readEventMessage(r); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at end of stream, got %v", err)
+	}
+
+	frame[len(frame)-5] ^= 0xff
+	if _, err := readEventMessage(bytes.NewReader(frame)); err == nil {
+		t.Error("expected a checksum error for a corrupted message")
+	}
+}
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(Options{
+		Model:       testModel,
+		Region:      "us-east-1",
+		Credentials: Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret", SessionToken: "token"},
+		BaseURL:     srv.URL,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c.(*client)
+}
+
+func TestChat_Converse(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.EscapedPath(); got != "/model/anthropic.claude-3-haiku-20240307-v1%3A0/converse" {
+			t.Errorf("unexpected path %q", got)
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.Contains(auth, "/us-east-1/bedrock/aws4_request") ||
+			!strings.Contains(auth, "SignedHeaders=host;x-amz-date;x-amz-security-token") {
+			t.Errorf("unexpected Authorization %q", auth)
+		}
+		if r.Header.Get("X-Amz-Security-Token") != "token" {
+			t.Error("missing session token header")
+		}
+
+		var req converseRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if len(req.System) != 1 || req.System[0].Text != "be brief" {
+			t.Errorf("unexpected system prompt %+v", req.System)
+		}
+		if len(req.Messages) != 1 || req.Messages[0].Role != "user" || req.Messages[0].Content[0].Text != "hello" {
+			t.Errorf("unexpected messages %+v", req.Messages)
+		}
+		if *req.InferenceConfig.MaxTokens != 100 || *req.InferenceConfig.Temperature != defaultTemperature {
+			t.Errorf("unexpected inference config %+v", req.InferenceConfig)
+		}
+		if len(req.InferenceConfig.StopSequences) != 1 || req.InferenceConfig.StopSequences[0] != "END" {
+			t.Errorf("unexpected stop sequences %v", req.InferenceConfig.StopSequences)
+		}
+
+		_, _ = io.WriteString(w, `{"output":{"message":{"role":"assistant","content":[{"text":"Hi!"}]}},`+
+			`"stopReason":"max_tokens","usage":{"inputTokens":12,"outputTokens":3,"totalTokens":15}}`)
+	})
+
+	resp, err := c.Chat(context.Background(), llm.ChatRequest{
+		SystemPrompt:  "be brief",
+		Messages:      []llm.Message{llm.UserText("hello")},
+		MaxTokens:     llm.Int(100),
+		StopSequences: []string{"END"},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Text != "Hi!" {
+		t.Errorf("unexpected content %+v", resp.Content)
+	}
+	if resp.StopReason != llm.StopReasonMaxTokens {
+		t.Errorf("unexpected stop reason %q", resp.StopReason)
+	}
+	want := llm.TokenUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+	if resp.Usage != want || c.Usage() != want {
+		t.Errorf("unexpected usage %+v / %+v", resp.Usage, c.Usage())
+	}
+}
+
+func TestChat_MapsErrors(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"message":"Too many requests"}`)
+	})
+
+	_, err := c.Chat(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if !errors.Is(err, llm.ErrRateLimit) {
+		t.Fatalf("expected ErrRateLimit, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "Too many requests") {
+		t.Errorf("error should carry the Bedrock message: %v", err)
+	}
+}
+
+func TestChatStream_ConverseStream(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.EscapedPath(), "/converse-stream") {
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+		w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+		event := func(kind, payload string) {
+			_, _ = w.Write(encodeEventMessage(map[string]string{
+				":message-type": "event",
+				":event-type":   kind,
+			}, []byte(payload)))
+		}
+		event("messageStart", `{"role":"assistant"}`)
+		event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Hel"}}`)
+		event("contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"lo"}}`)
+		event("messageStop", `{"stopReason":"end_turn"}`)
+		event("metadata", `{"usage":{"inputTokens":5,"outputTokens":2,"totalTokens":7}}`)
+	})
+
+	stream, err := c.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	var text strings.Builder
+	var usage *llm.TokenUsage
+	for chunk := range stream.Chunks {
+		switch chunk.Type {
+		case llm.ChunkText:
+			text.WriteString(chunk.Text)
+		case llm.ChunkDone:
+			usage = chunk.Usage
+		}
+	}
+	if err := <-stream.Err; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("unexpected text %q", text.String())
+	}
+	if usage == nil || usage.TotalTokens != 7 || usage.CompletionTokens != 2 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestChatStream_Exception(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(encodeEventMessage(map[string]string{
+			":message-type":   "exception",
+			":exception-type": "throttlingException",
+		}, []byte(`{"message":"slow down"}`)))
+	})
+
+	stream, err := c.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	for range stream.Chunks {
+	}
+	if err := <-stream.Err; !errors.Is(err, llm.ErrRateLimit) {
+		t.Errorf("expected ErrRateLimit, got %v", err)
+	}
+}
+
+func TestEmbedBatch_Titan(t *testing.T) {
+	calls := 0
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if !strings.HasSuffix(r.URL.EscapedPath(), "/invoke") {
+			t.Errorf("unexpected path %q", r.URL.EscapedPath())
+		}
+		var req titanEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		_ = json.NewEncoder(w).Encode(titanEmbedResponse{
+			Embedding:           []float64{float64(len(req.InputText)), 1},
+			InputTextTokenCount: 4,
+		})
+	})
+
+	got, err := c.EmbedBatch(context.Background(), []string{"a", "bbb"})
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if calls != 2 || len(got) != 2 || got[0][0] != 1 || got[1][0] != 3 {
+		t.Errorf("unexpected embeddings %v after %d calls", got, calls)
+	}
+	if u := c.Usage(); u.PromptTokens != 8 || u.TotalTokens != 8 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+}
+
+func TestListModels_FoundationModels(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/foundation-models" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"modelSummaries":[
+			{"modelId":"amazon.titan-embed-text-v2:0","inputModalities":["TEXT"],"outputModalities":["EMBEDDING"]},
+			{"modelId":"`+testModel+`","inputModalities":["TEXT","IMAGE"],"outputModalities":["TEXT"],"responseStreamingSupported":true}
+		]}`)
+	})
+
+	models, err := c.ListModels(context.Background(), llm.WithCapabilities(llm.ModelCapabilityEmbeddings))
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 1 || models[0] != "amazon.titan-embed-text-v2:0" {
+		t.Errorf("unexpected models %v", models)
+	}
+}
+
+func TestRerank_NotSupported(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	if _, err := c.Rerank(context.Background(), llm.RerankRequest{}); !errors.Is(err, llm.ErrNotSupported) {
+		t.Errorf("expected ErrNotSupported, got %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package bedrock
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// eventMessage is one frame of an AWS event stream
+// (application/vnd.amazon.eventstream), the binary framing Bedrock uses
+// for ConverseStream responses. Only string-valued headers are kept;
+// they are the only kind Bedrock sends that this package reads.
+type eventMessage struct {
+	headers map[string]string
+	payload []byte
+}
+
+// maxEventMessageSize guards against allocating for a corrupt length
+// prefix. Bedrock frames are far smaller.
+const maxEventMessageSize = 16 << 20
+
+// readEventMessage reads and verifies the next frame. It returns io.EOF
+// when the stream ends cleanly between frames.
+//
+// Frame layout: total length (4), headers length (4), prelude CRC (4),
+// headers, payload, message CRC (4); integers are big-endian and both
+// CRCs are CRC-32 (IEEE).
+func readEventMessage(r io.Reader) (*eventMessage, error) {
+	var prelude [12]byte
+	if _, err := io.ReadFull(r, prelude[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("truncated event stream prelude")
+		}
+		return nil, err
+	}
+
+	total := binary.BigEndian.Uint32(prelude[0:4])
+	headersLen := binary.BigEndian.Uint32(prelude[4:8])
+	if crc32.ChecksumIEEE(prelude[0:8]) != binary.BigEndian.Uint32(prelude[8:12]) {
+		return nil, fmt.Errorf("event stream prelude checksum mismatch")
+	}
+	if total < 16 || total > maxEventMessageSize || uint64(headersLen) > uint64(total)-16 {
+		return nil, fmt.Errorf("invalid event stream message length %d", total)
+	}
+
+	rest := make([]byte, total-12)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("truncated event stream message: %w", err)
+	}
+
+	crc := crc32.NewIEEE()
+	crc.Write(prelude[:])
+	crc.Write(rest[:len(rest)-4])
+	if crc.Sum32() != binary.BigEndian.Uint32(rest[len(rest)-4:]) {
+		return nil, fmt.Errorf("event stream message checksum mismatch")
+	}
+
+	headers, err := parseEventHeaders(rest[:headersLen])
+	if err != nil {
+		return nil, err
+	}
+	return &eventMessage{
+		headers: headers,
+		payload: rest[headersLen : len(rest)-4],
+	}, nil
+}
+
+// Sizes of the fixed-width header value types, indexed by type ID.
+var eventHeaderValueSizes = map[byte]int{
+	0: 0,  // bool true
+	1: 0,  // bool false
+	2: 1,  // byte
+	3: 2,  // short
+	4: 4,  // int
+	5: 8,  // long
+	8: 8,  // timestamp
+	9: 16, // uuid
+}
+
+func parseEventHeaders(b []byte) (map[string]string, error) {
+	headers := make(map[string]string)
+	for len(b) > 0 {
+		nameLen := int(b[0])
+		if len(b) < 1+nameLen+1 {
+			return nil, fmt.Errorf("truncated event stream header")
+		}
+		name := string(b[1 : 1+nameLen])
+		valueType := b[1+nameLen]
+		b = b[2+nameLen:]
+
+		switch valueType {
+		case 6, 7: // byte array, string
+			if len(b) < 2 {
+				return nil, fmt.Errorf("truncated event stream header %q", name)
+			}
+			n := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+n {
+				return nil, fmt.Errorf("truncated event stream header %q", name)
+			}
+			if valueType == 7 {
+				headers[name] = string(b[2 : 2+n])
+			}
+			b = b[2+n:]
+		default:
+			size, ok := eventHeaderValueSizes[valueType]
+			if !ok {
+				return nil, fmt.Errorf("unknown event stream header type %d", valueType)
+			}
+			if len(b) < size {
+				return nil, fmt.Errorf("truncated event stream header %q", name)
+			}
+			b = b[size:]
+		}
+	}
+	return headers, nil
+}
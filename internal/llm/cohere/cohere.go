@@ -0,0 +1,688 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package cohere implements llm.Client for Cohere, which
+// pgedge-go-llm-lib does not provide. Chat uses the v2 Chat API, Embed
+// the v2 Embed API (the embed-v3 and later models) and Rerank the v2
+// Rerank API. Requests are authenticated with a bearer API key.
+package cohere
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	llm "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+const (
+	providerName = "cohere"
+
+	defaultBaseURL = "https://api.cohere.com"
+
+	// Defaults matching pgedge-go-llm-lib's Options.WithDefaults, so a
+	// Cohere pipeline behaves like the other providers.
+	defaultMaxTokens      = 4096
+	defaultTemperature    = 0.7
+	defaultRequestTimeout = 120 * time.Second
+
+	// maxEmbedTexts is the most texts the Embed API takes in one
+	// request.
+	maxEmbedTexts = 96
+)
+
+// InputType tells an embed-v3 model what the texts it embeds are for;
+// it embeds queries and the documents they are searched against
+// differently.
+type InputType string
+
+const (
+	InputTypeSearchQuery    InputType = "search_query"
+	InputTypeSearchDocument InputType = "search_document"
+)
+
+type inputTypeKey struct{}
+
+// ContextWithInputType returns a context whose embedding requests are
+// made with input type t. Without one, texts are embedded as queries.
+func ContextWithInputType(ctx context.Context, t InputType) context.Context {
+	return context.WithValue(ctx, inputTypeKey{}, t)
+}
+
+func inputTypeFromContext(ctx context.Context) InputType {
+	if t, ok := ctx.Value(inputTypeKey{}).(InputType); ok {
+		return t
+	}
+	return InputTypeSearchQuery
+}
+
+// Options configure a Cohere client.
+type Options struct {
+	APIKey string
+	Model  string
+
+	// BaseURL replaces https://api.cohere.com, e.g. for a proxy.
+	BaseURL string
+
+	CustomHeaders map[string]string
+	HTTPClient    *http.Client
+
+	// RequestTimeout caps each request; for streams it caps the time to
+	// receive the response headers. Zero uses the library default.
+	RequestTimeout time.Duration
+}
+
+type client struct {
+	apiKey  string
+	model   string
+	baseURL string
+	headers map[string]string
+	http    *http.Client
+	timeout time.Duration
+
+	mu              sync.Mutex
+	cumulativeUsage llm.TokenUsage
+}
+
+// New creates a Cohere client. An API key is required.
+func New(opts Options) (llm.Client, error) {
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("Cohere API key not configured")
+	}
+
+	c := &client{
+		apiKey:  opts.APIKey,
+		model:   opts.Model,
+		baseURL: defaultBaseURL,
+		headers: opts.CustomHeaders,
+		http:    opts.HTTPClient,
+		timeout: opts.RequestTimeout,
+	}
+	if opts.BaseURL != "" {
+		c.baseURL = strings.TrimRight(opts.BaseURL, "/")
+	}
+	if c.http == nil {
+		c.http = &http.Client{}
+	}
+	if c.timeout <= 0 {
+		c.timeout = defaultRequestTimeout
+	}
+	return c, nil
+}
+
+// Provider implements llm.Client.
+func (c *client) Provider() string { return providerName }
+
+// Model implements llm.Client.
+func (c *client) Model() string { return c.model }
+
+// Usage implements llm.Client.
+func (c *client) Usage() llm.TokenUsage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.cumulativeUsage
+}
+
+// ResetUsage implements llm.Client.
+func (c *client) ResetUsage() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cumulativeUsage = llm.TokenUsage{}
+}
+
+func (c *client) addUsage(u llm.TokenUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cumulativeUsage.Add(u)
+}
+
+// Ping calls ListModels as a lightweight liveness probe that also
+// verifies the API key.
+func (c *client) Ping(ctx context.Context) error {
+	_, err := c.ListModels(ctx)
+	return err
+}
+
+// ---------- Chat ----------
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	MaxTokens      *int            `json:"max_tokens,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	StopSequences  []string        `json:"stop_sequences,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Stream         bool            `json:"stream,omitempty"`
+}
+
+type chatUsage struct {
+	Tokens struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"tokens"`
+}
+
+func (u chatUsage) tokenUsage() llm.TokenUsage {
+	return llm.TokenUsage{
+		PromptTokens:     u.Tokens.InputTokens,
+		CompletionTokens: u.Tokens.OutputTokens,
+		TotalTokens:      u.Tokens.InputTokens + u.Tokens.OutputTokens,
+	}
+}
+
+type chatResponse struct {
+	Message struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"message"`
+	FinishReason string    `json:"finish_reason"`
+	Usage        chatUsage `json:"usage"`
+}
+
+// buildChatRequest maps a ChatRequest onto the v2 Chat API. Only text
+// content is supported; the system prompt becomes the first message. A
+// JSON schema response format is passed on as Cohere's own.
+func (c *client) buildChatRequest(req llm.ChatRequest) (chatRequest, error) {
+	out := chatRequest{Model: c.model}
+	if req.SystemPrompt != "" {
+		out.Messages = append(out.Messages, chatMessage{Role: "system", Content: req.SystemPrompt})
+	}
+	if len(req.Tools) > 0 {
+		return out, invalidRequest("tools are not supported")
+	}
+
+	for _, m := range req.Messages {
+		var text strings.Builder
+		for _, block := range m.Content {
+			if block.Type != llm.BlockText {
+				return out, invalidRequest(fmt.Sprintf("%s content is not supported", block.Type))
+			}
+			text.WriteString(block.Text)
+		}
+		switch m.Role {
+		case llm.RoleSystem, llm.RoleUser, llm.RoleAssistant:
+			out.Messages = append(out.Messages, chatMessage{Role: string(m.Role), Content: text.String()})
+		default:
+			return out, invalidRequest(fmt.Sprintf("%s messages are not supported", m.Role))
+		}
+	}
+
+	out.MaxTokens = req.MaxTokens
+	if out.MaxTokens == nil {
+		out.MaxTokens = llm.Int(defaultMaxTokens)
+	}
+	out.Temperature = req.Temperature
+	if out.Temperature == nil {
+		out.Temperature = llm.Float(defaultTemperature)
+	}
+	out.StopSequences = req.StopSequences
+	if rf := req.ResponseFormat; rf != nil {
+		switch rf.Type {
+		case llm.ResponseFormatJSON:
+			out.ResponseFormat = &responseFormat{Type: "json_object"}
+		case llm.ResponseFormatJSONSchema:
+			out.ResponseFormat = &responseFormat{Type: "json_object", JSONSchema: rf.JSONSchema}
+		}
+	}
+	return out, nil
+}
+
+func mapFinishReason(reason string) llm.StopReason {
+	switch reason {
+	case "MAX_TOKENS":
+		return llm.StopReasonMaxTokens
+	case "STOP_SEQUENCE":
+		return llm.StopReasonStopSequence
+	case "TOOL_CALL":
+		return llm.StopReasonToolUse
+	case "ERROR":
+		return llm.StopReasonError
+	default:
+		return llm.StopReasonEndTurn
+	}
+}
+
+// Chat implements llm.Client using the v2 Chat API.
+func (c *client) Chat(ctx context.Context, req llm.ChatRequest) (*llm.ChatResponse, error) {
+	body, err := c.buildChatRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var resp chatResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/v2/chat", body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &llm.ChatResponse{
+		StopReason: mapFinishReason(resp.FinishReason),
+		Usage:      resp.Usage.tokenUsage(),
+	}
+	for _, block := range resp.Message.Content {
+		if block.Type == "text" && block.Text != "" {
+			out.Content = append(out.Content, llm.TextBlock(block.Text))
+		}
+	}
+	c.addUsage(out.Usage)
+	return out, nil
+}
+
+// streamEvent is an event of a v2 Chat API stream. Text arrives in
+// content-delta events and the usage in the final message-end event.
+type streamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Message struct {
+			Content struct {
+				Text string `json:"text"`
+			} `json:"content"`
+		} `json:"message"`
+		FinishReason string    `json:"finish_reason"`
+		Usage        chatUsage `json:"usage"`
+	} `json:"delta"`
+}
+
+// ChatStream implements llm.Client using the v2 Chat API's server-sent
+// events.
+func (c *client) ChatStream(ctx context.Context, req llm.ChatRequest) (*llm.Stream, error) {
+	body, err := c.buildChatRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = true
+
+	// The timeout only bounds the wait for response headers; the
+	// stream itself may run longer.
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(c.timeout, cancel)
+	resp, err := c.do(ctx, http.MethodPost, "/v2/chat", body)
+	timer.Stop()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	chunks := make(chan llm.StreamChunk)
+	errCh := make(chan error, 1)
+
+	go func() {
+		defer cancel()
+		defer close(chunks)
+		defer close(errCh)
+		defer resp.Body.Close()
+
+		send := func(chunk llm.StreamChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				errCh <- ctx.Err()
+				return false
+			}
+		}
+
+		usage := &llm.TokenUsage{}
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data:")
+			if !ok {
+				continue
+			}
+			var ev streamEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+				errCh <- fmt.Errorf("failed to decode Cohere stream event: %w", err)
+				return
+			}
+			switch ev.Type {
+			case "content-delta":
+				if text := ev.Delta.Message.Content.Text; text != "" &&
+					!send(llm.StreamChunk{Type: llm.ChunkText, Text: text}) {
+					return
+				}
+			case "message-end":
+				if ev.Delta.FinishReason == "ERROR" {
+					errCh <- &llm.ProviderError{
+						Err:      llm.ErrProviderError,
+						Message:  "the stream ended with an error",
+						Provider: providerName,
+					}
+					return
+				}
+				*usage = ev.Delta.Usage.tokenUsage()
+				c.addUsage(*usage)
+				send(llm.StreamChunk{Type: llm.ChunkDone, Usage: usage})
+				return
+			}
+		}
+		err := scanner.Err()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		errCh <- fmt.Errorf("Cohere stream ended early: %w", err)
+	}()
+
+	return &llm.Stream{Chunks: chunks, Err: errCh}, nil
+}
+
+// ---------- Embed ----------
+
+type embedRequest struct {
+	Model          string    `json:"model"`
+	Texts          []string  `json:"texts"`
+	InputType      InputType `json:"input_type"`
+	EmbeddingTypes []string  `json:"embedding_types"`
+}
+
+type billedUnits struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	SearchUnits  int `json:"search_units"`
+}
+
+type embedResponse struct {
+	Embeddings struct {
+		Float [][]float64 `json:"float"`
+	} `json:"embeddings"`
+	Meta struct {
+		BilledUnits billedUnits `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// Embed implements llm.Client. The text is embedded as a query unless
+// the context says otherwise (see ContextWithInputType).
+func (c *client) Embed(ctx context.Context, text string) ([]float64, error) {
+	embeddings, err := c.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch implements llm.Client, embedding up to maxEmbedTexts texts
+// per request.
+func (c *client) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	inputType := inputTypeFromContext(ctx)
+	out := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedTexts {
+		batch := texts[start:min(start+maxEmbedTexts, len(texts))]
+		embeddings, err := c.embed(ctx, batch, inputType)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, embeddings...)
+	}
+	return out, nil
+}
+
+func (c *client) embed(ctx context.Context, texts []string, inputType InputType) ([][]float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body := embedRequest{
+		Model:          c.model,
+		Texts:          texts,
+		InputType:      inputType,
+		EmbeddingTypes: []string{"float"},
+	}
+	var resp embedResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/v2/embed", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings.Float) != len(texts) {
+		return nil, &llm.ProviderError{
+			Err: llm.ErrProviderError,
+			Message: fmt.Sprintf("response contained %d embeddings for %d texts",
+				len(resp.Embeddings.Float), len(texts)),
+			Provider: providerName,
+		}
+	}
+	tokens := resp.Meta.BilledUnits.InputTokens
+	c.addUsage(llm.TokenUsage{PromptTokens: tokens, TotalTokens: tokens})
+	return resp.Embeddings.Float, nil
+}
+
+// EmbedMultimodal implements llm.Client; it is not supported.
+func (c *client) EmbedMultimodal(ctx context.Context, req llm.MultimodalEmbedRequest) ([][]float64, error) {
+	return nil, notSupported("multimodal embeddings")
+}
+
+// ---------- Rerank ----------
+
+type rerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      *int     `json:"top_n,omitempty"`
+}
+
+type rerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+	Meta struct {
+		BilledUnits billedUnits `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// Rerank implements llm.Client using the v2 Rerank API. Cohere bills
+// reranking in search units rather than tokens, so its usage is not
+// counted.
+func (c *client) Rerank(ctx context.Context, req llm.RerankRequest) (*llm.RerankResponse, error) {
+	if len(req.Documents) == 0 {
+		return &llm.RerankResponse{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	body := rerankRequest{
+		Model:     c.model,
+		Query:     req.Query,
+		Documents: req.Documents,
+		TopN:      req.TopK,
+	}
+	var resp rerankResponse
+	if err := c.doJSON(ctx, http.MethodPost, "/v2/rerank", body, &resp); err != nil {
+		return nil, err
+	}
+
+	out := &llm.RerankResponse{Results: make([]llm.RerankResult, 0, len(resp.Results))}
+	for _, r := range resp.Results {
+		if r.Index < 0 || r.Index >= len(req.Documents) {
+			return nil, &llm.ProviderError{
+				Err:      llm.ErrProviderError,
+				Message:  fmt.Sprintf("response ranked document %d of %d", r.Index, len(req.Documents)),
+				Provider: providerName,
+			}
+		}
+		out.Results = append(out.Results, llm.RerankResult{
+			Index:          r.Index,
+			RelevanceScore: r.RelevanceScore,
+			Document:       req.Documents[r.Index],
+		})
+	}
+	return out, nil
+}
+
+// ---------- Models ----------
+
+type modelsResponse struct {
+	Models []struct {
+		Name          string   `json:"name"`
+		Endpoints     []string `json:"endpoints"`
+		ContextLength float64  `json:"context_length"`
+	} `json:"models"`
+}
+
+// ListModels implements llm.Client.
+func (c *client) ListModels(ctx context.Context, opts ...llm.ListModelsOption) ([]string, error) {
+	infos, err := c.ListModelsWithMetadata(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(infos))
+	for i, info := range infos {
+		ids[i] = info.ID
+	}
+	return ids, nil
+}
+
+// ListModelsWithMetadata implements llm.Client using the v1 Models API.
+func (c *client) ListModelsWithMetadata(ctx context.Context, opts ...llm.ListModelsOption) ([]llm.ModelInfo, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var resp modelsResponse
+	if err := c.doJSON(ctx, http.MethodGet, "/v1/models", nil, &resp); err != nil {
+		return nil, err
+	}
+
+	infos := make([]llm.ModelInfo, 0, len(resp.Models))
+	for _, m := range resp.Models {
+		info := llm.ModelInfo{ID: m.Name, ContextWindow: int(m.ContextLength)}
+		for _, endpoint := range m.Endpoints {
+			switch endpoint {
+			case "chat":
+				info.Capabilities = append(info.Capabilities,
+					llm.ModelCapabilityChat, llm.ModelCapabilityStreaming, llm.ModelCapabilityJSONMode)
+			case "embed":
+				info.Capabilities = append(info.Capabilities, llm.ModelCapabilityEmbeddings)
+			case "rerank":
+				info.Capabilities = append(info.Capabilities, llm.ModelCapabilityReranking)
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	var cfg llm.ListModelsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return llm.FilterModelInfos(infos, cfg), nil
+}
+
+// ---------- HTTP ----------
+
+// do sends a request to path and returns the response if it succeeded.
+// A nil reqBody sends no body.
+func (c *client) do(ctx context.Context, method, path string, reqBody any) (*http.Response, error) {
+	var payload []byte
+	if reqBody != nil {
+		var err error
+		if payload, err = json.Marshal(reqBody); err != nil {
+			return nil, fmt.Errorf("failed to encode Cohere request: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cohere request: %w", err)
+	}
+	if reqBody != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, &llm.ProviderError{Err: llm.ErrProviderError, Message: err.Error(), Provider: providerName}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		return nil, mapError(resp.StatusCode, body)
+	}
+	return resp, nil
+}
+
+func (c *client) doJSON(ctx context.Context, method, path string, reqBody, dest any) error {
+	resp, err := c.do(ctx, method, path, reqBody)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(dest); err != nil {
+		return fmt.Errorf("failed to decode Cohere response: %w", err)
+	}
+	return nil
+}
+
+func mapError(status int, body []byte) error {
+	var errResp struct {
+		Message string `json:"message"`
+	}
+	_ = json.Unmarshal(body, &errResp) // best-effort; fall back to status-based message below
+
+	msg := errResp.Message
+	if msg == "" {
+		msg = fmt.Sprintf("HTTP %d", status)
+	}
+
+	var sentinel error
+	switch {
+	case status == 401 || status == 403:
+		sentinel = llm.ErrAuthentication
+	case status == 429:
+		sentinel = llm.ErrRateLimit
+	case status == 400 || status == 422:
+		sentinel = llm.ErrInvalidRequest
+	default:
+		sentinel = llm.ErrProviderError
+	}
+
+	return &llm.ProviderError{
+		Err:        sentinel,
+		StatusCode: status,
+		Message:    msg,
+		Provider:   providerName,
+	}
+}
+
+func invalidRequest(msg string) error {
+	return &llm.ProviderError{Err: llm.ErrInvalidRequest, Message: msg, Provider: providerName}
+}
+
+func notSupported(what string) error {
+	return &llm.ProviderError{
+		Err:      llm.ErrNotSupported,
+		Message:  what + " are not supported",
+		Provider: providerName,
+	}
+}
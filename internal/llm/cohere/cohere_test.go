@@ -0,0 +1,296 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package cohere
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	llm "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+func newTestClient(t *testing.T, model string, handler http.HandlerFunc) *client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(Options{APIKey: "test-key", Model: model, BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return c.(*client)
+}
+
+func TestNew_RequiresAPIKey(t *testing.T) {
+	if _, err := New(Options{Model: "command-r"}); err == nil {
+		t.Error("expected an error without an API key")
+	}
+}
+
+func TestChat(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/chat" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("unexpected Authorization %q", got)
+		}
+
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != "command-r" || len(req.Messages) != 2 ||
+			req.Messages[0] != (chatMessage{Role: "system", Content: "be brief"}) ||
+			req.Messages[1] != (chatMessage{Role: "user", Content: "hello"}) {
+			t.Errorf("unexpected request %+v", req)
+		}
+		if *req.MaxTokens != 100 || *req.Temperature != defaultTemperature {
+			t.Errorf("unexpected max_tokens %d or temperature %v", *req.MaxTokens, *req.Temperature)
+		}
+		if req.ResponseFormat == nil || req.ResponseFormat.Type != "json_object" ||
+			string(req.ResponseFormat.JSONSchema) != `{"type":"object"}` {
+			t.Errorf("unexpected response format %+v", req.ResponseFormat)
+		}
+
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":[{"type":"text","text":"{}"}]},`+
+			`"finish_reason":"MAX_TOKENS","usage":{"billed_units":{"input_tokens":10,"output_tokens":3},`+
+			`"tokens":{"input_tokens":12,"output_tokens":3}}}`)
+	})
+
+	resp, err := c.Chat(context.Background(), llm.ChatRequest{
+		SystemPrompt: "be brief",
+		Messages:     []llm.Message{llm.UserText("hello")},
+		MaxTokens:    llm.Int(100),
+		ResponseFormat: &llm.ResponseFormat{
+			Type:       llm.ResponseFormatJSONSchema,
+			JSONSchema: json.RawMessage(`{"type":"object"}`),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(resp.Content) != 1 || resp.Content[0].Text != "{}" {
+		t.Errorf("unexpected content %+v", resp.Content)
+	}
+	if resp.StopReason != llm.StopReasonMaxTokens {
+		t.Errorf("unexpected stop reason %q", resp.StopReason)
+	}
+	want := llm.TokenUsage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15}
+	if resp.Usage != want || c.Usage() != want {
+		t.Errorf("unexpected usage %+v / %+v", resp.Usage, c.Usage())
+	}
+}
+
+func TestChat_RejectsTools(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	})
+	_, err := c.Chat(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+		Tools:    []llm.Tool{{Name: "search"}},
+	})
+	if !errors.Is(err, llm.ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest, got %v", err)
+	}
+}
+
+func TestChat_MapsErrors(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"message":"invalid api token"}`)
+	})
+
+	_, err := c.Chat(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if !errors.Is(err, llm.ErrAuthentication) {
+		t.Fatalf("expected ErrAuthentication, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "invalid api token") {
+		t.Errorf("error should carry the Cohere message: %v", err)
+	}
+}
+
+func TestChatStream(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if !req.Stream {
+			t.Error("expected a streaming request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message-start","delta":{"message":{"role":"assistant"}}}`,
+			`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"Hel"}}}}`,
+			`{"type":"content-delta","index":0,"delta":{"message":{"content":{"text":"lo"}}}}`,
+			`{"type":"message-end","delta":{"finish_reason":"COMPLETE",` +
+				`"usage":{"tokens":{"input_tokens":5,"output_tokens":2}}}}`,
+		} {
+			_, _ = io.WriteString(w, "event: x\ndata: "+ev+"\n\n")
+		}
+	})
+
+	stream, err := c.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+
+	var text strings.Builder
+	var usage *llm.TokenUsage
+	for chunk := range stream.Chunks {
+		switch chunk.Type {
+		case llm.ChunkText:
+			text.WriteString(chunk.Text)
+		case llm.ChunkDone:
+			usage = chunk.Usage
+		}
+	}
+	if err := <-stream.Err; err != nil {
+		t.Fatalf("stream error: %v", err)
+	}
+	if text.String() != "Hello" {
+		t.Errorf("unexpected text %q", text.String())
+	}
+	if usage == nil || usage.TotalTokens != 7 || usage.CompletionTokens != 2 {
+		t.Errorf("unexpected usage %+v", usage)
+	}
+}
+
+func TestChatStream_EndsEarly(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, `data: {"type":"content-delta","delta":{"message":{"content":{"text":"Hel"}}}}`+"\n\n")
+	})
+
+	stream, err := c.ChatStream(context.Background(), llm.ChatRequest{
+		Messages: []llm.Message{llm.UserText("hello")},
+	})
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	for range stream.Chunks {
+	}
+	if err := <-stream.Err; !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestEmbedBatch(t *testing.T) {
+	var inputTypes []InputType
+	var sizes []int
+	c := newTestClient(t, "embed-english-v3.0", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/embed" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		var req embedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		inputTypes = append(inputTypes, req.InputType)
+		sizes = append(sizes, len(req.Texts))
+
+		var resp embedResponse
+		for _, text := range req.Texts {
+			resp.Embeddings.Float = append(resp.Embeddings.Float, []float64{float64(len(text)), 1})
+		}
+		resp.Meta.BilledUnits.InputTokens = len(req.Texts)
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+
+	texts := make([]string, maxEmbedTexts+1)
+	for i := range texts {
+		texts[i] = strings.Repeat("a", i%3+1)
+	}
+	ctx := ContextWithInputType(context.Background(), InputTypeSearchDocument)
+	got, err := c.EmbedBatch(ctx, texts)
+	if err != nil {
+		t.Fatalf("EmbedBatch: %v", err)
+	}
+	if len(got) != len(texts) || got[1][0] != 2 || got[maxEmbedTexts][0] != float64(maxEmbedTexts%3+1) {
+		t.Errorf("unexpected embeddings for %d texts", len(got))
+	}
+	if len(sizes) != 2 || sizes[0] != maxEmbedTexts || sizes[1] != 1 {
+		t.Errorf("unexpected batches %v", sizes)
+	}
+	if u := c.Usage(); u.PromptTokens != len(texts) || u.TotalTokens != len(texts) {
+		t.Errorf("unexpected usage %+v", u)
+	}
+
+	if _, err := c.Embed(context.Background(), "query"); err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	want := []InputType{InputTypeSearchDocument, InputTypeSearchDocument, InputTypeSearchQuery}
+	if !slices.Equal(inputTypes, want) {
+		t.Errorf("input types = %v, want %v", inputTypes, want)
+	}
+}
+
+func TestRerank(t *testing.T) {
+	c := newTestClient(t, "rerank-v3.5", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/rerank" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		var req rerankRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		if req.Model != "rerank-v3.5" || req.Query != "q" || len(req.Documents) != 3 ||
+			req.TopN == nil || *req.TopN != 2 {
+			t.Errorf("unexpected request %+v", req)
+		}
+		_, _ = io.WriteString(w, `{"results":[{"index":2,"relevance_score":0.9},{"index":0,"relevance_score":0.4}],`+
+			`"meta":{"billed_units":{"search_units":1}}}`)
+	})
+
+	resp, err := c.Rerank(context.Background(), llm.RerankRequest{
+		Query:     "q",
+		Documents: []string{"a", "b", "c"},
+		TopK:      llm.Int(2),
+	})
+	if err != nil {
+		t.Fatalf("Rerank: %v", err)
+	}
+	if len(resp.Results) != 2 || resp.Results[0].Index != 2 || resp.Results[0].Document != "c" ||
+		resp.Results[0].RelevanceScore != 0.9 || resp.Results[1].Index != 0 {
+		t.Errorf("unexpected results %+v", resp.Results)
+	}
+}
+
+func TestListModels(t *testing.T) {
+	c := newTestClient(t, "command-r", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = io.WriteString(w, `{"models":[
+			{"name":"command-r","endpoints":["chat","generate"],"context_length":128000},
+			{"name":"embed-english-v3.0","endpoints":["embed"],"context_length":512},
+			{"name":"rerank-v3.5","endpoints":["rerank"],"context_length":4096}
+		]}`)
+	})
+
+	models, err := c.ListModels(context.Background(), llm.WithCapabilities(llm.ModelCapabilityReranking))
+	if err != nil {
+		t.Fatalf("ListModels: %v", err)
+	}
+	if len(models) != 1 || models[0] != "rerank-v3.5" {
+		t.Errorf("unexpected models %v", models)
+	}
+}
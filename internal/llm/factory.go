@@ -10,12 +10,16 @@
 package llm
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
-	_ "github.com/pgEdge/pgedge-go-llm-lib/llm/all" // register all providers
+	_ "github.com/pgEdge/pgedge-go-llm-lib/llm/provider/ollama" // register the self-hostable providers
+	"github.com/pgEdge/pgedge-go-llm-lib/llm/provider/openai"
 
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
 )
@@ -28,14 +32,62 @@ const (
 	ProviderGemini    = "gemini"
 	ProviderVoyage    = "voyage"
 	ProviderOllama    = "ollama"
+	ProviderBedrock   = "bedrock"
+	ProviderCohere    = "cohere"
+
+	// ProviderOpenAICompatible is a server with an OpenAI-compatible
+	// API, such as vLLM, LiteLLM, LM Studio or llama.cpp, at a required
+	// base URL.
+	ProviderOpenAICompatible = "openai-compatible"
 )
 
+// hostedProviders are the providers only available as hosted services.
+// A binary built with the nohosted tag, for air-gapped deployments,
+// leaves their clients out; OpenAI stays, since its client also serves
+// OpenAI-compatible local servers.
+var hostedProviders = []string{ProviderAnthropic, ProviderGemini, ProviderVoyage, ProviderBedrock, ProviderCohere}
+
+// ErrProviderNotBuiltIn is returned by the factories for a hosted
+// provider in a binary built without them.
+var ErrProviderNotBuiltIn = errors.New("provider not built in")
+
+// BuiltInProviders returns the providers this binary can create
+// clients for, sorted.
+func BuiltInProviders() []string {
+	providers := []string{ProviderOllama, ProviderOpenAI, ProviderOpenAICompatible}
+	if hostedProvidersBuiltIn {
+		providers = append(providers, hostedProviders...)
+	}
+	slices.Sort(providers)
+	return providers
+}
+
+// checkBuiltIn returns ErrProviderNotBuiltIn, naming the build tag, for
+// a hosted provider in a binary built without them.
+func checkBuiltIn(provider string) error {
+	if !hostedProvidersBuiltIn && slices.Contains(hostedProviders, provider) {
+		return fmt.Errorf("%w: %s (this binary was built with the nohosted tag; available: %s)",
+			ErrProviderNotBuiltIn, provider, strings.Join(BuiltInProviders(), ", "))
+	}
+	return nil
+}
+
 // clientOptions collects the optional, provider-independent settings a
 // caller can apply to a client. It exists so the timeout knobs can be
 // threaded through the factory without expanding every call site.
 type clientOptions struct {
 	requestTimeout    time.Duration
 	perAttemptTimeout time.Duration
+	retry             *RetryPolicy
+	idempotent        bool
+	region            string
+	transport         http.RoundTripper
+	observeConn       func(reused bool)
+	observeRetry      func(llmlib.RetryEvent)
+	payloadLog        *PayloadLog
+	pipeline          string
+	limiter           *Limiter
+	observeLimit      func(reason string)
 }
 
 // ClientOption customises client construction.
@@ -52,16 +104,104 @@ func WithPerAttemptTimeout(d time.Duration) ClientOption {
 	return func(o *clientOptions) { o.perAttemptTimeout = d }
 }
 
-// withOptions stamps the resolved ClientOptions onto a base
-// llmlib.Options so every provider branch shares identical timeout
-// wiring.
-func withOptions(base llmlib.Options, opts []ClientOption) llmlib.Options {
+// WithRetry sets how transient provider failures are retried (without
+// it, DefaultRetryPolicy applies).
+func WithRetry(policy RetryPolicy) ClientOption {
+	return func(o *clientOptions) { o.retry = &policy }
+}
+
+// WithRetryObserver calls fn before every retry of a provider request.
+func WithRetryObserver(fn func(llmlib.RetryEvent)) ClientOption {
+	return func(o *clientOptions) { o.observeRetry = fn }
+}
+
+// WithRegion sets the AWS region of a bedrock client (empty falls back
+// to the AWS_REGION and AWS_DEFAULT_REGION environment variables).
+// Other providers ignore it.
+func WithRegion(region string) ClientOption {
+	return func(o *clientOptions) { o.region = region }
+}
+
+// WithTransport sends the client's requests through rt instead of
+// http.DefaultTransport, typically a pipeline's NewPooledTransport.
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(o *clientOptions) { o.transport = rt }
+}
+
+// WithConnObserver calls fn for every provider request with whether it
+// reused an idle keep-alive connection.
+func WithConnObserver(fn func(reused bool)) ClientOption {
+	return func(o *clientOptions) { o.observeConn = fn }
+}
+
+// WithPayloadLog writes every request the client sends, and its
+// response, to log, labelled with the pipeline's name.
+func WithPayloadLog(log *PayloadLog, pipeline string) ClientOption {
+	return func(o *clientOptions) { o.payloadLog, o.pipeline = log, pipeline }
+}
+
+// WithLimiter holds requests to limiter's limits, calling observe, if
+// non-nil, with the reason for every request it refuses. A nil limiter
+// leaves requests unlimited.
+func WithLimiter(limiter *Limiter, observe func(reason string)) ClientOption {
+	return func(o *clientOptions) { o.limiter, o.observeLimit = limiter, observe }
+}
+
+func resolveOptions(opts []ClientOption) clientOptions {
 	var co clientOptions
 	for _, fn := range opts {
 		fn(&co)
 	}
+	return co
+}
+
+// roundTripper returns the transport provider requests are sent
+// through, before any provider-specific wrapping. It retries transient
+// failures, enforces the per-attempt timeout and adds the request's
+// trace headers to each attempt. The payload log sits underneath, so it
+// records each attempt as sent; the limiter sits on top, so a request
+// holds its slot through its retries.
+func (co clientOptions) roundTripper() http.RoundTripper {
+	rt := co.transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	if co.observeConn != nil {
+		rt = &connTraceTransport{inner: rt, observe: co.observeConn}
+	}
+	if co.payloadLog != nil {
+		rt = &payloadLogTransport{inner: rt, log: co.payloadLog, pipeline: co.pipeline}
+	}
+
+	policy := DefaultRetryPolicy()
+	if co.retry != nil {
+		policy = *co.retry
+	}
+	rt = &retryTransport{
+		inner:             &traceHeadersTransport{inner: rt},
+		policy:            policy,
+		perAttemptTimeout: co.perAttemptTimeout,
+		idempotent:        co.idempotent,
+		observe:           co.observeRetry,
+	}
+	if co.limiter != nil {
+		rt = &limitTransport{inner: rt, limiter: co.limiter, observe: co.observeLimit}
+	}
+	return rt
+}
+
+// withOptions stamps the resolved ClientOptions onto a base
+// llmlib.Options so every provider branch shares identical timeout and
+// transport wiring. A branch that sets its own HTTPClient must build it
+// on roundTripper. Retries and per-attempt timeouts are left to
+// roundTripper, so the library's own retry layer is disabled.
+func withOptions(base llmlib.Options, opts []ClientOption) llmlib.Options {
+	co := resolveOptions(opts)
 	base.RequestTimeout = co.requestTimeout
-	base.PerAttemptTimeout = co.perAttemptTimeout
+	base.Retry = llmlib.RetryConfig{Disabled: true}
+	if base.HTTPClient == nil {
+		base.HTTPClient = &http.Client{Transport: co.roundTripper()}
+	}
 	return base
 }
 
@@ -79,6 +219,14 @@ func NewEmbeddingClient(
 		keys = &config.LoadedKeys{}
 	}
 	p := strings.ToLower(provider)
+	if err := checkBuiltIn(p); err != nil {
+		return nil, err
+	}
+	// Embedding the same text twice is harmless, so embedding requests
+	// are retried even after the provider may have received them.
+	co := resolveOptions(opts)
+	co.idempotent = true
+	rt := co.roundTripper()
 
 	switch p {
 	case ProviderAnthropic:
@@ -92,6 +240,7 @@ func NewEmbeddingClient(
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient:    embeddingHTTPClient(rt),
 		}, opts))
 	case ProviderVoyage:
 		if keys.Voyage == "" {
@@ -102,6 +251,7 @@ func NewEmbeddingClient(
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient:    embeddingHTTPClient(rt),
 		}, opts))
 	case ProviderGemini:
 		if keys.Gemini == "" {
@@ -112,13 +262,21 @@ func NewEmbeddingClient(
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient:    embeddingHTTPClient(rt),
 		}, opts))
 	case ProviderOllama:
 		return llmlib.NewClient(p, withOptions(llmlib.Options{
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient:    embeddingHTTPClient(rt),
 		}, opts))
+	case ProviderOpenAICompatible:
+		return newOpenAICompatibleClient(model, baseURL, headers, keys, embeddingHTTPClient(rt), opts)
+	case ProviderBedrock:
+		return newBedrockClient(model, baseURL, headers, keys, embeddingHTTPClient(rt), opts)
+	case ProviderCohere:
+		return newCohereClient(model, baseURL, headers, keys, embeddingHTTPClient(rt), opts)
 	default:
 		return nil, fmt.Errorf("unknown embedding provider: %s", provider)
 	}
@@ -137,6 +295,10 @@ func NewCompletionClient(
 		keys = &config.LoadedKeys{}
 	}
 	p := strings.ToLower(provider)
+	if err := checkBuiltIn(p); err != nil {
+		return nil, err
+	}
+	rt := resolveOptions(opts).roundTripper()
 
 	switch p {
 	case ProviderVoyage:
@@ -145,11 +307,17 @@ func NewCompletionClient(
 		if keys.OpenAI == "" && baseURL == "" {
 			return nil, fmt.Errorf("OpenAI API key or base URL required")
 		}
+		// The logit bias and seed transports are no-ops unless a
+		// request context carries a bias or seed (see
+		// ContextWithLogitBias and ContextWithSeed).
 		return llmlib.NewClient(p, withOptions(llmlib.Options{
 			APIKey:        keys.OpenAI,
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient: &http.Client{
+				Transport: &logitBiasTransport{inner: &seedTransport{inner: rt}},
+			},
 		}, opts))
 	case ProviderAnthropic:
 		if keys.Anthropic == "" {
@@ -176,19 +344,53 @@ func NewCompletionClient(
 			Model:         model,
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
+			HTTPClient:    &http.Client{Transport: &seedTransport{inner: rt}},
 		}, opts))
+	case ProviderOpenAICompatible:
+		return newOpenAICompatibleClient(model, baseURL, headers, keys,
+			&http.Client{Transport: &seedTransport{inner: rt}}, opts)
+	case ProviderBedrock:
+		return newBedrockClient(model, baseURL, headers, keys, &http.Client{Transport: rt}, opts)
+	case ProviderCohere:
+		return newCohereClient(model, baseURL, headers, keys, &http.Client{Transport: rt}, opts)
 	default:
 		return nil, fmt.Errorf("unknown completion provider: %s", provider)
 	}
 }
 
+// newOpenAICompatibleClient builds an OpenAI client for the
+// OpenAI-compatible server at baseURL, which is required. The API key
+// is optional, and the model name is sent as it is: requests always use
+// the Chat Completions API, which such servers implement, however the
+// model is named.
+func newOpenAICompatibleClient(
+	model, baseURL string,
+	headers map[string]string,
+	keys *config.LoadedKeys,
+	httpClient *http.Client,
+	opts []ClientOption,
+) (llmlib.Client, error) {
+	if baseURL == "" {
+		return nil, fmt.Errorf("base URL required for the %s provider", ProviderOpenAICompatible)
+	}
+	return llmlib.NewClient(ProviderOpenAI, withOptions(llmlib.Options{
+		APIKey:        keys.OpenAICompatible,
+		Model:         model,
+		BaseURL:       baseURL,
+		CustomHeaders: headers,
+		HTTPClient:    httpClient,
+		Extensions:    []llmlib.ProviderExtension{openai.Extension{ResponsesAPI: llmlib.Bool(false)}},
+	}, opts))
+}
+
 // NewRerankClient builds an llm.Client for reranking. The factory
-// rejects every provider except Voyage: it is currently the only
-// provider in pgedge-go-llm-lib whose Rerank implementation is not a
-// stub, so rejecting the others at construction time (rather than
-// deferring to their runtime ErrNotSupported) matches how
-// NewEmbeddingClient/NewCompletionClient already reject providers that
-// don't support the capability being requested.
+// rejects every provider except Voyage and Cohere: Voyage is currently
+// the only provider in pgedge-go-llm-lib whose Rerank implementation is
+// not a stub, and Cohere is implemented in-tree, so rejecting the others
+// at construction time (rather than deferring to their runtime
+// ErrNotSupported) matches how NewEmbeddingClient/NewCompletionClient
+// already reject providers that don't support the capability being
+// requested.
 func NewRerankClient(
 	provider, model, baseURL string,
 	headers map[string]string,
@@ -199,6 +401,9 @@ func NewRerankClient(
 		keys = &config.LoadedKeys{}
 	}
 	p := strings.ToLower(provider)
+	if err := checkBuiltIn(p); err != nil {
+		return nil, err
+	}
 
 	switch p {
 	case ProviderVoyage:
@@ -211,6 +416,9 @@ func NewRerankClient(
 			BaseURL:       baseURL,
 			CustomHeaders: headers,
 		}, opts))
+	case ProviderCohere:
+		return newCohereClient(model, baseURL, headers, keys,
+			&http.Client{Transport: resolveOptions(opts).roundTripper()}, opts)
 	default:
 		return nil, fmt.Errorf("provider %s does not support reranking", provider)
 	}
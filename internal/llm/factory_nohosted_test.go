@@ -0,0 +1,51 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+//go:build nohosted
+
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestNoHosted_HostedProvidersNotBuiltIn(t *testing.T) {
+	keys := &config.LoadedKeys{Anthropic: "a", Gemini: "g", Voyage: "v"}
+	for _, provider := range []string{"anthropic", "Gemini", "voyage", "bedrock", "cohere"} {
+		_, err := NewCompletionClient(provider, "model", "", nil, keys)
+		if !errors.Is(err, ErrProviderNotBuiltIn) || !strings.Contains(err.Error(), "nohosted") {
+			t.Errorf("expected %s completion to be not built in, got %v", provider, err)
+		}
+		if _, err := NewEmbeddingClient(provider, "model", "", nil, keys); !errors.Is(err, ErrProviderNotBuiltIn) {
+			t.Errorf("expected %s embeddings to be not built in, got %v", provider, err)
+		}
+	}
+	if _, err := NewRerankClient("voyage", "rerank-2", "", nil, keys); !errors.Is(err, ErrProviderNotBuiltIn) {
+		t.Errorf("expected voyage reranking to be not built in, got %v", err)
+	}
+}
+
+func TestNoHosted_SelfHostableProviders(t *testing.T) {
+	if got := strings.Join(BuiltInProviders(), ","); got != "ollama,openai,openai-compatible" {
+		t.Errorf("BuiltInProviders()=%s, want ollama,openai,openai-compatible", got)
+	}
+	if _, err := NewCompletionClient("ollama", "llama3", "http://localhost:11434", nil, nil); err != nil {
+		t.Errorf("unexpected ollama error: %v", err)
+	}
+	if _, err := NewEmbeddingClient("openai", "bge-m3", "http://localhost:8000/v1", nil, nil); err != nil {
+		t.Errorf("unexpected OpenAI-compatible error: %v", err)
+	}
+	if _, err := NewCompletionClient("openai-compatible", "qwen", "http://localhost:8000/v1", nil, nil); err != nil {
+		t.Errorf("unexpected openai-compatible error: %v", err)
+	}
+}
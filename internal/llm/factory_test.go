@@ -7,9 +7,16 @@
 //
 //-------------------------------------------------------------------------
 
+//go:build !nohosted
+
 package llm
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
@@ -215,6 +222,139 @@ func TestNewCompletionClient_UnknownProvider(t *testing.T) {
 	}
 }
 
+func TestNewCompletionClient_Bedrock(t *testing.T) {
+	keys := &config.LoadedKeys{AWS: config.AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}}
+	c, err := NewCompletionClient(
+		"bedrock", "anthropic.claude-3-haiku-20240307-v1:0", "", nil, keys,
+		WithRegion("us-east-1"),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Provider() != "bedrock" {
+		t.Errorf("expected provider bedrock, got %q", c.Provider())
+	}
+}
+
+func TestNewEmbeddingClient_BedrockRegionFromEnvironment(t *testing.T) {
+	t.Setenv(config.EnvAWSRegion, "")
+	t.Setenv(config.EnvAWSDefaultRegion, "")
+	keys := &config.LoadedKeys{AWS: config.AWSCredentials{
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	}}
+
+	_, err := NewEmbeddingClient("bedrock", "amazon.titan-embed-text-v2:0", "", nil, keys)
+	if err == nil || !strings.Contains(err.Error(), "region") {
+		t.Errorf("expected region error, got %v", err)
+	}
+
+	t.Setenv(config.EnvAWSDefaultRegion, "eu-west-1")
+	if _, err := NewEmbeddingClient("bedrock", "amazon.titan-embed-text-v2:0", "", nil, keys); err != nil {
+		t.Errorf("expected AWS_DEFAULT_REGION to be used, got %v", err)
+	}
+}
+
+func TestNewCompletionClient_BedrockMissingCredentials(t *testing.T) {
+	_, err := NewCompletionClient("bedrock", "amazon.titan-text-express-v1", "", nil, nil,
+		WithRegion("us-east-1"))
+	if err == nil || !strings.Contains(err.Error(), "AWS credentials") {
+		t.Errorf("expected AWS credentials error, got %v", err)
+	}
+}
+
+func TestNewClients_Cohere(t *testing.T) {
+	keys := &config.LoadedKeys{Cohere: "co-test"}
+	for name, newClient := range map[string]func(provider, model, baseURL string,
+		headers map[string]string, keys *config.LoadedKeys, opts ...ClientOption) (llmlib.Client, error){
+		"embedding":  NewEmbeddingClient,
+		"completion": NewCompletionClient,
+		"rerank":     NewRerankClient,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, err := newClient("Cohere", "some-model", "", nil, keys)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Provider() != "cohere" || c.Model() != "some-model" {
+				t.Errorf("unexpected client %s/%s", c.Provider(), c.Model())
+			}
+			if _, err := newClient("cohere", "some-model", "", nil, nil); err == nil ||
+				!strings.Contains(err.Error(), "Cohere API key") {
+				t.Errorf("expected Cohere key error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewClients_OpenAICompatible(t *testing.T) {
+	var paths, auths []string
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		auths = append(auths, r.Header.Get("Authorization"))
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/embeddings") {
+			_, _ = io.WriteString(w, `{"data":[{"index":0,"embedding":[0.1,0.2]}],"usage":{"prompt_tokens":1}}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	defer srv.Close()
+
+	// A model named like an OpenAI reasoning model is still sent to
+	// the Chat Completions API, under its own name, with no key.
+	c, err := NewCompletionClient("openai-compatible", "gpt-5-local", srv.URL+"/v1", nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	req := llmlib.ChatRequest{Messages: []llmlib.Message{llmlib.UserText("hi")}}
+	if _, err := c.Chat(ContextWithSeed(context.Background(), 3), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	e, err := NewEmbeddingClient("openai-compatible", "BAAI/bge-m3", srv.URL+"/v1", nil,
+		&config.LoadedKeys{OpenAI: "sk-openai", OpenAICompatible: "sk-local"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := e.Embed(context.Background(), "hi"); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	if len(paths) != 2 || paths[0] != "/v1/chat/completions" || paths[1] != "/v1/embeddings" {
+		t.Fatalf("unexpected requests %v", paths)
+	}
+	if bodies[0]["model"] != "gpt-5-local" || bodies[0]["seed"] != float64(3) {
+		t.Errorf("expected the model name verbatim and the seed, got %v", bodies[0])
+	}
+	if bodies[1]["model"] != "BAAI/bge-m3" {
+		t.Errorf("expected the model name verbatim, got %v", bodies[1]["model"])
+	}
+	if auths[0] != "" || auths[1] != "Bearer sk-local" {
+		t.Errorf("expected no key, then the openai_compatible key; got %q", auths)
+	}
+}
+
+func TestNewClients_OpenAICompatibleRequiresBaseURL(t *testing.T) {
+	keys := &config.LoadedKeys{OpenAI: "sk-test"}
+	if _, err := NewCompletionClient("openai-compatible", "llama3", "", nil, keys); err == nil ||
+		!strings.Contains(err.Error(), "base URL") {
+		t.Errorf("expected a base URL error, got %v", err)
+	}
+	if _, err := NewRerankClient("openai-compatible", "bge-reranker", "http://localhost:8000/v1", nil, keys); err == nil {
+		t.Error("expected reranking to be rejected")
+	}
+}
+
 // Nil-keys regression tests: passing a nil *config.LoadedKeys must
 // surface as a normal validation error, not a nil-pointer panic.
 func TestNewEmbeddingClient_NilKeys(t *testing.T) {
@@ -320,8 +460,14 @@ func TestWithOptions_AppliesTimeouts(t *testing.T) {
 	if got.RequestTimeout != 90*time.Second {
 		t.Errorf("RequestTimeout = %v, want 90s", got.RequestTimeout)
 	}
-	if got.PerAttemptTimeout != 30*time.Second {
-		t.Errorf("PerAttemptTimeout = %v, want 30s", got.PerAttemptTimeout)
+	if got.PerAttemptTimeout != 0 || !got.Retry.Disabled {
+		t.Errorf("expected the library's retry layer disabled, got per-attempt=%v retry=%+v",
+			got.PerAttemptTimeout, got.Retry)
+	}
+	rt, ok := got.HTTPClient.Transport.(*retryTransport)
+	if !ok || rt.perAttemptTimeout != 30*time.Second {
+		t.Errorf("expected the retry transport to enforce the 30s per-attempt timeout, got %#v",
+			got.HTTPClient.Transport)
 	}
 	if got.Model != "x" {
 		t.Errorf("base Options not preserved: Model = %q", got.Model)
@@ -335,3 +481,10 @@ func TestWithOptions_NoOptionsLeavesTimeoutsZero(t *testing.T) {
 			got.RequestTimeout, got.PerAttemptTimeout)
 	}
 }
+
+func TestBuiltInProviders(t *testing.T) {
+	want := []string{"anthropic", "bedrock", "cohere", "gemini", "ollama", "openai", "openai-compatible", "voyage"}
+	if got := BuiltInProviders(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("BuiltInProviders()=%v, want %v", got, want)
+	}
+}
@@ -0,0 +1,253 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// ErrProviderBusy is returned (wrapped) for a provider request that
+// could not be sent because the provider's max_concurrent_requests were
+// in flight and its queue was full, or the request waited in the queue
+// longer than queue_timeout.
+var ErrProviderBusy = errors.New("provider busy")
+
+// Reasons a request is refused by a Limiter, reported to the observer
+// WithLimiter is given.
+const (
+	LimitReasonQueueFull    = "queue_full"
+	LimitReasonQueueTimeout = "queue_timeout"
+)
+
+// Limiter bounds the requests in flight to a provider. Requests over
+// the limit wait their turn in order of arrival. Its limits may be
+// changed while requests are in flight; a lowered limit takes effect as
+// they finish.
+type Limiter struct {
+	mu      sync.Mutex
+	limits  config.ProviderLimitConfig
+	active  int
+	waiting []*limitWaiter
+}
+
+// limitWaiter is a request waiting in a Limiter's queue. ready is
+// closed when it is given a slot.
+type limitWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+// NewLimiter returns a limiter with the given limits.
+func NewLimiter(limits config.ProviderLimitConfig) *Limiter {
+	return &Limiter{limits: limits}
+}
+
+// setLimits changes the limiter's limits, giving waiting requests any
+// slots a raised limit frees.
+func (l *Limiter) setLimits(limits config.ProviderLimitConfig) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+	for len(l.waiting) > 0 && l.active < l.limits.MaxConcurrentRequests {
+		l.active++
+		l.grant()
+	}
+}
+
+// grant gives the slot being handed on to the longest waiting request.
+// The caller holds l.mu.
+func (l *Limiter) grant() {
+	w := l.waiting[0]
+	l.waiting = l.waiting[1:]
+	w.granted = true
+	close(w.ready)
+}
+
+// acquire takes a slot, waiting for one if they are all taken. It
+// returns the reason it failed, or the context's error, if it could
+// not; otherwise the slot must be released.
+func (l *Limiter) acquire(ctx context.Context) (reason string, err error) {
+	l.mu.Lock()
+	if l.active < l.limits.MaxConcurrentRequests && len(l.waiting) == 0 {
+		l.active++
+		l.mu.Unlock()
+		return "", nil
+	}
+	if l.limits.MaxQueue > 0 && len(l.waiting) >= l.limits.MaxQueue {
+		l.mu.Unlock()
+		return LimitReasonQueueFull, fmt.Errorf("%w: %d requests in flight and %d queued",
+			ErrProviderBusy, l.limits.MaxConcurrentRequests, l.limits.MaxQueue)
+	}
+	w := &limitWaiter{ready: make(chan struct{})}
+	l.waiting = append(l.waiting, w)
+	timeout := l.limits.QueueTimeout.Std()
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-w.ready:
+		return "", nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		reason = LimitReasonQueueTimeout
+		err = fmt.Errorf("%w: waited %s in the queue", ErrProviderBusy, timeout)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.granted {
+		// The slot came as the wait ended; hand it on.
+		l.releaseLocked()
+	} else {
+		for i, queued := range l.waiting {
+			if queued == w {
+				l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+				break
+			}
+		}
+	}
+	return reason, err
+}
+
+// release gives up a slot, handing it to the longest waiting request
+// unless a lowered limit means it should go.
+func (l *Limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.releaseLocked()
+}
+
+func (l *Limiter) releaseLocked() {
+	if len(l.waiting) > 0 && l.active <= l.limits.MaxConcurrentRequests {
+		l.grant()
+		return
+	}
+	l.active--
+}
+
+// Limiters holds the limiters of the providers, one for each API key
+// and base URL, so every pipeline using the same account shares one.
+// Like a metrics registry, pass the same Limiters across hot-reloads so
+// the queries of the old and new configurations share the limits.
+type Limiters struct {
+	mu       sync.Mutex
+	limiters map[[sha256.Size]byte]*Limiter
+}
+
+// NewLimiters returns an empty set of limiters.
+func NewLimiters() *Limiters {
+	return &Limiters{limiters: make(map[[sha256.Size]byte]*Limiter)}
+}
+
+// Limiter returns the limiter of requests to provider with the given
+// credentials and base URL, created with limits, or, if it exists, with
+// its limits changed to them. The credentials are only kept hashed.
+func (ls *Limiters) Limiter(provider, baseURL, region string, keys *config.LoadedKeys,
+	limits config.ProviderLimitConfig) *Limiter {
+	provider = strings.ToLower(provider)
+	id := sha256.Sum256([]byte(strings.Join(
+		[]string{provider, providerCredential(provider, keys), baseURL, region}, "\x00")))
+
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	l, ok := ls.limiters[id]
+	if !ok {
+		l = NewLimiter(limits)
+		ls.limiters[id] = l
+		return l
+	}
+	l.setLimits(limits)
+	return l
+}
+
+// providerCredential returns the credential a client of provider is
+// created with, which identifies the account its requests count
+// against.
+func providerCredential(provider string, keys *config.LoadedKeys) string {
+	if keys == nil {
+		return ""
+	}
+	switch provider {
+	case ProviderOpenAI:
+		return keys.OpenAI
+	case ProviderAnthropic:
+		return keys.Anthropic
+	case ProviderVoyage:
+		return keys.Voyage
+	case ProviderGemini:
+		return keys.Gemini
+	case ProviderBedrock:
+		return keys.AWS.AccessKeyID
+	case ProviderCohere:
+		return keys.Cohere
+	case ProviderOpenAICompatible:
+		return keys.OpenAICompatible
+	}
+	return ""
+}
+
+// limitTransport holds one of a limiter's slots for each request, until
+// the response body is closed, so a streamed response counts until it
+// ends.
+type limitTransport struct {
+	inner   http.RoundTripper
+	limiter *Limiter
+	observe func(reason string)
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reason, err := t.limiter.acquire(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		if reason != "" && t.observe != nil {
+			t.observe(reason)
+		}
+		return nil, err
+	}
+
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		t.limiter.release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: sync.OnceFunc(t.limiter.release)}
+	return resp, nil
+}
+
+// releaseBody releases a request's slot when its response body is
+// closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
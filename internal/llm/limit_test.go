@@ -0,0 +1,210 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestLimiter_Queue(t *testing.T) {
+	l := NewLimiter(config.ProviderLimitConfig{MaxConcurrentRequests: 1})
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Queued requests are given the slot in order of arrival.
+	order := make(chan int, 2)
+	for i := range 2 {
+		go func() {
+			if _, err := l.acquire(context.Background()); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			order <- i
+		}()
+		waitForQueue(t, l, i+1)
+	}
+	l.release()
+	if got := <-order; got != 0 {
+		t.Errorf("expected the first queued request to go first, got %d", got)
+	}
+	l.release()
+	if got := <-order; got != 1 {
+		t.Errorf("expected the second queued request next, got %d", got)
+	}
+	l.release()
+	if l.active != 0 || len(l.waiting) != 0 {
+		t.Errorf("expected the limiter to be idle, got %d active and %d waiting", l.active, len(l.waiting))
+	}
+}
+
+func TestLimiter_Refused(t *testing.T) {
+	tests := []struct {
+		name       string
+		limits     config.ProviderLimitConfig
+		queued     int
+		timeout    time.Duration
+		wantReason string
+		wantErr    error
+	}{
+		{"queue full", config.ProviderLimitConfig{MaxConcurrentRequests: 1, MaxQueue: 1}, 1, 0,
+			LimitReasonQueueFull, ErrProviderBusy},
+		{"queue timeout", config.ProviderLimitConfig{MaxConcurrentRequests: 1,
+			QueueTimeout: config.Duration(10 * time.Millisecond)}, 0, 0,
+			LimitReasonQueueTimeout, ErrProviderBusy},
+		{"request deadline", config.ProviderLimitConfig{MaxConcurrentRequests: 1}, 0, 10 * time.Millisecond,
+			"", context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(tt.limits)
+			if _, err := l.acquire(context.Background()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			for range tt.queued {
+				go func() { _, _ = l.acquire(ctx) }()
+			}
+			waitForQueue(t, l, tt.queued)
+
+			reqCtx := context.Background()
+			if tt.timeout > 0 {
+				var cancelReq context.CancelFunc
+				reqCtx, cancelReq = context.WithTimeout(reqCtx, tt.timeout)
+				defer cancelReq()
+			}
+			reason, err := l.acquire(reqCtx)
+			if reason != tt.wantReason || !errors.Is(err, tt.wantErr) {
+				t.Errorf("acquire() = %q, %v; want %q, %v", reason, err, tt.wantReason, tt.wantErr)
+			}
+			if len(l.waiting) != tt.queued {
+				t.Errorf("expected the refused request to leave the queue, got %d waiting", len(l.waiting))
+			}
+		})
+	}
+}
+
+func TestLimiter_SetLimits(t *testing.T) {
+	l := NewLimiter(config.ProviderLimitConfig{MaxConcurrentRequests: 1})
+	if _, err := l.acquire(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_, _ = l.acquire(context.Background())
+		close(done)
+	}()
+	waitForQueue(t, l, 1)
+
+	l.setLimits(config.ProviderLimitConfig{MaxConcurrentRequests: 2})
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected a raised limit to admit the queued request")
+	}
+
+	// Lowered, the limit takes effect as requests finish.
+	l.setLimits(config.ProviderLimitConfig{MaxConcurrentRequests: 1})
+	l.release()
+	if l.active != 1 {
+		t.Errorf("expected 1 active request, got %d", l.active)
+	}
+}
+
+func TestLimiters(t *testing.T) {
+	ls := NewLimiters()
+	limits := config.ProviderLimitConfig{MaxConcurrentRequests: 2}
+	keys := &config.LoadedKeys{OpenAI: "key-a"}
+
+	a := ls.Limiter("OpenAI", "", "", keys, limits)
+	if b := ls.Limiter("openai", "", "", keys, config.ProviderLimitConfig{MaxConcurrentRequests: 4}); b != a {
+		t.Error("expected the same provider and key to share a limiter")
+	}
+	if a.limits.MaxConcurrentRequests != 4 {
+		t.Errorf("expected the limits to be updated, got %+v", a.limits)
+	}
+	if b := ls.Limiter("openai", "", "", &config.LoadedKeys{OpenAI: "key-b"}, limits); b == a {
+		t.Error("expected another key to have its own limiter")
+	}
+	if b := ls.Limiter("openai", "http://gateway", "", keys, limits); b == a {
+		t.Error("expected another base URL to have its own limiter")
+	}
+}
+
+func TestLimitTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	l := NewLimiter(config.ProviderLimitConfig{MaxConcurrentRequests: 1, MaxQueue: 1,
+		QueueTimeout: config.Duration(10 * time.Millisecond)})
+	var refused []string
+	client := &http.Client{Transport: &limitTransport{
+		inner:   http.DefaultTransport,
+		limiter: l,
+		observe: func(reason string) { refused = append(refused, reason) },
+	}}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The slot is held until the body is closed.
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrProviderBusy) {
+		t.Errorf("expected the provider to be busy, got %v", err)
+	}
+	if len(refused) != 1 || refused[0] != LimitReasonQueueTimeout {
+		t.Errorf("expected a queue timeout to be observed, got %v", refused)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	_ = resp.Body.Close()
+	if !strings.Contains(string(body), "ok") {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if l.active != 0 {
+		t.Errorf("expected closing the body to release the slot once, got %d active", l.active)
+	}
+
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error after release: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+// waitForQueue waits until n requests are waiting for l.
+func waitForQueue(t *testing.T, l *Limiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		l.mu.Lock()
+		waiting := len(l.waiting)
+		l.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d waiting requests, got %d", n, waiting)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
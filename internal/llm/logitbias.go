@@ -0,0 +1,94 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// logitBiasKey is the context key under which a per-request logit bias
+// map is stored.
+type logitBiasKey struct{}
+
+// ContextWithLogitBias returns a copy of ctx carrying an OpenAI
+// logit_bias map (token ID -> bias in [-100, 100]). A client built by
+// NewCompletionClient for the openai provider adds it to the Chat
+// Completions request body. An empty map leaves ctx unchanged.
+func ContextWithLogitBias(ctx context.Context, bias map[string]int) context.Context {
+	if len(bias) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, logitBiasKey{}, bias)
+}
+
+// logitBiasFromContext returns the logit bias stored by
+// ContextWithLogitBias, if any.
+func logitBiasFromContext(ctx context.Context) map[string]int {
+	bias, _ := ctx.Value(logitBiasKey{}).(map[string]int)
+	return bias
+}
+
+// logitBiasTransport injects logit_bias into OpenAI Chat Completions
+// request bodies. pgedge-go-llm-lib has no field for it, so the value
+// travels on the request context and is spliced into the JSON body
+// here, underneath the library's retry and header middleware. Requests
+// to any other endpoint (embeddings, the Responses API) pass through
+// untouched.
+type logitBiasTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *logitBiasTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	bias := logitBiasFromContext(req.Context())
+	if len(bias) == 0 || req.Body == nil || req.Method != http.MethodPost ||
+		!strings.HasSuffix(req.URL.Path, "/chat/completions") {
+		return t.inner.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not a JSON object; send it as-is and let the provider decide.
+		return t.inner.RoundTrip(withBody(req, body))
+	}
+	encoded, err := json.Marshal(bias)
+	if err != nil {
+		return nil, err
+	}
+	payload["logit_bias"] = encoded
+	if body, err = json.Marshal(payload); err != nil {
+		return nil, err
+	}
+
+	return t.inner.RoundTrip(withBody(req, body))
+}
+
+// withBody returns a shallow copy of req whose body is replaced by b.
+// RoundTrippers must not modify the caller's request, so the original
+// is left alone.
+func withBody(req *http.Request, b []byte) *http.Request {
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(b))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	out.ContentLength = int64(len(b))
+	return out
+}
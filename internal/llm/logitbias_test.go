@@ -0,0 +1,92 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// chatCompletionsServer records each request body sent to
+// /chat/completions and replies with a minimal valid completion.
+func chatCompletionsServer(t *testing.T, bodies *[]map[string]any) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		*bodies = append(*bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNewCompletionClient_OpenAI_SendsLogitBiasFromContext(t *testing.T) {
+	var bodies []map[string]any
+	srv := chatCompletionsServer(t, &bodies)
+
+	c, err := NewCompletionClient("openai", "gpt-4o-mini", srv.URL, nil,
+		&config.LoadedKeys{OpenAI: "sk-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := ContextWithLogitBias(context.Background(), map[string]int{"50256": -100})
+	req := llmlib.ChatRequest{
+		Messages:      []llmlib.Message{llmlib.UserText("hi")},
+		StopSequences: []string{"###"},
+	}
+	if _, err := c.Chat(ctx, req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if _, err := c.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(bodies))
+	}
+
+	bias, ok := bodies[0]["logit_bias"].(map[string]any)
+	if !ok || bias["50256"] != float64(-100) {
+		t.Errorf("expected logit_bias {50256: -100}, got %v", bodies[0]["logit_bias"])
+	}
+	if stop, _ := bodies[0]["stop"].([]any); len(stop) != 1 || stop[0] != "###" {
+		t.Errorf("expected stop [###] to survive rewriting, got %v", bodies[0]["stop"])
+	}
+
+	if _, ok := bodies[1]["logit_bias"]; ok {
+		t.Errorf("expected no logit_bias without a context value, got %v", bodies[1]["logit_bias"])
+	}
+}
+
+func TestContextWithLogitBias_EmptyLeavesContextUnchanged(t *testing.T) {
+	ctx := context.Background()
+	if got := ContextWithLogitBias(ctx, nil); got != ctx {
+		t.Error("expected nil bias to return the original context")
+	}
+	if got := logitBiasFromContext(ctx); got != nil {
+		t.Errorf("expected no bias, got %v", got)
+	}
+}
@@ -0,0 +1,267 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redacted replaces the value of a header, query parameter or body
+// field that carries a credential.
+const redacted = "[REDACTED]"
+
+// maxLoggedBody is the most of a request or response body the payload
+// log keeps. Longer bodies, such as long streamed answers, are cut off.
+const maxLoggedBody = 1 << 20
+
+// maxLoggedNumbers is the most entries of an array of numbers, such as
+// an embedding, the payload log keeps.
+const maxLoggedNumbers = 8
+
+// PayloadLog writes provider requests and responses, one JSON line per
+// request attempt, for debugging. Credentials are redacted and long
+// strings truncated; see ProviderLogConfig. It is safe for concurrent
+// use by every pipeline's clients.
+type PayloadLog struct {
+	mu        sync.Mutex
+	w         io.Writer
+	maxString int
+}
+
+// NewPayloadLog returns a payload log writing to w, truncating strings
+// longer than maxString bytes.
+func NewPayloadLog(w io.Writer, maxString int) *PayloadLog {
+	return &PayloadLog{w: w, maxString: maxString}
+}
+
+// payloadEntry is one line of the payload log.
+type payloadEntry struct {
+	Time            time.Time         `json:"time"`
+	Pipeline        string            `json:"pipeline,omitempty"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers,omitempty"`
+	RequestBody     any               `json:"request_body,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    any               `json:"response_body,omitempty"`
+	DurationMS      int64             `json:"duration_ms"`
+	Error           string            `json:"error,omitempty"`
+}
+
+func (l *PayloadLog) write(e *payloadEntry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(append(line, '\n'))
+}
+
+// payloadLogTransport logs every request it sends and the response to
+// it. The response body is logged once the caller has read it, so
+// streamed answers are passed on as they arrive.
+type payloadLogTransport struct {
+	inner    http.RoundTripper
+	log      *PayloadLog
+	pipeline string
+}
+
+func (t *payloadLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := &payloadEntry{
+		Time:           time.Now(),
+		Pipeline:       t.pipeline,
+		Method:         req.Method,
+		URL:            redactURL(req.URL),
+		RequestHeaders: t.log.headers(req.Header),
+	}
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		entry.RequestBody = t.log.body(body)
+		req = withBody(req, body)
+	}
+
+	resp, err := t.inner.RoundTrip(req)
+	if err != nil {
+		entry.DurationMS = time.Since(entry.Time).Milliseconds()
+		entry.Error = err.Error()
+		t.log.write(entry)
+		return nil, err
+	}
+	entry.Status = resp.StatusCode
+	entry.ResponseHeaders = t.log.headers(resp.Header)
+	resp.Body = &loggedBody{ReadCloser: resp.Body, log: t.log, entry: entry}
+	return resp, nil
+}
+
+// loggedBody keeps what is read of a response body and logs the entry
+// when the body is closed.
+type loggedBody struct {
+	io.ReadCloser
+	log   *PayloadLog
+	entry *payloadEntry
+	buf   bytes.Buffer
+	once  sync.Once
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if room := maxLoggedBody - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(n, room)])
+	}
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.entry.DurationMS = time.Since(b.entry.Time).Milliseconds()
+		b.entry.ResponseBody = b.log.body(b.buf.Bytes())
+		b.log.write(b.entry)
+	})
+	return err
+}
+
+// headers returns the headers to log, one value each, with those that
+// carry credentials redacted.
+func (l *PayloadLog) headers(h http.Header) map[string]string {
+	if len(h) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if isSecretName(name) {
+			value = redacted
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// body returns a body to log: JSON with credentials redacted, long
+// strings truncated and long arrays of numbers shortened, or, when it
+// is not JSON, such as a stream of server-sent events, the text itself,
+// truncated.
+func (l *PayloadLog) body(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return l.truncate(string(b))
+	}
+	return l.scrub(v)
+}
+
+// scrub redacts and truncates a decoded JSON value.
+func (l *PayloadLog) scrub(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if _, isString := value.(string); isString && isSecretName(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = l.scrub(value)
+		}
+		return v
+	case []any:
+		if len(v) > maxLoggedNumbers && allNumbers(v) {
+			return append(v[:maxLoggedNumbers:maxLoggedNumbers],
+				fmt.Sprintf("... %d more", len(v)-maxLoggedNumbers))
+		}
+		for i, item := range v {
+			v[i] = l.scrub(item)
+		}
+		return v
+	case string:
+		return l.truncate(v)
+	}
+	return v
+}
+
+// truncate cuts s to the configured length, noting how much was cut.
+func (l *PayloadLog) truncate(s string) string {
+	if l.maxString <= 0 || len(s) <= l.maxString {
+		return s
+	}
+	cut := l.maxString
+	for cut > 0 && !isRuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s... [%d bytes truncated]", s[:cut], len(s)-cut)
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+func allNumbers(v []any) bool {
+	for _, item := range v {
+		if _, ok := item.(json.Number); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// secretNameParts mark a header, query parameter or body field as
+// carrying a credential, such as Authorization or x-api-key. Names
+// ending in "token", such as x-amz-security-token, do too, unlike
+// counts such as max_tokens.
+var secretNameParts = []string{"authorization", "api-key", "api_key", "apikey", "secret", "password", "credential", "cookie"}
+
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	if name == "key" || strings.HasSuffix(name, "token") {
+		return true // key is Gemini's ?key= query parameter
+	}
+	for _, part := range secretNameParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns u as a string with the values of query parameters
+// that carry credentials redacted.
+func redactURL(u *url.URL) string {
+	query := u.Query()
+	redact := false
+	for name := range query {
+		if isSecretName(name) {
+			query.Set(name, redacted)
+			redact = true
+		}
+	}
+	if !redact {
+		return u.String()
+	}
+	out := *u
+	out.RawQuery = query.Encode()
+	return out.String()
+}
@@ -0,0 +1,99 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPayloadLogTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "sk-secret") {
+			t.Errorf("expected the provider to receive the request unchanged, got %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"embedding": [0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9, 1.0], "usage": {"input_tokens": 12}}`))
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	log := NewPayloadLog(&out, 16)
+	client := &http.Client{Transport: &payloadLogTransport{inner: http.DefaultTransport, log: log, pipeline: "docs"}}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/embed?key=AIza-secret&alt=json",
+		strings.NewReader(`{"input": "`+strings.Repeat("context ", 10)+`", "api_key": "sk-secret", "max_tokens": 50}`))
+	req.Header.Set("Authorization", "Bearer sk-secret")
+	req.Header.Set("X-Amz-Security-Token", "session-secret")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "0.9") {
+		t.Errorf("expected the caller to receive the full response, got %s", body)
+	}
+
+	line := out.String()
+	if strings.Contains(line, "secret") {
+		t.Errorf("expected every credential to be redacted, got %s", line)
+	}
+	var entry struct {
+		Pipeline       string            `json:"pipeline"`
+		URL            string            `json:"url"`
+		RequestHeaders map[string]string `json:"request_headers"`
+		RequestBody    map[string]any    `json:"request_body"`
+		Status         int               `json:"status"`
+		ResponseBody   map[string]any    `json:"response_body"`
+	}
+	if err := json.Unmarshal([]byte(line), &entry); err != nil {
+		t.Fatalf("expected one JSON line, got %q: %v", line, err)
+	}
+	if entry.Pipeline != "docs" || entry.Status != http.StatusOK {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if !strings.Contains(entry.URL, "key=%5BREDACTED%5D") || !strings.Contains(entry.URL, "alt=json") {
+		t.Errorf("expected only the key parameter to be redacted, got %s", entry.URL)
+	}
+	if entry.RequestHeaders["Authorization"] != redacted || entry.RequestHeaders["Content-Type"] != "application/json" {
+		t.Errorf("unexpected request headers: %v", entry.RequestHeaders)
+	}
+	if got := entry.RequestBody["input"]; got != "context context ... [64 bytes truncated]" {
+		t.Errorf("expected the input to be truncated, got %q", got)
+	}
+	if entry.RequestBody["max_tokens"] != float64(50) {
+		t.Errorf("expected max_tokens to be logged, got %v", entry.RequestBody["max_tokens"])
+	}
+	embedding, _ := entry.ResponseBody["embedding"].([]any)
+	if len(embedding) != maxLoggedNumbers+1 || embedding[maxLoggedNumbers] != "... 2 more" {
+		t.Errorf("expected the embedding to be shortened, got %v", embedding)
+	}
+}
+
+func TestPayloadLog_Body(t *testing.T) {
+	log := NewPayloadLog(nil, 10)
+	if got := log.body([]byte("data: {\"a\":1}\n\ndata: {\"a\":2}\n\n")); got != "data: {\"a\"... [20 bytes truncated]" {
+		t.Errorf("expected a server-sent event stream to be logged as text, got %q", got)
+	}
+	if got := log.body(nil); got != nil {
+		t.Errorf("expected no body, got %v", got)
+	}
+	if got := log.truncate("ééééééé"); got != "ééééé... [4 bytes truncated]" {
+		t.Errorf("expected truncation at a rune boundary, got %q", got)
+	}
+}
@@ -0,0 +1,95 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+//go:build !nohosted
+
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+	_ "github.com/pgEdge/pgedge-go-llm-lib/llm/provider/anthropic" // register the hosted providers
+	_ "github.com/pgEdge/pgedge-go-llm-lib/llm/provider/gemini"
+	_ "github.com/pgEdge/pgedge-go-llm-lib/llm/provider/voyage"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/llm/bedrock"
+	"github.com/pgEdge/pgedge-rag-server/internal/llm/cohere"
+)
+
+// hostedProvidersBuiltIn reports whether the hosted-only providers are
+// compiled in; the nohosted build tag leaves them out.
+const hostedProvidersBuiltIn = true
+
+// newBedrockClient builds a client for the in-tree bedrock provider,
+// which pgedge-go-llm-lib does not implement. Retries and per-attempt
+// timeouts come from the transport httpClient is built on.
+func newBedrockClient(
+	model, baseURL string,
+	headers map[string]string,
+	keys *config.LoadedKeys,
+	httpClient *http.Client,
+	opts []ClientOption,
+) (llmlib.Client, error) {
+	co := resolveOptions(opts)
+	region := config.AWSRegion(co.region)
+	if region == "" {
+		return nil, fmt.Errorf("AWS region not configured: set region or the %s environment variable",
+			config.EnvAWSRegion)
+	}
+	if keys.AWS.AccessKeyID == "" {
+		return nil, fmt.Errorf("AWS credentials not configured")
+	}
+	return bedrock.New(bedrock.Options{
+		Model:  model,
+		Region: region,
+		Credentials: bedrock.Credentials{
+			AccessKeyID:     keys.AWS.AccessKeyID,
+			SecretAccessKey: keys.AWS.SecretAccessKey,
+			SessionToken:    keys.AWS.SessionToken,
+		},
+		BaseURL:        baseURL,
+		CustomHeaders:  headers,
+		HTTPClient:     httpClient,
+		RequestTimeout: co.requestTimeout,
+	})
+}
+
+// newCohereClient builds a client for the in-tree cohere provider,
+// which pgedge-go-llm-lib does not implement. Retries and per-attempt
+// timeouts come from the transport httpClient is built on.
+func newCohereClient(
+	model, baseURL string,
+	headers map[string]string,
+	keys *config.LoadedKeys,
+	httpClient *http.Client,
+	opts []ClientOption,
+) (llmlib.Client, error) {
+	if keys.Cohere == "" {
+		return nil, fmt.Errorf("Cohere API key not configured")
+	}
+	return cohere.New(cohere.Options{
+		APIKey:         keys.Cohere,
+		Model:          model,
+		BaseURL:        baseURL,
+		CustomHeaders:  headers,
+		HTTPClient:     httpClient,
+		RequestTimeout: resolveOptions(opts).requestTimeout,
+	})
+}
+
+// ContextWithDocumentEmbedding returns a context whose embedding
+// requests embed documents to be searched, rather than queries, for the
+// providers whose models embed the two differently (Cohere).
+func ContextWithDocumentEmbedding(ctx context.Context) context.Context {
+	return cohere.ContextWithInputType(ctx, cohere.InputTypeSearchDocument)
+}
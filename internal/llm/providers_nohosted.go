@@ -0,0 +1,55 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+//go:build nohosted
+
+package llm
+
+import (
+	"context"
+	"net/http"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// hostedProvidersBuiltIn reports whether the hosted-only providers are
+// compiled in; the nohosted build tag leaves them out.
+const hostedProvidersBuiltIn = false
+
+// newBedrockClient is never reached in a nohosted build, since
+// checkBuiltIn rejects the bedrock provider first.
+func newBedrockClient(
+	model, baseURL string,
+	headers map[string]string,
+	keys *config.LoadedKeys,
+	httpClient *http.Client,
+	opts []ClientOption,
+) (llmlib.Client, error) {
+	return nil, checkBuiltIn(ProviderBedrock)
+}
+
+// newCohereClient is never reached in a nohosted build, since
+// checkBuiltIn rejects the cohere provider first.
+func newCohereClient(
+	model, baseURL string,
+	headers map[string]string,
+	keys *config.LoadedKeys,
+	httpClient *http.Client,
+	opts []ClientOption,
+) (llmlib.Client, error) {
+	return nil, checkBuiltIn(ProviderCohere)
+}
+
+// ContextWithDocumentEmbedding returns ctx unchanged: none of the
+// providers in a nohosted build embed documents and queries differently.
+func ContextWithDocumentEmbedding(ctx context.Context) context.Context {
+	return ctx
+}
@@ -14,11 +14,17 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
 	"github.com/pgEdge/pgedge-go-llm-lib/llm/vec"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
 )
 
 // ContextDoc is a single retrieved document passed to an LLM as
@@ -28,6 +34,11 @@ type ContextDoc struct {
 	Content string
 	Source  string
 	Score   float64
+
+	// ID and ContentHash identify the stored document the content was
+	// taken from, for provenance. Neither is shown to the model.
+	ID          string
+	ContentHash string
 }
 
 // FormatContext renders retrieved documents as a block of text to
@@ -35,20 +46,92 @@ type ContextDoc struct {
 // is stable across releases — pipeline tests rely on the header
 // strings.
 func FormatContext(docs []ContextDoc) string {
+	return FormatContextWith(docs, config.ContextFormatConfig{})
+}
+
+// FormatContextWith renders retrieved documents in a pipeline's
+// context format. Scores are included when the format asks for them;
+// sources whenever a document has one.
+func FormatContextWith(docs []ContextDoc, format config.ContextFormatConfig) string {
 	var sb strings.Builder
 	sb.WriteString("Use the following context to answer the question:\n\n")
+	writeDocs(&sb, docs, format, 1)
+	return sb.String()
+}
+
+// FormatMoreContext renders documents found after the context was
+// first formatted, such as by a tool call, numbered on from first so
+// that they can be cited alongside the documents before them.
+func FormatMoreContext(docs []ContextDoc, format config.ContextFormatConfig, first int) string {
+	var sb strings.Builder
+	writeDocs(&sb, docs, format, first)
+	return sb.String()
+}
 
-	for i, doc := range docs {
-		fmt.Fprintf(&sb, "--- Document %d", i+1)
-		if doc.Source != "" {
-			fmt.Fprintf(&sb, " (Source: %s)", doc.Source)
+// writeDocs writes docs to sb in format, numbering them from first.
+func writeDocs(sb *strings.Builder, docs []ContextDoc, format config.ContextFormatConfig, first int) {
+	switch format.Style {
+	case config.ContextStyleXML:
+		sb.WriteString("<documents>\n")
+		for i, doc := range docs {
+			fmt.Fprintf(sb, `<document index="%d"`, first+i)
+			if doc.Source != "" {
+				sb.WriteString(` source="`)
+				_ = xml.EscapeText(sb, []byte(doc.Source))
+				sb.WriteString(`"`)
+			}
+			if format.IncludeScores {
+				fmt.Fprintf(sb, ` score="%.3f"`, doc.Score)
+			}
+			sb.WriteString(">\n")
+			sb.WriteString(doc.Content)
+			sb.WriteString("\n</document>\n")
 		}
-		sb.WriteString(" ---\n")
-		sb.WriteString(doc.Content)
-		sb.WriteString("\n\n")
-	}
+		sb.WriteString("</documents>\n")
 
-	return sb.String()
+	case config.ContextStyleJSON:
+		type jsonDoc struct {
+			Index   int      `json:"index"`
+			Source  string   `json:"source,omitempty"`
+			Score   *float64 `json:"score,omitempty"`
+			Content string   `json:"content"`
+		}
+		out := make([]jsonDoc, len(docs))
+		for i, doc := range docs {
+			out[i] = jsonDoc{Index: first + i, Source: doc.Source, Content: doc.Content}
+			if format.IncludeScores {
+				score := math.Round(doc.Score*1000) / 1000
+				out[i].Score = &score
+			}
+		}
+		// Marshalling strings and numbers cannot fail.
+		data, _ := json.MarshalIndent(out, "", "  ")
+		sb.Write(data)
+		sb.WriteString("\n")
+
+	default:
+		heading := format.Heading
+		if heading == "" {
+			heading = config.DefaultContextHeading
+		}
+		for i, doc := range docs {
+			n := strconv.Itoa(first + i)
+			var details []string
+			if doc.Source != "" {
+				details = append(details, "Source: "+doc.Source)
+			}
+			if format.IncludeScores {
+				details = append(details, fmt.Sprintf("Score: %.3f", doc.Score))
+			}
+			if len(details) > 0 {
+				n += " (" + strings.Join(details, ", ") + ")"
+			}
+			sb.WriteString(strings.ReplaceAll(heading, "{n}", n))
+			sb.WriteString("\n")
+			sb.WriteString(doc.Content)
+			sb.WriteString("\n\n")
+		}
+	}
 }
 
 // embedder is the minimal interface Embed32 needs from a client.
@@ -70,6 +153,30 @@ func Embed32(ctx context.Context, c embedder, text string) ([]float32, error) {
 	return vec.Float64ToFloat32(raw), nil
 }
 
+// modelLister is the minimal interface EmbeddingDimensions needs from
+// a client. The lib's llm.Client satisfies it structurally.
+type modelLister interface {
+	Model() string
+	ListModelsWithMetadata(ctx context.Context, opts ...llmlib.ListModelsOption) ([]llmlib.ModelInfo, error)
+}
+
+// EmbeddingDimensions returns the number of dimensions of the vectors
+// a client's embedding model produces, from the provider's model
+// metadata. It returns 0 when the provider does not report them, as
+// Ollama and Gemini do not, or does not list the model.
+func EmbeddingDimensions(ctx context.Context, c modelLister) (int, error) {
+	infos, err := c.ListModelsWithMetadata(ctx, llmlib.WithCapabilities(llmlib.ModelCapabilityEmbeddings))
+	if err != nil {
+		return 0, err
+	}
+	for _, info := range infos {
+		if info.ID == c.Model() {
+			return info.Dimensions, nil
+		}
+	}
+	return 0, nil
+}
+
 // StopReasonString maps the lib's normalised stop reason to the
 // finish_reason string the RAG server emits in streaming and
 // non-streaming responses. Preserved verbatim from the pre-migration
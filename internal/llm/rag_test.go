@@ -16,6 +16,8 @@ import (
 	"testing"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
 )
 
 func TestFormatContext_EmptyDocs(t *testing.T) {
@@ -74,6 +76,68 @@ func TestFormatContext_OrderingAndNumbering(t *testing.T) {
 	}
 }
 
+func TestFormatContextWith(t *testing.T) {
+	docs := []ContextDoc{
+		{Content: "WAL is streamed.", Source: `Replication & "HA"`, Score: 0.91234},
+		{Content: "Standbys replay WAL."},
+	}
+	tests := []struct {
+		name   string
+		format config.ContextFormatConfig
+		want   string
+	}{
+		{
+			name:   "default text",
+			format: config.ContextFormatConfig{},
+			want: "--- Document 1 (Source: Replication & \"HA\") ---\nWAL is streamed.\n\n" +
+				"--- Document 2 ---\nStandbys replay WAL.\n\n",
+		},
+		{
+			name:   "text with a heading and scores",
+			format: config.ContextFormatConfig{Heading: "## [{n}]", IncludeScores: true},
+			want: "## [1 (Source: Replication & \"HA\", Score: 0.912)]\nWAL is streamed.\n\n" +
+				"## [2 (Score: 0.000)]\nStandbys replay WAL.\n\n",
+		},
+		{
+			name:   "xml",
+			format: config.ContextFormatConfig{Style: config.ContextStyleXML, IncludeScores: true},
+			want: "<documents>\n" +
+				"<document index=\"1\" source=\"Replication &amp; &#34;HA&#34;\" score=\"0.912\">\nWAL is streamed.\n</document>\n" +
+				"<document index=\"2\" score=\"0.000\">\nStandbys replay WAL.\n</document>\n" +
+				"</documents>\n",
+		},
+		{
+			name:   "json",
+			format: config.ContextFormatConfig{Style: config.ContextStyleJSON},
+			want: "[\n  {\n    \"index\": 1,\n    \"source\": \"Replication \\u0026 \\\"HA\\\"\",\n    \"content\": \"WAL is streamed.\"\n  },\n" +
+				"  {\n    \"index\": 2,\n    \"content\": \"Standbys replay WAL.\"\n  }\n]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FormatContextWith(docs, tt.format)
+			want := "Use the following context to answer the question:\n\n" + tt.want
+			if got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestFormatMoreContext(t *testing.T) {
+	docs := []ContextDoc{{Content: "Slots retain WAL.", Source: "slots"}}
+
+	got := FormatMoreContext(docs, config.ContextFormatConfig{}, 4)
+	if want := "--- Document 4 (Source: slots) ---\nSlots retain WAL.\n\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	got = FormatMoreContext(docs, config.ContextFormatConfig{Style: config.ContextStyleXML}, 4)
+	if want := "<documents>\n<document index=\"4\" source=\"slots\">\nSlots retain WAL.\n</document>\n</documents>\n"; got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
 // stubEmbedClient implements just the Embed method of llm.Client for
 // testing Embed32. All other methods are unused; we don't need a full
 // llm.Client because Embed32 doesn't take one — see note in body.
@@ -160,3 +224,34 @@ func TestStopReasonString_EmptyFallsBackToStop(t *testing.T) {
 		t.Errorf("empty stop reason should fall back to 'stop', got %q", got)
 	}
 }
+
+type stubModelLister struct {
+	model string
+	infos []llmlib.ModelInfo
+	err   error
+}
+
+func (s *stubModelLister) Model() string { return s.model }
+
+func (s *stubModelLister) ListModelsWithMetadata(ctx context.Context, opts ...llmlib.ListModelsOption) ([]llmlib.ModelInfo, error) {
+	return s.infos, s.err
+}
+
+func TestEmbeddingDimensions(t *testing.T) {
+	infos := []llmlib.ModelInfo{
+		{ID: "text-embedding-3-small", Dimensions: 1536},
+		{ID: "text-embedding-3-large", Dimensions: 3072},
+	}
+
+	got, err := EmbeddingDimensions(context.Background(), &stubModelLister{model: "text-embedding-3-large", infos: infos})
+	if err != nil || got != 3072 {
+		t.Errorf("expected 3072 dimensions, got %d, %v", got, err)
+	}
+	got, err = EmbeddingDimensions(context.Background(), &stubModelLister{model: "nomic-embed-text", infos: infos})
+	if err != nil || got != 0 {
+		t.Errorf("expected 0 for an unlisted model, got %d, %v", got, err)
+	}
+	if _, err := EmbeddingDimensions(context.Background(), &stubModelLister{err: errors.New("unauthorized")}); err == nil {
+		t.Error("expected the listing error to propagate")
+	}
+}
@@ -0,0 +1,289 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+// Retry defaults, used for RetryPolicy fields left at zero (MaxRetries
+// and Jitter excepted, where zero is meaningful). Only requests the
+// provider did not process are retried by default, so a completion is
+// never billed twice.
+const (
+	DefaultMaxRetries     = 5
+	DefaultInitialBackoff = 2 * time.Second
+	DefaultMaxBackoff     = 60 * time.Second
+	DefaultRetryJitter    = 0.2
+)
+
+// Retry reasons reported by RetryReason.
+const (
+	RetryReasonRateLimited = "rate_limited"
+	RetryReasonServerError = "server_error"
+	RetryReasonNetwork     = "network"
+)
+
+// statusOverloaded is Anthropic's "overloaded" status.
+const statusOverloaded = 529
+
+// RetryPolicy controls how provider requests that fail transiently are
+// retried: those the provider refused unprocessed (429, 503 and 529)
+// and those that failed before the request was sent, such as a refused
+// connection. A request that may have reached the provider is never
+// sent again, since it may already have been processed and billed.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt;
+	// zero disables retries.
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry; it doubles
+	// after each retry, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Jitter randomizes each backoff by up to this fraction (0 to 1)
+	// in either direction, so clients that failed together do not
+	// retry together. A Retry-After wait is only ever lengthened.
+	Jitter float64
+}
+
+// DefaultRetryPolicy returns the policy clients use when none is given.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     DefaultMaxRetries,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+		Jitter:         DefaultRetryJitter,
+	}
+}
+
+// RetryReason classifies a retry for metrics: the provider rate
+// limited the request, was overloaded, or could not be reached.
+func RetryReason(e llmlib.RetryEvent) string {
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		return RetryReasonRateLimited
+	case e.StatusCode != 0:
+		return RetryReasonServerError
+	default:
+		return RetryReasonNetwork
+	}
+}
+
+// retryTransport retries requests that fail transiently, honoring the
+// Retry-After header of 429 and 503 responses, and bounds each attempt
+// by perAttemptTimeout when it is positive. An attempt that failed
+// after its request was written is not retried unless the request is
+// idempotent, as embedding requests are; an idempotent request is also
+// retried after any server error. The request body is
+// buffered so it can be replayed. A retry is not attempted when its
+// wait would outlast the request's deadline; the failed response is
+// returned instead, body intact, so the provider's error is reported.
+type retryTransport struct {
+	inner             http.RoundTripper
+	policy            RetryPolicy
+	perAttemptTimeout time.Duration
+	idempotent        bool
+	observe           func(llmlib.RetryEvent)
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := t.policy.InitialBackoff
+	if backoff <= 0 {
+		backoff = DefaultInitialBackoff
+	}
+	maxBackoff := t.policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	backoff = min(backoff, maxBackoff)
+
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		if body != nil {
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		var sent atomic.Bool
+		trace := &httptrace.ClientTrace{
+			WroteRequest: func(httptrace.WroteRequestInfo) { sent.Store(true) },
+		}
+		attemptReq := req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+		resp, err := roundTripWithTimeout(t.inner, attemptReq, t.perAttemptTimeout)
+		if ctx.Err() != nil {
+			// The caller gave up; don't report its cancellation as a
+			// provider failure worth retrying.
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if err == nil && !t.retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if err != nil && sent.Load() && !t.idempotent {
+			// The provider may have received, and be processing, the
+			// request.
+			return nil, err
+		}
+		if attempt > t.policy.MaxRetries {
+			return resp, err
+		}
+
+		wait := t.jitter(backoff)
+		event := llmlib.RetryEvent{Attempt: attempt, Err: err}
+		if err == nil {
+			event.StatusCode = resp.StatusCode
+			if after := parseRetryAfter(resp.Header.Get("Retry-After")); after > 0 {
+				wait = after + time.Duration(rand.Float64()*t.policy.Jitter*float64(after))
+			}
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return resp, err
+		}
+		event.Wait = wait
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		if t.observe != nil {
+			t.observe(event)
+		}
+		if !sleep(ctx, wait) {
+			return nil, ctx.Err()
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// jitter randomizes d by up to the policy's jitter fraction.
+func (t *retryTransport) jitter(d time.Duration) time.Duration {
+	if t.policy.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + t.policy.Jitter*(2*rand.Float64()-1)))
+}
+
+// retryableStatus reports whether a response says the provider turned
+// the request away without processing it: rate limited or overloaded.
+// Other server errors may follow a request the provider acted on, so
+// they are retried only for an idempotent request.
+func (t *retryTransport) retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusServiceUnavailable,
+		statusOverloaded:
+		return true
+	case http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusGatewayTimeout:
+		return t.idempotent
+	}
+	return false
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an
+// HTTP date, returning zero if it is absent or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// roundTripWithTimeout sends one attempt through inner, bounded by
+// timeout when it is positive. The attempt's context derives from the
+// request's, so a timeout never cancels the caller; on success the
+// timer is stopped and only released when the body is closed, so a
+// streaming body is not cut off.
+func roundTripWithTimeout(
+	inner http.RoundTripper,
+	req *http.Request,
+	timeout time.Duration,
+) (*http.Response, error) {
+	if timeout <= 0 {
+		return inner.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(timeout, cancel)
+	resp, err := inner.RoundTrip(req.WithContext(ctx))
+	timedOut := !timer.Stop()
+
+	if err == nil && !timedOut {
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		return resp, nil
+	}
+	if err == nil {
+		_ = resp.Body.Close()
+	}
+	cancel()
+	if timedOut && req.Context().Err() == nil {
+		return nil, fmt.Errorf("attempt timed out after %s: %w", timeout, context.DeadlineExceeded)
+	}
+	if err == nil {
+		err = req.Context().Err()
+	}
+	return nil, err
+}
+
+// cancelBody releases an attempt's context when its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// sleep waits for d, returning false if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,287 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+// fastPolicy retries quickly so tests don't sleep for real backoffs.
+func fastPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{MaxRetries: maxRetries, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+}
+
+func post(t *testing.T, ctx context.Context, rt http.RoundTripper, url string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip failed: %v", err)
+	}
+	return resp
+}
+
+func TestRetryTransport_RetriesTransientFailures(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if body, _ := io.ReadAll(r.Body); string(body) != "payload" {
+			t.Errorf("attempt %d: body not replayed, got %q", calls.Load()+1, body)
+		}
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	var events []llmlib.RetryEvent
+	rt := &retryTransport{
+		inner:   http.DefaultTransport,
+		policy:  fastPolicy(3),
+		observe: func(e llmlib.RetryEvent) { events = append(events, e) },
+	}
+	resp := post(t, context.Background(), rt, srv.URL)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("expected success on the third attempt, got %d after %d", resp.StatusCode, calls.Load())
+	}
+	if len(events) != 2 ||
+		RetryReason(events[0]) != RetryReasonRateLimited ||
+		RetryReason(events[1]) != RetryReasonServerError ||
+		events[1].Attempt != 2 {
+		t.Errorf("unexpected retry events: %+v", events)
+	}
+}
+
+func TestRetryTransport_GivesUp(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		status     int
+		maxRetries int
+		wantCalls  int32
+	}{
+		{name: "budget exhausted", status: http.StatusServiceUnavailable, maxRetries: 2, wantCalls: 3},
+		{name: "retries disabled", status: http.StatusServiceUnavailable, maxRetries: 0, wantCalls: 1},
+		{name: "not retryable", status: http.StatusBadRequest, maxRetries: 2, wantCalls: 1},
+		{name: "possibly processed", status: http.StatusBadGateway, maxRetries: 2, wantCalls: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, "upstream error")
+			}))
+			defer srv.Close()
+
+			rt := &retryTransport{inner: http.DefaultTransport, policy: fastPolicy(tt.maxRetries)}
+			resp := post(t, context.Background(), rt, srv.URL)
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.status || string(body) != "upstream error" {
+				t.Errorf("expected the final response intact, got %d %q", resp.StatusCode, body)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("expected %d attempts, got %d", tt.wantCalls, calls.Load())
+			}
+		})
+	}
+}
+
+// TestRetryTransport_RetryAfterBeyondDeadline verifies a rate limit
+// whose Retry-After outlasts the request's deadline is returned at once
+// rather than slept on.
+func TestRetryTransport_RetryAfterBeyondDeadline(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rt := &retryTransport{inner: http.DefaultTransport, policy: fastPolicy(3)}
+	start := time.Now()
+	resp := post(t, ctx, rt, srv.URL)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusTooManyRequests || calls.Load() != 1 {
+		t.Errorf("expected the 429 after one attempt, got %d after %d", resp.StatusCode, calls.Load())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected no wait, took %v", elapsed)
+	}
+}
+
+// TestRetryTransport_NotRetriedOnceSent verifies an attempt that times
+// out after its request was written is not sent again, since the
+// provider may already be processing it.
+func TestRetryTransport_NotRetriedOnceSent(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(500 * time.Millisecond):
+		}
+	}))
+	defer srv.Close()
+
+	var events []llmlib.RetryEvent
+	rt := &retryTransport{
+		inner:             http.DefaultTransport,
+		policy:            fastPolicy(3),
+		perAttemptTimeout: 50 * time.Millisecond,
+		observe:           func(e llmlib.RetryEvent) { events = append(events, e) },
+	}
+	req, err := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the attempt timeout, got %v", err)
+	}
+	if calls.Load() != 1 || len(events) != 0 {
+		t.Errorf("expected a single attempt, got %d with retries %+v", calls.Load(), events)
+	}
+}
+
+// TestRetryTransport_RetriesIdempotentRequests verifies an idempotent
+// request, such as an embedding, is retried after a server error and
+// after an attempt that timed out once its request was written.
+func TestRetryTransport_RetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			select {
+			case <-r.Context().Done():
+			case <-time.After(500 * time.Millisecond):
+			}
+		default:
+			_, _ = io.WriteString(w, "ok")
+		}
+	}))
+	defer srv.Close()
+
+	var events []llmlib.RetryEvent
+	rt := &retryTransport{
+		inner:             http.DefaultTransport,
+		policy:            fastPolicy(3),
+		perAttemptTimeout: 50 * time.Millisecond,
+		idempotent:        true,
+		observe:           func(e llmlib.RetryEvent) { events = append(events, e) },
+	}
+	resp := post(t, context.Background(), rt, srv.URL)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || calls.Load() != 3 {
+		t.Errorf("expected success on the third attempt, got %d after %d", resp.StatusCode, calls.Load())
+	}
+	if len(events) != 2 || events[0].StatusCode != http.StatusBadGateway ||
+		!errors.Is(events[1].Err, context.DeadlineExceeded) {
+		t.Errorf("unexpected retry events: %+v", events)
+	}
+}
+
+// unsentTransport fails its first failures attempts as a refused
+// connection would, before writing the request.
+type unsentTransport struct {
+	failures int
+	calls    int
+}
+
+func (t *unsentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	if t.calls <= t.failures {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestRetryTransport_RetriesUnsentRequests(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	var events []llmlib.RetryEvent
+	inner := &unsentTransport{failures: 2}
+	rt := &retryTransport{
+		inner:   inner,
+		policy:  fastPolicy(3),
+		observe: func(e llmlib.RetryEvent) { events = append(events, e) },
+	}
+	resp := post(t, context.Background(), rt, srv.URL)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || inner.calls != 3 {
+		t.Errorf("expected success on the third attempt, got %d after %d", resp.StatusCode, inner.calls)
+	}
+	if len(events) != 2 || !errors.Is(events[0].Err, syscall.ECONNREFUSED) ||
+		RetryReason(events[0]) != RetryReasonNetwork {
+		t.Errorf("expected two connection retries, got %+v", events)
+	}
+}
+
+func TestRetryTransport_Jitter(t *testing.T) {
+	rt := &retryTransport{policy: RetryPolicy{Jitter: 0.5}}
+	for i := 0; i < 100; i++ {
+		if d := rt.jitter(time.Second); d < 500*time.Millisecond || d > 1500*time.Millisecond {
+			t.Fatalf("jittered backoff %v outside [0.5s, 1.5s]", d)
+		}
+	}
+
+	rt.policy.Jitter = 0
+	if d := rt.jitter(time.Second); d != time.Second {
+		t.Errorf("expected no jitter, got %v", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d := parseRetryAfter("7"); d != 7*time.Second {
+		t.Errorf("seconds: got %v", d)
+	}
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if d := parseRetryAfter(date); d < 58*time.Second || d > time.Minute {
+		t.Errorf("HTTP date: got %v", d)
+	}
+	for _, v := range []string{"", "soon"} {
+		if d := parseRetryAfter(v); d != 0 {
+			t.Errorf("%q: expected 0, got %v", v, d)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultTemperature is the sampling temperature pgedge-go-llm-lib
+// sends when a request sets none. The ollama client sends no
+// temperature, leaving the model's own default in place.
+const DefaultTemperature = 0.7
+
+// seedKey is the context key under which a per-request sampling seed is
+// stored.
+type seedKey struct{}
+
+// SupportsSeed reports whether a provider's completion client sends the
+// seed set by ContextWithSeed. The other providers' APIs have no seed.
+func SupportsSeed(provider string) bool {
+	p := strings.ToLower(provider)
+	return p == ProviderOpenAI || p == ProviderOpenAICompatible || p == ProviderOllama
+}
+
+// Temperature returns the sampling temperature a provider's completion
+// client sends for a request that sets none, or nil when it sends none.
+func Temperature(provider string) *float64 {
+	if strings.ToLower(provider) == ProviderOllama {
+		return nil
+	}
+	t := DefaultTemperature
+	return &t
+}
+
+// ContextWithSeed returns a copy of ctx carrying a sampling seed. A
+// client built by NewCompletionClient for the openai,
+// openai-compatible or ollama provider adds it to the chat request
+// body, so repeating the request with the same seed and inputs gives
+// the same answer as far as the provider can.
+func ContextWithSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, seedKey{}, seed)
+}
+
+// seedFromContext returns the seed stored by ContextWithSeed, if any.
+func seedFromContext(ctx context.Context) (int64, bool) {
+	seed, ok := ctx.Value(seedKey{}).(int64)
+	return seed, ok
+}
+
+// seedTransport injects the request's seed into OpenAI Chat Completions
+// and Ollama chat request bodies: as the top-level seed for the former
+// and in the options object for the latter. Like logitBiasTransport it
+// exists because pgedge-go-llm-lib has no field for it. Requests to any
+// other endpoint pass through untouched.
+type seedTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *seedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	seed, ok := seedFromContext(req.Context())
+	openAI := strings.HasSuffix(req.URL.Path, "/chat/completions")
+	if !ok || req.Body == nil || req.Method != http.MethodPost ||
+		(!openAI && !strings.HasSuffix(req.URL.Path, "/api/chat")) {
+		return t.inner.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var payload map[string]json.RawMessage
+	if err := json.Unmarshal(body, &payload); err != nil {
+		// Not a JSON object; send it as-is and let the provider decide.
+		return t.inner.RoundTrip(withBody(req, body))
+	}
+	encoded, err := json.Marshal(seed)
+	if err != nil {
+		return nil, err
+	}
+	if openAI {
+		payload["seed"] = encoded
+	} else {
+		options := make(map[string]json.RawMessage)
+		if raw, ok := payload["options"]; ok {
+			if err := json.Unmarshal(raw, &options); err != nil {
+				return t.inner.RoundTrip(withBody(req, body))
+			}
+		}
+		options["seed"] = encoded
+		if payload["options"], err = json.Marshal(options); err != nil {
+			return nil, err
+		}
+	}
+	if body, err = json.Marshal(payload); err != nil {
+		return nil, err
+	}
+
+	return t.inner.RoundTrip(withBody(req, body))
+}
@@ -0,0 +1,107 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestNewCompletionClient_OpenAI_SendsSeedFromContext(t *testing.T) {
+	var bodies []map[string]any
+	srv := chatCompletionsServer(t, &bodies)
+
+	c, err := NewCompletionClient("openai", "gpt-4o-mini", srv.URL, nil,
+		&config.LoadedKeys{OpenAI: "sk-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := ContextWithLogitBias(ContextWithSeed(context.Background(), 42), map[string]int{"50256": -100})
+	req := llmlib.ChatRequest{Messages: []llmlib.Message{llmlib.UserText("hi")}}
+	if _, err := c.Chat(ctx, req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+	if _, err := c.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(bodies))
+	}
+	if bodies[0]["seed"] != float64(42) {
+		t.Errorf("expected seed 42, got %v", bodies[0]["seed"])
+	}
+	if _, ok := bodies[0]["logit_bias"]; !ok {
+		t.Error("expected the logit bias to be sent alongside the seed")
+	}
+	if _, ok := bodies[1]["seed"]; ok {
+		t.Errorf("expected no seed without a context value, got %v", bodies[1]["seed"])
+	}
+}
+
+func TestNewCompletionClient_Ollama_SendsSeedInOptions(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		if err := json.Unmarshal(raw, &body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"message":{"role":"assistant","content":"ok"},"done":true}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewCompletionClient("ollama", "llama3", srv.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := llmlib.ChatRequest{
+		Messages:      []llmlib.Message{llmlib.UserText("hi")},
+		StopSequences: []string{"###"},
+	}
+	if _, err := c.Chat(ContextWithSeed(context.Background(), 7), req); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(bodies))
+	}
+	options, _ := bodies[0]["options"].(map[string]any)
+	if options["seed"] != float64(7) {
+		t.Errorf("expected options.seed 7, got %v", bodies[0]["options"])
+	}
+	if stop, _ := options["stop"].([]any); len(stop) != 1 || stop[0] != "###" {
+		t.Errorf("expected options.stop [###] to survive rewriting, got %v", options["stop"])
+	}
+}
+
+func TestSupportsSeed(t *testing.T) {
+	for provider, want := range map[string]bool{
+		"openai": true, "OLLAMA": true, "openai-compatible": true, "anthropic": false, "gemini": false,
+		"bedrock": false,
+	} {
+		if got := SupportsSeed(provider); got != want {
+			t.Errorf("SupportsSeed(%q) = %v, want %v", provider, got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"net/http"
+)
+
+// traceHeadersKey is the context key under which the trace headers of
+// the request being served are stored.
+type traceHeadersKey struct{}
+
+// ContextWithTraceHeaders returns a copy of ctx carrying headers, such
+// as traceparent or X-Request-ID, that a client built by this package
+// adds to every provider request made with ctx, so a gateway's logs can
+// be correlated with the request that caused them. Empty headers leave
+// ctx unchanged.
+func ContextWithTraceHeaders(ctx context.Context, headers http.Header) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceHeadersKey{}, headers)
+}
+
+// traceHeadersFromContext returns the headers stored by
+// ContextWithTraceHeaders, if any.
+func traceHeadersFromContext(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(traceHeadersKey{}).(http.Header)
+	return headers
+}
+
+// traceHeadersTransport adds the trace headers on the request context
+// to each attempt of a provider request. It sits underneath the
+// library's header middleware, so a header the provider's
+// configuration sets, or its credentials, are never overridden.
+type traceHeadersTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *traceHeadersTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := traceHeadersFromContext(req.Context())
+	if len(headers) == 0 {
+		return t.inner.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request.
+	out := req.Clone(req.Context())
+	for name, values := range headers {
+		if _, set := out.Header[name]; !set {
+			out.Header[name] = values
+		}
+	}
+	return t.inner.RoundTrip(out)
+}
@@ -0,0 +1,70 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestNewCompletionClient_SendsTraceHeadersFromContext(t *testing.T) {
+	var got []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Clone())
+		if len(got) == 1 {
+			// Fail the first attempt, so the retry is checked too.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],`+
+			`"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewCompletionClient("openai", "gpt-4o-mini", srv.URL,
+		map[string]string{"X-Request-ID": "configured"},
+		&config.LoadedKeys{OpenAI: "sk-test"},
+		WithRetry(RetryPolicy{MaxRetries: 1, InitialBackoff: 1}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx := ContextWithTraceHeaders(context.Background(), http.Header{
+		"Traceparent":     {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		"X-Request-Id":    {"from-caller"},
+		"X-Portkey-Trace": {"trace-1"},
+	})
+	if _, err := c.Chat(ctx, llmlib.ChatRequest{Messages: []llmlib.Message{llmlib.UserText("hi")}}); err != nil {
+		t.Fatalf("Chat failed: %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("expected a failed attempt and a retry, got %d requests", len(got))
+	}
+	for i, h := range got {
+		if h.Get("Traceparent") == "" || h.Get("X-Portkey-Trace") != "trace-1" {
+			t.Errorf("attempt %d: expected the trace headers, got %v", i+1, h)
+		}
+		if v := h.Values("X-Request-Id"); len(v) != 1 || v[0] != "configured" {
+			t.Errorf("attempt %d: expected the configured header to win, got %v", i+1, v)
+		}
+		if h.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("attempt %d: expected the provider's credentials, got %q", i+1, h.Get("Authorization"))
+		}
+	}
+}
@@ -0,0 +1,90 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"time"
+)
+
+// Pool defaults, used when NewPooledTransport is given zero values.
+const (
+	DefaultMaxIdleConnsPerHost = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+)
+
+// NewPooledTransport returns a transport for a pipeline's provider
+// clients that keeps up to maxIdlePerHost idle connections per provider
+// host open for idleTimeout. http.DefaultTransport keeps only two per
+// host, so concurrent queries against one provider would otherwise
+// keep dialing, and paying a TLS handshake for, new connections. The
+// transport is otherwise a clone of http.DefaultTransport, so proxy
+// settings and HTTP/2 behave as before.
+func NewPooledTransport(maxIdlePerHost int, idleTimeout time.Duration) *http.Transport {
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = DefaultMaxIdleConnsPerHost
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleConnTimeout
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = maxIdlePerHost
+	if t.MaxIdleConns < maxIdlePerHost {
+		t.MaxIdleConns = maxIdlePerHost
+	}
+	t.IdleConnTimeout = idleTimeout
+	return t
+}
+
+// TransportWithCA returns a clone of base that trusts the PEM
+// certificates in caFile in addition to the system roots, for providers
+// reached through a gateway or egress proxy whose certificate is signed
+// by a private CA. The clone keeps its own connection pool.
+func TransportWithCA(base *http.Transport, caFile string) (*http.Transport, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in CA file %s", caFile)
+	}
+
+	t := base.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.RootCAs = pool
+	return t, nil
+}
+
+// connTraceTransport reports, for every request, whether it was sent on
+// a reused keep-alive connection.
+type connTraceTransport struct {
+	inner   http.RoundTripper
+	observe func(reused bool)
+}
+
+func (t *connTraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { t.observe(info.Reused) },
+	}
+	ctx := httptrace.WithClientTrace(req.Context(), trace)
+	return t.inner.RoundTrip(req.WithContext(ctx))
+}
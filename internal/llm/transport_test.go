@@ -0,0 +1,134 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestNewPooledTransport_Defaults(t *testing.T) {
+	tr := NewPooledTransport(0, 0)
+	if tr.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost {
+		t.Errorf("MaxIdleConnsPerHost = %d, want %d", tr.MaxIdleConnsPerHost, DefaultMaxIdleConnsPerHost)
+	}
+	if tr.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("IdleConnTimeout = %v, want %v", tr.IdleConnTimeout, DefaultIdleConnTimeout)
+	}
+
+	tr = NewPooledTransport(200, time.Minute)
+	if tr.MaxIdleConnsPerHost != 200 || tr.MaxIdleConns < 200 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("settings not applied: per-host=%d total=%d timeout=%v",
+			tr.MaxIdleConnsPerHost, tr.MaxIdleConns, tr.IdleConnTimeout)
+	}
+}
+
+// TestNewEmbeddingClient_ReusesPooledConnections verifies requests go
+// through the supplied transport and that the second call reuses the
+// connection the first one opened.
+func TestNewEmbeddingClient_ReusesPooledConnections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1]}]}`)
+	}))
+	defer srv.Close()
+
+	transport := NewPooledTransport(4, time.Minute)
+	defer transport.CloseIdleConnections()
+
+	var mu sync.Mutex
+	var reused []bool
+	c, err := NewEmbeddingClient("openai", "text-embedding-3-small", srv.URL, nil,
+		&config.LoadedKeys{OpenAI: "sk-test"},
+		WithTransport(transport),
+		WithConnObserver(func(r bool) {
+			mu.Lock()
+			defer mu.Unlock()
+			reused = append(reused, r)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Embed(context.Background(), "hello"); err != nil {
+			t.Fatalf("Embed failed: %v", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reused) != 2 || reused[0] || !reused[1] {
+		t.Errorf("expected a new connection then a reused one, got %v", reused)
+	}
+}
+
+// TestTransportWithCA verifies a client trusts a server whose
+// certificate is signed by the CA in the bundle, which the plain
+// pooled transport rejects.
+func TestTransportWithCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	base := NewPooledTransport(0, 0)
+	if _, err := (&http.Client{Transport: base}).Get(srv.URL); err == nil {
+		t.Fatal("expected the untrusted certificate to be rejected")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := TransportWithCA(base, caFile)
+	if err != nil {
+		t.Fatalf("TransportWithCA: %v", err)
+	}
+	defer tr.CloseIdleConnections()
+	if tr.MaxIdleConnsPerHost != base.MaxIdleConnsPerHost {
+		t.Errorf("pool settings not kept: per-host=%d", tr.MaxIdleConnsPerHost)
+	}
+
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request with CA bundle failed: %v", err)
+	}
+	_ = resp.Body.Close()
+}
+
+func TestTransportWithCA_Errors(t *testing.T) {
+	base := NewPooledTransport(0, 0)
+	dir := t.TempDir()
+
+	if _, err := TransportWithCA(base, filepath.Join(dir, "missing.pem")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+
+	notPEM := filepath.Join(dir, "not.pem")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TransportWithCA(base, notPEM); err == nil {
+		t.Error("expected an error for a file without certificates")
+	}
+}
@@ -0,0 +1,129 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+)
+
+// UsageRecorder accumulates the token usage reported by embedding
+// responses made with a given context. pgedge-go-llm-lib's Embed
+// returns only the vector, and its client-wide Usage counter cannot be
+// attributed to a single request when queries run concurrently, so the
+// embedding clients built by this package read the usage out of each
+// response instead.
+type UsageRecorder struct {
+	mu    sync.Mutex
+	usage llmlib.TokenUsage
+}
+
+// Usage returns the usage recorded so far.
+func (r *UsageRecorder) Usage() llmlib.TokenUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usage
+}
+
+func (r *UsageRecorder) add(u llmlib.TokenUsage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.usage.Add(u)
+}
+
+type usageRecorderKey struct{}
+
+// ContextWithUsageRecorder returns a copy of ctx that records the token
+// usage of embedding calls made with it, and the recorder to read it
+// from.
+func ContextWithUsageRecorder(ctx context.Context) (context.Context, *UsageRecorder) {
+	rec := &UsageRecorder{}
+	return context.WithValue(ctx, usageRecorderKey{}, rec), rec
+}
+
+// embeddingUsage covers the usage fields of every embedding API that
+// reports one: OpenAI (usage.prompt_tokens and usage.total_tokens),
+// Voyage (usage.total_tokens), Ollama (prompt_eval_count), Bedrock
+// Titan (inputTextTokenCount) and Cohere (meta.billed_units.input_tokens).
+// Gemini's embedding API does not report token counts.
+type embeddingUsage struct {
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+	PromptEvalCount     int `json:"prompt_eval_count"`
+	InputTextTokenCount int `json:"inputTextTokenCount"`
+	Meta                struct {
+		BilledUnits struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+func (e embeddingUsage) tokenUsage() llmlib.TokenUsage {
+	prompt := e.Usage.PromptTokens
+	if prompt == 0 {
+		prompt = e.Usage.TotalTokens
+	}
+	if prompt == 0 {
+		prompt = e.PromptEvalCount
+	}
+	if prompt == 0 {
+		prompt = e.InputTextTokenCount
+	}
+	if prompt == 0 {
+		prompt = e.Meta.BilledUnits.InputTokens
+	}
+	total := e.Usage.TotalTokens
+	if total == 0 {
+		total = prompt
+	}
+	return llmlib.TokenUsage{PromptTokens: prompt, TotalTokens: total}
+}
+
+// usageTransport records embedding usage for requests whose context
+// carries a UsageRecorder. The response body is buffered and replaced
+// so the library still decodes it as normal.
+type usageTransport struct {
+	inner http.RoundTripper
+}
+
+func (t *usageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.inner.RoundTrip(req)
+	rec, _ := req.Context().Value(usageRecorderKey{}).(*UsageRecorder)
+	if err != nil || rec == nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var parsed embeddingUsage
+	if json.Unmarshal(body, &parsed) == nil {
+		rec.add(parsed.tokenUsage())
+	}
+	return resp, nil
+}
+
+// embeddingHTTPClient returns the HTTP client embedding clients are
+// built with, sending requests through rt.
+func embeddingHTTPClient(rt http.RoundTripper) *http.Client {
+	return &http.Client{Transport: &usageTransport{inner: rt}}
+}
@@ -0,0 +1,81 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestNewEmbeddingClient_RecordsUsageFromResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],`+
+			`"usage":{"prompt_tokens":5,"total_tokens":5}}`)
+	}))
+	defer srv.Close()
+
+	c, err := NewEmbeddingClient("openai", "text-embedding-3-small", srv.URL, nil,
+		&config.LoadedKeys{OpenAI: "sk-test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, rec := ContextWithUsageRecorder(context.Background())
+	embedding, err := Embed32(ctx, c, "hello")
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(embedding) != 2 {
+		t.Errorf("expected the response body to still decode, got %v", embedding)
+	}
+	if u := rec.Usage(); u.PromptTokens != 5 || u.TotalTokens != 5 {
+		t.Errorf("expected 5 prompt tokens recorded, got %+v", u)
+	}
+
+	// Calls without a recorder are unaffected.
+	if _, err := Embed32(context.Background(), c, "hello"); err != nil {
+		t.Fatalf("Embed without recorder failed: %v", err)
+	}
+	if u := rec.Usage(); u.TotalTokens != 5 {
+		t.Errorf("expected recorder to be untouched by other calls, got %+v", u)
+	}
+}
+
+func TestEmbeddingUsage_Providers(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"voyage total only", `{"usage":{"total_tokens":7}}`, 7},
+		{"ollama prompt_eval_count", `{"embeddings":[[0.1]],"prompt_eval_count":3}`, 3},
+		{"cohere billed units", `{"embeddings":{"float":[[0.1]]},"meta":{"billed_units":{"input_tokens":4}}}`, 4},
+		{"gemini reports nothing", `{"embedding":{"values":[0.1]}}`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parsed embeddingUsage
+			if err := json.Unmarshal([]byte(tt.body), &parsed); err != nil {
+				t.Fatalf("unmarshal failed: %v", err)
+			}
+			u := parsed.tokenUsage()
+			if u.PromptTokens != tt.want || u.TotalTokens != tt.want {
+				t.Errorf("expected %d tokens, got %+v", tt.want, u)
+			}
+		})
+	}
+}
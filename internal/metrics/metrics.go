@@ -0,0 +1,470 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package metrics collects per-pipeline operational metrics and exposes
+// them in the Prometheus text exposition format.
+//
+// The server only needs a handful of counters and histograms, so this
+// package implements the exposition format directly rather than pulling
+// in the full Prometheus client library and its dependency tree. All
+// methods on *Registry are safe for concurrent use and are no-ops on a
+// nil receiver, so callers can thread an optional registry through
+// without guarding every call site.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage names used as the "stage" label on latency and error metrics.
+const (
+	StageQueryExpansion = "query_expansion"
+	StageEmbedding      = "embedding"
+	StageVectorSearch   = "vector_search"
+	StageBM25           = "bm25"
+	StageFullTextSearch = "full_text_search"
+	StageRerank         = "rerank"
+	StageCompletion     = "completion"
+	StageHistorySummary = "history_summary"
+	StageContextSummary = "context_summary"
+	StageAccessControl  = "access_control"
+	StageGroundedness   = "groundedness"
+	StageHook           = "hook"
+)
+
+// ProviderPostgres is the "provider" label used for stages served by
+// the pipeline's database rather than an LLM provider.
+const ProviderPostgres = "postgres"
+
+// ProviderHTTP is the "provider" label of access control checks made
+// by an HTTP callback.
+const ProviderHTTP = "http"
+
+// ProviderStarlark is the "provider" label of calls to a pipeline's
+// script hooks.
+const ProviderStarlark = "starlark"
+
+// DefaultBuckets are the histogram bucket upper bounds, in seconds. They
+// extend the usual Prometheus defaults upward because a completion call
+// routinely takes tens of seconds.
+var DefaultBuckets = []float64{
+	0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60,
+}
+
+// Registry holds every metric family the server reports.
+type Registry struct {
+	requests        *counterVec
+	requestDuration *histogramVec
+	firstByte       *histogramVec
+	stageDuration   *histogramVec
+	tokens          *counterVec
+	errors          *counterVec
+	providerConns   *counterVec
+	providerRetries *counterVec
+	providerRejects *counterVec
+	answerCache     *counterVec
+	documentCache   *counterVec
+	experiments     *counterVec
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		requests: newCounterVec("pgedge_rag_requests_total",
+			"Total pipeline queries by outcome.",
+			"pipeline", "status"),
+		requestDuration: newHistogramVec("pgedge_rag_request_duration_seconds",
+			"End-to-end pipeline query latency in seconds.",
+			"pipeline"),
+		firstByte: newHistogramVec("pgedge_rag_time_to_first_byte_seconds",
+			"Time from the start of a streamed pipeline query to its first answer text in seconds.",
+			"pipeline"),
+		stageDuration: newHistogramVec("pgedge_rag_stage_duration_seconds",
+			"Latency of individual pipeline stages in seconds.",
+			"pipeline", "stage", "provider"),
+		tokens: newCounterVec("pgedge_rag_tokens_total",
+			"LLM tokens consumed, by stage and token type.",
+			"pipeline", "stage", "provider", "type"),
+		errors: newCounterVec("pgedge_rag_errors_total",
+			"Pipeline stage failures.",
+			"pipeline", "stage", "provider"),
+		providerConns: newCounterVec("pgedge_rag_provider_connections_total",
+			"Connections used for LLM provider requests, by whether an idle keep-alive connection was reused.",
+			"pipeline", "provider", "reused"),
+		providerRetries: newCounterVec("pgedge_rag_provider_retries_total",
+			"Retried LLM provider requests, by reason (rate_limited, server_error or network).",
+			"pipeline", "provider", "reason"),
+		providerRejects: newCounterVec("pgedge_rag_provider_rejected_total",
+			"LLM provider requests refused by the provider's concurrency limit, by reason (queue_full or queue_timeout).",
+			"pipeline", "provider", "reason"),
+		answerCache: newCounterVec("pgedge_rag_answer_cache_total",
+			"Cacheable pipeline queries, by whether the answer cache had their answer.",
+			"pipeline", "hit"),
+		documentCache: newCounterVec("pgedge_rag_document_cache_total",
+			"BM25 document fetches, by whether the document cache served them fresh, stale or not at all.",
+			"pipeline", "result"),
+		experiments: newCounterVec("pgedge_rag_experiment_assignments_total",
+			"Queries asked of a pipeline running an experiment, by the variant pipeline that answered them.",
+			"pipeline", "variant"),
+	}
+}
+
+// ObserveRequest records the outcome and latency of one pipeline query.
+func (r *Registry) ObserveRequest(pipeline, status string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.requests.add(1, pipeline, status)
+	r.requestDuration.observe(d.Seconds(), pipeline)
+}
+
+// ObserveFirstByte records how long a streamed query took to send its
+// first answer text.
+func (r *Registry) ObserveFirstByte(pipeline string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.firstByte.observe(d.Seconds(), pipeline)
+}
+
+// ObserveStage records the latency of a single pipeline stage.
+func (r *Registry) ObserveStage(pipeline, stage, provider string, d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.stageDuration.observe(d.Seconds(), pipeline, stage, provider)
+}
+
+// AddTokens adds n tokens of the given type (e.g. "prompt",
+// "completion") consumed by a pipeline stage. Non-positive values are
+// ignored.
+func (r *Registry) AddTokens(pipeline, stage, provider, tokenType string, n int) {
+	if r == nil || n <= 0 {
+		return
+	}
+	r.tokens.add(float64(n), pipeline, stage, provider, tokenType)
+}
+
+// IncError counts a failure in the given stage.
+func (r *Registry) IncError(pipeline, stage, provider string) {
+	if r == nil {
+		return
+	}
+	r.errors.add(1, pipeline, stage, provider)
+}
+
+// ObserveProviderConn counts a provider request by whether it reused an
+// idle keep-alive connection rather than dialing a new one.
+func (r *Registry) ObserveProviderConn(pipeline, provider string, reused bool) {
+	if r == nil {
+		return
+	}
+	r.providerConns.add(1, pipeline, provider, strconv.FormatBool(reused))
+}
+
+// IncProviderRetry counts a provider request that failed and is being
+// retried, with the reason it failed.
+func (r *Registry) IncProviderRetry(pipeline, provider, reason string) {
+	if r == nil {
+		return
+	}
+	r.providerRetries.add(1, pipeline, provider, reason)
+}
+
+// IncProviderRejected counts a provider request refused by the
+// provider's concurrency limit, with the reason it was refused.
+func (r *Registry) IncProviderRejected(pipeline, provider, reason string) {
+	if r == nil {
+		return
+	}
+	r.providerRejects.add(1, pipeline, provider, reason)
+}
+
+// ObserveAnswerCache counts a cacheable query by whether its answer was
+// served from the pipeline's answer cache.
+func (r *Registry) ObserveAnswerCache(pipeline string, hit bool) {
+	if r == nil {
+		return
+	}
+	r.answerCache.add(1, pipeline, strconv.FormatBool(hit))
+}
+
+// Document cache results for ObserveDocumentCache.
+const (
+	DocumentCacheFresh = "fresh"
+	DocumentCacheStale = "stale"
+	DocumentCacheMiss  = "miss"
+)
+
+// ObserveDocumentCache counts a table's documents being fetched for
+// BM25 by whether the pipeline's document cache served them fresh,
+// served them stale while refreshing them, or missed.
+func (r *Registry) ObserveDocumentCache(pipeline, result string) {
+	if r == nil {
+		return
+	}
+	r.documentCache.add(1, pipeline, result)
+}
+
+// ObserveExperiment counts a query asked of a pipeline running an
+// experiment being routed to variant, which may be the pipeline itself.
+func (r *Registry) ObserveExperiment(pipeline, variant string) {
+	if r == nil {
+		return
+	}
+	r.experiments.add(1, pipeline, variant)
+}
+
+// WriteTo writes every metric family in the Prometheus text exposition
+// format (version 0.0.4).
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	if r == nil {
+		return 0, nil
+	}
+	cw := &countingWriter{w: w}
+	r.requests.write(cw)
+	r.requestDuration.write(cw)
+	r.firstByte.write(cw)
+	r.stageDuration.write(cw)
+	r.tokens.write(cw)
+	r.errors.write(cw)
+	r.providerConns.write(cw)
+	r.providerRetries.write(cw)
+	r.providerRejects.write(cw)
+	r.answerCache.write(cw)
+	r.documentCache.write(cw)
+	r.experiments.write(cw)
+	return cw.n, cw.err
+}
+
+// Handler returns an http.Handler that serves the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = r.WriteTo(w)
+	})
+}
+
+// countingWriter tracks bytes written and the first write error so the
+// per-family writers don't each need to handle errors.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) printf(format string, args ...any) {
+	if c.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(c.w, format, args...)
+	c.n += int64(n)
+	c.err = err
+}
+
+// counterVec is a family of counters partitioned by label values.
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{
+		name:   name,
+		help:   help,
+		labels: labels,
+		values: make(map[string]*counterSeries),
+	}
+}
+
+func (c *counterVec) add(v float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s, ok := c.values[key]
+	if !ok {
+		s = &counterSeries{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = s
+	}
+	s.value += v
+}
+
+func (c *counterVec) write(w *countingWriter) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w.printf("# HELP %s %s\n", c.name, c.help)
+	w.printf("# TYPE %s counter\n", c.name)
+	for _, key := range sortedKeys(c.values) {
+		s := c.values[key]
+		w.printf("%s%s %s\n", c.name, formatLabels(c.labels, s.labelValues, ""),
+			formatFloat(s.value))
+	}
+}
+
+// histogramVec is a family of histograms partitioned by label values.
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64 // per-bucket, non-cumulative
+	count       uint64
+	sum         float64
+}
+
+func newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{
+		name:    name,
+		help:    help,
+		labels:  labels,
+		buckets: DefaultBuckets,
+		values:  make(map[string]*histogramSeries),
+	}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := seriesKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.values[key]
+	if !ok {
+		s = &histogramSeries{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+			break
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+func (h *histogramVec) write(w *countingWriter) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	w.printf("# HELP %s %s\n", h.name, h.help)
+	w.printf("# TYPE %s histogram\n", h.name)
+	for _, key := range sortedKeys(h.values) {
+		s := h.values[key]
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += s.counts[i]
+			w.printf("%s_bucket%s %d\n", h.name,
+				formatLabels(h.labels, s.labelValues, formatFloat(upper)), cumulative)
+		}
+		w.printf("%s_bucket%s %d\n", h.name,
+			formatLabels(h.labels, s.labelValues, "+Inf"), s.count)
+		w.printf("%s_sum%s %s\n", h.name,
+			formatLabels(h.labels, s.labelValues, ""), formatFloat(s.sum))
+		w.printf("%s_count%s %d\n", h.name,
+			formatLabels(h.labels, s.labelValues, ""), s.count)
+	}
+}
+
+// seriesKey joins label values into a map key. The separator cannot
+// appear in a valid UTF-8 label value.
+func seriesKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+// sortedKeys returns a map's keys in sorted order so the exposition
+// output is stable between scrapes.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatLabels renders a {name="value",...} label set. When le is
+// non-empty it is appended as the histogram bucket bound.
+func formatLabels(names, values []string, le string) string {
+	if len(names) == 0 && le == "" {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(values[i]))
+		sb.WriteByte('"')
+	}
+	if le != "" {
+		if len(names) > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(`le="`)
+		sb.WriteString(le)
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+// escapeLabelValue escapes backslashes, double quotes, and newlines as
+// required by the exposition format.
+func escapeLabelValue(v string) string {
+	v = strings.ReplaceAll(v, `\`, `\\`)
+	v = strings.ReplaceAll(v, `"`, `\"`)
+	return strings.ReplaceAll(v, "\n", `\n`)
+}
+
+// formatFloat renders a sample value the way Prometheus expects.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,148 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func render(t *testing.T, r *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	return buf.String()
+}
+
+func TestRegistry_Counters(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveRequest("docs", "ok", 100*time.Millisecond)
+	r.ObserveRequest("docs", "ok", 200*time.Millisecond)
+	r.ObserveRequest("docs", "error", time.Second)
+	r.AddTokens("docs", StageCompletion, "openai", "prompt", 120)
+	r.AddTokens("docs", StageCompletion, "openai", "prompt", 30)
+	r.AddTokens("docs", StageEmbedding, "openai", "prompt", 8)
+	r.IncError("docs", StageCompletion, "openai")
+	r.ObserveProviderConn("docs", "openai", false)
+	r.ObserveProviderConn("docs", "openai", true)
+	r.ObserveProviderConn("docs", "openai", true)
+	r.IncProviderRetry("docs", "openai", "rate_limited")
+	r.ObserveAnswerCache("docs", true)
+	r.ObserveAnswerCache("docs", false)
+	r.ObserveAnswerCache("docs", true)
+	r.ObserveDocumentCache("docs", DocumentCacheStale)
+	r.ObserveExperiment("docs", "docs-sonnet")
+	r.ObserveExperiment("docs", "docs")
+	r.ObserveExperiment("docs", "docs-sonnet")
+
+	out := render(t, r)
+
+	for _, want := range []string{
+		`pgedge_rag_requests_total{pipeline="docs",status="ok"} 2`,
+		`pgedge_rag_requests_total{pipeline="docs",status="error"} 1`,
+		`pgedge_rag_tokens_total{pipeline="docs",stage="completion",provider="openai",type="prompt"} 150`,
+		`pgedge_rag_tokens_total{pipeline="docs",stage="embedding",provider="openai",type="prompt"} 8`,
+		`pgedge_rag_errors_total{pipeline="docs",stage="completion",provider="openai"} 1`,
+		`pgedge_rag_provider_connections_total{pipeline="docs",provider="openai",reused="false"} 1`,
+		`pgedge_rag_provider_connections_total{pipeline="docs",provider="openai",reused="true"} 2`,
+		`pgedge_rag_provider_retries_total{pipeline="docs",provider="openai",reason="rate_limited"} 1`,
+		`pgedge_rag_answer_cache_total{pipeline="docs",hit="true"} 2`,
+		`pgedge_rag_document_cache_total{pipeline="docs",result="stale"} 1`,
+		`pgedge_rag_experiment_assignments_total{pipeline="docs",variant="docs-sonnet"} 2`,
+		`pgedge_rag_experiment_assignments_total{pipeline="docs",variant="docs"} 1`,
+		`# TYPE pgedge_rag_requests_total counter`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistry_Histogram(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveStage("docs", StageEmbedding, "openai", 20*time.Millisecond)
+	r.ObserveStage("docs", StageEmbedding, "openai", 3*time.Second)
+	r.ObserveFirstByte("docs", 700*time.Millisecond)
+
+	out := render(t, r)
+
+	for _, want := range []string{
+		`# TYPE pgedge_rag_stage_duration_seconds histogram`,
+		`pgedge_rag_stage_duration_seconds_bucket{pipeline="docs",stage="embedding",provider="openai",le="0.01"} 0`,
+		`pgedge_rag_stage_duration_seconds_bucket{pipeline="docs",stage="embedding",provider="openai",le="0.025"} 1`,
+		`pgedge_rag_stage_duration_seconds_bucket{pipeline="docs",stage="embedding",provider="openai",le="5"} 2`,
+		`pgedge_rag_stage_duration_seconds_bucket{pipeline="docs",stage="embedding",provider="openai",le="+Inf"} 2`,
+		`pgedge_rag_stage_duration_seconds_count{pipeline="docs",stage="embedding",provider="openai"} 2`,
+		`pgedge_rag_stage_duration_seconds_sum{pipeline="docs",stage="embedding",provider="openai"} 3.02`,
+		`pgedge_rag_time_to_first_byte_seconds_bucket{pipeline="docs",le="1"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistry_IgnoresNonPositiveTokens(t *testing.T) {
+	r := NewRegistry()
+	r.AddTokens("docs", StageCompletion, "openai", "prompt", 0)
+	r.AddTokens("docs", StageCompletion, "openai", "prompt", -5)
+
+	if out := render(t, r); strings.Contains(out, "pgedge_rag_tokens_total{") {
+		t.Errorf("expected no token series, got:\n%s", out)
+	}
+}
+
+func TestRegistry_NilIsNoOp(t *testing.T) {
+	var r *Registry
+	r.ObserveRequest("docs", "ok", time.Second)
+	r.ObserveFirstByte("docs", time.Second)
+	r.ObserveStage("docs", StageBM25, ProviderPostgres, time.Second)
+	r.AddTokens("docs", StageEmbedding, "openai", "prompt", 10)
+	r.IncError("docs", StageVectorSearch, ProviderPostgres)
+	r.ObserveProviderConn("docs", "openai", true)
+	r.IncProviderRetry("docs", "openai", "network")
+	r.ObserveAnswerCache("docs", false)
+
+	if out := render(t, r); out != "" {
+		t.Errorf("expected empty output from nil registry, got %q", out)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	got := escapeLabelValue("a\"b\\c\nd")
+	want := `a\"b\\c\nd`
+	if got != want {
+		t.Errorf("escapeLabelValue = %q, want %q", got, want)
+	}
+}
+
+func TestHandler_ContentType(t *testing.T) {
+	r := NewRegistry()
+	r.ObserveRequest("docs", "ok", time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected Content-Type %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "pgedge_rag_requests_total") {
+		t.Errorf("body missing metrics:\n%s", rec.Body.String())
+	}
+}
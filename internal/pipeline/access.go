@@ -0,0 +1,194 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// maxAuthorizeResponseBytes bounds the response read from an access
+// control callback.
+const maxAuthorizeResponseBytes = 1 << 20
+
+// DocumentVisibility is the narrow interface a SQL access control
+// function is called through. *database.Pool satisfies it structurally.
+type DocumentVisibility interface {
+	VisibleDocuments(ctx context.Context, function, principal, table string, ids []string) ([]string, error)
+}
+
+// NewAuthorizer returns the access control hook a pipeline's settings
+// describe, or nil when they disable it. db runs SQL functions.
+func NewAuthorizer(cfg config.AccessControlConfig, db DocumentVisibility) Authorizer {
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = config.DefaultAccessControlTimeout
+	}
+	switch {
+	case cfg.Function != "":
+		return &sqlAuthorizer{db: db, function: cfg.Function, timeout: timeout}
+	case cfg.URL != "":
+		return &httpAuthorizer{
+			client:  &http.Client{Timeout: timeout},
+			url:     cfg.URL,
+			headers: cfg.Headers,
+		}
+	}
+	return nil
+}
+
+// sqlAuthorizer asks a SQL function in the pipeline's database.
+type sqlAuthorizer struct {
+	db       DocumentVisibility
+	function string
+	timeout  time.Duration
+}
+
+func (a *sqlAuthorizer) Authorize(ctx context.Context, principal, table string, ids []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	return a.db.VisibleDocuments(ctx, a.function, principal, table, ids)
+}
+
+func (a *sqlAuthorizer) Provider() string { return metrics.ProviderPostgres }
+
+// authorizeRequest is the body POSTed to an access control callback.
+type authorizeRequest struct {
+	Principal   string   `json:"principal"`
+	Table       string   `json:"table"`
+	DocumentIDs []string `json:"document_ids"`
+}
+
+// authorizeResponse is the body an access control callback answers
+// with.
+type authorizeResponse struct {
+	AllowedIDs []string `json:"allowed_ids"`
+}
+
+// httpAuthorizer asks an HTTP callback.
+type httpAuthorizer struct {
+	client  *http.Client
+	url     string
+	headers map[string]string
+}
+
+func (a *httpAuthorizer) Authorize(ctx context.Context, principal, table string, ids []string) ([]string, error) {
+	body, err := json.Marshal(authorizeRequest{Principal: principal, Table: table, DocumentIDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range a.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("access control callback failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("access control callback returned status %d", resp.StatusCode)
+	}
+	var out authorizeResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxAuthorizeResponseBytes)).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid access control callback response: %w", err)
+	}
+	return out.AllowedIDs, nil
+}
+
+func (a *httpAuthorizer) Provider() string { return metrics.ProviderHTTP }
+
+// principal returns the caller the pipeline's access control hook is
+// asked about, built from the request's filter variables.
+func (o *Orchestrator) principal(ctx context.Context) (string, error) {
+	vars := database.FilterVarsFrom(ctx)
+	var missing error
+	principal := config.ExpandFilterVariables(o.cfg.AccessControl.Principal, func(v config.FilterVariable) string {
+		value, ok := vars.Lookup(v)
+		if !ok && missing == nil {
+			missing = fmt.Errorf("%w: access control requires %s, which the request does not set",
+				ErrInvalidRequest, v)
+		}
+		return value
+	})
+	return principal, missing
+}
+
+// visible asks the access control hook which of the given documents
+// of table the caller may see. It returns nil when the pipeline has no
+// hook, and an error when the check fails.
+func (o *Orchestrator) visible(ctx context.Context, table string, ids []string) (map[string]bool, error) {
+	if o.authorizer == nil {
+		return nil, nil
+	}
+	principal, err := o.principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	visible, err := o.authorizer.Authorize(ctx, principal, table, ids)
+	o.observeStage(metrics.StageAccessControl, o.authorizer.Provider(), start, err)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(visible))
+	for _, id := range visible {
+		allowed[id] = true
+	}
+	return allowed, nil
+}
+
+// authorize keeps only the documents of a table's search that the
+// access control hook reports the caller may see. When the check
+// fails, none are kept and the table counts as failed, rather than
+// risk showing documents the caller may not see.
+func (o *Orchestrator) authorize(ctx context.Context, table config.TableSource, ts tableSearch) tableSearch {
+	if o.authorizer == nil || len(ts.results) == 0 {
+		return ts
+	}
+
+	ids := make([]string, len(ts.results))
+	for i, r := range ts.results {
+		ids[i] = r.ID
+	}
+	allowed, err := o.visible(ctx, table.Table, ids)
+	if err != nil {
+		o.logger.Warn("access control failed", "table", table.Table, "error", err)
+		tableTraceFrom(ctx).fail(err)
+		return tableSearch{failed: true}
+	}
+
+	kept := make([]database.SearchResult, 0, len(allowed))
+	for _, r := range ts.results {
+		if allowed[r.ID] {
+			kept = append(kept, r)
+		}
+	}
+	if dropped := len(ts.results) - len(kept); dropped > 0 {
+		o.logger.Debug("access control removed documents", "table", table.Table, "removed", dropped)
+	}
+	ts.results = kept
+	return ts
+}
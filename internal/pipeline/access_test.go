@@ -0,0 +1,111 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// stubAuthorizer is an Authorizer returning fixed IDs, or an error.
+type stubAuthorizer struct {
+	visible   []string
+	err       error
+	principal string
+	table     string
+	ids       []string
+}
+
+func (a *stubAuthorizer) Authorize(ctx context.Context, principal, table string, ids []string) ([]string, error) {
+	a.principal, a.table, a.ids = principal, table, ids
+	return a.visible, a.err
+}
+
+func (a *stubAuthorizer) Provider() string { return "stub" }
+
+func TestOrchestrator_AccessControl(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.cfg.AccessControl = config.AccessControlConfig{Principal: "user:{{header:X-User}}", Function: "visible_docs"}
+	authorizer := &stubAuthorizer{visible: []string{"doc-3", "doc-1", "elsewhere"}}
+	orch.authorizer = authorizer
+
+	ctx := database.WithFilterVars(context.Background(), func(v config.FilterVariable) (string, bool) {
+		return "ann", v.String() == "header:X-User"
+	})
+	resp, err := orch.Retrieve(ctx, RetrieveRequest{Query: "replication"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Documents) != 2 || resp.Documents[0].ID != "doc-1" || resp.Documents[1].ID != "doc-3" {
+		t.Errorf("expected only the visible documents, in ranked order, got %+v", resp.Documents)
+	}
+	if authorizer.principal != "user:ann" || authorizer.table != "docs" || len(authorizer.ids) != 3 {
+		t.Errorf("unexpected authorization call: %q, %q, %v", authorizer.principal, authorizer.table, authorizer.ids)
+	}
+
+	if _, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "replication"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest without a principal, got %v", err)
+	}
+
+	// A failed check shows nothing rather than everything.
+	authorizer.err = errors.New("authorization service unavailable")
+	if resp, err := orch.Retrieve(ctx, RetrieveRequest{Query: "replication"}); err == nil {
+		t.Errorf("expected an error when the check fails, got %+v", resp.Documents)
+	}
+}
+
+func TestHTTPAuthorizer(t *testing.T) {
+	var got authorizeRequest
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("expected the configured header, got %v", r.Header)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"allowed_ids": ["2"]}`))
+	}))
+	defer srv.Close()
+
+	authorizer := NewAuthorizer(config.AccessControlConfig{
+		Principal: "{{header:X-User}}",
+		URL:       srv.URL,
+		Headers:   map[string]string{"Authorization": "Bearer secret"},
+	}, nil)
+	visible, err := authorizer.Authorize(context.Background(), "ann", "docs", []string{"1", "2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(visible) != 1 || visible[0] != "2" {
+		t.Errorf("unexpected visible IDs: %v", visible)
+	}
+	if got.Principal != "ann" || got.Table != "docs" || len(got.DocumentIDs) != 2 {
+		t.Errorf("unexpected request: %+v", got)
+	}
+
+	status = http.StatusForbidden
+	if _, err := authorizer.Authorize(context.Background(), "ann", "docs", []string{"1"}); err == nil {
+		t.Error("expected an error for a non-200 response")
+	}
+
+	if NewAuthorizer(config.AccessControlConfig{}, nil) != nil {
+		t.Error("expected no authorizer when access control is disabled")
+	}
+}
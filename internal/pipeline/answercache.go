@@ -0,0 +1,277 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// cachedAnswer is what the answer cache keeps of a query's response.
+type cachedAnswer struct {
+	answer         string
+	sources        []Source
+	citations      []Citation
+	formatWarnings []string
+	guardrails     []string
+	provenance     []DocumentProvenance
+	answeredBy     Answerer
+}
+
+// answerEntry is a cached answer and the key it is cached under.
+type answerEntry struct {
+	key     string
+	answer  cachedAnswer
+	expires time.Time
+}
+
+// answerCache keeps the answers to a pipeline's recent queries, so a
+// query asked again is answered without retrieval or completion. The
+// least recently used answer is evicted once it holds maxEntries. It is
+// safe for concurrent use, and a nil cache caches nothing.
+type answerCache struct {
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time // overridden in tests
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *answerEntry, most recently used first
+}
+
+// newAnswerCache creates the answer cache a pipeline's settings
+// describe, or returns nil when they disable it.
+func newAnswerCache(cfg config.AnswerCacheConfig) *answerCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	c := &answerCache{
+		ttl:        time.Duration(cfg.TTL),
+		maxEntries: cfg.MaxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if c.ttl <= 0 {
+		c.ttl = time.Duration(config.DefaultAnswerCacheTTL)
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = config.DefaultAnswerCacheMaxEntries
+	}
+	return c
+}
+
+// get returns the answer cached under key, if it has not expired.
+func (c *answerCache) get(key string) (cachedAnswer, bool) {
+	if c == nil || key == "" {
+		return cachedAnswer{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return cachedAnswer{}, false
+	}
+	entry := el.Value.(*answerEntry)
+	if !c.now().Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return cachedAnswer{}, false
+	}
+	c.lru.MoveToFront(el)
+	return entry.answer, true
+}
+
+// put caches answer under key, evicting the least recently used answer
+// if the cache is full.
+func (c *answerCache) put(key string, answer cachedAnswer) {
+	if c == nil || key == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*answerEntry)
+		entry.answer, entry.expires = answer, expires
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&answerEntry{key: key, answer: answer, expires: expires})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*answerEntry).key)
+	}
+}
+
+// clear removes every cached answer, such as when the pipeline's
+// documents change.
+func (c *answerCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// answerCacheKey returns the key a query's answer is cached under, or
+// "" when it is not cached: when the pipeline has no answer cache, the
+// query carries conversation history, which its answer depends on, or
+// it sets a seed, asking for a fresh answer sampled with it. Besides
+// the normalized query text, the key covers the request's filter,
+// after any preset is applied, the settings that change the answer,
+// and the values of the filter variables that decide which documents
+// the caller may see.
+func (o *Orchestrator) answerCacheKey(ctx context.Context, req QueryRequest) string {
+	if o.answerCache == nil || len(req.Messages) > 0 || req.Seed != nil {
+		return ""
+	}
+	key, err := json.Marshal(struct {
+		Query          string
+		Filter         *config.Filter
+		TopN           int
+		IncludeSources bool
+		StopSequences  []string
+		LogitBias      map[string]int
+		AnswerLength   string
+		ResponseFormat *ResponseFormat
+		Caller         []string
+	}{
+		Query:          normalizeQuery(req.Query),
+		Filter:         req.Filter,
+		TopN:           req.TopN,
+		IncludeSources: req.IncludeSources,
+		StopSequences:  o.stopSequences(req),
+		LogitBias:      req.LogitBias,
+		AnswerLength:   req.AnswerLength,
+		ResponseFormat: req.ResponseFormat,
+		Caller:         o.callerScope(ctx),
+	})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:])
+}
+
+// callerScope returns the values of the variables the pipeline's tenant
+// isolation, table filters and access control principal read, which
+// decide the documents a request may retrieve.
+func (o *Orchestrator) callerScope(ctx context.Context) []string {
+	vars := database.FilterVarsFrom(ctx)
+	var scope []string
+	add := func(v config.FilterVariable) {
+		value, _ := vars.Lookup(v)
+		scope = append(scope, v.String()+"="+value)
+	}
+	if o.cfg.Tenant.Enabled() {
+		add(o.cfg.Tenant.Variable())
+	}
+	for _, table := range o.cfg.Tables {
+		for _, v := range table.Filter.Variables() {
+			add(v)
+		}
+	}
+	if o.authorizer != nil {
+		principal, _ := o.principal(ctx)
+		scope = append(scope, "principal="+principal)
+	}
+	return scope
+}
+
+// normalizeQuery folds the differences between two askings of a query
+// that do not change its answer: letter case, runs of whitespace, and
+// trailing punctuation.
+func normalizeQuery(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return strings.TrimRight(query, "?!. ")
+}
+
+// lookupAnswer returns the answer cached for a query, counting the
+// lookup in the pipeline's metrics.
+func (o *Orchestrator) lookupAnswer(key string) (cachedAnswer, bool) {
+	if key == "" {
+		return cachedAnswer{}, false
+	}
+	answer, ok := o.answerCache.get(key)
+	o.metrics.ObserveAnswerCache(o.pipelineName(), ok)
+	if ok {
+		o.logger.Debug("answer served from cache")
+	}
+	return answer, ok
+}
+
+// response rebuilds a query's response from its cached answer. No
+// tokens are used answering it.
+func (a cachedAnswer) response(req QueryRequest, timer *queryTimer) *QueryResponse {
+	return &QueryResponse{
+		Answer:         a.answer,
+		Sources:        a.sources,
+		Usage:          &StageUsage{},
+		FormatWarnings: a.formatWarnings,
+		Citations:      a.citations,
+		Guardrails:     a.guardrails,
+		Timings:        timer.result(req),
+		Cached:         true,
+		Provenance:     a.provenance,
+		AnsweredBy:     a.answeredBy,
+	}
+}
+
+// streamCachedAnswer sends a cached answer as a stream: its sources,
+// when the request asked for them, the answer in one chunk, and the
+// final chunk.
+func (o *Orchestrator) streamCachedAnswer(ctx context.Context, chunkChan chan<- StreamChunk,
+	a cachedAnswer, req QueryRequest, timer *queryTimer) error {
+	send := func(chunk StreamChunk) error {
+		select {
+		case chunkChan <- chunk:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if req.IncludeSources && a.sources != nil {
+		if err := send(StreamChunk{Sources: a.sources}); err != nil {
+			return err
+		}
+	}
+	timer.firstByte(o)
+	if err := send(StreamChunk{Content: a.answer}); err != nil {
+		return err
+	}
+	return send(StreamChunk{
+		FinishReason:   "stop",
+		Usage:          &StageUsage{},
+		FormatWarnings: a.formatWarnings,
+		Citations:      a.citations,
+		Guardrails:     a.guardrails,
+		Timings:        timer.result(req),
+		Cached:         true,
+		ResponseID:     o.recordResponse(req, a.provenance),
+		Provenance:     a.provenance,
+		AnsweredBy:     a.answeredBy,
+	})
+}
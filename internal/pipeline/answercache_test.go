@@ -0,0 +1,165 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
+)
+
+func TestAnswerCache(t *testing.T) {
+	c := newAnswerCache(config.AnswerCacheConfig{Enabled: true, TTL: config.Duration(time.Minute), MaxEntries: 2})
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.put("a", cachedAnswer{answer: "A"})
+	c.put("b", cachedAnswer{answer: "B"})
+	if got, ok := c.get("a"); !ok || got.answer != "A" {
+		t.Fatalf("expected the cached answer, got %+v, %v", got, ok)
+	}
+
+	// b is now the least recently used, so it is evicted.
+	c.put("c", cachedAnswer{answer: "C"})
+	if _, ok := c.get("b"); ok {
+		t.Error("expected the least recently used answer to be evicted")
+	}
+	if _, ok := c.get("a"); !ok {
+		t.Error("expected the recently used answer to be kept")
+	}
+
+	now = now.Add(time.Minute)
+	if _, ok := c.get("c"); ok {
+		t.Error("expected the answer to expire")
+	}
+
+	c.put("d", cachedAnswer{answer: "D"})
+	c.clear()
+	if _, ok := c.get("d"); ok {
+		t.Error("expected clear to remove every answer")
+	}
+
+	var disabled *answerCache
+	disabled.put("a", cachedAnswer{answer: "A"})
+	if _, ok := disabled.get("a"); ok || newAnswerCache(config.AnswerCacheConfig{}) != nil {
+		t.Error("expected no cache when disabled")
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	for _, q := range []string{"How does replication work?", "  how does\treplication  WORK ?! ", "how does replication work"} {
+		if got := normalizeQuery(q); got != "how does replication work" {
+			t.Errorf("normalizeQuery(%q) = %q", q, got)
+		}
+	}
+}
+
+func TestOrchestrator_Execute_AnswerCache(t *testing.T) {
+	orch, requests := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	orch.cfg.AnswerCache = config.AnswerCacheConfig{Enabled: true}
+	orch.answerCache = newAnswerCache(orch.cfg.AnswerCache)
+	ctx := context.Background()
+
+	first, err := orch.Execute(ctx, QueryRequest{Query: "How does replication work?", IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := orch.Execute(ctx, QueryRequest{Query: "how does replication work", IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*requests) != 1 || first.Cached || !second.Cached {
+		t.Fatalf("expected the second query answered from the cache, got %d completions", len(*requests))
+	}
+	if second.Answer != first.Answer || len(second.Sources) != len(first.Sources) || second.TokensUsed != 0 {
+		t.Errorf("expected the cached answer and sources without tokens, got %+v", second)
+	}
+
+	// A different filter, conversation history or sources setting is
+	// answered afresh.
+	for _, req := range []QueryRequest{
+		{Query: "How does replication work?", IncludeSources: true,
+			Filter: &config.Filter{Conditions: []config.FilterCondition{{Column: "product", Operator: "=", Value: "pgEdge"}}}},
+		{Query: "How does replication work?", IncludeSources: true,
+			Messages: []Message{{Role: "user", Content: "Hi"}, {Role: "assistant", Content: "Hello"}}},
+		{Query: "How does replication work?"},
+	} {
+		resp, err := orch.Execute(ctx, req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.Cached {
+			t.Errorf("expected %+v not to be answered from the cache", req)
+		}
+	}
+
+	// Uploading documents clears the cache.
+	orch.store = &MockChunkStore{}
+	orch.cfg.Ingest = config.IngestConfig{Enabled: true, Table: "docs"}
+	if _, err := orch.Ingest(ctx, &ingest.Document{Filename: "faq.md",
+		Pages: []ingest.Page{{Text: "Replication now uses slots."}}}, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp, err := orch.Execute(ctx, QueryRequest{Query: "How does replication work?", IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Cached {
+		t.Error("expected the cache to be cleared by the upload")
+	}
+}
+
+func TestOrchestrator_ExecuteStream_AnswerCache(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	orch.answerCache = newAnswerCache(config.AnswerCacheConfig{Enabled: true})
+	completer := orch.completionProv.(*MockCompleter)
+	streams := 0
+	startStream := completer.ChatStreamFunc
+	completer.ChatStreamFunc = func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+		streams++
+		return startStream(ctx, req)
+	}
+
+	stream := func() (string, []Source, bool) {
+		chunks, errs := orch.ExecuteStream(context.Background(),
+			QueryRequest{Query: "How does replication work?", IncludeSources: true})
+		var answer string
+		var sources []Source
+		cached := false
+		for chunk := range chunks {
+			answer += chunk.Content
+			if chunk.Sources != nil {
+				sources = chunk.Sources
+			}
+			cached = cached || chunk.Cached
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return answer, sources, cached
+	}
+
+	answer, sources, cached := stream()
+	if cached || answer != "WAL is streamed to the standby." {
+		t.Fatalf("expected a fresh answer, got %q (cached %v)", answer, cached)
+	}
+	again, againSources, cached := stream()
+	if !cached || again != answer || len(againSources) != len(sources) {
+		t.Errorf("expected the cached answer and sources, got %q, %d sources (cached %v)", again, len(againSources), cached)
+	}
+	if streams != 1 {
+		t.Errorf("expected one completion stream, got %d", streams)
+	}
+}
@@ -0,0 +1,157 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// documentSummaryPrompt asks the completion LLM to summarize a document
+// too long for the token budget, keeping what bears on the question.
+const documentSummaryPrompt = `Summarize the document below so it can answer the question that follows it.
+Keep the facts, figures, names and wording the question might depend on.
+Reply with the summary only.`
+
+// fitBudget returns the results to build the context from. When the top
+// result alone is over the token budget, the pipeline's budget_overflow
+// decides what happens: by default buildContext truncates it; with
+// summarize_first it is replaced by a summary, or truncated if that
+// fails; with error the query is rejected. The results themselves are
+// left as they are, for the sources and citations.
+func (o *Orchestrator) fitBudget(ctx context.Context, query string, results []database.SearchResult, usage *StageUsage) ([]database.SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+	tokens := o.counter().Count(results[0].Content)
+	if tokens <= o.tokenBudget {
+		return results, nil
+	}
+
+	switch o.cfg.BudgetOverflow {
+	case config.BudgetOverflowError:
+		return nil, budgetError(tokens, o.tokenBudget)
+	case config.BudgetOverflowSummarize:
+		summary := o.summarizeDocument(ctx, query, results[0].Content, usage)
+		if summary == "" {
+			break
+		}
+		fitted := append([]database.SearchResult(nil), results...)
+		fitted[0].Content = summary
+		return fitted, nil
+	}
+	o.logger.Debug("top document exceeds token_budget, truncating it",
+		"tokens", tokens, "token_budget", o.tokenBudget)
+	return results, nil
+}
+
+// budgetError reports a top document of the given estimated size that
+// does not fit in the token budget.
+func budgetError(tokens, budget int) error {
+	return fmt.Errorf("%w: the top document is about %d tokens, over the pipeline's token_budget of %d",
+		ErrInvalidRequest, tokens, budget)
+}
+
+// contextFor builds the context documents from fitted, the results as
+// fitBudget returned them, and returns them with the results they were
+// built from as retrieved, so a summarized top document is reported as
+// the document it summarizes.
+func (o *Orchestrator) contextFor(results, fitted []database.SearchResult) ([]ragllm.ContextDoc, []database.SearchResult) {
+	docs, kept := o.buildContext(fitted)
+	if len(kept) > 0 {
+		kept = slices.Clone(kept)
+		kept[0] = results[0]
+	}
+	return docs, kept
+}
+
+// dropLowestScoring leaves out results, lowest-scoring first and the
+// later of equal scores first, until those left fit in the token budget
+// with their token counts. The first result is never dropped; if it
+// alone is over the budget, buildContext truncates it. The results
+// left keep their order.
+func (o *Orchestrator) dropLowestScoring(results []database.SearchResult, counts []int) ([]database.SearchResult, []int) {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total <= o.tokenBudget {
+		return results, counts
+	}
+
+	order := make([]int, 0, len(results))
+	for i := len(results) - 1; i > 0; i-- {
+		order = append(order, i)
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return results[order[a]].Score < results[order[b]].Score
+	})
+	dropped := make([]bool, len(results))
+	n := 0
+	for _, i := range order {
+		if total <= o.tokenBudget {
+			break
+		}
+		dropped[i] = true
+		total -= counts[i]
+		n++
+	}
+
+	kept := make([]database.SearchResult, 0, len(results)-n)
+	keptCounts := make([]int, 0, len(results)-n)
+	for i, r := range results {
+		if !dropped[i] {
+			kept = append(kept, r)
+			keptCounts = append(keptCounts, counts[i])
+		}
+	}
+	o.logger.Debug("dropped lowest-scoring documents to fit token_budget",
+		"dropped", n, "token_budget", o.tokenBudget)
+	return kept, keptCounts
+}
+
+// summarizeDocument asks the completion provider to summarize content
+// within the token budget, bounded by the pipeline's
+// completion_timeout, and records the tokens it used. It returns "" if
+// no summary was produced.
+func (o *Orchestrator) summarizeDocument(ctx context.Context, query, content string, usage *StageUsage) string {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: documentSummaryPrompt,
+		Messages: []llmlib.Message{
+			llmlib.UserText("Document:\n" + content + "\n\nQuestion: " + query),
+		},
+		MaxTokens: llmlib.Int(o.tokenBudget),
+	})
+	o.observeStage(metrics.StageContextSummary, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("summarizing the top document failed, truncating it",
+			"error", stageTimeout(ctx, err))
+		return ""
+	}
+	usage.ContextSummary = &resp.Usage
+	o.recordUsage(metrics.StageContextSummary, o.completionProvider(), resp.Usage)
+
+	return strings.TrimSpace(joinTextBlocks(resp.Content))
+}
@@ -0,0 +1,269 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// longDocument is about 200 estimated tokens, over the 50 token budget
+// of newBudgetOrchestrator.
+var longDocument = "Replication starts here. " + strings.Repeat("WAL segments are shipped. ", 31)
+
+func newBudgetOrchestrator(completer *MockCompleter, overflow string) *Orchestrator {
+	return newTestOrchestrator([]database.SearchResult{
+		{ID: "doc-1", Content: longDocument, Score: 0.9},
+		{ID: "doc-2", Content: "Second document", Score: 0.8},
+	}, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.BudgetOverflow = overflow
+		cfg.CompletionProv = completer
+		cfg.TokenBudget = 50
+	})
+}
+
+func TestBuildContext_FirstDocumentOverBudget(t *testing.T) {
+	orch := &Orchestrator{tokenBudget: 20}
+	docs, _ := orch.buildContext([]database.SearchResult{
+		{Content: longDocument, Score: 0.9},
+		{Content: "Second document", Score: 0.8},
+	})
+	if len(docs) != 1 {
+		t.Fatalf("expected only the first document, got %d", len(docs))
+	}
+	if !strings.HasPrefix(docs[0].Content, "Replication starts here.") || len(docs[0].Content) > 20*4+3 {
+		t.Errorf("expected the first document truncated to the budget, got %q", docs[0].Content)
+	}
+}
+
+func TestBuildContext_TruncatesAtBoundary(t *testing.T) {
+	first := strings.TrimSpace(strings.Repeat("WAL segments are shipped. ", 3))
+	content := first + "\n\n" + strings.Repeat("Standbys replay them. ", 10)
+
+	tests := []struct {
+		name   string
+		budget int
+		want   string
+	}{
+		{"at a paragraph", 25, first + "..."},
+		{"at a sentence", 40, first + "\n\n" + strings.TrimSpace(strings.Repeat("Standbys replay them. ", 3)) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{tokenBudget: tt.budget}
+			docs, _ := orch.buildContext([]database.SearchResult{{Content: content, Score: 0.9}})
+			if len(docs) != 1 || docs[0].Content != tt.want {
+				t.Errorf("got %+v, want %q", docs, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildContext_DropLowestScoring(t *testing.T) {
+	results := []database.SearchResult{
+		{Content: strings.Repeat("a", 40), Score: 0.9},
+		{Content: strings.Repeat("b", 80), Score: 0.5},
+		{Content: strings.Repeat("c", 60), Score: 0.8},
+	}
+
+	for _, tt := range []struct {
+		drop bool
+		want string // the first letter of each document in the context
+	}{
+		{false, "ab"},
+		{true, "ac"},
+	} {
+		pCfg := config.Pipeline{Tokenizer: config.TokenizerEstimate, DropLowestScoring: tt.drop}
+		orch := NewOrchestrator(OrchestratorConfig{Pipeline: &pCfg, TokenBudget: 30})
+		var got strings.Builder
+		docs, _ := orch.buildContext(results)
+		for _, d := range docs {
+			if strings.HasSuffix(d.Content, "...") {
+				t.Errorf("drop_lowest_scoring=%v: unexpected truncated document %q", tt.drop, d.Content)
+			}
+			got.WriteByte(d.Content[0])
+		}
+		if got.String() != tt.want {
+			t.Errorf("drop_lowest_scoring=%v: got documents %s, want %s", tt.drop, got.String(), tt.want)
+		}
+	}
+}
+
+// TestOrchestrator_Execute_DropLowestScoringCitations verifies that
+// when a middle document is dropped, citation markers, provenance and
+// the captured sources follow the documents that were in the context.
+func TestOrchestrator_Execute_DropLowestScoringCitations(t *testing.T) {
+	citations := true
+	orch := newTestOrchestrator([]database.SearchResult{
+		{ID: "doc-1", Content: strings.Repeat("a", 40), Score: 0.9},
+		{ID: "doc-2", Content: strings.Repeat("b", 80), Score: 0.5},
+		{ID: "doc-3", Content: strings.Repeat("c", 60), Score: 0.8},
+	}, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Tokenizer = config.TokenizerEstimate
+		p.DropLowestScoring = true
+		p.Citations = &citations
+		cfg.CompletionProv = &MockCompleter{
+			ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+				return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{
+					{Type: llmlib.BlockText, Text: "Both apply [1][2]."}}}, nil
+			},
+		}
+		cfg.TokenBudget = 30
+	})
+	orch.evalCapture = newEvalCapture(config.EvalCaptureConfig{Enabled: true, SampleRate: 1})
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "q"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Citations) != 2 || resp.Citations[0].ID != "doc-1" || resp.Citations[1].ID != "doc-3" {
+		t.Errorf("expected [1] and [2] to cite doc-1 and doc-3, got %+v", resp.Citations)
+	}
+	if len(resp.Provenance) != 2 || resp.Provenance[1].ID != "doc-3" {
+		t.Errorf("expected the provenance of doc-1 and doc-3, got %+v", resp.Provenance)
+	}
+	if cases := orch.EvalDataset(); len(cases) != 1 || strings.Join(cases[0].ExpectedSources, ",") != "doc-1,doc-3" {
+		t.Errorf("expected doc-1 and doc-3 captured as the sources, got %+v", cases)
+	}
+}
+
+func TestBuildContext_CountsWithTokenizer(t *testing.T) {
+	pCfg := config.Pipeline{RAGLLM: config.LLMConfig{Provider: "openai", Model: "gpt-4o"}}
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &pCfg, TokenBudget: 20})
+	docs, _ := orch.buildContext([]database.SearchResult{
+		{Content: longDocument, Score: 0.9},
+		{Content: "Second document", Score: 0.8},
+	})
+	if len(docs) != 1 {
+		t.Fatalf("expected only the first document, got %d", len(docs))
+	}
+	text := strings.TrimSuffix(docs[0].Content, "...")
+	if n := orch.counter().Count(text); n == 0 || n > 20 || !strings.HasPrefix(longDocument, text) {
+		t.Errorf("expected the first document truncated to 20 tokens, got %d tokens: %q", n, text)
+	}
+}
+
+func TestOrchestrator_Execute_TruncatesTopDocument(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newBudgetOrchestrator(completer, "")
+
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected only the answer, got %d requests", len(requests))
+	}
+	text := requests[0].SystemPrompt
+	if !strings.Contains(text, "Replication starts here.") || strings.Contains(text, longDocument) {
+		t.Errorf("expected the top document truncated in the context, got %q", text)
+	}
+}
+
+func TestOrchestrator_Execute_SummarizesTopDocument(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			text := "answer"
+			if req.SystemPrompt == documentSummaryPrompt {
+				text = "WAL segments are shipped to replicas."
+			}
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}},
+				Usage:   llmlib.TokenUsage{PromptTokens: 210, CompletionTokens: 9, TotalTokens: 219},
+			}, nil
+		},
+	}
+	orch := newBudgetOrchestrator(completer, config.BudgetOverflowSummarize)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?", IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected a summary and an answer, got %d requests", len(requests))
+	}
+	prompt := requests[0].Messages[0].Content[0].Text
+	if !strings.Contains(prompt, longDocument) || !strings.Contains(prompt, "Question: how does replication work?") {
+		t.Errorf("expected the document and the question in the summary request, got %q", prompt)
+	}
+	if max := requests[0].MaxTokens; max == nil || *max != 50 {
+		t.Errorf("expected the summary bounded by the token budget, got %v", max)
+	}
+	if text := requests[1].SystemPrompt; !strings.Contains(text, "WAL segments are shipped to replicas.") {
+		t.Errorf("expected the summary in the context, got %q", text)
+	}
+	if u := resp.Usage.ContextSummary; u == nil || u.TotalTokens != 219 {
+		t.Errorf("expected the summary's usage, got %+v", u)
+	}
+	if len(resp.Sources) == 0 || resp.Sources[0].Content != longDocument {
+		t.Errorf("expected the sources to keep the original document, got %+v", resp.Sources)
+	}
+	if len(resp.Provenance) == 0 || resp.Provenance[0].ContentHash != contentHash(longDocument) {
+		t.Errorf("expected the provenance to hash the original document, got %+v", resp.Provenance)
+	}
+}
+
+func TestOrchestrator_Execute_SummaryFailureTruncatesTopDocument(t *testing.T) {
+	var answer llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if req.SystemPrompt == documentSummaryPrompt {
+				return nil, errors.New("provider unavailable")
+			}
+			answer = req
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newBudgetOrchestrator(completer, config.BudgetOverflowSummarize)
+
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?"}); err != nil {
+		t.Fatalf("expected the query to succeed without a summary, got %v", err)
+	}
+	if text := answer.SystemPrompt; !strings.Contains(text, "Replication starts here.") {
+		t.Errorf("expected the top document truncated in the context, got %q", text)
+	}
+}
+
+func TestOrchestrator_Execute_BudgetOverflowError(t *testing.T) {
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			t.Error("expected no completion")
+			return nil, errors.New("unexpected")
+		},
+	}
+	orch := newBudgetOrchestrator(completer, config.BudgetOverflowError)
+
+	_, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?"})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expected an invalid request error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "token_budget of 50") {
+		t.Errorf("expected the error to name the token budget, got %q", err)
+	}
+
+	if _, err := orch.Estimate(context.Background(), QueryRequest{Query: "how does replication work?"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected the estimate to fail too, got %v", err)
+	}
+}
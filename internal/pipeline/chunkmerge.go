@@ -0,0 +1,154 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// mergeChunks joins results that are chunks of the same document, in
+// the same table, with consecutive indices, as chunk_merge's columns
+// give them, into one result per run. A merged result takes the rank and score of its best
+// chunk, and the ID and metadata of its first. Results without both
+// columns are left as they are.
+func (o *Orchestrator) mergeChunks(results []database.SearchResult) []database.SearchResult {
+	cm := o.cfg.ChunkMerge
+	if !cm.Enabled || len(results) < 2 {
+		return results
+	}
+
+	type chunk struct {
+		rank  int
+		index int64
+	}
+	byDocument := make(map[string][]chunk)
+	for i, r := range results {
+		doc, ok := r.SourceInfo[cm.DocumentColumn]
+		if !ok || doc == nil {
+			continue
+		}
+		index, ok := chunkIndex(r.SourceInfo[cm.IndexColumn])
+		if !ok {
+			continue
+		}
+		key := r.Table + "\x00" + fmt.Sprint(doc)
+		byDocument[key] = append(byDocument[key], chunk{rank: i, index: index})
+	}
+
+	// runs maps the best ranked chunk of each run of two or more to the
+	// ranks of the run's chunks in document order; skip marks the
+	// others.
+	runs := make(map[int][]int)
+	skip := make(map[int]bool)
+	for _, chunks := range byDocument {
+		sort.Slice(chunks, func(i, j int) bool { return chunks[i].index < chunks[j].index })
+		for start := 0; start < len(chunks); {
+			end := start + 1
+			for end < len(chunks) && chunks[end].index == chunks[end-1].index+1 {
+				end++
+			}
+			if end-start > 1 {
+				ranks := make([]int, 0, end-start)
+				best := chunks[start].rank
+				for _, c := range chunks[start:end] {
+					ranks = append(ranks, c.rank)
+					skip[c.rank] = true
+					best = min(best, c.rank)
+				}
+				runs[best] = ranks
+				delete(skip, best)
+			}
+			start = end
+		}
+	}
+	if len(runs) == 0 {
+		return results
+	}
+
+	merged := make([]database.SearchResult, 0, len(results)-len(skip))
+	for i, r := range results {
+		if skip[i] {
+			continue
+		}
+		if ranks, ok := runs[i]; ok {
+			first := results[ranks[0]]
+			content := first.Content
+			for _, rank := range ranks[1:] {
+				content = joinChunks(content, results[rank].Content)
+			}
+			r = database.SearchResult{
+				ID:         first.ID,
+				Content:    content,
+				Score:      r.Score,
+				SourceInfo: first.SourceInfo,
+				Table:      first.Table,
+			}
+		}
+		merged = append(merged, r)
+	}
+	o.logger.Debug("merged adjacent chunks", "results", len(results), "merged", len(merged))
+	return merged
+}
+
+// chunkIndex reads a chunk's position from the value of its index
+// column.
+func chunkIndex(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		if v != math.Trunc(v) {
+			return 0, false
+		}
+		return int64(v), true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// joinChunks appends b to a, leaving out the start of b that repeats
+// the end of a, as overlapping chunks do. Only an overlap of whole
+// words counts, so a chance match of a few letters is kept.
+func joinChunks(a, b string) string {
+	for n := min(len(a), len(b)); n > 0; n-- {
+		if !strings.HasSuffix(a, b[:n]) {
+			continue
+		}
+		startsWord := n == len(a) || isSpace(a[len(a)-n-1])
+		endsWord := n == len(b) || isSpace(b[n])
+		if startsWord && endsWord {
+			return a + b[n:]
+		}
+	}
+	return a + " " + b
+}
+
+func isSpace(b byte) bool {
+	return unicode.IsSpace(rune(b))
+}
@@ -0,0 +1,153 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func chunkResult(id string, doc interface{}, index interface{}, content string, score float64) database.SearchResult {
+	return database.SearchResult{
+		ID:         id,
+		Content:    content,
+		Score:      score,
+		SourceInfo: map[string]interface{}{"source_id": doc, "chunk_index": index},
+	}
+}
+
+// inTable returns r as found in table.
+func inTable(r database.SearchResult, table string) database.SearchResult {
+	r.Table = table
+	return r
+}
+
+func TestMergeChunks(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		results []database.SearchResult
+		want    []database.SearchResult
+	}{
+		{
+			name:    "disabled",
+			enabled: false,
+			results: []database.SearchResult{
+				chunkResult("a1", 1, int32(1), "Second part.", 0.9),
+				chunkResult("a0", 1, int32(0), "First part.", 0.8),
+			},
+			want: []database.SearchResult{
+				chunkResult("a1", 1, int32(1), "Second part.", 0.9),
+				chunkResult("a0", 1, int32(0), "First part.", 0.8),
+			},
+		},
+		{
+			name:    "adjacent chunks merge at the best rank",
+			enabled: true,
+			results: []database.SearchResult{
+				chunkResult("b0", 2, int32(0), "Other document.", 0.95),
+				chunkResult("a1", 1, int32(1), "Second part.", 0.9),
+				chunkResult("a0", 1, int32(0), "First part.", 0.8),
+			},
+			want: []database.SearchResult{
+				chunkResult("b0", 2, int32(0), "Other document.", 0.95),
+				chunkResult("a0", 1, int32(0), "First part. Second part.", 0.9),
+			},
+		},
+		{
+			name:    "overlap is removed",
+			enabled: true,
+			results: []database.SearchResult{
+				chunkResult("a0", 1, int64(0), "WAL is written first. Then it is shipped", 0.9),
+				chunkResult("a1", 1, int64(1), "Then it is shipped to replicas.", 0.8),
+			},
+			want: []database.SearchResult{
+				chunkResult("a0", 1, int64(0), "WAL is written first. Then it is shipped to replicas.", 0.9),
+			},
+		},
+		{
+			name:    "gaps and other documents are kept apart",
+			enabled: true,
+			results: []database.SearchResult{
+				chunkResult("a0", 1, 0.0, "Zero.", 0.9),
+				chunkResult("a2", 1, 2.0, "Two.", 0.8),
+				chunkResult("b1", 2, 1.0, "Other.", 0.7),
+			},
+			want: []database.SearchResult{
+				chunkResult("a0", 1, 0.0, "Zero.", 0.9),
+				chunkResult("a2", 1, 2.0, "Two.", 0.8),
+				chunkResult("b1", 2, 1.0, "Other.", 0.7),
+			},
+		},
+		{
+			name:    "chunks of other tables are kept apart",
+			enabled: true,
+			results: []database.SearchResult{
+				inTable(chunkResult("a0", 1, int32(0), "Table A.", 0.9), "chunks_a"),
+				inTable(chunkResult("b1", 1, int32(1), "Table B.", 0.8), "chunks_b"),
+				inTable(chunkResult("b0", 1, int32(0), "Also table B.", 0.7), "chunks_b"),
+			},
+			want: []database.SearchResult{
+				inTable(chunkResult("a0", 1, int32(0), "Table A.", 0.9), "chunks_a"),
+				inTable(chunkResult("b0", 1, int32(0), "Also table B. Table B.", 0.8), "chunks_b"),
+			},
+		},
+		{
+			name:    "chunks without an index are kept",
+			enabled: true,
+			results: []database.SearchResult{
+				chunkResult("a0", 1, nil, "Zero.", 0.9),
+				chunkResult("a1", 1, "1", "One.", 0.8),
+			},
+			want: []database.SearchResult{
+				chunkResult("a0", 1, nil, "Zero.", 0.9),
+				chunkResult("a1", 1, "1", "One.", 0.8),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := NewOrchestrator(OrchestratorConfig{
+				Pipeline: &config.Pipeline{
+					Name: "docs",
+					ChunkMerge: config.ChunkMergeConfig{
+						Enabled:        tt.enabled,
+						DocumentColumn: "source_id",
+						IndexColumn:    "chunk_index",
+					},
+				},
+			})
+			got := orch.mergeChunks(tt.results)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJoinChunks(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"one two three", "two three four", "one two three four"},
+		{"one two", "three", "one two three"},
+		{"the cat", "cathedral", "the cat cathedral"},
+		{"same", "same", "same"},
+	}
+	for _, tt := range tests {
+		if got := joinChunks(tt.a, tt.b); got != tt.want {
+			t.Errorf("joinChunks(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// citationInstructions is added to the system prompt in citations mode.
+// The numbers match the "--- Document N ---" headers of the context.
+const citationInstructions = `Cite the context documents that support each statement by their document number in square brackets, such as [1] or [2][3], placed directly after the statement.
+Only cite document numbers that appear in the context.`
+
+// citationMarker matches [1], [2][3] (as two matches) and [1, 2].
+var citationMarker = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// citationsEnabled reports whether the pipeline runs in citations mode.
+func (o *Orchestrator) citationsEnabled() bool {
+	return o.cfg != nil && o.cfg.Citations != nil && *o.cfg.Citations
+}
+
+// extractCitations maps the citation markers in answer to the results
+// the numbered context documents were built from, in order of first
+// citation. Context documents are a prefix of results, so marker n
+// refers to results[n-1]; markers beyond the numDocs documents the
+// model was shown are returned in unknown instead. Markers in code, or
+// directly after an identifier as in "a[1]", are not citations.
+func extractCitations(
+	answer string,
+	results []database.SearchResult,
+	numDocs int,
+) (citations []Citation, unknown []int) {
+	text := codePattern.ReplaceAllString(answer, " ")
+	numDocs = min(numDocs, len(results))
+
+	seen := make(map[int]bool)
+	for _, loc := range citationMarker.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > 0 && isIdentifierByte(text[loc[0]-1]) {
+			continue
+		}
+		for _, field := range strings.Split(text[loc[2]:loc[3]], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil || seen[n] {
+				continue
+			}
+			seen[n] = true
+			if n < 1 || n > numDocs {
+				unknown = append(unknown, n)
+				continue
+			}
+			r := results[n-1]
+			citations = append(citations, Citation{
+				Marker:   n,
+				ID:       r.ID,
+				Score:    r.Score,
+				Metadata: r.SourceInfo,
+			})
+		}
+	}
+	return citations, unknown
+}
+
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// citations returns the answer's citations in citations mode, logging
+// any markers that do not match a context document, or nil otherwise.
+func (o *Orchestrator) citations(
+	answer string,
+	results []database.SearchResult,
+	numDocs int,
+) []Citation {
+	if !o.citationsEnabled() {
+		return nil
+	}
+	citations, unknown := extractCitations(answer, results, numDocs)
+	if len(unknown) > 0 {
+		o.logger.Info("answer cites documents that were not in the context",
+			"markers", unknown, "documents", numDocs)
+	}
+	return citations
+}
@@ -0,0 +1,85 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func TestExtractCitations(t *testing.T) {
+	results := []database.SearchResult{
+		{ID: "a", Score: 0.9, SourceInfo: map[string]interface{}{"url": "https://example.com/a"}},
+		{ID: "b", Score: 0.8},
+		{ID: "c", Score: 0.7},
+	}
+
+	tests := []struct {
+		name        string
+		answer      string
+		numDocs     int
+		wantMarkers []int
+		wantUnknown []int
+	}{
+		{
+			name:    "no markers",
+			answer:  "PostgreSQL is a database.",
+			numDocs: 3,
+		},
+		{
+			name:        "order of first citation without duplicates",
+			answer:      "Replication is logical [2]. It needs a slot [1][2].",
+			numDocs:     3,
+			wantMarkers: []int{2, 1},
+		},
+		{
+			name:        "comma lists",
+			answer:      "Both are supported [1, 3].",
+			numDocs:     3,
+			wantMarkers: []int{1, 3},
+		},
+		{
+			name:        "markers beyond the context",
+			answer:      "See [3] and [0]; also [2].",
+			numDocs:     2,
+			wantMarkers: []int{2},
+			wantUnknown: []int{3, 0},
+		},
+		{
+			name:        "code and indexing are not citations",
+			answer:      "Use `arr[1]` or items[2] as shown [3].\n```\nx = y[1]\n```",
+			numDocs:     3,
+			wantMarkers: []int{3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			citations, unknown := extractCitations(tt.answer, results, tt.numDocs)
+
+			var markers []int
+			for _, c := range citations {
+				markers = append(markers, c.Marker)
+				r := results[c.Marker-1]
+				if c.ID != r.ID || c.Score != r.Score || !reflect.DeepEqual(c.Metadata, r.SourceInfo) {
+					t.Errorf("citation [%d] = %+v, does not match result %+v", c.Marker, c, r)
+				}
+			}
+			if !reflect.DeepEqual(markers, tt.wantMarkers) {
+				t.Errorf("markers = %v, want %v", markers, tt.wantMarkers)
+			}
+			if !reflect.DeepEqual(unknown, tt.wantUnknown) {
+				t.Errorf("unknown = %v, want %v", unknown, tt.wantUnknown)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// Budget periods, as reported by BudgetExceededError.
+const (
+	BudgetPeriodDay   = "daily"
+	BudgetPeriodMonth = "monthly"
+)
+
+// ErrBudgetExceeded is returned (wrapped, by BudgetExceededError) when a
+// pipeline has spent its budget for the day or month.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// BudgetExceededError reports which of a pipeline's budgets its queries
+// have spent, and when the period ends. It wraps ErrBudgetExceeded.
+type BudgetExceededError struct {
+	Period string // BudgetPeriodDay or BudgetPeriodMonth
+	Budget float64
+	Resets time.Time
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("%s: the pipeline's %s budget of %g is spent until %s",
+		ErrBudgetExceeded, e.Period, e.Budget, e.Resets.Format(time.RFC3339))
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// Cost reports what a pipeline's queries have cost, as estimated from
+// its models' pricing: in total since the server started, and in the
+// current day and month, in UTC.
+type Cost struct {
+	Total         float64 `json:"total"`
+	Today         float64 `json:"today"`
+	ThisMonth     float64 `json:"this_month"`
+	DailyBudget   float64 `json:"daily_budget,omitempty"`
+	MonthlyBudget float64 `json:"monthly_budget,omitempty"`
+}
+
+// CostLedger accumulates the estimated cost of every pipeline's
+// queries, by pipeline name. Like the metrics registry, one ledger is
+// passed to each Manager so totals, and budgets, carry across
+// configuration reloads; they do not survive a restart. It is safe for
+// concurrent use.
+type CostLedger struct {
+	mu    sync.Mutex
+	costs map[string]*pipelineCost
+	now   func() time.Time
+}
+
+// pipelineCost is one pipeline's entry in a CostLedger.
+type pipelineCost struct {
+	total, day, month  float64
+	dayStart, monthEnd time.Time
+}
+
+// NewCostLedger returns an empty ledger.
+func NewCostLedger() *CostLedger {
+	return &CostLedger{costs: make(map[string]*pipelineCost), now: time.Now}
+}
+
+// entry returns a pipeline's entry, with the day's and month's costs
+// reset once their period has ended. The caller must hold l.mu.
+func (l *CostLedger) entry(name string) *pipelineCost {
+	c, ok := l.costs[name]
+	if !ok {
+		c = &pipelineCost{}
+		l.costs[name] = c
+	}
+	now := l.now().UTC()
+	if day := now.Truncate(24 * time.Hour); day.After(c.dayStart) {
+		c.day, c.dayStart = 0, day
+	}
+	if !now.Before(c.monthEnd) {
+		c.month = 0
+		c.monthEnd = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return c
+}
+
+// add records the cost of a query.
+func (l *CostLedger) add(name string, cost float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.entry(name)
+	c.total += cost
+	c.day += cost
+	c.month += cost
+}
+
+// check returns a BudgetExceededError when a pipeline has spent either
+// of its budgets.
+func (l *CostLedger) check(name string, budget config.BudgetConfig) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.entry(name)
+	if budget.Daily > 0 && c.day >= budget.Daily {
+		return &BudgetExceededError{Period: BudgetPeriodDay, Budget: budget.Daily,
+			Resets: c.dayStart.Add(24 * time.Hour)}
+	}
+	if budget.Monthly > 0 && c.month >= budget.Monthly {
+		return &BudgetExceededError{Period: BudgetPeriodMonth, Budget: budget.Monthly,
+			Resets: c.monthEnd}
+	}
+	return nil
+}
+
+// Cost returns what a pipeline's queries have cost.
+func (l *CostLedger) Cost(name string) Cost {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c := l.entry(name)
+	return Cost{Total: c.total, Today: c.day, ThisMonth: c.month}
+}
+
+// cost returns what the pipeline's queries have cost, with its budgets,
+// or nil when costs are not tracked or none of its models is priced.
+func (o *Orchestrator) cost() *Cost {
+	if o.costs == nil || !(o.cfg.EmbeddingLLM.Pricing.Priced() ||
+		o.cfg.RAGLLM.Pricing.Priced() || o.cfg.Rerank.Pricing.Priced()) {
+		return nil
+	}
+	c := o.costs.Cost(o.cfg.Name)
+	c.DailyBudget = o.cfg.Budget.Daily
+	c.MonthlyBudget = o.cfg.Budget.Monthly
+	return &c
+}
+
+// checkBudget refuses a query once the pipeline has spent its budget.
+func (o *Orchestrator) checkBudget() error {
+	if o.costs == nil || !o.cfg.Budget.Enabled() {
+		return nil
+	}
+	return o.costs.check(o.cfg.Name, o.cfg.Budget)
+}
+
+// chargeCost prices a query's usage with the pipeline's models'
+// pricing, sets it as the usage's cost and adds it to the ledger. The
+// completion, and the query expansion, summaries and groundedness
+// check the completion model runs, are priced as rag_llm, even when a
+// fallback answered.
+// Usage with no price set is left without a cost.
+func (o *Orchestrator) chargeCost(usage *StageUsage) {
+	var total float64
+	priced := false
+	add := func(u llmlib.TokenUsage, p config.PricingConfig) {
+		if !p.Priced() {
+			return
+		}
+		priced = true
+		total += float64(u.PromptTokens)/1e6*p.InputPerMillion +
+			float64(u.CompletionTokens)/1e6*p.OutputPerMillion
+	}
+	add(usage.Embedding, o.cfg.EmbeddingLLM.Pricing)
+	add(usage.Completion, o.cfg.RAGLLM.Pricing)
+	for _, stage := range []*llmlib.TokenUsage{usage.QueryExpansion, usage.HistorySummary, usage.ContextSummary, usage.Groundedness} {
+		if stage != nil {
+			add(*stage, o.cfg.RAGLLM.Pricing)
+		}
+	}
+	if usage.Rerank != nil {
+		add(*usage.Rerank, o.cfg.Rerank.Pricing)
+	}
+	if !priced {
+		return
+	}
+	usage.Cost = &total
+	if o.costs != nil {
+		o.costs.add(o.cfg.Name, total)
+	}
+}
@@ -0,0 +1,102 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestCostLedger_Periods(t *testing.T) {
+	ledger := NewCostLedger()
+	now := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	ledger.now = func() time.Time { return now }
+	budget := config.BudgetConfig{Daily: 1, Monthly: 1.5}
+
+	ledger.add("docs", 0.75)
+	if err := ledger.check("docs", budget); err != nil {
+		t.Fatalf("expected the budget not to be spent, got %v", err)
+	}
+	ledger.add("docs", 0.25)
+	var budgetErr *BudgetExceededError
+	if err := ledger.check("docs", budget); !errors.As(err, &budgetErr) || !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected a BudgetExceededError, got %v", err)
+	}
+	if budgetErr.Period != BudgetPeriodDay || !budgetErr.Resets.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected budget error: %+v", budgetErr)
+	}
+	if err := ledger.check("other", budget); err != nil {
+		t.Errorf("expected another pipeline's budget to be separate, got %v", err)
+	}
+
+	// A new day and month reset both periods, but not the total.
+	now = now.Add(2 * time.Hour)
+	ledger.add("docs", 0.5)
+	if c := ledger.Cost("docs"); c.Total != 1.5 || c.Today != 0.5 || c.ThisMonth != 0.5 {
+		t.Errorf("unexpected costs after the month ended: %+v", c)
+	}
+
+	// The monthly budget outlasts the day.
+	ledger.add("docs", 0.9)
+	now = now.Add(24 * time.Hour)
+	ledger.add("docs", 0.1)
+	if err := ledger.check("docs", budget); !errors.As(err, &budgetErr) ||
+		budgetErr.Period != BudgetPeriodMonth ||
+		!budgetErr.Resets.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the monthly budget to be spent, got %v", err)
+	}
+}
+
+func TestOrchestrator_ChargeCost(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.costs = NewCostLedger()
+	orch.cfg.EmbeddingLLM.Pricing = config.PricingConfig{InputPerMillion: 0.02}
+	orch.cfg.RAGLLM.Pricing = config.PricingConfig{InputPerMillion: 2, OutputPerMillion: 10}
+	orch.cfg.Budget = config.BudgetConfig{Daily: 0.009}
+
+	usage := &StageUsage{
+		Embedding:      llmlib.TokenUsage{PromptTokens: 1000},
+		QueryExpansion: &llmlib.TokenUsage{PromptTokens: 500, CompletionTokens: 100},
+		Rerank:         &llmlib.TokenUsage{PromptTokens: 4000},
+		Completion:     llmlib.TokenUsage{PromptTokens: 2000, CompletionTokens: 300},
+	}
+	orch.chargeCost(usage)
+	// Reranking is not priced, so only the other stages count.
+	want := 1000*0.02/1e6 + (500+2000)*2/1e6 + (100+300)*10/1e6
+	if usage.Cost == nil || *usage.Cost != want {
+		t.Fatalf("cost = %v, want %v", usage.Cost, want)
+	}
+	if c := orch.cost(); c == nil || c.Total != want || c.DailyBudget != 0.009 {
+		t.Errorf("unexpected pipeline cost: %+v", c)
+	}
+
+	_, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "streaming standby"})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected retrieval to be refused over budget, got %v", err)
+	}
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "streaming standby"}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("expected a query to be refused over budget, got %v", err)
+	}
+
+	unpriced := &StageUsage{Embedding: llmlib.TokenUsage{PromptTokens: 10}}
+	orch.cfg.EmbeddingLLM.Pricing = config.PricingConfig{}
+	orch.cfg.RAGLLM.Pricing = config.PricingConfig{}
+	orch.chargeCost(unpriced)
+	if unpriced.Cost != nil {
+		t.Errorf("expected no cost without pricing, got %v", *unpriced.Cost)
+	}
+}
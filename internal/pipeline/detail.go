@@ -0,0 +1,176 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"maps"
+	"net/url"
+	"slices"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// Detail is a pipeline's effective configuration, with defaults
+// applied, as clients may see it. Secrets are left out: API keys and
+// the database connection entirely, header values and raw SQL filters
+// by reporting only that they are set, and credentials in base URLs by
+// redacting them.
+type Detail struct {
+	Name            string        `json:"name"`
+	Description     string        `json:"description"`
+	EmbeddingLLM    ModelDetail   `json:"embedding_llm"`
+	RAGLLM          ModelDetail   `json:"rag_llm"`
+	RAGLLMFallbacks []ModelDetail `json:"rag_llm_fallbacks,omitempty"`
+	TopN            int           `json:"top_n"`
+	TokenBudget     int           `json:"token_budget"`
+	Search          SearchDetail  `json:"search"`
+	Rerank          RerankDetail  `json:"rerank"`
+	Tables          []TableDetail `json:"tables"`
+
+	// FilterColumns are the columns request filters may use; empty
+	// when any column may be used.
+	FilterColumns []string                 `json:"filter_columns,omitempty"`
+	FilterPresets map[string]config.Filter `json:"filter_presets,omitempty"`
+
+	// Ingest is set when documents may be uploaded to the pipeline.
+	Ingest bool `json:"ingest"`
+}
+
+// ModelDetail is a provider and model a pipeline calls.
+type ModelDetail struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	// BaseURL is the provider's custom base URL, if any, with any
+	// password and query parameter values redacted.
+	BaseURL string `json:"base_url,omitempty"`
+
+	// Headers are the names of the custom headers sent to the
+	// provider; their values are not reported.
+	Headers []string `json:"headers,omitempty"`
+}
+
+// SearchDetail is how a pipeline ranks the documents it retrieves.
+type SearchDetail struct {
+	Hybrid        bool     `json:"hybrid"`
+	VectorShare   float64  `json:"vector_share"`
+	Fusion        string   `json:"fusion"`
+	RRFK          int      `json:"rrf_k,omitempty"`
+	MinSimilarity *float64 `json:"min_similarity,omitempty"`
+}
+
+// RerankDetail is a pipeline's reranking stage.
+type RerankDetail struct {
+	Enabled  bool   `json:"enabled"`
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	TopK     int    `json:"top_k,omitempty"`
+}
+
+// TableDetail is a table a pipeline searches.
+type TableDetail struct {
+	Table           string         `json:"table"`
+	TextColumn      string         `json:"text_column"`
+	VectorColumn    string         `json:"vector_column"`
+	IDColumn        string         `json:"id_column,omitempty"`
+	MetadataColumns []string       `json:"metadata_columns,omitempty"`
+	LexicalSearch   string         `json:"lexical_search"`
+	Filter          *config.Filter `json:"filter,omitempty"`
+
+	// SQLFilter is set when the table also has a raw SQL filter, which
+	// is not reported.
+	SQLFilter bool `json:"sql_filter,omitempty"`
+}
+
+// Detail returns the pipeline's effective configuration.
+func (p *Pipeline) Detail() Detail {
+	cfg := p.config
+	fusion, hybrid := p.orchestrator.hybridSearch()
+	d := Detail{
+		Name:          p.name,
+		Description:   p.description,
+		EmbeddingLLM:  modelDetail(cfg.EmbeddingLLM, cfg.LLMHeaders),
+		RAGLLM:        modelDetail(cfg.RAGLLM, cfg.LLMHeaders),
+		TopN:          p.orchestrator.topN,
+		TokenBudget:   p.orchestrator.tokenBudget,
+		Tables:        make([]TableDetail, 0, len(cfg.Tables)),
+		FilterColumns: cfg.FilterColumns,
+		FilterPresets: cfg.FilterPresets,
+		Ingest:        cfg.Ingest.Enabled,
+		Search: SearchDetail{
+			Hybrid:        hybrid,
+			VectorShare:   fusion.VectorWeight,
+			Fusion:        config.FusionRRF,
+			RRFK:          int(fusion.K),
+			MinSimilarity: cfg.Search.MinSimilarity,
+		},
+		Rerank: RerankDetail{
+			Enabled:  cfg.Rerank.Provider != "",
+			Provider: cfg.Rerank.Provider,
+			Model:    cfg.Rerank.Model,
+			TopK:     cfg.Rerank.TopK,
+		},
+	}
+	if fusion.Method == config.FusionScore {
+		d.Search.Fusion, d.Search.RRFK = config.FusionScore, 0
+	}
+	for _, llm := range cfg.RAGLLMFallbacks {
+		d.RAGLLMFallbacks = append(d.RAGLLMFallbacks, modelDetail(llm, cfg.LLMHeaders))
+	}
+	for _, t := range cfg.Tables {
+		td := TableDetail{
+			Table:           t.Table,
+			TextColumn:      t.TextColumn,
+			VectorColumn:    t.VectorColumn,
+			IDColumn:        t.IDColumn,
+			MetadataColumns: t.MetadataColumns,
+			LexicalSearch:   t.LexicalSearch,
+		}
+		if td.LexicalSearch == "" {
+			td.LexicalSearch = config.LexicalSearchBM25
+		}
+		if t.Filter != nil {
+			td.Filter, td.SQLFilter = t.Filter.Structured, t.Filter.RawSQL != ""
+		}
+		d.Tables = append(d.Tables, td)
+	}
+	return d
+}
+
+// modelDetail reports a provider's settings without their secrets.
+func modelDetail(llm config.LLMConfig, pipelineHeaders map[string]string) ModelDetail {
+	return ModelDetail{
+		Provider: llm.Provider,
+		Model:    llm.Model,
+		BaseURL:  redactURL(llm.BaseURL),
+		Headers:  slices.Sorted(maps.Keys(mergeHeaders(pipelineHeaders, llm.Headers))),
+	}
+}
+
+// redactURL returns rawURL with its password and query parameter
+// values, which may carry API keys, replaced. A URL that does not parse
+// is left out altogether.
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			q.Set(k, "xxxxx")
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.Redacted()
+}
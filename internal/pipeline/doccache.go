@@ -0,0 +1,285 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/bm25"
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// documentRefreshTimeout bounds fetching a table's documents again in
+// the background. The search that started the refresh has already been
+// answered, so it is not bound by the search's own timeout.
+const documentRefreshTimeout = time.Minute
+
+// documentEntry is a table's fetched documents and the key they are
+// cached under.
+type documentEntry struct {
+	key        string
+	docs       *tableDocuments
+	fetched    time.Time
+	refreshing bool
+}
+
+// tableDocuments is a table's fetched documents and the BM25 index of
+// them, built the first time a search needs it. Once built the index is
+// only read, so searches of the same documents share it.
+type tableDocuments struct {
+	docs  map[string]database.Document
+	once  sync.Once
+	index *bm25.Index
+}
+
+// bm25Index returns the index of the documents, building it with the
+// pipeline's BM25 parameters and the table's lexical fields if no
+// search has yet.
+func (d *tableDocuments) bm25Index(p *config.Pipeline, table config.TableSource) *bm25.Index {
+	d.once.Do(func() {
+		d.index = newBM25Index(p)
+		for id, doc := range d.docs {
+			d.index.AddDocumentFields(id, doc.Content, lexicalFields(table, doc))
+		}
+	})
+	return d.index
+}
+
+// documentCache keeps the documents recently fetched to rank tables
+// with BM25, so searches reuse them instead of loading the table again.
+// Documents younger than ttl are served as they are; older ones are
+// served for up to maxStale more while a single background fetch
+// replaces them. The least recently used set is evicted once it holds
+// maxEntries. It is safe for concurrent use.
+type documentCache struct {
+	ttl        time.Duration
+	maxStale   time.Duration
+	maxEntries int
+	now        func() time.Time // overridden in tests
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // of *documentEntry, most recently used first
+}
+
+// newDocumentCache creates the document cache a pipeline's settings
+// describe, or returns nil when they disable it.
+func newDocumentCache(cfg config.DocumentCacheConfig) *documentCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	c := &documentCache{
+		ttl:        time.Duration(cfg.TTL),
+		maxStale:   time.Duration(cfg.MaxStale),
+		maxEntries: cfg.MaxEntries,
+		now:        time.Now,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+	if c.ttl <= 0 {
+		c.ttl = time.Duration(config.DefaultDocumentCacheTTL)
+	}
+	if c.maxStale <= 0 {
+		c.maxStale = time.Duration(config.DefaultDocumentCacheMaxStale)
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = config.DefaultDocumentCacheMaxEntries
+	}
+	return c
+}
+
+// fetchFunc loads a table's documents.
+type fetchFunc func(ctx context.Context) (*tableDocuments, error)
+
+// get returns the documents cached under key, calling fetch when there
+// are none or they are past the staleness bound. Stale documents are
+// returned at once, and fetch is called in the background, with ctx's
+// values but not its deadline, to replace them; a failed refresh is
+// logged and leaves them in place. The result is one of the
+// metrics.DocumentCache values.
+func (c *documentCache) get(ctx context.Context, key string, fetch fetchFunc,
+	logger *slog.Logger) (*tableDocuments, string, error) {
+	now := c.now()
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*documentEntry)
+		age := now.Sub(e.fetched)
+		if age < c.ttl {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			return e.docs, metrics.DocumentCacheFresh, nil
+		}
+		if age < c.ttl+c.maxStale {
+			c.lru.MoveToFront(el)
+			refresh := !e.refreshing
+			e.refreshing = true
+			c.mu.Unlock()
+			if refresh {
+				go c.refresh(context.WithoutCancel(ctx), key, fetch, logger)
+			}
+			return e.docs, metrics.DocumentCacheStale, nil
+		}
+	}
+	c.mu.Unlock()
+
+	docs, err := fetch(ctx)
+	if err != nil {
+		return nil, metrics.DocumentCacheMiss, err
+	}
+	c.put(key, docs, now)
+	return docs, metrics.DocumentCacheMiss, nil
+}
+
+// refresh fetches the documents cached under key again.
+func (c *documentCache) refresh(ctx context.Context, key string, fetch fetchFunc, logger *slog.Logger) {
+	ctx, cancel := context.WithTimeout(ctx, documentRefreshTimeout)
+	defer cancel()
+
+	start := c.now()
+	docs, err := fetch(ctx)
+	if err != nil {
+		logger.Warn("failed to refresh cached documents; serving them stale", "error", err)
+		c.mu.Lock()
+		if el, ok := c.entries[key]; ok {
+			el.Value.(*documentEntry).refreshing = false
+		}
+		c.mu.Unlock()
+		return
+	}
+	c.put(key, docs, start)
+}
+
+// put caches a table's documents, fetched at fetched, evicting the
+// least recently used set if the cache is full.
+func (c *documentCache) put(key string, docs *tableDocuments, fetched time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*documentEntry)
+		e.docs, e.fetched, e.refreshing = docs, fetched, false
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&documentEntry{key: key, docs: docs, fetched: fetched})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*documentEntry).key)
+	}
+}
+
+// clear removes every cached document set. Refreshes already running
+// may add theirs back.
+func (c *documentCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+}
+
+// fetchDocuments returns a table's documents matching filter, through
+// the pipeline's document cache when it has one. Documents are cached
+// per table, filter and the values of the variables that decide which
+// rows the caller may see.
+func (o *Orchestrator) fetchDocuments(ctx context.Context, table config.TableSource,
+	filter *config.Filter) (*tableDocuments, error) {
+	fetch := func(ctx context.Context) (*tableDocuments, error) {
+		docs, err := o.dbPool.FetchDocuments(ctx, table, filter, o.fetchLimits())
+		if err != nil {
+			return nil, err
+		}
+		return &tableDocuments{docs: docs}, nil
+	}
+	if o.documentCache == nil {
+		return fetch(ctx)
+	}
+
+	key, err := json.Marshal(struct {
+		Table  string
+		Filter *config.Filter
+		Caller []string
+	}{table.Table, filter, o.callerScope(ctx)})
+	if err != nil {
+		return fetch(ctx)
+	}
+	sum := sha256.Sum256(key)
+	docs, result, err := o.documentCache.get(ctx, hex.EncodeToString(sum[:]), fetch,
+		o.logger.With("table", table.Table))
+	o.metrics.ObserveDocumentCache(o.pipelineName(), result)
+	return docs, err
+}
+
+// warmUpTables returns the tables whose documents warmUp loads: those
+// hybrid search ranks with BM25, when the pipeline caches documents and
+// which ones a search may see does not depend on who makes it. ctx is
+// the context the pipeline starts with, which carries no caller.
+func (o *Orchestrator) warmUpTables(ctx context.Context) []config.TableSource {
+	if o.documentCache == nil || o.dbPool == nil || len(o.callerScope(ctx)) > 0 {
+		return nil
+	}
+	if _, useHybrid := o.hybridSearch(); !useHybrid {
+		return nil
+	}
+	var tables []config.TableSource
+	for _, table := range o.cfg.Tables {
+		if table.LexicalSearch != config.LexicalSearchPostgresFTS {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
+
+// warmedTable is a table's documents loaded by warmUp.
+type warmedTable struct {
+	table config.TableSource
+	docs  *tableDocuments
+}
+
+// warmUp loads the documents of tables into the document cache, as a
+// search with no filter would, so the first searches do not wait on
+// them. A table that fails to load is left to the first search of it;
+// the errors are joined and returned with the tables that loaded.
+func (o *Orchestrator) warmUp(ctx context.Context, tables []config.TableSource) ([]warmedTable, error) {
+	var warmed []warmedTable
+	var errs []error
+	for _, table := range tables {
+		fetchCtx, cancel := context.WithTimeout(ctx, documentRefreshTimeout)
+		docs, err := o.fetchDocuments(fetchCtx, table, nil)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load documents of %s: %w", table.Table, err))
+			continue
+		}
+		warmed = append(warmed, warmedTable{table: table, docs: docs})
+	}
+	return warmed, errors.Join(errs...)
+}
+
+// indexDocuments builds the BM25 index of each table warmUp loaded, so
+// the first search of it finds the index built.
+func (o *Orchestrator) indexDocuments(warmed []warmedTable) {
+	for _, w := range warmed {
+		w.docs.bm25Index(o.cfg, w.table)
+	}
+}
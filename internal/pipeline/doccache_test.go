@@ -0,0 +1,267 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+func TestDocumentCache(t *testing.T) {
+	c := newDocumentCache(config.DocumentCacheConfig{Enabled: true,
+		TTL: config.Duration(time.Minute), MaxStale: config.Duration(5 * time.Minute), MaxEntries: 2})
+	var mu sync.Mutex
+	now := time.Now()
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+
+	version, fetches := "v1", 0
+	var fetchErr error
+	fetch := func(ctx context.Context) (*tableDocuments, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fetches++
+		if fetchErr != nil {
+			return nil, fetchErr
+		}
+		return &tableDocuments{docs: map[string]database.Document{"1": {Content: version}}}, nil
+	}
+	waitForRefresh := func(key string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			c.mu.Lock()
+			el, ok := c.entries[key]
+			refreshing := ok && el.Value.(*documentEntry).refreshing
+			c.mu.Unlock()
+			if !refreshing {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("timed out waiting for the background refresh")
+	}
+	get := func(key string, f fetchFunc) (string, string) {
+		t.Helper()
+		docs, result, err := c.get(context.Background(), key, f, slog.Default())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return docs.docs["1"].Content, result
+	}
+
+	if got, result := get("a", fetch); got != "v1" || result != metrics.DocumentCacheMiss {
+		t.Errorf("expected a miss fetching v1, got %s %s", got, result)
+	}
+	if got, result := get("a", fetch); got != "v1" || result != metrics.DocumentCacheFresh {
+		t.Errorf("expected v1 served fresh, got %s %s", got, result)
+	}
+	mu.Lock()
+	if fetches != 1 {
+		t.Errorf("expected fresh documents to be served without a fetch, got %d fetches", fetches)
+	}
+	mu.Unlock()
+
+	// Past the TTL the stale documents are served while they are
+	// fetched again in the background, once.
+	mu.Lock()
+	version = "v2"
+	mu.Unlock()
+	advance(2 * time.Minute)
+	if got, result := get("a", fetch); got != "v1" || result != metrics.DocumentCacheStale {
+		t.Errorf("expected v1 served stale, got %s %s", got, result)
+	}
+	waitForRefresh("a")
+	if got, result := get("a", fetch); got != "v2" || result != metrics.DocumentCacheFresh {
+		t.Errorf("expected the refreshed v2, got %s %s", got, result)
+	}
+
+	// A failed refresh leaves the stale documents in place.
+	mu.Lock()
+	fetchErr = errors.New("connection refused")
+	mu.Unlock()
+	advance(2 * time.Minute)
+	get("a", fetch)
+	waitForRefresh("a")
+	if got, result := get("a", fetch); got != "v2" || result != metrics.DocumentCacheStale {
+		t.Errorf("expected v2 still served stale, got %s %s", got, result)
+	}
+	waitForRefresh("a")
+
+	// Past the staleness bound the documents are fetched before
+	// they are served.
+	mu.Lock()
+	fetchErr, version = nil, "v3"
+	mu.Unlock()
+	advance(10 * time.Minute)
+	if got, result := get("a", fetch); got != "v3" || result != metrics.DocumentCacheMiss {
+		t.Errorf("expected v3 fetched past the staleness bound, got %s %s", got, result)
+	}
+
+	// The least recently used set is evicted.
+	get("b", fetch)
+	get("a", fetch)
+	get("c", fetch)
+	if _, result := get("b", fetch); result != metrics.DocumentCacheMiss {
+		t.Errorf("expected the least recently used set to be evicted, got %s", result)
+	}
+
+	c.clear()
+	if _, result := get("a", fetch); result != metrics.DocumentCacheMiss {
+		t.Errorf("expected clear to remove every set, got %s", result)
+	}
+	if newDocumentCache(config.DocumentCacheConfig{}) != nil {
+		t.Error("expected no cache when disabled")
+	}
+}
+
+func TestOrchestrator_LexicalSearch_CachesDocuments(t *testing.T) {
+	fetches := 0
+	backend := &MockSearchBackend{
+		FetchDocumentsFunc: func(ctx context.Context, table config.TableSource,
+			filter *config.Filter) (map[string]database.Document, error) {
+			fetches++
+			return map[string]database.Document{
+				"1": {Content: "Streaming replication sends WAL to a standby."},
+				"2": {Content: "Vacuum reclaims storage from dead tuples."},
+			}, nil
+		},
+	}
+	pCfg := config.Pipeline{
+		Name:   "docs",
+		Tables: []config.TableSource{{Table: "docs", TextColumn: "content", VectorColumn: "embedding"}},
+		BM25:   config.BM25Config{Cache: config.DocumentCacheConfig{Enabled: true}},
+	}
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &pCfg, DBPool: backend})
+
+	filter := &config.Filter{Conditions: []config.FilterCondition{{Column: "product", Operator: "=", Value: "pgEdge"}}}
+	for _, req := range []QueryRequest{
+		{Query: "replication standby"},
+		{Query: "vacuum"},
+		{Query: "vacuum", Filter: filter},
+	} {
+		results, err := orch.lexicalSearch(context.Background(), req, pCfg.Tables[0], 5)
+		if err != nil || len(results) == 0 {
+			t.Fatalf("expected results for %q, got %v, %v", req.Query, results, err)
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("expected one fetch per filter, got %d", fetches)
+	}
+
+	orch.documentCache.clear()
+	if _, err := orch.lexicalSearch(context.Background(), QueryRequest{Query: "vacuum"}, pCfg.Tables[0], 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fetches != 3 {
+		t.Errorf("expected a cleared cache to fetch again, got %d fetches", fetches)
+	}
+}
+
+func TestOrchestrator_WarmUp(t *testing.T) {
+	fetches := 0
+	backend := &MockSearchBackend{
+		FetchDocumentsFunc: func(ctx context.Context, table config.TableSource,
+			filter *config.Filter) (map[string]database.Document, error) {
+			fetches++
+			if table.Table == "broken" {
+				return nil, errors.New("relation does not exist")
+			}
+			return map[string]database.Document{
+				"1": {Content: "Streaming replication sends WAL to a standby."},
+			}, nil
+		},
+	}
+	hybrid := true
+	newPipeline := func(mutate func(*config.Pipeline)) config.Pipeline {
+		pCfg := config.Pipeline{
+			Name: "docs",
+			Tables: []config.TableSource{
+				{Table: "docs", TextColumn: "content", VectorColumn: "embedding"},
+				{Table: "fts", TextColumn: "content", VectorColumn: "embedding",
+					LexicalSearch: config.LexicalSearchPostgresFTS},
+				{Table: "broken", TextColumn: "content", VectorColumn: "embedding"},
+			},
+			Search: config.SearchConfig{HybridEnabled: &hybrid},
+			BM25:   config.BM25Config{Cache: config.DocumentCacheConfig{Enabled: true}},
+		}
+		if mutate != nil {
+			mutate(&pCfg)
+		}
+		return pCfg
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*config.Pipeline)
+		want   []string
+	}{
+		{name: "bm25 tables", want: []string{"docs", "broken"}},
+		{name: "no cache", mutate: func(p *config.Pipeline) { p.BM25.Cache.Enabled = false }},
+		{name: "vector only", mutate: func(p *config.Pipeline) { p.Search.HybridEnabled = nil }},
+		{name: "caller scoped", mutate: func(p *config.Pipeline) {
+			p.Tables[0].Filter = &config.ConfigFilter{RawSQL: "tenant = {{header:X-Tenant}}"}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pCfg := newPipeline(tt.mutate)
+			orch := NewOrchestrator(OrchestratorConfig{Pipeline: &pCfg, DBPool: backend})
+			var got []string
+			for _, table := range orch.warmUpTables(context.Background()) {
+				got = append(got, table.Table)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("warmUpTables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	pCfg := newPipeline(nil)
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &pCfg, DBPool: backend})
+	warmed, err := orch.warmUp(context.Background(), orch.warmUpTables(context.Background()))
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("expected the table that failed to load to be named, got %v", err)
+	}
+	if len(warmed) != 1 || warmed[0].table.Table != "docs" {
+		t.Fatalf("expected the loaded table to be returned, got %+v", warmed)
+	}
+	orch.indexDocuments(warmed)
+	if warmed[0].docs.index == nil || warmed[0].docs.index.Size() != 1 {
+		t.Error("expected the loaded documents to be indexed")
+	}
+
+	// The first search finds the documents and index already loaded.
+	fetches = 0
+	results, err := orch.lexicalSearch(context.Background(), QueryRequest{Query: "standby"}, pCfg.Tables[0], 5)
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected a result, got %v, %v", results, err)
+	}
+	if fetches != 0 {
+		t.Errorf("expected the warmed documents to be served from the cache, got %d fetches", fetches)
+	}
+}
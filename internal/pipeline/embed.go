@@ -0,0 +1,79 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxEmbedInputs bounds the texts one embed request may embed.
+const MaxEmbedInputs = 100
+
+// EmbedRequest asks for the embeddings of texts, made as the pipeline
+// embeds its queries.
+type EmbedRequest struct {
+	Input []string `json:"input"`
+}
+
+// EmbedResponse holds one embedding for each input text, in order,
+// with the tokens embedding them used.
+type EmbedResponse struct {
+	Model      string      `json:"model"`
+	Dimensions int         `json:"dimensions"`
+	Embeddings [][]float32 `json:"embeddings"`
+	Usage      *StageUsage `json:"usage"`
+}
+
+// Embed embeds each input text with the pipeline's embedding LLM, as
+// its queries are embedded but without the rewrite_query hook, so the
+// vectors can be compared with its tables' embeddings.
+func (o *Orchestrator) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	if len(req.Input) == 0 {
+		return nil, fmt.Errorf("%w: input is required", ErrInvalidRequest)
+	}
+	if len(req.Input) > MaxEmbedInputs {
+		return nil, fmt.Errorf("%w: at most %d inputs are allowed (got %d)",
+			ErrInvalidRequest, MaxEmbedInputs, len(req.Input))
+	}
+	for i, text := range req.Input {
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("%w: input[%d] must not be empty", ErrInvalidRequest, i)
+		}
+	}
+	if err := o.checkBudget(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+	defer cancel()
+
+	usage := &StageUsage{}
+	resp := &EmbedResponse{
+		Model:      o.cfg.EmbeddingLLM.Model,
+		Embeddings: make([][]float32, len(req.Input)),
+		Usage:      usage,
+	}
+	for i, text := range req.Input {
+		textUsage := &StageUsage{}
+		embedding, err := o.embedWithTimeout(ctx, text, textUsage)
+		usage.Embedding.Add(textUsage.Embedding)
+		if err != nil {
+			o.chargeCost(usage)
+			return nil, fmt.Errorf("failed to embed input[%d]: %w", i, err)
+		}
+		resp.Embeddings[i] = embedding
+	}
+	o.chargeCost(usage)
+	resp.Dimensions = len(resp.Embeddings[0])
+	return resp, nil
+}
@@ -0,0 +1,60 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestOrchestrator_Embed(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.cfg.EmbeddingLLM = config.LLMConfig{Provider: "openai", Model: "text-embedding-3-small"}
+	var embedded []string
+	orch.embeddingProv = &MockEmbedder{EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+		embedded = append(embedded, text)
+		if text == "fail" {
+			return nil, errors.New("provider unavailable")
+		}
+		return []float64{float64(len(text)), 0.5}, nil
+	}}
+
+	resp, err := orch.Embed(context.Background(), EmbedRequest{Input: []string{"standby", "replication"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Model != "text-embedding-3-small" || resp.Dimensions != 2 || len(resp.Embeddings) != 2 ||
+		resp.Embeddings[0][0] != 7 || resp.Embeddings[1][0] != 11 || resp.Usage == nil {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if strings.Join(embedded, ",") != "standby,replication" {
+		t.Errorf("expected each input embedded in order, got %v", embedded)
+	}
+
+	_, err = orch.Embed(context.Background(), EmbedRequest{Input: []string{"ok", "fail"}})
+	if err == nil || !strings.Contains(err.Error(), "input[1]") {
+		t.Errorf("expected the failing input named, got %v", err)
+	}
+
+	tooMany := make([]string, MaxEmbedInputs+1)
+	for i := range tooMany {
+		tooMany[i] = "text"
+	}
+	for _, input := range [][]string{nil, {"ok", "  "}, tooMany} {
+		if _, err := orch.Embed(context.Background(), EmbedRequest{Input: input}); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("expected ErrInvalidRequest for %d inputs, got %v", len(input), err)
+		}
+	}
+}
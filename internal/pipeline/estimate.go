@@ -0,0 +1,157 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/tokens"
+)
+
+// Estimate is the result of a dry run of a query: the size of the
+// prompt it would send, and what each of the pipeline's completion
+// models would charge for it.
+type Estimate struct {
+	// PromptTokens estimates the tokens of the assembled prompt, as
+	// the pipeline's tokenizer counts them.
+	PromptTokens int `json:"prompt_tokens"`
+
+	// MaxCompletionTokens is the bound the answer length sets on the
+	// answer, when it sets one.
+	MaxCompletionTokens int `json:"max_completion_tokens,omitempty"`
+
+	// Documents is the number of retrieved documents in the context.
+	Documents int `json:"documents"`
+
+	// Usage is what the dry run itself spent on retrieval.
+	Usage *StageUsage `json:"usage"`
+
+	// Models are the completion model and its fallbacks, in the order
+	// they are tried.
+	Models []ModelEstimate `json:"models"`
+}
+
+// ModelEstimate projects the cost of a query on one completion model.
+// Costs are omitted when the model's pricing is not configured.
+type ModelEstimate struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	// PromptCost is the price of the prompt tokens.
+	PromptCost *float64 `json:"prompt_cost,omitempty"`
+
+	// MaxCompletionCost is the price of an answer of
+	// MaxCompletionTokens, the most the answer can add; it is omitted
+	// when the answer length sets no bound.
+	MaxCompletionCost *float64 `json:"max_completion_cost,omitempty"`
+}
+
+// Estimate runs a query's retrieval and assembles its prompt without
+// calling the completion API, and returns the prompt's estimated size
+// and projected cost. Retrieval runs as it would for the query,
+// including any query expansion, hooks and reranking, so their usage
+// is spent. Conversation history over max_history_tokens is dropped, and
+// a top document over the token budget truncated, not summarized.
+func (o *Orchestrator) Estimate(ctx context.Context, req QueryRequest) (*Estimate, error) {
+	if err := o.validateRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	filter, err := o.applyFilterPreset(req.FilterPreset, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	req.Filter = filter
+
+	topN := o.topN
+	if req.TopN > 0 {
+		topN = req.TopN
+	}
+
+	usage := &StageUsage{}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+	defer cancel()
+
+	searchReq := req
+	searchReq.Query = o.rewriteQuery(ctx, req.Query)
+	embedding, err := o.embedWithTimeout(ctx, searchReq.Query, usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	results, err := o.retrieve(ctx, searchReq, embedding, topN, usage)
+	if err != nil {
+		return nil, err
+	}
+	results = o.filterResults(ctx, req.Query, results)
+
+	estimate := &Estimate{Usage: usage}
+	defer o.chargeCost(usage)
+	if len(results) > 0 {
+		// As in Execute, a query that retrieves nothing is answered
+		// without a completion.
+		results = o.mergeChunks(o.rerank(ctx, req.Query, results, usage))
+		if tokens := o.counter().Count(results[0].Content); tokens > o.tokenBudget &&
+			o.cfg.BudgetOverflow == config.BudgetOverflowError {
+			return nil, budgetError(tokens, o.tokenBudget)
+		}
+		contextDocs, _ := o.buildContext(results)
+		req.Messages, _ = truncateHistory(o.counter(), req.Messages, o.cfg.MaxHistoryTokens)
+		chatReq := o.buildChatRequest(req, contextDocs)
+
+		estimate.PromptTokens = promptTokens(o.counter(), chatReq)
+		estimate.Documents = len(contextDocs)
+		if chatReq.MaxTokens != nil {
+			estimate.MaxCompletionTokens = *chatReq.MaxTokens
+		}
+	}
+
+	models := append([]config.LLMConfig{o.cfg.RAGLLM}, o.cfg.RAGLLMFallbacks...)
+	for _, m := range models {
+		me := ModelEstimate{
+			Provider:   strings.ToLower(m.Provider),
+			Model:      m.Model,
+			PromptCost: cost(estimate.PromptTokens, m.Pricing.InputPerMillion),
+		}
+		if estimate.MaxCompletionTokens > 0 {
+			me.MaxCompletionCost = cost(estimate.MaxCompletionTokens, m.Pricing.OutputPerMillion)
+		}
+		estimate.Models = append(estimate.Models, me)
+	}
+	return estimate, nil
+}
+
+// promptTokens counts the tokens of a chat request's system prompt
+// and messages.
+func promptTokens(counter tokens.Counter, req llmlib.ChatRequest) int {
+	n := counter.Count(req.SystemPrompt)
+	for _, m := range req.Messages {
+		for _, b := range m.Content {
+			n += counter.Count(b.Text)
+		}
+	}
+	return n
+}
+
+// cost returns the price of tokens at perMillion, or nil when the
+// price is not configured.
+func cost(tokens int, perMillion float64) *float64 {
+	if perMillion == 0 {
+		return nil
+	}
+	c := float64(tokens) / 1e6 * perMillion
+	return &c
+}
@@ -0,0 +1,69 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestOrchestrator_Estimate(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.completionProv = &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			t.Error("expected the estimate not to call the completion API")
+			return nil, errors.New("unexpected completion")
+		},
+	}
+	orch.cfg.RAGLLM = config.LLMConfig{Provider: "OpenAI", Model: "gpt-4o",
+		Pricing: config.PricingConfig{InputPerMillion: 2.5, OutputPerMillion: 10}}
+	orch.cfg.RAGLLMFallbacks = []config.LLMConfig{{Provider: "anthropic", Model: "claude"}}
+
+	req := QueryRequest{Query: "How does streaming replication work?", AnswerLength: config.AnswerLengthShort}
+	estimate, err := orch.Estimate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if estimate.Documents != 3 {
+		t.Errorf("expected 3 documents in the context, got %d", estimate.Documents)
+	}
+	noDocs, _ := orch.buildContext(nil)
+	chatReq := orch.buildChatRequest(req, noDocs)
+	if estimate.PromptTokens <= promptTokens(orch.counter(), chatReq) {
+		t.Errorf("expected the prompt to include the context, got %d tokens", estimate.PromptTokens)
+	}
+	wantMax := config.AnswerLengthMaxTokens[config.AnswerLengthShort]
+	if estimate.MaxCompletionTokens != wantMax {
+		t.Errorf("max completion tokens = %d, want %d", estimate.MaxCompletionTokens, wantMax)
+	}
+
+	if len(estimate.Models) != 2 {
+		t.Fatalf("expected the model and its fallback, got %+v", estimate.Models)
+	}
+	primary, fallback := estimate.Models[0], estimate.Models[1]
+	if primary.Provider != "openai" || primary.PromptCost == nil ||
+		*primary.PromptCost != float64(estimate.PromptTokens)/1e6*2.5 ||
+		primary.MaxCompletionCost == nil || *primary.MaxCompletionCost != float64(wantMax)/1e6*10 {
+		t.Errorf("unexpected primary estimate: %+v", primary)
+	}
+	if fallback.Model != "claude" || fallback.PromptCost != nil || fallback.MaxCompletionCost != nil {
+		t.Errorf("expected no costs without pricing, got %+v", fallback)
+	}
+
+	if _, err := orch.Estimate(context.Background(), QueryRequest{Query: "q", AnswerLength: "tiny"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for an invalid request, got %v", err)
+	}
+}
@@ -0,0 +1,546 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// Ratings accepted by a query's feedback.
+const (
+	FeedbackUp   = "up"
+	FeedbackDown = "down"
+)
+
+// ErrQueryNotFound is returned for feedback on a query the pipeline did
+// not capture, or no longer keeps.
+var ErrQueryNotFound = errors.New("query not found")
+
+// EvalCase is one query of an eval dataset: the query, the IDs of the
+// sources it should retrieve, and the feedback it was given. Captured
+// cases also carry the ID their feedback is given with and when they
+// were asked; hand-written ones need only the query and sources, or a
+// reference answer its answer is judged against.
+type EvalCase struct {
+	ID              string     `json:"id,omitempty"`
+	Time            *time.Time `json:"time,omitempty"`
+	Query           string     `json:"query"`
+	ExpectedSources []string   `json:"expected_sources"`
+	ReferenceAnswer string     `json:"reference_answer,omitempty"`
+	Feedback        string     `json:"feedback,omitempty"` // FeedbackUp or FeedbackDown
+	Comment         string     `json:"comment,omitempty"`
+}
+
+// EvalOptions sets how a dataset is evaluated. Zero K uses the
+// pipeline's top_n. With Judge set, each case with a reference answer
+// is also answered, and the completion LLM grades the answer against
+// the reference.
+type EvalOptions struct {
+	K     int
+	Judge bool
+}
+
+// FeedbackRequest rates an answer, identified by the response_id of
+// its response, the query_id when the query was captured for the eval
+// dataset, or both.
+type FeedbackRequest struct {
+	QueryID    string `json:"query_id,omitempty"`
+	ResponseID string `json:"response_id,omitempty"`
+	Rating     string `json:"rating"`            // FeedbackUp or FeedbackDown
+	Comment    string `json:"comment,omitempty"` // Anonymized as the query is
+}
+
+// EvalReport is the outcome of evaluating a pipeline's retrieval
+// against an eval dataset. HitRate is the fraction of evaluated cases
+// that retrieved at least one expected source, Recall the mean fraction
+// of expected sources retrieved, MRR the mean reciprocal rank of the
+// first expected source retrieved, and NDCG the mean normalized
+// discounted cumulative gain, which also rewards ranking the expected
+// sources above the rest. When answers were judged, AnswerQuality is
+// the mean grade of the judged answers, from 0 to 1.
+type EvalReport struct {
+	K             int          `json:"k"`
+	Cases         int          `json:"cases"`
+	Evaluated     int          `json:"evaluated"`
+	Judged        int          `json:"judged,omitempty"`
+	Skipped       int          `json:"skipped"` // With nothing to evaluate, or rated down
+	Failed        int          `json:"failed"`
+	HitRate       float64      `json:"hit_rate"`
+	Recall        float64      `json:"recall"`
+	MRR           float64      `json:"mrr"`
+	NDCG          float64      `json:"ndcg"`
+	AnswerQuality *float64     `json:"answer_quality,omitempty"`
+	Results       []EvalResult `json:"results"`
+}
+
+// EvalResult is how one case of a dataset fared. Retrieval is scored
+// when the case has expected sources; its answer is given and graded
+// when it has a reference answer and answers are judged.
+type EvalResult struct {
+	Query         string   `json:"query"`
+	Expected      []string `json:"expected_sources"`
+	Retrieved     []string `json:"retrieved_sources,omitempty"`
+	Hit           bool     `json:"hit"`
+	Recall        float64  `json:"recall"`
+	NDCG          float64  `json:"ndcg"`
+	Rank          int      `json:"rank,omitempty"` // Of the first expected source retrieved
+	Answer        string   `json:"answer,omitempty"`
+	AnswerQuality *float64 `json:"answer_quality,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// Patterns of personal details that captured queries are anonymized of,
+// whatever the pipeline's redact_patterns. A number is only removed
+// when it has at least minRedactedDigits digits, so versions and short
+// counts are kept.
+var (
+	emailPattern  = regexp.MustCompile(`[[:alnum:]._%+-]+@[[:alnum:].-]+\.[[:alpha:]]{2,}`)
+	numberPattern = regexp.MustCompile(`\+?\d[\d ().-]*\d`)
+)
+
+const minRedactedDigits = 7
+
+// evalCapture keeps a sample of a pipeline's queries as an eval
+// dataset, dropping the oldest once it holds maxEntries. It is safe for
+// concurrent use, and a nil capture captures nothing.
+type evalCapture struct {
+	rate       float64
+	maxEntries int
+	redact     []*regexp.Regexp
+	sample     func() float64   // overridden in tests
+	now        func() time.Time // overridden in tests
+
+	mu    sync.Mutex
+	cases []EvalCase // oldest first
+}
+
+// newEvalCapture creates the eval capture a pipeline's settings
+// describe, or returns nil when they disable it.
+func newEvalCapture(cfg config.EvalCaptureConfig) *evalCapture {
+	if !cfg.Enabled {
+		return nil
+	}
+	c := &evalCapture{
+		rate:       cfg.SampleRate,
+		maxEntries: cfg.MaxEntries,
+		sample:     mathrand.Float64,
+		now:        time.Now,
+	}
+	if c.rate <= 0 {
+		c.rate = config.DefaultEvalSampleRate
+	}
+	if c.maxEntries <= 0 {
+		c.maxEntries = config.DefaultEvalMaxEntries
+	}
+	for _, pattern := range cfg.RedactPatterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			c.redact = append(c.redact, re)
+		}
+	}
+	return c
+}
+
+// Anonymize replaces email addresses in text with [email] and long
+// numbers, such as phone and account numbers, with [number], as
+// captured queries are anonymized.
+func Anonymize(text string) string {
+	text = emailPattern.ReplaceAllLiteralString(text, "[email]")
+	return numberPattern.ReplaceAllStringFunc(text, func(number string) string {
+		digits := 0
+		for _, r := range number {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minRedactedDigits {
+			return number
+		}
+		return "[number]"
+	})
+}
+
+// anonymize removes email addresses, long numbers, and matches of the
+// redaction patterns from text.
+func (c *evalCapture) anonymize(text string) string {
+	text = Anonymize(text)
+	for _, re := range c.redact {
+		text = re.ReplaceAllLiteralString(text, "[redacted]")
+	}
+	return text
+}
+
+// capture samples a query, keeping it with the IDs of the sources it
+// retrieved, and returns the ID its feedback is given with, or "" when
+// it is not captured.
+func (c *evalCapture) capture(query string, sources []string) string {
+	if c == nil || c.sample() >= c.rate {
+		return ""
+	}
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	now := c.now().UTC()
+	ec := EvalCase{
+		ID:              hex.EncodeToString(b[:]),
+		Time:            &now,
+		Query:           c.anonymize(query),
+		ExpectedSources: sources,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cases = append(c.cases, ec)
+	if over := len(c.cases) - c.maxEntries; over > 0 {
+		c.cases = slices.Delete(c.cases, 0, over)
+	}
+	return ec.ID
+}
+
+// feedback records the rating of a captured query.
+func (c *evalCapture) feedback(req FeedbackRequest) error {
+	if c == nil {
+		return ErrQueryNotFound
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.cases {
+		if c.cases[i].ID == req.QueryID {
+			c.cases[i].Feedback = req.Rating
+			c.cases[i].Comment = c.anonymize(req.Comment)
+			return nil
+		}
+	}
+	return ErrQueryNotFound
+}
+
+// dataset returns a copy of the captured queries, oldest first.
+func (c *evalCapture) dataset() []EvalCase {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.cases)
+}
+
+// captureQuery samples a query into the pipeline's eval dataset with
+// the sources its answer was written from, and returns its ID, or ""
+// when it is not captured. Queries with conversation history depend on
+// it, so are never captured.
+func (o *Orchestrator) captureQuery(req QueryRequest, results []database.SearchResult) string {
+	if o.evalCapture == nil || len(req.Messages) > 0 {
+		return ""
+	}
+	var sources []string
+	for _, r := range results {
+		if r.ID != "" && !slices.Contains(sources, r.ID) {
+			sources = append(sources, r.ID)
+		}
+	}
+	return o.evalCapture.capture(req.Query, sources)
+}
+
+// Feedback records a rating of an answer: in the pipeline's feedback
+// table when it names a response, and in the eval dataset when it names
+// a captured query. It fails with ErrResponseNotFound when the pipeline
+// takes no feedback or no longer keeps the response, and with
+// ErrQueryNotFound when it captures no queries or no longer keeps the
+// query.
+func (o *Orchestrator) Feedback(ctx context.Context, req FeedbackRequest) error {
+	if req.QueryID == "" && req.ResponseID == "" {
+		return fmt.Errorf("%w: query_id or response_id is required", ErrInvalidRequest)
+	}
+	if req.Rating != FeedbackUp && req.Rating != FeedbackDown {
+		return fmt.Errorf("%w: rating must be %q or %q", ErrInvalidRequest, FeedbackUp, FeedbackDown)
+	}
+	if req.ResponseID != "" {
+		if err := o.rateResponse(ctx, req); err != nil {
+			return err
+		}
+	}
+	if req.QueryID == "" {
+		return nil
+	}
+	return o.evalCapture.feedback(req)
+}
+
+// EvalDataset returns the queries the pipeline has captured, oldest
+// first.
+func (o *Orchestrator) EvalDataset() []EvalCase {
+	return o.evalCapture.dataset()
+}
+
+// Evaluate retrieves the top k documents for each query of a dataset,
+// as the retrieve endpoint would, and scores them against the sources
+// the query should retrieve. With opts.Judge set, each query with a
+// reference answer is also answered, retrieving as many documents, and
+// its answer graded against the reference. Cases with nothing to
+// evaluate, or whose answer was rated down, are skipped. A query that
+// fails is reported, and the rest evaluated, unless ctx is done.
+func (o *Orchestrator) Evaluate(ctx context.Context, cases []EvalCase, opts EvalOptions) (*EvalReport, error) {
+	if opts.K < 0 {
+		return nil, fmt.Errorf("%w: k must not be negative", ErrInvalidRequest)
+	}
+	if opts.K == 0 {
+		opts.K = o.topN
+	}
+	return o.evaluate(ctx, cases, opts, func() {})
+}
+
+// evaluable reports whether the retrieval of a case of a dataset can be
+// evaluated.
+func evaluable(ec EvalCase) bool {
+	return ec.Query != "" && len(ec.ExpectedSources) > 0 && ec.Feedback != FeedbackDown
+}
+
+// judgeable reports whether the answer to a case of a dataset can be
+// judged.
+func judgeable(ec EvalCase) bool {
+	return ec.Query != "" && ec.ReferenceAnswer != "" && ec.Feedback != FeedbackDown
+}
+
+// evaluate scores the top k documents retrieved for each evaluable case
+// of a dataset, and judges the answers to the judgeable ones when
+// opts.Judge is set, calling evaluated after each case.
+func (o *Orchestrator) evaluate(ctx context.Context, cases []EvalCase, opts EvalOptions, evaluated func()) (*EvalReport, error) {
+	report := &EvalReport{K: opts.K, Cases: len(cases), Results: []EvalResult{}}
+	quality := 0.0
+	for _, ec := range cases {
+		retrieve, judge := evaluable(ec), opts.Judge && judgeable(ec)
+		if !retrieve && !judge {
+			report.Skipped++
+			continue
+		}
+
+		result := EvalResult{Query: ec.Query, Expected: ec.ExpectedSources}
+		err := o.evaluateCase(ctx, ec, opts.K, retrieve, judge, &result)
+		evaluated()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			result.Error = err.Error()
+			report.Failed++
+			report.Results = append(report.Results, result)
+			continue
+		}
+
+		if retrieve {
+			report.Evaluated++
+			if result.Hit {
+				report.HitRate++
+				report.MRR += 1 / float64(result.Rank)
+			}
+			report.Recall += result.Recall
+			report.NDCG += result.NDCG
+		}
+		if judge {
+			report.Judged++
+			quality += *result.AnswerQuality
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if report.Evaluated > 0 {
+		n := float64(report.Evaluated)
+		report.HitRate /= n
+		report.Recall /= n
+		report.MRR /= n
+		report.NDCG /= n
+	}
+	if report.Judged > 0 {
+		quality /= float64(report.Judged)
+		report.AnswerQuality = &quality
+	}
+	return report, nil
+}
+
+// evaluateCase scores the top k documents retrieved for a case when
+// retrieve is set, and answers it and grades the answer when judge is
+// set, recording the outcome in result.
+func (o *Orchestrator) evaluateCase(ctx context.Context, ec EvalCase, k int, retrieve, judge bool,
+	result *EvalResult) error {
+	if retrieve {
+		resp, err := o.Retrieve(ctx, RetrieveRequest{Query: ec.Query, K: k})
+		if err != nil {
+			return err
+		}
+		for _, doc := range resp.Documents {
+			result.Retrieved = append(result.Retrieved, doc.ID)
+		}
+		scoreRetrieval(result, k)
+	}
+
+	if judge {
+		resp, err := o.Execute(ctx, QueryRequest{Query: ec.Query, TopN: k})
+		if err != nil {
+			return err
+		}
+		grade, err := o.judgeAnswer(ctx, ec.Query, ec.ReferenceAnswer, resp.Answer)
+		if err != nil {
+			return err
+		}
+		result.Answer = resp.Answer
+		result.AnswerQuality = &grade
+	}
+	return nil
+}
+
+// judgePrompt asks the completion LLM to grade an answer against a
+// reference answer.
+const judgePrompt = `You grade answers to questions against a reference answer.
+Reply 5 if the answer states what the reference answer does, in any words, and contradicts none of it; 1 if it is wrong or does not answer the question; and 2, 3 or 4 for answers in between.
+Reply with the one digit only.`
+
+// judgeAnswer asks the completion provider to grade an answer to query
+// against a reference answer, bounded by the pipeline's
+// completion_timeout, and returns the grade scaled from 0 to 1.
+func (o *Orchestrator) judgeAnswer(ctx context.Context, query, reference, answer string) (float64, error) {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: judgePrompt,
+		Messages: []llmlib.Message{
+			llmlib.UserText("Question: " + query + "\n\nReference answer:\n" + reference +
+				"\n\nAnswer:\n" + answer),
+		},
+		MaxTokens: llmlib.Int(8),
+	})
+	o.observeStage(metrics.StageCompletion, o.completionProvider(), start, err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to judge the answer: %w", stageTimeout(ctx, err))
+	}
+	o.recordUsage(metrics.StageCompletion, o.completionProvider(), resp.Usage)
+
+	reply := strings.TrimSpace(joinTextBlocks(resp.Content))
+	if i := strings.IndexAny(reply, "12345"); i >= 0 {
+		return float64(reply[i]-'1') / 4, nil
+	}
+	return 0, fmt.Errorf("the judge's reply %q is not a grade from 1 to 5", reply)
+}
+
+// scoreRetrieval scores the sources a case retrieved against those it
+// expected, each relevant once however many of its chunks were
+// retrieved. The ideal ranking for nDCG puts as many expected sources
+// as fit in the top k first.
+func scoreRetrieval(result *EvalResult, k int) {
+	found := make(map[string]bool)
+	dcg := 0.0
+	for i, id := range result.Retrieved {
+		if found[id] || !slices.Contains(result.Expected, id) {
+			continue
+		}
+		found[id] = true
+		dcg += 1 / math.Log2(float64(i+2))
+		if result.Rank == 0 {
+			result.Rank = i + 1
+		}
+	}
+
+	ideal := 0.0
+	for i := range min(len(result.Expected), k) {
+		ideal += 1 / math.Log2(float64(i+2))
+	}
+	result.Hit = len(found) > 0
+	result.Recall = float64(len(found)) / float64(len(result.Expected))
+	if ideal > 0 {
+		result.NDCG = dcg / ideal
+	}
+}
+
+// PipelineEvalReport is a pipeline's EvalReport, named, as reports of
+// several pipelines are given.
+type PipelineEvalReport struct {
+	Pipeline string `json:"pipeline"`
+	*EvalReport
+}
+
+// evalCSVHeader names the columns WriteEvalCSV writes.
+var evalCSVHeader = []string{
+	"pipeline", "query", "expected_sources", "retrieved_sources", "hit", "rank",
+	"recall", "ndcg", "answer", "answer_quality", "error",
+}
+
+// WriteEvalCSV writes the results of reports as CSV, one row per case
+// evaluated, judged or failed, with a header row. Source IDs are
+// separated by spaces, and columns a case was not scored on are empty.
+func WriteEvalCSV(w io.Writer, reports []PipelineEvalReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(evalCSVHeader); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		for _, r := range report.Results {
+			row := []string{report.Pipeline, r.Query, strings.Join(r.Expected, " "),
+				strings.Join(r.Retrieved, " "), "", "", "", "", r.Answer, "", r.Error}
+			if r.Error == "" && len(r.Expected) > 0 {
+				row[4] = strconv.FormatBool(r.Hit)
+				if r.Rank > 0 {
+					row[5] = strconv.Itoa(r.Rank)
+				}
+				row[6] = strconv.FormatFloat(r.Recall, 'f', -1, 64)
+				row[7] = strconv.FormatFloat(r.NDCG, 'f', -1, 64)
+			}
+			if r.AnswerQuality != nil {
+				row[9] = strconv.FormatFloat(*r.AnswerQuality, 'f', -1, 64)
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadEvalDataset decodes a dataset of newline-delimited JSON cases, in
+// the format the eval dataset is exported in.
+func ReadEvalDataset(r io.Reader) ([]EvalCase, error) {
+	dec := json.NewDecoder(r)
+	var cases []EvalCase
+	for {
+		var ec EvalCase
+		err := dec.Decode(&ec)
+		if errors.Is(err, io.EOF) {
+			return cases, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid dataset case %d: %w", len(cases)+1, err)
+		}
+		if ec.Query == "" {
+			return nil, fmt.Errorf("invalid dataset case %d: query is required", len(cases)+1)
+		}
+		cases = append(cases, ec)
+	}
+}
@@ -0,0 +1,195 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestEvalCapture(t *testing.T) {
+	c := newEvalCapture(config.EvalCaptureConfig{
+		Enabled:        true,
+		SampleRate:     0.5,
+		MaxEntries:     2,
+		RedactPatterns: []string{`ACME-\w+`},
+	})
+	sample := 0.0
+	c.sample = func() float64 { return sample }
+
+	id := c.capture("Why was jane@example.com charged on card 4111 1111 1111 1111 for ACME-42 on 16.4.1?", []string{"doc-1"})
+	if id == "" {
+		t.Fatal("expected the query to be captured")
+	}
+	got := c.dataset()
+	if len(got) != 1 || got[0].Query != "Why was [email] charged on card [number] for [redacted] on 16.4.1?" ||
+		!reflect.DeepEqual(got[0].ExpectedSources, []string{"doc-1"}) || got[0].Time == nil {
+		t.Fatalf("expected the anonymized query and its sources, got %+v", got)
+	}
+
+	sample = 0.5
+	if c.capture("Not sampled", nil) != "" {
+		t.Error("expected a query outside the sample rate not to be captured")
+	}
+
+	sample = 0
+	c.capture("Second", nil)
+	c.capture("Third", nil)
+	if got := c.dataset(); len(got) != 2 || got[0].Query != "Second" {
+		t.Errorf("expected the oldest query dropped, got %+v", got)
+	}
+	if err := c.feedback(FeedbackRequest{QueryID: id, Rating: FeedbackUp}); !errors.Is(err, ErrQueryNotFound) {
+		t.Errorf("expected feedback on a dropped query to fail, got %v", err)
+	}
+
+	var disabled *evalCapture
+	if disabled.capture("q", nil) != "" || disabled.dataset() != nil || newEvalCapture(config.EvalCaptureConfig{}) != nil {
+		t.Error("expected no capture when disabled")
+	}
+}
+
+func TestOrchestrator_Execute_EvalCapture(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	orch.evalCapture = newEvalCapture(config.EvalCaptureConfig{Enabled: true, SampleRate: 1})
+	ctx := context.Background()
+
+	resp, err := orch.Execute(ctx, QueryRequest{Query: "How does replication work?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.QueryID == "" {
+		t.Fatal("expected the query to be captured")
+	}
+	if err := orch.Feedback(ctx, FeedbackRequest{QueryID: resp.QueryID, Rating: FeedbackDown, Comment: "Mail me at jane@example.com"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	dataset := orch.EvalDataset()
+	if len(dataset) != 1 || dataset[0].Query != "How does replication work?" ||
+		!reflect.DeepEqual(dataset[0].ExpectedSources, []string{"doc-1", "doc-2", "doc-3"}) ||
+		dataset[0].Feedback != FeedbackDown || dataset[0].Comment != "Mail me at [email]" {
+		t.Errorf("expected the query with its sources and feedback, got %+v", dataset)
+	}
+
+	// Queries with conversation history are not captured.
+	resp, err = orch.Execute(ctx, QueryRequest{Query: "And logical?",
+		Messages: []Message{{Role: "user", Content: "Hi"}, {Role: "assistant", Content: "Hello"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.QueryID != "" || len(orch.EvalDataset()) != 1 {
+		t.Error("expected a query with history not to be captured")
+	}
+
+	for _, req := range []FeedbackRequest{{Rating: FeedbackUp}, {QueryID: resp.QueryID + "x", Rating: "meh"}} {
+		if err := orch.Feedback(ctx, req); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("expected %+v to be invalid, got %v", req, err)
+		}
+	}
+}
+
+func TestOrchestrator_Evaluate(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+
+	report, err := orch.Evaluate(context.Background(), []EvalCase{
+		{Query: "streaming standby", ExpectedSources: []string{"doc-1"}},
+		{Query: "logical replication", ExpectedSources: []string{"doc-2", "doc-9"}},
+		{Query: "vacuum", ExpectedSources: []string{"doc-3"}},
+		{Query: "no sources"},
+		{Query: "rated down", ExpectedSources: []string{"doc-1"}, Feedback: FeedbackDown},
+	}, EvalOptions{K: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.K != 2 || report.Cases != 5 || report.Evaluated != 3 || report.Skipped != 2 || len(report.Results) != 3 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	// doc-1 at rank 1, doc-2 at rank 2, doc-3 not in the top 2.
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	if !near(report.HitRate, 2.0/3) || !near(report.Recall, 1.5/3) || !near(report.MRR, 1.5/3) {
+		t.Errorf("unexpected scores: hit rate %v, recall %v, MRR %v", report.HitRate, report.Recall, report.MRR)
+	}
+	if r := report.Results[1]; !r.Hit || r.Rank != 2 || r.Recall != 0.5 ||
+		!reflect.DeepEqual(r.Retrieved, []string{"doc-1", "doc-2"}) {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	if report.AnswerQuality != nil || report.Results[0].AnswerQuality != nil {
+		t.Errorf("expected no answers judged unless asked, got %+v", report)
+	}
+
+	if _, err := orch.Evaluate(context.Background(), nil, EvalOptions{K: -1}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected a negative k to be invalid, got %v", err)
+	}
+}
+
+func TestOrchestrator_Evaluate_Judge(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	var judged []string
+	orch.completionProv = &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			text := "WAL is streamed to the standby."
+			if req.SystemPrompt == judgePrompt {
+				prompt := req.Messages[0].Content[0].Text
+				judged = append(judged, prompt)
+				switch {
+				case strings.Contains(prompt, "Reference answer:\nLogical"):
+					text = "2"
+				case strings.Contains(prompt, "Reference answer:\nNo idea"):
+					text = "Unsure"
+				default:
+					text = "5."
+				}
+			}
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}}}, nil
+		},
+	}
+
+	report, err := orch.Evaluate(context.Background(), []EvalCase{
+		{Query: "streaming standby", ExpectedSources: []string{"doc-1"},
+			ReferenceAnswer: "The primary streams WAL to the standby."},
+		{Query: "logical replication", ReferenceAnswer: "Logical replication publishes table changes."},
+		{Query: "vacuum", ExpectedSources: []string{"doc-3"}},
+		{Query: "unknown", ReferenceAnswer: "No idea"},
+		{Query: "rated down", ReferenceAnswer: "Anything", Feedback: FeedbackDown},
+	}, EvalOptions{K: 2, Judge: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Evaluated != 2 || report.Judged != 2 || report.Failed != 1 || report.Skipped != 1 ||
+		len(report.Results) != 4 || len(judged) != 3 {
+		t.Fatalf("unexpected report %+v after %d judgements", report, len(judged))
+	}
+	if report.AnswerQuality == nil || *report.AnswerQuality != 0.625 {
+		t.Errorf("expected a mean answer quality of 0.625, got %v", report.AnswerQuality)
+	}
+	first := report.Results[0]
+	if !first.Hit || first.Answer != "WAL is streamed to the standby." || first.AnswerQuality == nil || *first.AnswerQuality != 1 {
+		t.Errorf("expected the first case retrieved and graded 1, got %+v", first)
+	}
+	if !strings.Contains(judged[0], "Question: streaming standby") ||
+		!strings.Contains(judged[0], "Answer:\nWAL is streamed to the standby.") {
+		t.Errorf("expected the question, reference and answer judged, got %q", judged[0])
+	}
+	if q := report.Results[1].AnswerQuality; q == nil || *q != 0.25 || report.Results[1].Retrieved != nil {
+		t.Errorf("expected the second case only judged, at 0.25, got %+v", report.Results[1])
+	}
+	if r := report.Results[3]; !strings.Contains(r.Error, "not a grade") {
+		t.Errorf("expected an ungraded answer reported, got %+v", r)
+	}
+}
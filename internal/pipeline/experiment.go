@@ -0,0 +1,54 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	mathrand "math/rand/v2"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// experiment splits the queries asked of a pipeline between its
+// variants.
+type experiment struct {
+	pipeline string
+	variants []config.ExperimentVariant
+	random   func() float64 // overridden in tests
+}
+
+func newExperiment(cfg config.ExperimentConfig) *experiment {
+	return &experiment{
+		pipeline: cfg.Pipeline,
+		variants: cfg.Variants,
+		random:   mathrand.Float64,
+	}
+}
+
+// assign returns the pipeline that answers a query: the variant whose
+// share of the traffic the query falls in, or the experiment's own
+// pipeline when it falls in none. Queries with the same non-empty key
+// fall in the same place; others fall at random.
+func (e *experiment) assign(key string) string {
+	point := e.random()
+	if key != "" {
+		sum := sha256.Sum256([]byte(e.pipeline + "\x00" + key))
+		point = float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+	}
+	point *= 100
+	for _, v := range e.variants {
+		if point < v.Traffic {
+			return v.Pipeline
+		}
+		point -= v.Traffic
+	}
+	return e.pipeline
+}
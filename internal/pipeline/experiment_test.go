@@ -0,0 +1,82 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestExperiment_Assign(t *testing.T) {
+	e := newExperiment(config.ExperimentConfig{
+		Pipeline: "docs",
+		Variants: []config.ExperimentVariant{
+			{Pipeline: "docs-sonnet", Traffic: 20},
+			{Pipeline: "docs-hybrid", Traffic: 30},
+		},
+	})
+
+	for _, tt := range []struct {
+		point float64
+		want  string
+	}{
+		{0, "docs-sonnet"},
+		{0.19, "docs-sonnet"},
+		{0.2, "docs-hybrid"},
+		{0.49, "docs-hybrid"},
+		{0.5, "docs"},
+		{0.99, "docs"},
+	} {
+		e.random = func() float64 { return tt.point }
+		if got := e.assign(""); got != tt.want {
+			t.Errorf("assign at %v = %q, want %q", tt.point, got, tt.want)
+		}
+	}
+
+	// Keyed queries ignore the random point, land in the same variant
+	// each time, and are split in about the configured shares.
+	e.random = func() float64 { return 0 }
+	counts := make(map[string]int)
+	for i := range 10000 {
+		key := fmt.Sprintf("session-%d", i)
+		got := e.assign(key)
+		if again := e.assign(key); again != got {
+			t.Fatalf("expected key %s assigned %q again, got %q", key, got, again)
+		}
+		counts[got]++
+	}
+	for name, want := range map[string]int{"docs-sonnet": 2000, "docs-hybrid": 3000, "docs": 5000} {
+		if got := counts[name]; got < want-300 || got > want+300 {
+			t.Errorf("expected about %d sessions assigned to %s, got %d", want, name, got)
+		}
+	}
+}
+
+func TestManager_Route(t *testing.T) {
+	cfg := &config.Config{
+		Pipelines: []config.Pipeline{{Name: "docs"}, {Name: "docs-sonnet"}, {Name: "faq"}},
+	}
+	m := newTestManager(cfg)
+	m.experiments = map[string]*experiment{
+		"docs": newExperiment(config.ExperimentConfig{
+			Pipeline: "docs",
+			Variants: []config.ExperimentVariant{{Pipeline: "docs-sonnet", Traffic: 100}},
+		}),
+	}
+
+	if name, ok := m.Route("docs", ""); !ok || name != "docs-sonnet" {
+		t.Errorf("expected docs routed to docs-sonnet, got %q, %v", name, ok)
+	}
+	if name, ok := m.Route("faq", "session-1"); ok || name != "faq" {
+		t.Errorf("expected faq to answer its own queries, got %q, %v", name, ok)
+	}
+}
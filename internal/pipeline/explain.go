@@ -0,0 +1,396 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/bm25"
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// ExplainRequest asks why a document ranked where it did for a query.
+type ExplainRequest struct {
+	Query        string         `json:"query"`
+	DocumentID   string         `json:"document_id"`             // Value of the table's id_column
+	TopN         int            `json:"top_n,omitempty"`         // Override default top-N results
+	Filter       *config.Filter `json:"filter,omitempty"`        // Structured filter, as on a query
+	FilterPreset string         `json:"filter_preset,omitempty"` // A pipeline filter preset, as on a query
+}
+
+// Explanation reports how a document fared at each retrieval step of
+// a query, from filtering through reranking.
+type Explanation struct {
+	Query      string             `json:"query"`
+	DocumentID string             `json:"document_id"`
+	Retrieved  bool               `json:"retrieved"`      // Among the results sent to the LLM
+	Rank       int                `json:"rank,omitempty"` // 1-based position in those results
+	Tables     []TableExplanation `json:"tables"`
+	Rerank     *RerankExplanation `json:"rerank,omitempty"` // Set when the document was reranked
+}
+
+// TableExplanation covers one search of one table. Vector, BM25,
+// FullText and Fusion are omitted for steps the pipeline does not run;
+// only one of BM25 and FullText is set, by the table's lexical_search.
+// Error is set when the table could not be searched. A retrieval
+// strategy that searches several times, such as multi_query, gives a
+// table one explanation per search, each with the query it ran.
+type TableExplanation struct {
+	Table    string               `json:"table"`
+	Query    string               `json:"query,omitempty"` // Set when the search ran a query other than the request's
+	Filter   *FilterExplanation   `json:"filter,omitempty"`
+	Vector   *VectorExplanation   `json:"vector,omitempty"`
+	BM25     *BM25Explanation     `json:"bm25,omitempty"`
+	FullText *FullTextExplanation `json:"full_text,omitempty"`
+	Fusion   *FusionExplanation   `json:"fusion,omitempty"`
+	Visible  *bool                `json:"visible,omitempty"` // Whether access control let the caller see the document; only when it was found
+	Error    string               `json:"error,omitempty"`
+}
+
+// FilterExplanation reports whether the document passes the table's
+// configured filter and the request's filter.
+type FilterExplanation struct {
+	InTable              bool  `json:"in_table"`                         // Present, with text, and passing the table's filter
+	MatchesRequestFilter *bool `json:"matches_request_filter,omitempty"` // Only when the request has a filter
+}
+
+// VectorExplanation reports the document's place in the vector search
+// results. Similarity is only known when the document is among them.
+type VectorExplanation struct {
+	Candidates int     `json:"candidates"`     // Results the vector search returned
+	Rank       int     `json:"rank,omitempty"` // 1-based; omitted when not among them
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+// BM25Explanation reports the document's place in the BM25 results and
+// how its score was made up.
+type BM25Explanation struct {
+	Candidates int `json:"candidates"`     // Results the BM25 search returned
+	Rank       int `json:"rank,omitempty"` // 1-based; omitted when not among them
+	bm25.Explanation
+}
+
+// FullTextExplanation reports the document's place in the PostgreSQL
+// full-text search results. Score is only known when the document is
+// among them.
+type FullTextExplanation struct {
+	Candidates int     `json:"candidates"`      // Results the full-text search returned
+	Rank       int     `json:"rank,omitempty"`  // 1-based; omitted when not among them
+	Score      float64 `json:"score,omitempty"` // ts_rank
+}
+
+// FusionExplanation shows how the document's vector and lexical
+// results were fused. With reciprocal rank fusion each rank r
+// contributes weight / (k + r); with score fusion each min-max
+// normalized score s contributes weight * s. BM25Score is the lexical
+// arm's share, from BM25 or full-text search.
+type FusionExplanation struct {
+	Method       string  `json:"method"`      // "rrf" or "score_fusion"
+	K            float64 `json:"k,omitempty"` // RRF only
+	VectorWeight float64 `json:"vector_weight"`
+	VectorScore  float64 `json:"vector_score"` // The vector result's contribution to score
+	BM25Score    float64 `json:"bm25_score"`   // The lexical result's contribution to score
+	Score        float64 `json:"score"`
+	Rank         int     `json:"rank,omitempty"` // 1-based among the table's fused results
+}
+
+// RerankExplanation shows how reranking moved the document.
+type RerankExplanation struct {
+	RankBefore int     `json:"rank_before"`
+	RankAfter  int     `json:"rank_after,omitempty"` // Omitted when rerank.top_k dropped the document
+	Score      float64 `json:"score,omitempty"`      // The reranker's relevance score
+}
+
+// Explain runs a query's retrieval as a query would, through its
+// retrieval strategy, rewrite_query and filter_results hooks, access
+// control, parent expansion, reranking and chunk merging, without
+// generating an answer, and reports how the requested document fared
+// at each step. Each table is additionally asked for the document
+// alone, to tell whether the filters exclude it.
+func (o *Orchestrator) Explain(ctx context.Context, req ExplainRequest) (*Explanation, error) {
+	if req.Query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidRequest)
+	}
+	if req.DocumentID == "" {
+		return nil, fmt.Errorf("%w: document_id is required", ErrInvalidRequest)
+	}
+	if err := o.checkFilter(req.Filter); err != nil {
+		return nil, err
+	}
+	if err := o.checkFilterVars(ctx); err != nil {
+		return nil, err
+	}
+	filter, err := o.applyFilterPreset(req.FilterPreset, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	topN := o.topN
+	if req.TopN > 0 {
+		topN = req.TopN
+	}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+	defer cancel()
+
+	trace := &explainTrace{query: req.Query, documentID: req.DocumentID}
+	ctx = context.WithValue(ctx, explainKey{}, trace)
+
+	usage := &StageUsage{}
+	defer o.chargeCost(usage)
+	searchReq := QueryRequest{Query: o.rewriteQuery(ctx, req.Query), TopN: topN, Filter: filter}
+	embedding, err := o.embedWithTimeout(ctx, searchReq.Query, usage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	results, err := o.retrieve(ctx, searchReq, embedding, topN, usage)
+	if err != nil {
+		return nil, err
+	}
+	results = o.filterResults(ctx, req.Query, results)
+
+	out := &Explanation{Query: req.Query, DocumentID: req.DocumentID, Tables: trace.tables}
+	o.explainFilters(ctx, out.Tables, req.DocumentID, filter)
+
+	if rank := resultRank(results, req.DocumentID); rank > 0 && o.reranker != nil {
+		results = o.rerank(ctx, req.Query, results, usage)
+		out.Rerank = &RerankExplanation{RankBefore: rank}
+		if after := resultRank(results, req.DocumentID); after > 0 {
+			out.Rerank.RankAfter = after
+			out.Rerank.Score = results[after-1].Score
+		}
+	}
+	out.Rank = resultRank(o.mergeChunks(results), req.DocumentID)
+	out.Retrieved = out.Rank > 0
+	return out, nil
+}
+
+// explainFilters sets the filter explanation of each table searched.
+// Each table is asked for the document by its id_column, alone and
+// with the request's filter, rather than fetched in full.
+func (o *Orchestrator) explainFilters(
+	ctx context.Context,
+	tables []TableExplanation,
+	documentID string,
+	filter *config.Filter,
+) {
+	if o.dbPool == nil {
+		return
+	}
+	for _, table := range o.cfg.Tables {
+		if table.IDColumn == "" {
+			continue
+		}
+		fe, err := o.explainFilter(ctx, table, documentID, filter)
+		for i := range tables {
+			if tables[i].Table != table.Table {
+				continue
+			}
+			if err != nil && tables[i].Error == "" {
+				tables[i].Error = err.Error()
+			}
+			tables[i].Filter = fe
+		}
+	}
+}
+
+// explainFilter reports whether document id is in table, passing its
+// configured filter, and whether it matches filter too.
+func (o *Orchestrator) explainFilter(
+	ctx context.Context,
+	table config.TableSource,
+	id string,
+	filter *config.Filter,
+) (*FilterExplanation, error) {
+	byID := config.Filter{Conditions: []config.FilterCondition{
+		{Column: table.IDColumn, Operator: "=", Value: id},
+	}}
+	limits := database.FetchLimits{MaxRows: 1}
+	docs, err := o.dbPool.FetchDocuments(ctx, table, &byID, limits)
+	if err != nil {
+		return nil, err
+	}
+	_, inTable := docs[id]
+	fe := &FilterExplanation{InTable: inTable}
+	if filter == nil {
+		return fe, nil
+	}
+
+	matches := false
+	if inTable {
+		both := byID
+		both.Groups = []config.Filter{*filter}
+		docs, err = o.dbPool.FetchDocuments(ctx, table, &both, limits)
+		if err != nil {
+			return nil, err
+		}
+		_, matches = docs[id]
+	}
+	fe.MatchesRequestFilter = &matches
+	return fe, nil
+}
+
+// explainKey is the context key under which an explained query's
+// searches record their explanationTrace.
+type explainKey struct{}
+
+// explainTrace collects the table explanations of an explained query's
+// searches; a retrieval strategy may search several times.
+type explainTrace struct {
+	query      string // The request's query, before rewriting
+	documentID string
+
+	mu     sync.Mutex
+	tables []TableExplanation
+}
+
+// explainTraceFrom returns the trace ctx records into, or nil.
+func explainTraceFrom(ctx context.Context) *explainTrace {
+	trace, _ := ctx.Value(explainKey{}).(*explainTrace)
+	return trace
+}
+
+// table starts the explanation of one table's search for query, or
+// returns nil when the query is not being explained.
+func (t *explainTrace) table(query string, table config.TableSource) *tableTrace {
+	if t == nil {
+		return nil
+	}
+	tt := &tableTrace{documentID: t.documentID, te: TableExplanation{Table: table.Table}}
+	if query != t.query {
+		tt.te.Query = query
+	}
+	if table.IDColumn == "" {
+		tt.te.Error = "table has no id_column, so its documents cannot be identified"
+	}
+	return tt
+}
+
+// add records the explanations of one search's tables, in order.
+func (t *explainTrace) add(tables []*tableTrace) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tt := range tables {
+		t.tables = append(t.tables, tt.te)
+	}
+}
+
+// tableTraceKey is the context key under which a table's search
+// records its tableTrace.
+type tableTraceKey struct{}
+
+// tableTrace records the search of one table for an explanation. Its
+// methods do nothing on a nil tableTrace, so searches that are not
+// explained pay nothing for it.
+type tableTrace struct {
+	documentID string
+	te         TableExplanation
+}
+
+// tableTraceFrom returns the trace ctx records a table's search into,
+// or nil.
+func tableTraceFrom(ctx context.Context) *tableTrace {
+	tt, _ := ctx.Value(tableTraceKey{}).(*tableTrace)
+	return tt
+}
+
+// fail records why the table's search failed.
+func (tt *tableTrace) fail(err error) {
+	if tt == nil {
+		return
+	}
+	tt.te.Error = err.Error()
+}
+
+// vector records the document's place in the vector search results.
+func (tt *tableTrace) vector(results []database.SearchResult) {
+	if tt == nil {
+		return
+	}
+	tt.te.Vector = &VectorExplanation{Candidates: len(results)}
+	if rank := resultRank(results, tt.documentID); rank > 0 {
+		tt.te.Vector.Rank = rank
+		tt.te.Vector.Similarity = results[rank-1].Score
+	}
+}
+
+// fullText records the document's place in the full-text search
+// results.
+func (tt *tableTrace) fullText(results []database.SearchResult) {
+	if tt == nil {
+		return
+	}
+	tt.te.FullText = &FullTextExplanation{Candidates: len(results)}
+	if rank := resultRank(results, tt.documentID); rank > 0 {
+		tt.te.FullText.Rank = rank
+		tt.te.FullText.Score = results[rank-1].Score
+	}
+}
+
+// bm25 records the document's place in the BM25 results, and how idx
+// scores it for query.
+func (tt *tableTrace) bm25(results []database.SearchResult, idx *bm25.Index, query string) {
+	if tt == nil {
+		return
+	}
+	tt.te.BM25 = &BM25Explanation{Candidates: len(results), Rank: resultRank(results, tt.documentID)}
+	if e, ok := idx.Explain(query, tt.documentID); ok {
+		tt.te.BM25.Explanation = e
+	}
+}
+
+// fusion records how the document's vector and lexical results fuse.
+// It fuses them as FusedSearch does, which breaks ties the same way.
+func (tt *tableTrace) fusion(vectorResults, lexicalResults []database.SearchResult, opts database.FusionOptions) {
+	if tt == nil {
+		return
+	}
+	tt.te.Fusion = &FusionExplanation{Method: config.FusionRRF, K: opts.K, VectorWeight: opts.VectorWeight}
+	if opts.Method == config.FusionScore {
+		tt.te.Fusion.Method, tt.te.Fusion.K = config.FusionScore, 0
+	}
+	for i, r := range database.Fuse(vectorResults, lexicalResults, opts) {
+		if r.ID != tt.documentID {
+			continue
+		}
+		tt.te.Fusion.VectorScore = r.VectorScore
+		tt.te.Fusion.BM25Score = r.LexicalScore
+		tt.te.Fusion.Score = r.Score
+		tt.te.Fusion.Rank = i + 1
+	}
+}
+
+// authorized records whether access control kept the document, when
+// the table's search found it and the pipeline has access control.
+func (tt *tableTrace) authorized(before, after []database.SearchResult) {
+	if tt == nil || resultRank(before, tt.documentID) == 0 {
+		return
+	}
+	visible := resultRank(after, tt.documentID) > 0
+	tt.te.Visible = &visible
+}
+
+// resultRank returns the 1-based position of document id in results,
+// or 0 if it is not there.
+func resultRank(results []database.SearchResult, id string) int {
+	for i, r := range results {
+		if r.ID == id {
+			return i + 1
+		}
+	}
+	return 0
+}
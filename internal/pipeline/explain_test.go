@@ -0,0 +1,269 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func newExplainOrchestrator(reranker Reranker) *Orchestrator {
+	docs := map[string]database.Document{
+		"doc-1": {Content: "Streaming replication sends WAL to a standby."},
+		"doc-2": {Content: "Logical replication publishes table changes."},
+		"doc-3": {Content: "Vacuum reclaims storage from dead tuples."},
+	}
+	hybrid := true
+	return newTestOrchestrator([]database.SearchResult{
+		{ID: "doc-2", Content: docs["doc-2"].Content, Score: 0.9},
+		{ID: "doc-1", Content: docs["doc-1"].Content, Score: 0.8},
+	}, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Search = config.SearchConfig{HybridEnabled: &hybrid}
+		backend := cfg.DBPool.(*MockSearchBackend)
+		backend.FetchDocumentsFunc = func(
+			ctx context.Context, table config.TableSource, filter *config.Filter,
+		) (map[string]database.Document, error) {
+			matching := docs
+			if filter != nil && (len(filter.Conditions) == 0 || len(filter.Groups) > 0) {
+				// The tests' request filter keeps only doc-2.
+				matching = map[string]database.Document{"doc-2": docs["doc-2"]}
+			}
+			if filter != nil && len(filter.Conditions) == 1 && filter.Conditions[0].Column == "id" {
+				id := filter.Conditions[0].Value.(string)
+				doc, ok := matching[id]
+				if !ok {
+					return map[string]database.Document{}, nil
+				}
+				return map[string]database.Document{id: doc}, nil
+			}
+			return matching, nil
+		}
+		cfg.Reranker = reranker
+	})
+}
+
+func TestOrchestrator_Explain(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(e.Tables) != 1 {
+		t.Fatalf("expected one table, got %+v", e.Tables)
+	}
+	te := e.Tables[0]
+	if te.Filter == nil || !te.Filter.InTable || te.Filter.MatchesRequestFilter != nil {
+		t.Errorf("unexpected filter evaluation: %+v", te.Filter)
+	}
+	if te.Vector == nil || te.Vector.Rank != 2 || te.Vector.Similarity != 0.8 {
+		t.Errorf("unexpected vector explanation: %+v", te.Vector)
+	}
+	if te.BM25 == nil || te.BM25.Rank != 1 || te.BM25.Score == 0 || len(te.BM25.Terms) != 2 {
+		t.Errorf("unexpected BM25 explanation: %+v", te.BM25)
+	}
+
+	f := te.Fusion
+	wantVector, wantBM25 := 0.5/(database.DefaultRRFConstant+2), 0.5/(database.DefaultRRFConstant+1)
+	if f == nil || math.Abs(f.VectorScore-wantVector) > 1e-12 || math.Abs(f.BM25Score-wantBM25) > 1e-12 ||
+		math.Abs(f.Score-(wantVector+wantBM25)) > 1e-12 || f.Rank != 1 {
+		t.Errorf("unexpected fusion explanation: %+v", f)
+	}
+	if !e.Retrieved || e.Rank != 1 || e.Rerank != nil {
+		t.Errorf("expected the document to be retrieved first, got %+v", e)
+	}
+}
+
+func TestOrchestrator_Explain_FullText(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+	orch.cfg.Tables[0].LexicalSearch = config.LexicalSearchPostgresFTS
+	orch.dbPool.(*MockSearchBackend).TextSearchFunc = func(
+		ctx context.Context, queryText string, table config.TableSource,
+		topN int, filter *config.Filter,
+	) ([]database.SearchResult, error) {
+		return []database.SearchResult{
+			{ID: "doc-1", Content: "Streaming replication sends WAL to a standby.", Score: 0.6},
+		}, nil
+	}
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	te := e.Tables[0]
+	if te.BM25 != nil {
+		t.Errorf("expected no BM25 explanation for a postgres_fts table, got %+v", te.BM25)
+	}
+	if te.FullText == nil || te.FullText.Candidates != 1 || te.FullText.Rank != 1 || te.FullText.Score != 0.6 {
+		t.Errorf("unexpected full-text explanation: %+v", te.FullText)
+	}
+	if te.Fusion == nil || te.Fusion.Rank != 1 || te.Fusion.BM25Score == 0 {
+		t.Errorf("expected the full-text rank to be fused, got %+v", te.Fusion)
+	}
+}
+
+func TestOrchestrator_Explain_ScoreFusion(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+	vectorWeight, lexicalWeight := 0.2, 0.3
+	orch.cfg.Search.Fusion = config.FusionScore
+	orch.cfg.Search.VectorWeight = &vectorWeight
+	orch.cfg.Search.LexicalWeight = &lexicalWeight
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// doc-1 has the lowest similarity, normalized to 0, and the only
+	// BM25 match, normalized to 1; the weights are shared 0.4 / 0.6.
+	f := e.Tables[0].Fusion
+	if f == nil || f.Method != config.FusionScore || f.K != 0 || math.Abs(f.VectorWeight-0.4) > 1e-12 {
+		t.Fatalf("unexpected fusion settings: %+v", f)
+	}
+	if f.VectorScore != 0 || math.Abs(f.BM25Score-0.6) > 1e-12 || math.Abs(f.Score-0.6) > 1e-12 || f.Rank != 1 {
+		t.Errorf("unexpected fusion explanation: %+v", f)
+	}
+}
+
+func TestOrchestrator_Explain_Filtered(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+		Filter:     &config.Filter{},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	te := e.Tables[0]
+	if te.Filter.MatchesRequestFilter == nil || *te.Filter.MatchesRequestFilter || !te.Filter.InTable {
+		t.Errorf("expected the request filter to exclude the document, got %+v", te.Filter)
+	}
+	if te.BM25.Rank != 0 || te.BM25.Score != 0 {
+		t.Errorf("expected a filtered-out document to have no BM25 result, got %+v", te.BM25)
+	}
+}
+
+func TestOrchestrator_Explain_Rerank(t *testing.T) {
+	reranker := &MockReranker{
+		RerankFunc: func(ctx context.Context, req llmlib.RerankRequest) (*llmlib.RerankResponse, error) {
+			// Reverse the order.
+			results := make([]llmlib.RerankResult, len(req.Documents))
+			for i := range req.Documents {
+				results[i] = llmlib.RerankResult{Index: len(req.Documents) - 1 - i, RelevanceScore: 0.5}
+			}
+			return &llmlib.RerankResponse{Results: results}, nil
+		},
+	}
+	orch := newExplainOrchestrator(reranker)
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e.Rerank == nil || e.Rerank.RankBefore != 1 || e.Rerank.RankAfter != 2 ||
+		e.Rerank.Score != 0.5 || e.Rank != 2 {
+		t.Errorf("unexpected rerank explanation: %+v (rank %d)", e.Rerank, e.Rank)
+	}
+}
+
+func TestOrchestrator_Explain_InvalidRequest(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+	for _, req := range []ExplainRequest{
+		{DocumentID: "doc-1"},
+		{Query: "replication"},
+	} {
+		if _, err := orch.Explain(context.Background(), req); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("%+v: expected ErrInvalidRequest, got %v", req, err)
+		}
+	}
+}
+
+func TestOrchestrator_Explain_AccessControl(t *testing.T) {
+	orch := newExplainOrchestrator(nil)
+	orch.authorizer = &stubAuthorizer{visible: []string{"doc-2"}}
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "streaming standby",
+		DocumentID: "doc-1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	te := e.Tables[0]
+	if te.Visible == nil || *te.Visible {
+		t.Errorf("expected access control to hide the document, got %+v", te.Visible)
+	}
+	if te.Fusion == nil || te.Fusion.Rank != 1 || e.Retrieved || e.Rank != 0 {
+		t.Errorf("expected a hidden document ranked first to be left out, got %+v", e)
+	}
+}
+
+func TestOrchestrator_Explain_MultiQuery(t *testing.T) {
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "set up a standby"}},
+			}, nil
+		},
+	}
+	orch := newMultiQueryOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a"},
+		"set up a standby":                {"doc-b", "doc-a"},
+	}, 1)
+
+	e, err := orch.Explain(context.Background(), ExplainRequest{
+		Query:      "configure streaming replication",
+		DocumentID: "doc-b",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Each search of the strategy is explained, the variant's with its
+	// query.
+	var ranks []string
+	for _, te := range e.Tables {
+		if te.Vector == nil {
+			t.Fatalf("expected a vector explanation, got %+v", te)
+		}
+		ranks = append(ranks, te.Query+":"+strconv.Itoa(te.Vector.Rank))
+	}
+	sort.Strings(ranks)
+	if got := strings.Join(ranks, ","); got != ":0,set up a standby:1" {
+		t.Errorf("unexpected searches: %s", got)
+	}
+	if !e.Retrieved || e.Rank != 2 {
+		t.Errorf("expected the variant's document to be fused in second, got %+v", e)
+	}
+}
@@ -0,0 +1,264 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// Circuit breaker defaults, used when the pipeline's circuit_breaker
+// settings are zero.
+const (
+	DefaultFailureThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrNoCompletionProvider is returned when every completion provider's
+// circuit breaker is open.
+var ErrNoCompletionProvider = errors.New("no completion provider available: all circuit breakers are open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker stops requests to a provider after threshold
+// consecutive failures. Once cooldown has passed it lets a single probe
+// through: success closes the breaker, failure reopens it.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent, and whether it is the
+// half-open probe.
+func (b *circuitBreaker) allow() (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, false
+		}
+		b.state = breakerHalfOpen
+		b.probing = true
+		return true, true
+	case breakerHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	default:
+		return true, false
+	}
+}
+
+// success records a successful request, reporting whether it closed an
+// open breaker.
+func (b *circuitBreaker) success() (recovered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recovered = b.state != breakerClosed
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+	return recovered
+}
+
+// failure records a failed request, reporting whether it opened the
+// breaker.
+func (b *circuitBreaker) failure() (tripped bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = b.now()
+		b.probing = false
+		return true
+	}
+	return false
+}
+
+// abandon releases a half-open probe whose caller gave up before the
+// provider answered, so the next request can probe instead.
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// completionTarget is one completion provider behind a failover
+// completer.
+type completionTarget struct {
+	name     string // provider/model, for logs
+	answerer Answerer
+	client   Completer
+	breaker  *circuitBreaker
+}
+
+// answererKey is the context key of the Answerer a query's completion
+// records the provider that answered in.
+type answererKey struct{}
+
+// withAnswerer returns ctx carrying a, which the failover completer
+// sets to the provider that answers a request made with it.
+func withAnswerer(ctx context.Context, a *Answerer) context.Context {
+	return context.WithValue(ctx, answererKey{}, a)
+}
+
+// answererFrom returns the Answerer ctx carries, or nil.
+func answererFrom(ctx context.Context) *Answerer {
+	a, _ := ctx.Value(answererKey{}).(*Answerer)
+	return a
+}
+
+// failoverCompleter sends each request to the first completion provider
+// whose circuit breaker allows it, moving on to the next when a
+// provider fails. Streams fail over only if they fail to start; once
+// the answer has begun streaming, an error is returned as is. Invalid
+// requests are not failed over, since another provider would reject
+// them too.
+type failoverCompleter struct {
+	targets []completionTarget
+	logger  *slog.Logger
+}
+
+func (f *failoverCompleter) Chat(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+	var resp *llmlib.ChatResponse
+	err := f.try(ctx, func(c Completer) error {
+		var err error
+		resp, err = c.Chat(ctx, req)
+		return err
+	})
+	return resp, err
+}
+
+func (f *failoverCompleter) ChatStream(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+	var stream *llmlib.Stream
+	err := f.try(ctx, func(c Completer) error {
+		var err error
+		stream, err = c.ChatStream(ctx, req)
+		return err
+	})
+	return stream, err
+}
+
+// try calls call with each available provider in turn until one
+// succeeds, updating the providers' circuit breakers as it goes.
+func (f *failoverCompleter) try(ctx context.Context, call func(Completer) error) error {
+	var lastErr error
+	var failed string
+	for _, t := range f.targets {
+		ok, probe := t.breaker.allow()
+		if !ok {
+			continue
+		}
+		if failed != "" {
+			f.logger.Warn("failing over to fallback completion provider",
+				"from", failed, "to", t.name, "error", lastErr)
+		}
+
+		err := call(t.client)
+		switch {
+		case err == nil || errors.Is(err, llmlib.ErrInvalidRequest):
+			if t.breaker.success() {
+				f.logger.Info("completion provider recovered; circuit breaker closed",
+					"provider", t.name)
+			}
+			if a := answererFrom(ctx); a != nil && err == nil {
+				*a = t.answerer
+			}
+			return err
+		case ctx.Err() != nil:
+			if probe {
+				t.breaker.abandon()
+			}
+			return err
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			// The provider is not failing, only at its limit, so the
+			// query fails over without counting against it.
+			if probe {
+				t.breaker.abandon()
+			}
+			lastErr, failed = err, t.name
+			continue
+		}
+
+		if t.breaker.failure() {
+			f.logger.Warn("completion provider failing; circuit breaker opened",
+				"provider", t.name, "cooldown", t.breaker.cooldown, "error", err)
+		}
+		lastErr, failed = err, t.name
+	}
+
+	if lastErr == nil {
+		return ErrNoCompletionProvider
+	}
+	return lastErr
+}
+
+// Usage returns the combined usage of every provider.
+func (f *failoverCompleter) Usage() llmlib.TokenUsage {
+	var total llmlib.TokenUsage
+	for _, t := range f.targets {
+		total.Add(t.client.Usage())
+	}
+	return total
+}
+
+// Ping succeeds if any provider is reachable, since queries can still
+// be answered; otherwise it returns the primary provider's error.
+func (f *failoverCompleter) Ping(ctx context.Context) error {
+	var firstErr error
+	for _, t := range f.targets {
+		err := t.client.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	return firstErr
+}
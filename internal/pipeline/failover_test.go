@@ -0,0 +1,332 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// fakeClock is a controllable time source for circuit breakers.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	b := newCircuitBreaker(2, time.Minute)
+	b.now = clock.now
+
+	if b.failure() {
+		t.Fatal("expected the breaker to stay closed after one failure")
+	}
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("expected a closed breaker to allow requests")
+	}
+	if !b.failure() {
+		t.Fatal("expected the breaker to open at the threshold")
+	}
+	if ok, _ := b.allow(); ok {
+		t.Fatal("expected an open breaker to reject requests")
+	}
+
+	clock.advance(time.Minute)
+	if ok, probe := b.allow(); !ok || !probe {
+		t.Fatalf("expected a probe after the cooldown, got ok=%v probe=%v", ok, probe)
+	}
+	if ok, _ := b.allow(); ok {
+		t.Fatal("expected only one probe at a time")
+	}
+	if !b.failure() {
+		t.Fatal("expected a failed probe to reopen the breaker")
+	}
+	if ok, _ := b.allow(); ok {
+		t.Fatal("expected the reopened breaker to reject requests")
+	}
+
+	clock.advance(time.Minute)
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("expected a second probe after another cooldown")
+	}
+	b.abandon()
+	if ok, _ := b.allow(); !ok {
+		t.Fatal("expected an abandoned probe to let the next request probe")
+	}
+	if !b.success() {
+		t.Fatal("expected a successful probe to report recovery")
+	}
+	if ok, probe := b.allow(); !ok || probe {
+		t.Fatalf("expected a closed breaker, got ok=%v probe=%v", ok, probe)
+	}
+	if b.success() {
+		t.Error("expected no recovery report for a closed breaker")
+	}
+}
+
+func TestCircuitBreaker_Defaults(t *testing.T) {
+	b := newCircuitBreaker(0, 0)
+	if b.threshold != DefaultFailureThreshold || b.cooldown != DefaultBreakerCooldown {
+		t.Errorf("expected defaults, got threshold=%d cooldown=%v", b.threshold, b.cooldown)
+	}
+}
+
+// answering returns a completer that answers with text, counting calls.
+func answering(text string, calls *int) *MockCompleter {
+	return &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			*calls++
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{llmlib.TextBlock(text)}}, nil
+		},
+	}
+}
+
+// failing returns a completer that fails with err, counting calls.
+func failing(err error, calls *int) *MockCompleter {
+	return &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			*calls++
+			return nil, err
+		},
+		ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+			*calls++
+			return nil, err
+		},
+	}
+}
+
+func newTestFailover(clock *fakeClock, clients ...Completer) *failoverCompleter {
+	f := &failoverCompleter{logger: slog.New(slog.DiscardHandler)}
+	for i, c := range clients {
+		b := newCircuitBreaker(2, time.Minute)
+		b.now = clock.now
+		name := []string{"primary", "fallback"}[i]
+		f.targets = append(f.targets, completionTarget{
+			name:     name,
+			answerer: Answerer{Provider: name, Model: name + "-model"},
+			client:   c,
+			breaker:  b,
+		})
+	}
+	return f
+}
+
+func TestFailoverCompleter_FailsOver(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var primaryCalls, fallbackCalls int
+	primaryErr := errors.New("upstream unavailable")
+	primary := failing(primaryErr, &primaryCalls)
+	f := newTestFailover(clock, primary, answering("from fallback", &fallbackCalls))
+
+	for i := 0; i < 3; i++ {
+		var by Answerer
+		resp, err := f.Chat(withAnswerer(context.Background(), &by), llmlib.ChatRequest{})
+		if err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+		if got := joinTextBlocks(resp.Content); got != "from fallback" {
+			t.Fatalf("request %d: expected the fallback's answer, got %q", i, got)
+		}
+		if by.Provider != "fallback" || by.Model != "fallback-model" {
+			t.Fatalf("request %d: expected the fallback recorded as the answerer, got %+v", i, by)
+		}
+	}
+	// The primary's breaker opens after two failures, so the third
+	// request goes straight to the fallback.
+	if primaryCalls != 2 || fallbackCalls != 3 {
+		t.Errorf("expected 2 primary and 3 fallback calls, got %d and %d", primaryCalls, fallbackCalls)
+	}
+
+	// After the cooldown the primary is probed and, once it recovers,
+	// takes traffic again.
+	clock.advance(time.Minute)
+	primary.ChatFunc = answering("from primary", &primaryCalls).ChatFunc
+	var by Answerer
+	resp, err := f.Chat(withAnswerer(context.Background(), &by), llmlib.ChatRequest{})
+	if err != nil || joinTextBlocks(resp.Content) != "from primary" {
+		t.Fatalf("expected the recovered primary to answer, got %v, %v", resp, err)
+	}
+	if by.Provider != "primary" {
+		t.Errorf("expected the primary recorded as the answerer, got %+v", by)
+	}
+	if primaryCalls != 3 || fallbackCalls != 3 {
+		t.Errorf("expected the probe to go to the primary, got %d and %d calls", primaryCalls, fallbackCalls)
+	}
+}
+
+func TestFailoverCompleter_ProviderBusy(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var primaryCalls, fallbackCalls int
+	busy := fmt.Errorf("send request: %w", ragllm.ErrProviderBusy)
+	f := newTestFailover(clock, failing(busy, &primaryCalls), answering("from fallback", &fallbackCalls))
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.Chat(context.Background(), llmlib.ChatRequest{}); err != nil {
+			t.Fatalf("request %d: unexpected error: %v", i, err)
+		}
+	}
+	// A busy provider fails over without opening its breaker.
+	if primaryCalls != 3 || fallbackCalls != 3 {
+		t.Errorf("expected 3 primary and 3 fallback calls, got %d and %d", primaryCalls, fallbackCalls)
+	}
+
+	f = newTestFailover(clock, failing(busy, &primaryCalls))
+	if _, err := f.Chat(context.Background(), llmlib.ChatRequest{}); !errors.Is(err, ragllm.ErrProviderBusy) {
+		t.Errorf("expected the busy error without a fallback, got %v", err)
+	}
+}
+
+func TestFailoverCompleter_Stream(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var primaryCalls int
+	f := newTestFailover(clock, failing(errors.New("connection refused"), &primaryCalls), &MockCompleter{})
+
+	stream, err := f.ChatStream(context.Background(), llmlib.ChatRequest{})
+	if err != nil {
+		t.Fatalf("expected the fallback stream, got %v", err)
+	}
+	resp, err := stream.Collect(context.Background())
+	if err != nil || joinTextBlocks(resp.Content) == "" {
+		t.Errorf("expected streamed text from the fallback, got %v, %v", resp, err)
+	}
+	if primaryCalls != 1 {
+		t.Errorf("expected one primary attempt, got %d", primaryCalls)
+	}
+}
+
+func TestFailoverCompleter_DoesNotFailOver(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+
+	t.Run("invalid request", func(t *testing.T) {
+		var primaryCalls, fallbackCalls int
+		invalid := &llmlib.ProviderError{Err: llmlib.ErrInvalidRequest, StatusCode: 400, Message: "bad"}
+		f := newTestFailover(clock, failing(invalid, &primaryCalls), answering("x", &fallbackCalls))
+
+		for i := 0; i < 3; i++ {
+			if _, err := f.Chat(context.Background(), llmlib.ChatRequest{}); !errors.Is(err, llmlib.ErrInvalidRequest) {
+				t.Fatalf("expected the invalid request error, got %v", err)
+			}
+		}
+		if fallbackCalls != 0 || primaryCalls != 3 {
+			t.Errorf("expected no failover and a closed breaker, got %d primary and %d fallback calls",
+				primaryCalls, fallbackCalls)
+		}
+	})
+
+	t.Run("caller cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var fallbackCalls int
+		primary := &MockCompleter{
+			ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+				cancel()
+				return nil, ctx.Err()
+			},
+		}
+		f := newTestFailover(clock, primary, answering("x", &fallbackCalls))
+
+		if _, err := f.Chat(ctx, llmlib.ChatRequest{}); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the cancellation, got %v", err)
+		}
+		if fallbackCalls != 0 || f.targets[0].breaker.failures != 0 {
+			t.Errorf("expected no failover or recorded failure, got %d fallback calls, %d failures",
+				fallbackCalls, f.targets[0].breaker.failures)
+		}
+	})
+}
+
+func TestFailoverCompleter_AllOpen(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	var primaryCalls, fallbackCalls int
+	lastErr := errors.New("fallback down")
+	f := newTestFailover(clock,
+		failing(errors.New("primary down"), &primaryCalls),
+		failing(lastErr, &fallbackCalls))
+
+	for i := 0; i < 2; i++ {
+		if _, err := f.Chat(context.Background(), llmlib.ChatRequest{}); !errors.Is(err, lastErr) {
+			t.Fatalf("expected the last provider's error, got %v", err)
+		}
+	}
+	if _, err := f.Chat(context.Background(), llmlib.ChatRequest{}); !errors.Is(err, ErrNoCompletionProvider) {
+		t.Errorf("expected ErrNoCompletionProvider with every breaker open, got %v", err)
+	}
+	if primaryCalls != 2 || fallbackCalls != 2 {
+		t.Errorf("expected open breakers to stop calls, got %d and %d", primaryCalls, fallbackCalls)
+	}
+}
+
+func TestFailoverCompleter_UsageAndPing(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(0, 0)}
+	primary := &MockCompleter{
+		UsageVal: llmlib.TokenUsage{PromptTokens: 10, TotalTokens: 10},
+		PingFunc: func(ctx context.Context) error { return errors.New("down") },
+	}
+	fallback := &MockCompleter{UsageVal: llmlib.TokenUsage{PromptTokens: 5, TotalTokens: 5}}
+	f := newTestFailover(clock, primary, fallback)
+
+	if u := f.Usage(); u.PromptTokens != 15 || u.TotalTokens != 15 {
+		t.Errorf("expected combined usage, got %+v", u)
+	}
+	if err := f.Ping(context.Background()); err != nil {
+		t.Errorf("expected ping to succeed via the fallback, got %v", err)
+	}
+	fallback.PingFunc = primary.PingFunc
+	if err := f.Ping(context.Background()); err == nil || err.Error() != "primary: down" {
+		t.Errorf("expected the primary's ping error, got %v", err)
+	}
+}
+
+func TestNewFailoverCompleter(t *testing.T) {
+	pCfg := config.Pipeline{
+		RAGLLM: config.LLMConfig{Provider: "Anthropic", Model: "claude"},
+		RAGLLMFallbacks: []config.LLMConfig{
+			{Provider: "openai", Model: "gpt-4o"},
+		},
+		CircuitBreaker: config.CircuitBreakerConfig{
+			FailureThreshold: 3,
+			Cooldown:         config.Duration(10 * time.Second),
+		},
+	}
+	var created []string
+	newClient := func(llm config.LLMConfig) (llmlib.Client, error) {
+		created = append(created, llm.Provider)
+		return nil, nil
+	}
+
+	f, err := newFailoverCompleter(pCfg, &MockCompleter{}, newClient, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(f.targets) != 2 || f.targets[0].name != "anthropic/claude" || f.targets[1].name != "openai/gpt-4o" {
+		t.Fatalf("unexpected targets: %+v", f.targets)
+	}
+	if len(created) != 1 || created[0] != "openai" {
+		t.Errorf("expected only the fallback client to be created, got %v", created)
+	}
+	for _, target := range f.targets {
+		if target.breaker.threshold != 3 || target.breaker.cooldown != 10*time.Second {
+			t.Errorf("%s: breaker settings not applied: %+v", target.name, target.breaker)
+		}
+	}
+
+	newClient = func(config.LLMConfig) (llmlib.Client, error) { return nil, errors.New("no key") }
+	if _, err := newFailoverCompleter(pCfg, &MockCompleter{}, newClient, slog.New(slog.DiscardHandler)); err == nil {
+		t.Error("expected an error when a fallback client cannot be created")
+	}
+}
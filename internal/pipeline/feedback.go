@@ -0,0 +1,153 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// ErrResponseNotFound is returned for feedback on an answer the
+// pipeline did not give, or no longer keeps.
+var ErrResponseNotFound = errors.New("response not found")
+
+// answered is what a pipeline keeps of an answer until it is rated:
+// the anonymized query, the IDs of the documents the answer was written
+// from, and the model that wrote it.
+type answered struct {
+	query    string
+	sources  []string
+	provider string
+	model    string
+	at       time.Time
+}
+
+// responseLog keeps what feedback needs of a pipeline's most recent
+// answers, dropping the oldest once it holds maxEntries. It is safe for
+// concurrent use, and a nil log gives answers no response IDs.
+type responseLog struct {
+	maxEntries int
+	now        func() time.Time // overridden in tests
+
+	mu      sync.Mutex
+	ids     []string // oldest first
+	answers map[string]answered
+}
+
+// newResponseLog creates the response log a pipeline's feedback
+// settings describe, or returns nil when they disable feedback.
+func newResponseLog(cfg config.FeedbackConfig) *responseLog {
+	if !cfg.Enabled {
+		return nil
+	}
+	l := &responseLog{
+		maxEntries: cfg.MaxResponses,
+		now:        time.Now,
+		answers:    make(map[string]answered),
+	}
+	if l.maxEntries <= 0 {
+		l.maxEntries = config.DefaultFeedbackMaxResponses
+	}
+	return l
+}
+
+// feedbackTable returns the table a pipeline records feedback in.
+func feedbackTable(cfg config.FeedbackConfig) string {
+	if cfg.Table == "" {
+		return config.DefaultFeedbackTable
+	}
+	return cfg.Table
+}
+
+// record keeps an answer and returns the response ID it is rated by, or
+// "" when the log is nil.
+func (l *responseLog) record(a answered) string {
+	if l == nil {
+		return ""
+	}
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return ""
+	}
+	id := hex.EncodeToString(b[:])
+	a.at = l.now().UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ids = append(l.ids, id)
+	l.answers[id] = a
+	if over := len(l.ids) - l.maxEntries; over > 0 {
+		for _, old := range l.ids[:over] {
+			delete(l.answers, old)
+		}
+		l.ids = slices.Delete(l.ids, 0, over)
+	}
+	return id
+}
+
+// lookup returns the answer with the given response ID.
+func (l *responseLog) lookup(id string) (answered, bool) {
+	if l == nil {
+		return answered{}, false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	a, ok := l.answers[id]
+	return a, ok
+}
+
+// recordResponse keeps what feedback needs of the answer to req,
+// written from the documents prov identifies, and returns the response
+// ID it is rated by, or "" when the pipeline takes no feedback.
+func (o *Orchestrator) recordResponse(req QueryRequest, prov []DocumentProvenance) string {
+	if o.responses == nil {
+		return ""
+	}
+	var sources []string
+	for _, p := range prov {
+		if p.ID != "" && !slices.Contains(sources, p.ID) {
+			sources = append(sources, p.ID)
+		}
+	}
+	return o.responses.record(answered{
+		query:    Anonymize(req.Query),
+		sources:  sources,
+		provider: o.completionProvider(),
+		model:    o.cfg.RAGLLM.Model,
+	})
+}
+
+// rateResponse records feedback on the answer req.ResponseID names in
+// the pipeline's feedback table.
+func (o *Orchestrator) rateResponse(ctx context.Context, req FeedbackRequest) error {
+	a, ok := o.responses.lookup(req.ResponseID)
+	if !ok || o.feedbackStore == nil {
+		return ErrResponseNotFound
+	}
+	return o.feedbackStore.InsertFeedback(ctx, feedbackTable(o.cfg.Feedback), database.Feedback{
+		ResponseID: req.ResponseID,
+		Pipeline:   o.cfg.Name,
+		Rating:     req.Rating,
+		Comment:    Anonymize(req.Comment),
+		Query:      a.query,
+		Sources:    a.sources,
+		Provider:   a.provider,
+		Model:      a.model,
+		AnsweredAt: a.at,
+	})
+}
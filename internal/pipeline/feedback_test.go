@@ -0,0 +1,125 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// recordingFeedbackStore keeps the feedback it is given.
+type recordingFeedbackStore struct {
+	table    string
+	feedback []database.Feedback
+}
+
+func (s *recordingFeedbackStore) InsertFeedback(_ context.Context, table string, fb database.Feedback) error {
+	s.table = table
+	s.feedback = append(s.feedback, fb)
+	return nil
+}
+
+func TestResponseLog(t *testing.T) {
+	l := newResponseLog(config.FeedbackConfig{Enabled: true, MaxResponses: 2})
+	answeredAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return answeredAt }
+
+	first := l.record(answered{query: "first"})
+	second := l.record(answered{query: "second"})
+	if first == "" || second == "" || first == second {
+		t.Fatalf("expected distinct response IDs, got %q and %q", first, second)
+	}
+	if a, ok := l.lookup(second); !ok || a.query != "second" || !a.at.Equal(answeredAt) {
+		t.Errorf("expected the second answer, got %+v, %v", a, ok)
+	}
+
+	l.record(answered{query: "third"})
+	if _, ok := l.lookup(first); ok {
+		t.Error("expected the oldest answer dropped")
+	}
+	if len(l.ids) != 2 || len(l.answers) != 2 {
+		t.Errorf("expected 2 answers kept, got %d IDs and %d answers", len(l.ids), len(l.answers))
+	}
+
+	var disabled *responseLog
+	if disabled.record(answered{}) != "" || newResponseLog(config.FeedbackConfig{}) != nil {
+		t.Error("expected no response IDs when feedback is disabled")
+	}
+	if _, ok := disabled.lookup(first); ok {
+		t.Error("expected no answers when feedback is disabled")
+	}
+}
+
+func TestOrchestrator_Execute_Feedback(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	orch.cfg.RAGLLM.Provider = "OpenAI"
+	orch.cfg.RAGLLM.Model = "gpt-4o"
+	orch.cfg.Feedback = config.FeedbackConfig{Enabled: true, Table: "ops.feedback"}
+	orch.responses = newResponseLog(orch.cfg.Feedback)
+	store := &recordingFeedbackStore{}
+	orch.feedbackStore = store
+	ctx := context.Background()
+
+	resp, err := orch.Execute(ctx, QueryRequest{Query: "Why was jane@example.com failed over?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ResponseID == "" {
+		t.Fatal("expected a response ID")
+	}
+	err = orch.Feedback(ctx, FeedbackRequest{ResponseID: resp.ResponseID, Rating: FeedbackDown,
+		Comment: "Call me on 555 123 4567"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if store.table != "ops.feedback" || len(store.feedback) != 1 {
+		t.Fatalf("expected one rating in ops.feedback, got %d in %q", len(store.feedback), store.table)
+	}
+	fb := store.feedback[0]
+	if fb.ResponseID != resp.ResponseID || fb.Pipeline != "docs" || fb.Rating != FeedbackDown ||
+		fb.Query != "Why was [email] failed over?" || fb.Comment != "Call me on [number]" ||
+		fb.Provider != "openai" || fb.Model != "gpt-4o" || fb.AnsweredAt.IsZero() {
+		t.Errorf("unexpected feedback %+v", fb)
+	}
+	if !reflect.DeepEqual(fb.Sources, []string{"doc-1", "doc-2", "doc-3"}) {
+		t.Errorf("expected the sources the answer was written from, got %v", fb.Sources)
+	}
+
+	err = orch.Feedback(ctx, FeedbackRequest{ResponseID: resp.ResponseID + "x", Rating: FeedbackUp})
+	if !errors.Is(err, ErrResponseNotFound) {
+		t.Errorf("expected an unknown response not found, got %v", err)
+	}
+}
+
+// TestOrchestrator_Feedback_Disabled verifies a pipeline that takes no
+// feedback gives its answers no response IDs and rates none.
+func TestOrchestrator_Feedback_Disabled(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "")
+	ctx := context.Background()
+
+	resp, err := orch.Execute(ctx, QueryRequest{Query: "How does replication work?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.ResponseID != "" {
+		t.Errorf("expected no response ID, got %q", resp.ResponseID)
+	}
+	err = orch.Feedback(ctx, FeedbackRequest{ResponseID: "r1", Rating: FeedbackUp})
+	if !errors.Is(err, ErrResponseNotFound) {
+		t.Errorf("expected the response not found, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// MaxFilterDepth is how deeply a request filter's groups may nest: a
+// filter with groups of groups has a depth of 2.
+const MaxFilterDepth = 3
+
+// FilterError reports every problem found with a request's filter,
+// each against the JSON path of the field at fault, such as
+// filter.groups[0].conditions[1].operator. It wraps ErrInvalidRequest.
+type FilterError struct {
+	Fields config.ValidationErrors
+}
+
+func (e *FilterError) Error() string {
+	return ErrInvalidRequest.Error() + ": " + e.Fields.Error()
+}
+
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidRequest
+}
+
+// checkFilter validates a request filter before anything is searched:
+// its logic, the operator and value of each condition, how deeply its
+// groups nest and, when the pipeline sets filter_columns, the columns
+// it references.
+func (o *Orchestrator) checkFilter(filter *config.Filter) error {
+	if filter == nil {
+		return nil
+	}
+	if errs := validateFilter(filter, "filter", 0, o.cfg.FilterColumns); len(errs) > 0 {
+		return &FilterError{Fields: errs}
+	}
+	return nil
+}
+
+// applyFilterPreset returns the filter a request searches with: its
+// own filter, combined with the pipeline's filter preset it names so
+// that documents must match both. The request's filter must already
+// have been checked; presets are checked when the pipeline starts.
+func (o *Orchestrator) applyFilterPreset(name string, filter *config.Filter) (*config.Filter, error) {
+	if name == "" {
+		return filter, nil
+	}
+	preset, ok := o.cfg.FilterPresets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown filter_preset %q; %s",
+			ErrInvalidRequest, name, presetNames(o.cfg.FilterPresets))
+	}
+	if filter == nil {
+		return &preset, nil
+	}
+	return &config.Filter{Groups: []config.Filter{preset, *filter}}, nil
+}
+
+// presetNames describes the filter presets a request may name.
+func presetNames(presets map[string]config.Filter) string {
+	if len(presets) == 0 {
+		return "the pipeline has no filter presets"
+	}
+	return "available presets are " + strings.Join(sortedPresets(presets), ", ")
+}
+
+// sortedPresets returns the names of a pipeline's filter presets in
+// sorted order.
+func sortedPresets(presets map[string]config.Filter) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// checkFilterPresets validates a pipeline's filter presets as request
+// filters are validated, except that they may use any column.
+func checkFilterPresets(presets map[string]config.Filter) error {
+	var errs config.ValidationErrors
+	for _, name := range sortedPresets(presets) {
+		preset := presets[name]
+		errs = append(errs, validateFilter(&preset, "filter_presets."+name, 0, nil)...)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid filter preset: %w", errs)
+	}
+	return nil
+}
+
+// validateFilter checks a filter, or a group within one, at the given
+// path and depth of nesting; columns, when not empty, are the only ones
+// it may use.
+func validateFilter(filter *config.Filter, path string, depth int, columns []string) config.ValidationErrors {
+	var errs config.ValidationErrors
+	fail := func(field, format string, args ...interface{}) {
+		errs = append(errs, config.ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if logic := strings.ToUpper(filter.Logic); logic != "" && logic != "AND" && logic != "OR" {
+		fail(path+".logic", "must be AND or OR")
+	}
+	for i, cond := range filter.Conditions {
+		field := fmt.Sprintf("%s.conditions[%d]", path, i)
+		switch {
+		case cond.Column == "":
+			fail(field+".column", "is required")
+		case len(columns) > 0 && !slices.Contains(columns, cond.Column):
+			fail(field+".column", "column %q is not filterable; allowed columns are %s",
+				cond.Column, strings.Join(columns, ", "))
+		}
+		if err := database.ValidateOperator(cond.Operator); err != nil {
+			fail(field+".operator", "%v", err)
+		} else if err := database.ValidateValue(cond.Operator, cond.Value); err != nil {
+			fail(field+".value", "%v", err)
+		}
+	}
+	for i := range filter.Groups {
+		group := &filter.Groups[i]
+		field := fmt.Sprintf("%s.groups[%d]", path, i)
+		switch {
+		case depth >= MaxFilterDepth:
+			fail(field, "groups may be nested at most %d deep", MaxFilterDepth)
+		case len(group.Conditions) == 0 && len(group.Groups) == 0:
+			fail(field, "must have at least one condition or group")
+		default:
+			errs = append(errs, validateFilter(group, field, depth+1, columns)...)
+		}
+	}
+	return errs
+}
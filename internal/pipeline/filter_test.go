@@ -0,0 +1,160 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestOrchestrator_CheckFilter(t *testing.T) {
+	cond := func(column, operator string, value interface{}) config.FilterCondition {
+		return config.FilterCondition{Column: column, Operator: operator, Value: value}
+	}
+	nest := func(depth int) config.Filter {
+		f := config.Filter{Conditions: []config.FilterCondition{cond("product", "=", "pgEdge")}}
+		for range depth {
+			f = config.Filter{Groups: []config.Filter{f}}
+		}
+		return f
+	}
+
+	tests := []struct {
+		name       string
+		columns    []string
+		filter     config.Filter
+		wantFields []string
+	}{
+		{
+			name: "valid nested filter",
+			filter: config.Filter{
+				Conditions: []config.FilterCondition{cond("product", "=", "pgEdge")},
+				Groups: []config.Filter{{
+					Conditions: []config.FilterCondition{
+						cond("version", "IN", []interface{}{"1", "2"}),
+						cond("deleted_at", "IS NULL", nil),
+					},
+					Logic: "or",
+				}},
+			},
+		},
+		{
+			name:   "groups as deep as allowed",
+			filter: nest(MaxFilterDepth),
+		},
+		{
+			name:       "groups too deep",
+			filter:     nest(MaxFilterDepth + 1),
+			wantFields: []string{"filter.groups[0].groups[0].groups[0].groups[0]"},
+		},
+		{
+			name: "every problem is reported",
+			filter: config.Filter{
+				Conditions: []config.FilterCondition{
+					cond("", "=", "x"),
+					cond("product", "~", "x"),
+					cond("version", "IN", "1"),
+				},
+				Groups: []config.Filter{
+					{},
+					{Conditions: []config.FilterCondition{cond("product", "=", nil)}, Logic: "XOR"},
+				},
+			},
+			wantFields: []string{
+				"filter.conditions[0].column",
+				"filter.conditions[1].operator",
+				"filter.conditions[2].value",
+				"filter.groups[0]",
+				"filter.groups[1].logic",
+				"filter.groups[1].conditions[0].value",
+			},
+		},
+		{
+			name:    "column outside filter_columns in a group",
+			columns: []string{"product"},
+			filter: config.Filter{
+				Conditions: []config.FilterCondition{cond("product", "=", "pgEdge")},
+				Groups:     []config.Filter{{Conditions: []config.FilterCondition{cond("owner", "=", "jo")}}},
+			},
+			wantFields: []string{"filter.groups[0].conditions[0].column"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := NewOrchestrator(OrchestratorConfig{
+				Pipeline: &config.Pipeline{Name: "docs", FilterColumns: tt.columns},
+			})
+			err := orch.checkFilter(&tt.filter)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var filterErr *FilterError
+			if !errors.As(err, &filterErr) || !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("expected a FilterError wrapping ErrInvalidRequest, got %v", err)
+			}
+			var fields []string
+			for _, f := range filterErr.Fields {
+				fields = append(fields, f.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("fields = %q, want %q", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_ApplyFilterPreset(t *testing.T) {
+	preset := config.Filter{Conditions: []config.FilterCondition{{Column: "version", Operator: "=", Value: "17"}}}
+	request := &config.Filter{Conditions: []config.FilterCondition{{Column: "product", Operator: "=", Value: "pgEdge"}}}
+	orch := NewOrchestrator(OrchestratorConfig{
+		Pipeline: &config.Pipeline{Name: "docs", FilterPresets: map[string]config.Filter{"v17-docs": preset}},
+	})
+
+	if got, err := orch.applyFilterPreset("", request); err != nil || got != request {
+		t.Errorf("expected the request's filter without a preset, got %+v, %v", got, err)
+	}
+	if got, err := orch.applyFilterPreset("v17-docs", nil); err != nil || !reflect.DeepEqual(*got, preset) {
+		t.Errorf("expected the preset alone, got %+v, %v", got, err)
+	}
+	got, err := orch.applyFilterPreset("v17-docs", request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := config.Filter{Groups: []config.Filter{preset, *request}}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("expected the preset and the request's filter combined, got %+v", got)
+	}
+
+	_, err = orch.applyFilterPreset("v16-docs", request)
+	if !errors.Is(err, ErrInvalidRequest) || !strings.Contains(err.Error(), "available presets are v17-docs") {
+		t.Errorf("expected an unknown preset to be rejected, got %v", err)
+	}
+}
+
+func TestCheckFilterPresets(t *testing.T) {
+	err := checkFilterPresets(map[string]config.Filter{
+		"ok":  {Conditions: []config.FilterCondition{{Column: "internal", Operator: "=", Value: true}}},
+		"bad": {Conditions: []config.FilterCondition{{Column: "version", Operator: "~~", Value: "17"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "filter_presets.bad.conditions[0].operator") ||
+		strings.Contains(err.Error(), "filter_presets.ok") {
+		t.Errorf("expected only the bad preset to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// formattingInstructions renders a pipeline's formatting conventions as
+// a paragraph for the system prompt, or "" when none are configured.
+func formattingInstructions(f config.FormattingConfig) string {
+	if f.IsZero() {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Format your answer for the reader's region:\n")
+	if f.Locale != "" {
+		fmt.Fprintf(&sb, "- Follow the conventions of the %s locale.\n", f.Locale)
+	}
+	switch f.DateFormat {
+	case config.DateFormatISO:
+		sb.WriteString("- Write dates as YYYY-MM-DD (e.g. 2025-12-31).\n")
+	case config.DateFormatDMY:
+		sb.WriteString("- Write dates day first, as DD.MM.YYYY or DD/MM/YYYY (e.g. 31.12.2025).\n")
+	case config.DateFormatMDY:
+		sb.WriteString("- Write dates month first, as MM/DD/YYYY (e.g. 12/31/2025).\n")
+	}
+	switch f.DecimalSeparator {
+	case ",":
+		sb.WriteString(`- Use "," as the decimal separator and "." to group thousands (e.g. 1.234,5).` + "\n")
+	case ".":
+		sb.WriteString(`- Use "." as the decimal separator and "," to group thousands (e.g. 1,234.5).` + "\n")
+	}
+	switch f.Units {
+	case config.UnitsMetric:
+		sb.WriteString("- Use metric units (km, kg, °C, litres), converting any other units quoted in the context.\n")
+	case config.UnitsImperial:
+		sb.WriteString("- Use imperial units (miles, pounds, °F, gallons), converting any other units quoted in the context.\n")
+	}
+	sb.WriteString("Keep code, commands, identifiers and version numbers exactly as written.")
+	return sb.String()
+}
+
+// Patterns used by checkFormatting. Dotted dates require a four-digit
+// year so version numbers such as "16.2.10" are not mistaken for dates,
+// and only numbers that use both separators are checked, since "3.5"
+// alone is as likely to be a version as a decimal.
+var (
+	isoDatePattern     = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)
+	numericDatePattern = regexp.MustCompile(`\b(\d{1,2})(?:/(\d{1,2})/(?:\d{4}|\d{2})|\.(\d{1,2})\.\d{4})\b`)
+	pointDecimalNumber = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+\.\d+\b`)
+	commaDecimalNumber = regexp.MustCompile(`\b\d{1,3}(?:\.\d{3})+,\d+\b`)
+
+	imperialQuantity = regexp.MustCompile(`\b\d+(?:[.,]\d+)?\s?(?:miles?|mph|feet|foot|ft|inch(?:es)?|yards?|pounds?|lbs?|ounces?|oz|gallons?|°F|degrees Fahrenheit)\b`)
+	metricQuantity   = regexp.MustCompile(`\b\d+(?:[.,]\d+)?\s?(?:km/h|km|kilomet(?:er|re)s?|met(?:er|re)s?|cm|mm|kg|kilograms?|grams?|lit(?:er|re)s?|ml|°C|degrees Celsius)\b`)
+
+	codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// checkFormatting reports the places where answer departs from the
+// pipeline's date, number and unit conventions. Code spans are skipped.
+// The checks are heuristics meant to surface prompt drift, so the
+// answer itself is never altered.
+func checkFormatting(answer string, f config.FormattingConfig) []string {
+	if f.DateFormat == "" && f.DecimalSeparator == "" && f.Units == "" {
+		return nil
+	}
+	text := codePattern.ReplaceAllString(answer, " ")
+
+	var warnings []string
+	seen := make(map[string]bool)
+	warn := func(format string, args ...any) {
+		w := fmt.Sprintf(format, args...)
+		if !seen[w] {
+			seen[w] = true
+			warnings = append(warnings, w)
+		}
+	}
+
+	if f.DateFormat != "" {
+		if f.DateFormat != config.DateFormatISO {
+			for _, m := range isoDatePattern.FindAllString(text, -1) {
+				warn("date %q is not in %s order", m, dateOrderName(f.DateFormat))
+			}
+		}
+		for _, m := range numericDatePattern.FindAllStringSubmatch(text, -1) {
+			first, _ := strconv.Atoi(m[1])
+			second, _ := strconv.Atoi(m[2] + m[3])
+			bad := false
+			switch f.DateFormat {
+			case config.DateFormatISO:
+				bad = true
+			case config.DateFormatDMY:
+				bad = second > 12 // month can't exceed 12, so this is month first
+			case config.DateFormatMDY:
+				bad = first > 12
+			}
+			if bad {
+				warn("date %q is not in %s order", m[0], dateOrderName(f.DateFormat))
+			}
+		}
+	}
+
+	switch f.DecimalSeparator {
+	case ",":
+		for _, m := range pointDecimalNumber.FindAllString(text, -1) {
+			warn(`number %q uses "." as the decimal separator`, m)
+		}
+	case ".":
+		for _, m := range commaDecimalNumber.FindAllString(text, -1) {
+			warn(`number %q uses "," as the decimal separator`, m)
+		}
+	}
+
+	switch f.Units {
+	case config.UnitsMetric:
+		for _, m := range imperialQuantity.FindAllString(text, -1) {
+			warn("quantity %q is not in metric units", m)
+		}
+	case config.UnitsImperial:
+		for _, m := range metricQuantity.FindAllString(text, -1) {
+			warn("quantity %q is not in imperial units", m)
+		}
+	}
+
+	return warnings
+}
+
+func dateOrderName(format string) string {
+	switch format {
+	case config.DateFormatDMY:
+		return "day-month-year"
+	case config.DateFormatMDY:
+		return "month-day-year"
+	default:
+		return "year-month-day"
+	}
+}
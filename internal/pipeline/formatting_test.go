@@ -0,0 +1,101 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestFormattingInstructions(t *testing.T) {
+	if got := formattingInstructions(config.FormattingConfig{}); got != "" {
+		t.Errorf("expected no instructions without conventions, got %q", got)
+	}
+
+	got := formattingInstructions(config.FormattingConfig{
+		Locale:           "de-DE",
+		DateFormat:       config.DateFormatDMY,
+		DecimalSeparator: ",",
+		Units:            config.UnitsMetric,
+	})
+	for _, want := range []string{"de-DE", "DD.MM.YYYY", "1.234,5", "metric units", "version numbers"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("instructions missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestCheckFormatting(t *testing.T) {
+	tests := []struct {
+		name   string
+		f      config.FormattingConfig
+		answer string
+		want   []string
+	}{
+		{
+			name:   "no conventions",
+			answer: "Released on 2025-12-31, weighs 5 lbs.",
+		},
+		{
+			name:   "dmy flags iso and month-first dates",
+			f:      config.FormattingConfig{DateFormat: config.DateFormatDMY},
+			answer: "Released 2025-12-31 and patched 12/31/2025; support ends 31.12.2026.",
+			want: []string{
+				`date "2025-12-31" is not in day-month-year order`,
+				`date "12/31/2025" is not in day-month-year order`,
+			},
+		},
+		{
+			name:   "mdy accepts ambiguous dates",
+			f:      config.FormattingConfig{DateFormat: config.DateFormatMDY},
+			answer: "Due 03/04/2025, not 31/03/2025.",
+			want:   []string{`date "31/03/2025" is not in month-day-year order`},
+		},
+		{
+			name:   "iso ignores version numbers",
+			f:      config.FormattingConfig{DateFormat: config.DateFormatISO},
+			answer: "Upgrade to 16.2.10 before 1.2.2025.",
+			want:   []string{`date "1.2.2025" is not in year-month-day order`},
+		},
+		{
+			name:   "comma decimal",
+			f:      config.FormattingConfig{DecimalSeparator: ","},
+			answer: "It costs 1,234.50 EUR, or 1.234,50 EUR; see version 3.5.",
+			want:   []string{`number "1,234.50" uses "." as the decimal separator`},
+		},
+		{
+			name:   "metric units skip code",
+			f:      config.FormattingConfig{Units: config.UnitsMetric},
+			answer: "The cable is 10 feet (3 m) long. Run `ping -s 5 ft` to test.",
+			want:   []string{`quantity "10 feet" is not in metric units`},
+		},
+		{
+			name:   "imperial units",
+			f:      config.FormattingConfig{Units: config.UnitsImperial},
+			answer: "Keep it below 30°C and under 2 kg.",
+			want: []string{
+				`quantity "30°C" is not in imperial units`,
+				`quantity "2 kg" is not in imperial units`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkFormatting(tt.answer, tt.f)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkFormatting() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// Guardrails reported on a response, for each one that changed its
+// answer.
+const (
+	GuardrailUngrounded = "ungrounded" // replaced by the fallback message
+	GuardrailRedacted   = "redacted"   // had text redacted
+	GuardrailTruncated  = "truncated"  // cut to max_answer_chars
+)
+
+// groundednessPrompt asks the completion LLM whether an answer is
+// supported by the documents it was written from.
+const groundednessPrompt = `You check answers against the documents they were written from.
+Reply SUPPORTED if every claim in the answer is stated in or follows from the documents, or the answer only says that the documents do not answer the question.
+Otherwise reply UNSUPPORTED. Reply with the one word only.`
+
+// guardrails are a pipeline's compiled guardrails.
+type guardrails struct {
+	redact       []*regexp.Regexp
+	redaction    string
+	maxChars     int
+	groundedness bool
+	fallback     string
+}
+
+// newGuardrails compiles a pipeline's guardrails, or returns nil when
+// it has none. Patterns are checked when the configuration is
+// validated; one that does not compile is skipped.
+func newGuardrails(cfg config.GuardrailsConfig) *guardrails {
+	if !cfg.Enabled() {
+		return nil
+	}
+	g := &guardrails{
+		redaction:    cfg.Redaction,
+		maxChars:     cfg.MaxAnswerChars,
+		groundedness: cfg.Groundedness.Enabled,
+		fallback:     cfg.Groundedness.FallbackMessage,
+	}
+	if g.redaction == "" {
+		g.redaction = config.DefaultRedaction
+	}
+	if g.fallback == "" {
+		g.fallback = config.DefaultUngroundedAnswer
+	}
+	for _, pattern := range cfg.RedactPatterns {
+		if re, err := regexp.Compile(pattern); err == nil {
+			g.redact = append(g.redact, re)
+		}
+	}
+	for _, term := range cfg.Denylist {
+		g.redact = append(g.redact, regexp.MustCompile(`(?i)`+regexp.QuoteMeta(term)))
+	}
+	return g
+}
+
+// guard applies the pipeline's guardrails to an answer written from
+// contextDocs, and returns the answer to send with the guardrails that
+// changed it. An answer the groundedness check rejects is replaced by
+// the fallback message, which is sent as configured.
+func (o *Orchestrator) guard(
+	ctx context.Context, query, answer string, contextDocs []ragllm.ContextDoc, usage *StageUsage,
+) (string, []string) {
+	g := o.guardrails
+	if g == nil {
+		return answer, nil
+	}
+	if g.groundedness && !o.grounded(ctx, query, answer, contextDocs, usage) {
+		return g.fallback, []string{GuardrailUngrounded}
+	}
+
+	var applied []string
+	redacted := answer
+	for _, re := range g.redact {
+		redacted = re.ReplaceAllLiteralString(redacted, g.redaction)
+	}
+	if redacted != answer {
+		applied = append(applied, GuardrailRedacted)
+	}
+	if truncated, ok := truncateAnswer(redacted, g.maxChars); ok {
+		redacted = truncated
+		applied = append(applied, GuardrailTruncated)
+	}
+	return redacted, applied
+}
+
+// grounded asks the completion provider whether an answer is supported
+// by the documents it was written from, bounded by the pipeline's
+// completion_timeout, and records the tokens it used. If the check
+// fails, the answer is let through, as the pipeline would answer
+// without the check.
+func (o *Orchestrator) grounded(
+	ctx context.Context, query, answer string, contextDocs []ragllm.ContextDoc, usage *StageUsage,
+) bool {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: groundednessPrompt,
+		Messages: []llmlib.Message{
+			llmlib.UserText(ragllm.FormatContext(contextDocs) +
+				"Question: " + query + "\n\nAnswer:\n" + answer),
+		},
+		MaxTokens: llmlib.Int(8),
+	})
+	o.observeStage(metrics.StageGroundedness, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("the groundedness check failed, returning the answer unchecked",
+			"error", stageTimeout(ctx, err))
+		return true
+	}
+	usage.Groundedness = &resp.Usage
+	o.recordUsage(metrics.StageGroundedness, o.completionProvider(), resp.Usage)
+
+	verdict := strings.ToUpper(strings.TrimSpace(joinTextBlocks(resp.Content)))
+	if !strings.HasPrefix(verdict, "SUPPORTED") {
+		o.logger.Info("answer failed the groundedness check", "verdict", verdict)
+		return false
+	}
+	return true
+}
+
+// truncateAnswer cuts an answer longer than maxChars characters at the
+// last word that fits, marking the cut with an ellipsis, and reports
+// whether it did. A maxChars of zero leaves the answer whole.
+func truncateAnswer(answer string, maxChars int) (string, bool) {
+	runes := []rune(answer)
+	if maxChars <= 0 || len(runes) <= maxChars {
+		return answer, false
+	}
+	cut := string(runes[:maxChars-1])
+	// Cut at a word boundary, unless that would lose most of the answer.
+	if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, unicode.IsSpace) + "…", true
+}
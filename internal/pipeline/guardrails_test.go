@@ -0,0 +1,179 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// newGuardrailsOrchestrator returns an orchestrator with guardrails
+// whose completer answers with answer and judges it with verdict.
+func newGuardrailsOrchestrator(g config.GuardrailsConfig, answer, verdict string,
+	opts ...testOrchestratorOption) (*Orchestrator, *[]llmlib.ChatRequest) {
+	var requests []llmlib.ChatRequest
+	var gotFilter *config.Filter
+	guarded := func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Guardrails = g
+		cfg.CompletionProv = &MockCompleter{
+			ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+				requests = append(requests, req)
+				text := answer
+				if req.SystemPrompt == groundednessPrompt {
+					if verdict == "" {
+						return nil, errors.New("provider unavailable")
+					}
+					text = verdict
+				}
+				return &llmlib.ChatResponse{
+					Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}},
+					Usage:   llmlib.TokenUsage{PromptTokens: 300, CompletionTokens: 1, TotalTokens: 301},
+				}, nil
+			},
+			ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+				chunks := make(chan llmlib.StreamChunk, 3)
+				errs := make(chan error, 1)
+				chunks <- llmlib.StreamChunk{Type: llmlib.ChunkText, Text: answer[:10]}
+				chunks <- llmlib.StreamChunk{Type: llmlib.ChunkText, Text: answer[10:]}
+				chunks <- llmlib.StreamChunk{Type: llmlib.ChunkDone}
+				close(chunks)
+				close(errs)
+				return &llmlib.Stream{Chunks: chunks, Err: errs}, nil
+			},
+		}
+	}
+	orch := newRetrieveOrchestrator(nil, &gotFilter, append([]testOrchestratorOption{guarded}, opts...)...)
+	return orch, &requests
+}
+
+func TestOrchestrator_Execute_Guardrails(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.GuardrailsConfig
+		answer  string
+		verdict string
+		want    string
+		applied []string
+	}{
+		{
+			name:    "redacts patterns and denylisted terms",
+			cfg:     config.GuardrailsConfig{RedactPatterns: []string{`sk-[A-Za-z0-9]{8,}`}, Denylist: []string{"Project Falcon"}},
+			answer:  "Use key sk-abcdef123456 with project falcon.",
+			want:    "Use key [REDACTED] with [REDACTED].",
+			applied: []string{GuardrailRedacted},
+		},
+		{
+			name:    "redacts, then truncates at a word",
+			cfg:     config.GuardrailsConfig{MaxAnswerChars: 24, Redaction: "***", Denylist: []string{"standby"}},
+			answer:  "WAL is streamed to the standby as it is written.",
+			want:    "WAL is streamed to the…",
+			applied: []string{GuardrailRedacted, GuardrailTruncated},
+		},
+		{
+			name:    "keeps a supported answer",
+			cfg:     config.GuardrailsConfig{Groundedness: config.GroundednessConfig{Enabled: true}},
+			answer:  "WAL is streamed to the standby.",
+			verdict: "SUPPORTED",
+			want:    "WAL is streamed to the standby.",
+		},
+		{
+			name:    "replaces an unsupported answer",
+			cfg:     config.GuardrailsConfig{Groundedness: config.GroundednessConfig{Enabled: true, FallbackMessage: "Ask support."}},
+			answer:  "Replication was added in 1995.",
+			verdict: "unsupported",
+			want:    "Ask support.",
+			applied: []string{GuardrailUngrounded},
+		},
+		{
+			name:   "lets the answer through when the check fails",
+			cfg:    config.GuardrailsConfig{Groundedness: config.GroundednessConfig{Enabled: true}},
+			answer: "WAL is streamed to the standby.",
+			want:   "WAL is streamed to the standby.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch, requests := newGuardrailsOrchestrator(tt.cfg, tt.answer, tt.verdict)
+			resp, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Answer != tt.want || !slices.Equal(resp.Guardrails, tt.applied) {
+				t.Errorf("got %q %v, want %q %v", resp.Answer, resp.Guardrails, tt.want, tt.applied)
+			}
+
+			if !tt.cfg.Groundedness.Enabled {
+				return
+			}
+			check := (*requests)[len(*requests)-1]
+			prompt := check.Messages[0].Content[0].Text
+			if check.SystemPrompt != groundednessPrompt || !strings.Contains(prompt, "Streaming replication sends WAL") ||
+				!strings.Contains(prompt, tt.answer) {
+				t.Errorf("expected the documents and the answer in the check, got %q", prompt)
+			}
+			if u := resp.Usage.Groundedness; (u != nil) != (tt.verdict != "") {
+				t.Errorf("unexpected groundedness usage: %+v", u)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_ExecuteStream_Guardrails(t *testing.T) {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{Denylist: []string{"standby"}},
+		"WAL is streamed to the standby.", "")
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "how does replication work?"})
+	var content []string
+	var final StreamChunk
+	for chunk := range chunks {
+		if chunk.Content != "" {
+			content = append(content, chunk.Content)
+		}
+		if chunk.FinishReason != "" {
+			final = chunk
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(content) != 1 || content[0] != "WAL is streamed to the [REDACTED]." {
+		t.Errorf("expected the redacted answer in one chunk, got %q", content)
+	}
+	if !slices.Equal(final.Guardrails, []string{GuardrailRedacted}) {
+		t.Errorf("expected the final chunk to report the redaction, got %v", final.Guardrails)
+	}
+}
+
+func TestTruncateAnswer(t *testing.T) {
+	for _, tt := range []struct {
+		answer string
+		max    int
+		want   string
+	}{
+		{"short answer", 0, "short answer"},
+		{"short answer", 12, "short answer"},
+		{"a longer answer", 10, "a longer…"},
+		{"supercalifragilistic", 6, "super…"},
+		{"héllo wörld", 8, "héllo…"},
+	} {
+		got, _ := truncateAnswer(tt.answer, tt.max)
+		if got != tt.want {
+			t.Errorf("truncateAnswer(%q, %d) = %q, want %q", tt.answer, tt.max, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+	"github.com/pgEdge/pgedge-rag-server/internal/tokens"
+)
+
+// historySummaryMaxTokens bounds the summary of the messages dropped
+// from a long conversation. It is sent in addition to the history that
+// fits in max_history_tokens.
+const historySummaryMaxTokens = 256
+
+// historySummaryPrompt asks the completion LLM to summarize the earlier
+// part of a conversation.
+const historySummaryPrompt = `Summarize the conversation below in one short paragraph.
+Keep the facts, names and decisions a follow-up question might refer to.
+Reply with the summary only.`
+
+// fitHistory returns req with its conversation history cut to the
+// pipeline's max_history_tokens, dropping the oldest messages. With
+// history_overflow set to summarize, the dropped messages are
+// summarized for the system prompt; if that fails they are only
+// dropped.
+func (o *Orchestrator) fitHistory(ctx context.Context, req QueryRequest, usage *StageUsage) QueryRequest {
+	kept, dropped := truncateHistory(o.counter(), req.Messages, o.cfg.MaxHistoryTokens)
+	if len(dropped) == 0 {
+		return req
+	}
+	o.logger.Debug("conversation history exceeds max_history_tokens",
+		"kept", len(kept), "dropped", len(dropped))
+
+	req.Messages = kept
+	if o.cfg.HistoryOverflow == config.HistoryOverflowSummarize {
+		req.historySummary = o.summarizeHistory(ctx, dropped, usage)
+	}
+	return req
+}
+
+// truncateHistory splits msgs into the most recent messages whose
+// combined size fits in maxTokens, counted by counter as the context
+// is, and the older ones before them. The
+// kept messages never start with an assistant message, since some
+// providers require the conversation to open with a user turn. A
+// maxTokens of zero or less keeps every message.
+func truncateHistory(counter tokens.Counter, msgs []Message, maxTokens int) (kept, dropped []Message) {
+	if maxTokens <= 0 {
+		return msgs, nil
+	}
+
+	start := len(msgs)
+	total := 0
+	for start > 0 {
+		n := counter.Count(msgs[start-1].Content)
+		if total+n > maxTokens {
+			break
+		}
+		total += n
+		start--
+	}
+	for start < len(msgs) && msgs[start].Role != "user" {
+		start++
+	}
+	return msgs[start:], msgs[:start]
+}
+
+// summarizeHistory asks the completion provider to summarize msgs,
+// bounded by the pipeline's completion_timeout, and records the tokens
+// it used. It returns "" if no summary was produced.
+func (o *Orchestrator) summarizeHistory(ctx context.Context, msgs []Message, usage *StageUsage) string {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	var transcript strings.Builder
+	for _, m := range msgs {
+		transcript.WriteString(m.Role + ": " + m.Content + "\n\n")
+	}
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: historySummaryPrompt,
+		Messages:     []llmlib.Message{llmlib.UserText(transcript.String())},
+		MaxTokens:    llmlib.Int(historySummaryMaxTokens),
+	})
+	o.observeStage(metrics.StageHistorySummary, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("summarizing the conversation history failed, dropping the older messages",
+			"error", stageTimeout(ctx, err))
+		return ""
+	}
+	usage.HistorySummary = &resp.Usage
+	o.recordUsage(metrics.StageHistorySummary, o.completionProvider(), resp.Usage)
+
+	return strings.TrimSpace(joinTextBlocks(resp.Content))
+}
@@ -0,0 +1,160 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/tokens"
+)
+
+// conversation returns four messages of 10 estimated tokens each,
+// alternating user and assistant.
+func conversation() []Message {
+	turn := strings.Repeat("x", 40)
+	return []Message{
+		{Role: "user", Content: "1" + turn[1:]},
+		{Role: "assistant", Content: "2" + turn[1:]},
+		{Role: "user", Content: "3" + turn[1:]},
+		{Role: "assistant", Content: "4" + turn[1:]},
+	}
+}
+
+func TestTruncateHistory(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxTokens   int
+		wantKept    int
+		wantDropped int
+	}{
+		{"unlimited", 0, 4, 0},
+		{"everything fits", 40, 4, 0},
+		{"oldest turn dropped", 25, 2, 2},
+		{"never starts with an assistant message", 30, 2, 2},
+		{"nothing fits", 5, 0, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, dropped := truncateHistory(tokens.Estimate{}, conversation(), tt.maxTokens)
+			if len(kept) != tt.wantKept || len(dropped) != tt.wantDropped {
+				t.Fatalf("kept %d and dropped %d messages, want %d and %d",
+					len(kept), len(dropped), tt.wantKept, tt.wantDropped)
+			}
+			if len(kept) > 0 && kept[0].Role != "user" {
+				t.Errorf("kept history starts with a %s message", kept[0].Role)
+			}
+		})
+	}
+}
+
+func newHistoryOrchestrator(completer *MockCompleter, overflow string) *Orchestrator {
+	return newTestOrchestrator([]database.SearchResult{{ID: "doc-1", Content: "WAL shipping"}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.MaxHistoryTokens = 25
+			p.HistoryOverflow = overflow
+			cfg.CompletionProv = completer
+		})
+}
+
+func TestOrchestrator_Execute_DropsHistory(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newHistoryOrchestrator(completer, config.HistoryOverflowDrop)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "and then?", Messages: conversation()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected only the answer to be requested, got %d requests", len(requests))
+	}
+	// The two most recent messages and the question.
+	if msgs := requests[0].Messages; len(msgs) != 3 || !strings.HasPrefix(msgs[0].Content[0].Text, "3") {
+		t.Errorf("unexpected messages sent: %+v", msgs)
+	}
+	if resp.Usage.HistorySummary != nil {
+		t.Errorf("expected no summary usage, got %+v", resp.Usage.HistorySummary)
+	}
+}
+
+func TestOrchestrator_Execute_SummarizesHistory(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			text := "answer"
+			if req.SystemPrompt == historySummaryPrompt {
+				text = "The user asked about replication."
+			}
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}},
+				Usage:   llmlib.TokenUsage{PromptTokens: 30, CompletionTokens: 8, TotalTokens: 38},
+			}, nil
+		},
+	}
+	orch := newHistoryOrchestrator(completer, config.HistoryOverflowSummarize)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "and then?", Messages: conversation()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected a summary and an answer, got %d requests", len(requests))
+	}
+	transcript := requests[0].Messages[0].Content[0].Text
+	if !strings.HasPrefix(transcript, "user: 1") || !strings.Contains(transcript, "assistant: 2") ||
+		strings.Contains(transcript, "user: 3") {
+		t.Errorf("expected only the dropped messages to be summarized, got %q", transcript)
+	}
+	answer := requests[1]
+	if !strings.Contains(answer.SystemPrompt, "Summary of the earlier conversation:\nThe user asked about replication.") {
+		t.Errorf("expected the summary in the system prompt, got %q", answer.SystemPrompt)
+	}
+	if len(answer.Messages) != 3 {
+		t.Errorf("expected the kept history and the question, got %d messages", len(answer.Messages))
+	}
+	if u := resp.Usage.HistorySummary; u == nil || u.TotalTokens != 38 {
+		t.Errorf("expected the summary's usage, got %+v", u)
+	}
+}
+
+func TestOrchestrator_Execute_SummaryFailureDropsHistory(t *testing.T) {
+	var answer llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if req.SystemPrompt == historySummaryPrompt {
+				return nil, errors.New("provider unavailable")
+			}
+			answer = req
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newHistoryOrchestrator(completer, config.HistoryOverflowSummarize)
+
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "and then?", Messages: conversation()}); err != nil {
+		t.Fatalf("expected the query to succeed without a summary, got %v", err)
+	}
+	if strings.Contains(answer.SystemPrompt, "Summary of the earlier conversation") || len(answer.Messages) != 3 {
+		t.Errorf("expected the older messages to be dropped without a summary, got %+v", answer)
+	}
+}
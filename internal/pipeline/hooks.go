@@ -0,0 +1,312 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// Functions a hook script may define, with the points of a query they
+// are called at.
+const (
+	HookRewriteQuery    = "rewrite_query"    // before retrieval, with the query
+	HookFilterResults   = "filter_results"   // after retrieval, with the query and documents
+	HookTransformAnswer = "transform_answer" // before the answer is returned, with the query and answer
+)
+
+// hookParams are the number of parameters each hook function takes.
+var hookParams = map[string]int{
+	HookRewriteQuery:    1,
+	HookFilterResults:   2,
+	HookTransformAnswer: 2,
+}
+
+// Hooks are a pipeline's loaded hook script. Its globals are frozen
+// once it has run, so its functions may be called by many queries at
+// once, each on a thread of its own.
+type Hooks struct {
+	funcs    map[string]*starlark.Function
+	timeout  time.Duration
+	maxSteps uint64
+	logger   *slog.Logger
+}
+
+// LoadHooks runs a pipeline's hook script and collects the hook
+// functions it defines, or returns nil when the pipeline has none.
+func LoadHooks(cfg config.HooksConfig, logger *slog.Logger) (*Hooks, error) {
+	if cfg.Script == "" {
+		return nil, nil
+	}
+	h := &Hooks{
+		funcs:    make(map[string]*starlark.Function),
+		timeout:  time.Duration(cfg.Timeout),
+		maxSteps: uint64(cfg.MaxSteps),
+		logger:   logger,
+	}
+	if h.timeout <= 0 {
+		h.timeout = config.DefaultHookTimeout
+	}
+	if h.maxSteps == 0 {
+		h.maxSteps = config.DefaultHookMaxSteps
+	}
+
+	src, err := os.ReadFile(cfg.ScriptPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook script: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	thread, stop := h.thread(ctx, "load")
+	defer stop()
+	globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, cfg.Script, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run hook script: %w", err)
+	}
+	globals.Freeze()
+
+	for _, name := range []string{HookRewriteQuery, HookFilterResults, HookTransformAnswer} {
+		params := hookParams[name]
+		v, ok := globals[name]
+		if !ok {
+			continue
+		}
+		fn, ok := v.(*starlark.Function)
+		if !ok || fn.NumParams() != params {
+			return nil, fmt.Errorf("hook script %s: %s must be a function of %d parameters",
+				cfg.Script, name, params)
+		}
+		h.funcs[name] = fn
+	}
+	if len(h.funcs) == 0 {
+		return nil, fmt.Errorf("hook script %s defines none of %s, %s or %s",
+			cfg.Script, HookRewriteQuery, HookFilterResults, HookTransformAnswer)
+	}
+	return h, nil
+}
+
+// defines reports whether the script defines the named hook.
+func (h *Hooks) defines(name string) bool {
+	return h != nil && h.funcs[name] != nil
+}
+
+// thread returns a sandboxed thread for one call of a hook, cancelled
+// with ctx. Scripts have no built-ins beyond the Starlark language, so
+// they cannot read files or reach the network; load is not supported,
+// and print writes to the debug log. Call stop once the call returns.
+func (h *Hooks) thread(ctx context.Context, name string) (thread *starlark.Thread, stop func() bool) {
+	thread = &starlark.Thread{
+		Name: "hook " + name,
+		Print: func(_ *starlark.Thread, msg string) {
+			h.logger.Debug("hook script output", "hook", name, "message", msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(h.maxSteps)
+	stop = context.AfterFunc(ctx, func() {
+		thread.Cancel(ctx.Err().Error())
+	})
+	return thread, stop
+}
+
+// call calls the named hook with args, bounded by the script's timeout
+// and step limit.
+func (h *Hooks) call(ctx context.Context, name string, args ...starlark.Value) (starlark.Value, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+	thread, stop := h.thread(ctx, name)
+	defer stop()
+	return starlark.Call(thread, h.funcs[name], args, nil)
+}
+
+// runHook calls the named hook, if the script defines it, and hands its
+// result to use, which rejects results of the wrong shape. A hook that
+// fails is logged and skipped, leaving the query as it was.
+func (o *Orchestrator) runHook(ctx context.Context, name string, use func(starlark.Value) error,
+	args ...starlark.Value) {
+	if !o.hooks.defines(name) {
+		return
+	}
+	start := time.Now()
+	v, err := o.hooks.call(ctx, name, args...)
+	if err == nil {
+		err = use(v)
+	}
+	o.observeStage(metrics.StageHook, metrics.ProviderStarlark, start, err)
+	if err != nil {
+		o.logger.Warn("hook failed; continuing without it", "hook", name, "error", err)
+	}
+}
+
+// rewriteQuery returns the text a query searches for: the query
+// itself, or what the rewrite_query hook turns it into.
+func (o *Orchestrator) rewriteQuery(ctx context.Context, query string) string {
+	rewritten := query
+	o.runHook(ctx, HookRewriteQuery, func(v starlark.Value) error {
+		s, ok := starlark.AsString(v)
+		if !ok || strings.TrimSpace(s) == "" {
+			return fmt.Errorf("must return a non-empty string, got %s", v.Type())
+		}
+		rewritten = s
+		return nil
+	}, starlark.String(query))
+	return rewritten
+}
+
+// filterResults returns the retrieved documents the filter_results hook
+// keeps, in the order it returns them.
+func (o *Orchestrator) filterResults(ctx context.Context, query string,
+	results []database.SearchResult) []database.SearchResult {
+	if !o.hooks.defines(HookFilterResults) || len(results) == 0 {
+		return results
+	}
+	docs := make([]starlark.Value, len(results))
+	for i, r := range results {
+		docs[i] = hookDocument(i, r)
+	}
+	kept := results
+	o.runHook(ctx, HookFilterResults, func(v starlark.Value) error {
+		filtered, err := keptResults(v, results)
+		if err != nil {
+			return err
+		}
+		kept = filtered
+		return nil
+	}, starlark.String(query), starlark.NewList(docs))
+	if dropped := len(results) - len(kept); dropped > 0 {
+		o.logger.Debug("hook removed documents", "hook", HookFilterResults, "removed", dropped)
+	}
+	return kept
+}
+
+// transformAnswer returns the answer to send: the completion's, or what
+// the transform_answer hook turns it into.
+func (o *Orchestrator) transformAnswer(ctx context.Context, query, answer string) string {
+	transformed := answer
+	o.runHook(ctx, HookTransformAnswer, func(v starlark.Value) error {
+		s, ok := starlark.AsString(v)
+		if !ok {
+			return fmt.Errorf("must return a string, got %s", v.Type())
+		}
+		transformed = s
+		return nil
+	}, starlark.String(query), starlark.String(answer))
+	return transformed
+}
+
+// buffersAnswer reports whether a streamed answer is held back until it
+// is complete, for the guardrails or the transform_answer hook to see
+// it whole.
+func (o *Orchestrator) buffersAnswer() bool {
+	return o.guardrails != nil || o.hooks.defines(HookTransformAnswer)
+}
+
+// hookDocument is a retrieved document as filter_results sees it. The
+// index identifies it among the documents passed in.
+func hookDocument(index int, r database.SearchResult) *starlark.Dict {
+	doc := starlark.NewDict(5)
+	_ = doc.SetKey(starlark.String("index"), starlark.MakeInt(index))
+	_ = doc.SetKey(starlark.String("id"), starlark.String(r.ID))
+	_ = doc.SetKey(starlark.String("content"), starlark.String(r.Content))
+	_ = doc.SetKey(starlark.String("score"), starlark.Float(r.Score))
+	_ = doc.SetKey(starlark.String("metadata"), hookValue(r.SourceInfo))
+	return doc
+}
+
+// keptResults maps the documents filter_results returned back to the
+// results they were made from. A document returned twice is kept once.
+func keptResults(v starlark.Value, results []database.SearchResult) ([]database.SearchResult, error) {
+	seq, ok := v.(starlark.Sequence)
+	if !ok {
+		return nil, fmt.Errorf("must return a list of documents, got %s", v.Type())
+	}
+	kept := make([]database.SearchResult, 0, seq.Len())
+	seen := make([]bool, len(results))
+	iter := seq.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		doc, ok := item.(starlark.Mapping)
+		if !ok {
+			return nil, fmt.Errorf("must return documents, got %s", item.Type())
+		}
+		v, found, err := doc.Get(starlark.String("index"))
+		if err != nil || !found {
+			return nil, fmt.Errorf("returned a document without its index")
+		}
+		i, err := starlark.AsInt32(v)
+		if err != nil || i < 0 || i >= len(results) {
+			return nil, fmt.Errorf("returned a document with an unknown index %s", v)
+		}
+		if !seen[i] {
+			seen[i] = true
+			kept = append(kept, results[i])
+		}
+	}
+	return kept, nil
+}
+
+// hookValue converts a document's metadata value for a hook script,
+// with map keys in sorted order. Values of other types are passed as
+// their text.
+func hookValue(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None
+	case string:
+		return starlark.String(v)
+	case bool:
+		return starlark.Bool(v)
+	case int:
+		return starlark.MakeInt(v)
+	case int16:
+		return starlark.MakeInt64(int64(v))
+	case int32:
+		return starlark.MakeInt64(int64(v))
+	case int64:
+		return starlark.MakeInt64(v)
+	case float32:
+		return starlark.Float(v)
+	case float64:
+		return starlark.Float(v)
+	case time.Time:
+		return starlark.String(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		items := make([]starlark.Value, len(v))
+		for i, item := range v {
+			items[i] = hookValue(item)
+		}
+		return starlark.NewList(items)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, key := range keys {
+			_ = dict.SetKey(starlark.String(key), hookValue(v[key]))
+		}
+		return dict
+	default:
+		return starlark.String(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,211 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// writeHookScript writes a hook script to a temporary file and returns
+// its configuration.
+func writeHookScript(t *testing.T, src string) config.HooksConfig {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hooks.star")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatalf("failed to write script: %v", err)
+	}
+	return config.HooksConfig{Script: path}
+}
+
+func TestLoadHooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		maxSteps int
+		wantErr  string
+	}{
+		{
+			name: "loads the hooks a script defines",
+			src:  "def rewrite_query(query):\n    return query\n",
+		},
+		{
+			name:    "rejects a script that does not compile",
+			src:     "def rewrite_query(query)\n    return query\n",
+			wantErr: "failed to run hook script",
+		},
+		{
+			name:    "rejects a hook with the wrong parameters",
+			src:     "def transform_answer(answer):\n    return answer\n",
+			wantErr: "transform_answer must be a function of 2 parameters",
+		},
+		{
+			name:    "rejects a script with no hooks",
+			src:     "def rewrite(query):\n    return query\n",
+			wantErr: "defines none of",
+		},
+		{
+			name:    "does not load other scripts",
+			src:     "load('other.star', 'x')\n",
+			wantErr: "failed to run hook script",
+		},
+		{
+			name:     "bounds the steps the script runs",
+			src:      "n = len([i for i in range(1000000)])\ndef rewrite_query(query):\n    return query\n",
+			maxSteps: 1000,
+			wantErr:  "too many steps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := writeHookScript(t, tt.src)
+			cfg.MaxSteps = tt.maxSteps
+			h, err := LoadHooks(cfg, slog.Default())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !h.defines(HookRewriteQuery) || h.defines(HookFilterResults) {
+				t.Errorf("unexpected hooks: %v", h.funcs)
+			}
+		})
+	}
+
+	if h, err := LoadHooks(config.HooksConfig{}, slog.Default()); h != nil || err != nil {
+		t.Errorf("expected no hooks without a script, got %v, %v", h, err)
+	}
+}
+
+const testHookScript = `
+def rewrite_query(query):
+    return query + " wal"
+
+def filter_results(query, documents):
+    kept = [d for d in documents if d["score"] >= 0.5]
+    return sorted(kept, key=lambda d: d["id"], reverse=True)
+
+def transform_answer(query, answer):
+    return answer + " (checked)"
+`
+
+// newHooksOrchestrator returns an orchestrator running script, and the
+// texts it embeds.
+func newHooksOrchestrator(t *testing.T, script string) (*Orchestrator, *[]string) {
+	t.Helper()
+	hooks, err := LoadHooks(writeHookScript(t, script), slog.Default())
+	if err != nil {
+		t.Fatalf("failed to load hooks: %v", err)
+	}
+	var embedded []string
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "",
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			cfg.EmbeddingProv = &MockEmbedder{
+				EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+					embedded = append(embedded, text)
+					return []float64{0.1, 0.2, 0.3}, nil
+				},
+			}
+			cfg.Hooks = hooks
+		})
+	return orch, &embedded
+}
+
+func TestOrchestrator_Execute_Hooks(t *testing.T) {
+	orch, embedded := newHooksOrchestrator(t, testHookScript)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query:          "how does replication work?",
+		IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*embedded) != 1 || (*embedded)[0] != "how does replication work? wal" {
+		t.Errorf("expected the rewritten query to be embedded, got %q", *embedded)
+	}
+	if resp.Answer != "WAL is streamed to the standby. (checked)" {
+		t.Errorf("unexpected answer %q", resp.Answer)
+	}
+	var ids []string
+	for _, s := range resp.Sources {
+		ids = append(ids, s.ID)
+	}
+	if got := strings.Join(ids, ","); got != "doc-2,doc-1" {
+		t.Errorf("expected the filtered documents doc-2,doc-1, got %s", got)
+	}
+}
+
+func TestOrchestrator_ExecuteStream_TransformHook(t *testing.T) {
+	orch, _ := newHooksOrchestrator(t, testHookScript)
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "how does replication work?"})
+	var content []string
+	for chunk := range chunks {
+		if chunk.Content != "" {
+			content = append(content, chunk.Content)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(content) != 1 || content[0] != "WAL is streamed to the standby. (checked)" {
+		t.Errorf("expected the transformed answer in one chunk, got %q", content)
+	}
+}
+
+func TestOrchestrator_Hooks_FailOpen(t *testing.T) {
+	orch, embedded := newHooksOrchestrator(t, `
+def rewrite_query(query):
+    return 42
+
+def filter_results(query, documents):
+    return [{"index": 7}]
+
+def transform_answer(query, answer):
+    return fail("broken")
+`)
+
+	resp, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "standby"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if (*embedded)[0] != "standby" || len(resp.Documents) != 3 {
+		t.Errorf("expected failed hooks to be skipped, embedded %q and got %d documents",
+			*embedded, len(resp.Documents))
+	}
+
+	answer, err := orch.Execute(context.Background(), QueryRequest{Query: "standby"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if answer.Answer != "WAL is streamed to the standby." {
+		t.Errorf("expected the untransformed answer, got %q", answer.Answer)
+	}
+}
+
+func TestHookValue(t *testing.T) {
+	v := hookValue(map[string]interface{}{"page": int32(3), "tags": []interface{}{"a", nil}})
+	if got := fmt.Sprint(v); got != `{"page": 3, "tags": ["a", None]}` {
+		t.Errorf("unexpected value %s", got)
+	}
+}
@@ -0,0 +1,133 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// DefaultHyDEWeight is the weight of the hypothetical answer's
+// embedding when retrieval.hyde_weight is unset: the draft alone.
+const DefaultHyDEWeight = 1.0
+
+// hydeMaxTokens bounds the length of the hypothetical answer. A short
+// passage is enough to land near the documents that answer the query,
+// and a long one only adds latency.
+const hydeMaxTokens = 256
+
+// hydePrompt asks the completion LLM for a hypothetical answer.
+const hydePrompt = `Write a short passage, of one paragraph, that answers the user's question the way a document in a technical knowledge base would.
+If you are unsure of the facts, write a plausible answer anyway; it is only used to find similar documents, never shown to the user.
+Reply with the passage only.`
+
+// hydeEmbedding returns the embedding to search for query with
+// hypothetical document embeddings (HyDE): the completion provider
+// drafts an answer, and the draft's embedding is blended with the
+// query's by the pipeline's hyde_weight. A short, keyword-like query
+// embeds far from the documents that answer it, while a draft answer,
+// right or wrong, embeds close to them.
+//
+// Drafting or embedding the answer failing only loses the draft, so it
+// is logged and the query's own embedding is returned.
+func (o *Orchestrator) hydeEmbedding(
+	ctx context.Context,
+	query string,
+	embedding []float32,
+	usage *StageUsage,
+) []float32 {
+	weight := DefaultHyDEWeight
+	if o.cfg.Retrieval.HyDEWeight != nil {
+		weight = *o.cfg.Retrieval.HyDEWeight
+	}
+	if weight == 0 {
+		return embedding
+	}
+
+	draft := o.draftAnswer(ctx, query, usage)
+	if draft == "" {
+		return embedding
+	}
+
+	draftUsage := &StageUsage{}
+	draftEmbedding, err := o.embedWithTimeout(ctx, draft, draftUsage)
+	usage.Embedding.Add(draftUsage.Embedding)
+	if err != nil {
+		o.logger.Warn("embedding the hypothetical answer failed, searching the query alone",
+			"error", err)
+		return embedding
+	}
+	if len(draftEmbedding) != len(embedding) {
+		o.logger.Warn("hypothetical answer embedding has a different dimension, searching the query alone",
+			"query_dimensions", len(embedding), "draft_dimensions", len(draftEmbedding))
+		return embedding
+	}
+	return blendEmbeddings(embedding, draftEmbedding, weight)
+}
+
+// draftAnswer asks the completion provider for a hypothetical answer to
+// query, bounded by the pipeline's completion_timeout, and records the
+// tokens it used. It returns "" if no draft was produced.
+func (o *Orchestrator) draftAnswer(ctx context.Context, query string, usage *StageUsage) string {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: hydePrompt,
+		Messages:     []llmlib.Message{llmlib.UserText(query)},
+		MaxTokens:    llmlib.Int(hydeMaxTokens),
+	})
+	o.observeStage(metrics.StageQueryExpansion, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("drafting a hypothetical answer failed, searching the query alone",
+			"error", stageTimeout(ctx, err))
+		return ""
+	}
+	usage.QueryExpansion = &resp.Usage
+	o.recordUsage(metrics.StageQueryExpansion, o.completionProvider(), resp.Usage)
+
+	return strings.TrimSpace(joinTextBlocks(resp.Content))
+}
+
+// blendEmbeddings returns the unit vector in the direction of
+// (1-weight)*query + weight*draft. Both embeddings are normalized
+// first, so the weight rather than their magnitudes decides the blend.
+func blendEmbeddings(query, draft []float32, weight float64) []float32 {
+	q, d := normalize(query), normalize(draft)
+	blended := make([]float32, len(q))
+	for i := range q {
+		blended[i] = float32((1-weight)*float64(q[i]) + weight*float64(d[i]))
+	}
+	return normalize(blended)
+}
+
+// normalize returns v scaled to unit length, or v itself if it is zero.
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
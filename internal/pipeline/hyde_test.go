@@ -0,0 +1,103 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+const hydeDraft = "Streaming replication ships WAL records from the primary to standbys."
+
+// newHyDEOrchestrator returns an orchestrator whose embedder maps the
+// query to [1, 0] and the draft answer to [0, 1], and which records
+// the embedding each vector search was given.
+func newHyDEOrchestrator(completer *MockCompleter, weight *float64, searched *[]float32) *Orchestrator {
+	return newTestOrchestrator(nil, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Retrieval = config.RetrievalConfig{Strategy: config.RetrievalStrategyHyDE, HyDEWeight: weight}
+		cfg.DBPool = &MockSearchBackend{
+			VectorSearchFunc: func(
+				ctx context.Context, embedding []float32, table config.TableSource,
+				topN int, filter *config.Filter, minSimilarity *float64,
+			) ([]database.SearchResult, error) {
+				*searched = embedding
+				return []database.SearchResult{{ID: "doc-1", Content: "WAL shipping"}}, nil
+			},
+		}
+		cfg.EmbeddingProv = &MockEmbedder{
+			EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+				if text == hydeDraft {
+					return []float64{0, 3}, nil
+				}
+				return []float64{1, 0}, nil
+			},
+		}
+		cfg.CompletionProv = completer
+	})
+}
+
+func hydeCompleter(draftErr error) *MockCompleter {
+	return &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if req.SystemPrompt == hydePrompt {
+				if draftErr != nil {
+					return nil, draftErr
+				}
+				return &llmlib.ChatResponse{
+					Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: hydeDraft + "\n"}},
+					Usage:   llmlib.TokenUsage{PromptTokens: 30, CompletionTokens: 15, TotalTokens: 45},
+				}, nil
+			}
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+}
+
+func TestOrchestrator_HyDE(t *testing.T) {
+	half := 0.5
+	tests := []struct {
+		name     string
+		weight   *float64
+		draftErr error
+		want     []float32
+		wantUsed bool
+	}{
+		{name: "draft alone by default", want: []float32{0, 1}, wantUsed: true},
+		{name: "blended", weight: &half, want: []float32{math.Sqrt2 / 2, math.Sqrt2 / 2}, wantUsed: true},
+		{name: "draft fails", draftErr: errors.New("provider unavailable"), want: []float32{1, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var searched []float32
+			orch := newHyDEOrchestrator(hydeCompleter(tt.draftErr), tt.weight, &searched)
+
+			resp, err := orch.Execute(context.Background(), QueryRequest{Query: "wal shipping"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(searched) != 2 ||
+				math.Abs(float64(searched[0]-tt.want[0])) > 1e-6 ||
+				math.Abs(float64(searched[1]-tt.want[1])) > 1e-6 {
+				t.Errorf("searched with %v, want %v", searched, tt.want)
+			}
+			if used := resp.Usage.QueryExpansion != nil; used != tt.wantUsed {
+				t.Errorf("query expansion usage = %+v, want recorded: %v", resp.Usage.QueryExpansion, tt.wantUsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// Ingest splits an uploaded document into chunks, embeds each with the
+// pipeline's embedding LLM, and inserts them into the ingest table with
+// the document's filename and page numbers, in the columns configured
+// for them. A pipeline that isolates tenants stores the chunks as the
+// rows of the tenant ctx's filter variables name. progress, if non-nil,
+// is called as chunks are embedded. It returns the number of chunks
+// stored; none are stored if any fails.
+func (o *Orchestrator) Ingest(
+	ctx context.Context,
+	doc *ingest.Document,
+	progress func(done, total int),
+) (int, error) {
+	if !o.cfg.Ingest.Enabled || o.store == nil {
+		return 0, fmt.Errorf("%w: document ingestion is not enabled for this pipeline", ErrInvalidRequest)
+	}
+	table, ok := o.cfg.IngestTable()
+	if !ok {
+		return 0, fmt.Errorf("ingest table %q is not one of the pipeline's tables", o.cfg.Ingest.Table)
+	}
+
+	var tenant string
+	if o.cfg.Tenant.Enabled() {
+		if tenant, ok = database.FilterVarsFrom(ctx).Lookup(o.cfg.Tenant.Variable()); !ok {
+			return 0, fmt.Errorf("%w: the pipeline isolates tenants and the upload names none; set %s",
+				ErrInvalidRequest, o.cfg.Tenant.Variable())
+		}
+	}
+
+	pieces := ingest.Split(doc, o.cfg.Ingest.ChunkTokens, o.cfg.Ingest.ChunkOverlap)
+	if len(pieces) == 0 {
+		return 0, fmt.Errorf("%w: no text found in %s", ErrInvalidRequest, doc.Filename)
+	}
+	if progress == nil {
+		progress = func(done, total int) {}
+	}
+	progress(0, len(pieces))
+
+	// The chunks are embedded as documents to be searched, for the
+	// models that embed them differently from queries.
+	embedCtx := ragllm.ContextWithDocumentEmbedding(ctx)
+	chunks := make([]database.Chunk, len(pieces))
+	usage := &StageUsage{}
+	for i, piece := range pieces {
+		embedding, err := o.embedWithTimeout(embedCtx, piece.Text, usage)
+		if err != nil {
+			return 0, fmt.Errorf("failed to embed chunk %d of %s: %w", i+1, doc.Filename, err)
+		}
+
+		metadata := make(map[string]interface{})
+		if col := o.cfg.Ingest.FilenameColumn; col != "" {
+			metadata[col] = doc.Filename
+		}
+		if col := o.cfg.Ingest.PageColumn; col != "" && piece.Page > 0 {
+			metadata[col] = piece.Page
+		}
+		if o.cfg.Tenant.Enabled() {
+			metadata[o.cfg.Tenant.Column] = tenant
+		}
+		chunks[i] = database.Chunk{Content: piece.Text, Embedding: embedding, Metadata: metadata}
+		progress(i+1, len(pieces))
+	}
+
+	if err := o.store.InsertChunks(ctx, table, chunks); err != nil {
+		return 0, err
+	}
+	// Answers and documents cached before the upload miss the new
+	// documents.
+	o.answerCache.clear()
+	o.documentCache.clear()
+	return len(chunks), nil
+}
@@ -0,0 +1,135 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
+)
+
+// MockChunkStore implements pipeline.ChunkStore, recording the chunks
+// it is asked to insert.
+type MockChunkStore struct {
+	Table  config.TableSource
+	Chunks []database.Chunk
+	Err    error
+}
+
+func (m *MockChunkStore) InsertChunks(ctx context.Context, table config.TableSource, chunks []database.Chunk) error {
+	m.Table, m.Chunks = table, chunks
+	return m.Err
+}
+
+func newIngestOrchestrator(store ChunkStore, embedder Embedder, in config.IngestConfig) *Orchestrator {
+	return newTestOrchestrator(nil, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Tables = []config.TableSource{
+			{Table: "articles", TextColumn: "body", VectorColumn: "embedding"},
+			{Table: "uploads", TextColumn: "content", VectorColumn: "embedding"},
+		}
+		p.Ingest = in
+		cfg.Store = store
+		cfg.EmbeddingProv = embedder
+	})
+}
+
+func TestOrchestrator_Ingest(t *testing.T) {
+	store := &MockChunkStore{}
+	orch := newIngestOrchestrator(store, &MockEmbedder{}, config.IngestConfig{
+		Enabled:        true,
+		Table:          "uploads",
+		ChunkTokens:    10,
+		FilenameColumn: "source",
+		PageColumn:     "page",
+	})
+
+	doc := &ingest.Document{
+		Filename: "guide.pdf",
+		Pages: []ingest.Page{
+			{Number: 1, Text: "Streaming replication."},
+			{Number: 2, Text: "Logical replication."},
+		},
+	}
+	var progress [][2]int
+	n, err := orch.Ingest(context.Background(), doc, func(done, total int) {
+		progress = append(progress, [2]int{done, total})
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 2 || len(store.Chunks) != 2 || store.Table.Table != "uploads" {
+		t.Fatalf("expected 2 chunks stored in uploads, got %d in %q", len(store.Chunks), store.Table.Table)
+	}
+	second := store.Chunks[1]
+	if second.Content != "Logical replication." || len(second.Embedding) != 3 ||
+		second.Metadata["source"] != "guide.pdf" || second.Metadata["page"] != 2 {
+		t.Errorf("unexpected second chunk: %+v", second)
+	}
+	if len(progress) != 3 || progress[0] != [2]int{0, 2} || progress[2] != [2]int{2, 2} {
+		t.Errorf("unexpected progress: %v", progress)
+	}
+}
+
+func TestOrchestrator_Ingest_Tenant(t *testing.T) {
+	store := &MockChunkStore{}
+	orch := newIngestOrchestrator(store, &MockEmbedder{}, config.IngestConfig{Enabled: true, Table: "uploads"})
+	orch.cfg.Tenant = config.TenantConfig{Column: "tenant_id", Header: "X-Tenant-ID"}
+	doc := &ingest.Document{Filename: "notes.md", Pages: []ingest.Page{{Text: "Some notes."}}}
+
+	if _, err := orch.Ingest(context.Background(), doc, nil); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for an upload naming no tenant, got %v", err)
+	}
+	if store.Chunks != nil {
+		t.Error("expected nothing stored for an upload naming no tenant")
+	}
+
+	ctx := database.WithFilterVars(context.Background(), func(v config.FilterVariable) (string, bool) {
+		return "acme", v == config.FilterVariable{Source: config.FilterVarHeader, Name: "X-Tenant-ID"}
+	})
+	if _, err := orch.Ingest(ctx, doc, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.Chunks) != 1 || store.Chunks[0].Metadata["tenant_id"] != "acme" {
+		t.Errorf("expected the chunk stored as the tenant's row, got %+v", store.Chunks)
+	}
+}
+
+func TestOrchestrator_Ingest_Errors(t *testing.T) {
+	doc := &ingest.Document{Filename: "notes.md", Pages: []ingest.Page{{Text: "Some notes."}}}
+
+	disabled := newIngestOrchestrator(&MockChunkStore{}, &MockEmbedder{}, config.IngestConfig{})
+	if _, err := disabled.Ingest(context.Background(), doc, nil); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest with ingestion disabled, got %v", err)
+	}
+
+	enabled := config.IngestConfig{Enabled: true}
+	empty := &ingest.Document{Filename: "blank.md", Pages: []ingest.Page{{Text: " \n"}}}
+	orch := newIngestOrchestrator(&MockChunkStore{}, &MockEmbedder{}, enabled)
+	if _, err := orch.Ingest(context.Background(), empty, nil); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for a document without text, got %v", err)
+	}
+
+	store := &MockChunkStore{}
+	failing := &MockEmbedder{EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+		return nil, errors.New("rate limited")
+	}}
+	orch = newIngestOrchestrator(store, failing, enabled)
+	if _, err := orch.Ingest(context.Background(), doc, nil); err == nil || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("expected the embedding error, got %v", err)
+	}
+	if store.Chunks != nil {
+		t.Error("expected nothing stored when embedding fails")
+	}
+}
@@ -0,0 +1,112 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Stages of a pipeline's initialization, as logged while it starts and
+// reported by Health afterwards.
+const (
+	InitStageDBConnect    = "db_connect"    // opening the connection pool
+	InitStageSchemaCheck  = "schema_check"  // checking tables, columns and filters
+	InitStageProviders    = "providers"     // creating clients and checking embedding dimensions
+	InitStageProviderPing = "provider_ping" // checking the providers respond
+	InitStageWarmUp       = "warm_up"       // loading the documents BM25 ranks into the cache
+	InitStageBM25Index    = "bm25_index"    // indexing the loaded documents
+)
+
+// InitError is the error that stopped a pipeline from starting, with
+// the stages of its initialization up to and including the one that
+// failed.
+type InitError struct {
+	Stages []InitStage
+	Err    error
+}
+
+func (e *InitError) Error() string { return e.Err.Error() }
+
+func (e *InitError) Unwrap() error { return e.Err }
+
+// initProgress times the stages of a pipeline's initialization, and
+// logs each as it starts and ends, so a slow startup or reload can be
+// traced to the stage that held it up.
+type initProgress struct {
+	logger  *slog.Logger
+	stages  []InitStage
+	current string
+	start   time.Time
+	err     error // recorded against the current stage by note
+	now     func() time.Time
+}
+
+func newInitProgress(logger *slog.Logger) *initProgress {
+	return &initProgress{logger: logger, now: time.Now}
+}
+
+// begin ends the current stage, if any, and starts the named one.
+func (p *initProgress) begin(stage string) {
+	p.end()
+	p.current, p.start, p.err = stage, p.now(), nil
+	p.logger.Debug("pipeline initialization stage started", "stage", stage)
+}
+
+// end ends the current stage, if any, and records how long it took and
+// any error noted against it.
+func (p *initProgress) end() {
+	if p.current == "" {
+		return
+	}
+	d := p.record()
+	if p.err != nil {
+		p.logger.Warn("pipeline initialization stage completed with an error",
+			"stage", p.current, "duration", d, "error", p.err)
+	} else {
+		p.logger.Info("pipeline initialization stage completed", "stage", p.current, "duration", d)
+	}
+	p.current = ""
+}
+
+// note records err against the current stage without stopping the
+// pipeline from starting, for a stage whose failure only costs the
+// first queries time. A nil err is ignored.
+func (p *initProgress) note(err error) {
+	if err != nil {
+		p.err = err
+	}
+}
+
+// fail ends the current stage, if any, with the error that stopped the
+// pipeline from starting, and returns that error as an *InitError
+// carrying the stages so far.
+func (p *initProgress) fail(err error) error {
+	if p.current != "" {
+		p.err = err
+		d := p.record()
+		p.logger.Error("pipeline initialization stage failed",
+			"stage", p.current, "duration", d, "error", err)
+		p.current = ""
+	}
+	return &InitError{Stages: p.stages, Err: err}
+}
+
+// record appends the current stage, with how long it has taken and any
+// error against it, and returns the duration.
+func (p *initProgress) record() time.Duration {
+	d := p.now().Sub(p.start)
+	stage := InitStage{Name: p.current, DurationMS: d.Milliseconds()}
+	if p.err != nil {
+		stage.Error = p.err.Error()
+	}
+	p.stages = append(p.stages, stage)
+	return d
+}
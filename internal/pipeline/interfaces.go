@@ -16,6 +16,7 @@ import (
 
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
 	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
 )
 
 // Embedder is the narrow interface the orchestrator needs from an
@@ -61,7 +62,36 @@ type SearchBackend interface {
 		ctx context.Context,
 		table config.TableSource,
 		filter *config.Filter,
-	) (map[string]string, error)
+		limits database.FetchLimits,
+	) (map[string]database.Document, error)
+
+	TextSearch(
+		ctx context.Context,
+		queryText string,
+		table config.TableSource,
+		topN int,
+		filter *config.Filter,
+	) ([]database.SearchResult, error)
+}
+
+// ChunkStore is the narrow interface document ingestion needs to store
+// the chunks of a document. *database.Pool satisfies it structurally.
+type ChunkStore interface {
+	InsertChunks(ctx context.Context, table config.TableSource, chunks []database.Chunk) error
+}
+
+// ParentStore is the narrow interface expanding matched chunks into
+// their parent documents or neighbouring chunks needs. *database.Pool
+// satisfies it structurally.
+type ParentStore interface {
+	FetchParents(ctx context.Context, table config.TableSource, ids []string, filter *config.Filter) (map[string]string, error)
+	FetchSiblings(ctx context.Context, table config.TableSource, matches []database.ChunkPosition, window int, filter *config.Filter) ([]database.Sibling, error)
+}
+
+// FeedbackStore is the narrow interface recording ratings of answers
+// needs. *database.Pool satisfies it structurally.
+type FeedbackStore interface {
+	InsertFeedback(ctx context.Context, table string, fb database.Feedback) error
 }
 
 // QueryExecutor is the narrow interface the server needs from a
@@ -74,6 +104,83 @@ type QueryExecutor interface {
 	ExecuteStreamWithOptions(ctx context.Context, req QueryRequest) (<-chan StreamChunk, <-chan error)
 }
 
+// Explainer is implemented by pipelines that can explain a document's
+// ranking for a query. *Pipeline satisfies it; the server checks for it
+// on the QueryExecutor it is given.
+type Explainer interface {
+	Explain(ctx context.Context, req ExplainRequest) (*Explanation, error)
+}
+
+// Retriever is implemented by pipelines that can return the documents
+// a query retrieves without answering it. *Pipeline satisfies it; the
+// server checks for it on the QueryExecutor it is given.
+type Retriever interface {
+	Retrieve(ctx context.Context, req RetrieveRequest) (*RetrieveResponse, error)
+}
+
+// Searcher is implemented by pipelines that can return the documents a
+// query retrieves, as a query's sources, without answering it.
+// *Pipeline satisfies it; the server checks for it on the QueryExecutor
+// it is given.
+type Searcher interface {
+	Search(ctx context.Context, req SearchRequest) (*SearchResponse, error)
+}
+
+// TextEmbedder is implemented by pipelines that can embed arbitrary
+// text as they embed queries. *Pipeline satisfies it; the server checks
+// for it on the QueryExecutor it is given.
+type TextEmbedder interface {
+	Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error)
+}
+
+// Estimator is implemented by pipelines that can estimate the prompt
+// size and cost of a query without answering it. *Pipeline satisfies
+// it; the server checks for it on the QueryExecutor it is given.
+type Estimator interface {
+	Estimate(ctx context.Context, req QueryRequest) (*Estimate, error)
+}
+
+// Evaluator is implemented by pipelines that can capture their queries
+// into an eval dataset, record feedback on their answers, and evaluate or tune
+// their retrieval against a dataset. *Pipeline satisfies it; the server
+// checks for it on the QueryExecutor it is given.
+type Evaluator interface {
+	Feedback(ctx context.Context, req FeedbackRequest) error
+	EvalDataset() []EvalCase
+	Evaluate(ctx context.Context, cases []EvalCase, opts EvalOptions) (*EvalReport, error)
+	Tune(ctx context.Context, cases []EvalCase, variants []TuningVariant, k int,
+		progress func(done, total int)) (*TuningReport, error)
+}
+
+// Ingester is implemented by pipelines that can store uploaded
+// documents. *Pipeline satisfies it; the server checks for it on the
+// QueryExecutor it is given.
+type Ingester interface {
+	Ingest(ctx context.Context, doc *ingest.Document, progress func(done, total int)) (int, error)
+}
+
+// Describer is implemented by pipelines that can report what their
+// requests and responses may contain. *Pipeline satisfies it; the
+// server checks for it on the QueryExecutor it is given.
+type Describer interface {
+	Capabilities() Capabilities
+}
+
+// Detailer is implemented by pipelines that can report their effective
+// configuration. *Pipeline satisfies it; the server checks for it on
+// the QueryExecutor it is given.
+type Detailer interface {
+	Detail() Detail
+}
+
+// Authorizer is the access control hook the orchestrator asks which
+// of the documents retrieved from a table the principal making a
+// request may see. It returns the IDs of those documents.
+type Authorizer interface {
+	Authorize(ctx context.Context, principal, table string, ids []string) ([]string, error)
+	Provider() string // The "provider" label of its metrics
+}
+
 // Reranker is the narrow interface the orchestrator needs from a
 // rerank-capable LLM client. The lib's llm.Client satisfies it
 // structurally; orchestrator tests provide a one-method mock.
@@ -0,0 +1,173 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// DefaultMaxIterations is the number of searches the iterative
+// retrieval strategy may make when retrieval.max_iterations is unset.
+const DefaultMaxIterations = 3
+
+// sufficiencyMaxTokens bounds the reply to a check of the results: a
+// verdict or a search query.
+const sufficiencyMaxTokens = 64
+
+// sufficientReply is the reply to a check that the documents found are
+// enough to answer the question.
+const sufficientReply = "SUFFICIENT"
+
+// sufficiencyPrompt asks the completion LLM whether the documents found
+// answer the question, and for a search query if they do not.
+const sufficiencyPrompt = `You help a search engine find documents that answer a user's question.
+You are given the question and the documents found so far. If they contain the information needed to answer the question, reply with ` + sufficientReply + ` only.
+Otherwise, reply with a search query, of one line, for the information that is missing, with no quotes or other text.`
+
+// iterativeSearch searches the query, then has the completion provider
+// check what was found: unless it judges the documents enough to
+// answer the query, the query it writes for what is missing is searched
+// too, and the results of all the searches are fused with reciprocal
+// rank fusion. This repeats until the documents are judged enough, a
+// query repeats, or the pipeline's max_iterations searches or
+// max_iteration_tokens tokens of checks are used.
+//
+// A check, or a search of the query it wrote, that fails ends the
+// iterations with the results found so far. A timeout still fails the
+// query.
+func (o *Orchestrator) iterativeSearch(
+	ctx context.Context,
+	req QueryRequest,
+	embedding []float32,
+	topN int,
+	usage *StageUsage,
+) ([]database.SearchResult, error) {
+	results, err := o.searchWithTimeout(ctx, req, embedding, topN)
+	if err != nil {
+		return nil, err
+	}
+	lists := [][]database.SearchResult{results}
+
+	maxIterations := o.cfg.Retrieval.MaxIterations
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxIterations
+	}
+	searched := map[string]bool{strings.ToLower(strings.TrimSpace(req.Query)): true}
+	spent := 0
+	for len(lists) < maxIterations {
+		query := o.checkSufficiency(ctx, req.Query, results, &spent, usage)
+		if query == "" || searched[strings.ToLower(query)] {
+			break
+		}
+		searched[strings.ToLower(query)] = true
+
+		queryUsage := &StageUsage{}
+		queryEmbedding, err := o.embedWithTimeout(ctx, query, queryUsage)
+		usage.Embedding.Add(queryUsage.Embedding)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("failed to generate embedding: %w", err)
+			}
+			o.logger.Warn("embedding a follow-up query failed, using the results so far", "error", err)
+			break
+		}
+
+		variant := req
+		variant.Query = query
+		found, err := o.searchWithTimeout(ctx, variant, queryEmbedding, topN)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			o.logger.Warn("searching a follow-up query failed, using the results so far", "error", err)
+			break
+		}
+		lists = append(lists, found)
+		results = database.FuseRankings(lists, database.DefaultRRFConstant, topN)
+	}
+
+	o.logger.Debug("iterative retrieval finished", "searches", len(lists))
+	return results, nil
+}
+
+// checkSufficiency shows the completion provider query and the context
+// results would give, bounded by the pipeline's completion_timeout, and
+// returns the search query it wrote for what is missing, or "" if it
+// judged them enough or the check failed. spent counts the tokens the
+// query's checks have used; a check estimated to take it past
+// max_iteration_tokens is not made.
+func (o *Orchestrator) checkSufficiency(
+	ctx context.Context,
+	query string,
+	results []database.SearchResult,
+	spent *int,
+	usage *StageUsage,
+) string {
+	docs, _ := o.buildContext(results)
+	prompt := "Question: " + query + "\n\n" + ragllm.FormatContextWith(docs, o.contextFormat())
+	if limit := o.cfg.Retrieval.MaxIterationTokens; limit > 0 {
+		estimate := o.counter().Count(sufficiencyPrompt+prompt) + sufficiencyMaxTokens
+		if *spent+estimate > limit {
+			o.logger.Debug("max_iteration_tokens reached, using the results so far",
+				"spent", *spent, "estimate", estimate)
+			return ""
+		}
+	}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: sufficiencyPrompt,
+		Messages:     []llmlib.Message{llmlib.UserText(prompt)},
+		MaxTokens:    llmlib.Int(sufficiencyMaxTokens),
+	})
+	o.observeStage(metrics.StageQueryExpansion, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("checking the retrieved documents failed, using the results so far",
+			"error", stageTimeout(ctx, err))
+		return ""
+	}
+	*spent += resp.Usage.TotalTokens
+	if usage.QueryExpansion == nil {
+		usage.QueryExpansion = &llmlib.TokenUsage{}
+	}
+	usage.QueryExpansion.Add(resp.Usage)
+	o.recordUsage(metrics.StageQueryExpansion, o.completionProvider(), resp.Usage)
+
+	return parseFollowUpQuery(joinTextBlocks(resp.Content))
+}
+
+// parseFollowUpQuery returns the search query in a reply to a check of
+// the results, or "" if the reply judged them enough.
+func parseFollowUpQuery(reply string) string {
+	for line := range strings.Lines(reply) {
+		line = strings.TrimSpace(strings.Trim(strings.TrimSpace(line), "\"'“”"))
+		if line == "" {
+			continue
+		}
+		if strings.EqualFold(strings.TrimRight(line, "."), sufficientReply) {
+			return ""
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestParseFollowUpQuery(t *testing.T) {
+	for _, tt := range []struct {
+		reply string
+		want  string
+	}{
+		{"SUFFICIENT", ""},
+		{"Sufficient.\n", ""},
+		{"", ""},
+		{"\n\"replication slot settings\"\nmore text", "replication slot settings"},
+	} {
+		if got := parseFollowUpQuery(tt.reply); got != tt.want {
+			t.Errorf("parseFollowUpQuery(%q) = %q, want %q", tt.reply, got, tt.want)
+		}
+	}
+}
+
+// newIterativeOrchestrator returns an orchestrator using the iterative
+// retrieval strategy, whose embedder encodes each query's length, and
+// whose vector search returns the documents registered for that length.
+func newIterativeOrchestrator(completer *MockCompleter, found map[string][]string,
+	retrieval config.RetrievalConfig) *Orchestrator {
+	retrieval.Strategy = config.RetrievalStrategyIterative
+	return newMultiQueryOrchestrator(completer, found, 0, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Retrieval = retrieval
+	})
+}
+
+func TestOrchestrator_Iterative(t *testing.T) {
+	var checks []string
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if req.SystemPrompt != sufficiencyPrompt {
+				return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+			}
+			checks = append(checks, req.Messages[0].Content[0].Text)
+			reply := "replication slots"
+			if len(checks) > 1 {
+				reply = sufficientReply
+			}
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: reply}},
+				Usage:   llmlib.TokenUsage{PromptTokens: 90, CompletionTokens: 10, TotalTokens: 100},
+			}, nil
+		},
+	}
+	orch := newIterativeOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a", "doc-b"},
+		"replication slots":               {"doc-c"},
+	}, config.RetrievalConfig{})
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query:          "configure streaming replication",
+		IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checks, got %d", len(checks))
+	}
+	if !strings.HasPrefix(checks[0], "Question: configure streaming replication") ||
+		!strings.Contains(checks[0], "content of doc-b") || strings.Contains(checks[0], "doc-c") {
+		t.Errorf("unexpected first check: %q", checks[0])
+	}
+	if !strings.Contains(checks[1], "content of doc-c") {
+		t.Errorf("expected the second check to see the follow-up's results, got %q", checks[1])
+	}
+
+	var ids []string
+	for _, s := range resp.Sources {
+		ids = append(ids, s.ID)
+	}
+	if want := []string{"doc-a", "doc-c", "doc-b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sources = %v, want %v", ids, want)
+	}
+	if resp.Usage.QueryExpansion == nil || resp.Usage.QueryExpansion.TotalTokens != 200 {
+		t.Errorf("expected both checks' usage, got %+v", resp.Usage.QueryExpansion)
+	}
+}
+
+func TestOrchestrator_Iterative_Caps(t *testing.T) {
+	tests := []struct {
+		name       string
+		retrieval  config.RetrievalConfig
+		wantChecks int
+	}{
+		// Every check asks for another query, so only the caps stop
+		// the iterations.
+		{"default iterations", config.RetrievalConfig{}, DefaultMaxIterations - 1},
+		{"one iteration", config.RetrievalConfig{MaxIterations: 1}, 0},
+		{"token cap", config.RetrievalConfig{MaxIterations: 5, MaxIterationTokens: 50}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := 0
+			completer := &MockCompleter{
+				ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+					text := "answer"
+					if req.SystemPrompt == sufficiencyPrompt {
+						checks++
+						text = strings.Repeat("more ", checks)
+					}
+					return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}}}, nil
+				},
+			}
+			orch := newIterativeOrchestrator(completer, map[string][]string{
+				"configure streaming replication": {"doc-a"},
+			}, tt.retrieval)
+
+			if _, err := orch.Execute(context.Background(), QueryRequest{Query: "configure streaming replication"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if checks != tt.wantChecks {
+				t.Errorf("expected %d checks, got %d", tt.wantChecks, checks)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// jsonSchema is the subset of JSON Schema that structured answers are
+// checked against: the keywords structured-output schemas use. Other
+// keywords, such as format and pattern, are passed to the provider but
+// not checked; $ref is rejected, since definitions are not resolved.
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                json.RawMessage        `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *additionalProperties  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	Ref                  string                 `json:"$ref"`
+}
+
+// schemaTypes is a schema's type keyword, which may name one type or
+// a list of them.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = many
+	return nil
+}
+
+// additionalProperties is a schema's additionalProperties keyword: a
+// boolean, or a schema the other properties must match.
+type additionalProperties struct {
+	allowed bool
+	schema  *jsonSchema
+}
+
+func (a *additionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.allowed); err == nil {
+		return nil
+	}
+	a.allowed = true
+	return json.Unmarshal(data, &a.schema)
+}
+
+// parseJSONSchema parses a schema for checking answers against.
+func parseJSONSchema(data json.RawMessage) (*jsonSchema, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return nil, errors.New("must be a JSON object")
+	}
+	var s jsonSchema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.usesRef() {
+		return nil, errors.New("$ref is not supported")
+	}
+	return &s, nil
+}
+
+// usesRef reports whether s or any schema in it is a reference.
+func (s *jsonSchema) usesRef() bool {
+	if s == nil {
+		return false
+	}
+	if s.Ref != "" || s.Items.usesRef() {
+		return true
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.schema.usesRef() {
+		return true
+	}
+	for _, p := range s.Properties {
+		if p.usesRef() {
+			return true
+		}
+	}
+	return slices.ContainsFunc(s.AnyOf, (*jsonSchema).usesRef)
+}
+
+// validate returns the ways the JSON document data departs from s, or
+// nil if it matches.
+func (s *jsonSchema) validate(data []byte) []string {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return []string{"not valid JSON: " + err.Error()}
+	}
+	var problems []string
+	s.check(v, "$", &problems)
+	return problems
+}
+
+func (s *jsonSchema) check(v any, path string, problems *[]string) {
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(s.Type) > 0 && !slices.ContainsFunc(s.Type, func(t string) bool { return hasJSONType(v, t) }) {
+		fail("must be of type %s", joinTypes(s.Type))
+		return
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+		fail("must be one of the enum values")
+	}
+	if len(s.Const) > 0 {
+		var c any
+		if json.Unmarshal(s.Const, &c) == nil && !reflect.DeepEqual(c, v) {
+			fail("must be %s", s.Const)
+		}
+	}
+	if len(s.AnyOf) > 0 && !slices.ContainsFunc(s.AnyOf, func(alt *jsonSchema) bool {
+		var altProblems []string
+		alt.check(v, path, &altProblems)
+		return len(altProblems) == 0
+	}) {
+		fail("must match one of the anyOf schemas")
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.check(item, path+"["+strconv.Itoa(i)+"]", problems)
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, path+"."+name+": is required")
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			field := path + "." + name
+			switch prop, ok := s.Properties[name]; {
+			case ok:
+				if prop != nil {
+					prop.check(v[name], field, problems)
+				}
+			case s.AdditionalProperties == nil:
+			case !s.AdditionalProperties.allowed:
+				*problems = append(*problems, field+": is not allowed")
+			case s.AdditionalProperties.schema != nil:
+				s.AdditionalProperties.schema.check(v[name], field, problems)
+			}
+		}
+	}
+}
+
+// hasJSONType reports whether the decoded JSON value v is of the JSON
+// Schema type t.
+func hasJSONType(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case float64:
+		return t == "number" || (t == "integer" && v == math.Trunc(v))
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+// joinTypes lists types for an error message.
+func joinTypes(types []string) string {
+	if len(types) == 1 {
+		return types[0]
+	}
+	return fmt.Sprintf("%s or %s", types[0], joinTypes(types[1:]))
+}
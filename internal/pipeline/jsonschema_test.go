@@ -0,0 +1,75 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONSchema(t *testing.T) {
+	for _, schema := range []string{
+		``,
+		`[]`,
+		`"object"`,
+		`{"type":1}`,
+		`{"type":"object","properties":{"a":{"$ref":"#/$defs/a"}}}`,
+	} {
+		if _, err := parseJSONSchema(json.RawMessage(schema)); err == nil {
+			t.Errorf("expected %q to be rejected", schema)
+		}
+	}
+}
+
+func TestJSONSchema_Validate(t *testing.T) {
+	schema, err := parseJSONSchema(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"answer": {"type": "string", "minLength": 1},
+			"confidence": {"type": "number", "minimum": 0, "maximum": 1},
+			"level": {"enum": ["low", "high"]},
+			"tags": {"type": "array", "items": {"type": "string"}, "maxItems": 2},
+			"count": {"type": ["integer", "null"]},
+			"source": {"anyOf": [{"type": "string"}, {"type": "object"}]}
+		},
+		"required": ["answer"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid", `{"answer":"yes","confidence":0.5,"level":"low","tags":["a"],"count":null,"source":{}}`, nil},
+		{"not JSON", `yes`, []string{"not valid JSON: invalid character 'y' looking for beginning of value"}},
+		{"not an object", `[]`, []string{"$: must be of type object"}},
+		{"missing required", `{}`, []string{"$.answer: is required"}},
+		{"extra property", `{"answer":"yes","other":1}`, []string{"$.other: is not allowed"}},
+		{"too short", `{"answer":""}`, []string{"$.answer: must be at least 1 characters"}},
+		{"out of range", `{"answer":"yes","confidence":2}`, []string{"$.confidence: must be at most 1"}},
+		{"not in enum", `{"answer":"yes","level":"mid"}`, []string{"$.level: must be one of the enum values"}},
+		{"bad item", `{"answer":"yes","tags":["a",1]}`, []string{"$.tags[1]: must be of type string"}},
+		{"too many items", `{"answer":"yes","tags":["a","b","c"]}`, []string{"$.tags: must have at most 2 items"}},
+		{"not an integer", `{"answer":"yes","count":1.5}`, []string{"$.count: must be of type integer or null"}},
+		{"no anyOf match", `{"answer":"yes","source":1}`, []string{"$.source: must match one of the anyOf schemas"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schema.validate([]byte(tt.doc)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validate(%s) = %q, want %q", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
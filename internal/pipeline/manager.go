@@ -14,18 +14,30 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"net/textproto"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
 	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
 	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
 )
 
 // ErrPipelineNotFound is returned when a requested pipeline does not exist.
 var ErrPipelineNotFound = errors.New("pipeline not found")
 
+// ErrInvalidRequest is returned (wrapped) when a query cannot be served
+// as submitted, e.g. it asks for a feature the pipeline's provider does
+// not support.
+var ErrInvalidRequest = errors.New("invalid request")
+
 // Default values for pipeline configuration
 const (
 	DefaultTokenBudget = 4000
@@ -37,7 +49,15 @@ type Manager struct {
 	mu        sync.RWMutex
 	pipelines map[string]*Pipeline
 	config    *config.Config
+	metrics   *metrics.Registry
+	costs     *CostLedger
 	logger    *slog.Logger
+	readOnly  bool
+
+	payloadLog *ragllm.PayloadLog
+	limiters   *ragllm.Limiters
+
+	experiments map[string]*experiment // by the pipeline whose queries they split
 }
 
 // Pipeline represents a configured RAG pipeline with all providers initialized.
@@ -46,9 +66,11 @@ type Pipeline struct {
 	description    string
 	config         config.Pipeline
 	dbPool         *database.Pool
+	transports     []*http.Transport // keep-alive pools of the provider clients
 	embeddingProv  Embedder
 	completionProv Completer
 	orchestrator   *Orchestrator
+	init           []InitStage // how long each stage of initialization took
 	logger         *slog.Logger
 }
 
@@ -56,6 +78,31 @@ type Pipeline struct {
 type ManagerConfig struct {
 	Config *config.Config
 	Logger *slog.Logger
+
+	// Metrics, when non-nil, receives per-stage latency, token, and
+	// error metrics from every pipeline. Pass the same registry across
+	// hot-reloads so counters survive a manager swap.
+	Metrics *metrics.Registry
+
+	// PayloadLog, when non-nil, receives every provider request and
+	// response, for debugging. Like Metrics, it outlives hot-reloads.
+	PayloadLog *ragllm.PayloadLog
+
+	// Costs, when non-nil, accumulates the estimated cost of every
+	// pipeline's queries and enforces their budgets. Like Metrics, pass
+	// the same ledger across hot-reloads so a reload does not reset
+	// spending.
+	Costs *CostLedger
+
+	// Limiters hold the provider_limits of the providers' requests.
+	// Like Metrics, pass the same set across hot-reloads so the old and
+	// new pipelines share the limits; nil gives the manager its own.
+	Limiters *ragllm.Limiters
+
+	// ReadOnly builds the pipelines without changing their databases:
+	// the feedback table is not created. Set it for the pipelines the
+	// -validate and -support-bundle modes only inspect.
+	ReadOnly bool
 }
 
 // NewManager creates a new pipeline manager from configuration.
@@ -76,7 +123,17 @@ func NewManagerWithLogger(cfg ManagerConfig) (*Manager, error) {
 	m := &Manager{
 		pipelines: make(map[string]*Pipeline),
 		config:    cfg.Config,
+		metrics:   cfg.Metrics,
+		costs:     cfg.Costs,
 		logger:    logger,
+		readOnly:  cfg.ReadOnly,
+
+		payloadLog:  cfg.PayloadLog,
+		limiters:    cfg.Limiters,
+		experiments: make(map[string]*experiment),
+	}
+	if m.limiters == nil {
+		m.limiters = ragllm.NewLimiters()
 	}
 
 	// Create pipelines from configuration
@@ -98,6 +155,9 @@ func NewManagerWithLogger(cfg ManagerConfig) (*Manager, error) {
 			"completion_provider", pCfg.RAGLLM.Provider,
 		)
 	}
+	for _, ec := range cfg.Config.Experiments {
+		m.experiments[ec.Pipeline] = newExperiment(ec)
+	}
 
 	return m, nil
 }
@@ -106,8 +166,14 @@ func NewManagerWithLogger(cfg ManagerConfig) (*Manager, error) {
 func (m *Manager) createPipeline(
 	ctx context.Context,
 	pCfg config.Pipeline,
-) (*Pipeline, error) {
+) (_ *Pipeline, err error) {
 	pipelineLogger := m.logger.With("pipeline", pCfg.Name)
+	progress := newInitProgress(pipelineLogger)
+	defer func() {
+		if err != nil {
+			err = progress.fail(err)
+		}
+	}()
 
 	// Load API keys for this pipeline (uses pipeline-specific config, cascaded from defaults/global)
 	keyLoader := config.NewAPIKeyLoader(pCfg.APIKeys)
@@ -116,49 +182,202 @@ func (m *Manager) createPipeline(
 		return nil, fmt.Errorf("failed to load API keys: %w", err)
 	}
 
+	// Check the filter presets' operators and values as request filters
+	// are checked, so a bad preset fails startup rather than its queries
+	if err := checkFilterPresets(pCfg.FilterPresets); err != nil {
+		return nil, err
+	}
+
+	// Load the hook script, so one that does not compile fails startup,
+	// or the reload, rather than every query
+	hooks, err := LoadHooks(pCfg.Hooks, pipelineLogger)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create database connection pool
+	progress.begin(InitStageDBConnect)
 	dbPool, err := database.NewPool(ctx, pCfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Check the tables and columns the pipeline searches, and then plan
+	// raw SQL filters, so a mistake fails startup, or the reload,
+	// rather than the first query.
+	progress.begin(InitStageSchemaCheck)
+	if err := dbPool.CheckSchema(ctx, pCfg.Tables); err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+	for _, ts := range pCfg.Tables {
+		if err := dbPool.CheckFilter(ctx, ts); err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("invalid filter for table %s: %w", ts.Table, err)
+		}
+	}
+
+	// A vector column without an ANN index works, but each search
+	// scans the whole table, which is slow once the table is large.
+	for _, ts := range pCfg.Tables {
+		indexed, err := dbPool.HasVectorIndex(ctx, ts)
+		switch {
+		case err != nil:
+			pipelineLogger.Warn("failed to check for a vector index", "table", ts.Table, "error", err)
+		case !indexed:
+			pipelineLogger.Warn("vector column has no ivfflat or hnsw index; searches will scan the whole table",
+				"table", ts.Table, "column", ts.VectorColumn)
+		}
+	}
+
+	// Create the feedback table now, so a database user that may not
+	// fails startup, or the reload, rather than the first rating. A
+	// read-only manager leaves the database as it is.
+	if pCfg.Feedback.Enabled && !m.readOnly {
+		if err := dbPool.CreateFeedbackTable(ctx, feedbackTable(pCfg.Feedback)); err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+	}
+
+	// Every provider client shares one keep-alive pool, so connections
+	// opened by earlier queries are reused rather than re-dialed.
+	transport := ragllm.NewPooledTransport(
+		pCfg.ProviderPool.MaxIdleConnsPerHost, pCfg.ProviderPool.IdleConnTimeout.Std())
+	transports := []*http.Transport{transport}
+	// Providers configured with a ca_file share a clone of the pool that
+	// also trusts its certificates, one per bundle.
+	caTransports := make(map[string]*http.Transport)
+	transportFor := func(caFile string) (ragllm.ClientOption, error) {
+		if caFile == "" {
+			return ragllm.WithTransport(transport), nil
+		}
+		t, ok := caTransports[caFile]
+		if !ok {
+			var err error
+			if t, err = ragllm.TransportWithCA(transport, caFile); err != nil {
+				return nil, err
+			}
+			caTransports[caFile] = t
+			transports = append(transports, t)
+		}
+		return ragllm.WithTransport(t), nil
+	}
+	connObserver := func(provider string) ragllm.ClientOption {
+		provider = strings.ToLower(provider)
+		return ragllm.WithConnObserver(func(reused bool) {
+			m.metrics.ObserveProviderConn(pCfg.Name, provider, reused)
+		})
+	}
+	retryObserver := func(provider string) ragllm.ClientOption {
+		provider = strings.ToLower(provider)
+		return ragllm.WithRetryObserver(func(e llmlib.RetryEvent) {
+			reason := ragllm.RetryReason(e)
+			m.metrics.IncProviderRetry(pCfg.Name, provider, reason)
+			pipelineLogger.Warn("retrying provider request",
+				"provider", provider, "reason", reason, "attempt", e.Attempt,
+				"status", e.StatusCode, "error", e.Err, "wait", e.Wait)
+		})
+	}
+	limiter := func(provider, baseURL, region string) ragllm.ClientOption {
+		limits, ok := m.config.ProviderLimit(provider)
+		if !ok {
+			return ragllm.WithLimiter(nil, nil)
+		}
+		provider = strings.ToLower(provider)
+		return ragllm.WithLimiter(m.limiters.Limiter(provider, baseURL, region, apiKeys, limits),
+			func(reason string) {
+				m.metrics.IncProviderRejected(pCfg.Name, provider, reason)
+				pipelineLogger.Warn("provider busy; request refused", "provider", provider, "reason", reason)
+			})
+	}
+
 	// Create embedding client
+	progress.begin(InitStageProviders)
 	embeddingHeaders := mergeHeaders(pCfg.LLMHeaders, pCfg.EmbeddingLLM.Headers)
+	embeddingTimeout, _ := pCfg.EmbeddingLLM.RequestTimeouts()
+	embeddingTransport, err := transportFor(pCfg.EmbeddingLLM.CAPath())
+	if err != nil {
+		dbPool.Close()
+		return nil, fmt.Errorf("failed to create embedding client: %w", err)
+	}
 	embeddingProv, err := ragllm.NewEmbeddingClient(
 		pCfg.EmbeddingLLM.Provider,
 		pCfg.EmbeddingLLM.Model,
 		pCfg.EmbeddingLLM.BaseURL,
 		embeddingHeaders,
 		apiKeys,
-		ragllm.WithRequestTimeout(pCfg.EmbeddingLLM.RequestTimeout.Std()),
+		ragllm.WithRequestTimeout(embeddingTimeout),
 		ragllm.WithPerAttemptTimeout(pCfg.EmbeddingLLM.PerAttemptTimeout.Std()),
+		ragllm.WithRetry(retryPolicy(pCfg.EmbeddingLLM.Retry)),
+		embeddingTransport,
+		connObserver(pCfg.EmbeddingLLM.Provider),
+		retryObserver(pCfg.EmbeddingLLM.Provider),
+		limiter(pCfg.EmbeddingLLM.Provider, pCfg.EmbeddingLLM.BaseURL, pCfg.EmbeddingLLM.Region),
+		ragllm.WithRegion(pCfg.EmbeddingLLM.Region),
+		ragllm.WithPayloadLog(m.payloadLog, pCfg.Name),
 	)
 	if err != nil {
 		dbPool.Close()
 		return nil, fmt.Errorf("failed to create embedding client: %w", err)
 	}
 
-	// Create completion client
-	completionHeaders := mergeHeaders(pCfg.LLMHeaders, pCfg.RAGLLM.Headers)
-	completionProv, err := ragllm.NewCompletionClient(
-		pCfg.RAGLLM.Provider,
-		pCfg.RAGLLM.Model,
-		pCfg.RAGLLM.BaseURL,
-		completionHeaders,
-		apiKeys,
-		ragllm.WithRequestTimeout(pCfg.RAGLLM.RequestTimeout.Std()),
-		ragllm.WithPerAttemptTimeout(pCfg.RAGLLM.PerAttemptTimeout.Std()),
-	)
+	dimensions := func(ctx context.Context) (int, error) {
+		return ragllm.EmbeddingDimensions(ctx, embeddingProv)
+	}
+	if err := checkEmbeddingDimensions(ctx, pCfg, dbPool, dimensions, pipelineLogger); err != nil {
+		dbPool.Close()
+		return nil, err
+	}
+
+	// Create completion client, and its fallbacks if any
+	newCompletionClient := func(llm config.LLMConfig) (llmlib.Client, error) {
+		_, timeout := llm.RequestTimeouts()
+		llmTransport, err := transportFor(llm.CAPath())
+		if err != nil {
+			return nil, err
+		}
+		return ragllm.NewCompletionClient(
+			llm.Provider,
+			llm.Model,
+			llm.BaseURL,
+			mergeHeaders(pCfg.LLMHeaders, llm.Headers),
+			apiKeys,
+			ragllm.WithRequestTimeout(timeout),
+			ragllm.WithPerAttemptTimeout(llm.PerAttemptTimeout.Std()),
+			ragllm.WithRetry(retryPolicy(llm.Retry)),
+			llmTransport,
+			connObserver(llm.Provider),
+			retryObserver(llm.Provider),
+			limiter(llm.Provider, llm.BaseURL, llm.Region),
+			ragllm.WithRegion(llm.Region),
+			ragllm.WithPayloadLog(m.payloadLog, pCfg.Name),
+		)
+	}
+	var completionProv Completer
+	completionProv, err = newCompletionClient(pCfg.RAGLLM)
 	if err != nil {
 		dbPool.Close()
 		return nil, fmt.Errorf("failed to create completion client: %w", err)
 	}
+	if len(pCfg.RAGLLMFallbacks) > 0 {
+		completionProv, err = newFailoverCompleter(pCfg, completionProv, newCompletionClient, pipelineLogger)
+		if err != nil {
+			dbPool.Close()
+			return nil, err
+		}
+	}
 
 	// Create rerank client (optional; disabled unless a provider is
 	// configured for this pipeline's rerank stage).
 	var reranker Reranker
 	if pCfg.Rerank.Provider != "" {
 		rerankHeaders := mergeHeaders(pCfg.LLMHeaders, pCfg.Rerank.Headers)
+		rerankTransport, err := transportFor(pCfg.Rerank.CAPath())
+		if err != nil {
+			dbPool.Close()
+			return nil, fmt.Errorf("failed to create rerank client: %w", err)
+		}
 		reranker, err = ragllm.NewRerankClient(
 			pCfg.Rerank.Provider,
 			pCfg.Rerank.Model,
@@ -167,12 +386,19 @@ func (m *Manager) createPipeline(
 			apiKeys,
 			ragllm.WithRequestTimeout(pCfg.Rerank.RequestTimeout.Std()),
 			ragllm.WithPerAttemptTimeout(pCfg.Rerank.PerAttemptTimeout.Std()),
+			ragllm.WithRetry(retryPolicy(pCfg.Rerank.Retry)),
+			rerankTransport,
+			connObserver(pCfg.Rerank.Provider),
+			retryObserver(pCfg.Rerank.Provider),
+			limiter(pCfg.Rerank.Provider, pCfg.Rerank.BaseURL, ""),
+			ragllm.WithPayloadLog(m.payloadLog, pCfg.Name),
 		)
 		if err != nil {
 			dbPool.Close()
 			return nil, fmt.Errorf("failed to create rerank client: %w", err)
 		}
 	}
+	progress.end()
 
 	// Determine token budget: pipeline > global defaults > hardcoded default
 	tokenBudget := DefaultTokenBudget
@@ -192,29 +418,112 @@ func (m *Manager) createPipeline(
 		topN = pCfg.TopN
 	}
 
+	// The orchestrator searches the tables with the tenant condition in
+	// their filters, so no search can return another tenant's rows.
+	oCfg := pCfg
+	oCfg.Tables = pCfg.TenantTables()
+
 	// Create orchestrator
 	orchestrator := NewOrchestrator(OrchestratorConfig{
-		Pipeline:       &pCfg,
+		Pipeline:       &oCfg,
 		DBPool:         dbPool,
+		Store:          dbPool,
+		Parents:        dbPool,
+		Feedback:       dbPool,
 		EmbeddingProv:  embeddingProv,
 		CompletionProv: completionProv,
 		Reranker:       reranker,
+		Authorizer:     NewAuthorizer(pCfg.AccessControl, dbPool),
+		Hooks:          hooks,
 		RerankTopK:     pCfg.Rerank.TopK,
 		TokenBudget:    tokenBudget,
 		TopN:           topN,
+		Metrics:        m.metrics,
+		Costs:          m.costs,
 		Logger:         pipelineLogger,
 	})
 
-	return &Pipeline{
+	p := &Pipeline{
 		name:           pCfg.Name,
 		description:    pCfg.Description,
 		config:         pCfg,
 		dbPool:         dbPool,
+		transports:     transports,
 		embeddingProv:  embeddingProv,
 		completionProv: completionProv,
 		orchestrator:   orchestrator,
 		logger:         pipelineLogger,
-	}, nil
+	}
+
+	// Check the providers respond, and load and index the documents
+	// BM25 ranks, so the first queries do not pay for either. Neither
+	// stops the pipeline starting: a provider may come up after it, and
+	// searches load the documents the warm-up could not.
+	if !m.readOnly {
+		progress.begin(InitStageProviderPing)
+		progress.note(p.Ping(ctx).unreachable())
+		if tables := orchestrator.warmUpTables(ctx); len(tables) > 0 {
+			progress.begin(InitStageWarmUp)
+			warmed, err := orchestrator.warmUp(ctx, tables)
+			progress.note(err)
+			progress.begin(InitStageBM25Index)
+			orchestrator.indexDocuments(warmed)
+		}
+		progress.end()
+	}
+	p.init = progress.stages
+
+	return p, nil
+}
+
+// dimensionLookupTimeout bounds the model metadata request made to
+// learn the embedding model's dimensions at startup.
+const dimensionLookupTimeout = 10 * time.Second
+
+// vectorColumns reports the declared dimensions of a table's vector
+// column. *database.Pool implements it.
+type vectorColumns interface {
+	VectorDimensions(ctx context.Context, table config.TableSource) (int, error)
+}
+
+// checkEmbeddingDimensions fails when one of a pipeline's vector columns
+// is declared with a different number of dimensions than the embedding
+// model produces, which would otherwise fail every query with a
+// pgvector error. Nothing is checked when the provider does not report
+// the model's dimensions, or cannot be reached; columns declared
+// without dimensions are not checked either.
+func checkEmbeddingDimensions(
+	ctx context.Context,
+	pCfg config.Pipeline,
+	db vectorColumns,
+	dimensions func(ctx context.Context) (int, error),
+	logger *slog.Logger,
+) error {
+	lookupCtx, cancel := context.WithTimeout(ctx, dimensionLookupTimeout)
+	want, err := dimensions(lookupCtx)
+	cancel()
+	if err != nil {
+		logger.Warn("could not look up the embedding model's dimensions; vector columns not checked",
+			"model", pCfg.EmbeddingLLM.Model, "error", err)
+		return nil
+	}
+	if want == 0 {
+		logger.Debug("embedding provider does not report the model's dimensions; vector columns not checked",
+			"model", pCfg.EmbeddingLLM.Model)
+		return nil
+	}
+
+	for _, ts := range pCfg.Tables {
+		got, err := db.VectorDimensions(ctx, ts)
+		if err != nil {
+			return fmt.Errorf("failed to check vector column: %w", err)
+		}
+		if got != 0 && got != want {
+			return fmt.Errorf("vector column %q of table %s has %d dimensions, but embedding model %s produces %d",
+				ts.VectorColumn, ts.Table, got, pCfg.EmbeddingLLM.Model, want)
+		}
+	}
+	return nil
 }
 
 // List returns information about all available pipelines.
@@ -264,6 +573,19 @@ func (m *Manager) GetExecutor(name string) (QueryExecutor, error) {
 	return p, nil
 }
 
+// Route returns the name of the pipeline that answers a query asked of
+// the named pipeline, and whether the pipeline runs an experiment: one
+// of the experiment's variants, chosen by its share of the traffic, or
+// the pipeline itself. Queries with the same non-empty key, such as a
+// session ID, are answered by the same pipeline.
+func (m *Manager) Route(name, key string) (string, bool) {
+	e, ok := m.experiments[name]
+	if !ok {
+		return name, false
+	}
+	return e.assign(key), true
+}
+
 // Stats returns cumulative token usage for every pipeline.
 func (m *Manager) Stats() []Usage {
 	m.mu.RLock()
@@ -303,6 +625,30 @@ func (m *Manager) Health(ctx context.Context) []PipelineHealth {
 	return results
 }
 
+// Ready checks every pipeline's database, and their providers when
+// providers is true, concurrently, like Health.
+func (m *Manager) Ready(ctx context.Context, providers bool) []PipelineReadiness {
+	m.mu.RLock()
+	pipelines := make([]*Pipeline, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		pipelines = append(pipelines, p)
+	}
+	m.mu.RUnlock()
+
+	results := make([]PipelineReadiness, len(pipelines))
+	var wg sync.WaitGroup
+	for i, p := range pipelines {
+		wg.Add(1)
+		go func(i int, p *Pipeline) {
+			defer wg.Done()
+			results[i] = p.Ready(ctx, providers)
+		}(i, p)
+	}
+	wg.Wait()
+
+	return results
+}
+
 // Execute runs a RAG query on the pipeline.
 func (p *Pipeline) Execute(ctx context.Context, query string) (*QueryResponse, error) {
 	return p.orchestrator.Execute(ctx, QueryRequest{
@@ -339,6 +685,109 @@ func (p *Pipeline) ExecuteStreamWithOptions(
 	return p.orchestrator.ExecuteStream(ctx, req)
 }
 
+// Explain explains how a document ranks for a query.
+func (p *Pipeline) Explain(ctx context.Context, req ExplainRequest) (*Explanation, error) {
+	return p.orchestrator.Explain(ctx, req)
+}
+
+// Retrieve returns the documents a query retrieves, without answering it.
+func (p *Pipeline) Retrieve(ctx context.Context, req RetrieveRequest) (*RetrieveResponse, error) {
+	return p.orchestrator.Retrieve(ctx, req)
+}
+
+// Search returns the documents a query retrieves as a query's sources,
+// without answering it.
+func (p *Pipeline) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	return p.orchestrator.Search(ctx, req)
+}
+
+// Embed embeds texts with the pipeline's embedding LLM.
+func (p *Pipeline) Embed(ctx context.Context, req EmbedRequest) (*EmbedResponse, error) {
+	return p.orchestrator.Embed(ctx, req)
+}
+
+// Estimate estimates the prompt size and cost of a query, without
+// answering it.
+func (p *Pipeline) Estimate(ctx context.Context, req QueryRequest) (*Estimate, error) {
+	return p.orchestrator.Estimate(ctx, req)
+}
+
+// Feedback records a rating of an answer.
+func (p *Pipeline) Feedback(ctx context.Context, req FeedbackRequest) error {
+	return p.orchestrator.Feedback(ctx, req)
+}
+
+// EvalDataset returns the queries the pipeline has captured.
+func (p *Pipeline) EvalDataset() []EvalCase {
+	return p.orchestrator.EvalDataset()
+}
+
+// Evaluate scores the pipeline's retrieval, and optionally its answers,
+// against an eval dataset.
+func (p *Pipeline) Evaluate(ctx context.Context, cases []EvalCase, opts EvalOptions) (*EvalReport, error) {
+	return p.orchestrator.Evaluate(ctx, cases, opts)
+}
+
+// Tune compares the pipeline's retrieval against a dataset under
+// different settings.
+func (p *Pipeline) Tune(
+	ctx context.Context,
+	cases []EvalCase,
+	variants []TuningVariant,
+	k int,
+	progress func(done, total int),
+) (*TuningReport, error) {
+	return p.orchestrator.Tune(ctx, cases, variants, k, progress)
+}
+
+// WithVariant returns a copy of the pipeline that retrieves with a
+// tuning variant's settings, for trying them on live queries. The copy
+// shares the pipeline's providers and connections but not its answer
+// cache, so its answers are neither cached nor served from the cache.
+func (p *Pipeline) WithVariant(v TuningVariant) (*Pipeline, error) {
+	if err := p.orchestrator.checkVariants([]TuningVariant{v}); err != nil {
+		return nil, err
+	}
+	orchestrator := p.orchestrator.withVariant(v, p.orchestrator.embeddingProv)
+	orchestrator.answerCache = nil
+	variant := *p
+	variant.orchestrator = orchestrator
+	return &variant, nil
+}
+
+// Ingest embeds and stores the chunks of an uploaded document.
+func (p *Pipeline) Ingest(
+	ctx context.Context,
+	doc *ingest.Document,
+	progress func(done, total int),
+) (int, error) {
+	return p.orchestrator.Ingest(ctx, doc, progress)
+}
+
+// Capabilities reports the pipeline's filter columns, the metadata
+// columns of its tables, and its upload and tenant settings.
+func (p *Pipeline) Capabilities() Capabilities {
+	var metadata []string
+	for _, table := range p.config.Tables {
+		for _, col := range table.MetadataColumns {
+			if !slices.Contains(metadata, col) {
+				metadata = append(metadata, col)
+			}
+		}
+	}
+	return Capabilities{
+		Name:            p.name,
+		Description:     p.description,
+		FilterColumns:   p.config.FilterColumns,
+		FilterPresets:   sortedPresets(p.config.FilterPresets),
+		MetadataColumns: metadata,
+		Ingest:          p.config.Ingest.Enabled,
+		MaxUploadBytes:  p.config.Ingest.MaxUploadBytes,
+		Tenant:          p.config.Tenant,
+		RateLimit:       p.config.RateLimit,
+	}
+}
+
 // Name returns the pipeline name.
 func (p *Pipeline) Name() string {
 	return p.name
@@ -349,14 +798,22 @@ func (p *Pipeline) Description() string {
 	return p.description
 }
 
+// SystemPrompt returns the system prompt the pipeline sends with every
+// query, before the answer length guidance and retrieved context.
+func (p *Pipeline) SystemPrompt() string {
+	return p.orchestrator.buildSystemPrompt()
+}
+
 // Usage returns this pipeline's cumulative embedding and completion
-// token usage.
+// token usage and, when its models are priced, what its queries have
+// cost.
 func (p *Pipeline) Usage() Usage {
 	return Usage{
 		Name:        p.name,
 		Description: p.description,
 		Embedding:   p.embeddingProv.Usage(),
 		Completion:  p.completionProv.Usage(),
+		Cost:        p.orchestrator.cost(),
 	}
 }
 
@@ -387,7 +844,65 @@ func (p *Pipeline) Ping(ctx context.Context) PipelineHealth {
 		Name:       p.name,
 		Embedding:  embedding,
 		Completion: completion,
+		Init:       p.init,
+	}
+}
+
+// unreachable returns an error naming each provider in h that did not
+// respond, or nil if both did.
+func (h PipelineHealth) unreachable() error {
+	var errs []error
+	if !h.Embedding.Reachable {
+		errs = append(errs, fmt.Errorf("embedding provider unreachable: %s", h.Embedding.Error))
+	}
+	if !h.Completion.Reachable {
+		errs = append(errs, fmt.Errorf("completion provider unreachable: %s", h.Completion.Error))
+	}
+	return errors.Join(errs...)
+}
+
+// Ready checks whether this pipeline can serve queries by pinging its
+// database and, when providers is true, its embedding and completion
+// providers, all concurrently and each bounded by DefaultPingTimeout.
+// Pinging the database only takes a connection from the pool and
+// round-trips to the server, so it is cheap enough for a frequently
+// polled readiness probe; pinging some providers makes a real request.
+func (p *Pipeline) Ready(ctx context.Context, providers bool) PipelineReadiness {
+	var database, embedding, completion ProviderHealth
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		database = pingProvider(ctx, p.pingDatabase)
+	}()
+	if providers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			embedding = pingProvider(ctx, p.embeddingProv.Ping)
+		}()
+		go func() {
+			defer wg.Done()
+			completion = pingProvider(ctx, p.completionProv.Ping)
+		}()
+	}
+	wg.Wait()
+
+	r := PipelineReadiness{Name: p.name, Ready: database.Reachable, Database: database}
+	if providers {
+		r.Embedding, r.Completion = &embedding, &completion
+		r.Ready = r.Ready && embedding.Reachable && completion.Reachable
+	}
+	return r
+}
+
+// pingDatabase pings the pipeline's database.
+func (p *Pipeline) pingDatabase(ctx context.Context) error {
+	if p.dbPool == nil {
+		return errors.New("no database connection pool")
 	}
+	return p.dbPool.Ping(ctx)
 }
 
 // pingProvider runs ping with a DefaultPingTimeout deadline and
@@ -419,6 +934,59 @@ func (p *Pipeline) Close() {
 	if p.dbPool != nil {
 		p.dbPool.Close()
 	}
+	for _, t := range p.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// newFailoverCompleter wraps the pipeline's primary completion client
+// and clients for its rag_llm_fallbacks, each behind its own circuit
+// breaker.
+func newFailoverCompleter(
+	pCfg config.Pipeline,
+	primary Completer,
+	newClient func(config.LLMConfig) (llmlib.Client, error),
+	logger *slog.Logger,
+) (*failoverCompleter, error) {
+	cb := pCfg.CircuitBreaker
+	f := &failoverCompleter{logger: logger}
+	add := func(llm config.LLMConfig, client Completer) {
+		f.targets = append(f.targets, completionTarget{
+			name:     strings.ToLower(llm.Provider) + "/" + llm.Model,
+			answerer: Answerer{Provider: llm.Provider, Model: llm.Model},
+			client:   client,
+			breaker:  newCircuitBreaker(cb.FailureThreshold, cb.Cooldown.Std()),
+		})
+	}
+
+	add(pCfg.RAGLLM, primary)
+	for i, fb := range pCfg.RAGLLMFallbacks {
+		client, err := newClient(fb)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback completion client %d: %w", i, err)
+		}
+		add(fb, client)
+	}
+	return f, nil
+}
+
+// retryPolicy converts a provider's retry settings to a retry policy,
+// using the defaults for any left unset.
+func retryPolicy(r config.RetryConfig) ragllm.RetryPolicy {
+	policy := ragllm.DefaultRetryPolicy()
+	if r.MaxRetries != nil {
+		policy.MaxRetries = *r.MaxRetries
+	}
+	if r.InitialBackoff > 0 {
+		policy.InitialBackoff = r.InitialBackoff.Std()
+	}
+	if r.MaxBackoff > 0 {
+		policy.MaxBackoff = r.MaxBackoff.Std()
+	}
+	if r.Jitter != nil {
+		policy.Jitter = *r.Jitter
+	}
+	return policy
 }
 
 // mergeHeaders merges pipeline-level and per-LLM headers.
@@ -13,6 +13,7 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -20,8 +21,8 @@ import (
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
 
-	"github.com/pgEdge/pgedge-rag-server/internal/bm25"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
 )
 
 // newTestManager creates a Manager with mock pipelines for testing.
@@ -91,7 +92,6 @@ func newTestPipeline(name, description string) *Pipeline {
 		cfg:            &pCfg,
 		embeddingProv:  embeddingProv,
 		completionProv: completionProv,
-		bm25Index:      bm25.NewIndex(),
 		tokenBudget:    DefaultTokenBudget,
 		topN:           DefaultTopN,
 		logger:         slog.Default(),
@@ -388,6 +388,36 @@ func TestPipeline_Ping_RecoversFromProviderPanic(t *testing.T) {
 	}
 }
 
+func TestPipeline_Ready(t *testing.T) {
+	pinged := false
+	p := &Pipeline{
+		name: "test-pipeline",
+		embeddingProv: &MockEmbedder{
+			PingFunc: func(ctx context.Context) error {
+				pinged = true
+				return nil
+			},
+		},
+		completionProv: &MockCompleter{
+			PingFunc: func(ctx context.Context) error { return errors.New("connection refused") },
+		},
+	}
+
+	result := p.Ready(context.Background(), false)
+	if result.Ready || result.Database.Reachable || result.Database.Error == "" {
+		t.Errorf("expected a pipeline without a database not to be ready, got %+v", result)
+	}
+	if pinged || result.Embedding != nil || result.Completion != nil {
+		t.Errorf("expected the providers not to be checked, got %+v", result)
+	}
+
+	result = p.Ready(context.Background(), true)
+	if !pinged || result.Embedding == nil || !result.Embedding.Reachable ||
+		result.Completion == nil || result.Completion.Error != "connection refused" {
+		t.Errorf("expected the providers checked, got %+v", result)
+	}
+}
+
 func TestManager_Get(t *testing.T) {
 	cfg := testConfig()
 	m := newTestManager(cfg)
@@ -462,7 +492,6 @@ func TestPipeline_ExecuteStream_NoDocuments(t *testing.T) {
 		cfg:            &pCfg,
 		embeddingProv:  embeddingProv,
 		completionProv: completionProv,
-		bm25Index:      bm25.NewIndex(),
 		tokenBudget:    DefaultTokenBudget,
 		topN:           DefaultTopN,
 		logger:         slog.Default(),
@@ -515,3 +544,154 @@ func TestManager_Close(t *testing.T) {
 		t.Error("expected pipelines to be nil after close")
 	}
 }
+
+func TestRetryPolicy(t *testing.T) {
+	if got := retryPolicy(config.RetryConfig{}); got != ragllm.DefaultRetryPolicy() {
+		t.Errorf("expected the default policy when unset, got %+v", got)
+	}
+
+	retries, jitter := 0, 0.0
+	got := retryPolicy(config.RetryConfig{
+		MaxRetries:     &retries,
+		InitialBackoff: config.Duration(500 * time.Millisecond),
+		MaxBackoff:     config.Duration(5 * time.Second),
+		Jitter:         &jitter,
+	})
+	want := ragllm.RetryPolicy{
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+	if got != want {
+		t.Errorf("retryPolicy = %+v, want %+v", got, want)
+	}
+}
+
+// stubVectorColumns reports fixed vector column dimensions by table.
+type stubVectorColumns map[string]int
+
+func (s stubVectorColumns) VectorDimensions(ctx context.Context, table config.TableSource) (int, error) {
+	dims, ok := s[table.Table]
+	if !ok {
+		return 0, errors.New("relation does not exist")
+	}
+	return dims, nil
+}
+
+func TestInitProgress(t *testing.T) {
+	var logs strings.Builder
+	progress := newInitProgress(slog.New(slog.NewTextHandler(&logs, nil)))
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	progress.now = func() time.Time { return now }
+
+	progress.begin(InitStageDBConnect)
+	now = now.Add(1500 * time.Millisecond)
+	progress.begin(InitStageSchemaCheck)
+	now = now.Add(20 * time.Millisecond)
+	progress.end()
+	progress.end()
+
+	want := []InitStage{{Name: InitStageDBConnect, DurationMS: 1500}, {Name: InitStageSchemaCheck, DurationMS: 20}}
+	if !slices.Equal(progress.stages, want) {
+		t.Errorf("stages = %+v, want %+v", progress.stages, want)
+	}
+	if !strings.Contains(logs.String(), "stage=db_connect duration=1.5s") {
+		t.Errorf("expected each stage to be logged with its duration, got %q", logs.String())
+	}
+
+	// A noted error is recorded with its stage, which still completes.
+	progress.begin(InitStageProviderPing)
+	now = now.Add(3 * time.Second)
+	progress.note(nil)
+	progress.note(errors.New("embedding provider unreachable"))
+	progress.begin(InitStageProviders)
+	if got := progress.stages[2]; got != (InitStage{Name: InitStageProviderPing, DurationMS: 3000,
+		Error: "embedding provider unreachable"}) {
+		t.Errorf("expected the noted error to be recorded with its stage, got %+v", got)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="pipeline initialization stage completed with an error" stage=provider_ping`) {
+		t.Errorf("expected the stage to be logged as completed with an error, got %q", logs.String())
+	}
+
+	// The stage that fails is recorded with its error, and returned
+	// with the stages before it.
+	now = now.Add(10 * time.Second)
+	err := progress.fail(errors.New("connection refused"))
+	var initErr *InitError
+	if !errors.As(err, &initErr) || err.Error() != "connection refused" {
+		t.Fatalf("expected an *InitError, got %v", err)
+	}
+	if len(initErr.Stages) != 4 || initErr.Stages[3] != (InitStage{Name: InitStageProviders,
+		DurationMS: 10000, Error: "connection refused"}) {
+		t.Errorf("expected the failed stage to be recorded, got %+v", initErr.Stages)
+	}
+	if !strings.Contains(logs.String(), `stage=providers duration=10s error="connection refused"`) {
+		t.Errorf("expected the failed stage to be logged, got %q", logs.String())
+	}
+
+	p := &Pipeline{name: "docs", embeddingProv: &MockEmbedder{}, completionProv: &MockCompleter{}, init: want}
+	if got := p.Ping(context.Background()).Init; !slices.Equal(got, want) {
+		t.Errorf("expected Ping to report the stages, got %+v", got)
+	}
+}
+
+func TestCheckEmbeddingDimensions(t *testing.T) {
+	pCfg := config.Pipeline{
+		Name:         "docs",
+		EmbeddingLLM: config.LLMConfig{Provider: "openai", Model: "text-embedding-3-small"},
+		Tables: []config.TableSource{
+			{Table: "articles", VectorColumn: "embedding"},
+			{Table: "faq", VectorColumn: "embedding"},
+		},
+	}
+	dims := func(n int, err error) func(context.Context) (int, error) {
+		return func(context.Context) (int, error) { return n, err }
+	}
+	logger := slog.New(slog.DiscardHandler)
+	ctx := context.Background()
+
+	if err := checkEmbeddingDimensions(ctx, pCfg, stubVectorColumns{"articles": 1536, "faq": 0}, dims(1536, nil), logger); err != nil {
+		t.Errorf("expected matching and undeclared columns to pass, got %v", err)
+	}
+
+	err := checkEmbeddingDimensions(ctx, pCfg, stubVectorColumns{"articles": 1536, "faq": 768}, dims(1536, nil), logger)
+	if err == nil || !strings.Contains(err.Error(), `vector column "embedding" of table faq has 768 dimensions`) ||
+		!strings.Contains(err.Error(), "text-embedding-3-small produces 1536") {
+		t.Errorf("expected a dimension mismatch error, got %v", err)
+	}
+
+	if err := checkEmbeddingDimensions(ctx, pCfg, stubVectorColumns{"articles": 1536}, dims(1536, nil), logger); err == nil {
+		t.Error("expected an error for a table whose column cannot be looked up")
+	}
+
+	// Without the model's dimensions there is nothing to compare.
+	for _, lookup := range []func(context.Context) (int, error){dims(0, nil), dims(0, errors.New("unreachable"))} {
+		if err := checkEmbeddingDimensions(ctx, pCfg, stubVectorColumns{}, lookup, logger); err != nil {
+			t.Errorf("expected no check without the model's dimensions, got %v", err)
+		}
+	}
+}
+
+func TestPipeline_Capabilities(t *testing.T) {
+	p := &Pipeline{
+		name:        "docs",
+		description: "Product documentation",
+		config: config.Pipeline{
+			FilterColumns: []string{"product"},
+			Tables: []config.TableSource{
+				{Table: "guides", MetadataColumns: []string{"title", "url"}},
+				{Table: "faqs", MetadataColumns: []string{"url", "section"}},
+			},
+		},
+	}
+
+	got := p.Capabilities()
+	if got.Name != "docs" || got.Description != "Product documentation" {
+		t.Errorf("unexpected name and description: %+v", got)
+	}
+	if !slices.Equal(got.FilterColumns, []string{"product"}) {
+		t.Errorf("FilterColumns = %v, want [product]", got.FilterColumns)
+	}
+	if want := []string{"title", "url", "section"}; !slices.Equal(got.MetadataColumns, want) {
+		t.Errorf("MetadataColumns = %v, want %v", got.MetadataColumns, want)
+	}
+}
@@ -0,0 +1,146 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// DefaultNumQueries is the number of paraphrases the multi_query
+// retrieval strategy searches when retrieval.num_queries is unset.
+const DefaultNumQueries = 3
+
+// expansionTokensPerQuery bounds the completion tokens allowed for each
+// paraphrase; a search query rarely needs more than a sentence.
+const expansionTokensPerQuery = 64
+
+// expansionPrompt asks the completion LLM for paraphrases of a query.
+const expansionPrompt = `You help a search engine find documents that answer a user's question.
+Write %d different versions of the question below, using other words, synonyms and phrasings a document might use, while keeping its meaning.
+Reply with one version per line, with no numbering, quotes or other text.`
+
+// multiQuerySearch searches the query and its paraphrases, and fuses
+// the results with reciprocal rank fusion, so documents that several
+// phrasings find rank above those only one finds. The variants are
+// searched one after another, since they share the BM25 index.
+//
+// A paraphrase that cannot be embedded or searched is skipped: the
+// query itself was searched, so the failure only narrows recall. A
+// timeout still fails the query.
+func (o *Orchestrator) multiQuerySearch(
+	ctx context.Context,
+	req QueryRequest,
+	embedding []float32,
+	topN int,
+	usage *StageUsage,
+) ([]database.SearchResult, error) {
+	results, err := o.searchWithTimeout(ctx, req, embedding, topN)
+	if err != nil {
+		return nil, err
+	}
+	lists := [][]database.SearchResult{results}
+
+	for _, paraphrase := range o.expandQuery(ctx, req.Query, usage) {
+		variantUsage := &StageUsage{}
+		variantEmbedding, err := o.embedWithTimeout(ctx, paraphrase, variantUsage)
+		usage.Embedding.Add(variantUsage.Embedding)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, fmt.Errorf("failed to generate embedding: %w", err)
+			}
+			o.logger.Warn("embedding a query paraphrase failed, skipping it", "error", err)
+			continue
+		}
+
+		variant := req
+		variant.Query = paraphrase
+		results, err := o.searchWithTimeout(ctx, variant, variantEmbedding, topN)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			o.logger.Warn("searching a query paraphrase failed, skipping it", "error", err)
+			continue
+		}
+		lists = append(lists, results)
+	}
+
+	return database.FuseRankings(lists, database.DefaultRRFConstant, topN), nil
+}
+
+// expandQuery asks the completion provider for paraphrases of query,
+// bounded by the pipeline's completion_timeout, and records the tokens
+// it used. A failure only loses the paraphrases, so it is logged and
+// no paraphrases are returned.
+func (o *Orchestrator) expandQuery(ctx context.Context, query string, usage *StageUsage) []string {
+	n := o.cfg.Retrieval.NumQueries
+	if n <= 0 {
+		n = DefaultNumQueries
+	}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageCompletion, time.Duration(o.cfg.CompletionTimeout))
+	defer cancel()
+
+	start := time.Now()
+	resp, err := o.completionProv.Chat(ctx, llmlib.ChatRequest{
+		SystemPrompt: fmt.Sprintf(expansionPrompt, n),
+		Messages:     []llmlib.Message{llmlib.UserText(query)},
+		MaxTokens:    llmlib.Int(n * expansionTokensPerQuery),
+	})
+	o.observeStage(metrics.StageQueryExpansion, o.completionProvider(), start, err)
+	if err != nil {
+		o.logger.Warn("query expansion failed, searching the query alone",
+			"error", stageTimeout(ctx, err))
+		return nil
+	}
+	usage.QueryExpansion = &resp.Usage
+	o.recordUsage(metrics.StageQueryExpansion, o.completionProvider(), resp.Usage)
+
+	paraphrases := parseParaphrases(joinTextBlocks(resp.Content), query, n)
+	o.logger.Debug("expanded query", "paraphrases", len(paraphrases))
+	return paraphrases
+}
+
+// listMarkerRe matches a list number or bullet at the start of a line.
+var listMarkerRe = regexp.MustCompile(`^(?:\d+[.)]|[-*•])\s+`)
+
+// parseParaphrases extracts up to n paraphrases from the LLM's reply,
+// one per line. Numbering, bullets and quotes the model added despite
+// the prompt are stripped, and lines repeating the query or an earlier
+// paraphrase are dropped.
+func parseParaphrases(reply, query string, n int) []string {
+	seen := map[string]bool{strings.ToLower(strings.TrimSpace(query)): true}
+	var paraphrases []string
+	for line := range strings.Lines(reply) {
+		line = listMarkerRe.ReplaceAllString(strings.TrimSpace(line), "")
+		line = strings.TrimSpace(strings.Trim(line, "\"'“”"))
+
+		key := strings.ToLower(line)
+		if line == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		paraphrases = append(paraphrases, line)
+		if len(paraphrases) == n {
+			break
+		}
+	}
+	return paraphrases
+}
@@ -0,0 +1,151 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func TestParseParaphrases(t *testing.T) {
+	reply := "1. How is streaming replication set up?\n" +
+		"\n" +
+		"- \"Configuring a standby server\"\n" +
+		"How do I configure streaming replication?\n" +
+		"* configuring a standby server\n" +
+		"2) 10 steps to replicate WAL\n" +
+		"Fourth paraphrase\n"
+
+	got := parseParaphrases(reply, "How do I configure streaming replication?", 3)
+	want := []string{
+		"How is streaming replication set up?",
+		"Configuring a standby server",
+		"10 steps to replicate WAL",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseParaphrases() = %q, want %q", got, want)
+	}
+}
+
+// newMultiQueryOrchestrator returns an orchestrator whose embedder
+// encodes each query's length, and whose vector search returns the
+// documents registered for that length, so each variant finds its own.
+func newMultiQueryOrchestrator(
+	completer *MockCompleter,
+	found map[string][]string,
+	numQueries int,
+	opts ...testOrchestratorOption,
+) *Orchestrator {
+	byLength := make(map[float32][]string)
+	for query, ids := range found {
+		byLength[float32(len(query))] = ids
+	}
+	multiQuery := func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Retrieval = config.RetrievalConfig{Strategy: config.RetrievalStrategyMultiQuery, NumQueries: numQueries}
+		cfg.DBPool = &MockSearchBackend{
+			VectorSearchFunc: func(
+				ctx context.Context, embedding []float32, table config.TableSource,
+				topN int, filter *config.Filter, minSimilarity *float64,
+			) ([]database.SearchResult, error) {
+				var results []database.SearchResult
+				for _, id := range byLength[embedding[0]] {
+					results = append(results, database.SearchResult{ID: id, Content: "content of " + id})
+				}
+				return results, nil
+			},
+		}
+		cfg.EmbeddingProv = &MockEmbedder{
+			EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+				return []float64{float64(len(text))}, nil
+			},
+		}
+		cfg.CompletionProv = completer
+	}
+	return newTestOrchestrator(nil, append([]testOrchestratorOption{multiQuery}, opts...)...)
+}
+
+func TestOrchestrator_MultiQuery(t *testing.T) {
+	var expansionReq llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if len(req.Messages) == 1 && strings.Contains(req.SystemPrompt, "different versions") {
+				expansionReq = req
+				return &llmlib.ChatResponse{
+					Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "set up a standby\nreplicate WAL"}},
+					Usage:   llmlib.TokenUsage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50},
+				}, nil
+			}
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newMultiQueryOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a", "doc-b"},
+		"set up a standby":                {"doc-c", "doc-b"},
+		"replicate WAL":                   {"doc-d"},
+	}, 2)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query:          "configure streaming replication",
+		IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(expansionReq.SystemPrompt, "Write 2 different versions") ||
+		expansionReq.MaxTokens == nil || *expansionReq.MaxTokens != 2*expansionTokensPerQuery {
+		t.Errorf("unexpected expansion request: %+v", expansionReq)
+	}
+
+	var ids []string
+	for _, s := range resp.Sources {
+		ids = append(ids, s.ID)
+	}
+	// doc-b is found by two variants, so it outranks every document
+	// only one variant found; ties keep the order they were found in.
+	if want := []string{"doc-b", "doc-a", "doc-c", "doc-d"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sources = %v, want %v", ids, want)
+	}
+	if resp.Usage.QueryExpansion == nil || resp.Usage.QueryExpansion.TotalTokens != 50 {
+		t.Errorf("expected query expansion usage, got %+v", resp.Usage.QueryExpansion)
+	}
+}
+
+func TestOrchestrator_MultiQuery_ExpansionFails(t *testing.T) {
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			if strings.Contains(req.SystemPrompt, "different versions") {
+				return nil, errors.New("provider unavailable")
+			}
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+		},
+	}
+	orch := newMultiQueryOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a", "doc-b"},
+	}, 0)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query:          "configure streaming replication",
+		IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("expected the query to be searched alone, got error: %v", err)
+	}
+	if len(resp.Sources) != 2 || resp.Sources[0].ID != "doc-a" || resp.Usage.QueryExpansion != nil {
+		t.Errorf("unexpected response: sources %+v, usage %+v", resp.Sources, resp.Usage)
+	}
+}
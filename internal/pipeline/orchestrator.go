@@ -16,26 +16,48 @@ import (
 	"io"
 	"log/slog"
 	"strings"
+	"time"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/pgEdge/pgedge-rag-server/internal/bm25"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
 	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
 	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+	"github.com/pgEdge/pgedge-rag-server/internal/tokens"
 )
 
+// maxConcurrentTableSearches bounds how many of a pipeline's tables are
+// searched at once, so a query against many tables does not hold as
+// many pooled database connections.
+const maxConcurrentTableSearches = 4
+
 // Orchestrator coordinates the RAG pipeline execution.
 type Orchestrator struct {
 	cfg            *config.Pipeline
 	dbPool         SearchBackend
+	store          ChunkStore
+	parents        ParentStore
 	embeddingProv  Embedder
 	completionProv Completer
 	reranker       Reranker
+	authorizer     Authorizer
+	guardrails     *guardrails
+	hooks          *Hooks
+	answerCache    *answerCache
+	documentCache  *documentCache
+	evalCapture    *evalCapture
+	responses      *responseLog
+	feedbackStore  FeedbackStore
+	tokenCounter   tokens.Counter
 	rerankTopK     int
-	bm25Index      *bm25.Index
 	tokenBudget    int
 	topN           int
+	metrics        *metrics.Registry
+	costs          *CostLedger
 	logger         *slog.Logger
 }
 
@@ -43,12 +65,19 @@ type Orchestrator struct {
 type OrchestratorConfig struct {
 	Pipeline       *config.Pipeline
 	DBPool         SearchBackend
+	Store          ChunkStore    // Optional; nil disables document ingestion
+	Parents        ParentStore   // Optional; nil disables parent expansion
+	Feedback       FeedbackStore // Optional; nil disables feedback on answers
 	EmbeddingProv  Embedder
 	CompletionProv Completer
-	Reranker       Reranker // Optional; nil disables the rerank stage
+	Reranker       Reranker   // Optional; nil disables the rerank stage
+	Authorizer     Authorizer // Optional; nil disables access control
+	Hooks          *Hooks     // Optional; nil disables script hooks
 	RerankTopK     int
 	TokenBudget    int
 	TopN           int
+	Metrics        *metrics.Registry // Optional; nil disables metrics
+	Costs          *CostLedger       // Optional; nil disables cost tracking and budgets
 	Logger         *slog.Logger
 }
 
@@ -59,69 +88,212 @@ func NewOrchestrator(cfg OrchestratorConfig) *Orchestrator {
 		logger = slog.Default()
 	}
 
+	var guard *guardrails
+	var cache *answerCache
+	var docCache *documentCache
+	var capture *evalCapture
+	var responses *responseLog
+	var counter tokens.Counter
+	if cfg.Pipeline != nil {
+		guard = newGuardrails(cfg.Pipeline.Guardrails)
+		cache = newAnswerCache(cfg.Pipeline.AnswerCache)
+		docCache = newDocumentCache(cfg.Pipeline.BM25.Cache)
+		capture = newEvalCapture(cfg.Pipeline.EvalCapture)
+		responses = newResponseLog(cfg.Pipeline.Feedback)
+		counter = tokenCounter(cfg.Pipeline)
+	}
+
 	return &Orchestrator{
 		cfg:            cfg.Pipeline,
 		dbPool:         cfg.DBPool,
+		store:          cfg.Store,
+		parents:        cfg.Parents,
 		embeddingProv:  cfg.EmbeddingProv,
 		completionProv: cfg.CompletionProv,
 		reranker:       cfg.Reranker,
+		authorizer:     cfg.Authorizer,
+		guardrails:     guard,
+		hooks:          cfg.Hooks,
+		answerCache:    cache,
+		documentCache:  docCache,
+		evalCapture:    capture,
+		responses:      responses,
+		feedbackStore:  cfg.Feedback,
+		tokenCounter:   counter,
 		rerankTopK:     cfg.RerankTopK,
-		bm25Index:      bm25.NewIndex(),
 		tokenBudget:    cfg.TokenBudget,
 		topN:           cfg.TopN,
+		metrics:        cfg.Metrics,
+		costs:          cfg.Costs,
 		logger:         logger,
 	}
 }
 
+// newBM25Index creates the lexical index with the pipeline's BM25
+// parameters, using the standard values for any it leaves unset.
+func newBM25Index(p *config.Pipeline) *bm25.Index {
+	k1, b := bm25.DefaultK1, bm25.DefaultB
+	if p != nil && p.BM25.K1 != nil {
+		k1 = *p.BM25.K1
+	}
+	if p != nil && p.BM25.B != nil {
+		b = *p.BM25.B
+	}
+	idx := bm25.NewIndexWithParams(k1, b)
+	if p != nil && p.BM25.ProximityWeight != nil {
+		idx.SetProximityWeight(*p.BM25.ProximityWeight)
+	}
+	if p != nil && p.BM25.Fuzzy {
+		similarity := bm25.DefaultFuzzySimilarity
+		if p.BM25.FuzzySimilarity != nil {
+			similarity = *p.BM25.FuzzySimilarity
+		}
+		idx.SetFuzzySimilarity(similarity)
+	}
+	return idx
+}
+
 // Execute runs the full RAG pipeline for a query.
 func (o *Orchestrator) Execute(ctx context.Context, req QueryRequest) (*QueryResponse, error) {
 	o.logger.Debug("executing RAG pipeline", "stream", req.Stream, "query_len", len(req.Query))
+	timer := newQueryTimer()
+
+	if err := o.validateRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	filter, err := o.applyFilterPreset(req.FilterPreset, req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	req.Filter = filter
+
+	cacheKey := o.answerCacheKey(ctx, req)
+	if cached, ok := o.lookupAnswer(cacheKey); ok {
+		o.recordCached(ctx)
+		resp := cached.response(req, timer)
+		resp.ResponseID = o.recordResponse(req, resp.Provenance)
+		return resp, nil
+	}
+	req.Seed = o.seed(ctx, req)
 
 	topN := o.topN
 	if req.TopN > 0 {
 		topN = req.TopN
 	}
 
-	embedding, err := ragllm.Embed32(ctx, o.embeddingProv, req.Query)
+	usage := &StageUsage{}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+	defer cancel()
+
+	searchReq := req
+	searchReq.Query = o.rewriteQuery(ctx, req.Query)
+
+	stageStart := time.Now()
+	embedding, err := o.embedWithTimeout(ctx, searchReq.Query, usage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding: %w", err)
 	}
+	timer.timings.EmbedMS = since(stageStart)
 
-	results, err := o.search(ctx, req, embedding, topN)
+	stageStart = time.Now()
+	results, err := o.retrieve(ctx, searchReq, embedding, topN, usage)
 	if err != nil {
 		return nil, err
 	}
+	timer.timings.SearchMS = since(stageStart)
+	results = o.filterResults(ctx, req.Query, results)
 
 	if len(results) == 0 {
+		o.chargeCost(usage)
 		return &QueryResponse{
 			Answer:     "No relevant information found in the available documents.",
 			TokensUsed: 0,
+			Usage:      usage,
+			Timings:    timer.result(req),
+			ResponseID: o.recordResponse(req, nil),
 		}, nil
 	}
 
-	results = o.rerank(ctx, req.Query, results)
+	results = o.mergeChunks(o.rerank(ctx, req.Query, results, usage))
 
-	contextDocs := o.buildContext(results)
+	fitted, err := o.fitBudget(ctx, req.Query, results, usage)
+	if err != nil {
+		return nil, err
+	}
+	contextDocs, contextResults := o.contextFor(results, fitted)
 
+	req = o.fitHistory(ctx, req, usage)
 	chatReq := o.buildChatRequest(req, contextDocs)
-
-	resp, err := o.completionProv.Chat(ctx, chatReq)
+	docProvenance := provenance(contextResults, contextDocs)
+	o.recordReproduction(ctx, req, contextResults, docProvenance, chatReq)
+
+	completionCtx, cancelCompletion := withStageTimeout(ctx, TimeoutStageCompletion,
+		time.Duration(o.cfg.CompletionTimeout))
+	defer cancelCompletion()
+	answeredBy := o.answerer()
+	completionCtx = withAnswerer(completionCtx, answeredBy)
+
+	start := time.Now()
+	var resp *llmlib.ChatResponse
+	switch {
+	case req.ResponseFormat != nil:
+		resp, err = o.chatStructured(o.withSeed(o.withLogitBias(completionCtx, req), req), req, chatReq)
+	case o.toolsEnabled():
+		run := newToolRun(req, topN, usage, results, contextResults, contextDocs)
+		resp, err = o.chatWithTools(o.withSeed(o.withLogitBias(completionCtx, req), req), run, chatReq)
+		results, contextDocs = run.results, run.docs
+		contextResults = run.contextResults()
+		docProvenance = provenance(contextResults, contextDocs)
+	default:
+		resp, err = o.completionProv.Chat(o.withSeed(o.withLogitBias(completionCtx, req), req), chatReq)
+	}
+	o.observeStage(metrics.StageCompletion, o.completionProvider(), start, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate completion: %w", err)
+		return nil, fmt.Errorf("failed to generate completion: %w", stageTimeout(completionCtx, err))
 	}
+	usage.Completion = resp.Usage
+	o.recordUsage(metrics.StageCompletion, o.completionProvider(), resp.Usage)
 
-	answer := joinTextBlocks(resp.Content)
+	answer := o.transformAnswer(ctx, req.Query, joinTextBlocks(resp.Content))
+	answer, guarded := o.guard(ctx, req.Query, answer, contextDocs, usage)
+	o.chargeCost(usage)
 
 	out := &QueryResponse{
-		Answer:     answer,
-		TokensUsed: resp.Usage.TotalTokens,
+		Answer:         answer,
+		TokensUsed:     resp.Usage.TotalTokens,
+		Usage:          usage,
+		FormatWarnings: o.formatWarnings(answer),
+		Citations:      o.citations(answer, contextResults, len(contextDocs)),
+		Guardrails:     guarded,
+		Timings:        timer.result(req),
+		QueryID:        o.captureQuery(req, contextResults),
+		ResponseID:     o.recordResponse(req, docProvenance),
+		Provenance:     docProvenance,
+		AnsweredBy:     *answeredBy,
 	}
 	if req.IncludeSources {
 		out.Sources = o.buildSources(results)
 	}
+	o.answerCache.put(cacheKey, cachedAnswer{
+		answer:         out.Answer,
+		sources:        out.Sources,
+		citations:      out.Citations,
+		formatWarnings: out.FormatWarnings,
+		guardrails:     out.Guardrails,
+		provenance:     out.Provenance,
+		answeredBy:     out.AnsweredBy,
+	})
 	return out, nil
 }
 
+// answerer returns the record of which completion model answers a
+// query: rag_llm, until the failover completer reports that a fallback
+// answered instead.
+func (o *Orchestrator) answerer() *Answerer {
+	return &Answerer{Provider: o.cfg.RAGLLM.Provider, Model: o.cfg.RAGLLM.Model}
+}
+
 // ExecuteStream runs the RAG pipeline and returns a streaming response.
 func (o *Orchestrator) ExecuteStream(
 	ctx context.Context,
@@ -133,50 +305,157 @@ func (o *Orchestrator) ExecuteStream(
 	go func() {
 		defer close(chunkChan)
 		defer close(errChan)
+		timer := newQueryTimer()
+
+		if err := o.validateRequest(ctx, req); err != nil {
+			errChan <- err
+			return
+		}
+		filter, err := o.applyFilterPreset(req.FilterPreset, req.Filter)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		req.Filter = filter
+
+		cacheKey := o.answerCacheKey(ctx, req)
+		if cached, ok := o.lookupAnswer(cacheKey); ok {
+			o.recordCached(ctx)
+			if err := o.streamCachedAnswer(ctx, chunkChan, cached, req, timer); err != nil {
+				errChan <- err
+			}
+			return
+		}
+		req.Seed = o.seed(ctx, req)
 
 		topN := o.topN
 		if req.TopN > 0 {
 			topN = req.TopN
 		}
 
-		embedding, err := ragllm.Embed32(ctx, o.embeddingProv, req.Query)
+		usage := &StageUsage{}
+
+		ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+		defer cancel()
+
+		searchReq := req
+		searchReq.Query = o.rewriteQuery(ctx, req.Query)
+
+		stageStart := time.Now()
+		embedding, err := o.embedWithTimeout(ctx, searchReq.Query, usage)
 		if err != nil {
 			errChan <- fmt.Errorf("failed to generate embedding: %w", err)
 			return
 		}
+		timer.timings.EmbedMS = since(stageStart)
 
-		results, err := o.search(ctx, req, embedding, topN)
+		stageStart = time.Now()
+		results, err := o.retrieve(ctx, searchReq, embedding, topN, usage)
 		if err != nil {
 			errChan <- err
 			return
 		}
+		timer.timings.SearchMS = since(stageStart)
+		results = o.filterResults(ctx, req.Query, results)
 
 		if len(results) == 0 {
+			o.chargeCost(usage)
+			timer.firstByte(o)
 			chunkChan <- StreamChunk{
 				Content:      "No relevant information found in the available documents.",
 				FinishReason: "stop",
+				Usage:        usage,
+				Timings:      timer.result(req),
+				ResponseID:   o.recordResponse(req, nil),
 			}
 			return
 		}
 
-		results = o.rerank(ctx, req.Query, results)
+		results = o.mergeChunks(o.rerank(ctx, req.Query, results, usage))
 
-		contextDocs := o.buildContext(results)
+		fitted, err := o.fitBudget(ctx, req.Query, results, usage)
+		if err != nil {
+			errChan <- err
+			return
+		}
+		contextDocs, contextResults := o.contextFor(results, fitted)
+		req = o.fitHistory(ctx, req, usage)
 		chatReq := o.buildChatRequest(req, contextDocs)
+		docProvenance := provenance(contextResults, contextDocs)
+		o.recordReproduction(ctx, req, contextResults, docProvenance, chatReq)
+
+		// A structured answer is checked before it is sent, and with
+		// tools the model may search again before it answers, so either
+		// is generated whole and sent in one chunk, after the sources,
+		// which include what the model's searches found.
+		var answered *llmlib.ChatResponse
+		answeredBy := o.answerer()
+		answerStart := time.Now()
+		if req.ResponseFormat != nil || o.toolsEnabled() {
+			answerCtx, cancelAnswer := withStageTimeout(ctx, TimeoutStageCompletion,
+				time.Duration(o.cfg.CompletionTimeout))
+			defer cancelAnswer()
+			answerCtx = withAnswerer(answerCtx, answeredBy)
+			if req.ResponseFormat != nil {
+				answered, err = o.chatStructured(o.withSeed(o.withLogitBias(answerCtx, req), req), req, chatReq)
+			} else {
+				run := newToolRun(req, topN, usage, results, contextResults, contextDocs)
+				answered, err = o.chatWithTools(o.withSeed(o.withLogitBias(answerCtx, req), req), run, chatReq)
+				results, contextDocs = run.results, run.docs
+				contextResults = run.contextResults()
+				docProvenance = provenance(contextResults, contextDocs)
+			}
+			if err != nil {
+				o.observeStage(metrics.StageCompletion, o.completionProvider(), answerStart, err)
+				errChan <- fmt.Errorf("failed to generate completion: %w", stageTimeout(answerCtx, err))
+				return
+			}
+		}
+
+		// Send the sources before the answer starts, so clients can
+		// show them while it streams.
+		var sources []Source
+		if req.IncludeSources {
+			sources = o.buildSources(results)
+			select {
+			case chunkChan <- StreamChunk{Sources: sources}:
+			case <-ctx.Done():
+				errChan <- stageTimeout(ctx, ctx.Err())
+				return
+			}
+		}
 
-		stream, err := o.completionProv.ChatStream(ctx, chatReq)
+		// The completion timeout covers the whole answer, not just the
+		// start of the stream.
+		queryCtx := ctx
+		ctx, cancelCompletion := withStageTimeout(ctx, TimeoutStageCompletion,
+			time.Duration(o.cfg.CompletionTimeout))
+		defer cancelCompletion()
+
+		start := time.Now()
+		var stream *llmlib.Stream
+		if answered != nil {
+			start, stream = answerStart, answerStream(answered)
+		} else {
+			stream, err = o.completionProv.ChatStream(
+				o.withSeed(o.withLogitBias(withAnswerer(ctx, answeredBy), req), req), chatReq)
+		}
 		if err != nil {
-			errChan <- fmt.Errorf("failed to start completion stream: %w", err)
+			o.observeStage(metrics.StageCompletion, o.completionProvider(), start, err)
+			errChan <- fmt.Errorf("failed to start completion stream: %w", stageTimeout(ctx, err))
 			return
 		}
 
+		var answer strings.Builder
 		for {
 			chunk, recvErr := stream.Recv()
 			if errors.Is(recvErr, io.EOF) {
+				o.observeStage(metrics.StageCompletion, o.completionProvider(), start, nil)
 				return
 			}
 			if recvErr != nil {
-				errChan <- recvErr
+				o.observeStage(metrics.StageCompletion, o.completionProvider(), start, recvErr)
+				errChan <- stageTimeout(ctx, recvErr)
 				return
 			}
 
@@ -185,22 +464,66 @@ func (o *Orchestrator) ExecuteStream(
 				if chunk.Text == "" {
 					continue
 				}
+				answer.WriteString(chunk.Text)
+				if o.buffersAnswer() {
+					// The answer is sent once the guardrails and
+					// transform_answer hook have seen it whole.
+					continue
+				}
+				timer.firstByte(o)
 				select {
 				case chunkChan <- StreamChunk{Content: chunk.Text}:
 				case <-ctx.Done():
-					errChan <- ctx.Err()
+					errChan <- stageTimeout(ctx, ctx.Err())
 					return
 				}
 			case llmlib.ChunkDone:
+				if chunk.Usage != nil {
+					usage.Completion = *chunk.Usage
+					o.recordUsage(metrics.StageCompletion, o.completionProvider(), *chunk.Usage)
+				}
+				text := o.transformAnswer(queryCtx, req.Query, answer.String())
+				text, guarded := o.guard(queryCtx, req.Query, text, contextDocs, usage)
+				o.chargeCost(usage)
+				if o.buffersAnswer() && text != "" {
+					timer.firstByte(o)
+					select {
+					case chunkChan <- StreamChunk{Content: text}:
+					case <-ctx.Done():
+						errChan <- stageTimeout(ctx, ctx.Err())
+						return
+					}
+				}
 				// The lib's ChunkDone does not carry a StopReason on
 				// the chunk; the pre-migration code emitted "stop" on
 				// clean finishes, so we do the same here. If we ever
 				// need to surface real stop reasons during streaming,
 				// switch to Stream.Collect and read resp.StopReason.
+				final := StreamChunk{
+					FinishReason:   "stop",
+					Usage:          usage,
+					FormatWarnings: o.formatWarnings(text),
+					Citations:      o.citations(text, contextResults, len(contextDocs)),
+					Guardrails:     guarded,
+					Timings:        timer.result(req),
+					QueryID:        o.captureQuery(req, contextResults),
+					ResponseID:     o.recordResponse(req, docProvenance),
+					Provenance:     docProvenance,
+					AnsweredBy:     *answeredBy,
+				}
+				o.answerCache.put(cacheKey, cachedAnswer{
+					answer:         text,
+					sources:        sources,
+					citations:      final.Citations,
+					formatWarnings: final.FormatWarnings,
+					guardrails:     final.Guardrails,
+					provenance:     final.Provenance,
+					answeredBy:     final.AnsweredBy,
+				})
 				select {
-				case chunkChan <- StreamChunk{FinishReason: "stop"}:
+				case chunkChan <- final:
 				case <-ctx.Done():
-					errChan <- ctx.Err()
+					errChan <- stageTimeout(ctx, ctx.Err())
 					return
 				}
 			}
@@ -210,6 +533,267 @@ func (o *Orchestrator) ExecuteStream(
 	return chunkChan, errChan
 }
 
+// validateRequest rejects per-request generation controls that the
+// pipeline cannot honour. The checks mirror the config-time ones in
+// config.validateGenerationControls, applied to the merged values.
+func (o *Orchestrator) validateRequest(ctx context.Context, req QueryRequest) error {
+	if err := o.checkBudget(); err != nil {
+		return err
+	}
+	for i, seq := range req.StopSequences {
+		if seq == "" {
+			return fmt.Errorf("%w: stop_sequences[%d] must not be empty", ErrInvalidRequest, i)
+		}
+	}
+	if msg := config.CheckAnswerLength(req.AnswerLength); msg != "" {
+		return fmt.Errorf("%w: answer_length %s", ErrInvalidRequest, msg)
+	}
+	if err := o.checkFilter(req.Filter); err != nil {
+		return err
+	}
+	if err := o.checkFilterVars(ctx); err != nil {
+		return err
+	}
+	if n := len(o.stopSequences(req)); n > config.MaxStopSequences {
+		return fmt.Errorf("%w: at most %d stop sequences are allowed, "+
+			"including the pipeline's configured ones (got %d)",
+			ErrInvalidRequest, config.MaxStopSequences, n)
+	}
+	if err := o.checkResponseFormat(req.ResponseFormat); err != nil {
+		return err
+	}
+	if req.Seed != nil && !ragllm.SupportsSeed(o.completionProvider()) {
+		return fmt.Errorf("%w: seed is only supported by the openai, openai-compatible and ollama providers",
+			ErrInvalidRequest)
+	}
+
+	if len(req.LogitBias) == 0 {
+		return nil
+	}
+	if o.completionProvider() != ragllm.ProviderOpenAI {
+		return fmt.Errorf("%w: logit_bias is only supported by the openai provider",
+			ErrInvalidRequest)
+	}
+	for token, bias := range req.LogitBias {
+		if msg := config.CheckLogitBiasEntry(token, bias); msg != "" {
+			return fmt.Errorf("%w: logit_bias[%s]: %s", ErrInvalidRequest, token, msg)
+		}
+	}
+	return nil
+}
+
+// checkFilterVars rejects a request that does not set every variable
+// the pipeline's config filters and access control principal refer to,
+// such as the header a tenant filter reads, rather than searching
+// without the filter.
+func (o *Orchestrator) checkFilterVars(ctx context.Context) error {
+	vars := database.FilterVarsFrom(ctx)
+	if o.cfg.Tenant.Enabled() {
+		if _, ok := vars.Lookup(o.cfg.Tenant.Variable()); !ok {
+			return fmt.Errorf("%w: the pipeline isolates tenants and the request names none; set %s",
+				ErrInvalidRequest, o.cfg.Tenant.Variable())
+		}
+	}
+	for _, table := range o.cfg.Tables {
+		for _, v := range table.Filter.Variables() {
+			if _, ok := vars.Lookup(v); !ok {
+				return fmt.Errorf("%w: the filter of table %s requires %s, which the request does not set",
+					ErrInvalidRequest, table.Table, v)
+			}
+		}
+	}
+	if o.authorizer != nil {
+		if _, err := o.principal(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopSequences returns the pipeline's configured stop sequences
+// followed by any the request adds, without duplicates.
+func (o *Orchestrator) stopSequences(req QueryRequest) []string {
+	var configured []string
+	if o.cfg != nil {
+		configured = o.cfg.RAGLLM.StopSequences
+	}
+	if len(req.StopSequences) == 0 {
+		return configured
+	}
+
+	seen := make(map[string]bool, len(configured)+len(req.StopSequences))
+	merged := make([]string, 0, len(configured)+len(req.StopSequences))
+	for _, seq := range append(append([]string(nil), configured...), req.StopSequences...) {
+		if !seen[seq] {
+			seen[seq] = true
+			merged = append(merged, seq)
+		}
+	}
+	return merged
+}
+
+// answerLengthGuidance is the system prompt instruction for each
+// answer length preset. It steers the model toward the length the
+// preset's token limit allows, so answers end naturally rather than
+// being cut off at the limit.
+var answerLengthGuidance = map[string]string{
+	config.AnswerLengthShort:  "Keep your answer brief: one to three sentences, with no preamble.",
+	config.AnswerLengthMedium: "Keep your answer focused: a short paragraph or a few bullet points.",
+	config.AnswerLengthLong:   "Give a thorough answer, covering relevant details, steps and caveats from the context.",
+}
+
+// answerLength returns the request's answer length preset, falling
+// back to the pipeline's. Empty means no preset.
+func (o *Orchestrator) answerLength(req QueryRequest) string {
+	if req.AnswerLength != "" {
+		return req.AnswerLength
+	}
+	if o.cfg != nil {
+		return o.cfg.AnswerLength
+	}
+	return ""
+}
+
+// withLogitBias attaches the effective logit bias (configured entries
+// overridden per token by the request's) to ctx for the completion
+// client to pick up.
+func (o *Orchestrator) withLogitBias(ctx context.Context, req QueryRequest) context.Context {
+	var configured map[string]int
+	if o.cfg != nil {
+		configured = o.cfg.RAGLLM.LogitBias
+	}
+	if len(req.LogitBias) == 0 {
+		return ragllm.ContextWithLogitBias(ctx, configured)
+	}
+
+	merged := make(map[string]int, len(configured)+len(req.LogitBias))
+	for token, bias := range configured {
+		merged[token] = bias
+	}
+	for token, bias := range req.LogitBias {
+		merged[token] = bias
+	}
+	return ragllm.ContextWithLogitBias(ctx, merged)
+}
+
+// embed generates the query embedding, recording its latency and the
+// tokens the embedding provider reported into usage.
+func (o *Orchestrator) embed(ctx context.Context, query string, usage *StageUsage) ([]float32, error) {
+	ctx, rec := ragllm.ContextWithUsageRecorder(ctx)
+	start := time.Now()
+	embedding, err := ragllm.Embed32(ctx, o.embeddingProv, query)
+	o.observeStage(metrics.StageEmbedding, o.embeddingProvider(), start, err)
+	usage.Embedding = rec.Usage()
+	o.recordUsage(metrics.StageEmbedding, o.embeddingProvider(), usage.Embedding)
+	return embedding, err
+}
+
+// embedWithTimeout embeds the query, bounded by the pipeline's
+// embedding_timeout.
+func (o *Orchestrator) embedWithTimeout(ctx context.Context, query string, usage *StageUsage) ([]float32, error) {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageEmbedding, time.Duration(o.cfg.EmbeddingTimeout))
+	defer cancel()
+
+	embedding, err := o.embed(ctx, query, usage)
+	if err != nil {
+		return nil, stageTimeout(ctx, err)
+	}
+	return embedding, nil
+}
+
+// retrieve runs the search stage using the pipeline's retrieval
+// strategy.
+func (o *Orchestrator) retrieve(
+	ctx context.Context,
+	req QueryRequest,
+	embedding []float32,
+	topN int,
+	usage *StageUsage,
+) ([]database.SearchResult, error) {
+	switch o.cfg.Retrieval.Strategy {
+	case config.RetrievalStrategyMultiQuery:
+		return o.multiQuerySearch(ctx, req, embedding, topN, usage)
+	case config.RetrievalStrategyIterative:
+		return o.iterativeSearch(ctx, req, embedding, topN, usage)
+	case config.RetrievalStrategyHyDE:
+		embedding = o.hydeEmbedding(ctx, req.Query, embedding, usage)
+	}
+	return o.searchWithTimeout(ctx, req, embedding, topN)
+}
+
+// searchWithTimeout runs the search stage, bounded by the pipeline's
+// search_timeout. Tables whose search fails are skipped, so a timeout
+// is reported whenever the deadline passed, even if some tables
+// returned results before it.
+func (o *Orchestrator) searchWithTimeout(
+	ctx context.Context,
+	req QueryRequest,
+	embedding []float32,
+	topN int,
+) ([]database.SearchResult, error) {
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageSearch, time.Duration(o.cfg.SearchTimeout))
+	defer cancel()
+
+	results, err := o.search(ctx, req, embedding, topN)
+	if ctx.Err() != nil {
+		return nil, stageTimeout(ctx, ctx.Err())
+	}
+	return results, err
+}
+
+// observeStage records a stage's latency, and counts it as a failure
+// when err is non-nil.
+func (o *Orchestrator) observeStage(stage, provider string, start time.Time, err error) {
+	if o.metrics == nil {
+		return
+	}
+	name := o.pipelineName()
+	o.metrics.ObserveStage(name, stage, provider, time.Since(start))
+	if err != nil {
+		o.metrics.IncError(name, stage, provider)
+	}
+}
+
+// recordUsage adds a single stage's token usage to the pipeline's
+// metrics.
+func (o *Orchestrator) recordUsage(stage, provider string, u llmlib.TokenUsage) {
+	name := o.pipelineName()
+	o.metrics.AddTokens(name, stage, provider, "prompt", u.PromptTokens)
+	o.metrics.AddTokens(name, stage, provider, "completion", u.CompletionTokens)
+}
+
+// pipelineName returns the configured pipeline name for metric labels.
+func (o *Orchestrator) pipelineName() string {
+	if o.cfg == nil {
+		return ""
+	}
+	return o.cfg.Name
+}
+
+// embeddingProvider returns the embedding provider name for metric labels.
+func (o *Orchestrator) embeddingProvider() string {
+	if o.cfg == nil {
+		return ""
+	}
+	return strings.ToLower(o.cfg.EmbeddingLLM.Provider)
+}
+
+// completionProvider returns the completion provider name for metric labels.
+func (o *Orchestrator) completionProvider() string {
+	if o.cfg == nil {
+		return ""
+	}
+	return strings.ToLower(o.cfg.RAGLLM.Provider)
+}
+
+// rerankProvider returns the reranking provider name for metric labels.
+func (o *Orchestrator) rerankProvider() string {
+	if o.cfg == nil {
+		return ""
+	}
+	return strings.ToLower(o.cfg.Rerank.Provider)
+}
+
 // retrievalFailureError distinguishes "search ran cleanly and found
 // nothing" from "the backend is broken" (issue #25). It returns a non-nil
 // error only when every configured table's search failed and none
@@ -256,6 +840,48 @@ func bm25ToSearchResults(
 	return out
 }
 
+// lexicalFields pairs a fetched document's lexical column text with
+// the table's configured boosts for BM25 indexing.
+func lexicalFields(table config.TableSource, doc database.Document) []bm25.Field {
+	if len(table.LexicalColumns) == 0 {
+		return nil
+	}
+	fields := make([]bm25.Field, 0, len(table.LexicalColumns))
+	for i, lc := range table.LexicalColumns {
+		if i >= len(doc.Lexical) {
+			break
+		}
+		boost := lc.Boost
+		if boost == 0 {
+			boost = 1
+		}
+		fields = append(fields, bm25.Field{Name: lc.Column, Text: doc.Lexical[i], Boost: boost})
+	}
+	return fields
+}
+
+// hybridSearch returns how hybrid search fuses vector and lexical
+// results, and whether hybrid search is in use: it must be enabled,
+// and a vector share of 1 leaves the lexical search nothing to
+// contribute.
+func (o *Orchestrator) hybridSearch() (fusion database.FusionOptions, useHybrid bool) {
+	fusion = database.FusionOptions{
+		Method:       o.cfg.Search.Fusion,
+		K:            database.DefaultRRFConstant,
+		VectorWeight: o.cfg.Search.VectorShare(),
+	}
+	if o.cfg.Search.RRFK != nil {
+		fusion.K = float64(*o.cfg.Search.RRFK)
+	}
+	if fusion.VectorWeight < 0 || fusion.VectorWeight > 1 {
+		fusion.VectorWeight = 0.5
+	}
+
+	useHybrid = o.cfg.Search.HybridEnabled != nil && *o.cfg.Search.HybridEnabled &&
+		fusion.VectorWeight < 1.0
+	return fusion, useHybrid
+}
+
 // search runs the configured vector / hybrid search across all tables
 // and returns deduplicated, topN-capped results. Extracted so Execute
 // and ExecuteStream share the same retrieval path.
@@ -273,75 +899,163 @@ func (o *Orchestrator) search(
 	embedding []float32,
 	topN int,
 ) ([]database.SearchResult, error) {
+	fusion, useHybrid := o.hybridSearch()
+
+	// Tables are searched concurrently, so a multi-table pipeline waits
+	// on its slowest table rather than the sum of them; each writes only
+	// its own slot, and the slots are merged in configuration order,
+	// as are the table explanations of an explained query.
+	searches := make([]tableSearch, len(o.cfg.Tables))
+	trace := explainTraceFrom(ctx)
+	traces := make([]*tableTrace, len(o.cfg.Tables))
+	var g errgroup.Group
+	g.SetLimit(maxConcurrentTableSearches)
+	for i, table := range o.cfg.Tables {
+		g.Go(func() error {
+			tt := trace.table(req.Query, table)
+			ctx := ctx
+			if tt != nil {
+				ctx = context.WithValue(ctx, tableTraceKey{}, tt)
+			}
+			ts := o.searchTable(ctx, req, table, embedding, topN, fusion, useHybrid)
+			searched := ts.results
+			ts = o.authorize(ctx, table, ts)
+			tt.authorized(searched, ts.results)
+			ts.results = o.expandParents(ctx, table, req.Filter, ts.results)
+			searches[i], traces[i] = ts, tt
+			return nil
+		})
+	}
+	_ = g.Wait() // Table failures are recorded in their results, never returned
+	trace.add(traces)
+
 	var allResults []database.SearchResult
 	var hadError, hadSuccessfulLookup bool
-
-	vectorWeight := 0.5
-	if o.cfg.Search.VectorWeight != nil {
-		vectorWeight = *o.cfg.Search.VectorWeight
+	for i, ts := range searches {
+		for j := range ts.results {
+			ts.results[j].Table = o.cfg.Tables[i].Table
+		}
+		allResults = append(allResults, ts.results...)
+		hadError = hadError || ts.failed
+		hadSuccessfulLookup = hadSuccessfulLookup || ts.lookedUp
 	}
-	if vectorWeight < 0 || vectorWeight > 1 {
-		vectorWeight = 0.5
+
+	if err := retrievalFailureError(len(allResults), hadError, hadSuccessfulLookup); err != nil {
+		return nil, err
 	}
 
-	useHybrid := o.cfg.Search.HybridEnabled != nil && *o.cfg.Search.HybridEnabled &&
-		vectorWeight < 1.0
+	return o.deduplicateResults(allResults, topN), nil
+}
 
-	for _, table := range o.cfg.Tables {
-		if o.dbPool == nil {
-			o.logger.Warn("no database pool configured", "table", table.Table)
-			// A missing pool means this table cannot be searched at all,
-			// which is an infrastructure failure rather than a legitimate
-			// empty result — mark it so a total absence of a usable pool
-			// surfaces as an error instead of a false "no relevant
-			// information" response (issue #25).
-			hadError = true
-			continue
-		}
+// tableSearch is the outcome of searching one table.
+type tableSearch struct {
+	results  []database.SearchResult
+	failed   bool // A lookup failed
+	lookedUp bool // The vector search completed
+}
 
-		vectorResults, err := o.dbPool.VectorSearch(
-			ctx, embedding, table, topN*2, req.Filter,
-			o.cfg.Search.MinSimilarity,
-		)
-		if err != nil {
-			o.logger.Warn("vector search failed", "table", table.Table, "error", err)
-			hadError = true
-			continue
-		}
-		hadSuccessfulLookup = true
+// searchTable runs the vector search, and for hybrid search the
+// lexical search, against one table. Failures are logged and recorded in the
+// result rather than returned, so the other tables' results are kept.
+func (o *Orchestrator) searchTable(
+	ctx context.Context,
+	req QueryRequest,
+	table config.TableSource,
+	embedding []float32,
+	topN int,
+	fusion database.FusionOptions,
+	useHybrid bool,
+) tableSearch {
+	tt := tableTraceFrom(ctx)
+	if o.dbPool == nil {
+		o.logger.Warn("no database pool configured", "table", table.Table)
+		tt.fail(errors.New("no database pool configured"))
+		// A missing pool means this table cannot be searched at all,
+		// which is an infrastructure failure rather than a legitimate
+		// empty result — mark it so a total absence of a usable pool
+		// surfaces as an error instead of a false "no relevant
+		// information" response (issue #25).
+		return tableSearch{failed: true}
+	}
 
-		if !useHybrid {
-			o.logger.Debug("using vector-only search", "table", table.Table)
-			allResults = append(allResults, vectorResults...)
-			continue
-		}
+	start := time.Now()
+	vectorResults, err := o.dbPool.VectorSearch(
+		ctx, embedding, table, topN*2, req.Filter,
+		o.cfg.Search.MinSimilarity,
+	)
+	o.observeStage(metrics.StageVectorSearch, metrics.ProviderPostgres, start, err)
+	if err != nil {
+		o.logger.Warn("vector search failed", "table", table.Table, "error", err)
+		tt.fail(err)
+		return tableSearch{failed: true}
+	}
+	tt.vector(vectorResults)
 
-		docs, err := o.dbPool.FetchDocuments(ctx, table, req.Filter)
-		if err != nil {
-			o.logger.Warn("failed to fetch documents for BM25",
-				"table", table.Table, "error", err)
-			hadError = true
-			allResults = append(allResults, vectorResults...)
-			continue
-		}
+	if !useHybrid {
+		o.logger.Debug("using vector-only search", "table", table.Table)
+		return tableSearch{results: vectorResults, lookedUp: true}
+	}
 
-		o.bm25Index.Clear()
-		o.bm25Index.AddDocuments(docs)
-		bm25Results := o.bm25Index.Search(req.Query, topN*2)
+	lexicalResults, err := o.lexicalSearch(ctx, req, table, topN)
+	if err != nil {
+		o.logger.Warn("lexical search failed", "table", table.Table, "error", err)
+		tt.fail(err)
+		return tableSearch{results: vectorResults, failed: true, lookedUp: true}
+	}
+	tt.fusion(vectorResults, lexicalResults, fusion)
+
+	return tableSearch{
+		results:  database.FusedSearch(vectorResults, lexicalResults, topN, fusion),
+		lookedUp: true,
+	}
+}
 
-		// Clear ids when the table has no stable id_column so fusion
-		// keys on content, matching the vector arm.
-		bm25SearchResults := bm25ToSearchResults(bm25Results, table.IDColumn != "")
+// lexicalSearch runs the keyword arm of hybrid search against one
+// table with its lexical_search backend: PostgreSQL full-text search
+// in the database, or BM25 over the table's fetched documents.
+func (o *Orchestrator) lexicalSearch(
+	ctx context.Context,
+	req QueryRequest,
+	table config.TableSource,
+	topN int,
+) ([]database.SearchResult, error) {
+	start := time.Now()
+	if table.LexicalSearch == config.LexicalSearchPostgresFTS {
+		results, err := o.dbPool.TextSearch(ctx, req.Query, table, topN*2, req.Filter)
+		o.observeStage(metrics.StageFullTextSearch, metrics.ProviderPostgres, start, err)
+		if err == nil {
+			tableTraceFrom(ctx).fullText(results)
+		}
+		return results, err
+	}
 
-		hybridResults := database.HybridSearch(vectorResults, bm25SearchResults, topN, vectorWeight)
-		allResults = append(allResults, hybridResults...)
+	docs, err := o.fetchDocuments(ctx, table, req.Filter)
+	if err != nil {
+		o.observeStage(metrics.StageBM25, metrics.ProviderPostgres, start, err)
+		return nil, fmt.Errorf("failed to fetch documents for BM25: %w", err)
 	}
 
-	if err := retrievalFailureError(len(allResults), hadError, hadSuccessfulLookup); err != nil {
-		return nil, err
+	// Cached documents carry the index built by the first search of
+	// them; Search only reads it, so concurrent searches share it.
+	idx := docs.bm25Index(o.cfg, table)
+	bm25Results := idx.Search(req.Query, topN*2)
+	o.observeStage(metrics.StageBM25, metrics.ProviderPostgres, start, nil)
+
+	// Clear ids when the table has no stable id_column so fusion
+	// keys on content, matching the vector arm.
+	results := bm25ToSearchResults(bm25Results, table.IDColumn != "")
+	for i, r := range bm25Results {
+		results[i].SourceInfo = docs.docs[r.ID].SourceInfo
 	}
+	tableTraceFrom(ctx).bm25(results, idx, req.Query)
+	return results, nil
+}
 
-	return o.deduplicateResults(allResults, topN), nil
+// fetchLimits returns how much of a table lexicalSearch may load to
+// rank it with BM25.
+func (o *Orchestrator) fetchLimits() database.FetchLimits {
+	maxRows, maxBytes := o.cfg.BM25.Limits()
+	return database.FetchLimits{MaxRows: maxRows, MaxBytes: maxBytes}
 }
 
 // rerank reorders results by relevance to the query using the
@@ -349,11 +1063,13 @@ func (o *Orchestrator) search(
 // an empty result set is a no-op. A reranking failure only degrades
 // ordering — the underlying retrieval already succeeded — so it is
 // logged and the original results are returned unchanged rather than
-// failing the whole request.
+// failing the whole request. The tokens the reranker reports are
+// recorded into usage when it is non-nil.
 func (o *Orchestrator) rerank(
 	ctx context.Context,
 	query string,
 	results []database.SearchResult,
+	usage *StageUsage,
 ) []database.SearchResult {
 	if o.reranker == nil || len(results) == 0 {
 		return results
@@ -369,16 +1085,29 @@ func (o *Orchestrator) rerank(
 		topK = &k
 	}
 
+	start := time.Now()
 	resp, err := o.reranker.Rerank(ctx, llmlib.RerankRequest{
 		Query:     query,
 		Documents: docs,
 		TopK:      topK,
 	})
+	o.observeStage(metrics.StageRerank, o.rerankProvider(), start, err)
 	if err != nil {
 		o.logger.Warn("rerank failed, falling back to original order", "error", err)
 		return results
 	}
 
+	// Rerank APIs report only a total; it is all input, so count it as
+	// prompt tokens like the embedding stage does.
+	rerankUsage := resp.Usage
+	if rerankUsage.PromptTokens == 0 {
+		rerankUsage.PromptTokens = rerankUsage.TotalTokens
+	}
+	if usage != nil {
+		usage.Rerank = &rerankUsage
+	}
+	o.recordUsage(metrics.StageRerank, o.rerankProvider(), rerankUsage)
+
 	reranked := o.applyRerankOrder(results, resp.Results)
 
 	// A successful call can still yield nothing usable — an empty
@@ -443,8 +1172,15 @@ func (o *Orchestrator) buildChatRequest(
 	contextDocs []ragllm.ContextDoc,
 ) llmlib.ChatRequest {
 	system := o.buildSystemPrompt()
+	length := o.answerLength(req)
+	if guidance := answerLengthGuidance[length]; guidance != "" {
+		system += "\n\n" + guidance
+	}
+	if req.historySummary != "" {
+		system += "\n\nSummary of the earlier conversation:\n" + req.historySummary
+	}
 	if len(contextDocs) > 0 {
-		system = system + "\n\n" + ragllm.FormatContext(contextDocs)
+		system = system + "\n\n" + ragllm.FormatContextWith(contextDocs, o.contextFormat())
 	}
 
 	messages := make([]llmlib.Message, 0, len(req.Messages)+1)
@@ -458,10 +1194,15 @@ func (o *Orchestrator) buildChatRequest(
 	}
 	messages = append(messages, llmlib.UserText(req.Query))
 
-	return llmlib.ChatRequest{
-		SystemPrompt: system,
-		Messages:     messages,
+	chatReq := llmlib.ChatRequest{
+		SystemPrompt:  system,
+		Messages:      messages,
+		StopSequences: o.stopSequences(req),
+	}
+	if maxTokens, ok := config.AnswerLengthMaxTokens[length]; ok {
+		chatReq.MaxTokens = llmlib.Int(maxTokens)
 	}
+	return chatReq
 }
 
 // joinTextBlocks concatenates the Text fields of all BlockText blocks
@@ -504,35 +1245,96 @@ func (o *Orchestrator) deduplicateResults(
 }
 
 // buildContext converts search results to context documents, respecting token budget.
-func (o *Orchestrator) buildContext(results []database.SearchResult) []ragllm.ContextDoc {
+// The first result is always included, truncated if it alone is over
+// the budget; later ones are truncated only when more than 100 tokens
+// of the budget remain. Truncated documents end at a paragraph, sentence
+// or word boundary. With drop_lowest_scoring, the lowest-scoring results
+// are left out first, so only the first may be truncated. Tokens are
+// counted by the pipeline's tokenizer. It also returns the results the
+// documents were built from, one per document and in the same order,
+// for the citations and provenance.
+func (o *Orchestrator) buildContext(results []database.SearchResult) ([]ragllm.ContextDoc, []database.SearchResult) {
+	counts := make([]int, len(results))
+	for i, r := range results {
+		counts[i] = o.counter().Count(r.Content)
+	}
+	if o.cfg != nil && o.cfg.DropLowestScoring {
+		results, counts = o.dropLowestScoring(results, counts)
+	}
+
 	contextDocs := make([]ragllm.ContextDoc, 0, len(results))
 	totalTokens := 0
 
-	for _, r := range results {
-		estimatedTokens := len(r.Content) / 4
-		if totalTokens+estimatedTokens > o.tokenBudget {
+	for i, r := range results {
+		docTokens := counts[i]
+		if totalTokens+docTokens > o.tokenBudget {
 			remaining := o.tokenBudget - totalTokens
-			if remaining > 100 {
-				truncated := r.Content[:min(len(r.Content), remaining*4)]
-				if idx := strings.LastIndex(truncated, ". "); idx > 0 {
-					truncated = truncated[:idx+1]
-				}
+			if remaining > 100 || i == 0 {
+				truncated := ingest.TrimToBoundary(o.counter().Truncate(r.Content, remaining))
 				contextDocs = append(contextDocs, ragllm.ContextDoc{
-					Content: truncated + "...",
-					Score:   r.Score,
+					Content:     truncated + "...",
+					Source:      o.contextSource(r),
+					Score:       r.Score,
+					ID:          r.ID,
+					ContentHash: contentHash(r.Content),
 				})
 			}
 			break
 		}
 
 		contextDocs = append(contextDocs, ragllm.ContextDoc{
-			Content: r.Content,
-			Score:   r.Score,
+			Content:     r.Content,
+			Source:      o.contextSource(r),
+			Score:       r.Score,
+			ID:          r.ID,
+			ContentHash: contentHash(r.Content),
 		})
-		totalTokens += estimatedTokens
+		totalTokens += docTokens
+	}
+
+	return contextDocs, results[:len(contextDocs)]
+}
+
+// tokenCounter returns the tokenizer that counts a pipeline's context
+// and history: the one its tokenizer setting names, or else the one
+// rag_llm's model uses.
+func tokenCounter(p *config.Pipeline) tokens.Counter {
+	if p.Tokenizer != "" {
+		return tokens.Get(p.Tokenizer)
 	}
+	return tokens.ForModel(p.RAGLLM.Provider, p.RAGLLM.Model)
+}
 
-	return contextDocs
+// counter returns the pipeline's tokenizer, or the len/4 estimate for
+// an orchestrator built without a pipeline.
+func (o *Orchestrator) counter() tokens.Counter {
+	if o.tokenCounter == nil {
+		return tokens.Estimate{}
+	}
+	return o.tokenCounter
+}
+
+// contextFormat returns how the pipeline lays out retrieved documents
+// in the prompt.
+func (o *Orchestrator) contextFormat() config.ContextFormatConfig {
+	if o.cfg == nil {
+		return config.ContextFormatConfig{}
+	}
+	return o.cfg.ContextFormat
+}
+
+// contextSource returns the source shown with a result in the context:
+// its value of the pipeline's context_format.source_column, if any.
+func (o *Orchestrator) contextSource(r database.SearchResult) string {
+	column := o.contextFormat().SourceColumn
+	if column == "" {
+		return ""
+	}
+	value, ok := r.SourceInfo[column]
+	if !ok || value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
 }
 
 // DefaultSystemPrompt is the default system prompt used when none is configured.
@@ -542,12 +1344,36 @@ If the context does not contain relevant information to answer the question, you
 Do NOT use your general knowledge to answer. Only use facts from the provided context.
 Be concise and accurate in your responses.`
 
-// buildSystemPrompt returns the system prompt for RAG.
+// buildSystemPrompt returns the system prompt for RAG, followed by the
+// pipeline's formatting conventions and citation instructions when it
+// has any.
 func (o *Orchestrator) buildSystemPrompt() string {
+	prompt := DefaultSystemPrompt
 	if o.cfg != nil && o.cfg.SystemPrompt != "" {
-		return o.cfg.SystemPrompt
+		prompt = o.cfg.SystemPrompt
+	}
+	if o.cfg != nil {
+		if instructions := formattingInstructions(o.cfg.Formatting); instructions != "" {
+			prompt += "\n\n" + instructions
+		}
 	}
-	return DefaultSystemPrompt
+	if o.citationsEnabled() {
+		prompt += "\n\n" + citationInstructions
+	}
+	return prompt
+}
+
+// formatWarnings checks a generated answer against the pipeline's
+// formatting conventions, logging any departures.
+func (o *Orchestrator) formatWarnings(answer string) []string {
+	if o.cfg == nil {
+		return nil
+	}
+	warnings := checkFormatting(answer, o.cfg.Formatting)
+	if len(warnings) > 0 {
+		o.logger.Info("answer departs from formatting conventions", "warnings", warnings)
+	}
+	return warnings
 }
 
 // buildSources extracts source information from results.
@@ -555,9 +1381,11 @@ func (o *Orchestrator) buildSources(results []database.SearchResult) []Source {
 	sources := make([]Source, len(results))
 	for i, r := range results {
 		sources[i] = Source{
-			ID:      r.ID,
-			Content: r.Content,
-			Score:   r.Score,
+			ID:          r.ID,
+			Content:     r.Content,
+			Score:       r.Score,
+			Metadata:    r.SourceInfo,
+			ContentHash: contentHash(r.Content),
 		}
 	}
 	return sources
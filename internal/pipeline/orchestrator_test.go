@@ -13,14 +13,18 @@ import (
 	"context"
 	"errors"
 	"io"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
 
 	"github.com/pgEdge/pgedge-rag-server/internal/bm25"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
 	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
 )
 
 // MockEmbedder implements pipeline.Embedder for orchestrator tests.
@@ -147,7 +151,14 @@ type MockSearchBackend struct {
 		ctx context.Context,
 		table config.TableSource,
 		filter *config.Filter,
-	) (map[string]string, error)
+	) (map[string]database.Document, error)
+	TextSearchFunc func(
+		ctx context.Context,
+		queryText string,
+		table config.TableSource,
+		topN int,
+		filter *config.Filter,
+	) ([]database.SearchResult, error)
 }
 
 func (m *MockSearchBackend) VectorSearch(
@@ -168,13 +179,60 @@ func (m *MockSearchBackend) FetchDocuments(
 	ctx context.Context,
 	table config.TableSource,
 	filter *config.Filter,
-) (map[string]string, error) {
+	limits database.FetchLimits,
+) (map[string]database.Document, error) {
 	if m.FetchDocumentsFunc != nil {
 		return m.FetchDocumentsFunc(ctx, table, filter)
 	}
 	return nil, nil
 }
 
+func (m *MockSearchBackend) TextSearch(
+	ctx context.Context,
+	queryText string,
+	table config.TableSource,
+	topN int,
+	filter *config.Filter,
+) ([]database.SearchResult, error) {
+	if m.TextSearchFunc != nil {
+		return m.TextSearchFunc(ctx, queryText, table, topN, filter)
+	}
+	return nil, nil
+}
+
+// testOrchestratorOption overrides part of the pipeline or orchestrator
+// configuration newTestOrchestrator builds from.
+type testOrchestratorOption func(p *config.Pipeline, cfg *OrchestratorConfig)
+
+// newTestOrchestrator creates an orchestrator for a "docs" pipeline with
+// mock providers, whose vector search returns results, after applying
+// opts to its configuration.
+func newTestOrchestrator(results []database.SearchResult, opts ...testOrchestratorOption) *Orchestrator {
+	pCfg := config.Pipeline{
+		Name:   "docs",
+		Tables: []config.TableSource{{Table: "docs", IDColumn: "id", TextColumn: "content", VectorColumn: "embedding"}},
+	}
+	cfg := OrchestratorConfig{
+		Pipeline: &pCfg,
+		DBPool: &MockSearchBackend{
+			VectorSearchFunc: func(
+				ctx context.Context, embedding []float32, table config.TableSource,
+				topN int, filter *config.Filter, minSimilarity *float64,
+			) ([]database.SearchResult, error) {
+				return results, nil
+			},
+		},
+		EmbeddingProv:  &MockEmbedder{},
+		CompletionProv: &MockCompleter{},
+		TokenBudget:    DefaultTokenBudget,
+		TopN:           DefaultTopN,
+	}
+	for _, opt := range opts {
+		opt(&pCfg, &cfg)
+	}
+	return NewOrchestrator(cfg)
+}
+
 func TestNewOrchestrator(t *testing.T) {
 	cfg := OrchestratorConfig{
 		Pipeline: &config.Pipeline{
@@ -197,18 +255,13 @@ func TestNewOrchestrator(t *testing.T) {
 	if orch.topN != 5 {
 		t.Errorf("expected topN 5, got %d", orch.topN)
 	}
-	if orch.bm25Index == nil {
-		t.Error("bm25Index should not be nil")
-	}
 	if orch.logger == nil {
 		t.Error("logger should not be nil")
 	}
 }
 
 func TestDeduplicateResults(t *testing.T) {
-	orch := &Orchestrator{
-		bm25Index: bm25.NewIndex(),
-	}
+	orch := &Orchestrator{}
 
 	tests := []struct {
 		name     string
@@ -321,10 +374,9 @@ func TestBuildContext(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			orch := &Orchestrator{
 				tokenBudget: tt.tokenBudget,
-				bm25Index:   bm25.NewIndex(),
 			}
 
-			contextDocs := orch.buildContext(tt.results)
+			contextDocs, _ := orch.buildContext(tt.results)
 
 			if len(contextDocs) != tt.expectCount {
 				t.Errorf("expected %d context docs, got %d", tt.expectCount, len(contextDocs))
@@ -341,9 +393,7 @@ func TestBuildContext(t *testing.T) {
 }
 
 func TestBuildSystemPrompt(t *testing.T) {
-	orch := &Orchestrator{
-		bm25Index: bm25.NewIndex(),
-	}
+	orch := &Orchestrator{}
 
 	prompt := orch.buildSystemPrompt()
 
@@ -373,7 +423,6 @@ func TestBuildSystemPrompt_CustomPrompt(t *testing.T) {
 			Name:         "test-pipeline",
 			SystemPrompt: customPrompt,
 		},
-		bm25Index: bm25.NewIndex(),
 	}
 
 	prompt := orch.buildSystemPrompt()
@@ -390,7 +439,6 @@ func TestBuildSystemPrompt_EmptyConfigPrompt(t *testing.T) {
 			Name:         "test-pipeline",
 			SystemPrompt: "", // Empty
 		},
-		bm25Index: bm25.NewIndex(),
 	}
 
 	prompt := orch.buildSystemPrompt()
@@ -431,9 +479,7 @@ func containsPhrase(s, phrase string) bool {
 }
 
 func TestBuildSources(t *testing.T) {
-	orch := &Orchestrator{
-		bm25Index: bm25.NewIndex(),
-	}
+	orch := &Orchestrator{}
 
 	results := []database.SearchResult{
 		{ID: "doc1", Content: "Content 1", Score: 0.95},
@@ -467,8 +513,7 @@ func TestBuildSources(t *testing.T) {
 func TestQueryRequestTopNOverride(t *testing.T) {
 	// Test that request-level TopN overrides orchestrator default
 	orch := &Orchestrator{
-		topN:      10, // Default
-		bm25Index: bm25.NewIndex(),
+		topN: 10, // Default
 	}
 
 	// Simulate getting topN from request
@@ -587,9 +632,7 @@ func TestMockEmbedder_CustomErrorFunc(t *testing.T) {
 }
 
 func TestBuildSystemPrompt_DefaultContainsAntiHallucination(t *testing.T) {
-	orch := &Orchestrator{
-		bm25Index: bm25.NewIndex(),
-	}
+	orch := &Orchestrator{}
 
 	prompt := orch.buildSystemPrompt()
 
@@ -703,7 +746,7 @@ func TestBM25ToSearchResults_FusesWithVectorArmWhenNoIDColumn(t *testing.T) {
 // temperature parameter outright (observed live against claude-sonnet-5:
 // "400: `temperature` is deprecated for this model").
 func TestBuildChatRequest_OmitsTemperature(t *testing.T) {
-	orch := &Orchestrator{bm25Index: bm25.NewIndex()}
+	orch := &Orchestrator{}
 
 	req := orch.buildChatRequest(QueryRequest{Query: "hello"}, nil)
 
@@ -712,11 +755,76 @@ func TestBuildChatRequest_OmitsTemperature(t *testing.T) {
 	}
 }
 
+func TestBuildChatRequest_AnswerLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		pipeline      string
+		request       string
+		wantMaxTokens int // 0 means MaxTokens must stay nil
+		wantGuidance  string
+	}{
+		{name: "no preset"},
+		{name: "pipeline preset", pipeline: "short", wantMaxTokens: 256, wantGuidance: "one to three sentences"},
+		{name: "request overrides pipeline", pipeline: "short", request: "long", wantMaxTokens: 4096, wantGuidance: "thorough answer"},
+		{name: "request preset only", request: "medium", wantMaxTokens: 1024, wantGuidance: "short paragraph"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := &Orchestrator{
+				cfg: &config.Pipeline{Name: "docs", AnswerLength: tt.pipeline},
+			}
+
+			req := orch.buildChatRequest(QueryRequest{Query: "hello", AnswerLength: tt.request}, nil)
+
+			if tt.wantMaxTokens == 0 {
+				if req.MaxTokens != nil {
+					t.Errorf("expected MaxTokens to be nil, got %d", *req.MaxTokens)
+				}
+				if req.SystemPrompt != DefaultSystemPrompt {
+					t.Errorf("expected the default system prompt, got %q", req.SystemPrompt)
+				}
+				return
+			}
+			if req.MaxTokens == nil || *req.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("MaxTokens = %v, want %d", req.MaxTokens, tt.wantMaxTokens)
+			}
+			if !strings.Contains(req.SystemPrompt, tt.wantGuidance) {
+				t.Errorf("system prompt missing %q guidance:\n%s", tt.wantGuidance, req.SystemPrompt)
+			}
+		})
+	}
+}
+
 // TestRetrievalFailureError_AllTablesFailed is a regression test for
 // issue #25: when every configured table's search failed and none
 // produced results, retrievalFailureError must return a non-nil error so
 // callers surface an infrastructure failure instead of a false "no
 // relevant information" response.
+func TestBuildChatRequest_ContextFormat(t *testing.T) {
+	orch := &Orchestrator{
+		cfg: &config.Pipeline{Name: "docs", ContextFormat: config.ContextFormatConfig{
+			Style: config.ContextStyleXML, SourceColumn: "url",
+		}},
+		tokenBudget: 1000,
+	}
+	docs, _ := orch.buildContext([]database.SearchResult{
+		{Content: "WAL is streamed.", Score: 0.9, SourceInfo: map[string]interface{}{"url": "https://docs/replication"}},
+		{Content: "Standbys replay WAL.", Score: 0.8, SourceInfo: map[string]interface{}{"url": nil}},
+	})
+
+	req := orch.buildChatRequest(QueryRequest{Query: "hello"}, docs)
+
+	for _, want := range []string{
+		`<document index="1" source="https://docs/replication">` + "\nWAL is streamed.\n</document>",
+		`<document index="2">` + "\nStandbys replay WAL.\n</document>",
+	} {
+		if !strings.Contains(req.SystemPrompt, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, req.SystemPrompt)
+		}
+	}
+}
+
 func TestRetrievalFailureError_AllTablesFailed(t *testing.T) {
 	err := retrievalFailureError(0, true, false)
 	if err == nil {
@@ -765,7 +873,7 @@ func TestRerank_NilReranker_ReturnsOriginalResults(t *testing.T) {
 	})
 	results := []database.SearchResult{{ID: "1", Content: "a"}, {ID: "2", Content: "b"}}
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 
 	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
 		t.Errorf("expected unchanged results with nil reranker, got %+v", got)
@@ -779,7 +887,7 @@ func TestRerank_EmptyResults_NoOp(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", nil)
+	got := orch.rerank(context.Background(), "query", nil, nil)
 
 	if len(got) != 0 {
 		t.Errorf("expected empty results, got %+v", got)
@@ -813,7 +921,7 @@ func TestRerank_ReordersByProviderResponse(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 
 	want := []string{"3", "1", "2"}
 	if len(got) != len(want) {
@@ -862,7 +970,7 @@ func TestRerank_UpdatesScoreToRelevanceScore(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 
 	if len(got) != 2 {
 		t.Fatalf("expected 2 results, got %d", len(got))
@@ -891,7 +999,7 @@ func TestRerank_ProviderReturnsFewerResults(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 
 	if len(got) != 1 || got[0].ID != "2" {
 		t.Errorf("expected exactly [ID=2], got %+v", got)
@@ -916,7 +1024,7 @@ func TestRerank_NegativeIndexSkipped(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 1 || got[0].ID != "2" {
 		t.Errorf("expected only the valid index to survive, got %+v", got)
 	}
@@ -945,7 +1053,7 @@ func TestRerank_TopKPassedWhenSmallerThanResultCount(t *testing.T) {
 		RerankTopK: 2,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 2 {
 		t.Errorf("expected 2 results, got %d", len(got))
 	}
@@ -972,7 +1080,7 @@ func TestRerank_TopKOmittedWhenNotSmallerThanResultCount(t *testing.T) {
 		RerankTopK: 2,
 	})
 
-	orch.rerank(context.Background(), "query", results)
+	orch.rerank(context.Background(), "query", results, nil)
 }
 
 // TestRerank_ProviderErrorFallsBackToOriginalOrder verifies that a
@@ -991,7 +1099,7 @@ func TestRerank_ProviderErrorFallsBackToOriginalOrder(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
 		t.Errorf("expected fallback to original order, got %+v", got)
 	}
@@ -1012,7 +1120,7 @@ func TestRerank_SkipsOutOfRangeIndex(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 1 || got[0].ID != "1" {
 		t.Errorf("expected only the valid index to survive, got %+v", got)
 	}
@@ -1039,7 +1147,7 @@ func TestRerank_AllIndicesInvalidFallsBackToOriginalOrder(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
 		t.Errorf("expected fallback to original order, got %+v", got)
 	}
@@ -1059,7 +1167,7 @@ func TestRerank_EmptyResponseFallsBackToOriginalOrder(t *testing.T) {
 		Reranker: mock,
 	})
 
-	got := orch.rerank(context.Background(), "query", results)
+	got := orch.rerank(context.Background(), "query", results, nil)
 	if len(got) != 2 || got[0].ID != "1" || got[1].ID != "2" {
 		t.Errorf("expected fallback to original order, got %+v", got)
 	}
@@ -1106,14 +1214,12 @@ func TestOrchestrator_Execute_TotalRetrievalFailureSurfacesError(t *testing.T) {
 // loop must still fall through to the legitimate "no relevant
 // information" response, not surface an error.
 func TestOrchestrator_Execute_PartialRetrievalFailureFallsThroughToEmptyResult(t *testing.T) {
-	var calls int
 	backend := &MockSearchBackend{
 		VectorSearchFunc: func(
 			ctx context.Context, embedding []float32, table config.TableSource,
 			topN int, filter *config.Filter, minSimilarity *float64,
 		) ([]database.SearchResult, error) {
-			calls++
-			if calls == 1 {
+			if table.Table == "docs1" {
 				return nil, errors.New("table 1 unreachable")
 			}
 			return nil, nil // table 2 succeeds, with zero matches
@@ -1146,6 +1252,96 @@ func TestOrchestrator_Execute_PartialRetrievalFailureFallsThroughToEmptyResult(t
 	}
 }
 
+// TestOrchestrator_Execute_SearchesTablesConcurrently checks that every
+// table's search is in flight at once, and that the merged results keep
+// the tables' configured order regardless of which finishes first.
+func TestOrchestrator_Execute_SearchesTablesConcurrently(t *testing.T) {
+	tables := []config.TableSource{
+		{Table: "docs1", TextColumn: "content", VectorColumn: "embedding"},
+		{Table: "docs2", TextColumn: "content", VectorColumn: "embedding"},
+		{Table: "docs3", TextColumn: "content", VectorColumn: "embedding"},
+	}
+	var inFlight sync.WaitGroup
+	inFlight.Add(len(tables))
+	allStarted := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(allStarted)
+	}()
+
+	orch := newTestOrchestrator(nil, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Tables = tables
+		cfg.DBPool = &MockSearchBackend{
+			VectorSearchFunc: func(
+				ctx context.Context, embedding []float32, table config.TableSource,
+				topN int, filter *config.Filter, minSimilarity *float64,
+			) ([]database.SearchResult, error) {
+				inFlight.Done()
+				select {
+				case <-allStarted:
+				case <-time.After(5 * time.Second):
+					return nil, errors.New("tables were searched one at a time")
+				}
+				return []database.SearchResult{{ID: table.Table, Content: "from " + table.Table}}, nil
+			},
+		}
+	})
+
+	results, err := orch.search(context.Background(), QueryRequest{Query: "test query"}, []float32{1}, DefaultTopN)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.ID)
+	}
+	if want := []string{"docs1", "docs2", "docs3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("results = %v, want %v", got, want)
+	}
+}
+
+// TestOrchestrator_Execute_PostgresFTS checks that a postgres_fts table's
+// lexical arm is ranked by the database, so its documents are never
+// fetched, and that its results are fused with the vector results.
+func TestOrchestrator_Execute_PostgresFTS(t *testing.T) {
+	var searchedText string
+	hybrid := true
+	orch := newTestOrchestrator([]database.SearchResult{{ID: "doc-1", Content: "vector match"}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.Tables[0].LexicalSearch = config.LexicalSearchPostgresFTS
+			p.Search = config.SearchConfig{HybridEnabled: &hybrid}
+			backend := cfg.DBPool.(*MockSearchBackend)
+			backend.FetchDocumentsFunc = func(
+				ctx context.Context, table config.TableSource, filter *config.Filter,
+			) (map[string]database.Document, error) {
+				t.Error("a postgres_fts table's documents should not be fetched")
+				return nil, nil
+			}
+			backend.TextSearchFunc = func(
+				ctx context.Context, queryText string, table config.TableSource,
+				topN int, filter *config.Filter,
+			) ([]database.SearchResult, error) {
+				searchedText = queryText
+				return []database.SearchResult{{ID: "doc-2", Content: "keyword match", Score: 0.4}}, nil
+			}
+		})
+
+	results, err := orch.search(context.Background(), QueryRequest{Query: "wal shipping"}, []float32{1}, DefaultTopN)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if searchedText != "wal shipping" {
+		t.Errorf("full-text search got query %q, want %q", searchedText, "wal shipping")
+	}
+	ids := make(map[string]bool)
+	for _, r := range results {
+		ids[r.ID] = true
+	}
+	if len(results) != 2 || !ids["doc-1"] || !ids["doc-2"] {
+		t.Errorf("expected vector and full-text results fused, got %+v", results)
+	}
+}
+
 // Verify mock providers implement the interfaces
 var (
 	_ Embedder      = (*MockEmbedder)(nil)
@@ -1153,3 +1349,523 @@ var (
 	_ Reranker      = (*MockReranker)(nil)
 	_ SearchBackend = (*MockSearchBackend)(nil)
 )
+
+func TestOrchestrator_Execute_RecordsStageMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	orch := newTestOrchestrator([]database.SearchResult{{ID: "1", Content: "PostgreSQL is a database.", Score: 0.9}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.EmbeddingLLM = config.LLMConfig{Provider: "OpenAI"}
+			p.RAGLLM = config.LLMConfig{Provider: "anthropic"}
+			cfg.Metrics = reg
+		})
+
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "what is postgres"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf strings.Builder
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`pgedge_rag_stage_duration_seconds_count{pipeline="docs",stage="embedding",provider="openai"} 1`,
+		`pgedge_rag_stage_duration_seconds_count{pipeline="docs",stage="vector_search",provider="postgres"} 1`,
+		`pgedge_rag_stage_duration_seconds_count{pipeline="docs",stage="completion",provider="anthropic"} 1`,
+		`pgedge_rag_tokens_total{pipeline="docs",stage="completion",provider="anthropic",type="prompt"} 100`,
+		`pgedge_rag_tokens_total{pipeline="docs",stage="completion",provider="anthropic",type="completion"} 20`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestOrchestrator_Execute_ReportsStageUsage(t *testing.T) {
+	reranker := &MockReranker{
+		RerankFunc: func(ctx context.Context, req llmlib.RerankRequest) (*llmlib.RerankResponse, error) {
+			return &llmlib.RerankResponse{
+				Results: []llmlib.RerankResult{{Index: 0, RelevanceScore: 0.8}},
+				Usage:   llmlib.TokenUsage{TotalTokens: 42},
+			}, nil
+		},
+	}
+	reg := metrics.NewRegistry()
+	orch := newTestOrchestrator([]database.SearchResult{{ID: "1", Content: "PostgreSQL is a database.", Score: 0.9}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.EmbeddingLLM = config.LLMConfig{Provider: "openai"}
+			p.RAGLLM = config.LLMConfig{Provider: "anthropic"}
+			p.Rerank = config.RerankConfig{Provider: "voyage"}
+			cfg.Reranker = reranker
+			cfg.Metrics = reg
+		})
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "what is postgres"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Usage == nil {
+		t.Fatal("expected a usage breakdown")
+	}
+	if resp.Usage.Rerank == nil || resp.Usage.Rerank.PromptTokens != 42 || resp.Usage.Rerank.TotalTokens != 42 {
+		t.Errorf("expected 42 rerank prompt tokens, got %+v", resp.Usage.Rerank)
+	}
+	if resp.Usage.Completion.PromptTokens != 100 || resp.Usage.Completion.CompletionTokens != 20 {
+		t.Errorf("unexpected completion usage: %+v", resp.Usage.Completion)
+	}
+	if resp.TokensUsed != resp.Usage.Completion.TotalTokens {
+		t.Errorf("expected tokens_used to remain the completion total, got %d", resp.TokensUsed)
+	}
+
+	var buf strings.Builder
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`pgedge_rag_stage_duration_seconds_count{pipeline="docs",stage="rerank",provider="voyage"} 1`,
+		`pgedge_rag_tokens_total{pipeline="docs",stage="rerank",provider="voyage",type="prompt"} 42`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestOrchestrator_Execute_CountsStageErrors(t *testing.T) {
+	reg := metrics.NewRegistry()
+	orch := newTestOrchestrator(nil, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.EmbeddingLLM = config.LLMConfig{Provider: "openai"}
+		cfg.EmbeddingProv = &MockEmbedder{EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+			return nil, errors.New("provider down")
+		}}
+		cfg.Metrics = reg
+	})
+
+	if _, err := orch.Execute(context.Background(), QueryRequest{Query: "q"}); err == nil {
+		t.Fatal("expected embedding error")
+	}
+
+	var buf strings.Builder
+	_, _ = reg.WriteTo(&buf)
+	want := `pgedge_rag_errors_total{pipeline="docs",stage="embedding",provider="openai"} 1`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics missing %q\n%s", want, buf.String())
+	}
+}
+
+func TestOrchestrator_Execute_MergesStopSequences(t *testing.T) {
+	var got []string
+	orch := newTestOrchestrator([]database.SearchResult{{ID: "1", Content: "PostgreSQL is a database.", Score: 0.9}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.RAGLLM = config.LLMConfig{Provider: "anthropic", StopSequences: []string{"</answer>", "###"}}
+			cfg.CompletionProv = &MockCompleter{
+				ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+					got = req.StopSequences
+					return &llmlib.ChatResponse{
+						Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "ok"}},
+					}, nil
+				},
+			}
+		})
+
+	_, err := orch.Execute(context.Background(), QueryRequest{
+		Query:         "what is postgres",
+		StopSequences: []string{"###", "<html>"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"</answer>", "###", "<html>"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("StopSequences = %v, want %v", got, want)
+	}
+}
+
+func TestOrchestrator_Execute_RejectsInvalidGenerationControls(t *testing.T) {
+	tests := []struct {
+		name          string
+		provider      string
+		filterColumns []string
+		req           QueryRequest
+		wantMsg       string
+	}{
+		{
+			name:     "logit bias on non-openai provider",
+			provider: "anthropic",
+			req:      QueryRequest{Query: "q", LogitBias: map[string]int{"13": -100}},
+			wantMsg:  "only supported by the openai provider",
+		},
+		{
+			name:     "seed on a provider without one",
+			provider: "anthropic",
+			req:      QueryRequest{Query: "q", Seed: new(int64)},
+			wantMsg:  "seed is only supported by the openai, openai-compatible and ollama providers",
+		},
+		{
+			name:     "logit bias out of range",
+			provider: "openai",
+			req:      QueryRequest{Query: "q", LogitBias: map[string]int{"13": -101}},
+			wantMsg:  "must be between -100 and 100",
+		},
+		{
+			name:     "too many stop sequences",
+			provider: "openai",
+			req:      QueryRequest{Query: "q", StopSequences: []string{"a", "b", "c", "d", "e"}},
+			wantMsg:  "at most 4 stop sequences",
+		},
+		{
+			name:     "empty stop sequence",
+			provider: "openai",
+			req:      QueryRequest{Query: "q", StopSequences: []string{""}},
+			wantMsg:  "must not be empty",
+		},
+		{
+			name:     "unknown answer length",
+			provider: "openai",
+			req:      QueryRequest{Query: "q", AnswerLength: "tiny"},
+			wantMsg:  "answer_length must be one of: short, medium, long",
+		},
+		{
+			name:          "filter on a column outside filter_columns",
+			provider:      "openai",
+			filterColumns: []string{"product", "version"},
+			req: QueryRequest{Query: "q", Filter: &config.Filter{Conditions: []config.FilterCondition{
+				{Column: "product", Operator: "=", Value: "pgEdge"},
+				{Column: "owner", Operator: "=", Value: "jo"},
+			}}},
+			wantMsg: `filter.conditions[1].column: column "owner" is not filterable`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pCfg := config.Pipeline{
+				Name:          "docs",
+				RAGLLM:        config.LLMConfig{Provider: tt.provider},
+				FilterColumns: tt.filterColumns,
+			}
+			embedCalled := false
+			orch := NewOrchestrator(OrchestratorConfig{
+				Pipeline: &pCfg,
+				EmbeddingProv: &MockEmbedder{EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+					embedCalled = true
+					return []float64{0.1}, nil
+				}},
+				CompletionProv: &MockCompleter{},
+			})
+
+			_, err := orch.Execute(context.Background(), tt.req)
+			if !errors.Is(err, ErrInvalidRequest) {
+				t.Fatalf("expected ErrInvalidRequest, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("expected %q in error, got %v", tt.wantMsg, err)
+			}
+			if embedCalled {
+				t.Error("expected validation to fail before embedding")
+			}
+		})
+	}
+}
+
+func TestOrchestrator_Execute_FormattingConventions(t *testing.T) {
+	var system string
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			system = req.SystemPrompt
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{llmlib.TextBlock("Support ends on 2026-12-31.")},
+			}, nil
+		},
+	}
+	orch := newTestOrchestrator([]database.SearchResult{{ID: "1", Content: "Support ends 2026-12-31.", Score: 0.9}},
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.Formatting = config.FormattingConfig{Locale: "en-GB", DateFormat: config.DateFormatDMY}
+			cfg.CompletionProv = completer
+		})
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "when does support end?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(system, "en-GB locale") || !strings.Contains(system, "day first") {
+		t.Errorf("system prompt missing formatting instructions:\n%s", system)
+	}
+	want := []string{`date "2026-12-31" is not in day-month-year order`}
+	if len(resp.FormatWarnings) != 1 || resp.FormatWarnings[0] != want[0] {
+		t.Errorf("FormatWarnings = %q, want %q", resp.FormatWarnings, want)
+	}
+}
+
+func TestOrchestrator_Execute_SourcesCarryMetadata(t *testing.T) {
+	hybrid := true
+	orch := newTestOrchestrator([]database.SearchResult{{
+		ID: "1", Content: "PostgreSQL is a database.", Score: 0.9,
+		SourceInfo: map[string]interface{}{"url": "https://example.com/1"},
+	}}, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Tables[0].MetadataColumns = []string{"url"}
+		p.Search = config.SearchConfig{HybridEnabled: &hybrid}
+		cfg.DBPool.(*MockSearchBackend).FetchDocumentsFunc = func(
+			ctx context.Context, table config.TableSource, filter *config.Filter,
+		) (map[string]database.Document, error) {
+			return map[string]database.Document{
+				"1": {Content: "PostgreSQL is a database.",
+					SourceInfo: map[string]interface{}{"url": "https://example.com/1"}},
+				"2": {Content: "pgvector adds vector search to postgres.",
+					SourceInfo: map[string]interface{}{"url": "https://example.com/2"}},
+				"3": {Content: "Release notes for the web console.",
+					SourceInfo: map[string]interface{}{"url": "https://example.com/3"}},
+			}, nil
+		}
+	})
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query: "what is postgres", IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Sources) != 2 {
+		t.Fatalf("expected 2 sources, got %d", len(resp.Sources))
+	}
+	for _, s := range resp.Sources {
+		if want := "https://example.com/" + s.ID; s.Metadata["url"] != want {
+			t.Errorf("source %s: url = %v, want %s", s.ID, s.Metadata["url"], want)
+		}
+	}
+}
+
+func TestOrchestrator_Citations(t *testing.T) {
+	const answer = "PostgreSQL is a database [1] with vector search [2][1]."
+	var system string
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			system = req.SystemPrompt
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{llmlib.TextBlock(answer)}}, nil
+		},
+		ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+			chunks := make(chan llmlib.StreamChunk, 3)
+			errs := make(chan error, 1)
+			chunks <- llmlib.StreamChunk{Type: llmlib.ChunkText, Text: answer[:30]}
+			chunks <- llmlib.StreamChunk{Type: llmlib.ChunkText, Text: answer[30:]}
+			chunks <- llmlib.StreamChunk{Type: llmlib.ChunkDone}
+			close(chunks)
+			close(errs)
+			return &llmlib.Stream{Chunks: chunks, Err: errs}, nil
+		},
+	}
+	citations := true
+	orch := newTestOrchestrator([]database.SearchResult{
+		{ID: "doc-1", Content: "PostgreSQL is a database.", Score: 0.9,
+			SourceInfo: map[string]interface{}{"url": "https://example.com/1"}},
+		{ID: "doc-2", Content: "pgvector adds vector search.", Score: 0.8,
+			SourceInfo: map[string]interface{}{"url": "https://example.com/2"}},
+	}, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Citations = &citations
+		cfg.CompletionProv = completer
+	})
+
+	want := []Citation{
+		{Marker: 1, ID: "doc-1", Score: 0.9, Metadata: map[string]interface{}{"url": "https://example.com/1"}},
+		{Marker: 2, ID: "doc-2", Score: 0.8, Metadata: map[string]interface{}{"url": "https://example.com/2"}},
+	}
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "what is postgres"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(system, citationInstructions) {
+		t.Errorf("system prompt missing citation instructions:\n%s", system)
+	}
+	if resp.Answer != answer {
+		t.Errorf("answer was modified: %q", resp.Answer)
+	}
+	if !reflect.DeepEqual(resp.Citations, want) {
+		t.Errorf("Citations = %+v, want %+v", resp.Citations, want)
+	}
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "what is postgres"})
+	var final StreamChunk
+	for chunk := range chunks {
+		if chunk.FinishReason != "" {
+			final = chunk
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if !reflect.DeepEqual(final.Citations, want) {
+		t.Errorf("stream Citations = %+v, want %+v", final.Citations, want)
+	}
+}
+
+func TestOrchestrator_StreamSources(t *testing.T) {
+	orch := newTestOrchestrator([]database.SearchResult{
+		{ID: "doc-1", Content: "PostgreSQL is a database.", Score: 0.9},
+	})
+
+	for _, include := range []bool{true, false} {
+		chunks, errs := orch.ExecuteStream(context.Background(),
+			QueryRequest{Query: "what is postgres", IncludeSources: include})
+		var got []StreamChunk
+		for chunk := range chunks {
+			got = append(got, chunk)
+		}
+		if err := <-errs; err != nil {
+			t.Fatalf("include_sources=%v: unexpected stream error: %v", include, err)
+		}
+
+		var sourceChunks int
+		for _, chunk := range got {
+			if chunk.Sources != nil {
+				sourceChunks++
+			}
+		}
+		if !include {
+			if sourceChunks != 0 {
+				t.Errorf("expected no sources without include_sources, got %+v", got)
+			}
+			continue
+		}
+		if sourceChunks != 1 || len(got[0].Sources) != 1 || got[0].Sources[0].ID != "doc-1" ||
+			got[0].Content != "" {
+			t.Errorf("expected a single sources chunk before the answer, got %+v", got)
+		}
+	}
+}
+
+func TestOrchestrator_StageTimeouts(t *testing.T) {
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	newOrchestrator := func(pCfg config.Pipeline, embedder *MockEmbedder,
+		backend *MockSearchBackend, completer *MockCompleter) *Orchestrator {
+		return newTestOrchestrator(nil, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.EmbeddingTimeout = pCfg.EmbeddingTimeout
+			p.SearchTimeout = pCfg.SearchTimeout
+			p.CompletionTimeout = pCfg.CompletionTimeout
+			p.TotalTimeout = pCfg.TotalTimeout
+			cfg.DBPool = backend
+			cfg.EmbeddingProv = embedder
+			cfg.CompletionProv = completer
+		})
+	}
+	found := &MockSearchBackend{
+		VectorSearchFunc: func(
+			ctx context.Context, embedding []float32, table config.TableSource,
+			topN int, filter *config.Filter, minSimilarity *float64,
+		) ([]database.SearchResult, error) {
+			return []database.SearchResult{{ID: "doc-1", Content: "PostgreSQL is a database.", Score: 0.9}}, nil
+		},
+	}
+	hangingSearch := &MockSearchBackend{
+		VectorSearchFunc: func(
+			ctx context.Context, embedding []float32, table config.TableSource,
+			topN int, filter *config.Filter, minSimilarity *float64,
+		) ([]database.SearchResult, error) {
+			return nil, block(ctx)
+		},
+	}
+	hangingEmbedder := &MockEmbedder{
+		EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+			return nil, block(ctx)
+		},
+	}
+	hangingCompleter := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			return nil, block(ctx)
+		},
+		ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+			return nil, block(ctx)
+		},
+	}
+	timeout := config.Duration(20 * time.Millisecond)
+
+	for _, tt := range []struct {
+		name      string
+		pCfg      config.Pipeline
+		embedder  *MockEmbedder
+		backend   *MockSearchBackend
+		completer *MockCompleter
+		wantStage string
+	}{
+		{"embedding", config.Pipeline{EmbeddingTimeout: timeout},
+			hangingEmbedder, found, &MockCompleter{}, TimeoutStageEmbedding},
+		{"search", config.Pipeline{SearchTimeout: timeout},
+			&MockEmbedder{}, hangingSearch, &MockCompleter{}, TimeoutStageSearch},
+		{"completion", config.Pipeline{CompletionTimeout: timeout},
+			&MockEmbedder{}, found, hangingCompleter, TimeoutStageCompletion},
+		{"total", config.Pipeline{TotalTimeout: timeout, CompletionTimeout: config.Duration(time.Minute)},
+			&MockEmbedder{}, found, hangingCompleter, TimeoutStageTotal},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := newOrchestrator(tt.pCfg, tt.embedder, tt.backend, tt.completer)
+
+			_, err := orch.Execute(context.Background(), QueryRequest{Query: "what is postgres"})
+			var stageErr *StageTimeoutError
+			if !errors.As(err, &stageErr) || stageErr.Stage != tt.wantStage ||
+				!errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected a %s timeout, got %v", tt.wantStage, err)
+			}
+
+			chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "what is postgres"})
+			for range chunks {
+			}
+			if err := <-errs; !errors.As(err, &stageErr) || stageErr.Stage != tt.wantStage {
+				t.Errorf("expected a streamed %s timeout, got %v", tt.wantStage, err)
+			}
+		})
+	}
+}
+
+// TestNewBM25Index_UsesPipelineParams checks that the configured b takes
+// effect: with no length normalization a long document that repeats the
+// term outranks a short one, and with full normalization the short one
+// wins.
+func TestNewBM25Index_UsesPipelineParams(t *testing.T) {
+	docs := map[string]string{
+		"short": "postgres replication",
+		"long":  "postgres postgres postgres " + strings.Repeat("filler ", 30),
+		"other": "unrelated release notes",
+	}
+
+	for _, tt := range []struct {
+		b    float64
+		want string
+	}{
+		{b: 0, want: "long"},
+		{b: 1, want: "short"},
+	} {
+		b := tt.b
+		idx := newBM25Index(&config.Pipeline{BM25: config.BM25Config{B: &b}})
+		idx.AddDocuments(docs)
+
+		results := idx.Search("postgres", 2)
+		if len(results) != 2 || results[0].ID != tt.want {
+			t.Errorf("b=%v: expected %q first, got %+v", tt.b, tt.want, results)
+		}
+	}
+}
+
+func TestLexicalFields(t *testing.T) {
+	table := config.TableSource{
+		LexicalColumns: []config.LexicalColumn{{Column: "title", Boost: 3}, {Column: "headings"}},
+	}
+	doc := database.Document{Content: "body", Lexical: []string{"Replication", "Setup"}}
+
+	got := lexicalFields(table, doc)
+	want := []bm25.Field{
+		{Name: "title", Text: "Replication", Boost: 3},
+		{Name: "headings", Text: "Setup", Boost: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lexicalFields() = %+v, want %+v", got, want)
+	}
+
+	if got := lexicalFields(config.TableSource{}, doc); got != nil {
+		t.Errorf("expected no fields without lexical columns, got %+v", got)
+	}
+}
@@ -0,0 +1,208 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// expandParents widens the chunks a search of table matched into the
+// context its parent settings describe: their parent documents, or the
+// chunks around them. Matches without a parent ID, or whose parent or
+// siblings are not found, are left as they are. The added context is
+// held to the request's filter and the access control hook, as the
+// matches were. Expansion only adds context, so a failure to fetch it
+// is logged and the matches are returned unchanged.
+func (o *Orchestrator) expandParents(
+	ctx context.Context,
+	table config.TableSource,
+	filter *config.Filter,
+	results []database.SearchResult,
+) []database.SearchResult {
+	if !table.Parent.Enabled() || o.parents == nil || len(results) == 0 {
+		return results
+	}
+
+	var expanded []database.SearchResult
+	var err error
+	if table.Parent.Table != "" {
+		expanded, err = o.expandToParents(ctx, table, filter, results)
+	} else {
+		expanded, err = o.expandToSiblings(ctx, table, filter, results)
+	}
+	if err != nil {
+		o.logger.Warn("parent expansion failed, using matched chunks", "table", table.Table, "error", err)
+		return results
+	}
+	o.logger.Debug("expanded matched chunks", "table", table.Table, "results", len(results), "expanded", len(expanded))
+	return expanded
+}
+
+// parentID reads the ID of a matched chunk's parent from its metadata.
+func parentID(r database.SearchResult, column string) (string, bool) {
+	v, ok := r.SourceInfo[column]
+	if !ok || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+// expandToParents replaces each match with its parent document's text.
+// The best ranked match of a parent stands for it; the others are
+// dropped, so a document is given once however many of its chunks
+// matched. A match whose parent the caller may not see is left as it
+// is.
+func (o *Orchestrator) expandToParents(
+	ctx context.Context,
+	table config.TableSource,
+	filter *config.Filter,
+	results []database.SearchResult,
+) ([]database.SearchResult, error) {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, r := range results {
+		if id, ok := parentID(r, table.Parent.ParentIDColumn); ok && !seen[id] {
+			ids = append(ids, id)
+			seen[id] = true
+		}
+	}
+	if len(ids) == 0 {
+		return results, nil
+	}
+
+	parents, err := o.parents.FetchParents(ctx, table, ids, filter)
+	if err != nil {
+		return nil, err
+	}
+	if len(parents) > 0 {
+		found := make([]string, 0, len(parents))
+		for id := range parents {
+			found = append(found, id)
+		}
+		allowed, err := o.visible(ctx, table.Parent.Table, found)
+		if err != nil {
+			return nil, err
+		}
+		for id := range parents {
+			if allowed != nil && !allowed[id] {
+				delete(parents, id)
+			}
+		}
+	}
+
+	expanded := make([]database.SearchResult, 0, len(results))
+	used := make(map[string]bool)
+	for _, r := range results {
+		id, ok := parentID(r, table.Parent.ParentIDColumn)
+		content, found := parents[id]
+		if ok && found {
+			if used[id] {
+				continue
+			}
+			used[id] = true
+			r.Content = content
+		}
+		expanded = append(expanded, r)
+	}
+	return expanded, nil
+}
+
+// expandToSiblings replaces each match with the run of chunks of its
+// parent within parent.siblings positions of it, joined in order. A
+// match inside the run of a better ranked one is already given by it,
+// so it is dropped. Chunks the caller may not see are left out of the
+// run.
+func (o *Orchestrator) expandToSiblings(
+	ctx context.Context,
+	table config.TableSource,
+	filter *config.Filter,
+	results []database.SearchResult,
+) ([]database.SearchResult, error) {
+	window := int64(table.Parent.Siblings)
+	positions := make([]*database.ChunkPosition, len(results))
+	var matches []database.ChunkPosition
+	for i, r := range results {
+		id, ok := parentID(r, table.Parent.ParentIDColumn)
+		if !ok {
+			continue
+		}
+		index, ok := chunkIndex(r.SourceInfo[table.Parent.IndexColumn])
+		if !ok {
+			continue
+		}
+		positions[i] = &database.ChunkPosition{ParentID: id, Index: index}
+		matches = append(matches, *positions[i])
+	}
+	if len(matches) == 0 {
+		return results, nil
+	}
+
+	siblings, err := o.parents.FetchSiblings(ctx, table, matches, table.Parent.Siblings, filter)
+	if err != nil {
+		return nil, err
+	}
+	var allowed map[string]bool
+	if len(siblings) > 0 {
+		ids := make([]string, len(siblings))
+		for i, s := range siblings {
+			ids[i] = s.ID
+		}
+		if allowed, err = o.visible(ctx, table.Table, ids); err != nil {
+			return nil, err
+		}
+	}
+	byParent := make(map[string][]database.Sibling)
+	for _, s := range siblings {
+		if allowed != nil && !allowed[s.ID] {
+			continue
+		}
+		byParent[s.ParentID] = append(byParent[s.ParentID], s)
+	}
+
+	type run struct{ first, last int64 }
+	runs := make(map[string][]run)
+	expanded := make([]database.SearchResult, 0, len(results))
+	for i, r := range results {
+		pos := positions[i]
+		if pos == nil {
+			expanded = append(expanded, r)
+			continue
+		}
+		covered := false
+		for _, prev := range runs[pos.ParentID] {
+			covered = covered || (pos.Index >= prev.first && pos.Index <= prev.last)
+		}
+		if covered {
+			continue
+		}
+
+		content := ""
+		for _, s := range byParent[pos.ParentID] {
+			if s.Index < pos.Index-window || s.Index > pos.Index+window {
+				continue
+			}
+			if content == "" {
+				content = s.Content
+			} else {
+				content = joinChunks(content, s.Content)
+			}
+		}
+		if content != "" {
+			r.Content = content
+		}
+		runs[pos.ParentID] = append(runs[pos.ParentID], run{pos.Index - window, pos.Index + window})
+		expanded = append(expanded, r)
+	}
+	return expanded, nil
+}
@@ -0,0 +1,240 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// MockParentStore implements pipeline.ParentStore with a fixed set of
+// parent documents and chunks, recording what it is asked for. Chunks
+// whose IDs are in Unmatched are left out when a filter is given, as
+// though they did not match it.
+type MockParentStore struct {
+	Parents   map[string]string
+	Chunks    []database.Sibling
+	Unmatched []string
+	Err       error
+	IDs       []string
+	Matches   []database.ChunkPosition
+	Window    int
+	Filter    *config.Filter
+	Requests  int
+}
+
+func (m *MockParentStore) FetchParents(ctx context.Context, table config.TableSource, ids []string,
+	filter *config.Filter) (map[string]string, error) {
+	m.Requests++
+	m.IDs, m.Filter = ids, filter
+	found := make(map[string]string)
+	for _, id := range ids {
+		if content, ok := m.Parents[id]; ok {
+			found[id] = content
+		}
+	}
+	return found, m.Err
+}
+
+func (m *MockParentStore) FetchSiblings(ctx context.Context, table config.TableSource,
+	matches []database.ChunkPosition, window int, filter *config.Filter) ([]database.Sibling, error) {
+	m.Requests++
+	m.Matches, m.Window, m.Filter = matches, window, filter
+	var found []database.Sibling
+	for _, c := range m.Chunks {
+		if filter == nil || !slices.Contains(m.Unmatched, c.ID) {
+			found = append(found, c)
+		}
+	}
+	return found, m.Err
+}
+
+func TestExpandParents_ParentTable(t *testing.T) {
+	store := &MockParentStore{Parents: map[string]string{
+		"1": "The whole replication guide.",
+		"2": "The whole backup guide.",
+	}}
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &config.Pipeline{Name: "docs"}, Parents: store})
+	table := config.TableSource{
+		Table: "doc_chunks",
+		Parent: config.ParentConfig{
+			ParentIDColumn: "source_id",
+			Table:          "documents",
+			IDColumn:       "id",
+			TextColumn:     "body",
+		},
+	}
+
+	got := orch.expandParents(context.Background(), table, nil, []database.SearchResult{
+		chunkResult("a1", 1, 1, "Replication chunk.", 0.9),
+		chunkResult("b0", 2, 0, "Backup chunk.", 0.8),
+		chunkResult("a3", 1, 3, "Another replication chunk.", 0.7),
+		chunkResult("c0", 3, 0, "Orphaned chunk.", 0.6),
+		chunkResult("d0", nil, 0, "Chunk without a parent.", 0.5),
+	})
+	want := []database.SearchResult{
+		chunkResult("a1", 1, 1, "The whole replication guide.", 0.9),
+		chunkResult("b0", 2, 0, "The whole backup guide.", 0.8),
+		chunkResult("c0", 3, 0, "Orphaned chunk.", 0.6),
+		chunkResult("d0", nil, 0, "Chunk without a parent.", 0.5),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(store.IDs, []string{"1", "2", "3"}) {
+		t.Errorf("expected each parent fetched once, got %v", store.IDs)
+	}
+}
+
+func TestExpandParents_Siblings(t *testing.T) {
+	store := &MockParentStore{Chunks: []database.Sibling{
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 3}, Content: "Three."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 4}, Content: "Four. It is"},
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 5}, Content: "It is five."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 6}, Content: "Six."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "2", Index: 0}, Content: "Zero."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "2", Index: 1}, Content: "One."},
+	}}
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &config.Pipeline{Name: "docs"}, Parents: store})
+	table := config.TableSource{
+		Table: "doc_chunks",
+		Parent: config.ParentConfig{
+			ParentIDColumn: "source_id",
+			Siblings:       1,
+			IndexColumn:    "chunk_index",
+		},
+	}
+
+	got := orch.expandParents(context.Background(), table, nil, []database.SearchResult{
+		chunkResult("a4", 1, int32(4), "Four. It is", 0.9),
+		chunkResult("b0", 2, int32(0), "Zero.", 0.8),
+		chunkResult("a5", 1, int32(5), "It is five.", 0.7),
+		chunkResult("a9", 1, "x", "Unindexed.", 0.6),
+	})
+	want := []database.SearchResult{
+		chunkResult("a4", 1, int32(4), "Three. Four. It is five.", 0.9),
+		chunkResult("b0", 2, int32(0), "Zero. One.", 0.8),
+		chunkResult("a9", 1, "x", "Unindexed.", 0.6),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if len(store.Matches) != 3 || store.Window != 1 {
+		t.Errorf("expected the three indexed matches with a window of 1, got %v, %d", store.Matches, store.Window)
+	}
+}
+
+func TestExpandParents_FilterAndAccessControl(t *testing.T) {
+	filter := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "product", Operator: "=", Value: "spock"},
+	}}
+	siblings := config.TableSource{
+		Table: "doc_chunks",
+		Parent: config.ParentConfig{
+			ParentIDColumn: "source_id",
+			Siblings:       1,
+			IndexColumn:    "chunk_index",
+		},
+	}
+	parents := config.TableSource{
+		Table:  "doc_chunks",
+		Parent: config.ParentConfig{ParentIDColumn: "source_id", Table: "documents", TextColumn: "body"},
+	}
+	chunks := []database.Sibling{
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 3}, ID: "a3", Content: "Unfiltered."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 4}, ID: "a4", Content: "Four."},
+		{ChunkPosition: database.ChunkPosition{ParentID: "1", Index: 5}, ID: "a5", Content: "Hidden."},
+	}
+	ctx := database.WithFilterVars(context.Background(), func(config.FilterVariable) (string, bool) {
+		return "ann", true
+	})
+
+	tests := []struct {
+		name       string
+		table      config.TableSource
+		store      *MockParentStore
+		authorizer *stubAuthorizer
+		want       string
+	}{
+		{
+			name:  "sibling outside the filter",
+			table: siblings,
+			store: &MockParentStore{Chunks: chunks, Unmatched: []string{"a3"}},
+			want:  "Four. Hidden.",
+		},
+		{
+			name:       "sibling refused by access control",
+			table:      siblings,
+			store:      &MockParentStore{Chunks: chunks, Unmatched: []string{"a3"}},
+			authorizer: &stubAuthorizer{visible: []string{"a3", "a4"}},
+			want:       "Four.",
+		},
+		{
+			name:       "access control failing",
+			table:      siblings,
+			store:      &MockParentStore{Chunks: chunks},
+			authorizer: &stubAuthorizer{err: errors.New("callback down")},
+			want:       "Four.",
+		},
+		{
+			name:       "parent refused by access control",
+			table:      parents,
+			store:      &MockParentStore{Parents: map[string]string{"1": "The whole guide."}},
+			authorizer: &stubAuthorizer{visible: []string{"2"}},
+			want:       "Four.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := NewOrchestrator(OrchestratorConfig{Pipeline: &config.Pipeline{Name: "docs"}, Parents: tt.store})
+			if tt.authorizer != nil {
+				orch.cfg.AccessControl = config.AccessControlConfig{Principal: "user:{{header:X-User}}", Function: "visible_docs"}
+				orch.authorizer = tt.authorizer
+			}
+			got := orch.expandParents(ctx, tt.table, filter, []database.SearchResult{
+				chunkResult("a4", 1, int32(4), "Four.", 0.9),
+			})
+			if len(got) != 1 || got[0].Content != tt.want {
+				t.Errorf("got %+v, want content %q", got, tt.want)
+			}
+			if tt.store.Filter != filter {
+				t.Errorf("expected the request's filter passed to the store, got %+v", tt.store.Filter)
+			}
+		})
+	}
+}
+
+func TestExpandParents_KeepsMatches(t *testing.T) {
+	results := []database.SearchResult{chunkResult("a1", 1, 1, "Replication chunk.", 0.9)}
+	parentTable := config.TableSource{
+		Table:  "doc_chunks",
+		Parent: config.ParentConfig{ParentIDColumn: "source_id", Table: "documents", TextColumn: "body"},
+	}
+
+	failing := &MockParentStore{Err: errors.New("connection refused")}
+	orch := NewOrchestrator(OrchestratorConfig{Pipeline: &config.Pipeline{Name: "docs"}, Parents: failing})
+	if got := orch.expandParents(context.Background(), parentTable, nil, results); !reflect.DeepEqual(got, results) {
+		t.Errorf("expected the matches kept when expansion fails, got %+v", got)
+	}
+
+	unused := &MockParentStore{}
+	orch = NewOrchestrator(OrchestratorConfig{Pipeline: &config.Pipeline{Name: "docs"}, Parents: unused})
+	orch.expandParents(context.Background(), config.TableSource{Table: "doc_chunks"}, nil, results)
+	if unused.Requests != 0 {
+		t.Error("expected no fetch for a table without parent expansion")
+	}
+}
@@ -0,0 +1,71 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// DocumentProvenance identifies a document an answer was written from
+// and the version of it that was used. ContentHash is the SHA-256 of
+// the document's text as stored, before any truncation or
+// summarization to fit the token budget, as "sha256:" and hex digits,
+// so it can be checked against the table's text column later.
+type DocumentProvenance struct {
+	ID          string `json:"id,omitempty"`
+	ContentHash string `json:"content_hash"`
+}
+
+// AnswerSignature signs an answer with the server's key, so a
+// downstream system can verify it came from the server unmodified.
+// Payload is the signed JSON document, holding the answer, the
+// provenance of its documents, the model and the time it was signed;
+// Signature is the signature of Payload's bytes by the key KeyID names.
+// Both are base64url-encoded without padding.
+type AnswerSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// Answerer names the completion model that wrote an answer: rag_llm,
+// or the fallback that answered in its place.
+type Answerer struct {
+	Provider string
+	Model    string
+}
+
+// contentHash returns the SHA-256 of a document's content in the form
+// DocumentProvenance reports it.
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// provenance returns the provenance of the documents given to the
+// model, docs, built from results, one per document. The first
+// document is hashed from its result, since fitBudget may have replaced
+// its content with a summary.
+func provenance(results []database.SearchResult, docs []ragllm.ContextDoc) []DocumentProvenance {
+	if len(docs) == 0 {
+		return nil
+	}
+	prov := make([]DocumentProvenance, len(docs))
+	for i, d := range docs {
+		prov[i] = DocumentProvenance{ID: d.ID, ContentHash: d.ContentHash}
+	}
+	prov[0].ContentHash = contentHash(results[0].Content)
+	return prov
+}
@@ -0,0 +1,84 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestOrchestrator_Execute_Provenance(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+
+	ctx, rec := ContextWithReproduction(context.Background())
+	resp, err := orch.Execute(ctx, QueryRequest{Query: "streaming standby", TopN: 2, IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []DocumentProvenance{
+		{ID: "doc-1", ContentHash: sha256Hex("Streaming replication sends WAL to a standby.")},
+		{ID: "doc-2", ContentHash: sha256Hex("Logical replication publishes table changes.")},
+	}
+	if !slices.Equal(resp.Provenance, want) {
+		t.Errorf("expected provenance %+v, got %+v", want, resp.Provenance)
+	}
+	for i, s := range resp.Sources {
+		if s.ContentHash != sha256Hex(s.Content) {
+			t.Errorf("source %d: expected the hash of its content, got %q", i, s.ContentHash)
+		}
+	}
+	if !slices.Equal(rec.Provenance, want) {
+		t.Errorf("expected the provenance recorded, got %+v", rec.Provenance)
+	}
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "streaming standby", TopN: 2})
+	var streamed []DocumentProvenance
+	for chunk := range chunks {
+		if chunk.Provenance != nil {
+			streamed = chunk.Provenance
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(streamed, want) {
+		t.Errorf("expected provenance %+v on the final chunk, got %+v", want, streamed)
+	}
+}
+
+func TestOrchestrator_Execute_ProvenanceLeavesOutUnusedDocuments(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	// Room for the first document only: the rest of the budget is too
+	// small for a truncated second one.
+	orch.tokenBudget = orch.counter().Count("Streaming replication sends WAL to a standby.") + 1
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "streaming standby", TopN: 3, IncludeSources: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Sources) != 3 {
+		t.Fatalf("expected 3 sources, got %d", len(resp.Sources))
+	}
+	if len(resp.Provenance) != 1 || resp.Provenance[0].ID != "doc-1" {
+		t.Errorf("expected only the document given to the model, got %+v", resp.Provenance)
+	}
+}
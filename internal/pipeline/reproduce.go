@@ -0,0 +1,144 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"math/rand/v2"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// Reproduction records the inputs of a query's answer that vary from
+// one run to the next, so the answer can be reproduced, or a later one
+// compared with it. Repeating the query with the same seed, documents
+// and prompt gives the same answer as far as the provider's sampling is
+// deterministic.
+type Reproduction struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+
+	// Temperature is the sampling temperature sent to the provider;
+	// unset when the model's own default applies.
+	Temperature *float64 `json:"temperature,omitempty"`
+
+	// Seed is the sampling seed sent to the provider; unset when the
+	// provider takes none.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// DocumentIDs are the IDs of the documents the answer was written
+	// from, in the order they were given to the model.
+	DocumentIDs []string `json:"document_ids"`
+
+	// Scores are the documents' similarity scores, in the same order
+	// as DocumentIDs.
+	Scores []float64 `json:"scores,omitempty"`
+
+	// Provenance identifies the documents as given to the model, with
+	// the hash of each one's stored content.
+	Provenance []DocumentProvenance `json:"provenance,omitempty"`
+
+	// PromptHash is the SHA-256 of the chat request sent to the model:
+	// its system prompt, with the documents, and messages.
+	PromptHash string `json:"prompt_hash,omitempty"`
+
+	// Cached is set when the answer came from the answer cache, so no
+	// model was called.
+	Cached bool `json:"cached,omitempty"`
+}
+
+// reproductionKey is the context key under which a query's
+// Reproduction is recorded.
+type reproductionKey struct{}
+
+// ContextWithReproduction returns a copy of ctx that records the
+// reproducibility inputs of the query run with it in the returned
+// Reproduction. A query recorded without a seed of its own on a
+// provider that takes one is given a random seed, so it can be
+// repeated.
+func ContextWithReproduction(ctx context.Context) (context.Context, *Reproduction) {
+	rec := &Reproduction{DocumentIDs: []string{}}
+	return context.WithValue(ctx, reproductionKey{}, rec), rec
+}
+
+// reproductionFrom returns the Reproduction ctx records into, or nil.
+func reproductionFrom(ctx context.Context) *Reproduction {
+	rec, _ := ctx.Value(reproductionKey{}).(*Reproduction)
+	return rec
+}
+
+// seed returns the sampling seed of a query's answer: the request's,
+// or a random one when the query's inputs are being recorded.
+func (o *Orchestrator) seed(ctx context.Context, req QueryRequest) *int64 {
+	if req.Seed != nil || reproductionFrom(ctx) == nil || !ragllm.SupportsSeed(o.completionProvider()) {
+		return req.Seed
+	}
+	seed := rand.Int64N(math.MaxInt32)
+	return &seed
+}
+
+// withSeed attaches the request's seed, if any, to ctx for the
+// completion client to pick up.
+func (o *Orchestrator) withSeed(ctx context.Context, req QueryRequest) context.Context {
+	if req.Seed == nil {
+		return ctx
+	}
+	return ragllm.ContextWithSeed(ctx, *req.Seed)
+}
+
+// recordReproduction records the inputs of an answer generated from
+// docs, whose provenance is prov, with chatReq, when ctx records them.
+func (o *Orchestrator) recordReproduction(ctx context.Context, req QueryRequest,
+	docs []database.SearchResult, prov []DocumentProvenance, chatReq llmlib.ChatRequest) {
+	rec := reproductionFrom(ctx)
+	if rec == nil {
+		return
+	}
+	o.recordModel(rec)
+	rec.Seed = req.Seed
+	for _, d := range docs {
+		rec.DocumentIDs = append(rec.DocumentIDs, d.ID)
+		rec.Scores = append(rec.Scores, d.Score)
+	}
+	rec.Provenance = prov
+	if b, err := json.Marshal(chatReq); err == nil {
+		sum := sha256.Sum256(b)
+		rec.PromptHash = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	if chatReq.Temperature != nil {
+		rec.Temperature = chatReq.Temperature
+	}
+}
+
+// recordCached records that a query was answered from the answer
+// cache, when ctx records its inputs.
+func (o *Orchestrator) recordCached(ctx context.Context) {
+	if rec := reproductionFrom(ctx); rec != nil {
+		o.recordModel(rec)
+		rec.Cached = true
+	}
+}
+
+// recordModel records the pipeline's completion model.
+func (o *Orchestrator) recordModel(rec *Reproduction) {
+	if o.cfg == nil {
+		return
+	}
+	rec.Provider = o.completionProvider()
+	rec.Model = o.cfg.RAGLLM.Model
+	rec.Temperature = ragllm.Temperature(rec.Provider)
+}
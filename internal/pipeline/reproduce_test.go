@@ -0,0 +1,74 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestOrchestrator_Execute_RecordsReproduction(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.cfg.RAGLLM = config.LLMConfig{Provider: "openai", Model: "gpt-4o-mini"}
+
+	ctx, rec := ContextWithReproduction(context.Background())
+	if _, err := orch.Execute(ctx, QueryRequest{Query: "streaming standby", TopN: 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rec.Provider != "openai" || rec.Model != "gpt-4o-mini" || rec.Temperature == nil || *rec.Temperature != 0.7 {
+		t.Errorf("expected the model and its temperature, got %+v", rec)
+	}
+	if rec.Seed == nil {
+		t.Error("expected a seed to be chosen for a recorded query")
+	}
+	if !slices.Equal(rec.DocumentIDs, []string{"doc-1", "doc-2"}) {
+		t.Errorf("expected documents [doc-1 doc-2], got %v", rec.DocumentIDs)
+	}
+	if !slices.Equal(rec.Scores, []float64{0.9, 0.8}) {
+		t.Errorf("expected the documents' scores [0.9 0.8], got %v", rec.Scores)
+	}
+	if !strings.HasPrefix(rec.PromptHash, "sha256:") {
+		t.Errorf("expected a prompt hash, got %q", rec.PromptHash)
+	}
+
+	// Repeating the query with the recorded seed gives the same inputs.
+	ctx, again := ContextWithReproduction(context.Background())
+	if _, err := orch.Execute(ctx, QueryRequest{Query: "streaming standby", TopN: 2, Seed: rec.Seed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *again.Seed != *rec.Seed || again.PromptHash != rec.PromptHash ||
+		!slices.Equal(again.DocumentIDs, rec.DocumentIDs) {
+		t.Errorf("expected the same inputs, got %+v and %+v", rec, again)
+	}
+
+	// A query that is not recorded is not given a seed.
+	if got := orch.seed(context.Background(), QueryRequest{}); got != nil {
+		t.Errorf("expected no seed without a recording, got %d", *got)
+	}
+}
+
+func TestOrchestrator_Execute_SeedBypassesAnswerCache(t *testing.T) {
+	orch := NewOrchestrator(OrchestratorConfig{
+		Pipeline:       &config.Pipeline{Name: "docs", AnswerCache: config.AnswerCacheConfig{Enabled: true}},
+		CompletionProv: &MockCompleter{},
+	})
+	seed := int64(7)
+	if key := orch.answerCacheKey(context.Background(), QueryRequest{Query: "q", Seed: &seed}); key != "" {
+		t.Errorf("expected a query with a seed not to be cached, got key %q", key)
+	}
+	if key := orch.answerCacheKey(context.Background(), QueryRequest{Query: "q"}); key == "" {
+		t.Error("expected a query without a seed to be cached")
+	}
+}
@@ -0,0 +1,171 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// RetrieveRequest asks for the documents a query would retrieve,
+// without generating an answer. Its shape follows the retriever
+// interfaces of frameworks such as LangChain and LlamaIndex.
+type RetrieveRequest struct {
+	Query        string         `json:"query"`
+	K            int            `json:"k,omitempty"`             // Override default top-N results
+	Filter       *config.Filter `json:"filter,omitempty"`        // Structured filter, as on a query
+	FilterPreset string         `json:"filter_preset,omitempty"` // A pipeline filter preset, as on a query
+}
+
+// RetrieveResponse lists the retrieved documents, best first, with the
+// tokens spent retrieving them.
+type RetrieveResponse struct {
+	Documents []RetrievedDocument `json:"documents"`
+	Usage     *StageUsage         `json:"usage"`
+}
+
+// RetrievedDocument is a retrieved chunk. PageContent and Metadata
+// use LangChain's Document field names.
+type RetrievedDocument struct {
+	ID          string                 `json:"id,omitempty"`
+	PageContent string                 `json:"page_content"`
+	Metadata    map[string]interface{} `json:"metadata"` // The table's metadata_columns
+	Score       float64                `json:"score"`
+}
+
+// SearchRequest asks for the documents a query would retrieve, without
+// generating an answer, in the terms of a query request.
+type SearchRequest struct {
+	Query          string         `json:"query"`
+	TopN           int            `json:"top_n,omitempty"`         // Override default top-N results
+	Filter         *config.Filter `json:"filter,omitempty"`        // Structured filter, as on a query
+	FilterPreset   string         `json:"filter_preset,omitempty"` // A pipeline filter preset, as on a query
+	IncludeTimings bool           `json:"include_timings"`         // Include stage Timings (default: false)
+}
+
+// SearchResponse lists the retrieved documents, best first, as a
+// query's sources, with the tokens spent retrieving them.
+type SearchResponse struct {
+	Results []Source    `json:"results"`
+	Usage   *StageUsage `json:"usage"`
+	Timings *Timings    `json:"timings,omitempty"`
+}
+
+// Retrieve runs the pipeline's retrieval and reranking for a query and
+// returns at most k documents, with the tokens the embedding, retrieval
+// strategy and reranking used, skipping context building and
+// completion.
+func (o *Orchestrator) Retrieve(ctx context.Context, req RetrieveRequest) (*RetrieveResponse, error) {
+	if req.K < 0 {
+		return nil, fmt.Errorf("%w: k must not be negative", ErrInvalidRequest)
+	}
+	results, usage, err := o.retrieveOnly(ctx, req.Query, req.K, req.Filter, req.FilterPreset, newQueryTimer())
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]RetrievedDocument, len(results))
+	for i, r := range results {
+		metadata := r.SourceInfo
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		docs[i] = RetrievedDocument{
+			ID:          r.ID,
+			PageContent: r.Content,
+			Metadata:    metadata,
+			Score:       r.Score,
+		}
+	}
+	return &RetrieveResponse{Documents: docs, Usage: usage}, nil
+}
+
+// Search runs the pipeline's retrieval and reranking for a query, as
+// Retrieve does, and returns at most top_n documents as a query's
+// sources, with the tokens the embedding and reranking used.
+func (o *Orchestrator) Search(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	if req.TopN < 0 {
+		return nil, fmt.Errorf("%w: top_n must not be negative", ErrInvalidRequest)
+	}
+	timer := newQueryTimer()
+	results, usage, err := o.retrieveOnly(ctx, req.Query, req.TopN, req.Filter, req.FilterPreset, timer)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResponse{
+		Results: o.buildSources(results),
+		Usage:   usage,
+		Timings: timer.result(QueryRequest{IncludeTimings: req.IncludeTimings}),
+	}, nil
+}
+
+// retrieveOnly embeds a query, searches, filters and reranks as a
+// query would, returning at most k results (the pipeline's top_n when
+// k is zero) and the tokens used. It records the embedding and search
+// times in timer.
+func (o *Orchestrator) retrieveOnly(
+	ctx context.Context,
+	query string,
+	k int,
+	filter *config.Filter,
+	filterPreset string,
+	timer *queryTimer,
+) ([]database.SearchResult, *StageUsage, error) {
+	if query == "" {
+		return nil, nil, fmt.Errorf("%w: query is required", ErrInvalidRequest)
+	}
+	if err := o.checkFilter(filter); err != nil {
+		return nil, nil, err
+	}
+	if err := o.checkFilterVars(ctx); err != nil {
+		return nil, nil, err
+	}
+	filter, err := o.applyFilterPreset(filterPreset, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.checkBudget(); err != nil {
+		return nil, nil, err
+	}
+
+	if k == 0 {
+		k = o.topN
+	}
+
+	ctx, cancel := withStageTimeout(ctx, TimeoutStageTotal, time.Duration(o.cfg.TotalTimeout))
+	defer cancel()
+
+	usage := &StageUsage{}
+	req := QueryRequest{Query: o.rewriteQuery(ctx, query), TopN: k, Filter: filter}
+	stageStart := time.Now()
+	embedding, err := o.embedWithTimeout(ctx, req.Query, usage)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	timer.timings.EmbedMS = since(stageStart)
+
+	stageStart = time.Now()
+	results, err := o.retrieve(ctx, req, embedding, k, usage)
+	if err != nil {
+		return nil, nil, err
+	}
+	timer.timings.SearchMS = since(stageStart)
+	results = o.filterResults(ctx, query, results)
+	results = o.rerank(ctx, query, results, usage)
+	o.chargeCost(usage)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, usage, nil
+}
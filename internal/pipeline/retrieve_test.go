@@ -0,0 +1,206 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func newRetrieveOrchestrator(reranker Reranker, gotFilter **config.Filter,
+	opts ...testOrchestratorOption) *Orchestrator {
+	retrieve := func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		cfg.DBPool = &MockSearchBackend{
+			VectorSearchFunc: func(
+				ctx context.Context, embedding []float32, table config.TableSource,
+				topN int, filter *config.Filter, minSimilarity *float64,
+			) ([]database.SearchResult, error) {
+				*gotFilter = filter
+				return []database.SearchResult{
+					{ID: "doc-1", Content: "Streaming replication sends WAL to a standby.", Score: 0.9,
+						SourceInfo: map[string]interface{}{"title": "Replication"}},
+					{ID: "doc-2", Content: "Logical replication publishes table changes.", Score: 0.8},
+					{ID: "doc-3", Content: "Vacuum reclaims storage from dead tuples.", Score: 0.4},
+				}, nil
+			},
+		}
+		cfg.Reranker = reranker
+	}
+	return newTestOrchestrator(nil, append([]testOrchestratorOption{retrieve}, opts...)...)
+}
+
+func TestOrchestrator_Retrieve(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+
+	filter := &config.Filter{Conditions: []config.FilterCondition{
+		{Column: "product", Operator: "=", Value: "pgEdge"},
+	}}
+	resp, err := orch.Retrieve(context.Background(), RetrieveRequest{
+		Query:  "streaming standby",
+		K:      2,
+		Filter: filter,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotFilter == nil || len(gotFilter.Conditions) != 1 || gotFilter.Conditions[0].Column != "product" {
+		t.Errorf("search got filter %+v, want the request's", gotFilter)
+	}
+	if len(resp.Documents) != 2 {
+		t.Fatalf("expected 2 documents, got %+v", resp.Documents)
+	}
+	first := resp.Documents[0]
+	if first.ID != "doc-1" || first.PageContent == "" || first.Score != 0.9 ||
+		first.Metadata["title"] != "Replication" {
+		t.Errorf("unexpected first document: %+v", first)
+	}
+	if resp.Documents[1].Metadata == nil {
+		t.Error("expected empty metadata, not nil, for a document without metadata")
+	}
+	if resp.Usage == nil || resp.Usage.Completion.TotalTokens != 0 {
+		t.Errorf("expected usage without completion tokens, got %+v", resp.Usage)
+	}
+
+	if _, err := orch.Retrieve(context.Background(), RetrieveRequest{K: 2}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest without a query, got %v", err)
+	}
+	if _, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "q", K: -1}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for a negative k, got %v", err)
+	}
+}
+
+func TestOrchestrator_Search(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+
+	resp, err := orch.Search(context.Background(), SearchRequest{
+		Query:          "streaming standby",
+		TopN:           2,
+		IncludeTimings: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", resp.Results)
+	}
+	first := resp.Results[0]
+	if first.ID != "doc-1" || first.Content == "" || first.Score != 0.9 || first.Metadata["title"] != "Replication" {
+		t.Errorf("unexpected first result: %+v", first)
+	}
+	if resp.Usage == nil || resp.Usage.Completion.TotalTokens != 0 {
+		t.Errorf("expected usage without completion tokens, got %+v", resp.Usage)
+	}
+	if resp.Timings == nil {
+		t.Error("expected timings when asked for")
+	}
+
+	resp, err = orch.Search(context.Background(), SearchRequest{Query: "streaming standby"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Results) != 3 || resp.Timings != nil {
+		t.Errorf("expected every result within top_n and no timings, got %+v", resp)
+	}
+
+	if _, err := orch.Search(context.Background(), SearchRequest{Query: "q", TopN: -1}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected ErrInvalidRequest for a negative top_n, got %v", err)
+	}
+}
+
+func TestOrchestrator_Retrieve_Rerank(t *testing.T) {
+	reranker := &MockReranker{
+		RerankFunc: func(ctx context.Context, req llmlib.RerankRequest) (*llmlib.RerankResponse, error) {
+			// Reverse the order.
+			results := make([]llmlib.RerankResult, len(req.Documents))
+			for i := range req.Documents {
+				results[i] = llmlib.RerankResult{Index: len(req.Documents) - 1 - i, RelevanceScore: 0.5}
+			}
+			return &llmlib.RerankResponse{Results: results}, nil
+		},
+	}
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(reranker, &gotFilter)
+
+	resp, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "vacuum"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Documents) != 3 || resp.Documents[0].ID != "doc-3" || resp.Documents[0].Score != 0.5 {
+		t.Errorf("expected the reranked order with reranker scores, got %+v", resp.Documents)
+	}
+}
+
+func TestOrchestrator_Retrieve_FilterPreset(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	preset := config.Filter{Conditions: []config.FilterCondition{{Column: "version", Operator: "=", Value: "17"}}}
+	orch.cfg.FilterPresets = map[string]config.Filter{"v17-docs": preset}
+
+	if _, err := orch.Retrieve(context.Background(), RetrieveRequest{
+		Query:        "streaming standby",
+		FilterPreset: "v17-docs",
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotFilter == nil || !reflect.DeepEqual(*gotFilter, preset) {
+		t.Errorf("expected the preset to be searched with, got %+v", gotFilter)
+	}
+
+	_, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "standby", FilterPreset: "v16-docs"})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected an unknown preset to be rejected, got %v", err)
+	}
+}
+
+func TestOrchestrator_Retrieve_FilterVars(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.cfg.Tables[0].Filter = &config.ConfigFilter{RawSQL: "tenant_id = {{header:X-Tenant}}"}
+
+	_, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "streaming standby"})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("expected ErrInvalidRequest without the tenant header, got %v", err)
+	}
+
+	ctx := database.WithFilterVars(context.Background(), func(v config.FilterVariable) (string, bool) {
+		return "acme", v.String() == "header:X-Tenant"
+	})
+	if _, err := orch.Retrieve(ctx, RetrieveRequest{Query: "streaming standby"}); err != nil {
+		t.Errorf("unexpected error with the tenant header set: %v", err)
+	}
+}
+
+func TestOrchestrator_Retrieve_Tenant(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	orch.cfg.Tenant = config.TenantConfig{Column: "tenant_id", Header: "X-Tenant-ID"}
+
+	_, err := orch.Retrieve(context.Background(), RetrieveRequest{Query: "streaming standby"})
+	if !errors.Is(err, ErrInvalidRequest) || !strings.Contains(err.Error(), "header:X-Tenant-ID") {
+		t.Fatalf("expected ErrInvalidRequest naming the tenant header, got %v", err)
+	}
+
+	ctx := database.WithFilterVars(context.Background(), func(v config.FilterVariable) (string, bool) {
+		return "acme", v.String() == "header:X-Tenant-ID"
+	})
+	if _, err := orch.Retrieve(ctx, RetrieveRequest{Query: "streaming standby"}); err != nil {
+		t.Errorf("unexpected error with the tenant header set: %v", err)
+	}
+}
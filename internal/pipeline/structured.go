@@ -0,0 +1,134 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// respondTool is the tool an Anthropic model is made to call with a
+// structured answer, as the tool's input, since Anthropic has no
+// structured-output mode of its own.
+const respondTool = "respond"
+
+// structuredRetryPrompt asks the model to correct an answer that does
+// not match the request's schema.
+const structuredRetryPrompt = "Your answer does not match the required JSON schema:\n%s\n" +
+	"Answer again with only a JSON document that matches it."
+
+// checkResponseFormat rejects a response_format the pipeline cannot
+// honour: one that is not a JSON schema of an object the answer can be
+// checked against, or any for a provider with no structured output.
+func (o *Orchestrator) checkResponseFormat(rf *ResponseFormat) error {
+	if rf == nil {
+		return nil
+	}
+	if rf.Type != ResponseFormatJSONSchema {
+		return fmt.Errorf("%w: response_format.type must be %q", ErrInvalidRequest, ResponseFormatJSONSchema)
+	}
+	schema, err := parseJSONSchema(rf.JSONSchema)
+	if err != nil {
+		return fmt.Errorf("%w: response_format.json_schema: %v", ErrInvalidRequest, err)
+	}
+	if !slices.Equal(schema.Type, schemaTypes{"object"}) {
+		return fmt.Errorf("%w: response_format.json_schema must have type \"object\"", ErrInvalidRequest)
+	}
+	if o.completionProvider() == ragllm.ProviderBedrock {
+		return fmt.Errorf("%w: response_format is not supported by the bedrock provider", ErrInvalidRequest)
+	}
+	return nil
+}
+
+// chatStructured asks the completion provider for chatReq's answer as
+// a JSON document matching the request's response_format, which
+// checkResponseFormat has accepted. OpenAI, Ollama, Gemini and Cohere
+// are given the schema as their structured-output format; Anthropic
+// models are made to call respondTool, whose input schema it is. An
+// answer that does not match the schema is sent back to the model with
+// what is wrong, once; if the second does not match either, the query
+// fails.
+// The response's usage is that of both attempts together.
+func (o *Orchestrator) chatStructured(ctx context.Context, req QueryRequest,
+	chatReq llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+	schema, err := parseJSONSchema(req.ResponseFormat.JSONSchema)
+	if err != nil {
+		return nil, err
+	}
+	if o.completionProvider() == ragllm.ProviderAnthropic {
+		chatReq.Tools = []llmlib.Tool{{
+			Name:        respondTool,
+			Description: "Give the answer to the user's question.",
+			InputSchema: req.ResponseFormat.JSONSchema,
+		}}
+		chatReq.ToolChoice = &llmlib.ToolChoice{Mode: llmlib.ToolChoiceSpecific, Name: respondTool}
+	} else {
+		chatReq.ResponseFormat = &llmlib.ResponseFormat{
+			Type:       llmlib.ResponseFormatJSONSchema,
+			JSONSchema: req.ResponseFormat.JSONSchema,
+		}
+	}
+	chatReq.Messages = slices.Clone(chatReq.Messages)
+
+	var usage llmlib.TokenUsage
+	for retried := false; ; retried = true {
+		resp, err := o.completionProv.Chat(ctx, chatReq)
+		if err != nil {
+			return nil, err
+		}
+		usage.Add(resp.Usage)
+
+		answer, call := structuredAnswer(resp)
+		problems := schema.validate([]byte(answer))
+		if len(problems) == 0 {
+			resp.Content = []llmlib.ContentBlock{llmlib.TextBlock(answer)}
+			resp.Usage = usage
+			return resp, nil
+		}
+		if retried {
+			return nil, fmt.Errorf("the answer does not match response_format.json_schema: %s",
+				strings.Join(problems, "; "))
+		}
+
+		o.logger.Info("answer does not match response_format.json_schema, asking again",
+			"problems", len(problems))
+		correction := fmt.Sprintf(structuredRetryPrompt, strings.Join(problems, "\n"))
+		chatReq.Messages = append(chatReq.Messages, llmlib.AssistantBlocks(resp.Content...))
+		if call != nil {
+			chatReq.Messages = append(chatReq.Messages, llmlib.ToolResultMessage(call.ID, correction, true))
+		} else {
+			chatReq.Messages = append(chatReq.Messages, llmlib.UserText(correction))
+		}
+	}
+}
+
+// structuredAnswer returns the JSON document in a structured answer:
+// the input of its call of respondTool, which is returned too, or else
+// its text, without the code fence models sometimes wrap it in.
+func structuredAnswer(resp *llmlib.ChatResponse) (string, *llmlib.ToolUse) {
+	for _, block := range resp.Content {
+		if block.Type == llmlib.BlockToolUse && block.ToolUse != nil && block.ToolUse.Name == respondTool {
+			return string(block.ToolUse.Input), block.ToolUse
+		}
+	}
+	text := strings.TrimSpace(joinTextBlocks(resp.Content))
+	if fenced, ok := strings.CutPrefix(text, "```"); ok {
+		if _, body, ok := strings.Cut(fenced, "\n"); ok {
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "```"))
+		}
+	}
+	return text, nil
+}
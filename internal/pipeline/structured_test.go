@@ -0,0 +1,210 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+const testAnswerSchema = `{"type":"object","properties":{"answer":{"type":"string"}},` +
+	`"required":["answer"],"additionalProperties":false}`
+
+func testResponseFormat() *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONSchema, JSONSchema: json.RawMessage(testAnswerSchema)}
+}
+
+// newStructuredOrchestrator returns an orchestrator of the given
+// completion provider whose search finds one document for "q".
+func newStructuredOrchestrator(completer *MockCompleter, provider string) *Orchestrator {
+	return newMultiQueryOrchestrator(completer, map[string][]string{"q": {"doc-a"}}, 0,
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			p.Retrieval.Strategy = ""
+			p.RAGLLM.Provider = provider
+		})
+}
+
+func textResponse(text string) *llmlib.ChatResponse {
+	return &llmlib.ChatResponse{
+		Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: text}},
+		Usage:   llmlib.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+	}
+}
+
+func TestOrchestrator_ResponseFormat(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			return textResponse("```json\n{\"answer\": \"yes\"}\n```"), nil
+		},
+	}
+	orch := newStructuredOrchestrator(completer, "openai")
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "q", ResponseFormat: testResponseFormat()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Answer != `{"answer": "yes"}` {
+		t.Errorf("unexpected answer: %q", resp.Answer)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	rf := requests[0].ResponseFormat
+	if rf == nil || rf.Type != llmlib.ResponseFormatJSONSchema || string(rf.JSONSchema) != testAnswerSchema {
+		t.Errorf("unexpected response format: %+v", rf)
+	}
+	if len(requests[0].Tools) != 0 {
+		t.Errorf("expected no tools, got %+v", requests[0].Tools)
+	}
+}
+
+func TestOrchestrator_ResponseFormat_Anthropic(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{
+				Type:    llmlib.BlockToolUse,
+				ToolUse: &llmlib.ToolUse{ID: "call-1", Name: respondTool, Input: json.RawMessage(`{"answer":"yes"}`)},
+			}}}, nil
+		},
+	}
+	orch := newStructuredOrchestrator(completer, "Anthropic")
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "q", ResponseFormat: testResponseFormat()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Answer != `{"answer":"yes"}` {
+		t.Errorf("unexpected answer: %q", resp.Answer)
+	}
+	req := requests[0]
+	if req.ResponseFormat != nil {
+		t.Errorf("expected no response format, got %+v", req.ResponseFormat)
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Name != respondTool || string(req.Tools[0].InputSchema) != testAnswerSchema {
+		t.Errorf("unexpected tools: %+v", req.Tools)
+	}
+	if req.ToolChoice == nil || req.ToolChoice.Mode != llmlib.ToolChoiceSpecific || req.ToolChoice.Name != respondTool {
+		t.Errorf("unexpected tool choice: %+v", req.ToolChoice)
+	}
+}
+
+func TestOrchestrator_ResponseFormat_Retry(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers []string
+		wantErr bool
+	}{
+		{"valid on retry", []string{`{"answer":1}`, `{"answer":"yes"}`}, false},
+		{"invalid twice", []string{`{"answer":1}`, `not JSON`}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []llmlib.ChatRequest
+			completer := &MockCompleter{
+				ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+					requests = append(requests, req)
+					return textResponse(tt.answers[len(requests)-1]), nil
+				},
+			}
+			orch := newStructuredOrchestrator(completer, "openai")
+
+			resp, err := orch.Execute(context.Background(), QueryRequest{Query: "q", ResponseFormat: testResponseFormat()})
+			if len(requests) != 2 {
+				t.Fatalf("expected 2 requests, got %d", len(requests))
+			}
+			retry := requests[1].Messages
+			last := retry[len(retry)-1].Content[0].Text
+			if !strings.Contains(last, "$.answer: must be of type string") {
+				t.Errorf("expected the retry to say what was wrong, got %q", last)
+			}
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "does not match response_format.json_schema") {
+					t.Errorf("expected a schema error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Answer != `{"answer":"yes"}` {
+				t.Errorf("unexpected answer: %q", resp.Answer)
+			}
+			if resp.Usage.Completion.TotalTokens != 30 {
+				t.Errorf("expected both attempts' usage, got %+v", resp.Usage.Completion)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_ResponseFormat_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		rf       *ResponseFormat
+		wantErr  string
+	}{
+		{"wrong type", "openai", &ResponseFormat{Type: "json_object"}, "response_format.type must be"},
+		{"bad schema", "openai", &ResponseFormat{Type: ResponseFormatJSONSchema, JSONSchema: json.RawMessage(`[]`)},
+			"response_format.json_schema: must be a JSON object"},
+		{"not an object", "openai",
+			&ResponseFormat{Type: ResponseFormatJSONSchema, JSONSchema: json.RawMessage(`{"type":"array"}`)},
+			`must have type "object"`},
+		{"bedrock", "bedrock", testResponseFormat(), "not supported by the bedrock provider"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orch := newStructuredOrchestrator(&MockCompleter{}, tt.provider)
+			_, err := orch.Execute(context.Background(), QueryRequest{Query: "q", ResponseFormat: tt.rf})
+			if !errors.Is(err, ErrInvalidRequest) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an invalid request error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOrchestrator_ResponseFormat_Stream(t *testing.T) {
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			return textResponse(`{"answer":"yes"}`), nil
+		},
+		ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+			t.Error("expected a structured answer not to be streamed")
+			return nil, errors.New("unexpected stream")
+		},
+	}
+	orch := newStructuredOrchestrator(completer, "openai")
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{Query: "q", ResponseFormat: testResponseFormat()})
+	var content []string
+	for c := range chunks {
+		if c.Content != "" {
+			content = append(content, c.Content)
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(content) != 1 || content[0] != `{"answer":"yes"}` {
+		t.Errorf("expected the answer in one chunk, got %q", content)
+	}
+}
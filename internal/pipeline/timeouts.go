@@ -0,0 +1,69 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stages reported by StageTimeoutError, matching the pipeline's
+// <stage>_timeout settings.
+const (
+	TimeoutStageEmbedding  = "embedding"
+	TimeoutStageSearch     = "search"
+	TimeoutStageCompletion = "completion"
+	TimeoutStageTotal      = "total"
+)
+
+// StageTimeoutError is returned (wrapped) when a query stage runs past
+// the pipeline's timeout for it. It matches context.DeadlineExceeded.
+type StageTimeoutError struct {
+	Stage   string // One of the TimeoutStage constants
+	Timeout time.Duration
+}
+
+func (e *StageTimeoutError) Error() string {
+	if e.Stage == TimeoutStageTotal {
+		return fmt.Sprintf("query timed out after %s", e.Timeout)
+	}
+	return fmt.Sprintf("%s stage timed out after %s", e.Stage, e.Timeout)
+}
+
+func (e *StageTimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// withStageTimeout bounds ctx by timeout when it is positive, recording
+// the stage as the cause so stageTimeout can report it.
+func withStageTimeout(
+	ctx context.Context,
+	stage string,
+	timeout time.Duration,
+) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, timeout, &StageTimeoutError{Stage: stage, Timeout: timeout})
+}
+
+// stageTimeout returns the *StageTimeoutError that ended ctx, if a
+// pipeline timeout ended it, or err otherwise. A stage context derives
+// from the total one, so an expired total_timeout is reported as such
+// whichever stage was running.
+func stageTimeout(ctx context.Context, err error) error {
+	var timeout *StageTimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return timeout
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import "time"
+
+// queryTimer times the stages of one query for its Timings.
+type queryTimer struct {
+	start    time.Time
+	timings  Timings
+	sentText bool
+}
+
+func newQueryTimer() *queryTimer {
+	return &queryTimer{start: time.Now()}
+}
+
+// since returns the milliseconds elapsed since start.
+func since(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+// firstByte records the time until a streamed query sends its first
+// answer text, in its Timings and the pipeline's metrics. Only the
+// first call counts.
+func (t *queryTimer) firstByte(o *Orchestrator) {
+	if t.sentText {
+		return
+	}
+	t.sentText = true
+	d := time.Since(t.start)
+	ms := d.Milliseconds()
+	t.timings.TTFBMS = &ms
+	o.metrics.ObserveFirstByte(o.pipelineName(), d)
+}
+
+// result returns the query's Timings for its response, or nil when the
+// request did not ask for them.
+func (t *queryTimer) result(req QueryRequest) *Timings {
+	if !req.IncludeTimings {
+		return nil
+	}
+	timings := t.timings
+	timings.TotalMS = since(t.start)
+	return &timings
+}
@@ -0,0 +1,87 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
+)
+
+// newTimingsOrchestrator returns an orchestrator whose embedding takes
+// at least 20ms, recording into reg.
+func newTimingsOrchestrator(reg *metrics.Registry) *Orchestrator {
+	orch, _ := newGuardrailsOrchestrator(config.GuardrailsConfig{}, "WAL is streamed to the standby.", "",
+		func(p *config.Pipeline, cfg *OrchestratorConfig) {
+			cfg.EmbeddingProv = &MockEmbedder{
+				EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+					time.Sleep(20 * time.Millisecond)
+					return []float64{0.1, 0.2, 0.3}, nil
+				},
+			}
+			cfg.Metrics = reg
+		})
+	return orch
+}
+
+func TestOrchestrator_Execute_Timings(t *testing.T) {
+	orch := newTimingsOrchestrator(nil)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Timings != nil {
+		t.Errorf("expected no timings unless asked for, got %+v", resp.Timings)
+	}
+
+	resp, err = orch.Execute(context.Background(), QueryRequest{Query: "how does replication work?", IncludeTimings: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tm := resp.Timings
+	if tm == nil || tm.EmbedMS < 20 || tm.TotalMS < tm.EmbedMS+tm.SearchMS || tm.TTFBMS != nil {
+		t.Errorf("unexpected timings: %+v", tm)
+	}
+}
+
+func TestOrchestrator_ExecuteStream_Timings(t *testing.T) {
+	reg := metrics.NewRegistry()
+	orch := newTimingsOrchestrator(reg)
+
+	chunks, errs := orch.ExecuteStream(context.Background(),
+		QueryRequest{Query: "how does replication work?", IncludeTimings: true})
+	var final StreamChunk
+	for chunk := range chunks {
+		if chunk.FinishReason != "" {
+			final = chunk
+		}
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tm := final.Timings
+	if tm == nil || tm.TTFBMS == nil || *tm.TTFBMS < tm.EmbedMS || tm.TotalMS < *tm.TTFBMS {
+		t.Fatalf("unexpected timings: %+v", tm)
+	}
+
+	var buf strings.Builder
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+	if want := `pgedge_rag_time_to_first_byte_seconds_count{pipeline="docs"} 1`; !strings.Contains(buf.String(), want) {
+		t.Errorf("metrics missing %q\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,246 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// DefaultToolRounds is the number of rounds of tool calls a query may
+// make when tools.max_rounds is unset.
+const DefaultToolRounds = 2
+
+// noFurtherDocuments is the search tool's result when it finds nothing
+// the model has not already been shown.
+const noFurtherDocuments = "No further relevant documents were found."
+
+// builtinTools are the definitions the completion model is given of
+// the tools tools.builtin names.
+var builtinTools = map[string]llmlib.Tool{
+	config.ToolSearch: {
+		Name: config.ToolSearch,
+		Description: "Search the knowledge base for more documents. Use it when the documents " +
+			"you were given do not answer the question, with a query for the missing " +
+			"information. The documents found are numbered on from the ones you have, " +
+			"and may be cited the same way.",
+		InputSchema: json.RawMessage(`{"type":"object","properties":{"query":{"type":"string",` +
+			`"description":"What to search for, as a question or keywords"}},"required":["query"]}`),
+	},
+}
+
+// toolRun is what a query's tool calls have added to its context: the
+// retrieved results and the context documents built from them, which
+// remain a prefix of the results as citations need.
+type toolRun struct {
+	req     QueryRequest
+	topN    int
+	usage   *StageUsage
+	results []database.SearchResult
+	docs    []ragllm.ContextDoc
+}
+
+// newToolRun starts a tool run from a query's retrieved results and the
+// context documents built from contextResults, moving those results to
+// the front so the documents are a prefix of them.
+func newToolRun(req QueryRequest, topN int, usage *StageUsage, results, contextResults []database.SearchResult,
+	docs []ragllm.ContextDoc) *toolRun {
+	run := &toolRun{req: req, topN: topN, usage: usage, results: results}
+	run.add(contextResults, docs)
+	return run
+}
+
+// contextResults returns the results the context documents were built
+// from.
+func (r *toolRun) contextResults() []database.SearchResult {
+	return r.results[:len(r.docs)]
+}
+
+// toolsEnabled reports whether the pipeline gives the completion model
+// tools to call.
+func (o *Orchestrator) toolsEnabled() bool {
+	return o.cfg != nil && len(o.cfg.Tools.Builtin) > 0
+}
+
+// toolRounds returns the number of rounds of tool calls a query may
+// make.
+func (o *Orchestrator) toolRounds() int {
+	if o.cfg.Tools.MaxRounds > 0 {
+		return o.cfg.Tools.MaxRounds
+	}
+	return DefaultToolRounds
+}
+
+// chatWithTools asks the completion provider for chatReq's answer,
+// giving it the pipeline's tools. Whenever the model calls tools
+// instead of answering, they are run and it is asked again with their
+// results, until it answers; in the last of the allowed rounds it is
+// told not to call any more. The response's usage is that of every
+// round together.
+//
+// A tool that fails reports the error to the model, which may answer
+// without it; only a timeout fails the query.
+func (o *Orchestrator) chatWithTools(ctx context.Context, run *toolRun,
+	chatReq llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+	for _, name := range o.cfg.Tools.Builtin {
+		chatReq.Tools = append(chatReq.Tools, builtinTools[name])
+	}
+	chatReq.Messages = slices.Clone(chatReq.Messages)
+
+	var usage llmlib.TokenUsage
+	rounds := o.toolRounds()
+	for round := 0; ; round++ {
+		if round == rounds {
+			chatReq.ToolChoice = &llmlib.ToolChoice{Mode: llmlib.ToolChoiceNone}
+		}
+		resp, err := o.completionProv.Chat(ctx, chatReq)
+		if err != nil {
+			return nil, err
+		}
+		usage.Add(resp.Usage)
+
+		var calls []llmlib.ToolUse
+		for _, block := range resp.Content {
+			if block.Type == llmlib.BlockToolUse && block.ToolUse != nil {
+				calls = append(calls, *block.ToolUse)
+			}
+		}
+		if len(calls) == 0 || round == rounds {
+			resp.Usage = usage
+			return resp, nil
+		}
+
+		// All of a round's results go in one message, as Anthropic
+		// requires; the other providers split it up.
+		results := llmlib.Message{Role: llmlib.RoleTool}
+		for _, call := range calls {
+			text, err := o.runTool(ctx, run, call)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, err
+			}
+			if err != nil {
+				o.logger.Warn("tool call failed", "tool", call.Name, "error", err)
+				text = err.Error()
+			}
+			results.Content = append(results.Content, llmlib.ContentBlock{
+				Type:      llmlib.BlockToolResult,
+				ToolUseID: call.ID,
+				Text:      text,
+				IsError:   err != nil,
+			})
+		}
+		chatReq.Messages = append(chatReq.Messages, llmlib.AssistantBlocks(resp.Content...), results)
+		o.logger.Debug("ran tool calls", "round", round+1, "calls", len(calls))
+	}
+}
+
+// runTool runs a tool call and returns its result for the model. The
+// model is only given the tools tools.builtin names, which validation
+// limits to known ones.
+func (o *Orchestrator) runTool(ctx context.Context, run *toolRun, call llmlib.ToolUse) (string, error) {
+	switch call.Name {
+	case config.ToolSearch:
+		return o.searchTool(ctx, run, call.Input)
+	}
+	return "", fmt.Errorf("unknown tool: %s", call.Name)
+}
+
+// searchTool searches the pipeline's tables for the query the model
+// wrote, with the request's filter and the caller's access, and adds
+// the results the model has not been shown to the context, fitted to
+// the token budget on their own. They are returned formatted as the
+// context is, numbered on from it.
+func (o *Orchestrator) searchTool(ctx context.Context, run *toolRun, input json.RawMessage) (string, error) {
+	var args struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(input, &args); err != nil || strings.TrimSpace(args.Query) == "" {
+		return "", errors.New(`search requires a non-empty "query"`)
+	}
+
+	searchUsage := &StageUsage{}
+	embedding, err := o.embedWithTimeout(ctx, args.Query, searchUsage)
+	run.usage.Embedding.Add(searchUsage.Embedding)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	req := run.req
+	req.Query = args.Query
+	results, err := o.searchWithTimeout(ctx, req, embedding, run.topN)
+	if err != nil {
+		return "", err
+	}
+	results = run.unseen(o.filterResults(ctx, args.Query, results))
+	if len(results) == 0 {
+		return noFurtherDocuments, nil
+	}
+
+	docs, kept := o.buildContext(results)
+	first := len(run.docs) + 1
+	run.add(kept, docs)
+	return ragllm.FormatMoreContext(docs, o.contextFormat(), first), nil
+}
+
+// resultKey identifies a result for deduplication: by its ID, or its
+// content when it has none.
+func resultKey(r database.SearchResult) string {
+	if r.ID != "" {
+		return r.ID
+	}
+	return r.Content
+}
+
+// unseen returns the results that are not already in the context.
+func (r *toolRun) unseen(results []database.SearchResult) []database.SearchResult {
+	shown := make(map[string]bool, len(r.docs))
+	for _, res := range r.results[:len(r.docs)] {
+		shown[resultKey(res)] = true
+	}
+	return slices.DeleteFunc(results, func(res database.SearchResult) bool {
+		return shown[resultKey(res)]
+	})
+}
+
+// add appends results, and the context documents built from them, to
+// the context, moving them up if they were retrieved but left out of
+// it before.
+func (r *toolRun) add(results []database.SearchResult, docs []ragllm.ContextDoc) {
+	added := make(map[string]bool, len(results))
+	for _, res := range results {
+		added[resultKey(res)] = true
+	}
+	rest := slices.DeleteFunc(slices.Clone(r.results[len(r.docs):]), func(res database.SearchResult) bool {
+		return added[resultKey(res)]
+	})
+	r.results = slices.Concat(r.results[:len(r.docs)], results, rest)
+	r.docs = append(slices.Clip(r.docs), docs...)
+}
+
+// answerStream returns a stream that yields the text of resp, an
+// answer generated whole, in one chunk.
+func answerStream(resp *llmlib.ChatResponse) *llmlib.Stream {
+	chunks := make(chan llmlib.StreamChunk, 2)
+	chunks <- llmlib.StreamChunk{Type: llmlib.ChunkText, Text: joinTextBlocks(resp.Content)}
+	chunks <- llmlib.StreamChunk{Type: llmlib.ChunkDone, Usage: &resp.Usage}
+	close(chunks)
+	errs := make(chan error)
+	close(errs)
+	return &llmlib.Stream{Chunks: chunks, Err: errs}
+}
@@ -0,0 +1,186 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// newToolsOrchestrator returns an orchestrator with the search tool,
+// whose embedder encodes each query's length, and whose vector search
+// returns the documents registered for that length.
+func newToolsOrchestrator(completer *MockCompleter, found map[string][]string, maxRounds int) *Orchestrator {
+	citations := true
+	return newMultiQueryOrchestrator(completer, found, 0, func(p *config.Pipeline, cfg *OrchestratorConfig) {
+		p.Retrieval = config.RetrievalConfig{}
+		p.Citations = &citations
+		p.Tools = config.ToolsConfig{Builtin: []string{config.ToolSearch}, MaxRounds: maxRounds}
+	})
+}
+
+// searchCall returns a response calling the search tool for query.
+func searchCall(id, query string) *llmlib.ChatResponse {
+	input, _ := json.Marshal(map[string]string{"query": query})
+	return &llmlib.ChatResponse{
+		Content: []llmlib.ContentBlock{{
+			Type:    llmlib.BlockToolUse,
+			ToolUse: &llmlib.ToolUse{ID: id, Name: config.ToolSearch, Input: input},
+		}},
+		StopReason: llmlib.StopReasonToolUse,
+		Usage:      llmlib.TokenUsage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110},
+	}
+}
+
+func TestOrchestrator_Tools_Search(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			if len(requests) == 1 {
+				return searchCall("call-1", "replicate WAL"), nil
+			}
+			return &llmlib.ChatResponse{
+				Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "Standbys replay WAL [3]."}},
+				Usage:   llmlib.TokenUsage{PromptTokens: 200, CompletionTokens: 20, TotalTokens: 220},
+			}, nil
+		},
+	}
+	orch := newToolsOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a", "doc-b"},
+		"replicate WAL":                   {"doc-b", "doc-c"},
+	}, 0)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{
+		Query:          "configure streaming replication",
+		IncludeSources: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 completion calls, got %d", len(requests))
+	}
+	if len(requests[0].Tools) != 1 || requests[0].Tools[0].Name != config.ToolSearch {
+		t.Errorf("expected the search tool to be offered, got %+v", requests[0].Tools)
+	}
+	msgs := requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != llmlib.RoleTool || len(last.Content) != 1 || last.Content[0].ToolUseID != "call-1" {
+		t.Fatalf("expected the search result last, got %+v", last)
+	}
+	// doc-b was already in the context, so only doc-c is added, as the
+	// third document.
+	result := last.Content[0].Text
+	if !strings.Contains(result, "--- Document 3 ---\ncontent of doc-c") || strings.Contains(result, "doc-b") {
+		t.Errorf("unexpected search result: %q", result)
+	}
+
+	var ids []string
+	for _, s := range resp.Sources {
+		ids = append(ids, s.ID)
+	}
+	if want := []string{"doc-a", "doc-b", "doc-c"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("sources = %v, want %v", ids, want)
+	}
+	if len(resp.Citations) != 1 || resp.Citations[0].ID != "doc-c" {
+		t.Errorf("expected [3] cited as doc-c, got %+v", resp.Citations)
+	}
+	if len(resp.Provenance) != 3 {
+		t.Errorf("expected provenance for 3 documents, got %+v", resp.Provenance)
+	}
+	if resp.Usage.Completion.TotalTokens != 330 || resp.TokensUsed != 330 {
+		t.Errorf("expected both rounds' usage, got %+v", resp.Usage.Completion)
+	}
+}
+
+func TestOrchestrator_Tools_MaxRounds(t *testing.T) {
+	var requests []llmlib.ChatRequest
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			requests = append(requests, req)
+			if req.ToolChoice != nil && req.ToolChoice.Mode == llmlib.ToolChoiceNone {
+				return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "answer"}}}, nil
+			}
+			return searchCall("call", "nothing here"), nil
+		},
+	}
+	orch := newToolsOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a"},
+	}, 1)
+
+	resp, err := orch.Execute(context.Background(), QueryRequest{Query: "configure streaming replication"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Answer != "answer" {
+		t.Errorf("expected the last round's answer, got %q", resp.Answer)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 completion calls, got %d", len(requests))
+	}
+	msgs := requests[1].Messages
+	if got := msgs[len(msgs)-1].Content[0].Text; got != noFurtherDocuments {
+		t.Errorf("expected an empty search result, got %q", got)
+	}
+}
+
+func TestOrchestrator_Tools_Stream(t *testing.T) {
+	calls := 0
+	completer := &MockCompleter{
+		ChatFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.ChatResponse, error) {
+			calls++
+			if calls == 1 {
+				return searchCall("call-1", "replicate WAL"), nil
+			}
+			return &llmlib.ChatResponse{Content: []llmlib.ContentBlock{{Type: llmlib.BlockText, Text: "streamed [2]"}}}, nil
+		},
+		ChatStreamFunc: func(ctx context.Context, req llmlib.ChatRequest) (*llmlib.Stream, error) {
+			t.Error("expected no streamed completion")
+			return nil, nil
+		},
+	}
+	orch := newToolsOrchestrator(completer, map[string][]string{
+		"configure streaming replication": {"doc-a"},
+		"replicate WAL":                   {"doc-c"},
+	}, 0)
+
+	chunks, errs := orch.ExecuteStream(context.Background(), QueryRequest{
+		Query:          "configure streaming replication",
+		IncludeSources: true,
+	})
+	var got []StreamChunk
+	for c := range chunks {
+		got = append(got, c)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected sources, answer and final chunks, got %+v", got)
+	}
+	if len(got[0].Sources) != 2 || got[0].Sources[1].ID != "doc-c" {
+		t.Errorf("expected the searched document among the sources, got %+v", got[0].Sources)
+	}
+	if got[1].Content != "streamed [2]" {
+		t.Errorf("expected the answer in one chunk, got %q", got[1].Content)
+	}
+	if len(got[2].Citations) != 1 || got[2].Citations[0].ID != "doc-c" {
+		t.Errorf("expected [2] cited as doc-c, got %+v", got[2].Citations)
+	}
+}
@@ -0,0 +1,230 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// TuningVariant is one combination of retrieval settings a tuning run
+// tries. Settings left unset keep the pipeline's own.
+type TuningVariant struct {
+	Name          string   `json:"name"`
+	Hybrid        *bool    `json:"hybrid,omitempty"`         // As search.hybrid_enabled
+	Fusion        string   `json:"fusion,omitempty"`         // As search.fusion
+	VectorWeight  *float64 `json:"vector_weight,omitempty"`  // As search.vector_weight
+	RRFK          *int     `json:"rrf_k,omitempty"`          // As search.rrf_k
+	MinSimilarity *float64 `json:"min_similarity,omitempty"` // As search.min_similarity
+	Rerank        *bool    `json:"rerank,omitempty"`         // False skips the pipeline's reranker
+}
+
+// TuningReport compares how a pipeline's retrieval scores against an
+// eval dataset under different settings. Results are ordered best
+// first, by nDCG and then recall; Best names the first.
+type TuningReport struct {
+	K       int            `json:"k"`
+	Cases   int            `json:"cases"`
+	Skipped int            `json:"skipped"` // Without expected sources, or rated down
+	Best    string         `json:"best,omitempty"`
+	Results []TuningResult `json:"results"`
+}
+
+// TuningResult is how the dataset scored under one variant.
+type TuningResult struct {
+	Variant   TuningVariant `json:"variant"`
+	Evaluated int           `json:"evaluated"`
+	Failed    int           `json:"failed"`
+	HitRate   float64       `json:"hit_rate"`
+	Recall    float64       `json:"recall"`
+	MRR       float64       `json:"mrr"`
+	NDCG      float64       `json:"ndcg"`
+}
+
+// CurrentVariant names the variant with the pipeline's own settings,
+// which a tuning run always includes as its baseline.
+const CurrentVariant = "current"
+
+// Tune evaluates the pipeline's retrieval against a dataset once for
+// each variant, as Evaluate would, and reports how they compare; zero k
+// uses the pipeline's top_n. Without variants, the pipeline's settings
+// are compared with each fusion method and, when it reranks, with
+// reranking skipped. Each query is embedded once, whatever the number
+// of variants. progress is called as queries are evaluated, with the
+// number evaluated out of the total.
+func (o *Orchestrator) Tune(
+	ctx context.Context,
+	cases []EvalCase,
+	variants []TuningVariant,
+	k int,
+	progress func(done, total int),
+) (*TuningReport, error) {
+	if k < 0 {
+		return nil, fmt.Errorf("%w: k must not be negative", ErrInvalidRequest)
+	}
+	if k == 0 {
+		k = o.topN
+	}
+	if len(variants) == 0 {
+		variants = o.defaultVariants()
+	}
+	if err := o.checkVariants(variants); err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(variants, func(v TuningVariant) bool { return v.Name == CurrentVariant }) {
+		variants = append([]TuningVariant{{Name: CurrentVariant}}, variants...)
+	}
+
+	report := &TuningReport{K: k, Cases: len(cases), Results: []TuningResult{}}
+	per := 0
+	for _, ec := range cases {
+		if evaluable(ec) {
+			per++
+		}
+	}
+	report.Skipped = len(cases) - per
+
+	embedder := &memoEmbedder{Embedder: o.embeddingProv, vectors: make(map[string][]float64)}
+	done, total := 0, per*len(variants)
+	progress(done, total)
+	for _, v := range variants {
+		eval, err := o.withVariant(v, embedder).evaluate(ctx, cases, EvalOptions{K: k}, func() {
+			done++
+			progress(done, total)
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.Results = append(report.Results, TuningResult{
+			Variant:   v,
+			Evaluated: eval.Evaluated,
+			Failed:    eval.Failed,
+			HitRate:   eval.HitRate,
+			Recall:    eval.Recall,
+			MRR:       eval.MRR,
+			NDCG:      eval.NDCG,
+		})
+	}
+
+	slices.SortStableFunc(report.Results, func(a, b TuningResult) int {
+		if c := cmp.Compare(b.NDCG, a.NDCG); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.Recall, a.Recall)
+	})
+	if per > 0 {
+		report.Best = report.Results[0].Variant.Name
+	}
+	return report, nil
+}
+
+// defaultVariants returns the variants a tuning run tries when none are
+// given.
+func (o *Orchestrator) defaultVariants() []TuningVariant {
+	hybrid := true
+	variants := []TuningVariant{
+		{Name: CurrentVariant},
+		{Name: config.FusionRRF, Hybrid: &hybrid, Fusion: config.FusionRRF},
+		{Name: config.FusionScore, Hybrid: &hybrid, Fusion: config.FusionScore},
+	}
+	if o.reranker != nil {
+		rerank := false
+		variants = append(variants, TuningVariant{Name: "no_rerank", Rerank: &rerank})
+	}
+	return variants
+}
+
+// checkVariants validates a tuning run's variants as the pipeline's
+// configuration is validated.
+func (o *Orchestrator) checkVariants(variants []TuningVariant) error {
+	seen := make(map[string]bool)
+	for i, v := range variants {
+		switch {
+		case v.Name == "":
+			return fmt.Errorf("%w: variant %d: name is required", ErrInvalidRequest, i+1)
+		case seen[v.Name]:
+			return fmt.Errorf("%w: variant %q is given twice", ErrInvalidRequest, v.Name)
+		case v.Fusion != "" && v.Fusion != config.FusionRRF && v.Fusion != config.FusionScore:
+			return fmt.Errorf("%w: variant %q: fusion must be %q or %q",
+				ErrInvalidRequest, v.Name, config.FusionRRF, config.FusionScore)
+		case v.VectorWeight != nil && (*v.VectorWeight < 0 || *v.VectorWeight > 1):
+			return fmt.Errorf("%w: variant %q: vector_weight must be between 0.0 and 1.0", ErrInvalidRequest, v.Name)
+		case v.MinSimilarity != nil && (*v.MinSimilarity < 0 || *v.MinSimilarity > 1):
+			return fmt.Errorf("%w: variant %q: min_similarity must be between 0.0 and 1.0", ErrInvalidRequest, v.Name)
+		case v.RRFK != nil && *v.RRFK < 1:
+			return fmt.Errorf("%w: variant %q: rrf_k must be positive", ErrInvalidRequest, v.Name)
+		case v.Rerank != nil && *v.Rerank && o.reranker == nil:
+			return fmt.Errorf("%w: variant %q: the pipeline has no reranker", ErrInvalidRequest, v.Name)
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// withVariant returns a copy of the orchestrator that retrieves with a
+// variant's settings, embedding through embedder.
+func (o *Orchestrator) withVariant(v TuningVariant, embedder Embedder) *Orchestrator {
+	variant := *o
+	cfg := *o.cfg
+	if v.Hybrid != nil {
+		cfg.Search.HybridEnabled = v.Hybrid
+	}
+	if v.Fusion != "" {
+		cfg.Search.Fusion = v.Fusion
+	}
+	if v.VectorWeight != nil {
+		cfg.Search.VectorWeight = v.VectorWeight
+		cfg.Search.LexicalWeight = nil
+	}
+	if v.RRFK != nil {
+		cfg.Search.RRFK = v.RRFK
+	}
+	if v.MinSimilarity != nil {
+		cfg.Search.MinSimilarity = v.MinSimilarity
+	}
+	if v.Rerank != nil && !*v.Rerank {
+		variant.reranker = nil
+	}
+	variant.cfg = &cfg
+	variant.embeddingProv = embedder
+	return &variant
+}
+
+// memoEmbedder embeds each text once, so a tuning run's variants share
+// their queries' embeddings. It is safe for concurrent use.
+type memoEmbedder struct {
+	Embedder
+
+	mu      sync.Mutex
+	vectors map[string][]float64
+}
+
+func (m *memoEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	m.mu.Lock()
+	vector, ok := m.vectors[text]
+	m.mu.Unlock()
+	if ok {
+		return vector, nil
+	}
+
+	vector, err := m.Embedder.Embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	m.vectors[text] = vector
+	m.mu.Unlock()
+	return vector, nil
+}
@@ -0,0 +1,145 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+func TestOrchestrator_Tune(t *testing.T) {
+	// The reranker reverses the order of what the search returns.
+	reranker := &MockReranker{
+		RerankFunc: func(ctx context.Context, req llmlib.RerankRequest) (*llmlib.RerankResponse, error) {
+			results := make([]llmlib.RerankResult, len(req.Documents))
+			for i := range req.Documents {
+				results[i] = llmlib.RerankResult{Index: len(req.Documents) - 1 - i, RelevanceScore: 0.5}
+			}
+			return &llmlib.RerankResponse{Results: results}, nil
+		},
+	}
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(reranker, &gotFilter)
+	embedded := 0
+	orch.embeddingProv = &MockEmbedder{EmbedFunc: func(ctx context.Context, text string) ([]float64, error) {
+		embedded++
+		return []float64{0.1, 0.2, 0.3}, nil
+	}}
+
+	var done, total int
+	rerank := false
+	report, err := orch.Tune(context.Background(), []EvalCase{
+		{Query: "streaming standby", ExpectedSources: []string{"doc-1"}},
+		{Query: "replication", ExpectedSources: []string{"doc-1", "doc-9"}},
+		{Query: "no sources"},
+	}, []TuningVariant{{Name: "no_rerank", Rerank: &rerank}}, 2, func(d, t int) { done, total = d, t })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if report.K != 2 || report.Cases != 3 || report.Skipped != 1 || len(report.Results) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if report.Best != "no_rerank" || report.Results[0].Variant.Name != "no_rerank" ||
+		report.Results[1].Variant.Name != CurrentVariant {
+		t.Errorf("expected the variant without reranking ranked first, got %+v", report.Results)
+	}
+	// The top 2 are doc-1 then doc-2, reversed by reranking, so doc-1
+	// is at rank 1 without it and rank 2 with it. The ideal ranking of
+	// the second case has both its sources first.
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	second := 1 / math.Log2(3)
+	if r := report.Results[0]; r.Evaluated != 2 || !near(r.Recall, 0.75) || !near(r.MRR, 1) ||
+		!near(r.NDCG, (1+1/(1+second))/2) {
+		t.Errorf("unexpected scores without reranking: %+v", r)
+	}
+	if r := report.Results[1]; !near(r.Recall, 0.75) || !near(r.MRR, 0.5) ||
+		!near(r.NDCG, (second+second/(1+second))/2) {
+		t.Errorf("unexpected scores with reranking: %+v", r)
+	}
+	if embedded != 2 {
+		t.Errorf("expected each query embedded once, got %d embeddings", embedded)
+	}
+	if done != 4 || total != 4 {
+		t.Errorf("expected progress to reach 4 of 4, got %d of %d", done, total)
+	}
+}
+
+func TestOrchestrator_Tune_Variants(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+
+	var names []string
+	for _, v := range orch.defaultVariants() {
+		names = append(names, v.Name)
+	}
+	if len(names) != 3 || names[0] != CurrentVariant || names[1] != config.FusionRRF || names[2] != config.FusionScore {
+		t.Errorf("expected the fusion methods compared without a reranker, got %v", names)
+	}
+
+	weight, rerank := 1.5, true
+	for _, variants := range [][]TuningVariant{
+		{{}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Fusion: "borda"}},
+		{{Name: "a", VectorWeight: &weight}},
+		{{Name: "a", Rerank: &rerank}},
+	} {
+		cases := []EvalCase{{Query: "q", ExpectedSources: []string{"doc-1"}}}
+		if _, err := orch.Tune(context.Background(), cases, variants, 0, func(int, int) {}); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("expected %+v to be invalid, got %v", variants, err)
+		}
+	}
+}
+
+func TestPipeline_WithVariant(t *testing.T) {
+	var gotFilter *config.Filter
+	orch := newRetrieveOrchestrator(nil, &gotFilter)
+	var gotMinSimilarity *float64
+	orch.dbPool.(*MockSearchBackend).VectorSearchFunc = func(
+		ctx context.Context, embedding []float32, table config.TableSource,
+		topN int, filter *config.Filter, minSimilarity *float64,
+	) ([]database.SearchResult, error) {
+		gotMinSimilarity = minSimilarity
+		return nil, nil
+	}
+	orch.answerCache = newAnswerCache(config.AnswerCacheConfig{Enabled: true})
+	p := &Pipeline{name: "docs", orchestrator: orch}
+
+	minSimilarity := 0.6
+	variant, err := p.WithVariant(TuningVariant{Name: "repl", MinSimilarity: &minSimilarity})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := variant.Retrieve(context.Background(), RetrieveRequest{Query: "replication"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotMinSimilarity == nil || *gotMinSimilarity != 0.6 {
+		t.Errorf("expected the variant's min_similarity, got %v", gotMinSimilarity)
+	}
+	if variant.orchestrator.answerCache != nil || p.orchestrator.answerCache == nil {
+		t.Error("expected only the variant to skip the answer cache")
+	}
+	if p.orchestrator.cfg.Search.MinSimilarity != nil {
+		t.Error("expected the pipeline's own settings unchanged")
+	}
+
+	invalid := 1.5
+	if _, err := p.WithVariant(TuningVariant{Name: "repl", MinSimilarity: &invalid}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("expected an invalid min_similarity to be rejected, got %v", err)
+	}
+}
@@ -11,6 +11,8 @@
 package pipeline
 
 import (
+	"encoding/json"
+
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
 
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
@@ -22,6 +24,22 @@ type Info struct {
 	Description string `json:"description"`
 }
 
+// Capabilities describes the filter columns a pipeline's requests may
+// reference, the metadata its sources carry, and whether it accepts
+// document uploads, for its OpenAPI document and upload endpoint.
+type Capabilities struct {
+	Name            string
+	Description     string
+	FilterColumns   []string // Empty when any column may be filtered
+	FilterPresets   []string // Names of the pipeline's filter presets, sorted
+	MetadataColumns []string // Every table's metadata_columns, without duplicates
+	Ingest          bool     // Documents may be uploaded to the pipeline
+	MaxUploadBytes  int64    // Largest accepted upload, when Ingest is set
+
+	Tenant    config.TenantConfig    // How the pipeline isolates tenants, if it does
+	RateLimit config.RateLimitConfig // Each caller's limits on the pipeline
+}
+
 // Usage reports a pipeline's cumulative LLM token consumption, broken
 // down by embedding and completion provider. Each is cumulative since
 // the underlying client was created (or last reset) — a monotonically
@@ -32,21 +50,43 @@ type Usage struct {
 	Description string            `json:"description"`
 	Embedding   llmlib.TokenUsage `json:"embedding"`
 	Completion  llmlib.TokenUsage `json:"completion"`
+	Cost        *Cost             `json:"cost,omitempty"`
 }
 
-// ProviderHealth reports whether a single LLM provider was reachable
-// during a health check. See issue #23.
+// ProviderHealth reports whether a single LLM provider, or a pipeline's
+// database, was reachable during a health check. See issue #23.
 type ProviderHealth struct {
 	Reachable bool   `json:"reachable"`
 	Error     string `json:"error,omitempty"`
 }
 
 // PipelineHealth reports connectivity for a single pipeline's
-// embedding and completion providers.
+// embedding and completion providers, and how long each stage of its
+// initialization took.
 type PipelineHealth struct {
 	Name       string         `json:"name"`
 	Embedding  ProviderHealth `json:"embedding"`
 	Completion ProviderHealth `json:"completion"`
+	Init       []InitStage    `json:"init,omitempty"`
+}
+
+// PipelineReadiness reports whether a pipeline can serve queries: its
+// database is reachable and, when they were checked, so are its
+// providers. Providers that were not checked are omitted.
+type PipelineReadiness struct {
+	Name       string          `json:"name"`
+	Ready      bool            `json:"ready"`
+	Database   ProviderHealth  `json:"database"`
+	Embedding  *ProviderHealth `json:"embedding,omitempty"`
+	Completion *ProviderHealth `json:"completion,omitempty"`
+}
+
+// InitStage reports how long one stage of a pipeline's initialization
+// took, and the error it ended with, if any.
+type InitStage struct {
+	Name       string `json:"name"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
 }
 
 // Message represents a message in the conversation history.
@@ -59,36 +99,211 @@ type Message struct {
 type QueryRequest struct {
 	Query          string         `json:"query"`
 	Stream         bool           `json:"stream"`
-	TopN           int            `json:"top_n,omitempty"`    // Override default top-N results
-	Filter         *config.Filter `json:"filter,omitempty"`   // Structured filter to filter results
-	IncludeSources bool           `json:"include_sources"`    // Include source documents (default: false)
-	Messages       []Message      `json:"messages,omitempty"` // Previous conversation history
+	TopN           int            `json:"top_n,omitempty"`         // Override default top-N results
+	Filter         *config.Filter `json:"filter,omitempty"`        // Structured filter to filter results
+	FilterPreset   string         `json:"filter_preset,omitempty"` // A pipeline filter preset, combined with Filter
+	IncludeSources bool           `json:"include_sources"`         // Include source documents (default: false)
+	IncludeTimings bool           `json:"include_timings"`         // Include stage Timings (default: false)
+	Messages       []Message      `json:"messages,omitempty"`      // Previous conversation history
+	SessionID      string         `json:"session_id,omitempty"`    // Server-side session supplying prior turns
+
+	// StopSequences are added to the pipeline's configured
+	// rag_llm.stop_sequences for this request.
+	StopSequences []string `json:"stop_sequences,omitempty"`
+
+	// LogitBias entries override the pipeline's configured
+	// rag_llm.logit_bias per token. OpenAI pipelines only.
+	LogitBias map[string]int `json:"logit_bias,omitempty"`
+
+	// AnswerLength overrides the pipeline's answer_length preset for
+	// this request.
+	AnswerLength string `json:"answer_length,omitempty"`
+
+	// Seed is the sampling seed sent to the provider, so a repeated
+	// query can be answered the same way. OpenAI and Ollama pipelines
+	// only; a query with a seed is not answered from the answer cache.
+	Seed *int64 `json:"seed,omitempty"`
+
+	// ResponseFormat asks for the answer as a JSON document matching a
+	// schema. Not supported by Bedrock pipelines.
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+
+	// historySummary summarizes the messages dropped from Messages to
+	// fit the pipeline's max_history_tokens.
+	historySummary string
+}
+
+// ResponseFormatJSONSchema is the response_format type of an answer
+// that is a JSON document matching a schema.
+const ResponseFormatJSONSchema = "json_schema"
+
+// ResponseFormat asks for a structured answer: with type
+// ResponseFormatJSONSchema, a JSON document matching JSONSchema, whose
+// type must be "object".
+type ResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema json.RawMessage `json:"json_schema"`
 }
 
 // QueryResponse represents a non-streaming RAG query response.
 type QueryResponse struct {
-	Answer     string   `json:"answer"`
-	Sources    []Source `json:"sources,omitempty"`
-	TokensUsed int      `json:"tokens_used"`
+	Answer     string      `json:"answer"`
+	Sources    []Source    `json:"sources,omitempty"`
+	TokensUsed int         `json:"tokens_used"`
+	Usage      *StageUsage `json:"usage,omitempty"`
+
+	// FormatWarnings lists places where the answer departs from the
+	// pipeline's configured formatting conventions.
+	FormatWarnings []string `json:"format_warnings,omitempty"`
+
+	// Citations maps the [n] markers in the answer to the sources they
+	// cite. Only set in citations mode.
+	Citations []Citation `json:"citations,omitempty"`
+
+	// Guardrails lists the pipeline's guardrails that changed the
+	// answer: GuardrailUngrounded, GuardrailRedacted or
+	// GuardrailTruncated.
+	Guardrails []string `json:"guardrails,omitempty"`
+
+	// Timings reports how long the query's stages took. Only set when
+	// the request asked for them.
+	Timings *Timings `json:"timings,omitempty"`
+
+	// Cached is set when the answer came from the pipeline's answer
+	// cache.
+	Cached bool `json:"cached,omitempty"`
+
+	// QueryID identifies the query when the pipeline captured it for
+	// its eval dataset, so feedback on the answer can be given.
+	QueryID string `json:"query_id,omitempty"`
+
+	// ResponseID identifies the answer when the pipeline takes
+	// feedback, so the answer can be rated.
+	ResponseID string `json:"response_id,omitempty"`
+
+	// Variant names the pipeline that answered the query when the
+	// pipeline asked runs an experiment: one of its variants, or the
+	// pipeline itself.
+	Variant string `json:"variant,omitempty"`
+
+	// Provenance identifies the documents the answer was written from,
+	// in the order they were given to the model, with the hash of each
+	// one's stored content.
+	Provenance []DocumentProvenance `json:"provenance,omitempty"`
+
+	// Signature signs the answer and its provenance, when the server
+	// signs answers.
+	Signature *AnswerSignature `json:"signature,omitempty"`
+
+	// AnsweredBy is the completion model that wrote the answer, unset
+	// when no model was asked.
+	AnsweredBy Answerer `json:"-"`
+}
+
+// Timings reports how long a query's stages took, in milliseconds.
+// Search includes writing the hypothetical answer, the paraphrases or
+// the follow-up queries of the hyde, multi_query and iterative
+// retrieval strategies. TTFB, the time
+// until the first answer text was sent, is only set for streamed
+// queries.
+type Timings struct {
+	EmbedMS  int64  `json:"embed_ms"`
+	SearchMS int64  `json:"search_ms"`
+	TTFBMS   *int64 `json:"ttfb_ms,omitempty"`
+	TotalMS  int64  `json:"total_ms"`
+}
+
+// Citation maps a [n] marker in an answer to the source document it
+// cites. Metadata carries the table's metadata_columns, such as a URL.
+type Citation struct {
+	Marker   int                    `json:"marker"`
+	ID       string                 `json:"id,omitempty"`
+	Score    float64                `json:"score"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// StageUsage breaks a single query's token consumption down by pipeline
+// stage, so each provider's cost can be attributed separately.
+// TokensUsed on QueryResponse remains the completion total. Rerank is
+// nil when the pipeline has no reranker or the reranker was not called,
+// QueryExpansion when the pipeline does not paraphrase queries,
+// HistorySummary when no conversation history was summarized,
+// ContextSummary when the top document was not summarized to fit the
+// token budget, Groundedness when the answer was not checked against
+// its documents, and Cost when none of the models used is priced.
+// Embedding includes the embeddings of any paraphrases.
+type StageUsage struct {
+	QueryExpansion *llmlib.TokenUsage `json:"query_expansion,omitempty"`
+	HistorySummary *llmlib.TokenUsage `json:"history_summary,omitempty"`
+	ContextSummary *llmlib.TokenUsage `json:"context_summary,omitempty"`
+	Embedding      llmlib.TokenUsage  `json:"embedding"`
+	Rerank         *llmlib.TokenUsage `json:"rerank,omitempty"`
+	Completion     llmlib.TokenUsage  `json:"completion"`
+	Groundedness   *llmlib.TokenUsage `json:"groundedness,omitempty"`
+	Cost           *float64           `json:"cost,omitempty"`
+}
+
+// TotalTokens returns the tokens used by every stage together.
+func (u *StageUsage) TotalTokens() int {
+	if u == nil {
+		return 0
+	}
+	total := u.Embedding.TotalTokens + u.Completion.TotalTokens
+	for _, stage := range []*llmlib.TokenUsage{u.QueryExpansion, u.HistorySummary, u.ContextSummary, u.Rerank, u.Groundedness} {
+		if stage != nil {
+			total += stage.TotalTokens
+		}
+	}
+	return total
 }
 
 // Source represents a source document used in the RAG response.
 type Source struct {
-	ID      string  `json:"id,omitempty"`
-	Content string  `json:"content"`
-	Score   float64 `json:"score"`
+	ID       string                 `json:"id,omitempty"`
+	Content  string                 `json:"content"`
+	Score    float64                `json:"score"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // The table's metadata_columns
+
+	ContentHash string `json:"content_hash,omitempty"` // See DocumentProvenance
 }
 
 // StreamEvent represents a streaming response event.
 type StreamEvent struct {
-	Type    string   `json:"type"`              // "chunk", "sources", "done", "error"
-	Content string   `json:"content,omitempty"` // For "chunk" type
-	Sources []Source `json:"sources,omitempty"` // For "sources" type
-	Error   string   `json:"error,omitempty"`   // For "error" type
+	Type    string      `json:"type"`              // "sources", "chunk", "usage", "done", "error", "server_shutting_down"
+	Content string      `json:"content,omitempty"` // For "chunk" type
+	Sources []Source    `json:"sources,omitempty"` // For "sources" type
+	Error   string      `json:"error,omitempty"`   // For "error" and "server_shutting_down" types
+	Stage   string      `json:"stage,omitempty"`   // For "error" type: the stage that timed out
+	Usage   *StageUsage `json:"usage,omitempty"`   // For "usage" and "done" types
+
+	FormatWarnings []string   `json:"format_warnings,omitempty"` // For "done" type
+	Citations      []Citation `json:"citations,omitempty"`       // For "done" type
+	Guardrails     []string   `json:"guardrails,omitempty"`      // For "done" type
+	Timings        *Timings   `json:"timings,omitempty"`         // For "done" type
+	Cached         bool       `json:"cached,omitempty"`          // For "done" type
+	QueryID        string     `json:"query_id,omitempty"`        // For "done" type
+	ResponseID     string     `json:"response_id,omitempty"`     // For "done" type
+	Variant        string     `json:"variant,omitempty"`         // For "done" type
+
+	Provenance []DocumentProvenance `json:"provenance,omitempty"` // For "done" type
+	Signature  *AnswerSignature     `json:"signature,omitempty"`  // For "done" type
 }
 
 // StreamChunk represents a chunk of streaming response from the orchestrator.
 type StreamChunk struct {
-	Content      string `json:"content,omitempty"`
-	FinishReason string `json:"finish_reason,omitempty"`
+	Content      string      `json:"content,omitempty"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Sources      []Source    `json:"sources,omitempty"` // set on a chunk of its own, before the answer
+	Usage        *StageUsage `json:"usage,omitempty"`   // set on the final chunk
+
+	FormatWarnings []string   `json:"format_warnings,omitempty"` // set on the final chunk
+	Citations      []Citation `json:"citations,omitempty"`       // set on the final chunk
+	Guardrails     []string   `json:"guardrails,omitempty"`      // set on the final chunk
+	Timings        *Timings   `json:"timings,omitempty"`         // set on the final chunk
+	Cached         bool       `json:"cached,omitempty"`          // set on the final chunk
+	QueryID        string     `json:"query_id,omitempty"`        // set on the final chunk
+	ResponseID     string     `json:"response_id,omitempty"`     // set on the final chunk
+
+	Provenance []DocumentProvenance `json:"provenance,omitempty"` // set on the final chunk
+	AnsweredBy Answerer             `json:"-"`                    // set on the final chunk
 }
@@ -0,0 +1,192 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package reports
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// SignatureHeader carries a webhook report's HMAC-SHA256 signature, as
+// "sha256=" and the hex digest of the body keyed with the secret.
+const SignatureHeader = "X-Signature-256"
+
+// webhook posts reports as JSON.
+type webhook struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// newWebhook creates the webhook cfg describes, reading its secret.
+func newWebhook(cfg config.ReportWebhookConfig) (*webhook, error) {
+	w := &webhook{url: cfg.URL, client: &http.Client{Timeout: deliveryTimeout}}
+	if cfg.SecretFile != "" {
+		secret, err := config.ReadSecretFile(cfg.SecretFile, "report webhook secret")
+		if err != nil {
+			return nil, err
+		}
+		w.secret = secret
+	}
+	return w, nil
+}
+
+// send posts r, failing unless the webhook answers with a 2xx status.
+func (w *webhook) send(ctx context.Context, r *Report) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// sendMailFunc sends a message over SMTP; smtp.SendMail, replaced in
+// tests.
+type sendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// email mails reports as plain text.
+type email struct {
+	from     *mail.Address
+	to       []*mail.Address
+	smtpAddr string
+	smtpAuth smtp.Auth
+	sendMail sendMailFunc
+}
+
+// newEmail creates the email delivery cfg describes, reading its SMTP
+// password.
+func newEmail(cfg config.ReportEmailConfig) (*email, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid report email from address: %w", err)
+	}
+	e := &email{
+		from:     from,
+		smtpAddr: net.JoinHostPort(cfg.SMTP.Host, strconv.Itoa(cfg.SMTP.Port)),
+		sendMail: smtp.SendMail,
+	}
+	for _, to := range cfg.To {
+		addr, err := mail.ParseAddress(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid report email recipient: %w", err)
+		}
+		e.to = append(e.to, addr)
+	}
+	if cfg.SMTP.Username != "" {
+		password, err := config.ReadSecretFile(cfg.SMTP.PasswordFile, "SMTP password")
+		if err != nil {
+			return nil, err
+		}
+		e.smtpAuth = smtp.PlainAuth("", cfg.SMTP.Username, password, cfg.SMTP.Host)
+	}
+	return e, nil
+}
+
+// send mails r to every recipient, with times shown in loc.
+func (e *email) send(r *Report, loc *time.Location) error {
+	to := make([]string, len(e.to))
+	for i, addr := range e.to {
+		to[i] = addr.Address
+	}
+	return e.sendMail(e.smtpAddr, e.smtpAuth, e.from.Address, to, e.buildMessage(r, loc))
+}
+
+// buildMessage builds the report's message, marked as automatic so
+// that autoresponders leave it alone.
+func (e *email) buildMessage(r *Report, loc *time.Location) []byte {
+	to := make([]string, len(e.to))
+	for i, addr := range e.to {
+		to[i] = addr.String()
+	}
+	subject := fmt.Sprintf("RAG server %s usage report: %s to %s", r.Schedule,
+		r.Start.In(loc).Format(time.DateOnly), r.End.In(loc).Format(time.DateOnly))
+
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", e.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Auto-Submitted", "auto-generated")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	_, _ = qp.Write([]byte(strings.ReplaceAll(formatText(r, loc), "\n", "\r\n")))
+	_ = qp.Close()
+	return buf.Bytes()
+}
+
+// formatText formats r as plain text, with times shown in loc.
+func formatText(r *Report, loc *time.Location) string {
+	var b strings.Builder
+	const stamp = "2006-01-02 15:04 MST"
+	fmt.Fprintf(&b, "Usage from %s to %s\n", r.Start.In(loc).Format(stamp), r.End.In(loc).Format(stamp))
+	if len(r.Pipelines) == 0 {
+		b.WriteString("\nNo pipelines are configured.\n")
+	}
+	for _, p := range r.Pipelines {
+		fmt.Fprintf(&b, "\n%s\n", p.Name)
+		fmt.Fprintf(&b, "  Queries:           %d\n", p.Queries)
+		fmt.Fprintf(&b, "  Failed:            %d (%.1f%%)\n", p.Failed, p.FailureRate*100)
+		fmt.Fprintf(&b, "  Embedding tokens:  %d\n", p.Embedding.TotalTokens)
+		fmt.Fprintf(&b, "  Completion tokens: %d (%d prompt, %d completion)\n",
+			p.Completion.TotalTokens, p.Completion.PromptTokens, p.Completion.CompletionTokens)
+		fmt.Fprintf(&b, "  Estimated cost:    $%.2f\n", p.Cost)
+		if len(p.TopQueries) > 0 {
+			b.WriteString("  Top queries:\n")
+			for _, q := range p.TopQueries {
+				fmt.Fprintf(&b, "    %5d  %s\n", q.Count, q.Query)
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,354 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package reports sends scheduled summaries of the pipelines' usage.
+// Each report covers the day or week since the last one and gives, for
+// every pipeline, the queries it was asked and the share that failed,
+// the tokens and estimated cost they spent, and the queries asked most
+// often. Reports are posted to a webhook as JSON, mailed as plain text,
+// or both.
+package reports
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// Stats reports each pipeline's cumulative token usage and cost. The
+// server satisfies it, always answering for the pipelines of the
+// current configuration.
+type Stats interface {
+	Stats() []pipeline.Usage
+}
+
+// maxTrackedQueries bounds the distinct queries counted for a pipeline
+// in one period, so busy pipelines do not grow the counts without
+// limit. Once it is reached, only queries already seen are counted.
+const maxTrackedQueries = 10000
+
+// maxQueryLength bounds, in runes, a query as listed in a report.
+const maxQueryLength = 200
+
+// deliveryTimeout bounds delivering one report to all its destinations.
+const deliveryTimeout = time.Minute
+
+// Report summarizes each pipeline's usage from Start to End, ordered by
+// pipeline name.
+type Report struct {
+	Schedule  string           `json:"schedule"`
+	Start     time.Time        `json:"start"`
+	End       time.Time        `json:"end"`
+	Pipelines []PipelineReport `json:"pipelines"`
+}
+
+// PipelineReport is one pipeline's usage over a report's period. Failed
+// counts the queries that ended in an error or a timeout; a client
+// disconnecting is not a failure.
+type PipelineReport struct {
+	Name        string            `json:"name"`
+	Queries     int               `json:"queries"`
+	Failed      int               `json:"failed"`
+	FailureRate float64           `json:"failure_rate"`
+	Embedding   llmlib.TokenUsage `json:"embedding"`
+	Completion  llmlib.TokenUsage `json:"completion"`
+	Cost        float64           `json:"cost"`
+	TopQueries  []QueryCount      `json:"top_queries,omitempty"`
+}
+
+// QueryCount is how often a query was asked. Queries are anonymized, as
+// captured eval queries are, and compared regardless of case and
+// spacing.
+type QueryCount struct {
+	Query string `json:"query"`
+	Count int    `json:"count"`
+}
+
+// queryCounts accumulates one pipeline's queries over a period.
+type queryCounts struct {
+	queries int
+	failed  int
+	asked   map[string]int
+}
+
+// Scheduler sends a report on the configured schedule, counting the
+// queries it is told of in between. Token usage and cost are taken as
+// the change in the pipelines' stats over the period. Counts are kept
+// in memory, so a report sent after a restart covers only the time
+// since. It is safe for concurrent use.
+type Scheduler struct {
+	cfg          config.ReportsConfig
+	loc          *time.Location
+	hour, minute int
+	weekday      time.Weekday
+	webhook      *webhook
+	email        *email
+	now          func() time.Time // overridden in tests
+	logger       *slog.Logger
+
+	mu       sync.Mutex
+	start    time.Time
+	baseline map[string]pipeline.Usage
+	counts   map[string]*queryCounts
+}
+
+// New creates the scheduler cfg describes, reading its webhook secret
+// and SMTP password from their files. The first report counts the
+// queries recorded from now.
+func New(cfg config.ReportsConfig, logger *slog.Logger) (*Scheduler, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &Scheduler{
+		cfg:    cfg,
+		now:    time.Now,
+		logger: logger,
+	}
+
+	var err error
+	if s.loc, err = time.LoadLocation(cfg.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid report time zone: %w", err)
+	}
+	if s.hour, s.minute, err = config.ParseTimeOfDay(cfg.Time); err != nil {
+		return nil, fmt.Errorf("invalid report time: %w", err)
+	}
+	if cfg.Schedule == config.ReportScheduleWeekly {
+		var ok bool
+		if s.weekday, ok = config.ParseWeekday(cfg.Weekday); !ok {
+			return nil, fmt.Errorf("invalid report weekday %q", cfg.Weekday)
+		}
+	}
+	if cfg.Webhook.URL != "" {
+		if s.webhook, err = newWebhook(cfg.Webhook); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Email.To) > 0 {
+		if s.email, err = newEmail(cfg.Email); err != nil {
+			return nil, err
+		}
+	}
+
+	s.start, s.counts = s.now(), make(map[string]*queryCounts)
+	return s, nil
+}
+
+// RecordQuery counts a query asked of a pipeline, and whether it failed.
+func (s *Scheduler) RecordQuery(pipelineName, query string, failed bool) {
+	if !s.reported(pipelineName) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.counts[pipelineName]
+	if c == nil {
+		c = &queryCounts{asked: make(map[string]int)}
+		s.counts[pipelineName] = c
+	}
+	c.queries++
+	if failed {
+		c.failed++
+	}
+	if s.cfg.TopQueries == 0 {
+		return
+	}
+	key := normalizeQuery(query)
+	if _, ok := c.asked[key]; ok || len(c.asked) < maxTrackedQueries {
+		c.asked[key]++
+	}
+}
+
+// Run sends a report at each scheduled time until ctx is done, taking
+// token usage and cost from stats. The first report's usage is measured
+// from when Run is called. A report that cannot be delivered is logged,
+// and its period is not reported again.
+func (s *Scheduler) Run(ctx context.Context, stats Stats) {
+	baseline := s.usage(stats)
+	s.mu.Lock()
+	s.baseline = baseline
+	s.mu.Unlock()
+
+	for {
+		next := s.next(s.now())
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		report := s.collect(s.now(), s.usage(stats))
+		deliverCtx, cancel := context.WithTimeout(ctx, deliveryTimeout)
+		if err := s.deliver(deliverCtx, report); err != nil {
+			s.logger.Error("failed to deliver usage report", "error", err)
+		} else {
+			s.logger.Info("usage report delivered", "start", report.Start, "end", report.End,
+				"pipelines", len(report.Pipelines))
+		}
+		cancel()
+	}
+}
+
+// next returns the first scheduled time after after: the configured
+// time of day in the configured time zone, on any day for a daily
+// report or on the configured weekday for a weekly one.
+func (s *Scheduler) next(after time.Time) time.Time {
+	local := after.In(s.loc)
+	y, m, d := local.Date()
+	for day := d; ; day++ {
+		t := time.Date(y, m, day, s.hour, s.minute, 0, 0, s.loc)
+		if !t.After(after) {
+			continue
+		}
+		if s.cfg.Schedule == config.ReportScheduleWeekly && t.Weekday() != s.weekday {
+			continue
+		}
+		return t
+	}
+}
+
+// collect returns the report of the period from the last report, or
+// from startup, to end, when the pipelines' usage had reached usage,
+// and starts the next period.
+func (s *Scheduler) collect(end time.Time, usage map[string]pipeline.Usage) *Report {
+	s.mu.Lock()
+	start, baseline, counts := s.start, s.baseline, s.counts
+	s.start, s.baseline, s.counts = end, usage, make(map[string]*queryCounts)
+	s.mu.Unlock()
+
+	report := &Report{Schedule: s.cfg.Schedule, Start: start, End: end, Pipelines: []PipelineReport{}}
+	names := make(map[string]bool)
+	for name := range usage {
+		names[name] = true
+	}
+	for name := range counts {
+		names[name] = true
+	}
+	for name := range names {
+		pr := PipelineReport{Name: name}
+		if u, ok := usage[name]; ok {
+			prev := baseline[name]
+			pr.Embedding = usageSince(u.Embedding, prev.Embedding)
+			pr.Completion = usageSince(u.Completion, prev.Completion)
+			pr.Cost = costSince(u.Cost, prev.Cost)
+		}
+		if c := counts[name]; c != nil {
+			pr.Queries, pr.Failed = c.queries, c.failed
+			pr.FailureRate = float64(c.failed) / float64(c.queries)
+			pr.TopQueries = topQueries(c.asked, s.cfg.TopQueries)
+		}
+		report.Pipelines = append(report.Pipelines, pr)
+	}
+	slices.SortFunc(report.Pipelines, func(a, b PipelineReport) int { return cmp.Compare(a.Name, b.Name) })
+	return report
+}
+
+// usage returns the stats of the reported pipelines, by name.
+func (s *Scheduler) usage(stats Stats) map[string]pipeline.Usage {
+	usage := make(map[string]pipeline.Usage)
+	for _, u := range stats.Stats() {
+		if s.reported(u.Name) {
+			usage[u.Name] = u
+		}
+	}
+	return usage
+}
+
+// reported reports whether a pipeline is included in the reports.
+func (s *Scheduler) reported(name string) bool {
+	return len(s.cfg.Pipelines) == 0 || slices.Contains(s.cfg.Pipelines, name)
+}
+
+// deliver sends a report to each configured destination, returning the
+// errors of those that failed.
+func (s *Scheduler) deliver(ctx context.Context, r *Report) error {
+	var errs []error
+	if s.webhook != nil {
+		if err := s.webhook.send(ctx, r); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if s.email != nil {
+		if err := s.email.send(r, s.loc); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// usageSince returns the tokens used since prev. A pipeline's usage
+// starts over when the configuration is reloaded, so usage lower than
+// prev is all new.
+func usageSince(cur, prev llmlib.TokenUsage) llmlib.TokenUsage {
+	if cur.TotalTokens < prev.TotalTokens {
+		return cur
+	}
+	return llmlib.TokenUsage{
+		PromptTokens:             cur.PromptTokens - prev.PromptTokens,
+		CompletionTokens:         cur.CompletionTokens - prev.CompletionTokens,
+		TotalTokens:              cur.TotalTokens - prev.TotalTokens,
+		CacheCreationInputTokens: cur.CacheCreationInputTokens - prev.CacheCreationInputTokens,
+		CacheReadInputTokens:     cur.CacheReadInputTokens - prev.CacheReadInputTokens,
+	}
+}
+
+// costSince returns the cost spent since prev, or zero for a pipeline
+// without pricing.
+func costSince(cur, prev *pipeline.Cost) float64 {
+	if cur == nil {
+		return 0
+	}
+	if prev == nil || cur.Total < prev.Total {
+		return cur.Total
+	}
+	return cur.Total - prev.Total
+}
+
+// normalizeQuery returns the form a query is counted under: anonymized,
+// lower case, with its whitespace collapsed, and truncated to
+// maxQueryLength.
+func normalizeQuery(query string) string {
+	query = strings.ToLower(strings.Join(strings.Fields(pipeline.Anonymize(query)), " "))
+	if r := []rune(query); len(r) > maxQueryLength {
+		query = string(r[:maxQueryLength]) + "…"
+	}
+	return query
+}
+
+// topQueries returns the n queries asked most often, most often first
+// and then alphabetically.
+func topQueries(asked map[string]int, n int) []QueryCount {
+	top := make([]QueryCount, 0, len(asked))
+	for query, count := range asked {
+		top = append(top, QueryCount{Query: query, Count: count})
+	}
+	slices.SortFunc(top, func(a, b QueryCount) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Query, b.Query)
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
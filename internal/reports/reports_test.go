@@ -0,0 +1,227 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package reports
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// fakeStats serves a fixed set of pipeline stats.
+type fakeStats []pipeline.Usage
+
+func (f fakeStats) Stats() []pipeline.Usage { return f }
+
+func testConfig() config.ReportsConfig {
+	return config.ReportsConfig{
+		Enabled:    true,
+		Schedule:   config.ReportScheduleDaily,
+		Time:       "08:00",
+		Weekday:    "monday",
+		Timezone:   "UTC",
+		TopQueries: 2,
+	}
+}
+
+func TestScheduler_Next(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		timezone string
+		after    string
+		want     string
+	}{
+		{"daily before the time", config.ReportScheduleDaily, "UTC",
+			"2026-10-14T07:59:00Z", "2026-10-14T08:00:00Z"},
+		{"daily at the time", config.ReportScheduleDaily, "UTC",
+			"2026-10-14T08:00:00Z", "2026-10-15T08:00:00Z"},
+		{"weekly", config.ReportScheduleWeekly, "UTC",
+			"2026-10-14T09:00:00Z", "2026-10-19T08:00:00Z"},
+		{"weekly at month end", config.ReportScheduleWeekly, "UTC",
+			"2026-10-27T09:00:00Z", "2026-11-02T08:00:00Z"},
+		// Clocks in New York go back on 1 November, so 08:00 local is
+		// 12:00 UTC before and 13:00 UTC after.
+		{"across a clock change", config.ReportScheduleDaily, "America/New_York",
+			"2026-10-31T12:30:00Z", "2026-11-01T13:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Schedule, cfg.Timezone = tt.schedule, tt.timezone
+			s, err := New(cfg, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			after, _ := time.Parse(time.RFC3339, tt.after)
+			want, _ := time.Parse(time.RFC3339, tt.want)
+			if got := s.next(after); !got.Equal(want) {
+				t.Errorf("expected %s, got %s", want, got.UTC())
+			}
+		})
+	}
+}
+
+func TestScheduler_Collect(t *testing.T) {
+	cfg := testConfig()
+	cfg.Pipelines = []string{"docs", "support"}
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	start := s.start
+	s.baseline = s.usage(fakeStats{
+		{Name: "docs", Embedding: llmlib.TokenUsage{PromptTokens: 100, TotalTokens: 100},
+			Completion: llmlib.TokenUsage{PromptTokens: 1000, CompletionTokens: 200, TotalTokens: 1200},
+			Cost:       &pipeline.Cost{Total: 1.5}},
+		{Name: "support", Completion: llmlib.TokenUsage{TotalTokens: 5000}},
+	})
+
+	for _, q := range []string{"How do I reset  my password?", "how do i reset my password?",
+		"Where is invoice 12345678?", "Where is invoice 87654321?", "What is pgEdge?"} {
+		s.RecordQuery("docs", q, false)
+	}
+	s.RecordQuery("docs", "What is pgEdge?", true)
+	s.RecordQuery("internal", "Not reported", true)
+
+	// The support pipeline's usage started over, as after a reload.
+	end := start.Add(24 * time.Hour)
+	report := s.collect(end, s.usage(fakeStats{
+		{Name: "docs", Embedding: llmlib.TokenUsage{PromptTokens: 160, TotalTokens: 160},
+			Completion: llmlib.TokenUsage{PromptTokens: 1600, CompletionTokens: 300, TotalTokens: 1900},
+			Cost:       &pipeline.Cost{Total: 2}},
+		{Name: "support", Completion: llmlib.TokenUsage{TotalTokens: 700}},
+		{Name: "internal", Completion: llmlib.TokenUsage{TotalTokens: 9000}},
+	}))
+
+	if !report.Start.Equal(start) || !report.End.Equal(end) || len(report.Pipelines) != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	docs, support := report.Pipelines[0], report.Pipelines[1]
+	if docs.Name != "docs" || docs.Queries != 6 || docs.Failed != 1 || docs.FailureRate != 1.0/6 {
+		t.Errorf("unexpected query counts %+v", docs)
+	}
+	if docs.Embedding.TotalTokens != 60 || docs.Completion.PromptTokens != 600 ||
+		docs.Completion.CompletionTokens != 100 || docs.Cost != 0.5 {
+		t.Errorf("unexpected usage %+v", docs)
+	}
+	want := []QueryCount{
+		{Query: "how do i reset my password?", Count: 2},
+		{Query: "what is pgedge?", Count: 2},
+	}
+	if len(docs.TopQueries) != 2 || docs.TopQueries[0] != want[0] || docs.TopQueries[1] != want[1] {
+		t.Errorf("expected top queries %v, got %v", want, docs.TopQueries)
+	}
+	if support.Name != "support" || support.Queries != 0 || support.Completion.TotalTokens != 700 {
+		t.Errorf("expected the support pipeline's usage since the reload, got %+v", support)
+	}
+
+	// The next period starts where this one ended.
+	s.RecordQuery("docs", "Where is invoice 12345678?", false)
+	next := s.collect(end.Add(24*time.Hour), s.usage(fakeStats{{Name: "docs"}}))
+	if !next.Start.Equal(end) || next.Pipelines[0].Queries != 1 ||
+		next.Pipelines[0].TopQueries[0].Query != "where is invoice [number]?" {
+		t.Errorf("unexpected next report %+v", next)
+	}
+}
+
+func TestScheduler_Deliver(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "secret")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotBody []byte
+	var gotSignature string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(SignatureHeader)
+	}))
+	defer ts.Close()
+
+	cfg := testConfig()
+	cfg.Webhook = config.ReportWebhookConfig{URL: ts.URL, SecretFile: secretFile}
+	cfg.Email = config.ReportEmailConfig{From: "RAG Server <rag@example.com>",
+		To:   []string{"ops@example.com", "Finance <finance@example.com>"},
+		SMTP: config.SMTPConfig{Host: "smtp.example.com", Port: 587}}
+	s, err := New(cfg, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var gotAddr string
+	var gotTo []string
+	var gotMsg []byte
+	s.email.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, msg
+		return nil
+	}
+
+	start := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	report := &Report{Schedule: config.ReportScheduleDaily, Start: start, End: start.Add(24 * time.Hour),
+		Pipelines: []PipelineReport{{Name: "docs", Queries: 40, Failed: 2, FailureRate: 0.05, Cost: 1.25,
+			TopQueries: []QueryCount{{Query: "what is pgedge?", Count: 7}}}}}
+	if err := s.deliver(context.Background(), report); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got Report
+	if err := json.Unmarshal(gotBody, &got); err != nil || got.Pipelines[0].Queries != 40 {
+		t.Errorf("unexpected webhook body %s: %v", gotBody, err)
+	}
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(gotBody)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); gotSignature != want {
+		t.Errorf("expected signature %q, got %q", want, gotSignature)
+	}
+
+	if gotAddr != "smtp.example.com:587" || len(gotTo) != 2 || gotTo[1] != "finance@example.com" {
+		t.Errorf("unexpected recipients %s %v", gotAddr, gotTo)
+	}
+	msg := string(gotMsg)
+	for _, want := range []string{
+		"Subject: RAG server daily usage report: 2026-10-16 to 2026-10-17\r\n",
+		"Auto-Submitted: auto-generated\r\n",
+		"Failed:            2 (5.0%)",
+		"Estimated cost:    $1.25",
+		"      7  what is pgedge?",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected the message to contain %q, got:\n%s", want, msg)
+		}
+	}
+
+	// A webhook that refuses the report fails the delivery.
+	s.webhook.url = ts.URL + "/missing"
+	if err := s.deliver(context.Background(), report); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected the webhook's 404 to fail delivery, got %v", err)
+	}
+}
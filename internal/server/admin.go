@@ -0,0 +1,138 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// ReloadResponse is the response of the POST /admin/reload endpoint.
+type ReloadResponse struct {
+	Status    string `json:"status"`
+	Pipelines int    `json:"pipelines"`
+}
+
+// WithReload sets the function the POST /v1/admin/reload endpoint calls
+// to reload the configuration. Without it the endpoint is not
+// registered.
+func WithReload(reload func() error) Option {
+	return func(s *Server) { s.reload = reload }
+}
+
+// adminEnabled reports whether the admin endpoints should be served.
+func (s *Server) adminEnabled() bool {
+	return s.config.Server.Admin.Enabled
+}
+
+// adminRoutes registers the admin endpoints, each behind the admin
+// token, on the API listener's v1 router or the admin listener's own.
+func (s *Server) adminRoutes(r *versionRouter) {
+	r.HandleFunc("GET /admin/pipelines", s.adminAuth(s.handleListPipelines))
+	r.HandleFunc("GET /admin/usage", s.adminAuth(s.handleStats))
+	r.HandleFunc("GET /admin/pipelines/{name}/eval-dataset", s.adminAuth(s.handleExportEvalDataset))
+	r.HandleFunc("POST /admin/pipelines/{name}/eval", s.adminAuth(s.handleEvaluate))
+	r.HandleFunc("POST /admin/eval", s.adminAuth(s.handleEvaluatePipelines))
+	if s.config.Server.Explain.Enabled {
+		r.HandleFunc("POST /admin/pipelines/{name}/explain", s.adminAuth(s.handleExplain))
+	}
+	if s.jobs != nil {
+		r.HandleFunc("POST /admin/pipelines/{name}/tune", s.adminAuth(s.handleTune))
+		r.HandleFunc("GET /admin/jobs/{id}", s.adminAuth(s.handleGetJob))
+	}
+	if s.replay != nil {
+		r.HandleFunc("GET /admin/requests/{id}", s.adminAuth(s.handleGetRequest))
+		r.HandleFunc("POST /admin/requests/{id}/replay", s.adminAuth(s.handleReplay))
+	}
+	if s.reload != nil {
+		r.HandleFunc("POST /admin/reload", s.adminAuth(s.handleReload))
+	}
+}
+
+// handleReload handles the POST /admin/reload endpoint, reloading the
+// configuration as a change to the config file would.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := s.reload(); err != nil {
+		s.respondError(w, http.StatusInternalServerError, "RELOAD_FAILED", err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, ReloadResponse{
+		Status:    "reloaded",
+		Pipelines: len(s.pipelineManager().List()),
+	})
+}
+
+// adminAuth requires the admin token, when one is configured, as a
+// bearer token. The token file is read on each request, so a rotated
+// secret takes effect without a restart.
+func (s *Server) adminAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := s.config.Server.Admin.TokenFile
+		if path == "" {
+			next(w, r)
+			return
+		}
+		token, err := config.ReadSecretFile(path, "admin token")
+		if err != nil {
+			s.logger.Error("failed to read the admin token", "error", err)
+			s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+				"failed to read the admin token")
+			return
+		}
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			s.respondError(w, http.StatusUnauthorized, "UNAUTHORIZED",
+				"a valid admin token is required")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// startAdminListener starts the dedicated admin listener when one is
+// configured (server.admin.port is non-zero). Like the metrics
+// listener, it binds synchronously so a port conflict fails startup,
+// then serves in the background until Shutdown.
+func (s *Server) startAdminListener() error {
+	ac := s.config.Server.Admin
+	if !s.adminEnabled() || ac.Port == 0 {
+		return nil
+	}
+
+	addr := fmt.Sprintf("%s:%d", ac.ListenAddress, ac.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for admin requests: %w", err)
+	}
+
+	router := &versionRouter{prefix: "/v1", mux: http.NewServeMux()}
+	s.adminRoutes(router)
+	s.adminServer = &http.Server{
+		Handler:      s.recoveryMiddleware(s.loggingMiddleware(router.mux)),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+	}
+
+	s.logger.Info("starting admin listener", "address", addr,
+		"auth", ac.TokenFile != "")
+	go func() {
+		if err := s.adminServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("admin listener failed", "error", err)
+		}
+	}()
+	return nil
+}
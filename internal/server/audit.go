@@ -0,0 +1,116 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/audit"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// auditKey is the context key under which a query's pendingAudit is
+// kept, so the token usage of a streamed answer can be added to it.
+type auditKey struct{}
+
+// pendingAudit is a query being recorded in the audit log.
+type pendingAudit struct {
+	entry audit.Entry
+	repro *pipeline.Reproduction
+	usage *pipeline.StageUsage
+	start time.Time
+}
+
+// auditRequest sets up a query asked over HTTP to be recorded in the
+// audit log, when the log is enabled. The query's request ID is
+// returned in the X-Request-ID response header; a query also recorded
+// for replay is audited under the same ID.
+func (s *Server) auditRequest(w http.ResponseWriter, r *http.Request, name string,
+	req pipeline.QueryRequest, replay *pendingReplay) (*http.Request, *pendingAudit) {
+	if s.audit == nil {
+		return r, nil
+	}
+	var id string
+	var repro *pipeline.Reproduction
+	if replay != nil {
+		id, repro = replay.id, replay.repro
+	} else {
+		id = requestID(r)
+		w.Header().Set(requestIDHeader, id)
+	}
+	ctx, pending := s.startAudit(r.Context(), id, name, r.RemoteAddr, req, repro)
+	return r.WithContext(ctx), pending
+}
+
+// startAudit sets up a query to be recorded in the audit log, when the
+// log is enabled: it returns a context recording the documents the
+// answer is written from into repro, or into a new Reproduction when
+// repro is nil, and the pending entry saveAudit completes.
+func (s *Server) startAudit(ctx context.Context, id, name, remoteAddr string,
+	req pipeline.QueryRequest, repro *pipeline.Reproduction) (context.Context, *pendingAudit) {
+	if s.audit == nil {
+		return ctx, nil
+	}
+	if repro == nil {
+		ctx, repro = pipeline.ContextWithReproduction(ctx)
+	}
+	client, _ := s.rateLimitCaller(ctx, remoteAddr)
+	pending := &pendingAudit{
+		entry: audit.Entry{
+			Time:      time.Now().UTC(),
+			RequestID: id,
+			Pipeline:  name,
+			Client:    client,
+			Query:     req.Query,
+			Filter:    req.Filter,
+		},
+		repro: repro,
+		start: time.Now(),
+	}
+	return context.WithValue(ctx, auditKey{}, pending), pending
+}
+
+// auditUsage records the token usage of the query ctx runs, when it is
+// being audited.
+func auditUsage(ctx context.Context, usage *pipeline.StageUsage) {
+	if pending, ok := ctx.Value(auditKey{}).(*pendingAudit); ok {
+		pending.usage = usage
+	}
+}
+
+// saveAudit records a finished query in the audit log, with its outcome
+// label and answer, and the error it failed with, if any; it does
+// nothing unless startAudit set the query up.
+func (s *Server) saveAudit(pending *pendingAudit, status, answer string, err error) {
+	if pending == nil {
+		return
+	}
+	e := pending.entry
+	e.Status = status
+	e.Answer = answer
+	if err != nil {
+		e.Error = err.Error()
+	}
+	e.Usage = pending.usage
+	e.LatencyMS = time.Since(pending.start).Milliseconds()
+	e.Provider = pending.repro.Provider
+	e.Model = pending.repro.Model
+	e.Cached = pending.repro.Cached
+	for i, id := range pending.repro.DocumentIDs {
+		src := audit.Source{ID: id}
+		if i < len(pending.repro.Scores) {
+			src.Score = pending.repro.Scores[i]
+		}
+		e.Sources = append(e.Sources, src)
+	}
+	s.audit.Record(e)
+}
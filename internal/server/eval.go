@@ -0,0 +1,344 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/jobs"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// maxEvalDatasetBytes bounds the eval dataset POST /admin/pipelines/
+// {name}/eval accepts, which may be larger than a query.
+const maxEvalDatasetBytes = 16 << 20
+
+// evaluator looks up the named pipeline's eval support, responding
+// with an error and returning false when there is none.
+func (s *Server) evaluator(w http.ResponseWriter, name string) (pipeline.Evaluator, bool) {
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return nil, false
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return nil, false
+	}
+	evaluator, ok := p.(pipeline.Evaluator)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support evaluation")
+		return nil, false
+	}
+	return evaluator, true
+}
+
+// handleFeedback handles the POST /pipelines/{name}/feedback endpoint,
+// recording a rating of an answer in the pipeline's feedback table, or
+// of the answer to a query the pipeline captured for its eval dataset.
+func (s *Server) handleFeedback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	evaluator, ok := s.evaluator(w, name)
+	if !ok {
+		return
+	}
+
+	var req pipeline.FeedbackRequest
+	if !s.decodeRequest(w, r, "FeedbackRequest", &req) {
+		return
+	}
+
+	if err := evaluator.Feedback(r.Context(), req); err != nil {
+		switch {
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrResponseNotFound):
+			s.respondError(w, http.StatusNotFound, "RESPONSE_NOT_FOUND",
+				"response not found: "+req.ResponseID)
+		case errors.Is(err, pipeline.ErrQueryNotFound):
+			s.respondError(w, http.StatusNotFound, "QUERY_NOT_FOUND",
+				"query not found: "+req.QueryID)
+		default:
+			s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleExportEvalDataset handles the GET /admin/pipelines/{name}/
+// eval-dataset endpoint, writing the queries the pipeline has captured
+// as newline-delimited JSON, one case per line, oldest first.
+func (s *Server) handleExportEvalDataset(w http.ResponseWriter, r *http.Request) {
+	evaluator, ok := s.evaluator(w, r.PathValue("name"))
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, ec := range evaluator.EvalDataset() {
+		if err := enc.Encode(ec); err != nil {
+			s.logger.Warn("failed to write eval dataset", "error", err)
+			return
+		}
+	}
+}
+
+// handleEvaluate handles the POST /admin/pipelines/{name}/eval
+// endpoint, evaluating the pipeline against the eval dataset in the
+// request body, in the format the export writes. See evalRequest for
+// the query parameters.
+func (s *Server) handleEvaluate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	evaluator, ok := s.evaluator(w, name)
+	if !ok {
+		return
+	}
+	cases, opts, csvFormat, ok := s.evalRequest(w, r)
+	if !ok {
+		return
+	}
+
+	report, ok := s.evaluate(w, r.Context(), name, evaluator, cases, opts)
+	if !ok {
+		return
+	}
+	if csvFormat {
+		s.respondEvalCSV(w, []pipeline.PipelineEvalReport{{Pipeline: name, EvalReport: report}})
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
+
+// EvalResponse is the response body of POST /admin/eval: each
+// pipeline's evaluation report, in the order the pipelines were named,
+// or by name when none were.
+type EvalResponse struct {
+	Pipelines []pipeline.PipelineEvalReport `json:"pipelines"`
+}
+
+// handleEvaluatePipelines handles the POST /admin/eval endpoint,
+// evaluating each pipeline the pipeline query parameters name, or
+// every pipeline, against the eval dataset in the request body, so
+// they can be compared on the same questions.
+func (s *Server) handleEvaluatePipelines(w http.ResponseWriter, r *http.Request) {
+	names := r.URL.Query()["pipeline"]
+	if len(names) == 0 {
+		for _, info := range s.pipelineManager().List() {
+			names = append(names, info.Name)
+		}
+		slices.Sort(names)
+	}
+	evaluators := make([]pipeline.Evaluator, len(names))
+	for i, name := range names {
+		evaluator, ok := s.evaluator(w, name)
+		if !ok {
+			return
+		}
+		evaluators[i] = evaluator
+	}
+	cases, opts, csvFormat, ok := s.evalRequest(w, r)
+	if !ok {
+		return
+	}
+
+	resp := EvalResponse{Pipelines: []pipeline.PipelineEvalReport{}}
+	for i, name := range names {
+		report, ok := s.evaluate(w, r.Context(), name, evaluators[i], cases, opts)
+		if !ok {
+			return
+		}
+		resp.Pipelines = append(resp.Pipelines, pipeline.PipelineEvalReport{Pipeline: name, EvalReport: report})
+	}
+	if csvFormat {
+		s.respondEvalCSV(w, resp.Pipelines)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// evalRequest reads an evaluation request: the eval dataset in the
+// body, k setting how many documents each query retrieves, judge
+// whether answers are judged against the cases' reference answers, and
+// format whether the report is written as json, the default, or csv.
+// It responds with an error and returns false when the request is
+// invalid.
+func (s *Server) evalRequest(w http.ResponseWriter, r *http.Request) ([]pipeline.EvalCase,
+	pipeline.EvalOptions, bool, bool) {
+	var opts pipeline.EvalOptions
+	params := r.URL.Query()
+	if v := params.Get("k"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+				"k must be a positive integer")
+			return nil, opts, false, false
+		}
+		opts.K = n
+	}
+	if v := params.Get("judge"); v != "" {
+		judge, err := strconv.ParseBool(v)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+				"judge must be true or false")
+			return nil, opts, false, false
+		}
+		opts.Judge = judge
+	}
+	format := params.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"format must be json or csv")
+		return nil, opts, false, false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxEvalDatasetBytes)
+	cases, err := pipeline.ReadEvalDataset(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("dataset exceeds maximum size of %d bytes", maxBytesErr.Limit))
+			return nil, opts, false, false
+		}
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return nil, opts, false, false
+	}
+	return cases, opts, format == "csv", true
+}
+
+// evaluate evaluates a pipeline against a dataset, responding with an
+// error and returning false when the evaluation fails.
+func (s *Server) evaluate(w http.ResponseWriter, ctx context.Context, name string,
+	evaluator pipeline.Evaluator, cases []pipeline.EvalCase, opts pipeline.EvalOptions) (*pipeline.EvalReport, bool) {
+	report, err := evaluator.Evaluate(ctx, cases, opts)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrInvalidRequest) {
+			s.respondInvalidRequest(w, err)
+			return nil, false
+		}
+		s.logger.Error("evaluation failed", "pipeline", name, "error", err)
+		s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		return nil, false
+	}
+	return report, true
+}
+
+// respondEvalCSV writes evaluation reports as CSV.
+func (s *Server) respondEvalCSV(w http.ResponseWriter, reports []pipeline.PipelineEvalReport) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	if err := pipeline.WriteEvalCSV(w, reports); err != nil {
+		s.logger.Warn("failed to write evaluation report", "error", err)
+	}
+}
+
+// TuneJobKind is the kind of the jobs that compare a pipeline's
+// retrieval settings.
+const TuneJobKind = "tune"
+
+// TuneRequest is the request body of POST /admin/pipelines/{name}/tune.
+// Without a dataset, the pipeline's captured eval dataset is used;
+// without variants, the pipeline's defaults are compared.
+type TuneRequest struct {
+	K        int                      `json:"k,omitempty"`
+	Variants []pipeline.TuningVariant `json:"variants,omitempty"`
+	Dataset  []pipeline.EvalCase      `json:"dataset,omitempty"`
+}
+
+// handleTune handles the POST /admin/pipelines/{name}/tune endpoint,
+// starting a background job that replays an eval dataset under each
+// variant of the pipeline's retrieval settings. The job's single task
+// reports progress in queries evaluated, and its result is the
+// comparison report.
+func (s *Server) handleTune(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	evaluator, ok := s.evaluator(w, name)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxEvalDatasetBytes)
+	var req TuneRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("request exceeds maximum size of %d bytes", maxBytesErr.Limit))
+			return
+		}
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid request body: "+err.Error())
+		return
+	}
+	if req.K < 0 {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "k must not be negative")
+		return
+	}
+	if len(req.Dataset) == 0 {
+		req.Dataset = evaluator.EvalDataset()
+	}
+	if len(req.Dataset) == 0 {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"no dataset given, and the pipeline has captured no queries")
+		return
+	}
+
+	job, err := s.jobs.Submit(r.Context(), TuneJobKind, name, []jobs.Step{s.tuneStep(name, req)})
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "60")
+			s.respondError(w, http.StatusServiceUnavailable, "QUEUE_FULL",
+				"too many jobs are waiting; try again later")
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	s.logger.Info("tuning job started", "job", job.ID, "pipeline", name,
+		"cases", len(req.Dataset), "variants", len(req.Variants))
+
+	prefix := strings.TrimSuffix(r.URL.Path, "/pipelines/"+name+"/tune")
+	w.Header().Set("Location", prefix+"/jobs/"+job.ID)
+	s.respondJSON(w, http.StatusAccepted, job)
+}
+
+// tuneStep returns the job step that runs a tuning request. Like an
+// ingestion step, it looks the pipeline up when it runs.
+func (s *Server) tuneStep(pipelineName string, req TuneRequest) jobs.Step {
+	var report *pipeline.TuningReport
+	return jobs.Step{
+		Name: "tune",
+		Run: func(ctx context.Context, progress func(done, total int)) error {
+			p, err := s.pipelineManager().GetExecutor(pipelineName)
+			if err != nil {
+				return err
+			}
+			evaluator, ok := p.(pipeline.Evaluator)
+			if !ok {
+				return errors.New("pipeline does not support evaluation")
+			}
+			report, err = evaluator.Tune(ctx, req.Dataset, req.Variants, req.K, progress)
+			return err
+		},
+		Result: func() any { return report },
+	}
+}
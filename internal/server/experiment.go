@@ -0,0 +1,54 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// variantHeader names the pipeline that answered a query asked of a
+// pipeline running an experiment.
+const variantHeader = "X-Pipeline-Variant"
+
+// variantKey is the context key under which the pipeline a query was
+// routed to by an experiment is kept, so a streamed answer's done event
+// can name it.
+type variantKey struct{}
+
+// routeQuery returns the name and executor of the pipeline that answers
+// a query asked of the named pipeline, whose executor is p. When the
+// pipeline runs an experiment, that is the variant the query is
+// assigned to, keyed by its session so a conversation stays with one
+// variant; the assignment is counted in the metrics and kept in the
+// returned context. Otherwise the pipeline answers the query itself.
+func (s *Server) routeQuery(ctx context.Context, name string, req pipeline.QueryRequest,
+	p pipeline.QueryExecutor) (context.Context, string, pipeline.QueryExecutor, error) {
+	variant, ok := s.pipelineManager().Route(name, req.SessionID)
+	if !ok {
+		return ctx, name, p, nil
+	}
+	if variant != name {
+		var err error
+		if p, err = s.pipelineManager().GetExecutor(variant); err != nil {
+			return ctx, "", nil, err
+		}
+	}
+	s.metrics.ObserveExperiment(name, variant)
+	return context.WithValue(ctx, variantKey{}, variant), variant, p, nil
+}
+
+// queryVariant returns the pipeline routeQuery routed the query ctx
+// runs to by an experiment, or "" when it was not routed by one.
+func queryVariant(ctx context.Context) string {
+	variant, _ := ctx.Value(variantKey{}).(string)
+	return variant
+}
@@ -0,0 +1,560 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/server/ragv1"
+	"github.com/pgEdge/pgedge-rag-server/internal/session"
+)
+
+// grpcService serves the gRPC API's RAGService from the server's
+// pipelines. Queries are run, limited and counted as they are over
+// HTTP; request metadata stands in for HTTP headers, such as for
+// {{header:X-Tenant}} filter variables.
+type grpcService struct {
+	ragv1.UnimplementedRAGServiceServer
+	s *Server
+}
+
+// newGRPCServer creates the gRPC server of the API, with server
+// reflection so tools such as grpcurl can list its methods.
+func (s *Server) newGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.grpcUnaryInterceptor),
+		grpc.ChainStreamInterceptor(s.grpcStreamInterceptor))
+	srv := grpc.NewServer(opts...)
+	ragv1.RegisterRAGServiceServer(srv, &grpcService{s: s})
+	reflection.Register(srv)
+	return srv
+}
+
+// startGRPCListener starts the gRPC listener when server.grpc is
+// enabled, using the server's TLS certificate when TLS is. It binds
+// synchronously so a port conflict fails startup, then serves in the
+// background until Shutdown.
+func (s *Server) startGRPCListener() error {
+	gc := s.config.Server.GRPC
+	if !gc.Enabled {
+		return nil
+	}
+
+	var opts []grpc.ServerOption
+	if tc := s.config.Server.TLS; tc.Enabled {
+		creds, err := credentials.NewServerTLSFromFile(tc.CertFile, tc.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS certificate for gRPC: %w", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	addr := fmt.Sprintf("%s:%d", gc.ListenAddress, gc.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for gRPC: %w", err)
+	}
+
+	s.grpcServer = s.newGRPCServer(opts...)
+	s.logger.Info("starting gRPC listener", "address", addr, "tls", s.config.Server.TLS.Enabled)
+	go func() {
+		if err := s.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			s.logger.Error("gRPC listener failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// stopGRPC stops the gRPC server, waiting until ctx is done for
+// in-flight calls to finish and then ending those still running.
+func (s *Server) stopGRPC(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		<-done
+	}
+}
+
+// grpcUnaryInterceptor logs each unary call and recovers from panics,
+// as the HTTP middleware does for requests.
+func (s *Server) grpcUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp any, err error) {
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.logger.Error("panic recovered", "error", rec, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		s.logGRPCCall(ctx, info.FullMethod, err, start)
+	}()
+	return handler(ctx, req)
+}
+
+// grpcStreamInterceptor logs each streaming call and recovers from
+// panics, as the HTTP middleware does for requests.
+func (s *Server) grpcStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		if rec := recover(); rec != nil {
+			s.logger.Error("panic recovered", "error", rec, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "internal server error")
+		}
+		s.logGRPCCall(ss.Context(), info.FullMethod, err, start)
+	}()
+	return handler(srv, ss)
+}
+
+// logGRPCCall logs a finished gRPC call.
+func (s *Server) logGRPCCall(ctx context.Context, method string, err error, start time.Time) {
+	s.logger.Info("grpc request",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration", time.Since(start).String(),
+		"remote", grpcRemoteAddr(ctx))
+}
+
+// Query answers a question with a pipeline, signing the answer when
+// the server signs answers.
+func (g *grpcService) Query(ctx context.Context, in *ragv1.QueryRequest) (*ragv1.QueryResponse, error) {
+	s := g.s
+	ctx, name, p, req, err := s.startGRPCQuery(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	ctx, audited := s.startAudit(ctx, newRequestID(), name, grpcRemoteAddr(ctx), req, nil)
+
+	start := time.Now()
+	queryCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+
+	resp, err := p.ExecuteWithOptions(queryCtx, req)
+	if err != nil {
+		status := requestStatusError
+		var stageErr *pipeline.StageTimeoutError
+		if isRequestTimeout(queryCtx) {
+			status, err = requestStatusTimeout, context.DeadlineExceeded
+		} else if errors.As(err, &stageErr) {
+			status = requestStatusTimeout
+		}
+		s.observeQuery(name, req.Query, status, time.Since(start))
+		s.saveAudit(audited, status, "", err)
+		s.logGRPCQueryError(name, status, err)
+		return nil, grpcError(ctx, err)
+	}
+
+	s.observeQuery(name, req.Query, requestStatusOK, time.Since(start))
+	s.chargeTokens(ctx, resp.Usage)
+	auditUsage(ctx, resp.Usage)
+	s.saveAudit(audited, requestStatusOK, resp.Answer, nil)
+	s.recordSessionTurn(ctx, req, resp.Answer)
+	return &ragv1.QueryResponse{
+		Answer:         resp.Answer,
+		Sources:        grpcSources(resp.Sources),
+		TokensUsed:     int32(resp.TokensUsed),
+		Usage:          grpcStageUsage(resp.Usage),
+		FormatWarnings: resp.FormatWarnings,
+		Citations:      grpcCitations(resp.Citations),
+		Guardrails:     resp.Guardrails,
+		Timings:        grpcTimings(resp.Timings),
+		Cached:         resp.Cached,
+		QueryId:        resp.QueryID,
+		ResponseId:     resp.ResponseID,
+		Provenance:     grpcProvenance(resp.Provenance),
+		Signature:      grpcSignature(s.signAnswer(p, resp.Answer, resp.Provenance, resp.AnsweredBy)),
+	}, nil
+}
+
+// QueryStream answers a question with a pipeline, sending the answer
+// as it is generated. A query that fails, times out or is ended by the
+// server shutting down ends the stream with an error status rather
+// than a done message.
+func (g *grpcService) QueryStream(in *ragv1.QueryRequest, stream ragv1.RAGService_QueryStreamServer) error {
+	s := g.s
+	ctx, name, p, req, err := s.startGRPCQuery(stream.Context(), in)
+	if err != nil {
+		return err
+	}
+	ctx, audited := s.startAudit(ctx, newRequestID(), name, grpcRemoteAddr(ctx), req, nil)
+
+	start := time.Now()
+	queryCtx, cancel := context.WithTimeout(ctx, s.requestTimeout)
+	defer cancel()
+
+	var sendErr error
+	failed := false
+	status, answer, err := s.runStream(queryCtx, p, req, func(event pipeline.StreamEvent) {
+		var msg *ragv1.QueryStreamResponse
+		switch event.Type {
+		case "sources":
+			msg = &ragv1.QueryStreamResponse{Event: &ragv1.QueryStreamResponse_Sources{
+				Sources: &ragv1.Sources{Sources: grpcSources(event.Sources)}}}
+		case "chunk":
+			msg = &ragv1.QueryStreamResponse{Event: &ragv1.QueryStreamResponse_Chunk{Chunk: event.Content}}
+		case "done":
+			if failed {
+				return
+			}
+			msg = &ragv1.QueryStreamResponse{Event: &ragv1.QueryStreamResponse_Done{Done: &ragv1.Done{
+				Usage:          grpcStageUsage(event.Usage),
+				FormatWarnings: event.FormatWarnings,
+				Citations:      grpcCitations(event.Citations),
+				Guardrails:     event.Guardrails,
+				Timings:        grpcTimings(event.Timings),
+				Cached:         event.Cached,
+				QueryId:        event.QueryID,
+				ResponseId:     event.ResponseID,
+				Provenance:     grpcProvenance(event.Provenance),
+				Signature:      grpcSignature(event.Signature),
+			}}}
+		case "error", "server_shutting_down":
+			failed = true
+			return
+		default:
+			return
+		}
+		if sendErr == nil {
+			sendErr = stream.Send(msg)
+		}
+	})
+	s.observeQuery(name, req.Query, status, time.Since(start))
+	s.saveAudit(audited, status, answer, err)
+	if status == requestStatusOK {
+		s.recordSessionTurn(ctx, req, answer)
+	}
+
+	if err != nil {
+		s.logGRPCQueryError(name, status, err)
+		return grpcError(ctx, err)
+	}
+	return sendErr
+}
+
+// ListPipelines lists the configured pipelines.
+func (g *grpcService) ListPipelines(ctx context.Context, in *ragv1.ListPipelinesRequest) (*ragv1.ListPipelinesResponse, error) {
+	resp := &ragv1.ListPipelinesResponse{}
+	for _, p := range g.s.pipelineManager().List() {
+		resp.Pipelines = append(resp.Pipelines, &ragv1.Pipeline{Name: p.Name, Description: p.Description})
+	}
+	return resp, nil
+}
+
+// Health checks that each pipeline's providers are reachable, as the
+// /health endpoint does.
+func (g *grpcService) Health(ctx context.Context, in *ragv1.HealthRequest) (*ragv1.HealthResponse, error) {
+	pipelines := g.s.pipelineManager().Health(ctx)
+	resp := &ragv1.HealthResponse{Status: healthStatus(pipelines)}
+	for _, p := range pipelines {
+		resp.Pipelines = append(resp.Pipelines, &ragv1.PipelineHealth{
+			Name:       p.Name,
+			Embedding:  &ragv1.ProviderHealth{Reachable: p.Embedding.Reachable, Error: p.Embedding.Error},
+			Completion: &ragv1.ProviderHealth{Reachable: p.Completion.Reachable, Error: p.Completion.Error},
+		})
+	}
+	return resp, nil
+}
+
+// startGRPCQuery prepares a gRPC query as the HTTP middleware and
+// handler prepare a query request: it looks up the pipeline, converts
+// the request, puts the call's filter variables and trace headers on
+// its context, admits it under the rate limits, and routes it to the
+// variant that answers it when the pipeline runs an experiment, naming
+// the variant in the x-pipeline-variant header. It returns the context
+// to run the query with and the name of the pipeline that answers it.
+func (s *Server) startGRPCQuery(ctx context.Context, in *ragv1.QueryRequest) (context.Context,
+	string, pipeline.QueryExecutor, pipeline.QueryRequest, error) {
+	name := in.GetPipeline()
+	if name == "" {
+		return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.InvalidArgument, "pipeline name required")
+	}
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.NotFound, "pipeline not found: "+name)
+		}
+		return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.Internal, err.Error())
+	}
+	req, err := grpcQueryRequest(in)
+	if err != nil {
+		return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if req.Query == "" {
+		return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.InvalidArgument, "query is required")
+	}
+
+	header := grpcHeader(ctx)
+	ctx = database.WithFilterVars(ctx, s.filterVars(header))
+	if len(s.config.Server.TraceHeaders) > 0 {
+		ctx = ragllm.ContextWithTraceHeaders(ctx, s.traceHeaders(header))
+	}
+
+	if limits := s.rateLimits(ctx, name, grpcRemoteAddr(ctx)); len(limits) > 0 {
+		if wait, ok := s.limiter.allow(limits); !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+			return nil, "", nil, pipeline.QueryRequest{}, status.Errorf(codes.ResourceExhausted,
+				"rate limit exceeded; retry in %d seconds", seconds)
+		}
+		ctx = context.WithValue(ctx, rateLimitsKey{}, limits)
+	}
+
+	if req.SessionID != "" {
+		if err := s.grpcSessionHistory(ctx, name, &req); err != nil {
+			return nil, "", nil, pipeline.QueryRequest{}, err
+		}
+	}
+
+	ctx, served, p, err := s.routeQuery(ctx, name, req, p)
+	if err != nil {
+		return nil, "", nil, pipeline.QueryRequest{}, status.Error(codes.Internal, err.Error())
+	}
+	if variant := queryVariant(ctx); variant != "" {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(variantHeader, variant))
+	}
+	return ctx, served, p, req, nil
+}
+
+// grpcSessionHistory prepends the history of the query's session to
+// its messages, as applySessionHistory does over HTTP, returning the
+// gRPC status of a session that cannot be used.
+func (s *Server) grpcSessionHistory(ctx context.Context, name string, req *pipeline.QueryRequest) error {
+	err := s.sessionHistory(ctx, name, req)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, pipeline.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, session.ErrNotFound):
+		return status.Error(codes.NotFound, "session not found: "+req.SessionID)
+	default:
+		s.logger.Error("session store failed", "session_id", req.SessionID, "error", err)
+		return status.Error(codes.Internal, "session store failed")
+	}
+}
+
+// logGRPCQueryError logs a query that failed with an error, unless the
+// error was the caller's, as over HTTP.
+func (s *Server) logGRPCQueryError(name, status string, err error) {
+	if status == requestStatusError && !errors.Is(err, pipeline.ErrInvalidRequest) &&
+		!errors.Is(err, pipeline.ErrBudgetExceeded) && !errors.Is(err, ragllm.ErrProviderBusy) {
+		s.logger.Error("pipeline execution failed", "pipeline", name, "error", err)
+	}
+}
+
+// grpcError returns the gRPC status of a failed query. A query refused
+// for its pipeline's budget or a busy provider carries a retry-after
+// header, as over HTTP.
+func grpcError(ctx context.Context, err error) error {
+	var stageErr *pipeline.StageTimeoutError
+	var budgetErr *pipeline.BudgetExceededError
+	switch {
+	case errors.Is(err, errServerShuttingDown):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.As(err, &stageErr):
+		return status.Error(codes.DeadlineExceeded, stageErr.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request took too long to process")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, pipeline.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.As(err, &budgetErr):
+		seconds := max(1, int(math.Ceil(time.Until(budgetErr.Resets).Seconds())))
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, pipeline.ErrBudgetExceeded):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, ragllm.ErrProviderBusy):
+		_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", providerBusyRetryAfter))
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// grpcHeader returns a call's metadata as HTTP headers.
+func grpcHeader(ctx context.Context) http.Header {
+	header := make(http.Header)
+	md, _ := metadata.FromIncomingContext(ctx)
+	for key, values := range md {
+		for _, v := range values {
+			header.Add(key, v)
+		}
+	}
+	return header
+}
+
+// grpcRemoteAddr returns the address a call came from.
+func grpcRemoteAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// grpcQueryRequest converts a gRPC query to a pipeline query. The
+// filter is taken in the JSON form the HTTP API accepts.
+func grpcQueryRequest(in *ragv1.QueryRequest) (pipeline.QueryRequest, error) {
+	req := pipeline.QueryRequest{
+		Query:          in.GetQuery(),
+		TopN:           int(in.GetTopN()),
+		FilterPreset:   in.GetFilterPreset(),
+		IncludeSources: in.GetIncludeSources(),
+		IncludeTimings: in.GetIncludeTimings(),
+		StopSequences:  in.GetStopSequences(),
+		AnswerLength:   in.GetAnswerLength(),
+		SessionID:      in.GetSessionId(),
+	}
+	if in.GetFilter() != nil {
+		data, err := protojson.Marshal(in.GetFilter())
+		if err != nil {
+			return req, fmt.Errorf("invalid filter: %w", err)
+		}
+		if err := json.Unmarshal(data, &req.Filter); err != nil {
+			return req, fmt.Errorf("invalid filter: %w", err)
+		}
+	}
+	for _, m := range in.GetMessages() {
+		req.Messages = append(req.Messages, pipeline.Message{Role: m.GetRole(), Content: m.GetContent()})
+	}
+	if len(in.GetLogitBias()) > 0 {
+		req.LogitBias = make(map[string]int, len(in.GetLogitBias()))
+		for token, bias := range in.GetLogitBias() {
+			req.LogitBias[token] = int(bias)
+		}
+	}
+	return req, nil
+}
+
+// grpcStruct converts metadata to a Struct by way of JSON, so values
+// are given as the HTTP API gives them. It returns nil for no metadata.
+func grpcStruct(m map[string]interface{}) *structpb.Struct {
+	if len(m) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	st := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, st); err != nil {
+		return nil
+	}
+	return st
+}
+
+// grpcSources converts sources to their gRPC form.
+func grpcSources(sources []pipeline.Source) []*ragv1.Source {
+	var out []*ragv1.Source
+	for _, src := range sources {
+		out = append(out, &ragv1.Source{Id: src.ID, Content: src.Content, Score: src.Score,
+			Metadata: grpcStruct(src.Metadata)})
+	}
+	return out
+}
+
+// grpcCitations converts citations to their gRPC form.
+func grpcCitations(citations []pipeline.Citation) []*ragv1.Citation {
+	var out []*ragv1.Citation
+	for _, c := range citations {
+		out = append(out, &ragv1.Citation{Marker: int32(c.Marker), Id: c.ID, Score: c.Score,
+			Metadata: grpcStruct(c.Metadata)})
+	}
+	return out
+}
+
+// grpcProvenance converts an answer's document provenance to its gRPC
+// form.
+func grpcProvenance(prov []pipeline.DocumentProvenance) []*ragv1.DocumentProvenance {
+	var out []*ragv1.DocumentProvenance
+	for _, d := range prov {
+		out = append(out, &ragv1.DocumentProvenance{Id: d.ID, ContentHash: d.ContentHash})
+	}
+	return out
+}
+
+// grpcSignature converts an answer's signature to its gRPC form, nil
+// for an unsigned answer.
+func grpcSignature(sig *pipeline.AnswerSignature) *ragv1.AnswerSignature {
+	if sig == nil {
+		return nil
+	}
+	return &ragv1.AnswerSignature{Algorithm: sig.Algorithm, KeyId: sig.KeyID,
+		Payload: sig.Payload, Signature: sig.Signature}
+}
+
+// grpcTokenUsage converts a stage's token usage to its gRPC form, nil
+// for a stage that did not run.
+func grpcTokenUsage(u *llmlib.TokenUsage) *ragv1.TokenUsage {
+	if u == nil {
+		return nil
+	}
+	return &ragv1.TokenUsage{
+		PromptTokens:             int32(u.PromptTokens),
+		CompletionTokens:         int32(u.CompletionTokens),
+		TotalTokens:              int32(u.TotalTokens),
+		CacheCreationInputTokens: int32(u.CacheCreationInputTokens),
+		CacheReadInputTokens:     int32(u.CacheReadInputTokens),
+	}
+}
+
+// grpcStageUsage converts a query's token usage to its gRPC form.
+func grpcStageUsage(u *pipeline.StageUsage) *ragv1.StageUsage {
+	if u == nil {
+		return nil
+	}
+	return &ragv1.StageUsage{
+		QueryExpansion: grpcTokenUsage(u.QueryExpansion),
+		HistorySummary: grpcTokenUsage(u.HistorySummary),
+		ContextSummary: grpcTokenUsage(u.ContextSummary),
+		Embedding:      grpcTokenUsage(&u.Embedding),
+		Rerank:         grpcTokenUsage(u.Rerank),
+		Completion:     grpcTokenUsage(&u.Completion),
+		Groundedness:   grpcTokenUsage(u.Groundedness),
+		Cost:           u.Cost,
+	}
+}
+
+// grpcTimings converts a query's timings to their gRPC form.
+func grpcTimings(t *pipeline.Timings) *ragv1.Timings {
+	if t == nil {
+		return nil
+	}
+	return &ragv1.Timings{EmbedMs: t.EmbedMS, SearchMs: t.SearchMS, TtfbMs: t.TTFBMS, TotalMs: t.TotalMS}
+}
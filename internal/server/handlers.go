@@ -15,8 +15,13 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
 	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/session"
 )
 
 // HealthResponse is the response for the health check endpoint.
@@ -30,6 +35,12 @@ type LiveResponse struct {
 	Status string `json:"status"`
 }
 
+// ReadyResponse is the response for the readiness endpoint.
+type ReadyResponse struct {
+	Status    string                       `json:"status"`
+	Pipelines []pipeline.PipelineReadiness `json:"pipelines,omitempty"`
+}
+
 // PipelinesResponse is the response for the list pipelines endpoint.
 type PipelinesResponse struct {
 	Pipelines []pipeline.Info `json:"pipelines"`
@@ -40,6 +51,11 @@ type StatsResponse struct {
 	Pipelines []pipeline.Usage `json:"pipelines"`
 }
 
+// CreateSessionRequest is the request body for creating a session.
+type CreateSessionRequest struct {
+	Pipeline string `json:"pipeline"`
+}
+
 // ErrorResponse is the standard error response format.
 type ErrorResponse struct {
 	Error ErrorDetail `json:"error"`
@@ -49,6 +65,43 @@ type ErrorResponse struct {
 type ErrorDetail struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Stage   string `json:"stage,omitempty"` // Pipeline stage that timed out, for STAGE_TIMEOUT
+
+	// Fields lists the request body fields an INVALID_REQUEST rejected.
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// queryRequestBody is the body of a query. Besides the QueryRequest
+// fields, it accepts the names other RAG tools use for two of them,
+// top_k and question, as deprecated aliases.
+type queryRequestBody struct {
+	pipeline.QueryRequest
+	TopK     *int    `json:"top_k"`
+	Question *string `json:"question"`
+}
+
+// resolveAliases returns the query with any aliases applied. A field
+// set under its own name takes precedence over its alias. Each alias
+// used is reported in a Warning response header.
+func (b queryRequestBody) resolveAliases(w http.ResponseWriter) pipeline.QueryRequest {
+	req := b.QueryRequest
+	if b.TopK != nil {
+		if req.TopN == 0 {
+			req.TopN = *b.TopK
+			w.Header().Add("Warning", `299 - "top_k is deprecated; use top_n"`)
+		} else {
+			w.Header().Add("Warning", `299 - "top_k ignored; top_n takes precedence"`)
+		}
+	}
+	if b.Question != nil {
+		if req.Query == "" {
+			req.Query = *b.Question
+			w.Header().Add("Warning", `299 - "question is deprecated; use query"`)
+		} else {
+			w.Header().Add("Warning", `299 - "question ignored; query takes precedence"`)
+		}
+	}
+	return req
 }
 
 // maxRequestBodyBytes caps the size of a query request body. Generous
@@ -84,16 +137,50 @@ func (s *Server) handleLive(w http.ResponseWriter, r *http.Request) {
 // /live for a latency-sensitive liveness probe.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	pipelines := s.pipelineManager().Health(r.Context())
+	s.respondJSON(w, http.StatusOK, HealthResponse{Status: healthStatus(pipelines), Pipelines: pipelines})
+}
 
-	status := "healthy"
+// healthStatus returns "healthy", or "degraded" when any pipeline's
+// providers are unreachable.
+func healthStatus(pipelines []pipeline.PipelineHealth) string {
 	for _, p := range pipelines {
 		if !p.Embedding.Reachable || !p.Completion.Reachable {
-			status = "degraded"
+			return "degraded"
+		}
+	}
+	return "healthy"
+}
+
+// handleReady handles the GET /ready endpoint, a readiness probe: it
+// pings every pipeline's database, and its providers too when the
+// providers query parameter is true, and responds 503 with "not_ready"
+// when any of them is unreachable, so a load balancer stops routing
+// queries to a server that cannot answer them. Unlike /health, the
+// providers are not checked by default, since pinging some of them
+// makes a billed request.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	providers := false
+	if v := r.URL.Query().Get("providers"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+				"providers must be true or false")
+			return
+		}
+		providers = b
+	}
+
+	pipelines := s.pipelineManager().Ready(r.Context(), providers)
+
+	status, code := "ready", http.StatusOK
+	for _, p := range pipelines {
+		if !p.Ready {
+			status, code = "not_ready", http.StatusServiceUnavailable
 			break
 		}
 	}
 
-	s.respondJSON(w, http.StatusOK, HealthResponse{Status: status, Pipelines: pipelines})
+	s.respondJSON(w, code, ReadyResponse{Status: status, Pipelines: pipelines})
 }
 
 // handleListPipelines handles the GET /pipelines endpoint.
@@ -102,6 +189,29 @@ func (s *Server) handleListPipelines(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, PipelinesResponse{Pipelines: pipelines})
 }
 
+// handlePipelineDetail handles the GET /pipelines/{name} endpoint,
+// which returns a pipeline's effective configuration.
+func (s *Server) handlePipelineDetail(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	detailer, ok := p.(pipeline.Detailer)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not report its configuration")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, detailer.Detail())
+}
+
 // handleStats handles the GET /stats endpoint, reporting cumulative
 // token usage for every configured pipeline. See issue #21.
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -132,20 +242,11 @@ func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse request body first to validate input before checking pipeline
-	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
-
-	var req pipeline.QueryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		var maxBytesErr *http.MaxBytesError
-		if errors.As(err, &maxBytesErr) {
-			s.respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
-				fmt.Sprintf("request body exceeds maximum size of %d bytes", maxBytesErr.Limit))
-			return
-		}
-		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
-			"invalid request body: "+err.Error())
+	var body queryRequestBody
+	if !s.decodeRequest(w, r, "QueryRequest", &body) {
 		return
 	}
+	req := body.resolveAliases(w)
 
 	if req.Query == "" {
 		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "query is required")
@@ -159,9 +260,43 @@ func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Prepend the referenced session's history, so the client only
+	// needs to send the new question.
+	if req.SessionID != "" {
+		if !s.applySessionHistory(w, r, name, &req) {
+			return
+		}
+	}
+
+	// A pipeline running an experiment may have one of its variants
+	// answer the query, which is then recorded as the variant's.
+	ctx, served, p, err := s.routeQuery(r.Context(), name, req, p)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	r = r.WithContext(ctx)
+	if variant := queryVariant(ctx); variant != "" {
+		w.Header().Set(variantHeader, variant)
+	}
+
+	r, pending := s.recordForReplay(w, r)
+	r, audited := s.auditRequest(w, r, served, req, pending)
+
 	// Handle streaming vs non-streaming
+	start := time.Now()
 	if req.Stream {
-		s.handleStreamingQuery(w, r, p, req)
+		if s.streams != nil {
+			s.handleResumableStream(w, r, name, served, p, req, pending, audited, start)
+			return
+		}
+		status, answer, err := s.handleStreamingQuery(w, r, p, req)
+		s.observeQuery(served, req.Query, status, time.Since(start))
+		s.saveAudit(audited, status, answer, err)
+		if status == requestStatusOK {
+			s.recordSessionTurn(r.Context(), req, answer)
+			s.saveReplay(pending, served, req, answer)
+		}
 		return
 	}
 
@@ -174,38 +309,99 @@ func (s *Server) handlePipeline(w http.ResponseWriter, r *http.Request) {
 	resp, err := p.ExecuteWithOptions(ctx, req)
 	if err != nil {
 		if isRequestTimeout(ctx) {
+			s.observeQuery(served, req.Query, requestStatusTimeout, time.Since(start))
+			s.saveAudit(audited, requestStatusTimeout, "", err)
 			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
 				"request took too long to process")
 			return
 		}
+		var stageErr *pipeline.StageTimeoutError
+		if errors.As(err, &stageErr) {
+			s.observeQuery(served, req.Query, requestStatusTimeout, time.Since(start))
+			s.saveAudit(audited, requestStatusTimeout, "", err)
+			s.respondJSON(w, http.StatusGatewayTimeout, ErrorResponse{
+				Error: ErrorDetail{
+					Code:    "STAGE_TIMEOUT",
+					Message: stageErr.Error(),
+					Stage:   stageErr.Stage,
+				},
+			})
+			return
+		}
+		s.observeQuery(served, req.Query, requestStatusError, time.Since(start))
+		s.saveAudit(audited, requestStatusError, "", err)
+		if errors.Is(err, pipeline.ErrInvalidRequest) {
+			s.respondInvalidRequest(w, err)
+			return
+		}
+		if errors.Is(err, pipeline.ErrBudgetExceeded) {
+			s.respondBudgetExceeded(w, err)
+			return
+		}
+		if errors.Is(err, ragllm.ErrProviderBusy) {
+			s.respondProviderBusy(w, err)
+			return
+		}
 		s.logger.Error("pipeline execution failed",
-			"pipeline", name,
+			"pipeline", served,
 			"error", err)
 		s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
 		return
 	}
 
+	s.observeQuery(served, req.Query, requestStatusOK, time.Since(start))
+	s.chargeTokens(r.Context(), resp.Usage)
+	auditUsage(r.Context(), resp.Usage)
+	s.saveAudit(audited, requestStatusOK, resp.Answer, nil)
+	s.recordSessionTurn(r.Context(), req, resp.Answer)
+	s.saveReplay(pending, served, req, resp.Answer)
+	resp.Variant = queryVariant(ctx)
+	resp.Signature = s.signAnswer(p, resp.Answer, resp.Provenance, resp.AnsweredBy)
 	s.respondJSON(w, http.StatusOK, resp)
 }
 
-// handleStreamingQuery handles a streaming RAG query using Server-Sent Events.
+// observeQuery records the outcome of a query in the metrics and tells
+// the query recorder of it. Only errors and timeouts count as failures.
+func (s *Server) observeQuery(name, query, status string, d time.Duration) {
+	s.metrics.ObserveRequest(name, status, d)
+	if s.queries != nil {
+		s.queries.RecordQuery(name, query,
+			status != requestStatusOK && status != requestStatusDisconnected)
+	}
+}
+
+// Request outcome labels for the pgedge_rag_requests_total metric.
+const (
+	requestStatusOK           = "ok"
+	requestStatusError        = "error"
+	requestStatusTimeout      = "timeout"
+	requestStatusDisconnected = "disconnected"
+	requestStatusShutdown     = "shutdown"
+)
+
+// errServerShuttingDown ends a stream still running when the server
+// drains streams for shutdown.
+var errServerShuttingDown = errors.New("server is shutting down; retry the query")
+
+// errStreamingUnsupported fails a streaming query whose connection
+// cannot be flushed.
+var errStreamingUnsupported = errors.New("streaming not supported")
+
+// handleStreamingQuery handles a streaming RAG query using Server-Sent
+// Events. It returns the request's outcome label for metrics, the
+// answer text streamed to the client and the error the query failed
+// with, if any.
 func (s *Server) handleStreamingQuery(w http.ResponseWriter, r *http.Request,
-	p pipeline.QueryExecutor, req pipeline.QueryRequest) {
+	p pipeline.QueryExecutor, req pipeline.QueryRequest) (string, string, error) {
 	// Check if the response writer supports flushing
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		s.respondError(w, http.StatusInternalServerError, "STREAMING_ERROR",
 			"streaming not supported")
-		return
+		return requestStatusError, "", errStreamingUnsupported
 	}
 
-	// Set SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
-	w.WriteHeader(http.StatusOK)
-	flusher.Flush()
+	writeSSEHeaders(w, flusher)
 
 	// Execute streaming query, bounded the same way as the non-streaming
 	// path: a hung upstream call gets a structured SSE error event
@@ -216,60 +412,584 @@ func (s *Server) handleStreamingQuery(w http.ResponseWriter, r *http.Request,
 	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
 	defer cancel()
 
+	return s.runStream(ctx, p, req, func(event pipeline.StreamEvent) {
+		s.sendSSE(w, flusher, event)
+	})
+}
+
+// writeSSEHeaders commits the response as an SSE stream.
+func writeSSEHeaders(w http.ResponseWriter, flusher http.Flusher) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+}
+
+// runStream executes a streaming query and passes each resulting SSE
+// event to emit: a "sources" event first when the request asked for
+// sources, then the answer's "chunk" events, a "usage" event with the
+// token counts, and a "done" event. The "done" event is sent unless ctx
+// is canceled for a reason other than the request timeout. A stream
+// still running when the server drains streams for shutdown ends with
+// a "server_shutting_down" event and a "done" event instead. It
+// returns the request's outcome label for metrics, the answer text
+// emitted, and the error the query failed with, if any.
+func (s *Server) runStream(ctx context.Context, p pipeline.QueryExecutor,
+	req pipeline.QueryRequest, emit func(pipeline.StreamEvent)) (string, string, error) {
 	chunkChan, errChan := p.ExecuteStreamWithOptions(ctx, req)
 
+	var answer strings.Builder
+	var usage *pipeline.StageUsage
+	var formatWarnings []string
+	var citations []pipeline.Citation
+	var guardrails []string
+	var timings *pipeline.Timings
+	var cached bool
+	var queryID string
+	var responseID string
+	var provenance []pipeline.DocumentProvenance
+	var answeredBy pipeline.Answerer
+
 	// Stream chunks to client
 	for {
 		select {
 		case chunk, ok := <-chunkChan:
 			if !ok {
 				// Channel closed, check for errors
-				if err := <-errChan; err != nil {
-					s.sendSSE(w, flusher, pipeline.StreamEvent{
+				status := requestStatusOK
+				err := <-errChan
+				if err != nil {
+					status = requestStatusError
+					event := pipeline.StreamEvent{
 						Type:  "error",
 						Error: err.Error(),
+					}
+					var stageErr *pipeline.StageTimeoutError
+					if errors.As(err, &stageErr) {
+						status = requestStatusTimeout
+						event.Error = stageErr.Error()
+						event.Stage = stageErr.Stage
+					}
+					emit(event)
+				}
+				s.chargeTokens(ctx, usage)
+				auditUsage(ctx, usage)
+				if usage != nil {
+					emit(pipeline.StreamEvent{
+						Type:  "usage",
+						Usage: usage,
 					})
 				}
-				// Send done event
-				s.sendSSE(w, flusher, pipeline.StreamEvent{
-					Type: "done",
+				// Send done event, signing the answer when the stream
+				// finished successfully.
+				var signature *pipeline.AnswerSignature
+				if err == nil {
+					signature = s.signAnswer(p, answer.String(), provenance, answeredBy)
+				}
+				emit(pipeline.StreamEvent{
+					Type:           "done",
+					Usage:          usage,
+					FormatWarnings: formatWarnings,
+					Citations:      citations,
+					Guardrails:     guardrails,
+					Timings:        timings,
+					Cached:         cached,
+					QueryID:        queryID,
+					ResponseID:     responseID,
+					Variant:        queryVariant(ctx),
+					Provenance:     provenance,
+					Signature:      signature,
+				})
+				return status, answer.String(), err
+			}
+
+			if chunk.Sources != nil {
+				emit(pipeline.StreamEvent{
+					Type:    "sources",
+					Sources: chunk.Sources,
 				})
-				return
+				continue
+			}
+
+			answer.WriteString(chunk.Content)
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.FormatWarnings != nil {
+				formatWarnings = chunk.FormatWarnings
+			}
+			if chunk.Citations != nil {
+				citations = chunk.Citations
+			}
+			if chunk.Guardrails != nil {
+				guardrails = chunk.Guardrails
+			}
+			if chunk.Timings != nil {
+				timings = chunk.Timings
+			}
+			cached = cached || chunk.Cached
+			if chunk.QueryID != "" {
+				queryID = chunk.QueryID
+			}
+			if chunk.ResponseID != "" {
+				responseID = chunk.ResponseID
+			}
+			if chunk.Provenance != nil {
+				provenance = chunk.Provenance
+			}
+			if chunk.AnsweredBy.Provider != "" {
+				answeredBy = chunk.AnsweredBy
 			}
 
 			// Send chunk event
-			event := pipeline.StreamEvent{
+			emit(pipeline.StreamEvent{
 				Type:    "chunk",
 				Content: chunk.Content,
-			}
-			s.sendSSE(w, flusher, event)
+			})
 
 		case <-ctx.Done():
 			if isRequestTimeout(ctx) {
-				s.sendSSE(w, flusher, pipeline.StreamEvent{
+				emit(pipeline.StreamEvent{
 					Type:  "error",
 					Error: "request took too long to process",
 				})
-				s.sendSSE(w, flusher, pipeline.StreamEvent{Type: "done"})
-				return
+				emit(pipeline.StreamEvent{Type: "done"})
+				return requestStatusTimeout, "", ctx.Err()
 			}
 			// Client disconnected
 			s.logger.Debug("client disconnected during streaming")
+			return requestStatusDisconnected, "", ctx.Err()
+
+		case <-s.draining:
+			emit(pipeline.StreamEvent{
+				Type:  "server_shutting_down",
+				Error: errServerShuttingDown.Error(),
+			})
+			emit(pipeline.StreamEvent{Type: "done"})
+			return requestStatusShutdown, "", errServerShuttingDown
+		}
+	}
+}
+
+// handleExplain handles the POST /admin/pipelines/{name}/explain endpoint,
+// reporting how a document ranked at each retrieval step of a query.
+func (s *Server) handleExplain(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
 			return
 		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	explainer, ok := p.(pipeline.Explainer)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support explanations")
+		return
+	}
+
+	var req pipeline.ExplainRequest
+	if !s.decodeRequest(w, r, "ExplainRequest", &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	explanation, err := explainer.Explain(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		default:
+			s.logger.Error("explanation failed", "pipeline", name, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, explanation)
+}
+
+// handleRetrieve handles the POST /pipelines/{name}/retrieve endpoint,
+// returning the documents a query retrieves without answering it, so
+// frameworks can use the pipeline as a remote retriever.
+func (s *Server) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	retriever, ok := p.(pipeline.Retriever)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support retrieval")
+		return
+	}
+
+	var req pipeline.RetrieveRequest
+	if !s.decodeRequest(w, r, "RetrieveRequest", &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	resp, err := retriever.Retrieve(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrBudgetExceeded):
+			s.respondBudgetExceeded(w, err)
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			s.respondProviderBusy(w, err)
+		default:
+			s.logger.Error("retrieval failed", "pipeline", name, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+	s.chargeTokens(ctx, resp.Usage)
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleSearch handles the POST /pipelines/{name}/search endpoint,
+// which returns the documents a query retrieves, as a query's sources,
+// without generating an answer.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	searcher, ok := p.(pipeline.Searcher)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support search")
+		return
+	}
+
+	var req pipeline.SearchRequest
+	if !s.decodeRequest(w, r, "SearchRequest", &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	resp, err := searcher.Search(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrBudgetExceeded):
+			s.respondBudgetExceeded(w, err)
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			s.respondProviderBusy(w, err)
+		default:
+			s.logger.Error("search failed", "pipeline", name, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+	s.chargeTokens(ctx, resp.Usage)
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleEmbed handles the POST /pipelines/{name}/embed endpoint, which
+// embeds texts with the pipeline's embedding LLM so clients can compare
+// them with its tables' embeddings without the provider's credentials.
+func (s *Server) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	embedder, ok := p.(pipeline.TextEmbedder)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support embedding")
+		return
+	}
+
+	var req pipeline.EmbedRequest
+	if !s.decodeRequest(w, r, "EmbedRequest", &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	resp, err := embedder.Embed(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrBudgetExceeded):
+			s.respondBudgetExceeded(w, err)
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			s.respondProviderBusy(w, err)
+		default:
+			s.logger.Error("embedding failed", "pipeline", name, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+	s.chargeTokens(ctx, resp.Usage)
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleEstimate handles the POST /pipelines/{name}/estimate endpoint,
+// a dry run of a query that reports the size of the prompt it would
+// send and its projected cost on each completion model, without
+// calling the completion API.
+func (s *Server) handleEstimate(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	estimator, ok := p.(pipeline.Estimator)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not support estimates")
+		return
+	}
+
+	var body queryRequestBody
+	if !s.decodeRequest(w, r, "QueryRequest", &body) {
+		return
+	}
+	req := body.resolveAliases(w)
+	if req.Query == "" {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "query is required")
+		return
+	}
+	if req.SessionID != "" {
+		if !s.applySessionHistory(w, r, name, &req) {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+
+	estimate, err := estimator.Estimate(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrBudgetExceeded):
+			s.respondBudgetExceeded(w, err)
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			s.respondProviderBusy(w, err)
+		default:
+			s.logger.Error("estimate failed", "pipeline", name, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+	s.chargeTokens(r.Context(), estimate.Usage)
+	s.respondJSON(w, http.StatusOK, estimate)
+}
+
+// handleCreateSession handles the POST /sessions endpoint, starting an
+// empty conversation bound to a pipeline.
+func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+
+	var req CreateSessionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"invalid request body: "+err.Error())
+		return
+	}
+	if req.Pipeline == "" {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", "pipeline is required")
+		return
+	}
+
+	if _, err := s.pipelineManager().GetExecutor(req.Pipeline); err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+req.Pipeline)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	sess, err := s.sessions.Create(r.Context(), req.Pipeline)
+	if err != nil {
+		s.logger.Error("failed to create session", "pipeline", req.Pipeline, "error", err)
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"failed to create session")
+		return
+	}
+
+	s.respondJSON(w, http.StatusCreated, sess)
+}
+
+// handleGetSession handles the GET /sessions/{id} endpoint.
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	sess, err := s.sessions.Get(r.Context(), id)
+	if err != nil {
+		s.respondSessionError(w, id, err)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, sess)
+}
+
+// handleDeleteSession handles the DELETE /sessions/{id} endpoint.
+func (s *Server) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.sessions.Delete(r.Context(), id); err != nil {
+		s.respondSessionError(w, id, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondSessionError maps a session store error to an HTTP response.
+func (s *Server) respondSessionError(w http.ResponseWriter, id string, err error) {
+	if errors.Is(err, session.ErrNotFound) {
+		s.respondError(w, http.StatusNotFound, "SESSION_NOT_FOUND",
+			"session not found: "+id)
+		return
+	}
+	s.logger.Error("session store failed", "session_id", id, "error", err)
+	s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "session store failed")
+}
+
+// applySessionHistory prepends the history of the session named by
+// req.SessionID to req.Messages, as sessionHistory does. It writes an
+// error response and returns false if the session cannot be used with
+// this pipeline.
+func (s *Server) applySessionHistory(w http.ResponseWriter, r *http.Request,
+	name string, req *pipeline.QueryRequest) bool {
+	err := s.sessionHistory(r.Context(), name, req)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, pipeline.ErrInvalidRequest):
+		s.respondInvalidRequest(w, err)
+	default:
+		s.respondSessionError(w, req.SessionID, err)
+	}
+	return false
+}
+
+// sessionHistory loads the session named by req.SessionID and prepends
+// its history, truncated to sessions.max_history_tokens, to
+// req.Messages. It returns an error wrapping pipeline.ErrInvalidRequest
+// if sessions are disabled or the session belongs to another pipeline,
+// and the session store's error if the session cannot be loaded.
+func (s *Server) sessionHistory(ctx context.Context, name string, req *pipeline.QueryRequest) error {
+	if !s.sessionsEnabled() {
+		return fmt.Errorf("%w: session_id requires sessions to be enabled", pipeline.ErrInvalidRequest)
+	}
+
+	sess, err := s.sessions.Get(ctx, req.SessionID)
+	if err != nil {
+		return err
+	}
+	if sess.Pipeline != name {
+		return fmt.Errorf("%w: session %s belongs to pipeline %s",
+			pipeline.ErrInvalidRequest, sess.ID, sess.Pipeline)
+	}
+
+	history := session.TruncateHistory(sess.Messages, s.config.Sessions.MaxHistoryTokens)
+	messages := make([]pipeline.Message, 0, len(history)+len(req.Messages))
+	for _, m := range history {
+		messages = append(messages, pipeline.Message{Role: m.Role, Content: m.Content})
+	}
+	req.Messages = append(messages, req.Messages...)
+	return nil
+}
+
+// recordSessionTurn appends a completed question and answer to the
+// request's session, if it has one. A failure is logged rather than
+// returned: the client already has its answer.
+func (s *Server) recordSessionTurn(ctx context.Context, req pipeline.QueryRequest, answer string) {
+	if req.SessionID == "" || !s.sessionsEnabled() {
+		return
+	}
+	err := s.sessions.Append(context.WithoutCancel(ctx), req.SessionID,
+		session.Message{Role: "user", Content: req.Query},
+		session.Message{Role: "assistant", Content: answer},
+	)
+	if err != nil {
+		s.logger.Warn("failed to record session turn", "session_id", req.SessionID, "error", err)
 	}
 }
 
 // sendSSE sends a Server-Sent Event.
 func (s *Server) sendSSE(w http.ResponseWriter, flusher http.Flusher, event pipeline.StreamEvent) {
+	s.sendSSEWithID(w, flusher, "", event)
+}
+
+// sendSSEWithID sends a Server-Sent Event, preceded by an id: field
+// when id is non-empty so the client can resume from it.
+func (s *Server) sendSSEWithID(w http.ResponseWriter, flusher http.Flusher, id string,
+	event pipeline.StreamEvent) {
 	data, err := json.Marshal(event)
 	if err != nil {
 		s.logger.Error("failed to marshal SSE event", "error", err)
 		return
 	}
 
-	// SSE format: data: {json}\n\n
-	if _, err := w.Write([]byte("data: " + string(data) + "\n\n")); err != nil {
+	// SSE format: [id: {id}\n]data: {json}\n\n
+	msg := "data: " + string(data) + "\n\n"
+	if id != "" {
+		msg = "id: " + id + "\n" + msg
+	}
+	if _, err := w.Write([]byte(msg)); err != nil {
 		s.logger.Error("failed to write SSE event", "error", err)
 		return
 	}
@@ -280,7 +1000,8 @@ func (s *Server) sendSSE(w http.ResponseWriter, flusher http.Flusher, event pipe
 func (s *Server) respondJSON(w http.ResponseWriter, status int, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	// RFC 8631: Link header for API documentation discovery
-	w.Header().Set("Link", `</v1/openapi.json>; rel="service-desc"`)
+	// Added, not set, so a deprecated version's Link survives.
+	w.Header().Add("Link", `</v1/openapi.json>; rel="service-desc"`)
 	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
@@ -297,3 +1018,25 @@ func (s *Server) respondError(w http.ResponseWriter, status int, code, message s
 		},
 	})
 }
+
+// respondInvalidRequest writes a 400 INVALID_REQUEST for a request the
+// pipeline rejected, listing the fields at fault when it names them, as
+// it does for the problems with a filter.
+func (s *Server) respondInvalidRequest(w http.ResponseWriter, err error) {
+	var filterErr *pipeline.FilterError
+	if !errors.As(err, &filterErr) {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	fields := make([]FieldError, len(filterErr.Fields))
+	for i, f := range filterErr.Fields {
+		fields[i] = FieldError{Field: f.Field, Message: f.Message}
+	}
+	s.respondJSON(w, http.StatusBadRequest, ErrorResponse{
+		Error: ErrorDetail{
+			Code:    "INVALID_REQUEST",
+			Message: "invalid filter",
+			Fields:  fields,
+		},
+	})
+}
@@ -0,0 +1,192 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
+	"github.com/pgEdge/pgedge-rag-server/internal/jobs"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// IngestJobKind is the kind of the jobs that ingest uploaded documents.
+const IngestJobKind = "ingest"
+
+// handleUploadDocuments handles the POST /pipelines/{name}/documents
+// endpoint. It accepts PDF, HTML and Markdown files as a
+// multipart/form-data upload and ingests them in a background job, one
+// task per file, responding with the job, whose progress GET /jobs/{id}
+// reports.
+func (s *Server) handleUploadDocuments(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	describer, ok := p.(pipeline.Describer)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not describe its capabilities")
+		return
+	}
+	caps := describer.Capabilities()
+	if !caps.Ingest {
+		s.respondError(w, http.StatusForbidden, "INGESTION_DISABLED",
+			"document ingestion is not enabled for pipeline "+name)
+		return
+	}
+
+	// The chunks are stored as the uploading tenant's rows, so a
+	// pipeline that isolates tenants needs to know whose they are.
+	vars := database.FilterVarsFrom(r.Context())
+	if caps.Tenant.Enabled() {
+		if _, ok := vars.Lookup(caps.Tenant.Variable()); !ok {
+			s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+				"the pipeline isolates tenants and the upload names none; set "+caps.Tenant.Variable().String())
+			return
+		}
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, caps.MaxUploadBytes)
+	files, err := readUploadedFiles(r)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			s.respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("upload exceeds maximum size of %d bytes", maxBytesErr.Limit))
+		case errors.Is(err, ingest.ErrUnsupportedFormat):
+			s.respondError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", err.Error())
+		default:
+			s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+				"invalid upload: "+err.Error())
+		}
+		return
+	}
+	if len(files) == 0 {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"no files uploaded; send them as multipart/form-data file fields")
+		return
+	}
+
+	steps := make([]jobs.Step, len(files))
+	for i, file := range files {
+		steps[i] = s.ingestStep(name, file, vars)
+	}
+	job, err := s.jobs.Submit(r.Context(), IngestJobKind, name, steps)
+	if err != nil {
+		if errors.Is(err, jobs.ErrQueueFull) {
+			w.Header().Set("Retry-After", "60")
+			s.respondError(w, http.StatusServiceUnavailable, "QUEUE_FULL",
+				"too many jobs are waiting; try again later")
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	s.logger.Info("ingestion job started", "job", job.ID, "pipeline", name, "files", len(files))
+
+	// The job is looked up under the same API version as the upload.
+	prefix := strings.TrimSuffix(r.URL.Path, "/pipelines/"+name+"/documents")
+	w.Header().Set("Location", prefix+"/jobs/"+job.ID)
+	s.respondJSON(w, http.StatusAccepted, job)
+}
+
+// ingestStep returns the job step that ingests one uploaded file. The
+// pipeline is looked up when the step runs, not when the file was
+// uploaded, so a job that outlasts a configuration reload does not use
+// a pipeline that has since been closed. vars are the upload's filter
+// variables, which name the tenant whose rows the chunks become.
+func (s *Server) ingestStep(pipelineName string, file ingest.File, vars database.FilterVars) jobs.Step {
+	return jobs.Step{
+		Name: file.Name,
+		Run: func(ctx context.Context, progress func(done, total int)) error {
+			doc, err := ingest.Parse(file.Format, file.Name, file.Data)
+			if err != nil {
+				return err
+			}
+			p, err := s.pipelineManager().GetExecutor(pipelineName)
+			if err != nil {
+				return err
+			}
+			ingester, ok := p.(pipeline.Ingester)
+			if !ok {
+				return errors.New("pipeline does not support document ingestion")
+			}
+			_, err = ingester.Ingest(database.WithFilterVars(ctx, vars), doc, progress)
+			return err
+		},
+	}
+}
+
+// readUploadedFiles reads every file of a multipart/form-data upload,
+// whatever its field name, detecting each file's format from its name
+// and content type. Parts that are not files are ignored.
+func readUploadedFiles(r *http.Request) ([]ingest.File, error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []ingest.File
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		filename := part.FileName()
+		if filename == "" {
+			part.Close()
+			continue
+		}
+		format, err := ingest.DetectFormat(filename, part.Header.Get("Content-Type"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		data, err := io.ReadAll(part)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, ingest.File{Name: filename, Format: format, Data: data})
+	}
+}
+
+// handleGetJob handles the GET /jobs/{id} endpoint, reporting the
+// status, progress and errors of a background job.
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	job, err := s.jobs.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, jobs.ErrNotFound) {
+			s.respondError(w, http.StatusNotFound, "JOB_NOT_FOUND",
+				"job not found or expired: "+id)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, job)
+}
@@ -10,10 +10,20 @@
 package server
 
 import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
 )
 
 // responseWriter wraps http.ResponseWriter to capture status code.
@@ -38,6 +48,10 @@ func (rw *responseWriter) Flush() {
 func (s *Server) applyMiddleware(handler http.Handler) http.Handler {
 	// Apply in reverse order (last applied runs first)
 	handler = s.routingMiddleware(handler)
+	handler = s.filterVarsMiddleware(handler)
+	if len(s.config.Server.TraceHeaders) > 0 {
+		handler = s.traceHeadersMiddleware(handler)
+	}
 	handler = s.loggingMiddleware(handler)
 	handler = s.recoveryMiddleware(handler)
 	if s.config.Server.CORS.Enabled {
@@ -50,7 +64,8 @@ func (s *Server) applyMiddleware(handler http.Handler) http.Handler {
 // route and returns a structured JSON error instead of net/http's default
 // plain-text response. http.ServeMux has no way to customize its built-in
 // "404 page not found" / "405 Method Not Allowed" handlers directly, so
-// this checks the match itself via mux.Handler, which returns an empty
+// this checks the match itself via mux.Handler, on the mux of the
+// request's API version (see muxFor), which returns an empty
 // pattern both when no route matches the path at all and when the path
 // matches but the method doesn't. Distinguishing those two cases (to
 // return 404 vs 405 with a correct Allow header) requires probing the
@@ -63,7 +78,7 @@ func (s *Server) applyMiddleware(handler http.Handler) http.Handler {
 // matched and dispatched to a handler.
 func (s *Server) routingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if _, pattern := s.mux.Handler(r); pattern == "" {
+		if _, pattern := s.muxFor(r.URL.Path).Handler(r); pattern == "" {
 			if allowed := s.allowedMethods(r); len(allowed) > 0 {
 				w.Header().Set("Allow", strings.Join(allowed, ", "))
 				s.respondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED",
@@ -91,7 +106,7 @@ func (s *Server) allowedMethods(r *http.Request) []string {
 	for _, method := range methods {
 		probe := r.Clone(r.Context())
 		probe.Method = method
-		if _, pattern := s.mux.Handler(probe); pattern != "" {
+		if _, pattern := s.muxFor(probe.URL.Path).Handler(probe); pattern != "" {
 			allowed = append(allowed, method)
 			// net/http.ServeMux implicitly serves HEAD for any
 			// GET-registered pattern, so a route supporting GET also
@@ -104,6 +119,108 @@ func (s *Server) allowedMethods(r *http.Request) []string {
 	return allowed
 }
 
+// filterVarsMiddleware makes the request's headers and identity claims
+// available to the variables of config filters, such as
+// {{header:X-Tenant}}, for the searches the request runs.
+func (s *Server) filterVarsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := s.filterVars(r.Header)
+		next.ServeHTTP(w, r.WithContext(database.WithFilterVars(r.Context(), vars)))
+	})
+}
+
+// traceHeadersMiddleware puts the request's server.trace_headers on its
+// context, so the provider calls made while serving it carry them.
+func (s *Server) traceHeadersMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ragllm.ContextWithTraceHeaders(r.Context(), s.traceHeaders(r.Header))))
+	})
+}
+
+// traceHeaders returns the server.trace_headers among a request's
+// headers.
+func (s *Server) traceHeaders(h http.Header) http.Header {
+	headers := make(http.Header)
+	for _, name := range s.config.Server.TraceHeaders {
+		if values := h.Values(name); len(values) > 0 {
+			headers[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	return headers
+}
+
+// filterVars returns the config filter variables of a request's
+// headers. Headers are read as sent; claims are read from the
+// server.claims_header header, which the authenticating proxy in front
+// of the server must set, and are decoded only if a filter refers to
+// one. A header sent
+// more than once is not set, so a client cannot add a second value.
+func (s *Server) filterVars(h http.Header) database.FilterVars {
+	claimsHeader := s.config.Server.ClaimsHeader
+	// Tables are searched concurrently, so the claims are decoded once.
+	claims := sync.OnceValue(func() map[string]string {
+		claims, err := decodeClaims(h.Get(claimsHeader))
+		if err != nil {
+			s.logger.Warn("ignoring invalid claims header", "header", claimsHeader, "error", err)
+		}
+		return claims
+	})
+	return func(v config.FilterVariable) (string, bool) {
+		switch v.Source {
+		case config.FilterVarHeader:
+			values := h.Values(v.Name)
+			if len(values) != 1 {
+				return "", false
+			}
+			return values[0], true
+		case config.FilterVarClaim:
+			if claimsHeader == "" {
+				return "", false
+			}
+			value, ok := claims()[v.Name]
+			return value, ok
+		}
+		return "", false
+	}
+}
+
+// decodeClaims decodes a claims header: a JSON object, either as is or
+// base64url-encoded as a JWT payload is. Claims that are strings,
+// numbers or booleans are returned as text; others are left out, since
+// a filter compares against a single value.
+func decodeClaims(header string) (map[string]string, error) {
+	if header == "" {
+		return nil, nil
+	}
+	data := []byte(header)
+	if !strings.HasPrefix(strings.TrimSpace(header), "{") {
+		var err error
+		data, err = base64.RawURLEncoding.DecodeString(strings.TrimRight(header, "="))
+		if err != nil {
+			return nil, fmt.Errorf("not JSON or base64url: %w", err)
+		}
+	}
+
+	var raw map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, err
+	}
+	claims := make(map[string]string, len(raw))
+	for name, value := range raw {
+		switch value := value.(type) {
+		case string:
+			claims[name] = value
+		case json.Number:
+			claims[name] = value.String()
+		case bool:
+			claims[name] = strconv.FormatBool(value)
+		}
+	}
+	return claims, nil
+}
+
 // loggingMiddleware logs request information.
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -10,7 +10,12 @@
 package server
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
 )
 
 // OpenAPISpec represents the OpenAPI v3 specification.
@@ -96,6 +101,9 @@ type OpenAPISchema struct {
 	Enum        []string                 `json:"enum,omitempty"`
 	MaxItems    *int                     `json:"maxItems,omitempty"`
 	Ref         string                   `json:"$ref,omitempty"`
+	Deprecated  bool                     `json:"deprecated,omitempty"`
+
+	AdditionalProperties *OpenAPISchema `json:"additionalProperties,omitempty"`
 }
 
 // OpenAPIComponents contains reusable components.
@@ -166,6 +174,44 @@ func BuildOpenAPISpec() OpenAPISpec {
 					},
 				},
 			},
+			"/ready": {
+				Get: &OpenAPIOperation{
+					Summary:     "Readiness check",
+					Description: "Check whether every pipeline can serve queries by pinging its database, and its LLM providers when providers is true. Suitable for a readiness probe",
+					OperationID: "getReady",
+					Tags:        []string{"System"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "providers",
+							In:          "query",
+							Description: "Also ping each pipeline's embedding and completion providers",
+							Schema:      OpenAPISchema{Type: "boolean"},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"200": {
+							Description: "Every pipeline is ready",
+							Content: map[string]OpenAPIMediaType{
+								"application/json": {
+									Schema: OpenAPISchema{
+										Ref: "#/components/schemas/ReadyResponse",
+									},
+								},
+							},
+						},
+						"503": {
+							Description: "A pipeline's database, or a checked provider, is unreachable",
+							Content: map[string]OpenAPIMediaType{
+								"application/json": {
+									Schema: OpenAPISchema{
+										Ref: "#/components/schemas/ReadyResponse",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
 			"/pipelines": {
 				Get: &OpenAPIOperation{
 					Summary:     "List pipelines",
@@ -206,7 +252,40 @@ func BuildOpenAPISpec() OpenAPISpec {
 					},
 				},
 			},
+			"/signing-key": {
+				Get: &OpenAPIOperation{
+					Summary:     "Get signing key",
+					Description: "Get the public key answers are signed with, for verifying their signatures. Only available when server.signing.enabled is set",
+					OperationID: "getSigningKey",
+					Tags:        []string{"System"},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("The public signing key", "SigningKey"),
+						"404": jsonResponse("Answers are not signed", "ErrorResponse"),
+					},
+				},
+			},
 			"/pipelines/{name}": {
+				Get: &OpenAPIOperation{
+					Summary:     "Get pipeline",
+					Description: "Get a pipeline's effective configuration, with defaults applied: its models, top_n, token budget, search and reranking settings, tables, and filters. API keys and the database connection are left out, and header values, raw SQL filters, and credentials in base URLs are not reported",
+					OperationID: "getPipeline",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Pipeline configuration", "PipelineDetail"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+					},
+				},
 				Post: &OpenAPIOperation{
 					Summary:     "Query pipeline",
 					Description: "Execute a RAG query against a specific pipeline",
@@ -222,6 +301,16 @@ func BuildOpenAPISpec() OpenAPISpec {
 								Type: "string",
 							},
 						},
+						{
+							Name: "Last-Event-ID",
+							In:   "header",
+							Description: "ID of the last SSE event received; when stream " +
+								"resumption is enabled, replays the rest of that stream " +
+								"instead of running a new query",
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
+						},
 					},
 					RequestBody: &OpenAPIRequestBody{
 						Description: "Query request",
@@ -245,8 +334,9 @@ func BuildOpenAPISpec() OpenAPISpec {
 								},
 								"text/event-stream": {
 									Schema: OpenAPISchema{
-										Type:        "string",
-										Description: "Server-Sent Events stream",
+										Type: "string",
+										Description: "Server-Sent Events stream; each event's data " +
+											"is a StreamEvent object",
 									},
 								},
 							},
@@ -262,7 +352,7 @@ func BuildOpenAPISpec() OpenAPISpec {
 							},
 						},
 						"404": {
-							Description: "Pipeline not found",
+							Description: "Pipeline not found, or resumed stream not found or expired",
 							Content: map[string]OpenAPIMediaType{
 								"application/json": {
 									Schema: OpenAPISchema{
@@ -271,6 +361,7 @@ func BuildOpenAPISpec() OpenAPISpec {
 								},
 							},
 						},
+						"429": jsonResponse("Caller is over a rate limit, or the pipeline over its budget", "ErrorResponse"),
 						"500": {
 							Description: "Server error",
 							Content: map[string]OpenAPIMediaType{
@@ -281,229 +372,1560 @@ func BuildOpenAPISpec() OpenAPISpec {
 								},
 							},
 						},
+						"504": jsonResponse("Query or one of its stages timed out", "ErrorResponse"),
 					},
 				},
 			},
-		},
-		Components: OpenAPIComponents{
-			Schemas: map[string]OpenAPISchema{
-				"LiveResponse": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"status": {
-							Type:        "string",
-							Description: "Liveness status; always \"ok\" when the process is serving",
+			"/pipelines/{name}/openapi.json": {
+				Get: &OpenAPIOperation{
+					Summary:     "Get pipeline OpenAPI specification",
+					Description: "OpenAPI v3 specification of one pipeline's operations. Filter columns are listed as an enum when the pipeline sets filter_columns, and metadata objects list the pipeline's metadata columns, so generated clients reflect the pipeline's corpus",
+					OperationID: "getPipelineOpenAPI",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
 						},
 					},
-					Required: []string{"status"},
-				},
-				"HealthResponse": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"status": {
-							Type:        "string",
-							Description: "Overall health status: \"healthy\" or \"degraded\" (one or more providers unreachable). Always HTTP 200",
-						},
-						"pipelines": {
-							Type:        "array",
-							Description: "Per-pipeline provider connectivity",
-							Items: &OpenAPISchema{
-								Ref: "#/components/schemas/PipelineHealth",
+					Responses: map[string]OpenAPIResponse{
+						"200": {
+							Description: "OpenAPI specification",
+							Content: map[string]OpenAPIMediaType{
+								"application/json": {
+									Schema: OpenAPISchema{
+										Type: "object",
+									},
+								},
 							},
 						},
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
 					},
-					Required: []string{"status"},
 				},
-				"PipelineHealth": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"name": {
-							Type:        "string",
+			},
+			"/pipelines/{name}/retrieve": {
+				Post: &OpenAPIOperation{
+					Summary:     "Retrieve documents",
+					Description: "Run a query's retrieval and reranking without generating an answer, returning the matching documents with their metadata and scores. The request and response follow common retriever interfaces, so frameworks such as LangChain and LlamaIndex can use the pipeline as a remote retriever",
+					OperationID: "retrieveDocuments",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
 							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
 						},
-						"embedding": {
-							Ref:         "#/components/schemas/ProviderHealth",
-							Description: "Embedding provider connectivity",
-						},
-						"completion": {
-							Ref:         "#/components/schemas/ProviderHealth",
-							Description: "Completion provider connectivity",
+					},
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Query to retrieve documents for",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/RetrieveRequest",
+								},
+							},
 						},
 					},
-					Required: []string{"name", "embedding", "completion"},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Retrieved documents", "RetrieveResponse"),
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit, or the pipeline over its budget", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+						"504": jsonResponse("Request timed out", "ErrorResponse"),
+					},
 				},
-				"ProviderHealth": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"reachable": {
-							Type:        "boolean",
-							Description: "Whether the provider responded to a connectivity check",
-						},
-						"error": {
-							Type:        "string",
-							Description: "Error message if unreachable",
+			},
+			"/pipelines/{name}/search": {
+				Post: &OpenAPIOperation{
+					Summary:     "Search documents",
+					Description: "Run a query's retrieval and reranking without generating an answer, returning the matching documents as a query's sources, best first, with the tokens the embedding and reranking used. Takes a query's top_n, filter and filter_preset, and costs no completion tokens",
+					OperationID: "searchDocuments",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
 						},
 					},
-					Required: []string{"reachable"},
-				},
-				"PipelinesResponse": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"pipelines": {
-							Type:        "array",
-							Description: "List of available pipelines",
-							Items: &OpenAPISchema{
-								Ref: "#/components/schemas/PipelineInfo",
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Query to search documents for",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/SearchRequest",
+								},
 							},
 						},
 					},
-					Required: []string{"pipelines"},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Matching documents", "SearchResponse"),
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit, or the pipeline over its budget", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+						"504": jsonResponse("Request timed out", "ErrorResponse"),
+					},
 				},
-				"PipelineInfo": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"name": {
-							Type:        "string",
+			},
+			"/pipelines/{name}/embed": {
+				Post: &OpenAPIOperation{
+					Summary:     "Embed texts",
+					Description: "Embed texts with the pipeline's embedding model, as it embeds queries, so clients can compare them with the pipeline's tables' embeddings without the provider's credentials",
+					OperationID: "embedTexts",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
 							Description: "Pipeline name",
-						},
-						"description": {
-							Type:        "string",
-							Description: "Pipeline description",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
 						},
 					},
-					Required: []string{"name"},
-				},
-				"StatsResponse": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"pipelines": {
-							Type:        "array",
-							Description: "Per-pipeline cumulative token usage",
-							Items: &OpenAPISchema{
-								Ref: "#/components/schemas/PipelineUsage",
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Texts to embed",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/EmbedRequest",
+								},
 							},
 						},
 					},
-					Required: []string{"pipelines"},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("The texts' embeddings", "EmbedResponse"),
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit, or the pipeline over its budget", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+						"504": jsonResponse("Request timed out", "ErrorResponse"),
+					},
 				},
-				"PipelineUsage": {
-					Type: "object",
-					Properties: map[string]OpenAPISchema{
-						"name": {
-							Type:        "string",
+			},
+			"/pipelines/{name}/feedback": {
+				Post: &OpenAPIOperation{
+					Summary:     "Rate an answer",
+					Description: "Record whether an answer was helpful. An answer whose response carries a response_id is rated in the pipeline's feedback table, with its query, sources and model, while the pipeline keeps the response; one whose response carries a query_id, because the query was captured for the eval dataset, is rated in the dataset, and exported with it. At least one of the two is required",
+					OperationID: "giveFeedback",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
 							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
 						},
-						"description": {
-							Type:        "string",
-							Description: "Pipeline description",
-						},
-						"embedding": {
-							Ref:         "#/components/schemas/TokenUsage",
-							Description: "Cumulative embedding token usage",
-						},
-						"completion": {
-							Ref:         "#/components/schemas/TokenUsage",
-							Description: "Cumulative completion token usage",
+					},
+					RequestBody: &OpenAPIRequestBody{
+						Description: "The answer and its rating",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/FeedbackRequest",
+								},
+							},
 						},
 					},
-					Required: []string{"name", "embedding", "completion"},
+					Responses: map[string]OpenAPIResponse{
+						"204": {Description: "Feedback recorded"},
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline, response or query not found", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+					},
 				},
-				"TokenUsage": {
-					Type:        "object",
-					Description: "Cumulative token usage since client creation or last reset",
-					Properties: map[string]OpenAPISchema{
-						"prompt_tokens": {
-							Type:        "integer",
-							Description: "Cumulative prompt/input tokens",
-						},
-						"completion_tokens": {
+			},
+			"/pipelines/{name}/estimate": {
+				Post: &OpenAPIOperation{
+					Summary:     "Estimate query cost",
+					Description: "Run a query's retrieval and assemble its prompt without calling the completion API, returning the prompt's estimated tokens and its projected cost on the completion model and each fallback, from their configured pricing. Retrieval runs as it would for the query, so embedding, query expansion and rerank tokens are spent",
+					OperationID: "estimateQuery",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
+						},
+					},
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Query to estimate, as it would be sent to the pipeline",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/QueryRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Estimated prompt size and cost", "Estimate"),
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit, or the pipeline over its budget", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+						"504": jsonResponse("Request timed out", "ErrorResponse"),
+					},
+				},
+			},
+			"/pipelines/{name}/documents": {
+				Post: &OpenAPIOperation{
+					Summary:     "Upload documents",
+					Description: "Upload PDF, HTML or Markdown files to ingest into the pipeline's ingest table. Text is extracted, stripped of boilerplate, chunked, embedded and stored with its filename and page number by a background job with a task per file; the response is the job, whose progress GET /jobs/{id} reports. Only available when the pipeline's ingest.enabled is set",
+					OperationID: "uploadDocuments",
+					Tags:        []string{"Pipelines"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "name",
+							In:          "path",
+							Description: "Pipeline name",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
+						},
+					},
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Files to ingest; every part with a filename is ingested",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"multipart/form-data": {
+								Schema: OpenAPISchema{
+									Type: "object",
+									Properties: map[string]OpenAPISchema{
+										"file": {
+											Type: "array",
+											Items: &OpenAPISchema{
+												Type:   "string",
+												Format: "binary",
+											},
+										},
+									},
+									Required: []string{"file"},
+								},
+							},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"202": jsonResponse("Ingestion job queued", "Job"),
+						"400": jsonResponse("Invalid upload", "ErrorResponse"),
+						"403": jsonResponse("Ingestion not enabled for the pipeline", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"413": jsonResponse("Upload too large", "ErrorResponse"),
+						"415": jsonResponse("Unsupported file format", "ErrorResponse"),
+						"429": jsonResponse("Caller is over a rate limit", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+						"503": jsonResponse("Job queue full", "ErrorResponse"),
+					},
+				},
+			},
+			"/jobs/{id}": {
+				Get: &OpenAPIOperation{
+					Summary:     "Get job",
+					Description: "Get the status, progress and errors of a background job, such as a document upload. Jobs are kept for jobs.retention after their last update",
+					OperationID: "getJob",
+					Tags:        []string{"Jobs"},
+					Parameters: []OpenAPIParameter{
+						{
+							Name:        "id",
+							In:          "path",
+							Description: "Job ID",
+							Required:    true,
+							Schema: OpenAPISchema{
+								Type: "string",
+							},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Job", "Job"),
+						"404": jsonResponse("Job not found or expired", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+					},
+				},
+			},
+			"/sessions": {
+				Post: &OpenAPIOperation{
+					Summary:     "Create session",
+					Description: "Start a server-side conversation bound to a pipeline. Queries that pass the returned id as session_id automatically include the session's prior turns. Only available when sessions are enabled",
+					OperationID: "createSession",
+					Tags:        []string{"Sessions"},
+					RequestBody: &OpenAPIRequestBody{
+						Description: "Session to create",
+						Required:    true,
+						Content: map[string]OpenAPIMediaType{
+							"application/json": {
+								Schema: OpenAPISchema{
+									Ref: "#/components/schemas/CreateSessionRequest",
+								},
+							},
+						},
+					},
+					Responses: map[string]OpenAPIResponse{
+						"201": jsonResponse("Session created", "Session"),
+						"400": jsonResponse("Invalid request", "ErrorResponse"),
+						"404": jsonResponse("Pipeline not found", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+					},
+				},
+			},
+			"/sessions/{id}": {
+				Get: &OpenAPIOperation{
+					Summary:     "Get session",
+					Description: "Get a session and its conversation history",
+					OperationID: "getSession",
+					Tags:        []string{"Sessions"},
+					Parameters:  []OpenAPIParameter{sessionIDParameter},
+					Responses: map[string]OpenAPIResponse{
+						"200": jsonResponse("Session", "Session"),
+						"404": jsonResponse("Session not found or expired", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+					},
+				},
+				Delete: &OpenAPIOperation{
+					Summary:     "Delete session",
+					Description: "Delete a session and its conversation history",
+					OperationID: "deleteSession",
+					Tags:        []string{"Sessions"},
+					Parameters:  []OpenAPIParameter{sessionIDParameter},
+					Responses: map[string]OpenAPIResponse{
+						"204": {Description: "Session deleted"},
+						"404": jsonResponse("Session not found or expired", "ErrorResponse"),
+						"500": jsonResponse("Server error", "ErrorResponse"),
+					},
+				},
+			},
+		},
+		Components: OpenAPIComponents{
+			Schemas: map[string]OpenAPISchema{
+				"LiveResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"status": {
+							Type:        "string",
+							Description: "Liveness status; always \"ok\" when the process is serving",
+						},
+					},
+					Required: []string{"status"},
+				},
+				"HealthResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"status": {
+							Type:        "string",
+							Description: "Overall health status: \"healthy\" or \"degraded\" (one or more providers unreachable). Always HTTP 200",
+						},
+						"pipelines": {
+							Type:        "array",
+							Description: "Per-pipeline provider connectivity",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/PipelineHealth",
+							},
+						},
+					},
+					Required: []string{"status"},
+				},
+				"ReadyResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"status": {
+							Type:        "string",
+							Description: "\"ready\", or \"not_ready\" (HTTP 503) when a pipeline is not ready",
+						},
+						"pipelines": {
+							Type:        "array",
+							Description: "Per-pipeline readiness",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/PipelineReadiness",
+							},
+						},
+					},
+					Required: []string{"status"},
+				},
+				"PipelineReadiness": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "Pipeline name",
+						},
+						"ready": {
+							Type:        "boolean",
+							Description: "Whether the database, and any checked providers, are reachable",
+						},
+						"database": {
+							Ref:         "#/components/schemas/ProviderHealth",
+							Description: "Database connectivity",
+						},
+						"embedding": {
+							Ref:         "#/components/schemas/ProviderHealth",
+							Description: "Embedding provider connectivity, when providers were checked",
+						},
+						"completion": {
+							Ref:         "#/components/schemas/ProviderHealth",
+							Description: "Completion provider connectivity, when providers were checked",
+						},
+					},
+					Required: []string{"name", "ready", "database"},
+				},
+				"PipelineHealth": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "Pipeline name",
+						},
+						"embedding": {
+							Ref:         "#/components/schemas/ProviderHealth",
+							Description: "Embedding provider connectivity",
+						},
+						"completion": {
+							Ref:         "#/components/schemas/ProviderHealth",
+							Description: "Completion provider connectivity",
+						},
+						"init": {
+							Type:        "array",
+							Description: "How long each stage of the pipeline's initialization took, when the server started or the configuration was last reloaded",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/InitStage",
+							},
+						},
+					},
+					Required: []string{"name", "embedding", "completion"},
+				},
+				"InitStage": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "Stage: db_connect, schema_check, providers, provider_ping, warm_up, or bm25_index",
+						},
+						"duration_ms": {
+							Type:        "integer",
+							Description: "How long the stage took, in milliseconds",
+						},
+						"error": {
+							Type:        "string",
+							Description: "The error the stage ended with, if any; a pipeline still starts after a provider_ping or warm_up error",
+						},
+					},
+					Required: []string{"name", "duration_ms"},
+				},
+				"ProviderHealth": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"reachable": {
+							Type:        "boolean",
+							Description: "Whether the provider, or database, responded to a connectivity check",
+						},
+						"error": {
+							Type:        "string",
+							Description: "Error message if unreachable",
+						},
+					},
+					Required: []string{"reachable"},
+				},
+				"PipelinesResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"pipelines": {
+							Type:        "array",
+							Description: "List of available pipelines",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/PipelineInfo",
+							},
+						},
+					},
+					Required: []string{"pipelines"},
+				},
+				"PipelineInfo": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "Pipeline name",
+						},
+						"description": {
+							Type:        "string",
+							Description: "Pipeline description",
+						},
+					},
+					Required: []string{"name"},
+				},
+				"PipelineDetail": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name":          {Type: "string", Description: "Pipeline name"},
+						"description":   {Type: "string", Description: "Pipeline description"},
+						"embedding_llm": {Ref: "#/components/schemas/ModelDetail"},
+						"rag_llm":       {Ref: "#/components/schemas/ModelDetail"},
+						"rag_llm_fallbacks": {
+							Type:        "array",
+							Description: "Completion providers tried in order when rag_llm fails",
+							Items:       &OpenAPISchema{Ref: "#/components/schemas/ModelDetail"},
+						},
+						"top_n":        {Type: "integer", Description: "Documents retrieved for a query that sets no top_n"},
+						"token_budget": {Type: "integer", Description: "Most tokens of context given to the model"},
+						"search":       {Ref: "#/components/schemas/SearchDetail"},
+						"rerank":       {Ref: "#/components/schemas/RerankDetail"},
+						"tables": {
+							Type:  "array",
+							Items: &OpenAPISchema{Ref: "#/components/schemas/TableDetail"},
+						},
+						"filter_columns": {
+							Type:        "array",
+							Description: "Columns request filters may use; absent when any column may be used",
+							Items:       &OpenAPISchema{Type: "string"},
+						},
+						"filter_presets": {
+							Type:                 "object",
+							Description:          "Named filters requests may apply with filter_preset",
+							AdditionalProperties: &OpenAPISchema{Ref: "#/components/schemas/Filter"},
+						},
+						"ingest": {Type: "boolean", Description: "Documents may be uploaded to the pipeline"},
+					},
+					Required: []string{"name", "embedding_llm", "rag_llm", "top_n", "token_budget", "search", "rerank", "tables", "ingest"},
+				},
+				"ModelDetail": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"provider": {Type: "string"},
+						"model":    {Type: "string"},
+						"base_url": {
+							Type:        "string",
+							Description: "Custom base URL, with any password and query parameter values redacted",
+						},
+						"headers": {
+							Type:        "array",
+							Description: "Names of the custom headers sent to the provider",
+							Items:       &OpenAPISchema{Type: "string"},
+						},
+					},
+					Required: []string{"provider", "model"},
+				},
+				"SearchDetail": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"hybrid":         {Type: "boolean", Description: "Vector and keyword rankings are combined"},
+						"vector_share":   {Type: "number", Description: "Share of the vector ranking in hybrid search, 0-1"},
+						"fusion":         {Type: "string", Enum: []string{"rrf", "score_fusion"}},
+						"rrf_k":          {Type: "integer", Description: "Reciprocal rank fusion constant; absent with score_fusion"},
+						"min_similarity": {Type: "number", Description: "Lowest vector similarity score a document may have"},
+					},
+					Required: []string{"hybrid", "vector_share", "fusion"},
+				},
+				"RerankDetail": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"enabled":  {Type: "boolean"},
+						"provider": {Type: "string"},
+						"model":    {Type: "string"},
+						"top_k":    {Type: "integer", Description: "Reranked documents kept; absent when all are"},
+					},
+					Required: []string{"enabled"},
+				},
+				"TableDetail": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"table":         {Type: "string"},
+						"text_column":   {Type: "string"},
+						"vector_column": {Type: "string"},
+						"id_column":     {Type: "string"},
+						"metadata_columns": {
+							Type:  "array",
+							Items: &OpenAPISchema{Type: "string"},
+						},
+						"lexical_search": {Type: "string", Enum: []string{"bm25", "postgres_fts"}},
+						"filter":         {Ref: "#/components/schemas/Filter"},
+						"sql_filter": {
+							Type:        "boolean",
+							Description: "The table also has a raw SQL filter, which is not reported",
+						},
+					},
+					Required: []string{"table", "text_column", "vector_column", "lexical_search"},
+				},
+				"StatsResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"pipelines": {
+							Type:        "array",
+							Description: "Per-pipeline cumulative token usage",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/PipelineUsage",
+							},
+						},
+					},
+					Required: []string{"pipelines"},
+				},
+				"PipelineUsage": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "Pipeline name",
+						},
+						"description": {
+							Type:        "string",
+							Description: "Pipeline description",
+						},
+						"embedding": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Cumulative embedding token usage",
+						},
+						"completion": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Cumulative completion token usage",
+						},
+						"cost": {
+							Ref:         "#/components/schemas/PipelineCost",
+							Description: "Estimated cost of the pipeline's queries; omitted when none of its models is priced",
+						},
+					},
+					Required: []string{"name", "embedding", "completion"},
+				},
+				"PipelineCost": {
+					Type:        "object",
+					Description: "Estimated cost of a pipeline's queries, from its models' pricing, since the server started",
+					Properties: map[string]OpenAPISchema{
+						"total":          {Type: "number", Format: "double", Description: "Cost since the server started"},
+						"today":          {Type: "number", Format: "double", Description: "Cost in the current UTC day"},
+						"this_month":     {Type: "number", Format: "double", Description: "Cost in the current UTC month"},
+						"daily_budget":   {Type: "number", Format: "double", Description: "The pipeline's daily budget; omitted when not set"},
+						"monthly_budget": {Type: "number", Format: "double", Description: "The pipeline's monthly budget; omitted when not set"},
+					},
+					Required: []string{"total", "today", "this_month"},
+				},
+				"TokenUsage": {
+					Type:        "object",
+					Description: "Cumulative token usage since client creation or last reset",
+					Properties: map[string]OpenAPISchema{
+						"prompt_tokens": {
+							Type:        "integer",
+							Description: "Cumulative prompt/input tokens",
+						},
+						"completion_tokens": {
+							Type:        "integer",
+							Description: "Cumulative completion/output tokens",
+						},
+						"total_tokens": {
+							Type:        "integer",
+							Description: "Cumulative total tokens (prompt + completion)",
+						},
+						"cache_creation_input_tokens": {
+							Type:        "integer",
+							Description: "Cumulative tokens used to write to the prompt cache",
+						},
+						"cache_read_input_tokens": {
+							Type:        "integer",
+							Description: "Cumulative tokens read from the prompt cache",
+						},
+					},
+					Required: []string{"prompt_tokens", "completion_tokens", "total_tokens"},
+				},
+				"Message": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"role": {
+							Type:        "string",
+							Description: "Message role (user or assistant)",
+						},
+						"content": {
+							Type:        "string",
+							Description: "Message content",
+						},
+					},
+					Required: []string{"role", "content"},
+				},
+				"CreateSessionRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"pipeline": {
+							Type:        "string",
+							Description: "Pipeline the session's queries will run against",
+						},
+					},
+					Required: []string{"pipeline"},
+				},
+				"Session": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"id": {
+							Type:        "string",
+							Description: "Session identifier, passed as session_id on queries",
+						},
+						"pipeline": {
+							Type:        "string",
+							Description: "Pipeline the session is bound to",
+						},
+						"messages": {
+							Type:        "array",
+							Description: "Conversation history, oldest first",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Message",
+							},
+						},
+						"created_at": {
+							Type:   "string",
+							Format: "date-time",
+						},
+						"updated_at": {
+							Type:        "string",
+							Format:      "date-time",
+							Description: "Time of the last recorded turn; sessions expire after the configured idle TTL",
+						},
+					},
+					Required: []string{"id", "pipeline", "messages", "created_at", "updated_at"},
+				},
+				"Job": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"id": {
+							Type:        "string",
+							Description: "Job identifier",
+						},
+						"kind": {
+							Type:        "string",
+							Description: "What the job does; ingest for document uploads",
+						},
+						"pipeline": {
+							Type:        "string",
+							Description: "Pipeline the job works on",
+						},
+						"status": {
+							Type:        "string",
+							Description: "Job state; failed when any of its tasks failed",
+							Enum:        []string{"pending", "running", "completed", "failed"},
+						},
+						"tasks": {
+							Type:        "array",
+							Description: "The job's tasks, such as one per uploaded file, in the order they run",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/JobTask",
+							},
+						},
+						"error": {
+							Type:        "string",
+							Description: "Why the job as a whole failed, such as the server stopping; omitted unless it did",
+						},
+						"created_at": {
+							Type:   "string",
+							Format: "date-time",
+						},
+						"updated_at": {
+							Type:   "string",
+							Format: "date-time",
+						},
+					},
+					Required: []string{"id", "kind", "pipeline", "status", "tasks", "created_at", "updated_at"},
+				},
+				"JobTask": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"name": {
+							Type:        "string",
+							Description: "What the task works on, such as an uploaded file's name",
+						},
+						"status": {
+							Type: "string",
+							Enum: []string{"pending", "running", "completed", "failed"},
+						},
+						"done": {
 							Type:        "integer",
-							Description: "Cumulative completion/output tokens",
+							Description: "Units of work done so far, such as chunks embedded",
+						},
+						"total": {
+							Type:        "integer",
+							Description: "Units of work in the task, once known",
+						},
+						"error": {
+							Type:        "string",
+							Description: "Why the task failed; omitted unless it did",
+						},
+					},
+					Required: []string{"name", "status", "done", "total"},
+				},
+				"QueryRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query": {
+							Type:        "string",
+							Description: "The question to answer (required; question is accepted as an alias)",
+						},
+						"question": {
+							Type:        "string",
+							Description: "Deprecated alias of query; ignored when query is set",
+							Deprecated:  true,
+						},
+						"stream": {
+							Type:        "boolean",
+							Description: "Enable streaming response (SSE)",
+							Default:     false,
+						},
+						"top_n": {
+							Type:        "integer",
+							Description: "Override default result limit",
+						},
+						"top_k": {
+							Type:        "integer",
+							Description: "Deprecated alias of top_n; ignored when top_n is set",
+							Deprecated:  true,
+						},
+						"filter": {
+							Ref:         "#/components/schemas/Filter",
+							Description: "Structured filter to apply to search results",
+						},
+						"filter_preset": {
+							Type:        "string",
+							Description: "Name of one of the pipeline's filter presets, combined with filter so documents must match both",
+						},
+						"include_sources": {
+							Type:        "boolean",
+							Description: "Include source documents in response",
+							Default:     false,
+						},
+						"include_timings": {
+							Type:        "boolean",
+							Description: "Include how long the query's stages took in the response (the done event when streaming)",
+							Default:     false,
+						},
+						"messages": {
+							Type:        "array",
+							Description: "Previous conversation history for context",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Message",
+							},
+						},
+						"session_id": {
+							Type:        "string",
+							Description: "Session whose prior turns are prepended to messages; the question and answer are recorded in it on success",
+						},
+						"stop_sequences": {
+							Type: "array",
+							Description: "Additional stop sequences for this request, merged " +
+								"with the pipeline's configured ones (at most 4 in total)",
+							MaxItems: intPtr(4),
+							Items: &OpenAPISchema{
+								Type: "string",
+							},
+						},
+						"answer_length": {
+							Type: "string",
+							Description: "Answer length preset for this request, " +
+								"overriding the pipeline's answer_length",
+							Enum: []string{"short", "medium", "long"},
+						},
+						"logit_bias": {
+							Type: "object",
+							Description: "OpenAI logit bias: token ID to a bias between " +
+								"-100 and 100. Overrides the pipeline's configured " +
+								"entries per token. Only supported by OpenAI pipelines.",
+							AdditionalProperties: &OpenAPISchema{
+								Type: "integer",
+							},
+						},
+						"seed": {
+							Type:   "integer",
+							Format: "int64",
+							Description: "Sampling seed, so a repeated query can be answered " +
+								"the same way; the answer is not served from the answer " +
+								"cache. Only supported by OpenAI and Ollama pipelines.",
+						},
+						"response_format": {
+							Type: "object",
+							Description: "Ask for the answer as a JSON document matching " +
+								"a schema. The answer is checked against the schema and " +
+								"asked for again once if it does not match. Not " +
+								"supported by Bedrock pipelines.",
+							Properties: map[string]OpenAPISchema{
+								"type": {
+									Type: "string",
+									Enum: []string{"json_schema"},
+								},
+								"json_schema": {
+									Type:        "object",
+									Description: "JSON Schema of the answer; its type must be \"object\".",
+								},
+							},
+							Required: []string{"type", "json_schema"},
+						},
+					},
+				},
+				"QueryResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"answer": {
+							Type:        "string",
+							Description: "The generated answer",
+						},
+						"sources": {
+							Type:        "array",
+							Description: "Source documents (only if include_sources=true)",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Source",
+							},
+						},
+						"tokens_used": {
+							Type:        "integer",
+							Description: "Total completion tokens consumed",
+						},
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens consumed by this request, by pipeline stage",
+						},
+						"format_warnings": {
+							Type: "array",
+							Description: "Places where the answer departs from the " +
+								"pipeline's date, number or unit conventions; omitted " +
+								"when there are none",
+							Items: &OpenAPISchema{
+								Type: "string",
+							},
+						},
+						"citations": {
+							Type:        "array",
+							Description: "Sources cited by [n] markers in the answer, in order of first citation (citations mode only)",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Citation",
+							},
+						},
+						"guardrails": {
+							Type:        "array",
+							Description: "The pipeline's guardrails that changed the answer; omitted when none did",
+							Items: &OpenAPISchema{
+								Type: "string",
+								Enum: []string{"ungrounded", "redacted", "truncated"},
+							},
+						},
+						"timings": {
+							Ref:         "#/components/schemas/Timings",
+							Description: "How long the query's stages took (only if include_timings=true)",
+						},
+						"cached": {
+							Type:        "boolean",
+							Description: "The answer came from the pipeline's answer cache; omitted otherwise",
+						},
+						"query_id": {
+							Type:        "string",
+							Description: "Identifies the query when the pipeline captured it for its eval dataset, for POST /pipelines/{name}/feedback; omitted otherwise",
+						},
+						"response_id": {
+							Type:        "string",
+							Description: "Identifies the answer when the pipeline takes feedback, for POST /pipelines/{name}/feedback; omitted otherwise",
+						},
+						"variant": {
+							Type:        "string",
+							Description: "The pipeline that answered the query when the pipeline asked runs an experiment: one of its variants, or the pipeline itself; omitted otherwise. Also returned in the X-Pipeline-Variant header",
+						},
+						"provenance": {
+							Type:        "array",
+							Description: "The documents the answer was written from, in the order they were given to the model, with the hash of each one's stored content; omitted when no documents were found",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/DocumentProvenance",
+							},
+						},
+						"signature": {
+							Ref:         "#/components/schemas/AnswerSignature",
+							Description: "Signature of the answer and its provenance (only if server.signing.enabled)",
+						},
+					},
+					Required: []string{"answer", "tokens_used"},
+				},
+				"AnswerSignature": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"algorithm": {
+							Type:        "string",
+							Description: "Signature algorithm: Ed25519",
+						},
+						"key_id": {
+							Type:        "string",
+							Description: "ID of the signing key, as GET /signing-key reports it",
+						},
+						"payload": {
+							Type:        "string",
+							Description: "The signed JSON document, base64url-encoded without padding: pipeline, provider, model, answer, documents (the answer's provenance) and signed_at",
+						},
+						"signature": {
+							Type:        "string",
+							Description: "Signature of the payload's decoded bytes, base64url-encoded without padding",
+						},
+					},
+					Required: []string{"algorithm", "key_id", "payload", "signature"},
+				},
+				"SigningKey": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"algorithm": {
+							Type:        "string",
+							Description: "Signature algorithm: Ed25519",
+						},
+						"key_id": {
+							Type:        "string",
+							Description: "Key ID: the first 16 hex digits of the SHA-256 of the PKIX-encoded public key",
+						},
+						"public_key": {
+							Type:        "string",
+							Description: "PEM-encoded PKIX public key",
+						},
+					},
+					Required: []string{"algorithm", "key_id", "public_key"},
+				},
+				"DocumentProvenance": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"id": {
+							Type:        "string",
+							Description: "Document identifier",
+						},
+						"content_hash": {
+							Type:        "string",
+							Description: "SHA-256 of the document's stored text, before any truncation or summarization, as sha256: and hex digits",
+						},
+					},
+					Required: []string{"content_hash"},
+				},
+				"Timings": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"embed_ms": {
+							Type:        "integer",
+							Description: "Milliseconds spent embedding the query",
+						},
+						"search_ms": {
+							Type:        "integer",
+							Description: "Milliseconds spent searching the tables, including any hypothetical answer or paraphrases the retrieval strategy writes",
+						},
+						"ttfb_ms": {
+							Type:        "integer",
+							Description: "Milliseconds until the first answer text was sent (streamed queries only)",
+						},
+						"total_ms": {
+							Type:        "integer",
+							Description: "Milliseconds the whole query took",
+						},
+					},
+					Required: []string{"embed_ms", "search_ms", "total_ms"},
+				},
+				"Citation": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"marker": {
+							Type:        "integer",
+							Description: "Number used in the answer's [n] marker",
+						},
+						"id": {
+							Type:        "string",
+							Description: "Identifier of the cited document",
+						},
+						"score": {
+							Type:        "number",
+							Format:      "double",
+							Description: "Relevance score of the cited document",
+						},
+						"metadata": {
+							Type: "object",
+							Description: "Values of the table's configured metadata " +
+								"columns, keyed by column name",
+							AdditionalProperties: &OpenAPISchema{},
+						},
+					},
+					Required: []string{"marker", "score"},
+				},
+				"FeedbackRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query_id": {
+							Type:        "string",
+							Description: "The query_id of the rated answer's response",
+						},
+						"response_id": {
+							Type:        "string",
+							Description: "The response_id of the rated answer's response",
+						},
+						"rating": {
+							Type:        "string",
+							Description: "Whether the answer was helpful",
+							Enum:        []string{"up", "down"},
+						},
+						"comment": {
+							Type:        "string",
+							Description: "Free-text feedback, anonymized as the query is",
+						},
+					},
+					Required: []string{"rating"},
+				},
+				"RetrieveRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query": {
+							Type:        "string",
+							Description: "The query to retrieve documents for",
+						},
+						"k": {
+							Type:        "integer",
+							Description: "Maximum number of documents to return; defaults to the pipeline's top_n",
+						},
+						"filter": {
+							Ref:         "#/components/schemas/Filter",
+							Description: "Structured filter, as on a query",
+						},
+						"filter_preset": {
+							Type:        "string",
+							Description: "Name of one of the pipeline's filter presets, as on a query",
+						},
+					},
+					Required: []string{"query"},
+				},
+				"RetrieveResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"documents": {
+							Type:        "array",
+							Description: "Retrieved documents, best first",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/RetrievedDocument",
+							},
+						},
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens the embedding, retrieval strategy and reranking used; completion is always zero",
+						},
+					},
+					Required: []string{"documents", "usage"},
+				},
+				"SearchRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query": {
+							Type:        "string",
+							Description: "The query to search documents for",
+						},
+						"top_n": {
+							Type:        "integer",
+							Description: "Maximum number of documents to return; defaults to the pipeline's top_n",
+						},
+						"filter": {
+							Ref:         "#/components/schemas/Filter",
+							Description: "Structured filter, as on a query",
+						},
+						"filter_preset": {
+							Type:        "string",
+							Description: "Name of one of the pipeline's filter presets, as on a query",
 						},
-						"total_tokens": {
-							Type:        "integer",
-							Description: "Cumulative total tokens (prompt + completion)",
+						"include_timings": {
+							Type:        "boolean",
+							Description: "Include how long embedding and searching took in the response",
+							Default:     false,
 						},
-						"cache_creation_input_tokens": {
-							Type:        "integer",
-							Description: "Cumulative tokens used to write to the prompt cache",
+					},
+					Required: []string{"query"},
+				},
+				"SearchResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"results": {
+							Type:        "array",
+							Description: "Matching documents, best first; scores are the reranker's when the pipeline reranks",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Source",
+							},
 						},
-						"cache_read_input_tokens": {
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens the embedding, retrieval strategy and reranking used; completion is always zero",
+						},
+						"timings": {
+							Ref:         "#/components/schemas/Timings",
+							Description: "How long the search's stages took; only present when include_timings was set",
+						},
+					},
+					Required: []string{"results", "usage"},
+				},
+				"EmbedRequest": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"input": {
+							Type:        "array",
+							Description: "Texts to embed",
+							MaxItems:    intPtr(pipeline.MaxEmbedInputs),
+							Items: &OpenAPISchema{
+								Type: "string",
+							},
+						},
+					},
+					Required: []string{"input"},
+				},
+				"EmbedResponse": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"model": {
+							Type:        "string",
+							Description: "The pipeline's embedding model",
+						},
+						"dimensions": {
 							Type:        "integer",
-							Description: "Cumulative tokens read from the prompt cache",
+							Description: "Dimensions of each embedding",
+						},
+						"embeddings": {
+							Type:        "array",
+							Description: "One embedding for each input text, in order",
+							Items: &OpenAPISchema{
+								Type: "array",
+								Items: &OpenAPISchema{
+									Type:   "number",
+									Format: "float",
+								},
+							},
+						},
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens the embeddings used, as embedding; completion is always zero",
 						},
 					},
-					Required: []string{"prompt_tokens", "completion_tokens", "total_tokens"},
+					Required: []string{"model", "dimensions", "embeddings", "usage"},
 				},
-				"Message": {
+				"RetrievedDocument": {
 					Type: "object",
 					Properties: map[string]OpenAPISchema{
-						"role": {
+						"id": {
 							Type:        "string",
-							Description: "Message role (user or assistant)",
+							Description: "Value of the table's id_column; omitted when none is configured",
 						},
-						"content": {
+						"page_content": {
 							Type:        "string",
-							Description: "Message content",
+							Description: "The document's text",
+						},
+						"metadata": {
+							Type: "object",
+							Description: "Values of the table's configured metadata " +
+								"columns, keyed by column name",
+							AdditionalProperties: &OpenAPISchema{},
+						},
+						"score": {
+							Type:        "number",
+							Format:      "double",
+							Description: "Relevance score; the reranker's when the pipeline reranks",
 						},
 					},
-					Required: []string{"role", "content"},
+					Required: []string{"page_content", "metadata", "score"},
 				},
-				"QueryRequest": {
+				"ExplainRequest": {
 					Type: "object",
 					Properties: map[string]OpenAPISchema{
 						"query": {
 							Type:        "string",
-							Description: "The question to answer",
+							Description: "The query to explain",
 						},
-						"stream": {
-							Type:        "boolean",
-							Description: "Enable streaming response (SSE)",
-							Default:     false,
+						"document_id": {
+							Type:        "string",
+							Description: "Value of the table's id_column for the document to explain",
 						},
 						"top_n": {
 							Type:        "integer",
-							Description: "Override default result limit",
+							Description: "Override the number of results, as on a query",
 						},
 						"filter": {
 							Ref:         "#/components/schemas/Filter",
-							Description: "Structured filter to apply to search results",
+							Description: "Structured filter, as on a query",
 						},
-						"include_sources": {
+						"filter_preset": {
+							Type:        "string",
+							Description: "Name of one of the pipeline's filter presets, as on a query",
+						},
+					},
+					Required: []string{"query", "document_id"},
+				},
+				"Explanation": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query": {
+							Type: "string",
+						},
+						"document_id": {
+							Type: "string",
+						},
+						"retrieved": {
 							Type:        "boolean",
-							Description: "Include source documents in response",
-							Default:     false,
+							Description: "Whether the document is among the results sent to the LLM",
 						},
-						"messages": {
+						"rank": {
+							Type:        "integer",
+							Description: "1-based position among those results; omitted when not retrieved",
+						},
+						"tables": {
 							Type:        "array",
-							Description: "Previous conversation history for context",
+							Description: "How the document fared in each table's search",
 							Items: &OpenAPISchema{
-								Ref: "#/components/schemas/Message",
+								Ref: "#/components/schemas/TableExplanation",
+							},
+						},
+						"rerank": {
+							Type:        "object",
+							Description: "How reranking moved the document; omitted without a reranker or when the document was not retrieved",
+							Properties: map[string]OpenAPISchema{
+								"rank_before": {Type: "integer"},
+								"rank_after":  {Type: "integer", Description: "Omitted when rerank.top_k dropped the document"},
+								"score":       {Type: "number", Format: "double", Description: "The reranker's relevance score"},
 							},
 						},
 					},
-					Required: []string{"query"},
+					Required: []string{"query", "document_id", "retrieved", "tables"},
 				},
-				"QueryResponse": {
+				"TableExplanation": {
 					Type: "object",
 					Properties: map[string]OpenAPISchema{
-						"answer": {
+						"table": {
+							Type: "string",
+						},
+						"query": {
 							Type:        "string",
-							Description: "The generated answer",
+							Description: "The query this search ran, when a rewrite or the retrieval strategy made it differ from the request's",
+						},
+						"filter": {
+							Type: "object",
+							Properties: map[string]OpenAPISchema{
+								"in_table": {
+									Type:        "boolean",
+									Description: "The table has the document, with text, passing its configured filter",
+								},
+								"matches_request_filter": {
+									Type:        "boolean",
+									Description: "The document passes the request's filter; omitted without one",
+								},
+							},
+						},
+						"vector": {
+							Type: "object",
+							Properties: map[string]OpenAPISchema{
+								"candidates": {Type: "integer", Description: "Results the vector search returned"},
+								"rank":       {Type: "integer", Description: "1-based; omitted when not among them"},
+								"similarity": {Type: "number", Format: "double"},
+							},
+						},
+						"bm25": {
+							Type:        "object",
+							Description: "Omitted when hybrid search is disabled",
+							Properties: map[string]OpenAPISchema{
+								"candidates": {Type: "integer", Description: "Results the BM25 search returned"},
+								"rank":       {Type: "integer", Description: "1-based; omitted when not among them"},
+								"terms": {
+									Type:        "array",
+									Description: "Each query term's contribution to the score",
+									Items: &OpenAPISchema{
+										Type: "object",
+										Properties: map[string]OpenAPISchema{
+											"term":     {Type: "string"},
+											"doc_freq": {Type: "integer", Description: "Documents containing the term"},
+											"idf":      {Type: "number", Format: "double"},
+											"tf": {
+												Type:                 "object",
+												Description:          "Occurrences by field: content, or a lexical column",
+												AdditionalProperties: &OpenAPISchema{Type: "integer"},
+											},
+											"score": {Type: "number", Format: "double"},
+										},
+									},
+								},
+								"corrections": {
+									Type:                 "object",
+									Description:          "Misspelled query terms and their corrections, when spelling correction applied",
+									AdditionalProperties: &OpenAPISchema{Type: "string"},
+								},
+								"phrase_match": {Type: "boolean", Description: "The document contains every quoted phrase"},
+								"proximity":    {Type: "number", Format: "double", Description: "Weighted proximity bonus"},
+								"score":        {Type: "number", Format: "double", Description: "Total BM25 score; zero when the document does not match"},
+							},
+						},
+						"full_text": {
+							Type:        "object",
+							Description: "Set instead of bm25 for a table whose lexical_search is postgres_fts",
+							Properties: map[string]OpenAPISchema{
+								"candidates": {Type: "integer", Description: "Results the full-text search returned"},
+								"rank":       {Type: "integer", Description: "1-based; omitted when not among them"},
+								"score":      {Type: "number", Format: "double", Description: "ts_rank"},
+							},
+						},
+						"fusion": {
+							Type:        "object",
+							Description: "How the vector and lexical results were fused: by reciprocal rank fusion, where each rank r contributes weight / (k + r), or by score fusion, where each min-max normalized score s contributes weight * s; omitted when hybrid search is disabled",
+							Properties: map[string]OpenAPISchema{
+								"method":        {Type: "string", Enum: []string{"rrf", "score_fusion"}},
+								"k":             {Type: "number", Description: "RRF only"},
+								"vector_weight": {Type: "number", Format: "double"},
+								"vector_score":  {Type: "number", Format: "double", Description: "The vector result's contribution to score"},
+								"bm25_score":    {Type: "number", Format: "double", Description: "The lexical result's contribution to score, from BM25 or full-text search"},
+								"score":         {Type: "number", Format: "double"},
+								"rank":          {Type: "integer", Description: "1-based among the table's fused results"},
+							},
+						},
+						"visible": {
+							Type:        "boolean",
+							Description: "Whether access control let the caller see the document; only when the search found it",
+						},
+						"error": {
+							Type:        "string",
+							Description: "Why the table could not be searched",
+						},
+					},
+					Required: []string{"table"},
+				},
+				"StreamEvent": {
+					Type: "object",
+					Description: "A Server-Sent Event of a streaming query: an optional " +
+						"sources event, then chunk events, a usage event, and a done event",
+					Properties: map[string]OpenAPISchema{
+						"type": {
+							Type:        "string",
+							Description: "Event type",
+							Enum:        []string{"sources", "chunk", "usage", "done", "error"},
+						},
+						"content": {
+							Type:        "string",
+							Description: "Partial answer text (chunk events)",
 						},
 						"sources": {
-							Type:        "array",
-							Description: "Source documents (only if include_sources=true)",
+							Type: "array",
+							Description: "Source documents, sent before the first chunk " +
+								"(sources events; only if include_sources=true)",
 							Items: &OpenAPISchema{
 								Ref: "#/components/schemas/Source",
 							},
 						},
-						"tokens_used": {
-							Type:        "integer",
-							Description: "Total tokens consumed",
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens consumed by this request, by pipeline stage (usage and done events)",
+						},
+						"error": {
+							Type:        "string",
+							Description: "Error message (error events)",
+						},
+						"stage": {
+							Type:        "string",
+							Description: "Pipeline stage that timed out (error events)",
+							Enum:        []string{"embedding", "search", "completion", "total"},
+						},
+						"format_warnings": {
+							Type:        "array",
+							Description: "Formatting convention warnings (done events)",
+							Items: &OpenAPISchema{
+								Type: "string",
+							},
+						},
+						"citations": {
+							Type:        "array",
+							Description: "Sources cited by [n] markers in the answer (done events, citations mode only)",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Citation",
+							},
+						},
+						"guardrails": {
+							Type:        "array",
+							Description: "The pipeline's guardrails that changed the answer (done events)",
+							Items: &OpenAPISchema{
+								Type: "string",
+								Enum: []string{"ungrounded", "redacted", "truncated"},
+							},
+						},
+						"timings": {
+							Ref:         "#/components/schemas/Timings",
+							Description: "How long the query's stages took (done events, only if include_timings=true)",
+						},
+						"cached": {
+							Type:        "boolean",
+							Description: "The answer came from the pipeline's answer cache (done events); omitted otherwise",
+						},
+						"query_id": {
+							Type:        "string",
+							Description: "Identifies the query when the pipeline captured it for its eval dataset (done events); omitted otherwise",
+						},
+						"response_id": {
+							Type:        "string",
+							Description: "Identifies the answer when the pipeline takes feedback (done events); omitted otherwise",
+						},
+						"variant": {
+							Type:        "string",
+							Description: "The pipeline that answered the query when the pipeline asked runs an experiment (done events); omitted otherwise",
+						},
+						"provenance": {
+							Type:        "array",
+							Description: "The documents the answer was written from, with the hash of each one's stored content (done events); omitted when no documents were found",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/DocumentProvenance",
+							},
+						},
+						"signature": {
+							Ref:         "#/components/schemas/AnswerSignature",
+							Description: "Signature of the answer and its provenance (done events of streams that finished successfully, only if server.signing.enabled)",
 						},
 					},
-					Required: []string{"answer", "tokens_used"},
+					Required: []string{"type"},
+				},
+				"StageUsage": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"query_expansion": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Tokens used to paraphrase the query, draft a hypothetical answer or check the documents found; omitted unless the pipeline uses the multi_query, hyde or iterative retrieval strategy",
+						},
+						"history_summary": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Tokens used to summarize conversation history beyond the pipeline's max_history_tokens; omitted unless history was summarized",
+						},
+						"context_summary": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Tokens used to summarize a top document over the pipeline's token_budget; omitted unless one was summarized",
+						},
+						"embedding": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Query embedding tokens, including any paraphrases or draft (zero for providers that do not report them)",
+						},
+						"rerank": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Reranking tokens; omitted when the pipeline does not rerank",
+						},
+						"completion": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Answer generation tokens",
+						},
+						"groundedness": {
+							Ref:         "#/components/schemas/TokenUsage",
+							Description: "Tokens used to check the answer against the retrieved documents; omitted unless the pipeline's groundedness check ran",
+						},
+						"cost": {
+							Type:        "number",
+							Format:      "double",
+							Description: "Estimated cost of the request, from the pipeline's models' pricing; omitted when none of them is priced",
+						},
+					},
+					Required: []string{"embedding", "completion"},
 				},
 				"Source": {
 					Type: "object",
@@ -521,9 +1943,70 @@ func BuildOpenAPISpec() OpenAPISpec {
 							Format:      "double",
 							Description: "Relevance score",
 						},
+						"metadata": {
+							Type: "object",
+							Description: "Values of the table's configured metadata " +
+								"columns, keyed by column name",
+							AdditionalProperties: &OpenAPISchema{},
+						},
+						"content_hash": {
+							Type:        "string",
+							Description: "SHA-256 of the document's stored text, as sha256: and hex digits",
+						},
 					},
 					Required: []string{"content", "score"},
 				},
+				"Estimate": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"prompt_tokens": {
+							Type:        "integer",
+							Description: "Estimated tokens of the assembled prompt: system prompt, context, history and query",
+						},
+						"max_completion_tokens": {
+							Type:        "integer",
+							Description: "Bound the answer length sets on the answer; omitted when it sets none",
+						},
+						"documents": {
+							Type:        "integer",
+							Description: "Retrieved documents in the context; with none, the query is answered without a completion",
+						},
+						"usage": {
+							Ref:         "#/components/schemas/StageUsage",
+							Description: "Tokens the estimate itself spent on retrieval",
+						},
+						"models": {
+							Type:        "array",
+							Description: "The completion model and its fallbacks, in the order they are tried",
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/ModelEstimate",
+							},
+						},
+					},
+					Required: []string{"prompt_tokens", "documents", "usage", "models"},
+				},
+				"ModelEstimate": {
+					Type: "object",
+					Properties: map[string]OpenAPISchema{
+						"provider": {
+							Type:        "string",
+							Description: "Completion provider",
+						},
+						"model": {
+							Type:        "string",
+							Description: "Completion model",
+						},
+						"prompt_cost": {
+							Type:        "number",
+							Description: "Price of the prompt tokens; omitted when the model's pricing.input_per_million is not configured",
+						},
+						"max_completion_cost": {
+							Type:        "number",
+							Description: "Price of an answer of max_completion_tokens; omitted without that bound or pricing.output_per_million",
+						},
+					},
+					Required: []string{"provider", "model"},
+				},
 				"Filter": {
 					Type: "object",
 					Properties: map[string]OpenAPISchema{
@@ -535,14 +2018,21 @@ func BuildOpenAPISpec() OpenAPISpec {
 								Ref: "#/components/schemas/FilterCondition",
 							},
 						},
+						"groups": {
+							Type:        "array",
+							Description: "Nested filters, each combined with the conditions as one parenthesized term, so AND and OR can be mixed; groups nest at most 3 deep",
+							MaxItems:    intPtr(10),
+							Items: &OpenAPISchema{
+								Ref: "#/components/schemas/Filter",
+							},
+						},
 						"logic": {
 							Type:        "string",
 							Default:     "AND",
-							Description: "Logical operator to combine conditions: AND or OR (default: AND)",
+							Description: "Logical operator to combine conditions and groups: AND or OR (default: AND)",
 							Enum:        []string{"AND", "OR"},
 						},
 					},
-					Required: []string{"conditions"},
 				},
 				"FilterCondition": {
 					Type: "object",
@@ -582,6 +2072,23 @@ func BuildOpenAPISpec() OpenAPISpec {
 							Type:        "string",
 							Description: "Error message",
 						},
+						"stage": {
+							Type:        "string",
+							Description: "Pipeline stage that timed out (STAGE_TIMEOUT only)",
+							Enum:        []string{"embedding", "search", "completion", "total"},
+						},
+						"fields": {
+							Type:        "array",
+							Description: "Request body fields that do not match the schema (INVALID_REQUEST only)",
+							Items: &OpenAPISchema{
+								Type: "object",
+								Properties: map[string]OpenAPISchema{
+									"field":   {Type: "string", Description: "JSON path, such as filter.conditions[0].column"},
+									"message": {Type: "string"},
+								},
+								Required: []string{"field", "message"},
+							},
+						},
 					},
 					Required: []string{"code", "message"},
 				},
@@ -589,3 +2096,172 @@ func BuildOpenAPISpec() OpenAPISpec {
 		},
 	}
 }
+
+// sessionIDParameter is the {id} path parameter of the session routes.
+var sessionIDParameter = OpenAPIParameter{
+	Name:        "id",
+	In:          "path",
+	Description: "Session ID",
+	Required:    true,
+	Schema: OpenAPISchema{
+		Type: "string",
+	},
+}
+
+// jsonResponse describes a response whose application/json body is the
+// named component schema.
+func jsonResponse(description, schema string) OpenAPIResponse {
+	return OpenAPIResponse{
+		Description: description,
+		Content: map[string]OpenAPIMediaType{
+			"application/json": {
+				Schema: OpenAPISchema{
+					Ref: "#/components/schemas/" + schema,
+				},
+			},
+		},
+	}
+}
+
+// handlePipelineOpenAPI handles the GET /v1/pipelines/{name}/openapi.json
+// endpoint, describing the pipeline's operations with its filter and
+// metadata columns.
+func (s *Server) handlePipelineOpenAPI(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+name)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	describer, ok := p.(pipeline.Describer)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR",
+			"pipeline does not describe its capabilities")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, BuildPipelineOpenAPISpec(describer.Capabilities()))
+}
+
+// BuildPipelineOpenAPISpec constructs the OpenAPI specification of one
+// pipeline: its operations, with the {name} path parameter filled in,
+// and only the schemas they use. The document upload operation is left
+// out unless the pipeline ingests uploads. Filter columns become an
+// enum on FilterCondition.column when the pipeline restricts them,
+// filter preset names an enum on each request's filter_preset, and
+// every metadata object lists the pipeline's metadata columns.
+func BuildPipelineOpenAPISpec(caps pipeline.Capabilities) OpenAPISpec {
+	spec := BuildOpenAPISpec()
+	spec.Info.Title = "pgEdge RAG Server API: " + caps.Name
+	if caps.Description != "" {
+		spec.Info.Description = caps.Description
+	}
+
+	paths := make(map[string]OpenAPIPath)
+	for path, item := range spec.Paths {
+		if rest, ok := strings.CutPrefix(path, "/pipelines/{name}"); ok {
+			if rest == "/documents" && !caps.Ingest {
+				continue
+			}
+			paths["/pipelines/"+caps.Name+rest] = withoutNameParameter(item)
+		}
+	}
+	spec.Paths = paths
+
+	schemas := spec.Components.Schemas
+	if len(caps.FilterColumns) > 0 {
+		cond := schemas["FilterCondition"]
+		column := cond.Properties["column"]
+		column.Enum = caps.FilterColumns
+		cond.Properties["column"] = column
+	}
+	if len(caps.FilterPresets) > 0 {
+		for _, name := range []string{"QueryRequest", "RetrieveRequest", "SearchRequest"} {
+			preset := schemas[name].Properties["filter_preset"]
+			preset.Enum = caps.FilterPresets
+			schemas[name].Properties["filter_preset"] = preset
+		}
+	}
+	if len(caps.MetadataColumns) > 0 {
+		for _, schema := range schemas {
+			metadata, ok := schema.Properties["metadata"]
+			if !ok {
+				continue
+			}
+			metadata.Properties = make(map[string]OpenAPISchema, len(caps.MetadataColumns))
+			for _, col := range caps.MetadataColumns {
+				metadata.Properties[col] = OpenAPISchema{Description: "Value of the " + col + " column"}
+			}
+			metadata.AdditionalProperties = nil
+			schema.Properties["metadata"] = metadata
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, item := range spec.Paths {
+		for _, op := range []*OpenAPIOperation{item.Get, item.Post, item.Put, item.Delete} {
+			if op == nil {
+				continue
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					collectSchemaRefs(media.Schema, schemas, used)
+				}
+			}
+			for _, resp := range op.Responses {
+				for _, media := range resp.Content {
+					collectSchemaRefs(media.Schema, schemas, used)
+				}
+			}
+		}
+	}
+	for name := range schemas {
+		if !used[name] {
+			delete(schemas, name)
+		}
+	}
+	return spec
+}
+
+// withoutNameParameter returns a copy of item whose operations no
+// longer take the {name} path parameter.
+func withoutNameParameter(item OpenAPIPath) OpenAPIPath {
+	strip := func(op *OpenAPIOperation) *OpenAPIOperation {
+		if op == nil {
+			return nil
+		}
+		out := *op
+		out.Parameters = slices.DeleteFunc(slices.Clone(op.Parameters), func(p OpenAPIParameter) bool {
+			return p.In == "path" && p.Name == "name"
+		})
+		return &out
+	}
+	return OpenAPIPath{
+		Get:    strip(item.Get),
+		Post:   strip(item.Post),
+		Put:    strip(item.Put),
+		Delete: strip(item.Delete),
+	}
+}
+
+// collectSchemaRefs marks the component schemas schema refers to,
+// directly or through other schemas, as used.
+func collectSchemaRefs(schema OpenAPISchema, schemas map[string]OpenAPISchema, used map[string]bool) {
+	if name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/"); ok && !used[name] {
+		used[name] = true
+		collectSchemaRefs(schemas[name], schemas, used)
+	}
+	for _, prop := range schema.Properties {
+		collectSchemaRefs(prop, schemas, used)
+	}
+	if schema.Items != nil {
+		collectSchemaRefs(*schema.Items, schemas, used)
+	}
+	if schema.AdditionalProperties != nil {
+		collectSchemaRefs(*schema.AdditionalProperties, schemas, used)
+	}
+}
@@ -0,0 +1,1746 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: pgedge/rag/v1/rag.proto
+
+package ragv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// QueryRequest is a question for a pipeline. Fields mean what they do
+// in the HTTP API's query request.
+type QueryRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Pipeline that answers the question.
+	Pipeline string `protobuf:"bytes,1,opt,name=pipeline,proto3" json:"pipeline,omitempty"`
+	Query    string `protobuf:"bytes,2,opt,name=query,proto3" json:"query,omitempty"`
+	// Documents retrieved; zero uses the pipeline's top_n.
+	TopN int32 `protobuf:"varint,3,opt,name=top_n,json=topN,proto3" json:"top_n,omitempty"`
+	// Structured filter, in the HTTP API's JSON form.
+	Filter         *structpb.Struct `protobuf:"bytes,4,opt,name=filter,proto3" json:"filter,omitempty"`
+	FilterPreset   string           `protobuf:"bytes,5,opt,name=filter_preset,json=filterPreset,proto3" json:"filter_preset,omitempty"`
+	IncludeSources bool             `protobuf:"varint,6,opt,name=include_sources,json=includeSources,proto3" json:"include_sources,omitempty"`
+	IncludeTimings bool             `protobuf:"varint,7,opt,name=include_timings,json=includeTimings,proto3" json:"include_timings,omitempty"`
+	// Previous conversation turns, oldest first.
+	Messages      []*Message       `protobuf:"bytes,8,rep,name=messages,proto3" json:"messages,omitempty"`
+	StopSequences []string         `protobuf:"bytes,9,rep,name=stop_sequences,json=stopSequences,proto3" json:"stop_sequences,omitempty"`
+	LogitBias     map[string]int32 `protobuf:"bytes,10,rep,name=logit_bias,json=logitBias,proto3" json:"logit_bias,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	AnswerLength  string           `protobuf:"bytes,11,opt,name=answer_length,json=answerLength,proto3" json:"answer_length,omitempty"`
+	// Server-side session whose history precedes messages; the turn is
+	// recorded in it once the question is answered.
+	SessionId     string `protobuf:"bytes,12,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QueryRequest) GetPipeline() string {
+	if x != nil {
+		return x.Pipeline
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetTopN() int32 {
+	if x != nil {
+		return x.TopN
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetFilter() *structpb.Struct {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetFilterPreset() string {
+	if x != nil {
+		return x.FilterPreset
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetIncludeSources() bool {
+	if x != nil {
+		return x.IncludeSources
+	}
+	return false
+}
+
+func (x *QueryRequest) GetIncludeTimings() bool {
+	if x != nil {
+		return x.IncludeTimings
+	}
+	return false
+}
+
+func (x *QueryRequest) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetStopSequences() []string {
+	if x != nil {
+		return x.StopSequences
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetLogitBias() map[string]int32 {
+	if x != nil {
+		return x.LogitBias
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetAnswerLength() string {
+	if x != nil {
+		return x.AnswerLength
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+// Message is a turn of a conversation.
+type Message struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "user" or "assistant".
+	Role          string `protobuf:"bytes,1,opt,name=role,proto3" json:"role,omitempty"`
+	Content       string `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Message) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *Message) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+// QueryResponse is a pipeline's answer to a question.
+type QueryResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Answer         string                 `protobuf:"bytes,1,opt,name=answer,proto3" json:"answer,omitempty"`
+	Sources        []*Source              `protobuf:"bytes,2,rep,name=sources,proto3" json:"sources,omitempty"`
+	TokensUsed     int32                  `protobuf:"varint,3,opt,name=tokens_used,json=tokensUsed,proto3" json:"tokens_used,omitempty"`
+	Usage          *StageUsage            `protobuf:"bytes,4,opt,name=usage,proto3" json:"usage,omitempty"`
+	FormatWarnings []string               `protobuf:"bytes,5,rep,name=format_warnings,json=formatWarnings,proto3" json:"format_warnings,omitempty"`
+	Citations      []*Citation            `protobuf:"bytes,6,rep,name=citations,proto3" json:"citations,omitempty"`
+	Guardrails     []string               `protobuf:"bytes,7,rep,name=guardrails,proto3" json:"guardrails,omitempty"`
+	Timings        *Timings               `protobuf:"bytes,8,opt,name=timings,proto3" json:"timings,omitempty"`
+	Cached         bool                   `protobuf:"varint,9,opt,name=cached,proto3" json:"cached,omitempty"`
+	QueryId        string                 `protobuf:"bytes,10,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
+	// Identifies the answer for feedback and replay.
+	ResponseId string `protobuf:"bytes,11,opt,name=response_id,json=responseId,proto3" json:"response_id,omitempty"`
+	// The documents the answer was written from.
+	Provenance []*DocumentProvenance `protobuf:"bytes,12,rep,name=provenance,proto3" json:"provenance,omitempty"`
+	// Signs the answer and its provenance, when the server signs answers.
+	Signature     *AnswerSignature `protobuf:"bytes,13,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *QueryResponse) GetAnswer() string {
+	if x != nil {
+		return x.Answer
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetSources() []*Source {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetTokensUsed() int32 {
+	if x != nil {
+		return x.TokensUsed
+	}
+	return 0
+}
+
+func (x *QueryResponse) GetUsage() *StageUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetFormatWarnings() []string {
+	if x != nil {
+		return x.FormatWarnings
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCitations() []*Citation {
+	if x != nil {
+		return x.Citations
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetGuardrails() []string {
+	if x != nil {
+		return x.Guardrails
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetTimings() *Timings {
+	if x != nil {
+		return x.Timings
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *QueryResponse) GetQueryId() string {
+	if x != nil {
+		return x.QueryId
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetResponseId() string {
+	if x != nil {
+		return x.ResponseId
+	}
+	return ""
+}
+
+func (x *QueryResponse) GetProvenance() []*DocumentProvenance {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetSignature() *AnswerSignature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// QueryStreamResponse is one message of a streamed answer.
+type QueryStreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*QueryStreamResponse_Sources
+	//	*QueryStreamResponse_Chunk
+	//	*QueryStreamResponse_Done
+	Event         isQueryStreamResponse_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryStreamResponse) Reset() {
+	*x = QueryStreamResponse{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryStreamResponse) ProtoMessage() {}
+
+func (x *QueryStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryStreamResponse.ProtoReflect.Descriptor instead.
+func (*QueryStreamResponse) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *QueryStreamResponse) GetEvent() isQueryStreamResponse_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *QueryStreamResponse) GetSources() *Sources {
+	if x != nil {
+		if x, ok := x.Event.(*QueryStreamResponse_Sources); ok {
+			return x.Sources
+		}
+	}
+	return nil
+}
+
+func (x *QueryStreamResponse) GetChunk() string {
+	if x != nil {
+		if x, ok := x.Event.(*QueryStreamResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return ""
+}
+
+func (x *QueryStreamResponse) GetDone() *Done {
+	if x != nil {
+		if x, ok := x.Event.(*QueryStreamResponse_Done); ok {
+			return x.Done
+		}
+	}
+	return nil
+}
+
+type isQueryStreamResponse_Event interface {
+	isQueryStreamResponse_Event()
+}
+
+type QueryStreamResponse_Sources struct {
+	// The documents the answer is written from.
+	Sources *Sources `protobuf:"bytes,1,opt,name=sources,proto3,oneof"`
+}
+
+type QueryStreamResponse_Chunk struct {
+	// The next piece of the answer's text.
+	Chunk string `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"`
+}
+
+type QueryStreamResponse_Done struct {
+	// The end of the answer.
+	Done *Done `protobuf:"bytes,3,opt,name=done,proto3,oneof"`
+}
+
+func (*QueryStreamResponse_Sources) isQueryStreamResponse_Event() {}
+
+func (*QueryStreamResponse_Chunk) isQueryStreamResponse_Event() {}
+
+func (*QueryStreamResponse_Done) isQueryStreamResponse_Event() {}
+
+// Sources lists the documents an answer is written from.
+type Sources struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sources       []*Source              `protobuf:"bytes,1,rep,name=sources,proto3" json:"sources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sources) Reset() {
+	*x = Sources{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sources) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sources) ProtoMessage() {}
+
+func (x *Sources) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sources.ProtoReflect.Descriptor instead.
+func (*Sources) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Sources) GetSources() []*Source {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+// Done ends a streamed answer.
+type Done struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Usage          *StageUsage            `protobuf:"bytes,1,opt,name=usage,proto3" json:"usage,omitempty"`
+	FormatWarnings []string               `protobuf:"bytes,2,rep,name=format_warnings,json=formatWarnings,proto3" json:"format_warnings,omitempty"`
+	Citations      []*Citation            `protobuf:"bytes,3,rep,name=citations,proto3" json:"citations,omitempty"`
+	Guardrails     []string               `protobuf:"bytes,4,rep,name=guardrails,proto3" json:"guardrails,omitempty"`
+	Timings        *Timings               `protobuf:"bytes,5,opt,name=timings,proto3" json:"timings,omitempty"`
+	Cached         bool                   `protobuf:"varint,6,opt,name=cached,proto3" json:"cached,omitempty"`
+	QueryId        string                 `protobuf:"bytes,7,opt,name=query_id,json=queryId,proto3" json:"query_id,omitempty"`
+	ResponseId     string                 `protobuf:"bytes,8,opt,name=response_id,json=responseId,proto3" json:"response_id,omitempty"`
+	Provenance     []*DocumentProvenance  `protobuf:"bytes,9,rep,name=provenance,proto3" json:"provenance,omitempty"`
+	// Unset when the server does not sign answers.
+	Signature     *AnswerSignature `protobuf:"bytes,10,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Done) Reset() {
+	*x = Done{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Done) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Done) ProtoMessage() {}
+
+func (x *Done) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Done.ProtoReflect.Descriptor instead.
+func (*Done) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Done) GetUsage() *StageUsage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
+func (x *Done) GetFormatWarnings() []string {
+	if x != nil {
+		return x.FormatWarnings
+	}
+	return nil
+}
+
+func (x *Done) GetCitations() []*Citation {
+	if x != nil {
+		return x.Citations
+	}
+	return nil
+}
+
+func (x *Done) GetGuardrails() []string {
+	if x != nil {
+		return x.Guardrails
+	}
+	return nil
+}
+
+func (x *Done) GetTimings() *Timings {
+	if x != nil {
+		return x.Timings
+	}
+	return nil
+}
+
+func (x *Done) GetCached() bool {
+	if x != nil {
+		return x.Cached
+	}
+	return false
+}
+
+func (x *Done) GetQueryId() string {
+	if x != nil {
+		return x.QueryId
+	}
+	return ""
+}
+
+func (x *Done) GetResponseId() string {
+	if x != nil {
+		return x.ResponseId
+	}
+	return ""
+}
+
+func (x *Done) GetProvenance() []*DocumentProvenance {
+	if x != nil {
+		return x.Provenance
+	}
+	return nil
+}
+
+func (x *Done) GetSignature() *AnswerSignature {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
+// Source is a document an answer was written from.
+type Source struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Content string                 `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"`
+	Score   float64                `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`
+	// The table's metadata_columns.
+	Metadata      *structpb.Struct `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Source) Reset() {
+	*x = Source{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Source) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Source) ProtoMessage() {}
+
+func (x *Source) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Source.ProtoReflect.Descriptor instead.
+func (*Source) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Source) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Source) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *Source) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Source) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// Citation maps a [n] marker in an answer to the source it cites.
+type Citation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Marker        int32                  `protobuf:"varint,1,opt,name=marker,proto3" json:"marker,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Score         float64                `protobuf:"fixed64,3,opt,name=score,proto3" json:"score,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Citation) Reset() {
+	*x = Citation{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Citation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Citation) ProtoMessage() {}
+
+func (x *Citation) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Citation.ProtoReflect.Descriptor instead.
+func (*Citation) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Citation) GetMarker() int32 {
+	if x != nil {
+		return x.Marker
+	}
+	return 0
+}
+
+func (x *Citation) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Citation) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Citation) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+// DocumentProvenance identifies a document an answer was written from
+// and the version of it that was used.
+type DocumentProvenance struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// "sha256:" and the hex SHA-256 of the document's text as stored.
+	ContentHash   string `protobuf:"bytes,2,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DocumentProvenance) Reset() {
+	*x = DocumentProvenance{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocumentProvenance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocumentProvenance) ProtoMessage() {}
+
+func (x *DocumentProvenance) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocumentProvenance.ProtoReflect.Descriptor instead.
+func (*DocumentProvenance) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DocumentProvenance) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *DocumentProvenance) GetContentHash() string {
+	if x != nil {
+		return x.ContentHash
+	}
+	return ""
+}
+
+// AnswerSignature signs an answer with the server's key. The payload
+// is the signed JSON document; the signature is of the payload's
+// bytes, by the key key_id names, as GET /v1/signing-key serves it.
+// Both are base64url-encoded without padding.
+type AnswerSignature struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Algorithm     string                 `protobuf:"bytes,1,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	KeyId         string                 `protobuf:"bytes,2,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	Payload       string                 `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Signature     string                 `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AnswerSignature) Reset() {
+	*x = AnswerSignature{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AnswerSignature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AnswerSignature) ProtoMessage() {}
+
+func (x *AnswerSignature) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AnswerSignature.ProtoReflect.Descriptor instead.
+func (*AnswerSignature) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *AnswerSignature) GetAlgorithm() string {
+	if x != nil {
+		return x.Algorithm
+	}
+	return ""
+}
+
+func (x *AnswerSignature) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+func (x *AnswerSignature) GetPayload() string {
+	if x != nil {
+		return x.Payload
+	}
+	return ""
+}
+
+func (x *AnswerSignature) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+// TokenUsage counts the tokens of a provider's calls.
+type TokenUsage struct {
+	state                    protoimpl.MessageState `protogen:"open.v1"`
+	PromptTokens             int32                  `protobuf:"varint,1,opt,name=prompt_tokens,json=promptTokens,proto3" json:"prompt_tokens,omitempty"`
+	CompletionTokens         int32                  `protobuf:"varint,2,opt,name=completion_tokens,json=completionTokens,proto3" json:"completion_tokens,omitempty"`
+	TotalTokens              int32                  `protobuf:"varint,3,opt,name=total_tokens,json=totalTokens,proto3" json:"total_tokens,omitempty"`
+	CacheCreationInputTokens int32                  `protobuf:"varint,4,opt,name=cache_creation_input_tokens,json=cacheCreationInputTokens,proto3" json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int32                  `protobuf:"varint,5,opt,name=cache_read_input_tokens,json=cacheReadInputTokens,proto3" json:"cache_read_input_tokens,omitempty"`
+	unknownFields            protoimpl.UnknownFields
+	sizeCache                protoimpl.SizeCache
+}
+
+func (x *TokenUsage) Reset() {
+	*x = TokenUsage{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenUsage) ProtoMessage() {}
+
+func (x *TokenUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenUsage.ProtoReflect.Descriptor instead.
+func (*TokenUsage) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *TokenUsage) GetPromptTokens() int32 {
+	if x != nil {
+		return x.PromptTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCompletionTokens() int32 {
+	if x != nil {
+		return x.CompletionTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetTotalTokens() int32 {
+	if x != nil {
+		return x.TotalTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCacheCreationInputTokens() int32 {
+	if x != nil {
+		return x.CacheCreationInputTokens
+	}
+	return 0
+}
+
+func (x *TokenUsage) GetCacheReadInputTokens() int32 {
+	if x != nil {
+		return x.CacheReadInputTokens
+	}
+	return 0
+}
+
+// StageUsage breaks a query's tokens down by pipeline stage. Stages
+// that did not run are unset.
+type StageUsage struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	QueryExpansion *TokenUsage            `protobuf:"bytes,1,opt,name=query_expansion,json=queryExpansion,proto3" json:"query_expansion,omitempty"`
+	HistorySummary *TokenUsage            `protobuf:"bytes,2,opt,name=history_summary,json=historySummary,proto3" json:"history_summary,omitempty"`
+	ContextSummary *TokenUsage            `protobuf:"bytes,3,opt,name=context_summary,json=contextSummary,proto3" json:"context_summary,omitempty"`
+	Embedding      *TokenUsage            `protobuf:"bytes,4,opt,name=embedding,proto3" json:"embedding,omitempty"`
+	Rerank         *TokenUsage            `protobuf:"bytes,5,opt,name=rerank,proto3" json:"rerank,omitempty"`
+	Completion     *TokenUsage            `protobuf:"bytes,6,opt,name=completion,proto3" json:"completion,omitempty"`
+	Groundedness   *TokenUsage            `protobuf:"bytes,7,opt,name=groundedness,proto3" json:"groundedness,omitempty"`
+	// Estimated cost; unset when none of the models used is priced.
+	Cost          *float64 `protobuf:"fixed64,8,opt,name=cost,proto3,oneof" json:"cost,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageUsage) Reset() {
+	*x = StageUsage{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageUsage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageUsage) ProtoMessage() {}
+
+func (x *StageUsage) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageUsage.ProtoReflect.Descriptor instead.
+func (*StageUsage) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *StageUsage) GetQueryExpansion() *TokenUsage {
+	if x != nil {
+		return x.QueryExpansion
+	}
+	return nil
+}
+
+func (x *StageUsage) GetHistorySummary() *TokenUsage {
+	if x != nil {
+		return x.HistorySummary
+	}
+	return nil
+}
+
+func (x *StageUsage) GetContextSummary() *TokenUsage {
+	if x != nil {
+		return x.ContextSummary
+	}
+	return nil
+}
+
+func (x *StageUsage) GetEmbedding() *TokenUsage {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *StageUsage) GetRerank() *TokenUsage {
+	if x != nil {
+		return x.Rerank
+	}
+	return nil
+}
+
+func (x *StageUsage) GetCompletion() *TokenUsage {
+	if x != nil {
+		return x.Completion
+	}
+	return nil
+}
+
+func (x *StageUsage) GetGroundedness() *TokenUsage {
+	if x != nil {
+		return x.Groundedness
+	}
+	return nil
+}
+
+func (x *StageUsage) GetCost() float64 {
+	if x != nil && x.Cost != nil {
+		return *x.Cost
+	}
+	return 0
+}
+
+// Timings reports how long a query's stages took, in milliseconds.
+type Timings struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	EmbedMs  int64                  `protobuf:"varint,1,opt,name=embed_ms,json=embedMs,proto3" json:"embed_ms,omitempty"`
+	SearchMs int64                  `protobuf:"varint,2,opt,name=search_ms,json=searchMs,proto3" json:"search_ms,omitempty"`
+	// Time until the first answer text was sent; streamed queries only.
+	TtfbMs        *int64 `protobuf:"varint,3,opt,name=ttfb_ms,json=ttfbMs,proto3,oneof" json:"ttfb_ms,omitempty"`
+	TotalMs       int64  `protobuf:"varint,4,opt,name=total_ms,json=totalMs,proto3" json:"total_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Timings) Reset() {
+	*x = Timings{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Timings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Timings) ProtoMessage() {}
+
+func (x *Timings) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Timings.ProtoReflect.Descriptor instead.
+func (*Timings) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *Timings) GetEmbedMs() int64 {
+	if x != nil {
+		return x.EmbedMs
+	}
+	return 0
+}
+
+func (x *Timings) GetSearchMs() int64 {
+	if x != nil {
+		return x.SearchMs
+	}
+	return 0
+}
+
+func (x *Timings) GetTtfbMs() int64 {
+	if x != nil && x.TtfbMs != nil {
+		return *x.TtfbMs
+	}
+	return 0
+}
+
+func (x *Timings) GetTotalMs() int64 {
+	if x != nil {
+		return x.TotalMs
+	}
+	return 0
+}
+
+type ListPipelinesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesRequest) Reset() {
+	*x = ListPipelinesRequest{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesRequest) ProtoMessage() {}
+
+func (x *ListPipelinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPipelinesRequest.ProtoReflect.Descriptor instead.
+func (*ListPipelinesRequest) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{13}
+}
+
+type ListPipelinesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pipelines     []*Pipeline            `protobuf:"bytes,1,rep,name=pipelines,proto3" json:"pipelines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPipelinesResponse) Reset() {
+	*x = ListPipelinesResponse{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPipelinesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPipelinesResponse) ProtoMessage() {}
+
+func (x *ListPipelinesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPipelinesResponse.ProtoReflect.Descriptor instead.
+func (*ListPipelinesResponse) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListPipelinesResponse) GetPipelines() []*Pipeline {
+	if x != nil {
+		return x.Pipelines
+	}
+	return nil
+}
+
+// Pipeline describes a configured pipeline.
+type Pipeline struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Pipeline) Reset() {
+	*x = Pipeline{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Pipeline) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Pipeline) ProtoMessage() {}
+
+func (x *Pipeline) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Pipeline.ProtoReflect.Descriptor instead.
+func (*Pipeline) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Pipeline) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Pipeline) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type HealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthRequest) Reset() {
+	*x = HealthRequest{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthRequest) ProtoMessage() {}
+
+func (x *HealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthRequest.ProtoReflect.Descriptor instead.
+func (*HealthRequest) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{16}
+}
+
+// HealthResponse reports whether every pipeline's providers are
+// reachable.
+type HealthResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "healthy", or "degraded" when any provider is unreachable.
+	Status        string            `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	Pipelines     []*PipelineHealth `protobuf:"bytes,2,rep,name=pipelines,proto3" json:"pipelines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthResponse) Reset() {
+	*x = HealthResponse{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthResponse) ProtoMessage() {}
+
+func (x *HealthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthResponse.ProtoReflect.Descriptor instead.
+func (*HealthResponse) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *HealthResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *HealthResponse) GetPipelines() []*PipelineHealth {
+	if x != nil {
+		return x.Pipelines
+	}
+	return nil
+}
+
+// PipelineHealth reports whether a pipeline's providers are reachable.
+type PipelineHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Embedding     *ProviderHealth        `protobuf:"bytes,2,opt,name=embedding,proto3" json:"embedding,omitempty"`
+	Completion    *ProviderHealth        `protobuf:"bytes,3,opt,name=completion,proto3" json:"completion,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PipelineHealth) Reset() {
+	*x = PipelineHealth{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PipelineHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PipelineHealth) ProtoMessage() {}
+
+func (x *PipelineHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PipelineHealth.ProtoReflect.Descriptor instead.
+func (*PipelineHealth) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PipelineHealth) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *PipelineHealth) GetEmbedding() *ProviderHealth {
+	if x != nil {
+		return x.Embedding
+	}
+	return nil
+}
+
+func (x *PipelineHealth) GetCompletion() *ProviderHealth {
+	if x != nil {
+		return x.Completion
+	}
+	return nil
+}
+
+// ProviderHealth reports whether a provider was reachable.
+type ProviderHealth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reachable     bool                   `protobuf:"varint,1,opt,name=reachable,proto3" json:"reachable,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProviderHealth) Reset() {
+	*x = ProviderHealth{}
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProviderHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProviderHealth) ProtoMessage() {}
+
+func (x *ProviderHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_pgedge_rag_v1_rag_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProviderHealth.ProtoReflect.Descriptor instead.
+func (*ProviderHealth) Descriptor() ([]byte, []int) {
+	return file_pgedge_rag_v1_rag_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ProviderHealth) GetReachable() bool {
+	if x != nil {
+		return x.Reachable
+	}
+	return false
+}
+
+func (x *ProviderHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+var File_pgedge_rag_v1_rag_proto protoreflect.FileDescriptor
+
+const file_pgedge_rag_v1_rag_proto_rawDesc = "" +
+	"\n" +
+	"\x17pgedge/rag/v1/rag.proto\x12\rpgedge.rag.v1\x1a\x1cgoogle/protobuf/struct.proto\"\xa5\x04\n" +
+	"\fQueryRequest\x12\x1a\n" +
+	"\bpipeline\x18\x01 \x01(\tR\bpipeline\x12\x14\n" +
+	"\x05query\x18\x02 \x01(\tR\x05query\x12\x13\n" +
+	"\x05top_n\x18\x03 \x01(\x05R\x04topN\x12/\n" +
+	"\x06filter\x18\x04 \x01(\v2\x17.google.protobuf.StructR\x06filter\x12#\n" +
+	"\rfilter_preset\x18\x05 \x01(\tR\ffilterPreset\x12'\n" +
+	"\x0finclude_sources\x18\x06 \x01(\bR\x0eincludeSources\x12'\n" +
+	"\x0finclude_timings\x18\a \x01(\bR\x0eincludeTimings\x122\n" +
+	"\bmessages\x18\b \x03(\v2\x16.pgedge.rag.v1.MessageR\bmessages\x12%\n" +
+	"\x0estop_sequences\x18\t \x03(\tR\rstopSequences\x12I\n" +
+	"\n" +
+	"logit_bias\x18\n" +
+	" \x03(\v2*.pgedge.rag.v1.QueryRequest.LogitBiasEntryR\tlogitBias\x12#\n" +
+	"\ranswer_length\x18\v \x01(\tR\fanswerLength\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\f \x01(\tR\tsessionId\x1a<\n" +
+	"\x0eLogitBiasEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"7\n" +
+	"\aMessage\x12\x12\n" +
+	"\x04role\x18\x01 \x01(\tR\x04role\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\"\xb1\x04\n" +
+	"\rQueryResponse\x12\x16\n" +
+	"\x06answer\x18\x01 \x01(\tR\x06answer\x12/\n" +
+	"\asources\x18\x02 \x03(\v2\x15.pgedge.rag.v1.SourceR\asources\x12\x1f\n" +
+	"\vtokens_used\x18\x03 \x01(\x05R\n" +
+	"tokensUsed\x12/\n" +
+	"\x05usage\x18\x04 \x01(\v2\x19.pgedge.rag.v1.StageUsageR\x05usage\x12'\n" +
+	"\x0fformat_warnings\x18\x05 \x03(\tR\x0eformatWarnings\x125\n" +
+	"\tcitations\x18\x06 \x03(\v2\x17.pgedge.rag.v1.CitationR\tcitations\x12\x1e\n" +
+	"\n" +
+	"guardrails\x18\a \x03(\tR\n" +
+	"guardrails\x120\n" +
+	"\atimings\x18\b \x01(\v2\x16.pgedge.rag.v1.TimingsR\atimings\x12\x16\n" +
+	"\x06cached\x18\t \x01(\bR\x06cached\x12\x19\n" +
+	"\bquery_id\x18\n" +
+	" \x01(\tR\aqueryId\x12\x1f\n" +
+	"\vresponse_id\x18\v \x01(\tR\n" +
+	"responseId\x12A\n" +
+	"\n" +
+	"provenance\x18\f \x03(\v2!.pgedge.rag.v1.DocumentProvenanceR\n" +
+	"provenance\x12<\n" +
+	"\tsignature\x18\r \x01(\v2\x1e.pgedge.rag.v1.AnswerSignatureR\tsignature\"\x95\x01\n" +
+	"\x13QueryStreamResponse\x122\n" +
+	"\asources\x18\x01 \x01(\v2\x16.pgedge.rag.v1.SourcesH\x00R\asources\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\tH\x00R\x05chunk\x12)\n" +
+	"\x04done\x18\x03 \x01(\v2\x13.pgedge.rag.v1.DoneH\x00R\x04doneB\a\n" +
+	"\x05event\":\n" +
+	"\aSources\x12/\n" +
+	"\asources\x18\x01 \x03(\v2\x15.pgedge.rag.v1.SourceR\asources\"\xbe\x03\n" +
+	"\x04Done\x12/\n" +
+	"\x05usage\x18\x01 \x01(\v2\x19.pgedge.rag.v1.StageUsageR\x05usage\x12'\n" +
+	"\x0fformat_warnings\x18\x02 \x03(\tR\x0eformatWarnings\x125\n" +
+	"\tcitations\x18\x03 \x03(\v2\x17.pgedge.rag.v1.CitationR\tcitations\x12\x1e\n" +
+	"\n" +
+	"guardrails\x18\x04 \x03(\tR\n" +
+	"guardrails\x120\n" +
+	"\atimings\x18\x05 \x01(\v2\x16.pgedge.rag.v1.TimingsR\atimings\x12\x16\n" +
+	"\x06cached\x18\x06 \x01(\bR\x06cached\x12\x19\n" +
+	"\bquery_id\x18\a \x01(\tR\aqueryId\x12\x1f\n" +
+	"\vresponse_id\x18\b \x01(\tR\n" +
+	"responseId\x12A\n" +
+	"\n" +
+	"provenance\x18\t \x03(\v2!.pgedge.rag.v1.DocumentProvenanceR\n" +
+	"provenance\x12<\n" +
+	"\tsignature\x18\n" +
+	" \x01(\v2\x1e.pgedge.rag.v1.AnswerSignatureR\tsignature\"}\n" +
+	"\x06Source\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\"}\n" +
+	"\bCitation\x12\x16\n" +
+	"\x06marker\x18\x01 \x01(\x05R\x06marker\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x14\n" +
+	"\x05score\x18\x03 \x01(\x01R\x05score\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\"G\n" +
+	"\x12DocumentProvenance\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\fcontent_hash\x18\x02 \x01(\tR\vcontentHash\"~\n" +
+	"\x0fAnswerSignature\x12\x1c\n" +
+	"\talgorithm\x18\x01 \x01(\tR\talgorithm\x12\x15\n" +
+	"\x06key_id\x18\x02 \x01(\tR\x05keyId\x12\x18\n" +
+	"\apayload\x18\x03 \x01(\tR\apayload\x12\x1c\n" +
+	"\tsignature\x18\x04 \x01(\tR\tsignature\"\xf7\x01\n" +
+	"\n" +
+	"TokenUsage\x12#\n" +
+	"\rprompt_tokens\x18\x01 \x01(\x05R\fpromptTokens\x12+\n" +
+	"\x11completion_tokens\x18\x02 \x01(\x05R\x10completionTokens\x12!\n" +
+	"\ftotal_tokens\x18\x03 \x01(\x05R\vtotalTokens\x12=\n" +
+	"\x1bcache_creation_input_tokens\x18\x04 \x01(\x05R\x18cacheCreationInputTokens\x125\n" +
+	"\x17cache_read_input_tokens\x18\x05 \x01(\x05R\x14cacheReadInputTokens\"\xe0\x03\n" +
+	"\n" +
+	"StageUsage\x12B\n" +
+	"\x0fquery_expansion\x18\x01 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\x0equeryExpansion\x12B\n" +
+	"\x0fhistory_summary\x18\x02 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\x0ehistorySummary\x12B\n" +
+	"\x0fcontext_summary\x18\x03 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\x0econtextSummary\x127\n" +
+	"\tembedding\x18\x04 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\tembedding\x121\n" +
+	"\x06rerank\x18\x05 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\x06rerank\x129\n" +
+	"\n" +
+	"completion\x18\x06 \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\n" +
+	"completion\x12=\n" +
+	"\fgroundedness\x18\a \x01(\v2\x19.pgedge.rag.v1.TokenUsageR\fgroundedness\x12\x17\n" +
+	"\x04cost\x18\b \x01(\x01H\x00R\x04cost\x88\x01\x01B\a\n" +
+	"\x05_cost\"\x86\x01\n" +
+	"\aTimings\x12\x19\n" +
+	"\bembed_ms\x18\x01 \x01(\x03R\aembedMs\x12\x1b\n" +
+	"\tsearch_ms\x18\x02 \x01(\x03R\bsearchMs\x12\x1c\n" +
+	"\attfb_ms\x18\x03 \x01(\x03H\x00R\x06ttfbMs\x88\x01\x01\x12\x19\n" +
+	"\btotal_ms\x18\x04 \x01(\x03R\atotalMsB\n" +
+	"\n" +
+	"\b_ttfb_ms\"\x16\n" +
+	"\x14ListPipelinesRequest\"N\n" +
+	"\x15ListPipelinesResponse\x125\n" +
+	"\tpipelines\x18\x01 \x03(\v2\x17.pgedge.rag.v1.PipelineR\tpipelines\"@\n" +
+	"\bPipeline\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\"\x0f\n" +
+	"\rHealthRequest\"e\n" +
+	"\x0eHealthResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12;\n" +
+	"\tpipelines\x18\x02 \x03(\v2\x1d.pgedge.rag.v1.PipelineHealthR\tpipelines\"\xa0\x01\n" +
+	"\x0ePipelineHealth\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12;\n" +
+	"\tembedding\x18\x02 \x01(\v2\x1d.pgedge.rag.v1.ProviderHealthR\tembedding\x12=\n" +
+	"\n" +
+	"completion\x18\x03 \x01(\v2\x1d.pgedge.rag.v1.ProviderHealthR\n" +
+	"completion\"D\n" +
+	"\x0eProviderHealth\x12\x1c\n" +
+	"\treachable\x18\x01 \x01(\bR\treachable\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error2\xc5\x02\n" +
+	"\n" +
+	"RAGService\x12B\n" +
+	"\x05Query\x12\x1b.pgedge.rag.v1.QueryRequest\x1a\x1c.pgedge.rag.v1.QueryResponse\x12P\n" +
+	"\vQueryStream\x12\x1b.pgedge.rag.v1.QueryRequest\x1a\".pgedge.rag.v1.QueryStreamResponse0\x01\x12Z\n" +
+	"\rListPipelines\x12#.pgedge.rag.v1.ListPipelinesRequest\x1a$.pgedge.rag.v1.ListPipelinesResponse\x12E\n" +
+	"\x06Health\x12\x1c.pgedge.rag.v1.HealthRequest\x1a\x1d.pgedge.rag.v1.HealthResponseBAZ?github.com/pgEdge/pgedge-rag-server/internal/server/ragv1;ragv1b\x06proto3"
+
+var (
+	file_pgedge_rag_v1_rag_proto_rawDescOnce sync.Once
+	file_pgedge_rag_v1_rag_proto_rawDescData []byte
+)
+
+func file_pgedge_rag_v1_rag_proto_rawDescGZIP() []byte {
+	file_pgedge_rag_v1_rag_proto_rawDescOnce.Do(func() {
+		file_pgedge_rag_v1_rag_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pgedge_rag_v1_rag_proto_rawDesc), len(file_pgedge_rag_v1_rag_proto_rawDesc)))
+	})
+	return file_pgedge_rag_v1_rag_proto_rawDescData
+}
+
+var file_pgedge_rag_v1_rag_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
+var file_pgedge_rag_v1_rag_proto_goTypes = []any{
+	(*QueryRequest)(nil),          // 0: pgedge.rag.v1.QueryRequest
+	(*Message)(nil),               // 1: pgedge.rag.v1.Message
+	(*QueryResponse)(nil),         // 2: pgedge.rag.v1.QueryResponse
+	(*QueryStreamResponse)(nil),   // 3: pgedge.rag.v1.QueryStreamResponse
+	(*Sources)(nil),               // 4: pgedge.rag.v1.Sources
+	(*Done)(nil),                  // 5: pgedge.rag.v1.Done
+	(*Source)(nil),                // 6: pgedge.rag.v1.Source
+	(*Citation)(nil),              // 7: pgedge.rag.v1.Citation
+	(*DocumentProvenance)(nil),    // 8: pgedge.rag.v1.DocumentProvenance
+	(*AnswerSignature)(nil),       // 9: pgedge.rag.v1.AnswerSignature
+	(*TokenUsage)(nil),            // 10: pgedge.rag.v1.TokenUsage
+	(*StageUsage)(nil),            // 11: pgedge.rag.v1.StageUsage
+	(*Timings)(nil),               // 12: pgedge.rag.v1.Timings
+	(*ListPipelinesRequest)(nil),  // 13: pgedge.rag.v1.ListPipelinesRequest
+	(*ListPipelinesResponse)(nil), // 14: pgedge.rag.v1.ListPipelinesResponse
+	(*Pipeline)(nil),              // 15: pgedge.rag.v1.Pipeline
+	(*HealthRequest)(nil),         // 16: pgedge.rag.v1.HealthRequest
+	(*HealthResponse)(nil),        // 17: pgedge.rag.v1.HealthResponse
+	(*PipelineHealth)(nil),        // 18: pgedge.rag.v1.PipelineHealth
+	(*ProviderHealth)(nil),        // 19: pgedge.rag.v1.ProviderHealth
+	nil,                           // 20: pgedge.rag.v1.QueryRequest.LogitBiasEntry
+	(*structpb.Struct)(nil),       // 21: google.protobuf.Struct
+}
+var file_pgedge_rag_v1_rag_proto_depIdxs = []int32{
+	21, // 0: pgedge.rag.v1.QueryRequest.filter:type_name -> google.protobuf.Struct
+	1,  // 1: pgedge.rag.v1.QueryRequest.messages:type_name -> pgedge.rag.v1.Message
+	20, // 2: pgedge.rag.v1.QueryRequest.logit_bias:type_name -> pgedge.rag.v1.QueryRequest.LogitBiasEntry
+	6,  // 3: pgedge.rag.v1.QueryResponse.sources:type_name -> pgedge.rag.v1.Source
+	11, // 4: pgedge.rag.v1.QueryResponse.usage:type_name -> pgedge.rag.v1.StageUsage
+	7,  // 5: pgedge.rag.v1.QueryResponse.citations:type_name -> pgedge.rag.v1.Citation
+	12, // 6: pgedge.rag.v1.QueryResponse.timings:type_name -> pgedge.rag.v1.Timings
+	8,  // 7: pgedge.rag.v1.QueryResponse.provenance:type_name -> pgedge.rag.v1.DocumentProvenance
+	9,  // 8: pgedge.rag.v1.QueryResponse.signature:type_name -> pgedge.rag.v1.AnswerSignature
+	4,  // 9: pgedge.rag.v1.QueryStreamResponse.sources:type_name -> pgedge.rag.v1.Sources
+	5,  // 10: pgedge.rag.v1.QueryStreamResponse.done:type_name -> pgedge.rag.v1.Done
+	6,  // 11: pgedge.rag.v1.Sources.sources:type_name -> pgedge.rag.v1.Source
+	11, // 12: pgedge.rag.v1.Done.usage:type_name -> pgedge.rag.v1.StageUsage
+	7,  // 13: pgedge.rag.v1.Done.citations:type_name -> pgedge.rag.v1.Citation
+	12, // 14: pgedge.rag.v1.Done.timings:type_name -> pgedge.rag.v1.Timings
+	8,  // 15: pgedge.rag.v1.Done.provenance:type_name -> pgedge.rag.v1.DocumentProvenance
+	9,  // 16: pgedge.rag.v1.Done.signature:type_name -> pgedge.rag.v1.AnswerSignature
+	21, // 17: pgedge.rag.v1.Source.metadata:type_name -> google.protobuf.Struct
+	21, // 18: pgedge.rag.v1.Citation.metadata:type_name -> google.protobuf.Struct
+	10, // 19: pgedge.rag.v1.StageUsage.query_expansion:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 20: pgedge.rag.v1.StageUsage.history_summary:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 21: pgedge.rag.v1.StageUsage.context_summary:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 22: pgedge.rag.v1.StageUsage.embedding:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 23: pgedge.rag.v1.StageUsage.rerank:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 24: pgedge.rag.v1.StageUsage.completion:type_name -> pgedge.rag.v1.TokenUsage
+	10, // 25: pgedge.rag.v1.StageUsage.groundedness:type_name -> pgedge.rag.v1.TokenUsage
+	15, // 26: pgedge.rag.v1.ListPipelinesResponse.pipelines:type_name -> pgedge.rag.v1.Pipeline
+	18, // 27: pgedge.rag.v1.HealthResponse.pipelines:type_name -> pgedge.rag.v1.PipelineHealth
+	19, // 28: pgedge.rag.v1.PipelineHealth.embedding:type_name -> pgedge.rag.v1.ProviderHealth
+	19, // 29: pgedge.rag.v1.PipelineHealth.completion:type_name -> pgedge.rag.v1.ProviderHealth
+	0,  // 30: pgedge.rag.v1.RAGService.Query:input_type -> pgedge.rag.v1.QueryRequest
+	0,  // 31: pgedge.rag.v1.RAGService.QueryStream:input_type -> pgedge.rag.v1.QueryRequest
+	13, // 32: pgedge.rag.v1.RAGService.ListPipelines:input_type -> pgedge.rag.v1.ListPipelinesRequest
+	16, // 33: pgedge.rag.v1.RAGService.Health:input_type -> pgedge.rag.v1.HealthRequest
+	2,  // 34: pgedge.rag.v1.RAGService.Query:output_type -> pgedge.rag.v1.QueryResponse
+	3,  // 35: pgedge.rag.v1.RAGService.QueryStream:output_type -> pgedge.rag.v1.QueryStreamResponse
+	14, // 36: pgedge.rag.v1.RAGService.ListPipelines:output_type -> pgedge.rag.v1.ListPipelinesResponse
+	17, // 37: pgedge.rag.v1.RAGService.Health:output_type -> pgedge.rag.v1.HealthResponse
+	34, // [34:38] is the sub-list for method output_type
+	30, // [30:34] is the sub-list for method input_type
+	30, // [30:30] is the sub-list for extension type_name
+	30, // [30:30] is the sub-list for extension extendee
+	0,  // [0:30] is the sub-list for field type_name
+}
+
+func init() { file_pgedge_rag_v1_rag_proto_init() }
+func file_pgedge_rag_v1_rag_proto_init() {
+	if File_pgedge_rag_v1_rag_proto != nil {
+		return
+	}
+	file_pgedge_rag_v1_rag_proto_msgTypes[3].OneofWrappers = []any{
+		(*QueryStreamResponse_Sources)(nil),
+		(*QueryStreamResponse_Chunk)(nil),
+		(*QueryStreamResponse_Done)(nil),
+	}
+	file_pgedge_rag_v1_rag_proto_msgTypes[11].OneofWrappers = []any{}
+	file_pgedge_rag_v1_rag_proto_msgTypes[12].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pgedge_rag_v1_rag_proto_rawDesc), len(file_pgedge_rag_v1_rag_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   21,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_pgedge_rag_v1_rag_proto_goTypes,
+		DependencyIndexes: file_pgedge_rag_v1_rag_proto_depIdxs,
+		MessageInfos:      file_pgedge_rag_v1_rag_proto_msgTypes,
+	}.Build()
+	File_pgedge_rag_v1_rag_proto = out.File
+	file_pgedge_rag_v1_rag_proto_goTypes = nil
+	file_pgedge_rag_v1_rag_proto_depIdxs = nil
+}
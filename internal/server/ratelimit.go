@@ -0,0 +1,268 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// rateLimit is one set of limits applied to a caller, under a key that
+// names both the caller and the scope the limits cover: the whole
+// server or one pipeline.
+type rateLimit struct {
+	key    string
+	limits config.RateLimitConfig
+}
+
+// bucket is a token bucket holding up to capacity, a minute's
+// allowance, refilled continuously at capacity per minute.
+type bucket struct {
+	level    float64
+	capacity float64
+	updated  time.Time
+}
+
+// refill brings a bucket up to date. When a reload has changed its
+// capacity, what the caller had already spent stays spent.
+func (b *bucket) refill(now time.Time, capacity float64) {
+	if capacity != b.capacity {
+		b.level += capacity - b.capacity
+		b.capacity = capacity
+	}
+	b.level = min(b.capacity, b.level+now.Sub(b.updated).Minutes()*b.capacity)
+	b.updated = now
+}
+
+// rateLimiter keeps the token buckets of every caller. Buckets that have
+// refilled are dropped, since a new one starts full.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+	now     func() time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// bucket returns the bucket of a key, refilled to now, creating it full.
+// The caller must hold l.mu.
+func (l *rateLimiter) bucket(key string, capacity int, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{level: float64(capacity), capacity: float64(capacity), updated: now}
+		l.buckets[key] = b
+	}
+	b.refill(now, float64(capacity))
+	return b
+}
+
+// allow admits a request if every limit has a request, and a token, to
+// spend, taking a request from each. Otherwise it returns how long the
+// caller must wait before the limit that is furthest from allowing it
+// will.
+func (l *rateLimiter) allow(limits []rateLimit) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	var requests []*bucket
+	var wait time.Duration
+	for _, rl := range limits {
+		check := func(b *bucket) {
+			if b.level < 1 {
+				wait = max(wait, time.Duration((1-b.level)/b.capacity*float64(time.Minute)))
+			}
+		}
+		if n := rl.limits.RequestsPerMinute; n > 0 {
+			b := l.bucket(rl.key+"\x00requests", n, now)
+			check(b)
+			requests = append(requests, b)
+		}
+		if n := rl.limits.TokensPerMinute; n > 0 {
+			check(l.bucket(rl.key+"\x00tokens", n, now))
+		}
+	}
+	if wait > 0 {
+		return wait, false
+	}
+	for _, b := range requests {
+		b.level--
+	}
+	return 0, true
+}
+
+// charge spends the tokens a request used. It may leave a bucket below
+// empty, so later requests wait until the overdraft has refilled.
+func (l *rateLimiter) charge(limits []rateLimit, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	for _, rl := range limits {
+		if n := rl.limits.TokensPerMinute; n > 0 {
+			l.bucket(rl.key+"\x00tokens", n, now).level -= float64(tokens)
+		}
+	}
+}
+
+// sweep drops, at most once a minute, the buckets that have refilled.
+// The caller must hold l.mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	l.swept = now
+	for key, b := range l.buckets {
+		b.refill(now, b.capacity)
+		if b.level >= b.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type rateLimitsKey struct{}
+
+// rateLimited applies the server's and the pipeline's rate limits to a
+// pipeline route. A request over either is rejected with 429 and a
+// Retry-After header; an admitted one carries its limits in its
+// context, so chargeTokens can spend the tokens it uses.
+func (s *Server) rateLimited(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limits := s.rateLimits(r.Context(), r.PathValue("name"), r.RemoteAddr)
+		if len(limits) == 0 {
+			next(w, r)
+			return
+		}
+		if wait, ok := s.limiter.allow(limits); !ok {
+			seconds := int(math.Ceil(wait.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			s.respondError(w, http.StatusTooManyRequests, "RATE_LIMITED",
+				fmt.Sprintf("rate limit exceeded; retry in %d seconds", seconds))
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), rateLimitsKey{}, limits)))
+	}
+}
+
+// rateLimits returns the limits that apply to a request for the named
+// pipeline from remoteAddr: the server's and the pipeline's, for the
+// client's address and, when server.rate_limit.key is set, for the
+// caller's key as well, with the server's limits replaced by those
+// server.rate_limit.keys sets for it. The address is always limited,
+// since the key comes from request headers a client may vary to start
+// afresh. ctx carries the request's filter variables.
+func (s *Server) rateLimits(ctx context.Context, name, remoteAddr string) []rateLimit {
+	server := s.config.Server.RateLimit.RateLimitConfig
+	callers := []string{"addr:" + clientHost(remoteAddr)}
+	var limits []rateLimit
+	if server.Enabled() {
+		limits = append(limits, rateLimit{key: "server\x00" + callers[0], limits: server})
+	}
+	if caller, keyed := s.rateLimitCaller(ctx, remoteAddr); keyed {
+		if override, ok := s.config.Server.RateLimit.Keys[caller]; ok {
+			server = override
+		}
+		callers = append(callers, "key:"+caller)
+		if server.Enabled() {
+			limits = append(limits, rateLimit{key: "server\x00" + callers[1], limits: server})
+		}
+	}
+
+	p, err := s.pipelineManager().GetExecutor(name)
+	if err != nil {
+		return limits
+	}
+	if describer, ok := p.(pipeline.Describer); ok {
+		if rl := describer.Capabilities().RateLimit; rl.Enabled() {
+			for _, caller := range callers {
+				limits = append(limits, rateLimit{key: "pipeline:" + name + "\x00" + caller, limits: rl})
+			}
+		}
+	}
+	return limits
+}
+
+// rateLimitCaller returns who a request is from: the value of
+// server.rate_limit.key, reporting true, or, when no key is set or the
+// request does not set every variable it refers to, the client's
+// address.
+func (s *Server) rateLimitCaller(ctx context.Context, remoteAddr string) (string, bool) {
+	if key := s.config.Server.RateLimit.Key; key != "" {
+		vars := database.FilterVarsFrom(ctx)
+		complete := true
+		caller := config.ExpandFilterVariables(key, func(v config.FilterVariable) string {
+			value, ok := vars.Lookup(v)
+			complete = complete && ok
+			return value
+		})
+		if complete {
+			return caller, true
+		}
+	}
+	return clientHost(remoteAddr), false
+}
+
+// clientHost returns the host of a client's address, without its port.
+func clientHost(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// chargeTokens spends the tokens a request used against the token
+// limits of the caller it was admitted for.
+func (s *Server) chargeTokens(ctx context.Context, usage *pipeline.StageUsage) {
+	if limits, ok := ctx.Value(rateLimitsKey{}).([]rateLimit); ok {
+		s.limiter.charge(limits, usage.TotalTokens())
+	}
+}
+
+// respondBudgetExceeded writes a 429 BUDGET_EXCEEDED for a query refused
+// because its pipeline has spent its budget, with a Retry-After header
+// giving the seconds until the budget's period ends.
+func (s *Server) respondBudgetExceeded(w http.ResponseWriter, err error) {
+	var budgetErr *pipeline.BudgetExceededError
+	if errors.As(err, &budgetErr) {
+		seconds := max(1, int(math.Ceil(time.Until(budgetErr.Resets).Seconds())))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+	s.respondError(w, http.StatusTooManyRequests, "BUDGET_EXCEEDED", err.Error())
+}
+
+// providerBusyRetryAfter is the Retry-After, in seconds, of a query
+// refused because a provider is at its concurrency limit.
+const providerBusyRetryAfter = "5"
+
+// respondProviderBusy writes a 503 PROVIDER_BUSY for a query refused
+// because a provider it needed was at its provider_limits, with a
+// Retry-After header.
+func (s *Server) respondProviderBusy(w http.ResponseWriter, err error) {
+	w.Header().Set("Retry-After", providerBusyRetryAfter)
+	s.respondError(w, http.StatusServiceUnavailable, "PROVIDER_BUSY", err.Error())
+}
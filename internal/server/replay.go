@@ -0,0 +1,267 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// requestIDHeader carries the ID a query is recorded for replay under.
+const requestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds a client's X-Request-ID; longer IDs, or
+// ones with characters outside printable ASCII, are replaced by one
+// the server generates.
+const maxRequestIDLength = 128
+
+// ReplayRecord is a query recorded for replay: the request as the
+// pipeline received it, with a session's history resolved into its
+// messages, its answer, and the inputs that decided the answer.
+type ReplayRecord struct {
+	RequestID    string                 `json:"request_id"`
+	Pipeline     string                 `json:"pipeline"`
+	Time         time.Time              `json:"time"`
+	Request      pipeline.QueryRequest  `json:"request"`
+	Answer       string                 `json:"answer"`
+	Reproduction *pipeline.Reproduction `json:"reproduction"`
+
+	// header holds the request's headers, less its credentials, so a
+	// replay resolves the same filter variables.
+	header http.Header
+}
+
+// ReplayAnswer is an answer and the inputs that decided it.
+type ReplayAnswer struct {
+	Answer       string                 `json:"answer"`
+	Reproduction *pipeline.Reproduction `json:"reproduction"`
+}
+
+// ReplayResponse is the response of the POST
+// /admin/requests/{id}/replay endpoint: the recorded answer, the answer
+// to the same query now, and which of their inputs and outputs match.
+type ReplayResponse struct {
+	RequestID     string       `json:"request_id"`
+	Pipeline      string       `json:"pipeline"`
+	Original      ReplayAnswer `json:"original"`
+	Replay        ReplayAnswer `json:"replay"`
+	SameAnswer    bool         `json:"same_answer"`
+	SameDocuments bool         `json:"same_documents"`
+	SamePrompt    bool         `json:"same_prompt"`
+}
+
+// replayStore keeps the most recent queries' records, evicting the
+// oldest beyond its size. Records are local to the server instance. It
+// is safe for concurrent use.
+type replayStore struct {
+	size int
+
+	mu      sync.Mutex
+	records map[string]*list.Element // of *ReplayRecord, by request ID
+	order   *list.List               // most recent first
+}
+
+func newReplayStore(size int) *replayStore {
+	return &replayStore{
+		size:    size,
+		records: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// put records a query, replacing any earlier one with its request ID.
+func (s *replayStore) put(rec *ReplayRecord) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.records[rec.RequestID]; ok {
+		s.order.Remove(el)
+	}
+	s.records[rec.RequestID] = s.order.PushFront(rec)
+	for s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.records, oldest.Value.(*ReplayRecord).RequestID)
+	}
+}
+
+// get returns the record of a request ID.
+func (s *replayStore) get(id string) (*ReplayRecord, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.records[id]
+	if !ok {
+		return nil, false
+	}
+	return el.Value.(*ReplayRecord), true
+}
+
+// requestID returns the ID a query is recorded under: the client's
+// X-Request-ID when it is usable, or a random one.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); validRequestID(id) {
+		return id
+	}
+	return newRequestID()
+}
+
+// newRequestID returns a random request ID.
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID reports whether a client's request ID can be used.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// pendingReplay is a query being recorded for replay.
+type pendingReplay struct {
+	id     string
+	header http.Header
+	repro  *pipeline.Reproduction
+}
+
+// recordForReplay sets up a query to be recorded for replay, when
+// replay is enabled: it returns the request with a context recording
+// the query's inputs, and the pending record saveReplay completes. The
+// query's request ID is returned in the X-Request-ID response header.
+func (s *Server) recordForReplay(w http.ResponseWriter, r *http.Request) (*http.Request, *pendingReplay) {
+	if s.replay == nil {
+		return r, nil
+	}
+	pending := &pendingReplay{id: requestID(r), header: r.Header.Clone()}
+	for _, h := range []string{"Authorization", "Proxy-Authorization", "Cookie"} {
+		pending.header.Del(h)
+	}
+	w.Header().Set(requestIDHeader, pending.id)
+	ctx, repro := pipeline.ContextWithReproduction(r.Context())
+	pending.repro = repro
+	return r.WithContext(ctx), pending
+}
+
+// saveReplay records an answered query for replay; it does nothing
+// unless recordForReplay set the query up.
+func (s *Server) saveReplay(pending *pendingReplay, name string, req pipeline.QueryRequest, answer string) {
+	if pending == nil {
+		return
+	}
+	s.replay.put(&ReplayRecord{
+		RequestID:    pending.id,
+		Pipeline:     name,
+		Time:         time.Now().UTC(),
+		Request:      req,
+		Answer:       answer,
+		Reproduction: pending.repro,
+		header:       pending.header,
+	})
+}
+
+// handleGetRequest handles the GET /admin/requests/{id} endpoint, which
+// returns a query recorded for replay.
+func (s *Server) handleGetRequest(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok := s.replay.get(id)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "REQUEST_NOT_FOUND",
+			"request not found or no longer recorded: "+id)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, rec)
+}
+
+// handleReplay handles the POST /admin/requests/{id}/replay endpoint,
+// which answers a recorded query again with the same request, seed and
+// filter variables, and compares the answer with the recorded one. The
+// replay is not itself recorded.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok := s.replay.get(id)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "REQUEST_NOT_FOUND",
+			"request not found or no longer recorded: "+id)
+		return
+	}
+	p, err := s.pipelineManager().GetExecutor(rec.Pipeline)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrPipelineNotFound) {
+			s.respondError(w, http.StatusNotFound, "PIPELINE_NOT_FOUND",
+				"pipeline not found: "+rec.Pipeline)
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+
+	// The seed the pipeline chose, when the request set none, is taken
+	// from the recorded inputs.
+	req := rec.Request
+	req.Stream = false
+	if rec.Reproduction.Seed != nil {
+		req.Seed = rec.Reproduction.Seed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+	defer cancel()
+	ctx = database.WithFilterVars(ctx, s.filterVars(rec.header))
+	ctx, repro := pipeline.ContextWithReproduction(ctx)
+
+	resp, err := p.ExecuteWithOptions(ctx, req)
+	if err != nil {
+		switch {
+		case isRequestTimeout(ctx):
+			s.respondError(w, http.StatusGatewayTimeout, "REQUEST_TIMEOUT",
+				"request took too long to process")
+		case errors.Is(err, pipeline.ErrInvalidRequest):
+			s.respondInvalidRequest(w, err)
+		case errors.Is(err, pipeline.ErrBudgetExceeded):
+			s.respondBudgetExceeded(w, err)
+		case errors.Is(err, ragllm.ErrProviderBusy):
+			s.respondProviderBusy(w, err)
+		default:
+			s.logger.Error("replay failed", "pipeline", rec.Pipeline, "request_id", id, "error", err)
+			s.respondError(w, http.StatusInternalServerError, "EXECUTION_ERROR", err.Error())
+		}
+		return
+	}
+
+	// The same documents means the same versions of them, too.
+	sameDocuments := slices.Equal(repro.DocumentIDs, rec.Reproduction.DocumentIDs) &&
+		slices.Equal(repro.Provenance, rec.Reproduction.Provenance)
+	s.respondJSON(w, http.StatusOK, ReplayResponse{
+		RequestID:     id,
+		Pipeline:      rec.Pipeline,
+		Original:      ReplayAnswer{Answer: rec.Answer, Reproduction: rec.Reproduction},
+		Replay:        ReplayAnswer{Answer: resp.Answer, Reproduction: repro},
+		SameAnswer:    resp.Answer == rec.Answer,
+		SameDocuments: sameDocuments,
+		SamePrompt:    repro.PromptHash == rec.Reproduction.PromptHash,
+	})
+}
@@ -0,0 +1,288 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// streamBuffer records the events of one streaming answer so a client
+// that loses its connection can reconnect with Last-Event-ID and
+// receive the events it missed. Event IDs have the form
+// "<stream id>-<n>", where n is the event's 1-based position.
+type streamBuffer struct {
+	id       string
+	pipeline string
+
+	mu       sync.Mutex
+	events   []pipeline.StreamEvent
+	done     bool
+	finished time.Time
+	changed  chan struct{} // closed and replaced on every update
+}
+
+func newStreamBuffer(id, pipelineName string) *streamBuffer {
+	return &streamBuffer{
+		id:       id,
+		pipeline: pipelineName,
+		changed:  make(chan struct{}),
+	}
+}
+
+// append adds an event and wakes any followers.
+func (b *streamBuffer) append(event pipeline.StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event)
+	b.notifyLocked()
+}
+
+// finish marks the stream complete; no further events will be added.
+func (b *streamBuffer) finish(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	b.finished = now
+	b.notifyLocked()
+}
+
+func (b *streamBuffer) notifyLocked() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// since returns the events after the first n, whether the stream has
+// finished, and a channel that is closed on the next update.
+func (b *streamBuffer) since(n int) ([]pipeline.StreamEvent, bool, <-chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	if n > len(b.events) {
+		n = len(b.events)
+	}
+	return b.events[n:len(b.events):len(b.events)], b.done, b.changed
+}
+
+// sent returns the number of events recorded so far.
+func (b *streamBuffer) sent() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.events)
+}
+
+// expired reports whether the stream finished more than window ago.
+func (b *streamBuffer) expired(now time.Time, window time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.done && now.Sub(b.finished) > window
+}
+
+// eventID returns the SSE ID of the event at 1-based position n.
+func (b *streamBuffer) eventID(n int) string {
+	return b.id + "-" + strconv.Itoa(n)
+}
+
+// streamRegistry holds the buffers of in-progress streams, and of
+// finished ones until their resume window has passed.
+type streamRegistry struct {
+	window time.Duration
+	now    func() time.Time // overridden in tests
+
+	mu      sync.Mutex
+	streams map[string]*streamBuffer
+}
+
+func newStreamRegistry(window time.Duration) *streamRegistry {
+	return &streamRegistry{
+		window:  window,
+		now:     time.Now,
+		streams: make(map[string]*streamBuffer),
+	}
+}
+
+// start registers a new, empty stream for the named pipeline. Expired
+// streams are dropped at the same time, so memory stays bounded by the
+// streams started within the last window without a background
+// goroutine.
+func (r *streamRegistry) start(pipelineName string) (*streamBuffer, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate stream ID: %w", err)
+	}
+	buf := newStreamBuffer(hex.EncodeToString(b[:]), pipelineName)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	for id, s := range r.streams {
+		if s.expired(now, r.window) {
+			delete(r.streams, id)
+		}
+	}
+	r.streams[buf.id] = buf
+	return buf, nil
+}
+
+// finish marks buf complete, starting its resume window.
+func (r *streamRegistry) finish(buf *streamBuffer) {
+	buf.finish(r.now())
+}
+
+// get returns the stream with the given ID if it is still resumable.
+func (r *streamRegistry) get(id string) (*streamBuffer, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	buf, ok := r.streams[id]
+	if !ok {
+		return nil, false
+	}
+	if buf.expired(r.now(), r.window) {
+		delete(r.streams, id)
+		return nil, false
+	}
+	return buf, true
+}
+
+// parseLastEventID splits a Last-Event-ID header value into its stream
+// ID and the number of events the client has already received.
+func parseLastEventID(v string) (string, int, bool) {
+	i := strings.LastIndexByte(v, '-')
+	if i <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(v[i+1:])
+	if err != nil || n < 0 {
+		return "", 0, false
+	}
+	return v[:i], n, true
+}
+
+// handleResumableStream runs a streaming query whose events are
+// buffered for resumption and streams them to the client. Generation is
+// detached from the client connection, so the answer keeps being
+// produced while a client reconnects; it is still bounded by the
+// request timeout. Metrics, the session turn and the replay record are
+// recorded when generation finishes rather than when this client goes
+// away. The stream is resumed from the pipeline name asked, and
+// recorded as served, the pipeline that answers it.
+func (s *Server) handleResumableStream(w http.ResponseWriter, r *http.Request,
+	name, served string, p pipeline.QueryExecutor, req pipeline.QueryRequest,
+	pending *pendingReplay, audited *pendingAudit, start time.Time) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "STREAMING_ERROR",
+			"streaming not supported")
+		s.observeQuery(served, req.Query, requestStatusError, time.Since(start))
+		s.saveAudit(audited, requestStatusError, "", errStreamingUnsupported)
+		return
+	}
+
+	buf, err := s.streams.start(name)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		s.observeQuery(served, req.Query, requestStatusError, time.Since(start))
+		s.saveAudit(audited, requestStatusError, "", err)
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), s.requestTimeout)
+		defer cancel()
+
+		status, answer, err := s.runStream(ctx, p, req, buf.append)
+		s.streams.finish(buf)
+		s.observeQuery(served, req.Query, status, time.Since(start))
+		s.saveAudit(audited, status, answer, err)
+		if status == requestStatusOK {
+			s.recordSessionTurn(ctx, req, answer)
+			s.saveReplay(pending, served, req, answer)
+		}
+	}()
+
+	writeSSEHeaders(w, flusher)
+	s.followStream(w, r, flusher, buf, 0)
+}
+
+// resumingStreams serves a request that carries Last-Event-ID, from a
+// client reconnecting to an interrupted stream, with the events it
+// missed rather than a new answer, and passes any other request to
+// next. It wraps the query route outside its rate limits, since a
+// resumed stream does not start a new answer.
+func (s *Server) resumingStreams(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" && s.streams != nil {
+			s.handleResumeStream(w, r, r.PathValue("name"), lastEventID)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleResumeStream replays the events of a buffered stream after the
+// one named by lastEventID, then follows the stream until it finishes.
+func (s *Server) handleResumeStream(w http.ResponseWriter, r *http.Request,
+	name, lastEventID string) {
+	id, n, ok := parseLastEventID(lastEventID)
+	var buf *streamBuffer
+	if ok {
+		buf, ok = s.streams.get(id)
+	}
+	// An ID past the events recorded was never sent, and following on
+	// from it would number every later event wrongly.
+	if !ok || buf.pipeline != name || n > buf.sent() {
+		s.respondError(w, http.StatusNotFound, "STREAM_NOT_FOUND",
+			"stream not found or no longer resumable: "+lastEventID)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "STREAMING_ERROR",
+			"streaming not supported")
+		return
+	}
+
+	writeSSEHeaders(w, flusher)
+	s.followStream(w, r, flusher, buf, n)
+}
+
+// followStream sends buf's events from position from onwards, waiting
+// for new ones until the stream finishes or the client disconnects.
+func (s *Server) followStream(w http.ResponseWriter, r *http.Request,
+	flusher http.Flusher, buf *streamBuffer, from int) {
+	for {
+		events, done, changed := buf.since(from)
+		for _, event := range events {
+			from++
+			s.sendSSEWithID(w, flusher, buf.eventID(from), event)
+		}
+		if done {
+			return
+		}
+		select {
+		case <-changed:
+		case <-r.Context().Done():
+			s.logger.Debug("client disconnected during resumable stream", "stream", buf.id)
+			return
+		}
+	}
+}
@@ -11,11 +11,51 @@ package server
 
 // setupRoutes configures all HTTP routes.
 func (s *Server) setupRoutes() {
-	// API v1 routes
-	s.mux.HandleFunc("GET /v1/openapi.json", s.handleOpenAPI)
-	s.mux.HandleFunc("GET /v1/live", s.handleLive)
-	s.mux.HandleFunc("GET /v1/health", s.handleHealth)
-	s.mux.HandleFunc("GET /v1/pipelines", s.handleListPipelines)
-	s.mux.HandleFunc("POST /v1/pipelines/{name}", s.handlePipeline)
-	s.mux.HandleFunc("GET /v1/stats", s.handleStats)
+	for _, v := range apiVersions {
+		s.mountVersion(v)
+	}
+
+	// Metrics share the API listener unless a dedicated port is set.
+	if s.metricsEnabled() && s.config.Server.Metrics.Port == 0 {
+		s.mux.Handle("GET "+s.config.Server.Metrics.Path, s.metrics.Handler())
+	}
+}
+
+// v1Routes registers the routes of API version 1.
+func (s *Server) v1Routes(r *versionRouter) {
+	r.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+	r.HandleFunc("GET /live", s.handleLive)
+	r.HandleFunc("GET /health", s.handleHealth)
+	r.HandleFunc("GET /ready", s.handleReady)
+	r.HandleFunc("GET /pipelines", s.handleListPipelines)
+	r.HandleFunc("GET /pipelines/{name}", s.handlePipelineDetail)
+	r.HandleFunc("POST /pipelines/{name}", s.resumingStreams(s.rateLimited(s.handlePipeline)))
+	r.HandleFunc("POST /pipelines/{name}/retrieve", s.rateLimited(s.handleRetrieve))
+	r.HandleFunc("POST /pipelines/{name}/search", s.rateLimited(s.handleSearch))
+	r.HandleFunc("POST /pipelines/{name}/embed", s.rateLimited(s.handleEmbed))
+	r.HandleFunc("POST /pipelines/{name}/estimate", s.rateLimited(s.handleEstimate))
+	r.HandleFunc("POST /pipelines/{name}/feedback", s.rateLimited(s.handleFeedback))
+	r.HandleFunc("GET /pipelines/{name}/openapi.json", s.handlePipelineOpenAPI)
+	r.HandleFunc("GET /stats", s.handleStats)
+
+	if s.signer != nil {
+		r.HandleFunc("GET /signing-key", s.handleSigningKey)
+	}
+
+	if s.jobs != nil {
+		r.HandleFunc("POST /pipelines/{name}/documents", s.rateLimited(s.handleUploadDocuments))
+		r.HandleFunc("GET /jobs/{id}", s.handleGetJob)
+	}
+
+	if s.sessionsEnabled() {
+		r.HandleFunc("POST /sessions", s.rateLimited(s.handleCreateSession))
+		r.HandleFunc("GET /sessions/{id}", s.handleGetSession)
+		r.HandleFunc("DELETE /sessions/{id}", s.handleDeleteSession)
+	}
+
+	// Admin endpoints share the API listener unless a dedicated port is
+	// set, in which case they are served only there.
+	if s.adminEnabled() && s.config.Server.Admin.Port == 0 {
+		s.adminRoutes(r)
+	}
 }
@@ -20,8 +20,14 @@ import (
 	"sync"
 	"time"
 
+	"google.golang.org/grpc"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/audit"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/jobs"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
 	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/session"
 )
 
 // PipelineManager defines the interface for pipeline management.
@@ -35,11 +41,23 @@ type PipelineManager interface {
 	// returns a controlled result. See issue #37.
 	GetExecutor(name string) (pipeline.QueryExecutor, error)
 
+	// Route returns the name of the pipeline that answers a query asked
+	// of the named pipeline, and whether the pipeline runs an
+	// experiment, which may route the query to one of its variants.
+	Route(name, key string) (string, bool)
+
 	Stats() []pipeline.Usage
 	Health(ctx context.Context) []pipeline.PipelineHealth
+	Ready(ctx context.Context, providers bool) []pipeline.PipelineReadiness
 	Close() error
 }
 
+// QueryRecorder is told of each query asked of a pipeline over the API,
+// and whether it failed, such as by the usage reports.
+type QueryRecorder interface {
+	RecordQuery(pipeline, query string, failed bool)
+}
+
 // DefaultRequestTimeout bounds how long a single pipeline query may run
 // (embedding + search + LLM call) before the server gives up and returns
 // a structured JSON timeout error. Kept comfortably below WriteTimeout so
@@ -55,14 +73,78 @@ type Server struct {
 	config         *config.Config
 	logger         *slog.Logger
 	server         *http.Server
-	mux            *http.ServeMux
+	mux            *http.ServeMux            // root mux; routes each API version to its own
+	versions       map[string]*http.ServeMux // API version muxes by name, e.g. "v1"
 	pipelinesMu    sync.RWMutex
 	pipelines      PipelineManager // guarded by pipelinesMu; use pipelineManager()/SwapPipelineManager
 	requestTimeout time.Duration
+	metrics        *metrics.Registry
+	metricsServer  *http.Server // dedicated metrics listener, if configured
+	adminServer    *http.Server // dedicated admin listener, if configured
+	grpcServer     *grpc.Server // gRPC listener, if enabled
+	reload         func() error // reloads the configuration; nil if unavailable
+	sessions       session.Store
+	streams        *streamRegistry // nil unless stream resumption is enabled
+	jobs           *jobs.Queue     // nil unless background jobs are available
+	queries        QueryRecorder   // nil unless usage reports are enabled
+	replay         *replayStore    // nil unless replay is enabled
+	signer         *Signer         // nil unless answers are signed
+	audit          *audit.Log      // nil unless the audit log is enabled
+	limiter        *rateLimiter
+
+	// draining is closed when in-flight streams must end because the
+	// server is shutting down; see Shutdown.
+	draining  chan struct{}
+	drainOnce sync.Once
+}
+
+// Option customises server construction.
+type Option func(*Server)
+
+// WithMetrics sets the registry served on the metrics endpoint and
+// updated with per-request counters. Without it (or with a nil
+// registry) the metrics endpoint is not registered even when enabled in
+// configuration.
+func WithMetrics(reg *metrics.Registry) Option {
+	return func(s *Server) { s.metrics = reg }
+}
+
+// WithJobs sets the queue that runs background jobs, such as ingesting
+// uploaded documents, and backs the /v1/jobs endpoint. Without it (or
+// with a nil queue) document uploads are unavailable.
+func WithJobs(q *jobs.Queue) Option {
+	return func(s *Server) { s.jobs = q }
+}
+
+// WithQueryRecorder sets the recorder told of each query, in addition
+// to the metrics.
+func WithQueryRecorder(r QueryRecorder) Option {
+	return func(s *Server) { s.queries = r }
+}
+
+// WithSessions sets the store backing the /v1/sessions endpoints and
+// the session_id query parameter. Without it (or with a nil store)
+// sessions are unavailable even when enabled in configuration.
+func WithSessions(store session.Store) Option {
+	return func(s *Server) { s.sessions = store }
+}
+
+// WithSigner sets the signer answers are signed with, and whose public
+// key the /v1/signing-key endpoint serves. Without it (or with a nil
+// signer) answers are not signed.
+func WithSigner(signer *Signer) Option {
+	return func(s *Server) { s.signer = signer }
+}
+
+// WithAudit sets the log every query asked of a pipeline over the API
+// is recorded in. Without it (or with a nil log) queries are not
+// audited.
+func WithAudit(log *audit.Log) Option {
+	return func(s *Server) { s.audit = log }
 }
 
 // New creates a new HTTP server.
-func New(cfg *config.Config, pm PipelineManager, logger *slog.Logger) *Server {
+func New(cfg *config.Config, pm PipelineManager, logger *slog.Logger, opts ...Option) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -72,7 +154,19 @@ func New(cfg *config.Config, pm PipelineManager, logger *slog.Logger) *Server {
 		pipelines:      pm,
 		logger:         logger,
 		mux:            http.NewServeMux(),
+		versions:       make(map[string]*http.ServeMux),
 		requestTimeout: DefaultRequestTimeout,
+		limiter:        newRateLimiter(),
+		draining:       make(chan struct{}),
+	}
+	if cfg != nil && cfg.Server.StreamResume.Enabled {
+		s.streams = newStreamRegistry(cfg.Server.StreamResume.Window.Std())
+	}
+	if cfg != nil && cfg.Server.Admin.Enabled && cfg.Server.Replay.Enabled {
+		s.replay = newReplayStore(cfg.Server.Replay.MaxRequests)
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
 
 	// Set up routes
@@ -101,6 +195,35 @@ func (s *Server) SwapPipelineManager(pm PipelineManager) PipelineManager {
 	return old
 }
 
+// GetExecutor retrieves a pipeline of the currently active
+// PipelineManager by name, so components outside the HTTP API, such as
+// the chat integrations, follow configuration reloads too.
+func (s *Server) GetExecutor(name string) (pipeline.QueryExecutor, error) {
+	pm := s.pipelineManager()
+	if pm == nil {
+		return nil, pipeline.ErrPipelineNotFound
+	}
+	return pm.GetExecutor(name)
+}
+
+// Stats returns the token usage and cost of the pipelines of the
+// currently active PipelineManager, so the usage reports follow
+// configuration reloads.
+func (s *Server) Stats() []pipeline.Usage {
+	pm := s.pipelineManager()
+	if pm == nil {
+		return nil
+	}
+	return pm.Stats()
+}
+
+// Handle registers an unversioned handler on the API listener, such as
+// a chat integration's webhook. It must be called before
+// ListenAndServe.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
 // ListenAndServe starts the HTTP server.
 func (s *Server) ListenAndServe() error {
 	addr := fmt.Sprintf("%s:%d", s.config.Server.ListenAddress, s.config.Server.Port)
@@ -111,11 +234,24 @@ func (s *Server) ListenAndServe() error {
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 60 * time.Second,
 		IdleTimeout:  120 * time.Second,
+		Protocols:    s.protocols(),
 	}
 
 	s.logger.Info("starting server",
 		"address", addr,
-		"tls", s.config.Server.TLS.Enabled)
+		"tls", s.config.Server.TLS.Enabled,
+		"http2", s.config.Server.HTTP2.Enabled,
+		"h2c", s.config.Server.HTTP2.H2C)
+
+	if err := s.startMetricsListener(); err != nil {
+		return err
+	}
+	if err := s.startAdminListener(); err != nil {
+		return err
+	}
+	if err := s.startGRPCListener(); err != nil {
+		return err
+	}
 
 	if s.config.Server.TLS.Enabled {
 		return s.serveTLS()
@@ -129,6 +265,61 @@ func (s *Server) ListenAndServe() error {
 	return s.server.Serve(listener)
 }
 
+// protocols returns the HTTP protocols the API listener accepts.
+// HTTP/2 lets many concurrent SSE streams share one connection instead
+// of each holding its own.
+func (s *Server) protocols() *http.Protocols {
+	h2 := s.config.Server.HTTP2
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetHTTP2(h2.Enabled)
+	p.SetUnencryptedHTTP2(h2.Enabled && h2.H2C)
+	return &p
+}
+
+// sessionsEnabled reports whether conversation sessions are available.
+func (s *Server) sessionsEnabled() bool {
+	return s.sessions != nil && s.config.Sessions.Enabled
+}
+
+// metricsEnabled reports whether the metrics endpoint should be served.
+func (s *Server) metricsEnabled() bool {
+	return s.metrics != nil && s.config.Server.Metrics.Enabled
+}
+
+// startMetricsListener starts the dedicated metrics listener when one is
+// configured (server.metrics.port is non-zero). It binds synchronously
+// so a port conflict fails startup rather than being logged and
+// ignored, then serves in the background until Shutdown.
+func (s *Server) startMetricsListener() error {
+	mc := s.config.Server.Metrics
+	if !s.metricsEnabled() || mc.Port == 0 {
+		return nil
+	}
+
+	addr := fmt.Sprintf("%s:%d", mc.ListenAddress, mc.Port)
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET "+mc.Path, s.metrics.Handler())
+	s.metricsServer = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	s.logger.Info("starting metrics listener", "address", addr, "path", mc.Path)
+	go func() {
+		if err := s.metricsServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			s.logger.Error("metrics listener failed", "error", err)
+		}
+	}()
+	return nil
+}
+
 // serveTLS starts the server with TLS.
 func (s *Server) serveTLS() error {
 	tlsCfg := &tls.Config{
@@ -142,10 +333,51 @@ func (s *Server) serveTLS() error {
 	)
 }
 
-// Shutdown gracefully shuts down the server.
+// streamDrainMargin is how long before the shutdown deadline streams
+// still running are told the server is shutting down, leaving them time
+// to send that event and end before their connections are closed.
+const streamDrainMargin = 2 * time.Second
+
+// Shutdown gracefully shuts down the server: it stops accepting
+// connections and waits until ctx is done for in-flight requests to
+// finish. Streams are given as long as the deadline allows; any still
+// running shortly before it are sent a final "server_shutting_down"
+// event and ended, so their clients learn why the answer stopped
+// rather than seeing the connection drop.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.logger.Info("shutting down server")
 
+	drainCtx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		margin := min(streamDrainMargin, time.Until(deadline)/4)
+		var cancel context.CancelFunc
+		drainCtx, cancel = context.WithDeadline(ctx, deadline.Add(-margin))
+		defer cancel()
+	}
+	stop := context.AfterFunc(drainCtx, s.drainStreams)
+	defer stop()
+
+	// gRPC calls are given the same time as HTTP requests to finish.
+	grpcStopped := make(chan struct{})
+	go func() {
+		defer close(grpcStopped)
+		if s.grpcServer != nil {
+			s.stopGRPC(ctx)
+		}
+	}()
+	defer func() { <-grpcStopped }()
+
+	if s.metricsServer != nil {
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			s.logger.Warn("failed to shut down metrics listener", "error", err)
+		}
+	}
+	if s.adminServer != nil {
+		if err := s.adminServer.Shutdown(ctx); err != nil {
+			s.logger.Warn("failed to shut down admin listener", "error", err)
+		}
+	}
+
 	if s.server != nil {
 		return s.server.Shutdown(ctx)
 	}
@@ -153,6 +385,15 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// drainStreams ends every in-flight stream with a final
+// "server_shutting_down" event.
+func (s *Server) drainStreams() {
+	s.drainOnce.Do(func() {
+		s.logger.Info("ending in-flight streams for shutdown")
+		close(s.draining)
+	})
+}
+
 // Addr returns the server's address. Returns empty string if not started.
 func (s *Server) Addr() string {
 	if s.server != nil {
@@ -10,19 +10,51 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	llmlib "github.com/pgEdge/pgedge-go-llm-lib/llm"
-
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/audit"
 	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+	"github.com/pgEdge/pgedge-rag-server/internal/ingest"
+	"github.com/pgEdge/pgedge-rag-server/internal/jobs"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+	"github.com/pgEdge/pgedge-rag-server/internal/metrics"
 	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+	"github.com/pgEdge/pgedge-rag-server/internal/server/ragv1"
+	"github.com/pgEdge/pgedge-rag-server/internal/session"
 )
 
 // mockPipelineManager implements PipelineManager for testing.
@@ -43,6 +75,12 @@ type mockPipelineInfo struct {
 	// health, when non-nil, is returned verbatim by Health for this
 	// pipeline. Nil means "reachable", matching the default healthy case.
 	health *pipeline.PipelineHealth
+	// dbErr, when non-empty, makes Ready report this pipeline's
+	// database unreachable with it.
+	dbErr string
+	// variant, when non-empty, is the pipeline Route sends this
+	// pipeline's queries to, as though it ran an experiment.
+	variant string
 }
 
 func newMockPipelineManager() *mockPipelineManager {
@@ -80,6 +118,13 @@ func (m *mockPipelineManager) GetExecutor(name string) (pipeline.QueryExecutor,
 	return info.executor, nil
 }
 
+func (m *mockPipelineManager) Route(name, key string) (string, bool) {
+	if info, ok := m.pipelines[name]; ok && info.variant != "" {
+		return info.variant, true
+	}
+	return name, false
+}
+
 func (m *mockPipelineManager) Stats() []pipeline.Usage {
 	stats := make([]pipeline.Usage, 0, len(m.pipelines))
 	for _, p := range m.pipelines {
@@ -109,6 +154,23 @@ func (m *mockPipelineManager) Health(ctx context.Context) []pipeline.PipelineHea
 	return results
 }
 
+func (m *mockPipelineManager) Ready(ctx context.Context, providers bool) []pipeline.PipelineReadiness {
+	results := make([]pipeline.PipelineReadiness, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		r := pipeline.PipelineReadiness{
+			Name:     p.name,
+			Ready:    p.dbErr == "",
+			Database: pipeline.ProviderHealth{Reachable: p.dbErr == "", Error: p.dbErr},
+		}
+		if providers {
+			r.Embedding = &pipeline.ProviderHealth{Reachable: true}
+			r.Completion = &pipeline.ProviderHealth{Reachable: true}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
 func (m *mockPipelineManager) Close() error {
 	return nil
 }
@@ -124,6 +186,35 @@ type mockQueryExecutor struct {
 	ExecuteStreamWithOptionsFunc func(
 		ctx context.Context, req pipeline.QueryRequest,
 	) (<-chan pipeline.StreamChunk, <-chan error)
+	ExplainFunc func(
+		ctx context.Context, req pipeline.ExplainRequest,
+	) (*pipeline.Explanation, error)
+	RetrieveFunc func(
+		ctx context.Context, req pipeline.RetrieveRequest,
+	) (*pipeline.RetrieveResponse, error)
+	SearchFunc func(
+		ctx context.Context, req pipeline.SearchRequest,
+	) (*pipeline.SearchResponse, error)
+	EmbedFunc func(
+		ctx context.Context, req pipeline.EmbedRequest,
+	) (*pipeline.EmbedResponse, error)
+	EstimateFunc func(
+		ctx context.Context, req pipeline.QueryRequest,
+	) (*pipeline.Estimate, error)
+	CapabilitiesFunc func() pipeline.Capabilities
+	DetailFunc       func() pipeline.Detail
+	IngestFunc       func(
+		ctx context.Context, doc *ingest.Document, progress func(done, total int),
+	) (int, error)
+	FeedbackFunc    func(req pipeline.FeedbackRequest) error
+	EvalDatasetFunc func() []pipeline.EvalCase
+	EvaluateFunc    func(
+		ctx context.Context, cases []pipeline.EvalCase, opts pipeline.EvalOptions,
+	) (*pipeline.EvalReport, error)
+	TuneFunc func(
+		ctx context.Context, cases []pipeline.EvalCase, variants []pipeline.TuningVariant, k int,
+		progress func(done, total int),
+	) (*pipeline.TuningReport, error)
 }
 
 func (m *mockQueryExecutor) ExecuteWithOptions(
@@ -148,6 +239,107 @@ func (m *mockQueryExecutor) ExecuteStreamWithOptions(
 	return chunkChan, errChan
 }
 
+func (m *mockQueryExecutor) Explain(
+	ctx context.Context, req pipeline.ExplainRequest,
+) (*pipeline.Explanation, error) {
+	if m.ExplainFunc != nil {
+		return m.ExplainFunc(ctx, req)
+	}
+	return &pipeline.Explanation{Query: req.Query, DocumentID: req.DocumentID}, nil
+}
+
+func (m *mockQueryExecutor) Retrieve(
+	ctx context.Context, req pipeline.RetrieveRequest,
+) (*pipeline.RetrieveResponse, error) {
+	if m.RetrieveFunc != nil {
+		return m.RetrieveFunc(ctx, req)
+	}
+	return &pipeline.RetrieveResponse{Documents: []pipeline.RetrievedDocument{}}, nil
+}
+
+func (m *mockQueryExecutor) Search(
+	ctx context.Context, req pipeline.SearchRequest,
+) (*pipeline.SearchResponse, error) {
+	if m.SearchFunc != nil {
+		return m.SearchFunc(ctx, req)
+	}
+	return &pipeline.SearchResponse{Results: []pipeline.Source{}, Usage: &pipeline.StageUsage{}}, nil
+}
+
+func (m *mockQueryExecutor) Embed(
+	ctx context.Context, req pipeline.EmbedRequest,
+) (*pipeline.EmbedResponse, error) {
+	if m.EmbedFunc != nil {
+		return m.EmbedFunc(ctx, req)
+	}
+	return &pipeline.EmbedResponse{Embeddings: [][]float32{}, Usage: &pipeline.StageUsage{}}, nil
+}
+
+func (m *mockQueryExecutor) Estimate(
+	ctx context.Context, req pipeline.QueryRequest,
+) (*pipeline.Estimate, error) {
+	if m.EstimateFunc != nil {
+		return m.EstimateFunc(ctx, req)
+	}
+	return &pipeline.Estimate{Usage: &pipeline.StageUsage{}}, nil
+}
+
+func (m *mockQueryExecutor) Capabilities() pipeline.Capabilities {
+	if m.CapabilitiesFunc != nil {
+		return m.CapabilitiesFunc()
+	}
+	return pipeline.Capabilities{Name: "test-pipeline"}
+}
+
+func (m *mockQueryExecutor) Detail() pipeline.Detail {
+	if m.DetailFunc != nil {
+		return m.DetailFunc()
+	}
+	return pipeline.Detail{Name: "test-pipeline", Tables: []pipeline.TableDetail{}}
+}
+
+func (m *mockQueryExecutor) Ingest(
+	ctx context.Context, doc *ingest.Document, progress func(done, total int),
+) (int, error) {
+	if m.IngestFunc != nil {
+		return m.IngestFunc(ctx, doc, progress)
+	}
+	return 0, nil
+}
+
+func (m *mockQueryExecutor) Feedback(_ context.Context, req pipeline.FeedbackRequest) error {
+	if m.FeedbackFunc != nil {
+		return m.FeedbackFunc(req)
+	}
+	return nil
+}
+
+func (m *mockQueryExecutor) EvalDataset() []pipeline.EvalCase {
+	if m.EvalDatasetFunc != nil {
+		return m.EvalDatasetFunc()
+	}
+	return nil
+}
+
+func (m *mockQueryExecutor) Evaluate(
+	ctx context.Context, cases []pipeline.EvalCase, opts pipeline.EvalOptions,
+) (*pipeline.EvalReport, error) {
+	if m.EvaluateFunc != nil {
+		return m.EvaluateFunc(ctx, cases, opts)
+	}
+	return &pipeline.EvalReport{K: opts.K, Cases: len(cases)}, nil
+}
+
+func (m *mockQueryExecutor) Tune(
+	ctx context.Context, cases []pipeline.EvalCase, variants []pipeline.TuningVariant, k int,
+	progress func(done, total int),
+) (*pipeline.TuningReport, error) {
+	if m.TuneFunc != nil {
+		return m.TuneFunc(ctx, cases, variants, k, progress)
+	}
+	return &pipeline.TuningReport{K: k, Cases: len(cases)}, nil
+}
+
 func testConfig() *config.Config {
 	return &config.Config{
 		Server: config.ServerConfig{
@@ -265,6 +457,50 @@ func TestHealthEndpoint_DegradedWhenProviderUnreachable(t *testing.T) {
 	}
 }
 
+func TestReadyEndpoint(t *testing.T) {
+	pm := newMockPipelineManager()
+	srv := New(testConfig(), pm, nil)
+
+	get := func(path string) (int, ReadyResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp ReadyResponse
+		if w.Code != http.StatusBadRequest {
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+		}
+		return w.Code, resp
+	}
+
+	code, resp := get("/v1/ready")
+	if code != http.StatusOK || resp.Status != "ready" || len(resp.Pipelines) != 1 {
+		t.Fatalf("expected ready, got %d %+v", code, resp)
+	}
+	if got := resp.Pipelines[0]; !got.Database.Reachable || got.Embedding != nil || got.Completion != nil {
+		t.Errorf("expected only the database checked, got %+v", got)
+	}
+
+	code, resp = get("/v1/ready?providers=true")
+	if got := resp.Pipelines[0]; code != http.StatusOK || got.Embedding == nil || got.Completion == nil {
+		t.Errorf("expected the providers checked, got %d %+v", code, got)
+	}
+
+	if code, _ = get("/v1/ready?providers=maybe"); code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an invalid providers value, got %d", http.StatusBadRequest, code)
+	}
+
+	pm.pipelines["test-pipeline"].dbErr = "connection refused"
+	code, resp = get("/v1/ready")
+	if code != http.StatusServiceUnavailable || resp.Status != "not_ready" {
+		t.Fatalf("expected not_ready with status %d, got %d %+v", http.StatusServiceUnavailable, code, resp)
+	}
+	if got := resp.Pipelines[0]; got.Ready || got.Database.Error != "connection refused" {
+		t.Errorf("expected the database error reported, got %+v", got)
+	}
+}
+
 func TestHealthEndpoint_MethodNotAllowed(t *testing.T) {
 	srv := testServer()
 
@@ -466,370 +702,3124 @@ func TestPipelineEndpoint_NonStreamingTimeout(t *testing.T) {
 	}
 }
 
-// TestPipelineEndpoint_StreamingTimeout is a regression test for issue
-// #37: it drives the streaming timeout path added in #33 through a
-// fake QueryExecutor whose stream channels never receive anything,
-// forcing the handler's ctx.Done() case to fire once the request
-// timeout elapses. Confirms the client gets an SSE "error" event
-// followed by "done", with no chunks in between — previously only
-// verified by hand against a live, artificially slow backend.
-func TestPipelineEndpoint_StreamingTimeout(t *testing.T) {
+func TestPipelineEndpoint_StageTimeout(t *testing.T) {
+	stageErr := &pipeline.StageTimeoutError{Stage: pipeline.TimeoutStageCompletion, Timeout: 30 * time.Second}
 	pm := newMockPipelineManager()
 	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return nil, fmt.Errorf("failed to generate completion: %w", stageErr)
+		},
 		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
-			// Channels that never receive anything: the handler's
-			// ctx.Done() case is the only way this select resolves.
-			return make(chan pipeline.StreamChunk), make(chan error, 1)
+			chunkChan := make(chan pipeline.StreamChunk)
+			errChan := make(chan error, 1)
+			errChan <- stageErr
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
 		},
 	}
 	srv := New(testConfig(), pm, nil)
-	srv.requestTimeout = 50 * time.Millisecond
 
-	body := bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	body := bytes.NewBufferString(`{"query": "test query"}`)
 	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
 	srv.mux.ServeHTTP(w, req)
 
-	got := w.Body.String()
-	errIdx := strings.Index(got, `"type":"error"`)
-	doneIdx := strings.Index(got, `"type":"done"`)
-	if errIdx < 0 {
-		t.Fatalf("expected an SSE error event, got body: %s", got)
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("expected status %d, got %d", http.StatusGatewayTimeout, w.Code)
 	}
-	if doneIdx < 0 {
-		t.Fatalf("expected an SSE done event, got body: %s", got)
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
 	}
-	if errIdx > doneIdx {
-		t.Errorf("expected the error event before the done event, got body: %s", got)
+	if resp.Error.Code != "STAGE_TIMEOUT" || resp.Error.Stage != "completion" ||
+		resp.Error.Message != "completion stage timed out after 30s" {
+		t.Errorf("unexpected error: %+v", resp.Error)
 	}
-	if !strings.Contains(got, "request took too long to process") {
-		t.Errorf("expected the timeout message in the error event, got body: %s", got)
+
+	body = bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	req = httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	want := `{"type":"error","error":"completion stage timed out after 30s","stage":"completion"}`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("expected error event %s, got body: %s", want, w.Body.String())
 	}
 }
 
-func TestSSEFormat(t *testing.T) {
-	// Test that SSE events are properly formatted
-	event := pipeline.StreamEvent{
-		Type:    "chunk",
-		Content: "Hello",
+// TestExplainEndpoint verifies the explain route only exists among the
+// admin endpoints when enabled, requires the admin token, and passes
+// the request through to the pipeline.
+func TestExplainEndpoint(t *testing.T) {
+	var got pipeline.ExplainRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExplainFunc: func(ctx context.Context, req pipeline.ExplainRequest) (*pipeline.Explanation, error) {
+			got = req
+			return &pipeline.Explanation{Query: req.Query, DocumentID: req.DocumentID, Retrieved: true, Rank: 3}, nil
+		},
 	}
-
-	data, err := json.Marshal(event)
-	if err != nil {
-		t.Fatalf("failed to marshal event: %v", err)
+	tokenFile := filepath.Join(t.TempDir(), "admin-token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
 	}
-
-	sseData := "data: " + string(data) + "\n\n"
-
-	if !strings.HasPrefix(sseData, "data: ") {
-		t.Error("SSE data should start with 'data: '")
+	explainAs := func(srv *Server, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
-
-	if !strings.HasSuffix(sseData, "\n\n") {
-		t.Error("SSE data should end with '\\n\\n'")
+	explain := func(srv *Server, body string) *httptest.ResponseRecorder {
+		return explainAs(srv, "/v1/admin/pipelines/test-pipeline/explain", "s3cret", body)
 	}
-}
 
-func TestOpenAPIEndpoint(t *testing.T) {
-	srv := testServer()
+	for _, tt := range []struct {
+		name           string
+		admin, explain bool
+	}{
+		{"explain disabled", true, false},
+		{"admin disabled", false, true},
+	} {
+		cfg := testConfig()
+		cfg.Server.Admin = config.AdminConfig{Enabled: tt.admin, TokenFile: tokenFile}
+		cfg.Server.Explain.Enabled = tt.explain
+		if w := explain(New(cfg, pm, nil), `{"query": "q", "document_id": "42"}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", tt.name, http.StatusNotFound, w.Code)
+		}
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
-	w := httptest.NewRecorder()
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true, TokenFile: tokenFile}
+	cfg.Server.Explain.Enabled = true
+	srv := New(cfg, pm, nil)
 
-	srv.mux.ServeHTTP(w, req)
+	body := `{"query": "q", "document_id": "42", "top_n": 5}`
+	if w := explainAs(srv, "/v1/pipelines/test-pipeline/explain", "s3cret", body); w.Code != http.StatusNotFound {
+		t.Errorf("expected no explain route outside the admin endpoints, got %d", w.Code)
+	}
+	if w := explainAs(srv, "/v1/admin/pipelines/test-pipeline/explain", "", body); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d without the admin token, got %d", http.StatusUnauthorized, w.Code)
+	}
 
+	w := explain(srv, body)
 	if w.Code != http.StatusOK {
-		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
 	}
-
-	// Check Content-Type
-	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
-		t.Errorf("expected Content-Type 'application/json', got '%s'", ct)
+	var resp pipeline.Explanation
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Retrieved || resp.Rank != 3 || resp.DocumentID != "42" {
+		t.Errorf("unexpected explanation: %+v", resp)
+	}
+	if got.Query != "q" || got.DocumentID != "42" || got.TopN != 5 {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
 	}
 
-	// Check RFC 8631 Link header
-	link := w.Header().Get("Link")
-	if link == "" {
-		t.Error("expected Link header for RFC 8631 API discovery")
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExplainFunc: func(ctx context.Context, req pipeline.ExplainRequest) (*pipeline.Explanation, error) {
+			return nil, fmt.Errorf("%w: document_id is required", pipeline.ErrInvalidRequest)
+		},
 	}
-	if !strings.Contains(link, `rel="service-desc"`) {
-		t.Errorf("Link header should contain rel=\"service-desc\", got '%s'", link)
+	if w := explain(srv, `{"query": "q"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
 	}
+}
 
-	// Verify response is valid OpenAPI spec
-	var spec map[string]interface{}
-	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+// TestRetrieveEndpoint verifies the retrieve route passes the request
+// through to the pipeline and returns its documents.
+func TestRetrieveEndpoint(t *testing.T) {
+	var got pipeline.RetrieveRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		RetrieveFunc: func(ctx context.Context, req pipeline.RetrieveRequest) (*pipeline.RetrieveResponse, error) {
+			got = req
+			if req.Query == "" {
+				return nil, fmt.Errorf("%w: query is required", pipeline.ErrInvalidRequest)
+			}
+			return &pipeline.RetrieveResponse{Documents: []pipeline.RetrievedDocument{{
+				ID: "42", PageContent: "Streaming replication", Score: 0.8,
+				Metadata: map[string]interface{}{"title": "Replication"},
+			}}}, nil
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+	retrieve := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/"+name+"/retrieve", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
 
-	// Check required OpenAPI fields
-	if spec["openapi"] == nil {
-		t.Error("OpenAPI spec missing 'openapi' field")
+	w := retrieve("test-pipeline", `{"query": "q", "k": 4, "filter": {"conditions": [{"column": "product", "operator": "=", "value": "pgEdge"}]}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
 	}
-	if spec["info"] == nil {
-		t.Error("OpenAPI spec missing 'info' field")
+	var resp map[string][]map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
 	}
-	if spec["paths"] == nil {
-		t.Error("OpenAPI spec missing 'paths' field")
+	if docs := resp["documents"]; len(docs) != 1 || docs[0]["page_content"] != "Streaming replication" ||
+		docs[0]["score"] != 0.8 || docs[0]["metadata"] == nil {
+		t.Errorf("unexpected documents: %+v", resp)
 	}
-	if spec["components"] == nil {
-		t.Error("OpenAPI spec missing 'components' field")
+	if got.Query != "q" || got.K != 4 || got.Filter == nil || len(got.Filter.Conditions) != 1 {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
 	}
 
-	// Check version
-	if spec["openapi"] != "3.0.3" {
-		t.Errorf("expected OpenAPI version '3.0.3', got '%v'", spec["openapi"])
+	if w := retrieve("test-pipeline", `{"k": 4}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a query, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := retrieve("missing", `{"query": "q"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
 	}
 }
 
-// TestUnknownRoute_ReturnsJSON404 is a regression test for issue #31:
-// requests to unregistered paths must get a structured JSON error, not
-// net/http's default plain-text "404 page not found". Uses the full
-// middleware chain (applyMiddleware), not the raw mux, since that's
-// where routingMiddleware intercepts the mismatch.
-func TestUnknownRoute_ReturnsJSON404(t *testing.T) {
-	srv := testServer()
-	handler := srv.applyMiddleware(srv.mux)
-
-	req := httptest.NewRequest(http.MethodGet, "/this-route-does-not-exist", nil)
-	w := httptest.NewRecorder()
-	handler.ServeHTTP(w, req)
-
-	if w.Code != http.StatusNotFound {
-		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+// TestSearchEndpoint verifies the search route passes the request
+// through to the pipeline and returns its documents and usage.
+func TestSearchEndpoint(t *testing.T) {
+	var got pipeline.SearchRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		SearchFunc: func(ctx context.Context, req pipeline.SearchRequest) (*pipeline.SearchResponse, error) {
+			got = req
+			if req.Query == "" {
+				return nil, fmt.Errorf("%w: query is required", pipeline.ErrInvalidRequest)
+			}
+			return &pipeline.SearchResponse{
+				Results: []pipeline.Source{{ID: "42", Content: "Streaming replication", Score: 0.8,
+					Metadata: map[string]interface{}{"title": "Replication"}}},
+				Usage: &pipeline.StageUsage{Embedding: llmlib.TokenUsage{PromptTokens: 6, TotalTokens: 6}},
+			}, nil
+		},
 	}
-	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
-		t.Errorf("expected Content-Type application/json, got %q", ct)
+	srv := New(testConfig(), pm, nil)
+	search := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/"+name+"/search", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
 
-	var resp ErrorResponse
+	w := search("test-pipeline", `{"query": "q", "top_n": 4, "filter_preset": "v17-docs", "include_timings": true}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp pipeline.SearchResponse
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-		t.Fatalf("response body is not valid JSON: %v", err)
+		t.Fatalf("failed to decode response: %v", err)
 	}
-	if resp.Error.Code != "NOT_FOUND" {
-		t.Errorf("expected error code NOT_FOUND, got %q", resp.Error.Code)
+	if len(resp.Results) != 1 || resp.Results[0].Content != "Streaming replication" ||
+		resp.Results[0].Metadata["title"] != "Replication" || resp.Usage == nil || resp.Usage.Embedding.TotalTokens != 6 {
+		t.Errorf("unexpected response: %+v", resp)
 	}
-}
-
-// TestMethodNotAllowed_ThroughMiddleware_ReturnsJSON405 is a regression
-// test for issue #31: a registered path hit with the wrong method must
-// get a structured JSON error with a correct Allow header, not net/http's
-// default plain-text "405 Method Not Allowed". net/http's ServeMux
-// intercepts this before any handler runs, so this only exercises the
-// fix when going through the full middleware chain (applyMiddleware),
-// unlike TestHealthEndpoint_MethodNotAllowed above which hits the raw
-// mux and observes net/http's own (also-405, but plain-text) response.
-func TestMethodNotAllowed_ThroughMiddleware_ReturnsJSON405(t *testing.T) {
-	srv := testServer()
-	handler := srv.applyMiddleware(srv.mux)
-
-	req := httptest.NewRequest(http.MethodPost, "/v1/health", nil)
-	w := httptest.NewRecorder()
-	handler.ServeHTTP(w, req)
-
-	if w.Code != http.StatusMethodNotAllowed {
-		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	if got.Query != "q" || got.TopN != 4 || got.FilterPreset != "v17-docs" || !got.IncludeTimings {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
 	}
-	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
-		t.Errorf("expected Content-Type application/json, got %q", ct)
+
+	if w := search("test-pipeline", `{"top_n": 4}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a query, got %d", http.StatusBadRequest, w.Code)
 	}
-	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "GET") {
-		t.Errorf("expected Allow header to contain GET, got %q", allow)
+	if w := search("missing", `{"query": "q"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
 	}
+}
 
-	var resp ErrorResponse
-	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-		t.Fatalf("response body is not valid JSON: %v", err)
+// TestEmbedEndpoint verifies the embed route passes the texts through
+// to the pipeline, returns their embeddings, and rejects too many.
+func TestEmbedEndpoint(t *testing.T) {
+	var got pipeline.EmbedRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		EmbedFunc: func(ctx context.Context, req pipeline.EmbedRequest) (*pipeline.EmbedResponse, error) {
+			got = req
+			return &pipeline.EmbedResponse{
+				Model:      "text-embedding-3-small",
+				Dimensions: 2,
+				Embeddings: [][]float32{{0.25, -0.5}, {1, 0}},
+				Usage:      &pipeline.StageUsage{Embedding: llmlib.TokenUsage{PromptTokens: 4, TotalTokens: 4}},
+			}, nil
+		},
 	}
-	if resp.Error.Code != "METHOD_NOT_ALLOWED" {
-		t.Errorf("expected error code METHOD_NOT_ALLOWED, got %q", resp.Error.Code)
+	srv := New(testConfig(), pm, nil)
+	embed := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/"+name+"/embed", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
-}
 
-// TestAllowedMethods_ReflectsRegisteredRoutes checks the mux-probing
-// helper directly against this server's actual registered routes.
-func TestAllowedMethods_ReflectsRegisteredRoutes(t *testing.T) {
-	srv := testServer()
+	w := embed("test-pipeline", `{"input": ["standby", "replication"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp pipeline.EmbedResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Model != "text-embedding-3-small" || resp.Dimensions != 2 || len(resp.Embeddings) != 2 ||
+		resp.Embeddings[0][1] != -0.5 || resp.Usage.Embedding.TotalTokens != 4 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if len(got.Input) != 2 || got.Input[1] != "replication" {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
+	}
 
-	req := httptest.NewRequest(http.MethodDelete, "/v1/pipelines/some-name", nil)
-	allowed := srv.allowedMethods(req)
+	tooMany, _ := json.Marshal(map[string][]string{"input": make([]string, pipeline.MaxEmbedInputs+1)})
+	for _, body := range []string{`{}`, `{"input": "standby"}`, string(tooMany)} {
+		if w := embed("test-pipeline", body); w.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d for %.40s, got %d", http.StatusBadRequest, body, w.Code)
+		}
+	}
+	if w := embed("missing", `{"input": ["q"]}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
+	}
+}
 
-	if len(allowed) != 1 || allowed[0] != http.MethodPost {
-		t.Errorf("expected only POST allowed for /v1/pipelines/{name}, got %v", allowed)
+// TestFeedbackEndpoint verifies ratings are passed to the pipeline and
+// a response or query it does not keep is reported as not found.
+func TestFeedbackEndpoint(t *testing.T) {
+	var got pipeline.FeedbackRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		FeedbackFunc: func(req pipeline.FeedbackRequest) error {
+			got = req
+			switch {
+			case req.Rating != pipeline.FeedbackUp && req.Rating != pipeline.FeedbackDown:
+				return fmt.Errorf("%w: rating must be \"up\" or \"down\"", pipeline.ErrInvalidRequest)
+			case req.ResponseID != "":
+				if req.ResponseID != "r1" {
+					return pipeline.ErrResponseNotFound
+				}
+			case req.QueryID != "q1":
+				return pipeline.ErrQueryNotFound
+			}
+			return nil
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+	feedback := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline/feedback", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
 
-	req2 := httptest.NewRequest(http.MethodDelete, "/no-such-path", nil)
-	if allowed2 := srv.allowedMethods(req2); len(allowed2) != 0 {
-		t.Errorf("expected no allowed methods for an unregistered path, got %v", allowed2)
+	if w := feedback(`{"query_id": "q1", "rating": "down", "comment": "Wrong version"}`); w.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if got.QueryID != "q1" || got.Rating != pipeline.FeedbackDown || got.Comment != "Wrong version" {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
+	}
+	if w := feedback(`{"query_id": "q2", "rating": "up"}`); w.Code != http.StatusNotFound ||
+		!strings.Contains(w.Body.String(), "QUERY_NOT_FOUND") {
+		t.Errorf("expected 404 QUERY_NOT_FOUND, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := feedback(`{"response_id": "r1", "rating": "up"}`); w.Code != http.StatusNoContent || got.ResponseID != "r1" {
+		t.Errorf("expected status %d for a response it keeps, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := feedback(`{"response_id": "r2", "rating": "up"}`); w.Code != http.StatusNotFound ||
+		!strings.Contains(w.Body.String(), "RESPONSE_NOT_FOUND") {
+		t.Errorf("expected 404 RESPONSE_NOT_FOUND, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := feedback(`{"query_id": "q1", "rating": "meh"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown rating, got %d", http.StatusBadRequest, w.Code)
 	}
 }
 
-// TestAllowedMethods_IncludesImplicitHEAD verifies that a GET-registered
-// route reports HEAD as allowed too, matching net/http.ServeMux's own
-// behavior of implicitly serving HEAD wherever GET is registered — even
-// though only GET appears in routes.go.
-func TestAllowedMethods_IncludesImplicitHEAD(t *testing.T) {
-	srv := testServer()
+// TestEvalEndpoints verifies the admin API exports a pipeline's eval
+// dataset as newline-delimited JSON and evaluates one posted back.
+func TestEvalEndpoints(t *testing.T) {
+	var gotCases []pipeline.EvalCase
+	var gotOpts pipeline.EvalOptions
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		EvalDatasetFunc: func() []pipeline.EvalCase {
+			return []pipeline.EvalCase{
+				{ID: "a", Query: "How does replication work?", ExpectedSources: []string{"1", "2"}},
+				{ID: "b", Query: "How do I back up?", ExpectedSources: []string{"3"}, Feedback: pipeline.FeedbackUp},
+			}
+		},
+		EvaluateFunc: func(ctx context.Context, cases []pipeline.EvalCase, opts pipeline.EvalOptions) (*pipeline.EvalReport, error) {
+			gotCases, gotOpts = cases, opts
+			quality := 0.75
+			return &pipeline.EvalReport{K: opts.K, Cases: len(cases), Evaluated: len(cases), HitRate: 0.5,
+				Results: []pipeline.EvalResult{{Query: cases[0].Query, Expected: cases[0].ExpectedSources,
+					Retrieved: []string{"2", "9"}, Hit: true, Rank: 1, Recall: 0.5, NDCG: 0.6,
+					Answer: "WAL is streamed, to the standby.", AnswerQuality: &quality}}}, nil
+		},
+	}
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true}
+	srv := New(cfg, pm, nil)
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
 
-	req := httptest.NewRequest(http.MethodDelete, "/v1/health", nil)
-	allowed := srv.allowedMethods(req)
+	w := admin(http.MethodGet, "/v1/admin/pipelines/test-pipeline/eval-dataset", "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected 200 ndjson, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	dataset := w.Body.String()
+	if lines := strings.Split(strings.TrimSpace(dataset), "\n"); len(lines) != 2 ||
+		!strings.Contains(lines[1], `"feedback":"up"`) {
+		t.Errorf("expected a case per line, got %q", dataset)
+	}
 
-	hasGet, hasHead := false, false
-	for _, m := range allowed {
-		if m == http.MethodGet {
-			hasGet = true
-		}
-		if m == http.MethodHead {
-			hasHead = true
+	w = admin(http.MethodPost, "/v1/admin/pipelines/test-pipeline/eval?k=3", dataset)
+	var report pipeline.EvalReport
+	if err := json.NewDecoder(w.Body).Decode(&report); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with a report, got %d: %v", w.Code, err)
+	}
+	if gotOpts.K != 3 || gotOpts.Judge || len(gotCases) != 2 || gotCases[0].Query != "How does replication work?" ||
+		len(gotCases[0].ExpectedSources) != 2 || report.HitRate != 0.5 {
+		t.Errorf("unexpected evaluation: options %+v, cases %+v, report %+v", gotOpts, gotCases, report)
+	}
+
+	w = admin(http.MethodPost, "/v1/admin/pipelines/test-pipeline/eval?judge=true&format=csv", dataset)
+	want := "pipeline,query,expected_sources,retrieved_sources,hit,rank,recall,ndcg,answer,answer_quality,error\n" +
+		"test-pipeline,How does replication work?,1 2,2 9,true,1,0.5,0.6,\"WAL is streamed, to the standby.\",0.75,\n"
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/csv" || w.Body.String() != want {
+		t.Errorf("expected a CSV report, got %d %q: %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+	if !gotOpts.Judge {
+		t.Error("expected answers judged")
+	}
+
+	w = admin(http.MethodPost, "/v1/admin/eval", dataset)
+	var all EvalResponse
+	if err := json.NewDecoder(w.Body).Decode(&all); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("expected 200 with reports, got %d: %v", w.Code, err)
+	}
+	if len(all.Pipelines) != 1 || all.Pipelines[0].Pipeline != "test-pipeline" ||
+		all.Pipelines[0].EvalReport == nil || all.Pipelines[0].HitRate != 0.5 {
+		t.Errorf("expected a report for every pipeline, got %+v", all.Pipelines)
+	}
+	if w := admin(http.MethodPost, "/v1/admin/eval?pipeline=missing", dataset); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
+	}
+
+	for _, tc := range []struct{ path, body string }{
+		{"/v1/admin/pipelines/test-pipeline/eval?k=0", dataset},
+		{"/v1/admin/pipelines/test-pipeline/eval?judge=maybe", dataset},
+		{"/v1/admin/pipelines/test-pipeline/eval?format=xml", dataset},
+		{"/v1/admin/pipelines/test-pipeline/eval", `{"expected_sources": ["1"]}`},
+		{"/v1/admin/pipelines/test-pipeline/eval", `{"query": `},
+	} {
+		if w := admin(http.MethodPost, tc.path, tc.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s %q: expected status %d, got %d", tc.path, tc.body, http.StatusBadRequest, w.Code)
 		}
 	}
-	if !hasGet || !hasHead {
-		t.Errorf("expected both GET and HEAD allowed for /v1/health, got %v", allowed)
+	if w := admin(http.MethodGet, "/v1/admin/pipelines/missing/eval-dataset", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
 	}
 }
 
-// TestPipelineEndpoint_RequestTooLarge is a regression test for issue
-// #31: a request body over maxRequestBodyBytes must be rejected with a
-// structured JSON 413, not silently accepted (previously there was no
-// size limit at all) or surfaced as a generic 400.
-func TestPipelineEndpoint_RequestTooLarge(t *testing.T) {
-	srv := testServer()
+// TestTuneEndpoint verifies a tuning run is started as a job whose
+// task carries the comparison report, over the captured dataset unless
+// one is given.
+func TestTuneEndpoint(t *testing.T) {
+	captured := []pipeline.EvalCase{{ID: "a", Query: "How does replication work?", ExpectedSources: []string{"1"}}}
+	var gotCases []pipeline.EvalCase
+	var gotVariants []pipeline.TuningVariant
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		EvalDatasetFunc: func() []pipeline.EvalCase { return captured },
+		TuneFunc: func(ctx context.Context, cases []pipeline.EvalCase, variants []pipeline.TuningVariant, k int,
+			progress func(done, total int)) (*pipeline.TuningReport, error) {
+			gotCases, gotVariants = cases, variants
+			progress(len(cases), len(cases))
+			return &pipeline.TuningReport{K: k, Cases: len(cases), Best: "score_fusion", Results: []pipeline.TuningResult{
+				{Variant: pipeline.TuningVariant{Name: "score_fusion", Fusion: "score_fusion"}, Evaluated: 1, NDCG: 0.9},
+				{Variant: pipeline.TuningVariant{Name: "current"}, Evaluated: 1, NDCG: 0.6},
+			}}, nil
+		},
+	}
+	queue := jobs.NewQueue(jobs.NewMemoryStore(), config.JobsConfig{}, nil)
+	defer queue.Close()
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true}
+	srv := New(cfg, pm, nil, WithJobs(queue))
+	admin := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(method, path, bytes.NewBufferString(body)))
+		return w
+	}
 
-	oversized := strings.Repeat("x", maxRequestBodyBytes+1)
-	body := `{"query":"` + oversized + `"}`
+	w := admin(http.MethodPost, "/v1/admin/pipelines/test-pipeline/tune",
+		`{"k": 5, "variants": [{"name": "score_fusion", "fusion": "score_fusion"}]}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if loc := w.Header().Get("Location"); job.Kind != TuneJobKind || loc != "/v1/admin/jobs/"+job.ID {
+		t.Fatalf("unexpected job %+v at %q", job, loc)
+	}
 
-	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
-		bytes.NewBufferString(body))
-	w := httptest.NewRecorder()
+	deadline := time.Now().Add(5 * time.Second)
+	for !job.Finished() && time.Now().Before(deadline) {
+		w := admin(http.MethodGet, "/v1/admin/jobs/"+job.ID, "")
+		job = jobs.Job{}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	var report pipeline.TuningReport
+	if job.Status != jobs.StatusCompleted || json.Unmarshal(job.Tasks[0].Result, &report) != nil ||
+		report.K != 5 || report.Best != "score_fusion" || len(report.Results) != 2 {
+		t.Fatalf("expected the job to complete with the report, got %+v", job)
+	}
+	if !reflect.DeepEqual(gotCases, captured) || len(gotVariants) != 1 || gotVariants[0].Fusion != "score_fusion" {
+		t.Errorf("expected the captured dataset and the given variants, got %+v, %+v", gotCases, gotVariants)
+	}
 
-	srv.mux.ServeHTTP(w, req)
+	captured = nil
+	for _, body := range []string{`{"k": -1}`, `{}`, `{"dataset": `} {
+		if w := admin(http.MethodPost, "/v1/admin/pipelines/test-pipeline/tune", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%q: expected status %d, got %d", body, http.StatusBadRequest, w.Code)
+		}
+	}
+}
 
-	if w.Code != http.StatusRequestEntityTooLarge {
-		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+// TestEstimateEndpoint verifies the estimate route passes the query
+// through to the pipeline and returns its estimate.
+func TestEstimateEndpoint(t *testing.T) {
+	var got pipeline.QueryRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		EstimateFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.Estimate, error) {
+			got = req
+			promptCost := 0.0036
+			return &pipeline.Estimate{
+				PromptTokens: 1200,
+				Documents:    5,
+				Usage:        &pipeline.StageUsage{},
+				Models:       []pipeline.ModelEstimate{{Provider: "openai", Model: "gpt-4o", PromptCost: &promptCost}},
+			}, nil
+		},
 	}
-	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
-		t.Errorf("expected Content-Type application/json, got %q", ct)
+	srv := New(testConfig(), pm, nil)
+	estimate := func(name, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/"+name+"/estimate", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
 	}
 
-	var resp ErrorResponse
+	w := estimate("test-pipeline", `{"question": "q", "top_n": 5, "answer_length": "short"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var resp pipeline.Estimate
 	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
-		t.Fatalf("response body is not valid JSON: %v", err)
+		t.Fatalf("failed to decode response: %v", err)
 	}
-	if resp.Error.Code != "REQUEST_TOO_LARGE" {
-		t.Errorf("expected error code REQUEST_TOO_LARGE, got %q", resp.Error.Code)
+	if resp.PromptTokens != 1200 || len(resp.Models) != 1 || resp.Models[0].PromptCost == nil ||
+		*resp.Models[0].PromptCost != 0.0036 || resp.Models[0].MaxCompletionCost != nil {
+		t.Errorf("unexpected estimate: %+v", resp)
+	}
+	if got.Query != "q" || got.TopN != 5 || got.AnswerLength != "short" {
+		t.Errorf("unexpected request passed to pipeline: %+v", got)
 	}
-}
 
-func TestRFC8631LinkHeader(t *testing.T) {
-	srv := testServer()
+	if w := estimate("test-pipeline", `{"top_n": 5}`); w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d without a query, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := estimate("missing", `{"query": "q"}`); w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
+	}
+}
 
-	// Test that Link header is present on all API responses
-	endpoints := []struct {
-		method string
-		path   string
-	}{
-		{http.MethodGet, "/v1/live"},
-		{http.MethodGet, "/v1/health"},
-		{http.MethodGet, "/v1/pipelines"},
-		{http.MethodGet, "/v1/stats"},
-		{http.MethodGet, "/v1/openapi.json"},
+// TestFilterVars verifies the request headers and proxy-set claims
+// config filter variables refer to reach the pipeline's searches.
+func TestFilterVars(t *testing.T) {
+	var vars database.FilterVars
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		RetrieveFunc: func(ctx context.Context, req pipeline.RetrieveRequest) (*pipeline.RetrieveResponse, error) {
+			vars = database.FilterVarsFrom(ctx)
+			return &pipeline.RetrieveResponse{}, nil
+		},
 	}
+	cfg := testConfig()
+	cfg.Server.ClaimsHeader = "X-Claims"
+	srv := New(cfg, pm, nil)
 
-	for _, ep := range endpoints {
-		req := httptest.NewRequest(ep.method, ep.path, nil)
-		w := httptest.NewRecorder()
-		srv.mux.ServeHTTP(w, req)
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"org_id": 42, "sub": "ann", "admin": true, "groups": ["a"]}`))
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline/retrieve", bytes.NewBufferString(`{"query": "q"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Add("X-Team", "a")
+	req.Header.Add("X-Team", "b")
+	req.Header.Set("X-Claims", claims)
+	w := httptest.NewRecorder()
+	srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+	if w.Code != http.StatusOK || vars == nil {
+		t.Fatalf("expected filter variables on the request, got status %d", w.Code)
+	}
 
-		link := w.Header().Get("Link")
-		if link == "" {
-			t.Errorf("%s %s: missing Link header", ep.method, ep.path)
-			continue
-		}
-		if !strings.Contains(link, "</v1/openapi.json>") {
-			t.Errorf("%s %s: Link header should reference /v1/openapi.json", ep.method, ep.path)
-		}
-		if !strings.Contains(link, `rel="service-desc"`) {
-			t.Errorf("%s %s: Link header should have rel=\"service-desc\"", ep.method, ep.path)
+	tests := []struct {
+		v      config.FilterVariable
+		want   string
+		wantOK bool
+	}{
+		{config.FilterVariable{Source: "header", Name: "x-tenant"}, "acme", true},
+		{config.FilterVariable{Source: "header", Name: "X-Team"}, "", false},
+		{config.FilterVariable{Source: "header", Name: "X-Missing"}, "", false},
+		{config.FilterVariable{Source: "claim", Name: "org_id"}, "42", true},
+		{config.FilterVariable{Source: "claim", Name: "sub"}, "ann", true},
+		{config.FilterVariable{Source: "claim", Name: "admin"}, "true", true},
+		{config.FilterVariable{Source: "claim", Name: "groups"}, "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := vars.Lookup(tt.v); got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: expected %q, %v, got %q, %v", tt.v, tt.want, tt.wantOK, got, ok)
 		}
 	}
+
+	if got, err := decodeClaims(`{"org_id": "7"}`); err != nil || got["org_id"] != "7" {
+		t.Errorf("expected a plain JSON claims header to decode, got %v, %v", got, err)
+	}
+	if _, err := decodeClaims("not claims"); err == nil {
+		t.Error("expected an invalid claims header to fail to decode")
+	}
 }
 
-// TestIsRequestTimeout_DeadlineExceeded is a regression test for issue
-// #31: isRequestTimeout must report true when a context's own deadline
-// elapsed (the server's request timeout firing).
-func TestIsRequestTimeout_DeadlineExceeded(t *testing.T) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
-	defer cancel()
-	<-ctx.Done()
+// TestUploadDocumentsEndpoint verifies uploaded files are ingested in a
+// background job, one task per file, whose progress the jobs route
+// reports.
+func TestUploadDocumentsEndpoint(t *testing.T) {
+	ingested := make(chan *ingest.Document, 1)
+	ingestedTenant := make(chan string, 1)
+	enabled := true
+	var tenant config.TenantConfig
+	var tenantHeader string
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		CapabilitiesFunc: func() pipeline.Capabilities {
+			return pipeline.Capabilities{Name: "test-pipeline", Ingest: enabled, MaxUploadBytes: 1 << 10,
+				Tenant: tenant}
+		},
+		IngestFunc: func(ctx context.Context, doc *ingest.Document, progress func(done, total int)) (int, error) {
+			progress(1, 1)
+			if tenant.Enabled() {
+				value, _ := database.FilterVarsFrom(ctx).Lookup(tenant.Variable())
+				ingestedTenant <- value
+			}
+			ingested <- doc
+			return 1, nil
+		},
+	}
+	queue := jobs.NewQueue(jobs.NewMemoryStore(), config.JobsConfig{}, nil)
+	defer queue.Close()
+	srv := New(testConfig(), pm, nil, WithJobs(queue))
+	upload := func(filename, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, _ := mw.CreateFormFile("file", filename)
+		part.Write([]byte(content))
+		mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline/documents", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		if tenantHeader != "" {
+			req.Header.Set("X-Tenant-ID", tenantHeader)
+		}
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
 
-	if !isRequestTimeout(ctx) {
-		t.Errorf("expected isRequestTimeout to be true after the deadline elapsed, ctx.Err()=%v", ctx.Err())
+	w := upload("guide.md", "# Guide\n\nStreaming replication.")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	var job jobs.Job
+	if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if job.ID == "" || job.Kind != IngestJobKind || len(job.Tasks) != 1 || job.Tasks[0].Name != "guide.md" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+	if loc := w.Header().Get("Location"); loc != "/v1/jobs/"+job.ID {
+		t.Errorf("expected the job's location, got %q", loc)
+	}
+	if doc := <-ingested; doc.Filename != "guide.md" || doc.Pages[0].Text != "Guide\n\nStreaming replication." {
+		t.Errorf("unexpected document ingested: %+v", doc)
 	}
-}
 
-// TestIsRequestTimeout_Canceled verifies isRequestTimeout distinguishes
-// its own timeout from an ordinary cancellation (e.g. the client
-// disconnecting), which must NOT be reported as a timeout.
-func TestIsRequestTimeout_Canceled(t *testing.T) {
-	ctx, cancel := context.WithCancel(context.Background())
-	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != jobs.StatusCompleted && time.Now().Before(deadline) {
+		req := httptest.NewRequest(http.MethodGet, "/v1/jobs/"+job.ID, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		job = jobs.Job{}
+		if err := json.NewDecoder(w.Body).Decode(&job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if job.Status != jobs.StatusCompleted || job.Tasks[0].Done != 1 {
+		t.Errorf("expected the job to complete, got %+v", job)
+	}
 
-	if isRequestTimeout(ctx) {
-		t.Error("expected isRequestTimeout to be false for a plain cancellation, not a deadline")
+	if w := upload("slides.pptx", "x"); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status %d for an unsupported file, got %d", http.StatusUnsupportedMediaType, w.Code)
 	}
-}
+	if w := upload("big.md", strings.Repeat("x", 2<<10)); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d for an oversized upload, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	// A pipeline that isolates tenants needs the uploading tenant.
+	tenant = config.TenantConfig{Column: "tenant_id", Header: "X-Tenant-ID"}
+	if w := upload("guide.md", "text"); w.Code != http.StatusBadRequest ||
+		!strings.Contains(w.Body.String(), "header:X-Tenant-ID") {
+		t.Errorf("expected status %d for an upload naming no tenant, got %d: %s",
+			http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	tenantHeader = "acme"
+	if w := upload("guide.md", "text"); w.Code != http.StatusAccepted {
+		t.Fatalf("expected status %d for a tenant's upload, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+	}
+	<-ingested
+	if got := <-ingestedTenant; got != "acme" {
+		t.Errorf("expected the job to ingest as the uploading tenant, got %q", got)
+	}
+	tenant, tenantHeader = config.TenantConfig{}, ""
 
-// TestIsRequestTimeout_StillRunning verifies isRequestTimeout is false
-// while a context is still active.
-func TestIsRequestTimeout_StillRunning(t *testing.T) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
-	defer cancel()
+	enabled = false
+	if w := upload("guide.md", "text"); w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d with ingestion disabled, got %d", http.StatusForbidden, w.Code)
+	}
 
-	if isRequestTimeout(ctx) {
-		t.Error("expected isRequestTimeout to be false for a context that hasn't finished")
+	req := httptest.NewRequest(http.MethodGet, "/v1/jobs/missing", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown job, got %d", http.StatusNotFound, w.Code)
 	}
 }
 
-// TestSwapPipelineManager is a regression test for issue #30 (config/
-// secret hot-reload): swapping the active PipelineManager must both
-// return the previous one (so the caller can close it) and make
-// subsequent requests observe the new one, with no restart required.
-func TestSwapPipelineManager(t *testing.T) {
-	srv := testServer()
+// TestPipelineOpenAPIEndpoint verifies a pipeline's OpenAPI document
+// covers only its operations and lists its filter columns, filter
+// presets and metadata columns.
+func TestPipelineDetailEndpoint(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		DetailFunc: func() pipeline.Detail {
+			return pipeline.Detail{
+				Name:   "test-pipeline",
+				RAGLLM: pipeline.ModelDetail{Provider: "openai", Model: "gpt-4o-mini"},
+				TopN:   5,
+				Rerank: pipeline.RerankDetail{Enabled: true, Provider: "voyage", Model: "rerank-2"},
+			}
+		},
+	}
+	srv := New(testConfig(), pm, nil)
 
-	// Confirm the original manager is in effect.
-	req := httptest.NewRequest(http.MethodGet, "/v1/pipelines", nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/pipelines/test-pipeline", nil)
 	w := httptest.NewRecorder()
 	srv.mux.ServeHTTP(w, req)
-	var resp PipelinesResponse
-	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var detail pipeline.Detail
+	if err := json.NewDecoder(w.Body).Decode(&detail); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
-	if len(resp.Pipelines) != 1 || resp.Pipelines[0].Name != "test-pipeline" {
-		t.Fatalf("expected the original pipeline before swap, got %+v", resp.Pipelines)
+	if detail.RAGLLM.Model != "gpt-4o-mini" || detail.TopN != 5 || !detail.Rerank.Enabled {
+		t.Errorf("unexpected pipeline detail: %+v", detail)
 	}
 
-	newPM := &mockPipelineManager{
-		pipelines: map[string]*mockPipelineInfo{
-			"reloaded-pipeline": {name: "reloaded-pipeline", description: "after reload"},
+	req = httptest.NewRequest(http.MethodGet, "/v1/pipelines/missing", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestPipelineOpenAPIEndpoint(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		CapabilitiesFunc: func() pipeline.Capabilities {
+			return pipeline.Capabilities{
+				Name:            "test-pipeline",
+				Description:     "Product documentation",
+				FilterColumns:   []string{"product", "version"},
+				FilterPresets:   []string{"v17-docs"},
+				MetadataColumns: []string{"title", "url"},
+			}
 		},
 	}
-	oldPM := srv.SwapPipelineManager(newPM)
-	if oldPM == nil {
-		t.Fatal("expected SwapPipelineManager to return the previous manager, got nil")
+	srv := New(testConfig(), pm, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/pipelines/test-pipeline/openapi.json", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	var spec OpenAPISpec
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
 	}
 
-	// Subsequent requests must observe the new manager, with no restart.
-	req2 := httptest.NewRequest(http.MethodGet, "/v1/pipelines", nil)
-	w2 := httptest.NewRecorder()
+	if spec.Info.Description != "Product documentation" {
+		t.Errorf("expected the pipeline's description, got %q", spec.Info.Description)
+	}
+	query, ok := spec.Paths["/pipelines/test-pipeline"]
+	if !ok || query.Post == nil {
+		t.Fatalf("expected the pipeline's query operation, got paths %v", slices.Sorted(maps.Keys(spec.Paths)))
+	}
+	for _, param := range query.Post.Parameters {
+		if param.In == "path" {
+			t.Errorf("expected the name parameter to be filled in, got %+v", param)
+		}
+	}
+	if _, ok := spec.Paths["/pipelines/test-pipeline/documents"]; ok {
+		t.Error("expected no upload operation for a pipeline that does not ingest uploads")
+	}
+	for path := range spec.Paths {
+		if !strings.HasPrefix(path, "/pipelines/test-pipeline") {
+			t.Errorf("unexpected path %s in a pipeline's specification", path)
+		}
+	}
+
+	schemas := spec.Components.Schemas
+	if got := schemas["FilterCondition"].Properties["column"].Enum; !slices.Equal(got, []string{"product", "version"}) {
+		t.Errorf("expected the filter columns as an enum, got %v", got)
+	}
+	for _, name := range []string{"QueryRequest", "RetrieveRequest", "SearchRequest"} {
+		if got := schemas[name].Properties["filter_preset"].Enum; !slices.Equal(got, []string{"v17-docs"}) {
+			t.Errorf("expected the filter presets as an enum on %s, got %v", name, got)
+		}
+	}
+	metadata := schemas["Source"].Properties["metadata"]
+	if _, ok := metadata.Properties["url"]; !ok || len(metadata.Properties) != 2 {
+		t.Errorf("expected the metadata columns as properties, got %+v", metadata)
+	}
+	if _, ok := schemas["Session"]; ok {
+		t.Error("expected schemas unused by the pipeline's operations to be left out")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/pipelines/missing/openapi.json", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d for an unknown pipeline, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestPipelineEndpoint_RequestValidation verifies request bodies are
+// checked against the published schema: type errors are rejected with
+// field-level errors, and unknown fields are warned about, or rejected
+// in strict mode.
+func TestPipelineEndpoint_RequestValidation(t *testing.T) {
+	var got pipeline.QueryRequest
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			got = req
+			return &pipeline.QueryResponse{Answer: "ok"}, nil
+		},
+	}
+	query := func(srv *Server, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+		return w
+	}
+	decodeError := func(t *testing.T, w *httptest.ResponseRecorder) ErrorDetail {
+		t.Helper()
+		var resp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return resp.Error
+	}
+
+	srv := New(testConfig(), pm, nil)
+	w := query(srv, `{"query": "q", "include_source": true, "filter": {"conditions": [], "logic": "AND", "mode": "x"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got.Query != "q" {
+		t.Errorf("expected the query to run, got %+v", got)
+	}
+	want := []string{`299 - "unknown field filter.mode ignored"`, `299 - "unknown field include_source ignored"`}
+	if warnings := w.Header().Values("Warning"); !slices.Equal(slices.Sorted(slices.Values(warnings)), want) {
+		t.Errorf("Warning headers = %q, want %q", warnings, want)
+	}
+
+	w = query(srv, `{"query": "q", "top_n": "5", "stream": 1, "messages": [{"role": "user"}], `+
+		`"filter": {"conditions": [{"column": 7, "operator": "="}]}, "logit_bias": {"13": 1.5}}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	detail := decodeError(t, w)
+	wantFields := []FieldError{
+		{Field: "filter.conditions[0].column", Message: "must be a string"},
+		{Field: "logit_bias.13", Message: "must be an integer"},
+		{Field: "messages[0].content", Message: "is required"},
+		{Field: "stream", Message: "must be a boolean"},
+		{Field: "top_n", Message: "must be an integer"},
+	}
+	if detail.Code != "INVALID_REQUEST" || !slices.Equal(detail.Fields, wantFields) {
+		t.Errorf("unexpected error: %+v\nwant fields %+v", detail, wantFields)
+	}
+
+	cfg := testConfig()
+	cfg.Server.RequestValidation = config.RequestValidationStrict
+	w = query(New(cfg, pm, nil), `{"query": "q", "include_source": true}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d in strict mode, got %d", http.StatusBadRequest, w.Code)
+	}
+	detail = decodeError(t, w)
+	if !slices.Equal(detail.Fields, []FieldError{{Field: "include_source", Message: "unknown field"}}) {
+		t.Errorf("unexpected fields: %+v", detail.Fields)
+	}
+}
+
+// TestPipelineEndpoint_FilterErrors verifies a filter the pipeline
+// rejects is answered with a 400 naming each field at fault.
+func TestPipelineEndpoint_FilterErrors(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return nil, &pipeline.FilterError{Fields: config.ValidationErrors{
+				{Field: "filter.groups[0].conditions[0].column", Message: `column "owner" is not filterable`},
+				{Field: "filter.groups[0].conditions[1].operator", Message: "unsupported operator: ~"},
+			}}
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	body := `{"query": "q", "filter": {"groups": [{"conditions": [` +
+		`{"column": "owner", "operator": "=", "value": "jo"}, {"column": "product", "operator": "~", "value": "x"}]}]}}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	wantFields := []FieldError{
+		{Field: "filter.groups[0].conditions[0].column", Message: `column "owner" is not filterable`},
+		{Field: "filter.groups[0].conditions[1].operator", Message: "unsupported operator: ~"},
+	}
+	if resp.Error.Code != "INVALID_REQUEST" || !slices.Equal(resp.Error.Fields, wantFields) {
+		t.Errorf("unexpected error: %+v", resp.Error)
+	}
+}
+
+// TestPipelineEndpoint_Aliases verifies top_k and question are accepted
+// for top_n and query, that the canonical fields take precedence, and
+// that each alias used is reported as deprecated.
+func TestPipelineEndpoint_Aliases(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantQuery    string
+		wantTopN     int
+		wantWarnings []string
+	}{
+		{
+			name:      "canonical fields",
+			body:      `{"query": "q", "top_n": 3}`,
+			wantQuery: "q",
+			wantTopN:  3,
+		},
+		{
+			name:      "aliases",
+			body:      `{"question": "q", "top_k": 5}`,
+			wantQuery: "q",
+			wantTopN:  5,
+			wantWarnings: []string{
+				`299 - "top_k is deprecated; use top_n"`,
+				`299 - "question is deprecated; use query"`,
+			},
+		},
+		{
+			name:      "canonical fields take precedence",
+			body:      `{"query": "q", "question": "other", "top_n": 3, "top_k": 5}`,
+			wantQuery: "q",
+			wantTopN:  3,
+			wantWarnings: []string{
+				`299 - "top_k ignored; top_n takes precedence"`,
+				`299 - "question ignored; query takes precedence"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got pipeline.QueryRequest
+			pm := newMockPipelineManager()
+			pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+				ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+					got = req
+					return &pipeline.QueryResponse{Answer: "ok"}, nil
+				},
+			}
+			cfg := testConfig()
+			cfg.Server.RequestValidation = config.RequestValidationStrict
+			srv := New(cfg, pm, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+			}
+			if got.Query != tt.wantQuery || got.TopN != tt.wantTopN {
+				t.Errorf("got query %q and top_n %d, want %q and %d", got.Query, got.TopN, tt.wantQuery, tt.wantTopN)
+			}
+			if warnings := w.Header().Values("Warning"); !slices.Equal(warnings, tt.wantWarnings) {
+				t.Errorf("Warning headers = %q, want %q", warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+// TestPipelineEndpoint_InvalidRequestFromPipeline verifies that a
+// pipeline rejecting a request (e.g. logit_bias on a non-OpenAI
+// provider) surfaces as 400 INVALID_REQUEST rather than a 500.
+func TestPipelineEndpoint_InvalidRequestFromPipeline(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			if req.LogitBias["13"] != -100 {
+				t.Errorf("expected logit_bias to be decoded, got %v", req.LogitBias)
+			}
+			return nil, fmt.Errorf("%w: logit_bias is only supported by the openai provider",
+				pipeline.ErrInvalidRequest)
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	body := bytes.NewBufferString(`{"query": "test query", "logit_bias": {"13": -100}}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Error.Code != "INVALID_REQUEST" {
+		t.Errorf("expected error code INVALID_REQUEST, got %q", resp.Error.Code)
+	}
+}
+
+// TestPipelineEndpoint_StreamingTimeout is a regression test for issue
+// #37: it drives the streaming timeout path added in #33 through a
+// fake QueryExecutor whose stream channels never receive anything,
+// forcing the handler's ctx.Done() case to fire once the request
+// timeout elapses. Confirms the client gets an SSE "error" event
+// followed by "done", with no chunks in between — previously only
+// verified by hand against a live, artificially slow backend.
+func TestPipelineEndpoint_StreamingTimeout(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			// Channels that never receive anything: the handler's
+			// ctx.Done() case is the only way this select resolves.
+			return make(chan pipeline.StreamChunk), make(chan error, 1)
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+	srv.requestTimeout = 50 * time.Millisecond
+
+	body := bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	got := w.Body.String()
+	errIdx := strings.Index(got, `"type":"error"`)
+	doneIdx := strings.Index(got, `"type":"done"`)
+	if errIdx < 0 {
+		t.Fatalf("expected an SSE error event, got body: %s", got)
+	}
+	if doneIdx < 0 {
+		t.Fatalf("expected an SSE done event, got body: %s", got)
+	}
+	if errIdx > doneIdx {
+		t.Errorf("expected the error event before the done event, got body: %s", got)
+	}
+	if !strings.Contains(got, "request took too long to process") {
+		t.Errorf("expected the timeout message in the error event, got body: %s", got)
+	}
+}
+
+func TestPipelineEndpoint_StreamingDoneCarriesUsage(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 2)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "hello"}
+			chunkChan <- pipeline.StreamChunk{
+				FinishReason: "stop",
+				Usage: &pipeline.StageUsage{
+					Embedding:  llmlib.TokenUsage{PromptTokens: 4, TotalTokens: 4},
+					Completion: llmlib.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+				},
+			}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	body := bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	got := w.Body.String()
+	want := `{"type":"done","usage":{"embedding":{"prompt_tokens":4,"completion_tokens":0,"total_tokens":4},` +
+		`"completion":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}}`
+	if !strings.Contains(got, want) {
+		t.Errorf("expected done event %s, got body: %s", want, got)
+	}
+}
+
+// TestShutdown_DrainsStreams verifies that a stream still running as
+// the shutdown deadline nears is ended with a server_shutting_down
+// event rather than having its connection dropped.
+func TestShutdown_DrainsStreams(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 1)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "hello"}
+			go func() {
+				<-ctx.Done()
+				close(chunkChan)
+				close(errChan)
+			}()
+			return chunkChan, errChan
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+	ts := httptest.NewUnstartedServer(srv.mux)
+	srv.server = ts.Config
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/v1/pipelines/test-pipeline", "application/json",
+		strings.NewReader(`{"query": "test query", "stream": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil || !strings.Contains(line, `"type":"chunk"`) {
+		t.Fatalf("expected the first chunk, got %q, %v", line, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("expected the stream to end before the deadline, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected the stream to be given until near the deadline, ended after %v", elapsed)
+	}
+
+	rest, _ := io.ReadAll(resp.Body)
+	got := string(rest)
+	shutdownIdx := strings.Index(got, `{"type":"server_shutting_down","error":"server is shutting down; retry the query"}`)
+	doneIdx := strings.Index(got, `{"type":"done"}`)
+	if shutdownIdx < 0 || doneIdx < shutdownIdx {
+		t.Errorf("expected server_shutting_down then done events, got body: %s", got)
+	}
+}
+
+func TestPipelineEndpoint_StreamingSourcesAndUsageEvents(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 3)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Sources: []pipeline.Source{{ID: "doc-1", Content: "text", Score: 0.9}}}
+			chunkChan <- pipeline.StreamChunk{Content: "hello"}
+			chunkChan <- pipeline.StreamChunk{
+				FinishReason: "stop",
+				Usage: &pipeline.StageUsage{
+					Completion: llmlib.TokenUsage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+				},
+			}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	body := bytes.NewBufferString(`{"query": "test query", "stream": true, "include_sources": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	got := w.Body.String()
+	sourcesIdx := strings.Index(got, `{"type":"sources","sources":[{"id":"doc-1","content":"text","score":0.9}]}`)
+	chunkIdx := strings.Index(got, `{"type":"chunk","content":"hello"}`)
+	usageIdx := strings.Index(got, `{"type":"usage","usage":{"embedding":{"prompt_tokens":0,"completion_tokens":0,"total_tokens":0},`+
+		`"completion":{"prompt_tokens":10,"completion_tokens":2,"total_tokens":12}}}`)
+	doneIdx := strings.Index(got, `{"type":"done",`)
+	if sourcesIdx < 0 || chunkIdx < sourcesIdx || usageIdx < chunkIdx || doneIdx < usageIdx {
+		t.Errorf("expected sources, chunk, usage and done events in order, got body: %s", got)
+	}
+}
+
+func TestPipelineEndpoint_StreamingDoneCarriesCitationsTimingsAndProvenance(t *testing.T) {
+	ttfb := int64(310)
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 2)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "Postgres is a database [1]."}
+			chunkChan <- pipeline.StreamChunk{
+				FinishReason: "stop",
+				Citations: []pipeline.Citation{{
+					Marker: 1, ID: "doc-1", Score: 0.9,
+					Metadata: map[string]interface{}{"url": "https://example.com/1"},
+				}},
+				Timings:    &pipeline.Timings{EmbedMS: 40, SearchMS: 12, TTFBMS: &ttfb, TotalMS: 900},
+				Provenance: []pipeline.DocumentProvenance{{ID: "doc-1", ContentHash: "sha256:ab12"}},
+			}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	body := bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	got := w.Body.String()
+	for _, want := range []string{
+		`"citations":[{"marker":1,"id":"doc-1","score":0.9,"metadata":{"url":"https://example.com/1"}}]`,
+		`"timings":{"embed_ms":40,"search_ms":12,"ttfb_ms":310,"total_ms":900},`,
+		`"provenance":[{"id":"doc-1","content_hash":"sha256:ab12"}]}`,
+	} {
+		if !strings.Contains(got, `{"type":"done",`) || !strings.Contains(got, want) {
+			t.Errorf("expected done event with %s, got body: %s", want, got)
+		}
+	}
+}
+
+func TestSSEFormat(t *testing.T) {
+	// Test that SSE events are properly formatted
+	event := pipeline.StreamEvent{
+		Type:    "chunk",
+		Content: "Hello",
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+
+	sseData := "data: " + string(data) + "\n\n"
+
+	if !strings.HasPrefix(sseData, "data: ") {
+		t.Error("SSE data should start with 'data: '")
+	}
+
+	if !strings.HasSuffix(sseData, "\n\n") {
+		t.Error("SSE data should end with '\\n\\n'")
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	srv := testServer()
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	// Check Content-Type
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type 'application/json', got '%s'", ct)
+	}
+
+	// Check RFC 8631 Link header
+	link := w.Header().Get("Link")
+	if link == "" {
+		t.Error("expected Link header for RFC 8631 API discovery")
+	}
+	if !strings.Contains(link, `rel="service-desc"`) {
+		t.Errorf("Link header should contain rel=\"service-desc\", got '%s'", link)
+	}
+
+	// Verify response is valid OpenAPI spec
+	var spec map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&spec); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	// Check required OpenAPI fields
+	if spec["openapi"] == nil {
+		t.Error("OpenAPI spec missing 'openapi' field")
+	}
+	if spec["info"] == nil {
+		t.Error("OpenAPI spec missing 'info' field")
+	}
+	if spec["paths"] == nil {
+		t.Error("OpenAPI spec missing 'paths' field")
+	}
+	if spec["components"] == nil {
+		t.Error("OpenAPI spec missing 'components' field")
+	}
+
+	// Check version
+	if spec["openapi"] != "3.0.3" {
+		t.Errorf("expected OpenAPI version '3.0.3', got '%v'", spec["openapi"])
+	}
+}
+
+// TestUnknownRoute_ReturnsJSON404 is a regression test for issue #31:
+// requests to unregistered paths must get a structured JSON error, not
+// net/http's default plain-text "404 page not found". Uses the full
+// middleware chain (applyMiddleware), not the raw mux, since that's
+// where routingMiddleware intercepts the mismatch.
+func TestUnknownRoute_ReturnsJSON404(t *testing.T) {
+	srv := testServer()
+	handler := srv.applyMiddleware(srv.mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/this-route-does-not-exist", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", ct)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("response body is not valid JSON: %v", err)
+	}
+	if resp.Error.Code != "NOT_FOUND" {
+		t.Errorf("expected error code NOT_FOUND, got %q", resp.Error.Code)
+	}
+}
+
+// TestMethodNotAllowed_ThroughMiddleware_ReturnsJSON405 is a regression
+// test for issue #31: a registered path hit with the wrong method must
+// get a structured JSON error with a correct Allow header, not net/http's
+// default plain-text "405 Method Not Allowed". net/http's ServeMux
+// intercepts this before any handler runs, so this only exercises the
+// fix when going through the full middleware chain (applyMiddleware),
+// unlike TestHealthEndpoint_MethodNotAllowed above which hits the raw
+// mux and observes net/http's own (also-405, but plain-text) response.
+func TestMethodNotAllowed_ThroughMiddleware_ReturnsJSON405(t *testing.T) {
+	srv := testServer()
+	handler := srv.applyMiddleware(srv.mux)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", ct)
+	}
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "GET") {
+		t.Errorf("expected Allow header to contain GET, got %q", allow)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("response body is not valid JSON: %v", err)
+	}
+	if resp.Error.Code != "METHOD_NOT_ALLOWED" {
+		t.Errorf("expected error code METHOD_NOT_ALLOWED, got %q", resp.Error.Code)
+	}
+}
+
+// TestAllowedMethods_ReflectsRegisteredRoutes checks the mux-probing
+// helper directly against this server's actual registered routes.
+func TestAllowedMethods_ReflectsRegisteredRoutes(t *testing.T) {
+	srv := testServer()
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/pipelines/some-name", nil)
+	allowed := srv.allowedMethods(req)
+
+	if !slices.Equal(allowed, []string{http.MethodGet, http.MethodHead, http.MethodPost}) {
+		t.Errorf("expected GET, HEAD and POST allowed for /v1/pipelines/{name}, got %v", allowed)
+	}
+
+	req2 := httptest.NewRequest(http.MethodDelete, "/no-such-path", nil)
+	if allowed2 := srv.allowedMethods(req2); len(allowed2) != 0 {
+		t.Errorf("expected no allowed methods for an unregistered path, got %v", allowed2)
+	}
+}
+
+// TestAllowedMethods_IncludesImplicitHEAD verifies that a GET-registered
+// route reports HEAD as allowed too, matching net/http.ServeMux's own
+// behavior of implicitly serving HEAD wherever GET is registered — even
+// though only GET appears in routes.go.
+func TestAllowedMethods_IncludesImplicitHEAD(t *testing.T) {
+	srv := testServer()
+
+	req := httptest.NewRequest(http.MethodDelete, "/v1/health", nil)
+	allowed := srv.allowedMethods(req)
+
+	hasGet, hasHead := false, false
+	for _, m := range allowed {
+		if m == http.MethodGet {
+			hasGet = true
+		}
+		if m == http.MethodHead {
+			hasHead = true
+		}
+	}
+	if !hasGet || !hasHead {
+		t.Errorf("expected both GET and HEAD allowed for /v1/health, got %v", allowed)
+	}
+}
+
+// TestAPIVersions_DeprecatedVersionHeaders mounts a deprecated version
+// alongside v1 and checks that only its responses carry the
+// Deprecation, Sunset and Link headers, and that routing errors under
+// it still get structured JSON responses.
+func TestAPIVersions_DeprecatedVersionHeaders(t *testing.T) {
+	srv := testServer()
+	deprecated := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+	srv.mountVersion(apiVersion{
+		name:       "v0",
+		deprecated: deprecated,
+		sunset:     sunset,
+		link:       "https://example.com/migrating-to-v1",
+		routes: func(s *Server, r *versionRouter) {
+			r.HandleFunc("GET /live", s.handleLive)
+		},
+	})
+	handler := srv.applyMiddleware(srv.mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/live", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if got, want := w.Header().Get("Deprecation"), "@1767225600"; got != want {
+		t.Errorf("Deprecation = %q, want %q", got, want)
+	}
+	if got, want := w.Header().Get("Sunset"), "Wed, 01 Jul 2026 00:00:00 GMT"; got != want {
+		t.Errorf("Sunset = %q, want %q", got, want)
+	}
+	links := strings.Join(w.Header().Values("Link"), ", ")
+	if !strings.Contains(links, `<https://example.com/migrating-to-v1>; rel="deprecation"`) {
+		t.Errorf("Link should reference the migration guide, got %q", links)
+	}
+	if !strings.Contains(links, `rel="service-desc"`) {
+		t.Errorf("Link should still reference the service description, got %q", links)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v0/live", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %d, got %d", http.StatusMethodNotAllowed, w.Code)
+	}
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "GET") {
+		t.Errorf("expected Allow header to contain GET, got %q", allow)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/live", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if dep := w.Header().Get("Deprecation"); dep != "" {
+		t.Errorf("current version should not be deprecated, got Deprecation %q", dep)
+	}
+	if sun := w.Header().Get("Sunset"); sun != "" {
+		t.Errorf("current version should not have a sunset, got %q", sun)
+	}
+}
+
+// TestAPIVersions_UnknownVersionedPath checks that an unregistered path
+// under a mounted version gets the structured JSON 404, not the version
+// mux's plain-text one.
+func TestAPIVersions_UnknownVersionedPath(t *testing.T) {
+	srv := testServer()
+	handler := srv.applyMiddleware(srv.mux)
+
+	for _, path := range []string{"/v1/no-such-route", "/v2/health"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusNotFound, w.Code)
+		}
+		var resp ErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("%s: response body is not valid JSON: %v", path, err)
+		}
+		if resp.Error.Code != "NOT_FOUND" {
+			t.Errorf("%s: expected error code NOT_FOUND, got %q", path, resp.Error.Code)
+		}
+	}
+}
+
+// TestPipelineEndpoint_RequestTooLarge is a regression test for issue
+// #31: a request body over maxRequestBodyBytes must be rejected with a
+// structured JSON 413, not silently accepted (previously there was no
+// size limit at all) or surfaced as a generic 400.
+func TestPipelineEndpoint_RequestTooLarge(t *testing.T) {
+	srv := testServer()
+
+	oversized := strings.Repeat("x", maxRequestBodyBytes+1)
+	body := `{"query":"` + oversized + `"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	srv.mux.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected Content-Type application/json, got %q", ct)
+	}
+
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("response body is not valid JSON: %v", err)
+	}
+	if resp.Error.Code != "REQUEST_TOO_LARGE" {
+		t.Errorf("expected error code REQUEST_TOO_LARGE, got %q", resp.Error.Code)
+	}
+}
+
+func TestRFC8631LinkHeader(t *testing.T) {
+	srv := testServer()
+
+	// Test that Link header is present on all API responses
+	endpoints := []struct {
+		method string
+		path   string
+	}{
+		{http.MethodGet, "/v1/live"},
+		{http.MethodGet, "/v1/health"},
+		{http.MethodGet, "/v1/pipelines"},
+		{http.MethodGet, "/v1/stats"},
+		{http.MethodGet, "/v1/openapi.json"},
+	}
+
+	for _, ep := range endpoints {
+		req := httptest.NewRequest(ep.method, ep.path, nil)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, req)
+
+		link := w.Header().Get("Link")
+		if link == "" {
+			t.Errorf("%s %s: missing Link header", ep.method, ep.path)
+			continue
+		}
+		if !strings.Contains(link, "</v1/openapi.json>") {
+			t.Errorf("%s %s: Link header should reference /v1/openapi.json", ep.method, ep.path)
+		}
+		if !strings.Contains(link, `rel="service-desc"`) {
+			t.Errorf("%s %s: Link header should have rel=\"service-desc\"", ep.method, ep.path)
+		}
+	}
+}
+
+// TestIsRequestTimeout_DeadlineExceeded is a regression test for issue
+// #31: isRequestTimeout must report true when a context's own deadline
+// elapsed (the server's request timeout firing).
+func TestIsRequestTimeout_DeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Millisecond)
+	defer cancel()
+	<-ctx.Done()
+
+	if !isRequestTimeout(ctx) {
+		t.Errorf("expected isRequestTimeout to be true after the deadline elapsed, ctx.Err()=%v", ctx.Err())
+	}
+}
+
+// TestIsRequestTimeout_Canceled verifies isRequestTimeout distinguishes
+// its own timeout from an ordinary cancellation (e.g. the client
+// disconnecting), which must NOT be reported as a timeout.
+func TestIsRequestTimeout_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if isRequestTimeout(ctx) {
+		t.Error("expected isRequestTimeout to be false for a plain cancellation, not a deadline")
+	}
+}
+
+// TestIsRequestTimeout_StillRunning verifies isRequestTimeout is false
+// while a context is still active.
+func TestIsRequestTimeout_StillRunning(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Hour)
+	defer cancel()
+
+	if isRequestTimeout(ctx) {
+		t.Error("expected isRequestTimeout to be false for a context that hasn't finished")
+	}
+}
+
+// TestSwapPipelineManager is a regression test for issue #30 (config/
+// secret hot-reload): swapping the active PipelineManager must both
+// return the previous one (so the caller can close it) and make
+// subsequent requests observe the new one, with no restart required.
+func TestSwapPipelineManager(t *testing.T) {
+	srv := testServer()
+
+	// Confirm the original manager is in effect.
+	req := httptest.NewRequest(http.MethodGet, "/v1/pipelines", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var resp PipelinesResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Pipelines) != 1 || resp.Pipelines[0].Name != "test-pipeline" {
+		t.Fatalf("expected the original pipeline before swap, got %+v", resp.Pipelines)
+	}
+
+	newPM := &mockPipelineManager{
+		pipelines: map[string]*mockPipelineInfo{
+			"reloaded-pipeline": {name: "reloaded-pipeline", description: "after reload"},
+		},
+	}
+	oldPM := srv.SwapPipelineManager(newPM)
+	if oldPM == nil {
+		t.Fatal("expected SwapPipelineManager to return the previous manager, got nil")
+	}
+
+	// Subsequent requests must observe the new manager, with no restart.
+	req2 := httptest.NewRequest(http.MethodGet, "/v1/pipelines", nil)
+	w2 := httptest.NewRecorder()
 	srv.mux.ServeHTTP(w2, req2)
 	var resp2 PipelinesResponse
 	if err := json.NewDecoder(w2.Body).Decode(&resp2); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
-	if len(resp2.Pipelines) != 1 || resp2.Pipelines[0].Name != "reloaded-pipeline" {
-		t.Fatalf("expected the reloaded pipeline after swap, got %+v", resp2.Pipelines)
+	if len(resp2.Pipelines) != 1 || resp2.Pipelines[0].Name != "reloaded-pipeline" {
+		t.Fatalf("expected the reloaded pipeline after swap, got %+v", resp2.Pipelines)
+	}
+}
+
+func metricsTestServer(metricsCfg config.MetricsConfig, reg *metrics.Registry) *Server {
+	cfg := testConfig()
+	cfg.Server.Metrics = metricsCfg
+	return New(cfg, newMockPipelineManager(), nil, WithMetrics(reg))
+}
+
+func TestMetricsEndpoint_SharedListener(t *testing.T) {
+	reg := metrics.NewRegistry()
+	srv := metricsTestServer(config.MetricsConfig{Enabled: true, Path: "/metrics"}, reg)
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{}
+
+	body := bytes.NewBufferString(`{"query": "hello"}`)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+	if w.Code != http.StatusOK {
+		t.Fatalf("query: expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("metrics: expected 200, got %d", w.Code)
+	}
+	want := `pgedge_rag_requests_total{pipeline="test-pipeline",status="ok"} 1`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("metrics missing %q\n%s", want, w.Body.String())
+	}
+}
+
+// recordedQuery is a query told to a fakeQueryRecorder.
+type recordedQuery struct {
+	pipeline, query string
+	failed          bool
+}
+
+// fakeQueryRecorder keeps the queries it is told of.
+type fakeQueryRecorder struct {
+	queries []recordedQuery
+}
+
+func (f *fakeQueryRecorder) RecordQuery(pipeline, query string, failed bool) {
+	f.queries = append(f.queries, recordedQuery{pipeline, query, failed})
+}
+
+func TestQueryRecorder(t *testing.T) {
+	rec := &fakeQueryRecorder{}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			if req.Query == "fail" {
+				return nil, fmt.Errorf("completion failed")
+			}
+			return &pipeline.QueryResponse{Answer: "ok"}, nil
+		},
+	}
+	srv := New(testConfig(), pm, nil, WithQueryRecorder(rec))
+
+	for _, query := range []string{"hello", "fail"} {
+		body := bytes.NewBufferString(`{"query": "` + query + `"}`)
+		srv.mux.ServeHTTP(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+	}
+
+	want := []recordedQuery{{"test-pipeline", "hello", false}, {"test-pipeline", "fail", true}}
+	if !slices.Equal(rec.queries, want) {
+		t.Errorf("expected %v recorded, got %v", want, rec.queries)
+	}
+}
+
+func TestExperimentRouting(t *testing.T) {
+	reg := metrics.NewRegistry()
+	rec := &fakeQueryRecorder{}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].variant = "test-pipeline-b"
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{}
+	pm.pipelines["test-pipeline-b"] = &mockPipelineInfo{
+		name: "test-pipeline-b",
+		executor: &mockQueryExecutor{
+			ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+				return &pipeline.QueryResponse{Answer: "variant answer"}, nil
+			},
+		},
+	}
+	pm.pipelines["other"] = &mockPipelineInfo{name: "other", executor: &mockQueryExecutor{}}
+	srv := New(testConfig(), pm, nil, WithMetrics(reg), WithQueryRecorder(rec))
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		strings.NewReader(`{"query": "hello"}`)))
+	var resp pipeline.QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if w.Code != http.StatusOK || resp.Answer != "variant answer" || resp.Variant != "test-pipeline-b" ||
+		w.Header().Get(variantHeader) != "test-pipeline-b" {
+		t.Errorf("expected the query answered by the variant and tagged with it, got %d %+v, header %q",
+			w.Code, resp, w.Header().Get(variantHeader))
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		strings.NewReader(`{"query": "hello", "stream": true}`)))
+	if !strings.Contains(w.Body.String(), `{"type":"done","variant":"test-pipeline-b"}`) ||
+		w.Header().Get(variantHeader) != "test-pipeline-b" {
+		t.Errorf("expected the streamed answer's done event tagged with the variant, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/other",
+		strings.NewReader(`{"query": "hello"}`)))
+	if w.Header().Get(variantHeader) != "" || strings.Contains(w.Body.String(), "variant") {
+		t.Errorf("expected a pipeline without an experiment left untagged, got %q", w.Body.String())
+	}
+
+	var out strings.Builder
+	if _, err := reg.WriteTo(&out); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	for _, want := range []string{
+		`pgedge_rag_experiment_assignments_total{pipeline="test-pipeline",variant="test-pipeline-b"} 2`,
+		`pgedge_rag_requests_total{pipeline="test-pipeline-b",status="ok"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), `pgedge_rag_requests_total{pipeline="test-pipeline",`) {
+		t.Errorf("expected the variant's queries recorded as its own\n%s", out.String())
+	}
+	if len(rec.queries) != 3 || rec.queries[0].pipeline != "test-pipeline-b" {
+		t.Errorf("expected the variant's queries recorded as its own, got %v", rec.queries)
+	}
+}
+
+func TestMetricsEndpoint_NotRegisteredWhenDisabled(t *testing.T) {
+	srv := metricsTestServer(config.MetricsConfig{Path: "/metrics"}, metrics.NewRegistry())
+
+	w := httptest.NewRecorder()
+	srv.applyMiddleware(srv.mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 with metrics disabled, got %d", w.Code)
+	}
+}
+
+func TestMetricsEndpoint_DedicatedPortNotOnAPIMux(t *testing.T) {
+	srv := metricsTestServer(config.MetricsConfig{
+		Enabled: true, Path: "/metrics", ListenAddress: "127.0.0.1", Port: 9090,
+	}, metrics.NewRegistry())
+
+	w := httptest.NewRecorder()
+	srv.applyMiddleware(srv.mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 on the API listener when a dedicated metrics port is set, got %d", w.Code)
+	}
+}
+
+func sessionsTestServer(store session.Store) *Server {
+	cfg := testConfig()
+	cfg.Sessions = config.SessionsConfig{Enabled: true, MaxHistoryTokens: 2000}
+	return New(cfg, newMockPipelineManager(), nil, WithSessions(store))
+}
+
+func createTestSession(t *testing.T, srv *Server, pipelineName string) session.Session {
+	t.Helper()
+	body := bytes.NewBufferString(`{"pipeline": "` + pipelineName + `"}`)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/sessions", body))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create session: expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var sess session.Session
+	if err := json.NewDecoder(w.Body).Decode(&sess); err != nil {
+		t.Fatalf("failed to decode session: %v", err)
+	}
+	return sess
+}
+
+func TestSessions_QueryIncludesAndRecordsHistory(t *testing.T) {
+	store := session.NewMemoryStore(time.Hour, 0)
+	srv := sessionsTestServer(store)
+
+	var seen [][]pipeline.Message
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			seen = append(seen, req.Messages)
+			return &pipeline.QueryResponse{Answer: "answer to " + req.Query}, nil
+		},
+	}
+
+	sess := createTestSession(t, srv, "test-pipeline")
+
+	for _, q := range []string{"first", "second"} {
+		body := bytes.NewBufferString(`{"query": "` + q + `", "session_id": "` + sess.ID + `"}`)
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+		if w.Code != http.StatusOK {
+			t.Fatalf("query %q: expected 200, got %d: %s", q, w.Code, w.Body.String())
+		}
+	}
+
+	if len(seen[0]) != 0 {
+		t.Errorf("expected no history on the first query, got %+v", seen[0])
+	}
+	want := []pipeline.Message{
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "answer to first"},
+	}
+	if len(seen[1]) != 2 || seen[1][0] != want[0] || seen[1][1] != want[1] {
+		t.Errorf("second query history = %+v, want %+v", seen[1], want)
+	}
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/sessions/"+sess.ID, nil))
+	var got session.Session
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode session: %v", err)
+	}
+	if len(got.Messages) != 4 {
+		t.Errorf("expected 4 recorded messages, got %d", len(got.Messages))
+	}
+}
+
+func TestSessions_StreamingRecordsAnswer(t *testing.T) {
+	store := session.NewMemoryStore(time.Hour, 0)
+	srv := sessionsTestServer(store)
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 2)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "Hello, "}
+			chunkChan <- pipeline.StreamChunk{Content: "world"}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+
+	sess := createTestSession(t, srv, "test-pipeline")
+
+	body := bytes.NewBufferString(`{"query": "hi", "stream": true, "session_id": "` + sess.ID + `"}`)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+
+	got, err := store.Get(context.Background(), sess.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].Content != "Hello, world" {
+		t.Errorf("expected streamed answer to be recorded, got %+v", got.Messages)
+	}
+}
+
+func TestSessions_QueryErrors(t *testing.T) {
+	store := session.NewMemoryStore(time.Hour, 0)
+	srv := sessionsTestServer(store)
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{}
+	other, _ := store.Create(context.Background(), "other-pipeline")
+
+	tests := []struct {
+		name      string
+		sessionID string
+		wantCode  int
+		wantError string
+	}{
+		{"unknown session", "does-not-exist", http.StatusNotFound, "SESSION_NOT_FOUND"},
+		{"session for another pipeline", other.ID, http.StatusBadRequest, "INVALID_REQUEST"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := bytes.NewBufferString(`{"query": "q", "session_id": "` + tt.sessionID + `"}`)
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+
+			if w.Code != tt.wantCode {
+				t.Errorf("expected status %d, got %d", tt.wantCode, w.Code)
+			}
+			var resp ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Error.Code != tt.wantError {
+				t.Errorf("expected error code %s, got %q", tt.wantError, resp.Error.Code)
+			}
+		})
+	}
+}
+
+func TestSessions_CreateUnknownPipeline(t *testing.T) {
+	srv := sessionsTestServer(session.NewMemoryStore(time.Hour, 0))
+
+	body := bytes.NewBufferString(`{"pipeline": "nope"}`)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/sessions", body))
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestSessions_Delete(t *testing.T) {
+	srv := sessionsTestServer(session.NewMemoryStore(time.Hour, 0))
+	sess := createTestSession(t, srv, "test-pipeline")
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/sessions/"+sess.ID, nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/sessions/"+sess.ID, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after delete, got %d", w.Code)
+	}
+}
+
+func TestSessions_DisabledRejectsSessionID(t *testing.T) {
+	srv := testServer()
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{}
+
+	body := bytes.NewBufferString(`{"query": "q", "session_id": "abc"}`)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/sessions", bytes.NewBufferString(`{}`)))
+	if w.Code == http.StatusCreated {
+		t.Error("expected /v1/sessions to be unavailable when sessions are disabled")
+	}
+}
+
+func resumeTestServer(chunks ...string) *Server {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, len(chunks))
+			errChan := make(chan error, 1)
+			for _, c := range chunks {
+				chunkChan <- pipeline.StreamChunk{Content: c}
+			}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+	cfg := testConfig()
+	cfg.Server.StreamResume = config.StreamResumeConfig{Enabled: true, Window: config.Duration(time.Minute)}
+	return New(cfg, pm, nil)
+}
+
+func streamRequest(ctx context.Context, lastEventID string) *http.Request {
+	body := bytes.NewBufferString(`{"query": "test query", "stream": true}`)
+	req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", body).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	return req
+}
+
+// sseIDs returns the id: fields of an SSE body, in order.
+func sseIDs(body string) []string {
+	var ids []string
+	for _, line := range strings.Split(body, "\n") {
+		if id, ok := strings.CutPrefix(line, "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestStreamResume_ReplaysMissedEvents(t *testing.T) {
+	srv := resumeTestServer("Hello, ", "world")
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, streamRequest(context.Background(), ""))
+
+	ids := sseIDs(w.Body.String())
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 event IDs (2 chunks and done), got %v in body: %s", ids, w.Body.String())
+	}
+	streamID, _, ok := parseLastEventID(ids[0])
+	if !ok || ids[0] != streamID+"-1" || ids[2] != streamID+"-3" {
+		t.Fatalf("unexpected event IDs: %v", ids)
+	}
+
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, streamRequest(context.Background(), ids[0]))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	got := w.Body.String()
+	if strings.Contains(got, "Hello, ") {
+		t.Errorf("expected the already-received chunk to be skipped, got body: %s", got)
+	}
+	if !strings.Contains(got, `"content":"world"`) || !strings.Contains(got, `"type":"done"`) {
+		t.Errorf("expected the remaining chunk and done event, got body: %s", got)
+	}
+	if resumed := sseIDs(got); len(resumed) != 2 || resumed[0] != ids[1] {
+		t.Errorf("expected resumed IDs %v, got %v", ids[1:], resumed)
+	}
+}
+
+func TestStreamResume_ContinuesAfterDisconnect(t *testing.T) {
+	srv := resumeTestServer("complete answer")
+
+	// The client is gone before any event is written.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	srv.mux.ServeHTTP(httptest.NewRecorder(), streamRequest(ctx, ""))
+
+	srv.streams.mu.Lock()
+	var streamID string
+	for id := range srv.streams.streams {
+		streamID = id
+	}
+	srv.streams.mu.Unlock()
+	if streamID == "" {
+		t.Fatal("expected the stream to be registered")
+	}
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, streamRequest(context.Background(), streamID+"-0"))
+	got := w.Body.String()
+	if !strings.Contains(got, `"content":"complete answer"`) || !strings.Contains(got, `"type":"done"`) {
+		t.Errorf("expected the full answer on resume, got body: %s", got)
+	}
+}
+
+func TestStreamResume_UnknownOrExpiredStream(t *testing.T) {
+	srv := resumeTestServer("answer")
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, streamRequest(context.Background(), ""))
+	first := sseIDs(w.Body.String())[0]
+	streamID, _, _ := parseLastEventID(first)
+
+	for name, lastEventID := range map[string]string{
+		"unknown":         "0123456789abcdef-1",
+		"malformed":       "not-an-id",
+		"past the buffer": streamID + "-99",
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			srv.mux.ServeHTTP(w, streamRequest(context.Background(), lastEventID))
+			if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "STREAM_NOT_FOUND") {
+				t.Errorf("expected 404 STREAM_NOT_FOUND, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("expired", func(t *testing.T) {
+		srv.streams.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+		w := httptest.NewRecorder()
+		srv.mux.ServeHTTP(w, streamRequest(context.Background(), first))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("expected 404 after the resume window, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestStreamResume_DisabledOmitsEventIDs(t *testing.T) {
+	srv := resumeTestServer("answer")
+	srv.streams = nil // as New leaves it when stream_resume is disabled
+
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, streamRequest(context.Background(), "abc-1"))
+
+	if ids := sseIDs(w.Body.String()); len(ids) != 0 {
+		t.Errorf("expected no event IDs with resumption disabled, got %v", ids)
+	}
+	if !strings.Contains(w.Body.String(), `"type":"done"`) {
+		t.Errorf("expected Last-Event-ID to be ignored and a normal stream served, got body: %s", w.Body.String())
+	}
+}
+
+// h2cClient speaks HTTP/2 over plaintext with prior knowledge.
+func h2cClient() *http.Client {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+}
+
+func TestProtocols_H2CServesStreams(t *testing.T) {
+	srv := resumeTestServer("multiplexed")
+	srv.streams = nil
+	srv.config.Server.HTTP2 = config.HTTP2Config{Enabled: true, H2C: true}
+
+	ts := httptest.NewUnstartedServer(srv.applyMiddleware(srv.mux))
+	ts.Config.Protocols = srv.protocols()
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := h2cClient().Post(ts.URL+"/v1/pipelines/test-pipeline", "application/json",
+		strings.NewReader(`{"query": "test query", "stream": true}`))
+	if err != nil {
+		t.Fatalf("h2c request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2, got %s", resp.Proto)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `"content":"multiplexed"`) || !strings.Contains(string(body), `"type":"done"`) {
+		t.Errorf("expected a complete SSE stream, got body: %s", body)
+	}
+}
+
+func TestProtocols_H2CDisabledByDefault(t *testing.T) {
+	srv := testServer()
+	srv.config.Server.HTTP2 = config.DefaultConfig().Server.HTTP2
+
+	ts := httptest.NewUnstartedServer(srv.applyMiddleware(srv.mux))
+	ts.Config.Protocols = srv.protocols()
+	ts.Start()
+	defer ts.Close()
+
+	if _, err := h2cClient().Get(ts.URL + "/v1/pipelines"); err == nil {
+		t.Error("expected a prior-knowledge h2c request to fail without server.http2.h2c")
+	}
+}
+
+// TestRateLimiter verifies a bucket admits a minute's allowance in a
+// burst, refills continuously, and lets a token overdraft hold later
+// requests back until it has refilled.
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+	limits := []rateLimit{{key: "caller", limits: config.RateLimitConfig{RequestsPerMinute: 2, TokensPerMinute: 100}}}
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.allow(limits); !ok {
+			t.Fatalf("expected request %d to be admitted", i+1)
+		}
+	}
+	wait, ok := l.allow(limits)
+	if ok || wait != 30*time.Second {
+		t.Fatalf("expected the third request to wait 30s, got %v, %v", wait, ok)
+	}
+	now = now.Add(30 * time.Second)
+	if _, ok := l.allow(limits); !ok {
+		t.Fatal("expected a request to be admitted once the bucket refilled")
+	}
+
+	now = now.Add(time.Minute)
+	l.charge(limits, 150)
+	if wait, ok := l.allow(limits); ok || wait != 30600*time.Millisecond {
+		t.Errorf("expected the token overdraft to wait 30.6s, got %v, %v", wait, ok)
+	}
+
+	other := []rateLimit{{key: "other", limits: limits[0].limits}}
+	if _, ok := l.allow(other); !ok {
+		t.Error("expected another caller's bucket to be separate")
+	}
+}
+
+// TestRateLimit verifies the pipeline routes reject a caller over the
+// server's or the pipeline's limits with 429 and Retry-After, that a
+// caller cannot escape its address's limits by varying its key, that
+// server.rate_limit.keys overrides the limits of a key, and that the
+// tokens a query uses count against its caller.
+func TestRateLimit(t *testing.T) {
+	pipelineLimit := config.RateLimitConfig{}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return &pipeline.QueryResponse{
+				Answer: "mock answer",
+				Usage:  &pipeline.StageUsage{Completion: llmlib.TokenUsage{TotalTokens: 500}},
+			}, nil
+		},
+		CapabilitiesFunc: func() pipeline.Capabilities {
+			return pipeline.Capabilities{Name: "test-pipeline", RateLimit: pipelineLimit}
+		},
+	}
+	query := func(srv *Server, addr, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", bytes.NewBufferString(`{"query": "q"}`))
+		req.RemoteAddr = addr + ":1234"
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
+
+	cfg := testConfig()
+	cfg.Server.RateLimit = config.ServerRateLimitConfig{
+		RateLimitConfig: config.RateLimitConfig{RequestsPerMinute: 1},
+		Key:             "{{header:X-API-Key}}",
+		Keys:            map[string]config.RateLimitConfig{"batch": {TokensPerMinute: 400}},
+	}
+	srv := New(cfg, pm, nil)
+
+	if w := query(srv, "192.0.2.1", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	w := query(srv, "192.0.2.1", "alice")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "60" ||
+		!strings.Contains(w.Body.String(), "RATE_LIMITED") {
+		t.Errorf("expected 429 with Retry-After: 60, got %d %q: %s",
+			w.Code, w.Header().Get("Retry-After"), w.Body.String())
+	}
+	if w := query(srv, "192.0.2.1", "mallory"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a new key from the same address to be limited by address, got %d", w.Code)
+	}
+	if w := query(srv, "192.0.2.2", "alice"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a key to keep its limit from another address, got %d", w.Code)
+	}
+	if w := query(srv, "192.0.2.3", "bob"); w.Code != http.StatusOK {
+		t.Errorf("expected another key and address to have their own limits, got %d", w.Code)
+	}
+	if w := query(srv, "192.0.2.4", ""); w.Code != http.StatusOK {
+		t.Errorf("expected a request without a key to be limited by address, got %d", w.Code)
+	}
+
+	// The batch key has no request limit, but its first query's 500
+	// tokens overdraw its 400 a minute.
+	if w := query(srv, "192.0.2.5", "batch"); w.Code != http.StatusOK {
+		t.Fatalf("expected the batch key's first request to succeed, got %d", w.Code)
+	}
+	if w := query(srv, "192.0.2.6", "batch"); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "16" {
+		t.Errorf("expected the token overdraft to be retried after 16s, got %d %q",
+			w.Code, w.Header().Get("Retry-After"))
+	}
+
+	pipelineLimit = config.RateLimitConfig{RequestsPerMinute: 1}
+	srv = New(testConfig(), pm, nil)
+	if w := query(srv, "192.0.2.1", ""); w.Code != http.StatusOK {
+		t.Fatalf("expected the first request to succeed, got %d", w.Code)
+	}
+	if w := query(srv, "192.0.2.1", ""); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the pipeline's limit to apply, got %d", w.Code)
+	}
+}
+
+// TestRateLimit_RetrieveTokens verifies the tokens a retrieval used
+// count against the caller's token limit.
+func TestRateLimit_RetrieveTokens(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		RetrieveFunc: func(ctx context.Context, req pipeline.RetrieveRequest) (*pipeline.RetrieveResponse, error) {
+			return &pipeline.RetrieveResponse{
+				Documents: []pipeline.RetrievedDocument{},
+				Usage:     &pipeline.StageUsage{QueryExpansion: &llmlib.TokenUsage{TotalTokens: 500}},
+			}, nil
+		},
+	}
+	cfg := testConfig()
+	cfg.Server.RateLimit = config.ServerRateLimitConfig{
+		RateLimitConfig: config.RateLimitConfig{TokensPerMinute: 400},
+	}
+	srv := New(cfg, pm, nil)
+	retrieve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline/retrieve",
+			bytes.NewBufferString(`{"query": "q"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
+
+	if w := retrieve(); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"usage"`) {
+		t.Fatalf("expected the first retrieval to succeed with its usage, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := retrieve(); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the retrieval's tokens to exhaust the limit, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestRateLimit_LastEventID verifies a Last-Event-ID header does not
+// lift the rate limits of a request that resumes no stream.
+func TestRateLimit_LastEventID(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.StreamResume = config.StreamResumeConfig{Enabled: true, Window: config.Duration(time.Minute)}
+	cfg.Server.RateLimit = config.ServerRateLimitConfig{
+		RateLimitConfig: config.RateLimitConfig{RequestsPerMinute: 1},
+	}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{}
+	srv := New(cfg, pm, nil)
+	search := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline/search",
+			bytes.NewBufferString(`{"query": "q"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Last-Event-ID", "x")
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
+
+	if w := search(); w.Code != http.StatusOK {
+		t.Fatalf("expected the first search to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := search(); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected a bogus Last-Event-ID to be rate limited, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRateLimit_CreateSession(t *testing.T) {
+	cfg := testConfig()
+	cfg.Sessions = config.SessionsConfig{Enabled: true}
+	cfg.Server.RateLimit = config.ServerRateLimitConfig{
+		RateLimitConfig: config.RateLimitConfig{RequestsPerMinute: 1},
+	}
+	srv := New(cfg, newMockPipelineManager(), nil, WithSessions(session.NewMemoryStore(time.Hour, 0)))
+	create := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/sessions",
+			bytes.NewBufferString(`{"pipeline": "test-pipeline"}`)))
+		return w
+	}
+
+	if w := create(); w.Code != http.StatusCreated {
+		t.Fatalf("expected the first session to be created, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := create(); w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected creating sessions to be rate limited, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	budgetErr := &pipeline.BudgetExceededError{
+		Period: pipeline.BudgetPeriodDay,
+		Budget: 5,
+		Resets: time.Now().Add(90 * time.Second),
+	}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return nil, budgetErr
+		},
+		RetrieveFunc: func(ctx context.Context, req pipeline.RetrieveRequest) (*pipeline.RetrieveResponse, error) {
+			return nil, fmt.Errorf("retrieval refused: %w", budgetErr)
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	for _, path := range []string{"/v1/pipelines/test-pipeline", "/v1/pipelines/test-pipeline/retrieve"} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"query": "q"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+
+		retryAfter, _ := strconv.Atoi(w.Header().Get("Retry-After"))
+		if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), "BUDGET_EXCEEDED") ||
+			retryAfter < 89 || retryAfter > 90 {
+			t.Errorf("%s: expected 429 BUDGET_EXCEEDED with Retry-After: 90, got %d %q: %s",
+				path, w.Code, w.Header().Get("Retry-After"), w.Body.String())
+		}
+	}
+}
+
+func TestProviderBusy(t *testing.T) {
+	busy := fmt.Errorf("failed to generate completion: %w", ragllm.ErrProviderBusy)
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return nil, busy
+		},
+		RetrieveFunc: func(ctx context.Context, req pipeline.RetrieveRequest) (*pipeline.RetrieveResponse, error) {
+			return nil, busy
+		},
+	}
+	srv := New(testConfig(), pm, nil)
+
+	for _, path := range []string{"/v1/pipelines/test-pipeline", "/v1/pipelines/test-pipeline/retrieve"} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(`{"query": "q"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+
+		if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "PROVIDER_BUSY") ||
+			w.Header().Get("Retry-After") != providerBusyRetryAfter {
+			t.Errorf("%s: expected 503 PROVIDER_BUSY with Retry-After, got %d %q: %s",
+				path, w.Code, w.Header().Get("Retry-After"), w.Body.String())
+		}
+	}
+}
+
+// TestAdminEndpoints_SharedListener verifies the admin endpoints on the
+// API listener require the admin token, and that the reload endpoint
+// reports a failed reload.
+func TestAdminEndpoints_SharedListener(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "admin-token")
+	if err := os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true, TokenFile: tokenFile}
+	var reloadErr error
+	reloads := 0
+	srv := New(cfg, newMockPipelineManager(), nil, WithReload(func() error {
+		reloads++
+		return reloadErr
+	}))
+	admin := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		w := admin(http.MethodGet, "/v1/admin/usage", token)
+		if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("token %q: expected 401 with WWW-Authenticate, got %d", token, w.Code)
+		}
+	}
+	if w := admin(http.MethodGet, "/v1/admin/usage", "s3cret"); w.Code != http.StatusOK ||
+		!strings.Contains(w.Body.String(), "test-pipeline") {
+		t.Errorf("usage: expected 200 listing the pipeline, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := admin(http.MethodGet, "/v1/admin/pipelines", "s3cret"); w.Code != http.StatusOK {
+		t.Errorf("pipelines: expected 200, got %d", w.Code)
+	}
+
+	w := admin(http.MethodPost, "/v1/admin/reload", "s3cret")
+	var resp ReloadResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK ||
+		resp.Status != "reloaded" || resp.Pipelines != 1 || reloads != 1 {
+		t.Errorf("reload: expected 200 with 1 pipeline, got %d %+v (%d reloads)", w.Code, resp, reloads)
+	}
+	reloadErr = fmt.Errorf("failed to load configuration: bad yaml")
+	if w := admin(http.MethodPost, "/v1/admin/reload", "s3cret"); w.Code != http.StatusInternalServerError ||
+		!strings.Contains(w.Body.String(), "RELOAD_FAILED") {
+		t.Errorf("failed reload: expected 500 RELOAD_FAILED, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// TestAdminEndpoints_DedicatedListener verifies the admin endpoints are
+// served on their own listener, without a token when none is set, and
+// never on the API listener.
+func TestAdminEndpoints_DedicatedListener(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true, ListenAddress: "127.0.0.1", Port: 1}
+	srv := New(cfg, newMockPipelineManager(), nil)
+
+	w := httptest.NewRecorder()
+	srv.applyMiddleware(srv.mux).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/usage", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 on the API listener when a dedicated admin port is set, got %d", w.Code)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.config.Server.Admin.Port = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	if err := srv.startAdminListener(); err != nil {
+		t.Fatalf("failed to start the admin listener: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d/v1/admin/usage", srv.config.Server.Admin.Port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 from the admin listener, got %d", resp.StatusCode)
+	}
+}
+
+// TestReplayEndpoints verifies an answered query is recorded under its
+// request ID and can be looked up and replayed with the same request
+// and filter variables.
+func TestReplayEndpoints(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.Admin = config.AdminConfig{Enabled: true}
+	cfg.Server.Replay = config.ReplayConfig{Enabled: true, MaxRequests: 2}
+	pm := newMockPipelineManager()
+	calls := 0
+	var gotReq pipeline.QueryRequest
+	var gotTenant string
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			calls++
+			gotReq = req
+			gotTenant, _ = database.FilterVarsFrom(ctx).Lookup(
+				config.FilterVariable{Source: config.FilterVarHeader, Name: "X-Tenant"})
+			return &pipeline.QueryResponse{Answer: fmt.Sprintf("answer %d", calls)}, nil
+		},
+	}
+	srv := New(cfg, pm, nil)
+	do := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		maps.Copy(req.Header, header)
+		w := httptest.NewRecorder()
+		srv.applyMiddleware(srv.mux).ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/v1/pipelines/test-pipeline", `{"query": "What is pgEdge?", "seed": 42}`,
+		http.Header{"X-Request-Id": {"req-1"}, "X-Tenant": {"acme"}, "Authorization": {"Bearer t"}})
+	if w.Code != http.StatusOK || w.Header().Get("X-Request-ID") != "req-1" {
+		t.Fatalf("expected 200 with the client's request ID, got %d %q", w.Code, w.Header().Get("X-Request-ID"))
+	}
+
+	w = do(http.MethodGet, "/v1/admin/requests/req-1", "", nil)
+	var rec ReplayRecord
+	if err := json.NewDecoder(w.Body).Decode(&rec); err != nil || w.Code != http.StatusOK ||
+		rec.Pipeline != "test-pipeline" || rec.Request.Query != "What is pgEdge?" || rec.Answer != "answer 1" {
+		t.Errorf("expected the recorded query, got %d %+v", w.Code, rec)
+	}
+	if strings.Contains(w.Body.String(), "Bearer") {
+		t.Errorf("expected the record to leave out the request's credentials, got %s", w.Body.String())
+	}
+
+	gotTenant = ""
+	w = do(http.MethodPost, "/v1/admin/requests/req-1/replay", "", nil)
+	var resp ReplayResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("replay: expected 200, got %d: %v", w.Code, err)
+	}
+	if resp.Original.Answer != "answer 1" || resp.Replay.Answer != "answer 2" || resp.SameAnswer {
+		t.Errorf("expected the original and differing replayed answers, got %+v", resp)
+	}
+	if gotReq.Query != "What is pgEdge?" || gotReq.Seed == nil || *gotReq.Seed != 42 || gotTenant != "acme" {
+		t.Errorf("expected the replay to reuse the query, seed and filter variables, got %+v tenant %q",
+			gotReq, gotTenant)
+	}
+
+	// Replays are not recorded, and the oldest query is evicted beyond
+	// max_requests.
+	for range 2 {
+		w := do(http.MethodPost, "/v1/pipelines/test-pipeline", `{"query": "Another question"}`, nil)
+		if id := w.Header().Get("X-Request-ID"); len(id) != 32 {
+			t.Errorf("expected a generated request ID, got %q", id)
+		}
+	}
+	for _, path := range []string{"/v1/admin/requests/req-1", "/v1/admin/requests/req-1/replay"} {
+		method := http.MethodGet
+		if strings.HasSuffix(path, "/replay") {
+			method = http.MethodPost
+		}
+		if w := do(method, path, "", nil); w.Code != http.StatusNotFound ||
+			!strings.Contains(w.Body.String(), "REQUEST_NOT_FOUND") {
+			t.Errorf("%s %s: expected 404 REQUEST_NOT_FOUND, got %d", method, path, w.Code)
+		}
+	}
+}
+
+// grpcTestClient serves srv's gRPC API over an in-memory listener and
+// returns a client of it.
+func grpcTestClient(t *testing.T, srv *Server) ragv1.RAGServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	gs := srv.newGRPCServer()
+	go func() { _ = gs.Serve(listener) }()
+	t.Cleanup(gs.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return ragv1.NewRAGServiceClient(conn)
+}
+
+func TestGRPC_Query(t *testing.T) {
+	var gotReq pipeline.QueryRequest
+	var gotTenant string
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			gotReq = req
+			gotTenant, _ = database.FilterVarsFrom(ctx).Lookup(config.FilterVariable{
+				Source: config.FilterVarHeader, Name: "X-Tenant"})
+			if req.Query == "bad" {
+				return nil, fmt.Errorf("%w: unknown filter_preset", pipeline.ErrInvalidRequest)
+			}
+			return &pipeline.QueryResponse{
+				Answer:  "Use pg_basebackup.",
+				Sources: []pipeline.Source{{ID: "doc-1", Content: "...", Score: 0.9, Metadata: map[string]interface{}{"page": 4}}},
+				Usage:   &pipeline.StageUsage{Completion: llmlib.TokenUsage{TotalTokens: 12}},
+			}, nil
+		},
+	}
+	client := grpcTestClient(t, New(testConfig(), pm, nil))
+
+	filter, err := structpb.NewStruct(map[string]interface{}{
+		"conditions": []interface{}{map[string]interface{}{"column": "product", "operator": "=", "value": "pgedge"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "acme")
+	resp, err := client.Query(ctx, &ragv1.QueryRequest{
+		Pipeline: "test-pipeline", Query: "How do I take a backup?", TopN: 3, Filter: filter,
+		IncludeSources: true, Messages: []*ragv1.Message{{Role: "user", Content: "Hi"}},
+		LogitBias: map[string]int32{"50256": -100},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Answer != "Use pg_basebackup." || len(resp.Sources) != 1 ||
+		resp.Sources[0].Metadata.Fields["page"].GetNumberValue() != 4 ||
+		resp.Usage.GetCompletion().GetTotalTokens() != 12 {
+		t.Errorf("unexpected response %v", resp)
+	}
+	if gotReq.TopN != 3 || !gotReq.IncludeSources || len(gotReq.Messages) != 1 ||
+		gotReq.LogitBias["50256"] != -100 || gotReq.Filter == nil || len(gotReq.Filter.Conditions) != 1 ||
+		gotReq.Filter.Conditions[0].Column != "product" {
+		t.Errorf("unexpected query %+v", gotReq)
+	}
+	if gotTenant != "acme" {
+		t.Errorf("expected the tenant header from metadata, got %q", gotTenant)
+	}
+
+	for _, tt := range []struct {
+		req  *ragv1.QueryRequest
+		code codes.Code
+	}{
+		{&ragv1.QueryRequest{Pipeline: "missing", Query: "q"}, codes.NotFound},
+		{&ragv1.QueryRequest{Pipeline: "test-pipeline"}, codes.InvalidArgument},
+		{&ragv1.QueryRequest{Pipeline: "test-pipeline", Query: "bad"}, codes.InvalidArgument},
+	} {
+		if _, err := client.Query(context.Background(), tt.req); status.Code(err) != tt.code {
+			t.Errorf("expected %s for %v, got %v", tt.code, tt.req, err)
+		}
+	}
+}
+
+func TestGRPC_QuerySession(t *testing.T) {
+	store := session.NewMemoryStore(time.Hour, 0)
+	srv := sessionsTestServer(store)
+
+	var seen [][]pipeline.Message
+	srv.pipelineManager().(*mockPipelineManager).pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			seen = append(seen, req.Messages)
+			return &pipeline.QueryResponse{Answer: "answer to " + req.Query}, nil
+		},
+	}
+	client := grpcTestClient(t, srv)
+	sess := createTestSession(t, srv, "test-pipeline")
+
+	for _, q := range []string{"first", "second"} {
+		if _, err := client.Query(context.Background(), &ragv1.QueryRequest{
+			Pipeline: "test-pipeline", Query: q, SessionId: sess.ID,
+		}); err != nil {
+			t.Fatalf("query %q: unexpected error: %v", q, err)
+		}
+	}
+	if len(seen) != 2 || len(seen[0]) != 0 || len(seen[1]) != 2 ||
+		seen[1][0].Content != "first" || seen[1][1].Content != "answer to first" {
+		t.Errorf("expected the second query to carry the first turn, got %+v", seen)
+	}
+	got, err := store.Get(context.Background(), sess.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Messages) != 4 {
+		t.Errorf("expected both turns recorded, got %+v", got.Messages)
+	}
+
+	other, _ := store.Create(context.Background(), "other-pipeline")
+	for _, tt := range []struct {
+		sessionID string
+		code      codes.Code
+	}{
+		{"does-not-exist", codes.NotFound},
+		{other.ID, codes.InvalidArgument},
+	} {
+		_, err := client.Query(context.Background(), &ragv1.QueryRequest{
+			Pipeline: "test-pipeline", Query: "q", SessionId: tt.sessionID,
+		})
+		if status.Code(err) != tt.code {
+			t.Errorf("expected %s for session %q, got %v", tt.code, tt.sessionID, err)
+		}
+	}
+
+	noSessions := grpcTestClient(t, New(testConfig(), newMockPipelineManager(), nil))
+	_, err = noSessions.Query(context.Background(), &ragv1.QueryRequest{
+		Pipeline: "test-pipeline", Query: "q", SessionId: sess.ID,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument with sessions disabled, got %v", err)
+	}
+}
+
+func TestGRPC_QueryStream(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunks := make(chan pipeline.StreamChunk, 3)
+			errs := make(chan error, 1)
+			if req.Query == "fail" {
+				chunks <- pipeline.StreamChunk{Content: "Partial"}
+				errs <- fmt.Errorf("completion failed")
+			} else {
+				chunks <- pipeline.StreamChunk{Sources: []pipeline.Source{{ID: "doc-1"}}}
+				chunks <- pipeline.StreamChunk{Content: "Hello"}
+				chunks <- pipeline.StreamChunk{Content: " world", QueryID: "q-1",
+					Usage: &pipeline.StageUsage{Completion: llmlib.TokenUsage{TotalTokens: 7}}}
+			}
+			close(chunks)
+			close(errs)
+			return chunks, errs
+		},
+	}
+	rec := &fakeQueryRecorder{}
+	client := grpcTestClient(t, New(testConfig(), pm, nil, WithQueryRecorder(rec)))
+
+	recv := func(query string) ([]*ragv1.QueryStreamResponse, error) {
+		stream, err := client.QueryStream(context.Background(),
+			&ragv1.QueryRequest{Pipeline: "test-pipeline", Query: query, IncludeSources: true})
+		if err != nil {
+			return nil, err
+		}
+		var msgs []*ragv1.QueryStreamResponse
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return msgs, nil
+			}
+			if err != nil {
+				return msgs, err
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+
+	msgs, err := recv("hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msgs) != 4 || msgs[0].GetSources().GetSources()[0].GetId() != "doc-1" ||
+		msgs[1].GetChunk() != "Hello" || msgs[2].GetChunk() != " world" ||
+		msgs[3].GetDone().GetQueryId() != "q-1" || msgs[3].GetDone().GetUsage().GetCompletion().GetTotalTokens() != 7 {
+		t.Errorf("unexpected stream %v", msgs)
+	}
+
+	msgs, err = recv("fail")
+	if status.Code(err) != codes.Internal || len(msgs) != 1 || msgs[0].GetChunk() != "Partial" {
+		t.Errorf("expected the failed stream to end with Internal after its chunk, got %v, %v", msgs, err)
+	}
+
+	want := []recordedQuery{{"test-pipeline", "hello", false}, {"test-pipeline", "fail", true}}
+	if !slices.Equal(rec.queries, want) {
+		t.Errorf("expected %v recorded, got %v", want, rec.queries)
+	}
+}
+
+func TestGRPC_ListPipelinesAndHealth(t *testing.T) {
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].health = &pipeline.PipelineHealth{
+		Name:       "test-pipeline",
+		Embedding:  pipeline.ProviderHealth{Reachable: true},
+		Completion: pipeline.ProviderHealth{Error: "connection refused"},
+	}
+	client := grpcTestClient(t, New(testConfig(), pm, nil))
+
+	list, err := client.ListPipelines(context.Background(), &ragv1.ListPipelinesRequest{})
+	if err != nil || len(list.Pipelines) != 1 || list.Pipelines[0].Name != "test-pipeline" ||
+		list.Pipelines[0].Description != "A test pipeline" {
+		t.Errorf("unexpected pipelines %v: %v", list, err)
+	}
+
+	health, err := client.Health(context.Background(), &ragv1.HealthRequest{})
+	if err != nil || health.Status != "degraded" || health.Pipelines[0].Completion.Error != "connection refused" {
+		t.Errorf("unexpected health %v: %v", health, err)
+	}
+}
+
+func TestAnswerSigning(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(t.TempDir(), "signing-key.pem")
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := LoadSigner(keyFile)
+	if err != nil {
+		t.Fatalf("failed to load the signing key: %v", err)
+	}
+	signedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	signer.now = func() time.Time { return signedAt }
+
+	prov := []pipeline.DocumentProvenance{{ID: "doc-1", ContentHash: "sha256:ab12"}}
+	pm := newMockPipelineManager()
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			return &pipeline.QueryResponse{Answer: "WAL is shipped to the standby.", Provenance: prov,
+				ResponseID: "resp-1"}, nil
+		},
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 3)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "WAL is shipped"}
+			chunkChan <- pipeline.StreamChunk{Content: " to the standby."}
+			chunkChan <- pipeline.StreamChunk{FinishReason: "stop", Provenance: prov, ResponseID: "resp-1"}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+		DetailFunc: func() pipeline.Detail {
+			return pipeline.Detail{Name: "test-pipeline", RAGLLM: pipeline.ModelDetail{Provider: "openai", Model: "gpt-4o-mini"}}
+		},
+	}
+	srv := New(testConfig(), pm, nil, WithSigner(signer))
+
+	// The public key is published for verifiers.
+	req := httptest.NewRequest(http.MethodGet, "/v1/signing-key", nil)
+	w := httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var published SigningKeyResponse
+	if err := json.NewDecoder(w.Body).Decode(&published); err != nil {
+		t.Fatalf("failed to decode the signing key: %v", err)
+	}
+	block, _ := pem.Decode([]byte(published.PublicKey))
+	if block == nil {
+		t.Fatalf("expected a PEM public key, got %q", published.PublicKey)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse the public key: %v", err)
+	}
+	if published.Algorithm != "Ed25519" || len(published.KeyID) != 16 {
+		t.Errorf("unexpected signing key %+v", published)
+	}
+
+	verify := func(sig *pipeline.AnswerSignature, answer string) {
+		t.Helper()
+		if sig == nil {
+			t.Fatal("expected the answer signed")
+		}
+		payload, err := base64.RawURLEncoding.DecodeString(sig.Payload)
+		if err != nil {
+			t.Fatalf("failed to decode the payload: %v", err)
+		}
+		signature, err := base64.RawURLEncoding.DecodeString(sig.Signature)
+		if err != nil {
+			t.Fatalf("failed to decode the signature: %v", err)
+		}
+		if sig.KeyID != published.KeyID || !ed25519.Verify(pub.(ed25519.PublicKey), payload, signature) {
+			t.Fatalf("expected a signature the published key verifies, got %+v", sig)
+		}
+		var signed SignedAnswer
+		if err := json.Unmarshal(payload, &signed); err != nil {
+			t.Fatalf("failed to decode the signed answer: %v", err)
+		}
+		want := SignedAnswer{Pipeline: "test-pipeline", Provider: "openai", Model: "gpt-4o-mini",
+			Answer: answer, Documents: prov, SignedAt: signedAt}
+		if !reflect.DeepEqual(signed, want) {
+			t.Errorf("expected signed answer %+v, got %+v", want, signed)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		bytes.NewBufferString(`{"query": "how does replication work?"}`))
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var resp pipeline.QueryResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	verify(resp.Signature, "WAL is shipped to the standby.")
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		bytes.NewBufferString(`{"query": "how does replication work?", "stream": true}`))
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	var done pipeline.StreamEvent
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok && strings.Contains(data, `"type":"done"`) {
+			if err := json.Unmarshal([]byte(data), &done); err != nil {
+				t.Fatalf("failed to decode the done event: %v", err)
+			}
+		}
+	}
+	verify(done.Signature, "WAL is shipped to the standby.")
+
+	// Answers served over gRPC are signed too, and carry what a client
+	// needs to verify and replay them.
+	fromGRPC := func(sig *ragv1.AnswerSignature) *pipeline.AnswerSignature {
+		if sig == nil {
+			return nil
+		}
+		return &pipeline.AnswerSignature{Algorithm: sig.GetAlgorithm(), KeyID: sig.GetKeyId(),
+			Payload: sig.GetPayload(), Signature: sig.GetSignature()}
+	}
+	client := grpcTestClient(t, srv)
+	grpcResp, err := client.Query(context.Background(),
+		&ragv1.QueryRequest{Pipeline: "test-pipeline", Query: "how does replication work?"})
+	if err != nil {
+		t.Fatalf("unexpected gRPC error: %v", err)
+	}
+	verify(fromGRPC(grpcResp.GetSignature()), "WAL is shipped to the standby.")
+	if grpcResp.GetResponseId() != "resp-1" || len(grpcResp.GetProvenance()) != 1 ||
+		grpcResp.GetProvenance()[0].GetContentHash() != "sha256:ab12" {
+		t.Errorf("expected the response ID and provenance over gRPC, got %v", grpcResp)
+	}
+	stream, err := client.QueryStream(context.Background(),
+		&ragv1.QueryRequest{Pipeline: "test-pipeline", Query: "how does replication work?"})
+	if err != nil {
+		t.Fatalf("unexpected gRPC error: %v", err)
+	}
+	var grpcDone *ragv1.Done
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected gRPC stream error: %v", err)
+		}
+		if msg.GetDone() != nil {
+			grpcDone = msg.GetDone()
+		}
+	}
+	verify(fromGRPC(grpcDone.GetSignature()), "WAL is shipped to the standby.")
+	if grpcDone.GetResponseId() != "resp-1" || grpcDone.GetProvenance()[0].GetId() != "doc-1" {
+		t.Errorf("expected the response ID and provenance in the done message, got %v", grpcDone)
+	}
+
+	// Without a signer, answers are not signed and no key is served.
+	srv = New(testConfig(), pm, nil)
+	req = httptest.NewRequest(http.MethodGet, "/v1/signing-key", nil)
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status %d without a signer, got %d", http.StatusNotFound, w.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline",
+		bytes.NewBufferString(`{"query": "how does replication work?"}`))
+	w = httptest.NewRecorder()
+	srv.mux.ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), `"signature"`) {
+		t.Errorf("expected no signature without a signer, got %s", w.Body.String())
+	}
+}
+
+func TestLoadSigner_RejectsInvalidKeys(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "not-pem")
+	if err := os.WriteFile(notPEM, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigner(notPEM); err == nil || !strings.Contains(err.Error(), "no PEM-encoded PKCS #8 private key") {
+		t.Errorf("expected a non-PEM file rejected, got %v", err)
+	}
+	if _, err := LoadSigner(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected a missing file rejected")
+	}
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigner(empty); err == nil || !strings.Contains(err.Error(), "signing key file is empty") {
+		t.Errorf("expected an empty file rejected, got %v", err)
+	}
+}
+
+// auditStore keeps the audit entries it is given.
+type auditStore struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (s *auditStore) Insert(_ context.Context, e audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *auditStore) Prune(context.Context, time.Time) error { return nil }
+
+func (s *auditStore) Close() error { return nil }
+
+func TestAuditLog(t *testing.T) {
+	pm := newMockPipelineManager()
+	usage := &pipeline.StageUsage{Completion: llmlib.TokenUsage{TotalTokens: 42}}
+	pm.pipelines["test-pipeline"].executor = &mockQueryExecutor{
+		ExecuteWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (*pipeline.QueryResponse, error) {
+			if req.Query == "fail" {
+				return nil, errors.New("completion provider unavailable")
+			}
+			return &pipeline.QueryResponse{Answer: "WAL is shipped to the standby.", Usage: usage}, nil
+		},
+		ExecuteStreamWithOptionsFunc: func(ctx context.Context, req pipeline.QueryRequest) (<-chan pipeline.StreamChunk, <-chan error) {
+			chunkChan := make(chan pipeline.StreamChunk, 2)
+			errChan := make(chan error, 1)
+			chunkChan <- pipeline.StreamChunk{Content: "Vacuum reclaims "}
+			chunkChan <- pipeline.StreamChunk{Content: "dead tuples.", Usage: usage}
+			close(chunkChan)
+			close(errChan)
+			return chunkChan, errChan
+		},
+	}
+	store := &auditStore{}
+	log := audit.New(store, config.AuditConfig{}, nil)
+	srv := New(testConfig(), pm, nil, WithAudit(log))
+
+	for _, body := range []string{
+		`{"query": "how does replication work?", "filter": {"conditions": [{"column": "product", "operator": "=", "value": "pgedge"}]}}`,
+		`{"query": "fail"}`,
+		`{"query": "what does vacuum do?", "stream": true}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/v1/pipelines/test-pipeline", strings.NewReader(body))
+		req.Header.Set(requestIDHeader, "req-1")
+		req.RemoteAddr = "192.0.2.7:4711"
+		srv.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	log.Close()
+
+	if len(store.entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d: %+v", len(store.entries), store.entries)
+	}
+	ok, failed, streamed := store.entries[0], store.entries[1], store.entries[2]
+	if ok.RequestID != "req-1" || ok.Pipeline != "test-pipeline" || ok.Client != "192.0.2.7" ||
+		ok.Query != "how does replication work?" || ok.Answer != "WAL is shipped to the standby." ||
+		ok.Status != requestStatusOK || ok.Error != "" || ok.Usage.TotalTokens() != 42 || ok.Time.IsZero() {
+		t.Errorf("unexpected entry for the answered query: %+v", ok)
+	}
+	if ok.Filter == nil || len(ok.Filter.Conditions) != 1 || ok.Filter.Conditions[0].Column != "product" {
+		t.Errorf("expected the query's filter recorded, got %+v", ok.Filter)
+	}
+	if failed.Status != requestStatusError || failed.Error != "completion provider unavailable" || failed.Answer != "" {
+		t.Errorf("unexpected entry for the failed query: %+v", failed)
+	}
+	if streamed.Status != requestStatusOK || streamed.Answer != "Vacuum reclaims dead tuples." ||
+		streamed.Usage.TotalTokens() != 42 {
+		t.Errorf("unexpected entry for the streamed query: %+v", streamed)
 	}
 }
@@ -0,0 +1,149 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/pipeline"
+)
+
+// signingAlgorithm names the algorithm answers are signed with.
+const signingAlgorithm = "Ed25519"
+
+// SignedAnswer is the document an answer's signature signs: the answer,
+// the provenance of the documents it was written from, the pipeline and
+// its completion model, and when it was signed.
+type SignedAnswer struct {
+	Pipeline  string                        `json:"pipeline"`
+	Provider  string                        `json:"provider"`
+	Model     string                        `json:"model"`
+	Answer    string                        `json:"answer"`
+	Documents []pipeline.DocumentProvenance `json:"documents"`
+	SignedAt  time.Time                     `json:"signed_at"`
+}
+
+// SigningKeyResponse is the response of the GET /v1/signing-key
+// endpoint: the public key answers are signed with.
+type SigningKeyResponse struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"` // PEM-encoded PKIX public key
+}
+
+// Signer signs answers with an Ed25519 key.
+type Signer struct {
+	key       ed25519.PrivateKey
+	keyID     string
+	publicKey string           // PEM-encoded
+	now       func() time.Time // overridden in tests
+}
+
+// NewSigner creates a signer that signs with key. The key's ID is the
+// first 16 hex digits of the SHA-256 of its PKIX-encoded public key.
+func NewSigner(key ed25519.PrivateKey) (*Signer, error) {
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the public key: %w", err)
+	}
+	sum := sha256.Sum256(der)
+	return &Signer{
+		key:       key,
+		keyID:     hex.EncodeToString(sum[:8]),
+		publicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+		now:       time.Now,
+	}, nil
+}
+
+// LoadSigner creates a signer from the PEM-encoded PKCS #8 Ed25519
+// private key in the file at path, read as config.ReadSecretFile reads
+// secret files.
+func LoadSigner(path string) (*Signer, error) {
+	data, err := config.ReadSecretFile(path, "signing key")
+	if err != nil {
+		return nil, err
+	}
+	path = config.ExpandPath(path)
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s holds no PEM-encoded PKCS #8 private key", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the private key in %s: %w", path, err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("the signing key must be an Ed25519 key")
+	}
+	return NewSigner(edKey)
+}
+
+// Sign signs a, setting its SignedAt to the current time.
+func (sg *Signer) Sign(a SignedAnswer) (*pipeline.AnswerSignature, error) {
+	if a.Documents == nil {
+		a.Documents = []pipeline.DocumentProvenance{}
+	}
+	a.SignedAt = sg.now().UTC()
+	payload, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeline.AnswerSignature{
+		Algorithm: signingAlgorithm,
+		KeyID:     sg.keyID,
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Signature: base64.RawURLEncoding.EncodeToString(ed25519.Sign(sg.key, payload)),
+	}, nil
+}
+
+// signAnswer signs answer, written for p by the completion model by from
+// the documents prov identifies, or returns nil when the server does not
+// sign answers or signing fails. When by is unset, p's rag_llm is named.
+func (s *Server) signAnswer(p pipeline.QueryExecutor, answer string,
+	prov []pipeline.DocumentProvenance, by pipeline.Answerer) *pipeline.AnswerSignature {
+	if s.signer == nil {
+		return nil
+	}
+	a := SignedAnswer{Answer: answer, Documents: prov, Provider: by.Provider, Model: by.Model}
+	if d, ok := p.(pipeline.Detailer); ok {
+		detail := d.Detail()
+		a.Pipeline = detail.Name
+		if a.Provider == "" {
+			a.Provider, a.Model = detail.RAGLLM.Provider, detail.RAGLLM.Model
+		}
+	}
+	sig, err := s.signer.Sign(a)
+	if err != nil {
+		s.logger.Error("failed to sign the answer", "pipeline", a.Pipeline, "error", err)
+		return nil
+	}
+	return sig
+}
+
+// handleSigningKey handles the GET /v1/signing-key endpoint.
+func (s *Server) handleSigningKey(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, SigningKeyResponse{
+		Algorithm: signingAlgorithm,
+		KeyID:     s.signer.keyID,
+		PublicKey: s.signer.publicKey,
+	})
+}
@@ -0,0 +1,189 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// FieldError reports a problem with one field of a request body.
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, such as filter.conditions[0].column
+	Message string `json:"message"`
+}
+
+// requestSchemas are the component schemas of the published OpenAPI
+// specification, which request bodies are checked against.
+var requestSchemas = sync.OnceValue(func() map[string]OpenAPISchema {
+	return BuildOpenAPISpec().Components.Schemas
+})
+
+// decodeRequest reads a JSON request body into v after checking it
+// against the named schema of the published OpenAPI specification.
+// Fields of the wrong type are rejected with field-level errors.
+// Unknown fields are rejected in strict mode; otherwise they are logged
+// and named in Warning response headers. It reports whether the body
+// was accepted; when it was not, the error response has been written.
+func (s *Server) decodeRequest(w http.ResponseWriter, r *http.Request, schema string, v any) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestBodyBytes)
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.respondError(w, http.StatusRequestEntityTooLarge, "REQUEST_TOO_LARGE",
+				fmt.Sprintf("request body exceeds maximum size of %d bytes", maxBytesErr.Limit))
+			return false
+		}
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"invalid request body: "+err.Error())
+		return false
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"invalid request body: "+err.Error())
+		return false
+	}
+
+	var check schemaCheck
+	check.value(doc, OpenAPISchema{Ref: "#/components/schemas/" + schema}, "")
+	if s.config.Server.RequestValidation == config.RequestValidationStrict {
+		check.errs = append(check.errs, check.unknown...)
+	} else {
+		for _, f := range check.unknown {
+			s.logger.Warn("ignoring unknown request field", "path", r.URL.Path, "field", f.Field)
+			w.Header().Add("Warning", fmt.Sprintf("299 - %s", strconv.Quote("unknown field "+f.Field+" ignored")))
+		}
+	}
+	if len(check.errs) > 0 {
+		slices.SortFunc(check.errs, func(a, b FieldError) int { return strings.Compare(a.Field, b.Field) })
+		s.respondJSON(w, http.StatusBadRequest, ErrorResponse{
+			Error: ErrorDetail{
+				Code:    "INVALID_REQUEST",
+				Message: "request body does not match the " + schema + " schema",
+				Fields:  check.errs,
+			},
+		})
+		return false
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		s.respondError(w, http.StatusBadRequest, "INVALID_REQUEST",
+			"invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// schemaCheck collects the problems found checking a decoded JSON
+// document against a schema. Only types, required properties, array
+// lengths and unknown properties are checked; values such as filter
+// operators are validated where they are used.
+type schemaCheck struct {
+	errs    []FieldError
+	unknown []FieldError
+}
+
+func (c *schemaCheck) value(v any, schema OpenAPISchema, path string) {
+	if name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/"); ok {
+		schema = requestSchemas()[name]
+	}
+	if v == nil {
+		return // Omitted, as far as decoding is concerned
+	}
+
+	switch schema.Type {
+	case "string":
+		if _, ok := v.(string); !ok {
+			c.mismatch(path, "a string")
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			c.mismatch(path, "a boolean")
+		}
+	case "integer":
+		n, ok := v.(json.Number)
+		if _, err := n.Int64(); !ok || err != nil {
+			c.mismatch(path, "an integer")
+		}
+	case "number":
+		if _, ok := v.(json.Number); !ok {
+			c.mismatch(path, "a number")
+		}
+	case "array":
+		items, ok := v.([]any)
+		if !ok {
+			c.mismatch(path, "an array")
+			return
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			c.errs = append(c.errs, FieldError{Field: path,
+				Message: fmt.Sprintf("must have at most %d items", *schema.MaxItems)})
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				c.value(item, *schema.Items, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			c.mismatch(path, "an object")
+			return
+		}
+		c.object(obj, schema, path)
+	}
+}
+
+func (c *schemaCheck) object(obj map[string]any, schema OpenAPISchema, path string) {
+	field := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	for _, name := range schema.Required {
+		if _, ok := obj[name]; !ok {
+			c.errs = append(c.errs, FieldError{Field: field(name), Message: "is required"})
+		}
+	}
+	for name, v := range obj {
+		switch prop, ok := schema.Properties[name]; {
+		case ok:
+			c.value(v, prop, field(name))
+		case schema.AdditionalProperties != nil:
+			c.value(v, *schema.AdditionalProperties, field(name))
+		case len(schema.Properties) > 0:
+			c.unknown = append(c.unknown, FieldError{Field: field(name), Message: "unknown field"})
+		}
+	}
+}
+
+func (c *schemaCheck) mismatch(path, want string) {
+	if path == "" {
+		path = "body"
+	}
+	c.errs = append(c.errs, FieldError{Field: path, Message: "must be " + want})
+}
@@ -0,0 +1,101 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// apiVersion is one version of the HTTP API. Each version is served
+// under its own path prefix (/v1/, /v2/, ...) from its own mux, so a
+// new version can change response shapes while clients of the older
+// one keep the behavior they were written against.
+type apiVersion struct {
+	name       string                                 // Path prefix, e.g. "v1"
+	deprecated time.Time                              // When the version was deprecated; zero while current
+	sunset     time.Time                              // When it will be removed; zero until scheduled
+	link       string                                 // Migration guide for clients of a deprecated version
+	routes     func(s *Server, router *versionRouter) // Registers the version's routes
+}
+
+// apiVersions lists the API versions the server serves, oldest first.
+// To retire a version, set its deprecated date, and its sunset date
+// once removal is scheduled, rather than deleting it: its clients then
+// see the Deprecation and Sunset headers on every response well before
+// it goes.
+var apiVersions = []apiVersion{
+	{name: "v1", routes: (*Server).v1Routes},
+}
+
+// versionRouter registers one API version's routes on its mux,
+// prefixing each pattern's path with the version.
+type versionRouter struct {
+	prefix string
+	mux    *http.ServeMux
+}
+
+// HandleFunc registers handler for pattern, written without the
+// version prefix: "GET /health" serves GET /v1/health under v1.
+func (vr *versionRouter) HandleFunc(pattern string, handler http.HandlerFunc) {
+	method, path, ok := strings.Cut(pattern, " ")
+	if !ok {
+		method, path = "", pattern
+	}
+	vr.mux.HandleFunc(strings.TrimSpace(method+" "+vr.prefix+path), handler)
+}
+
+// mountVersion gives an API version its own mux, registers its routes
+// there, and routes its path prefix to it from the root mux.
+func (s *Server) mountVersion(v apiVersion) {
+	router := &versionRouter{prefix: "/" + v.name, mux: http.NewServeMux()}
+	v.routes(s, router)
+	s.versions[v.name] = router.mux
+	s.mux.Handle(router.prefix+"/", versionHeaders(v, router.mux))
+}
+
+// muxFor returns the mux that serves path: its API version's mux, or
+// the root mux for unversioned paths such as the metrics endpoint.
+func (s *Server) muxFor(path string) *http.ServeMux {
+	name, _, ok := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if mux := s.versions[name]; ok && mux != nil {
+		return mux
+	}
+	return s.mux
+}
+
+// versionHeaders tells clients of a deprecated API version so on every
+// response: Deprecation (RFC 9745) carries when it was deprecated,
+// Sunset (RFC 8594) when it will be removed, and Link the migration
+// guide. Current versions are served unchanged.
+func versionHeaders(v apiVersion, next http.Handler) http.Handler {
+	if v.deprecated.IsZero() && v.sunset.IsZero() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if !v.deprecated.IsZero() {
+			h.Set("Deprecation", fmt.Sprintf("@%d", v.deprecated.Unix()))
+		}
+		if !v.sunset.IsZero() {
+			h.Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+		}
+		if v.link != "" {
+			rel := "deprecation"
+			if v.deprecated.IsZero() {
+				rel = "sunset"
+			}
+			h.Add("Link", fmt.Sprintf("<%s>; rel=%q", v.link, rel))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,161 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in process memory. Sessions are lost on
+// restart and are not shared between server instances.
+type MemoryStore struct {
+	ttl         time.Duration
+	maxSessions int
+	now         func() time.Time // overridden in tests
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewMemoryStore creates a MemoryStore whose sessions expire after ttl
+// without activity, and that keeps at most maxSessions, evicting the
+// least recently active to make room for a new one. A ttl of zero
+// keeps sessions until deleted; a maxSessions of zero keeps any number.
+func NewMemoryStore(ttl time.Duration, maxSessions int) *MemoryStore {
+	return &MemoryStore{
+		ttl:         ttl,
+		maxSessions: maxSessions,
+		now:         time.Now,
+		sessions:    make(map[string]*Session),
+	}
+}
+
+// Create implements Store.
+func (m *MemoryStore) Create(ctx context.Context, pipeline string) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := m.now()
+	s := &Session{
+		ID:        id,
+		Pipeline:  pipeline,
+		Messages:  []Message{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweepLocked(now)
+	if m.maxSessions > 0 && len(m.sessions) >= m.maxSessions {
+		m.evictLocked()
+	}
+	m.sessions[id] = s
+	return copySession(s), nil
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.liveLocked(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return copySession(s), nil
+}
+
+// Append implements Store.
+func (m *MemoryStore) Append(ctx context.Context, id string, msgs ...Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.liveLocked(id)
+	if !ok {
+		return ErrNotFound
+	}
+	s.Messages = append(s.Messages, msgs...)
+	s.UpdatedAt = m.now()
+	return nil
+}
+
+// Delete implements Store.
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.liveLocked(id); !ok {
+		return ErrNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+// Close implements Store.
+func (m *MemoryStore) Close() error {
+	return nil
+}
+
+// liveLocked returns the session if it exists and has not expired,
+// dropping it if it has. m.mu must be held.
+func (m *MemoryStore) liveLocked(id string) (*Session, bool) {
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, false
+	}
+	if m.expired(s, m.now()) {
+		delete(m.sessions, id)
+		return nil, false
+	}
+	return s, true
+}
+
+// sweepLocked drops every expired session. It runs on Create so memory
+// stays bounded by the number of active sessions without needing a
+// background goroutine. m.mu must be held.
+func (m *MemoryStore) sweepLocked(now time.Time) {
+	if m.ttl <= 0 {
+		return
+	}
+	for id, s := range m.sessions {
+		if m.expired(s, now) {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// evictLocked drops the least recently active session. m.mu must be
+// held.
+func (m *MemoryStore) evictLocked() {
+	var oldest *Session
+	for _, s := range m.sessions {
+		if oldest == nil || s.UpdatedAt.Before(oldest.UpdatedAt) {
+			oldest = s
+		}
+	}
+	if oldest != nil {
+		delete(m.sessions, oldest.ID)
+	}
+}
+
+func (m *MemoryStore) expired(s *Session, now time.Time) bool {
+	return m.ttl > 0 && now.Sub(s.UpdatedAt) > m.ttl
+}
+
+// copySession returns a copy of s that the caller may modify freely.
+func copySession(s *Session) *Session {
+	out := *s
+	out.Messages = append([]Message{}, s.Messages...)
+	return &out
+}
@@ -0,0 +1,131 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package session
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour, 0)
+
+	sess, err := store.Create(ctx, "docs")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if sess.ID == "" || sess.Pipeline != "docs" {
+		t.Fatalf("unexpected session: %+v", sess)
+	}
+
+	if err := store.Append(ctx, sess.ID,
+		Message{Role: "user", Content: "q"},
+		Message{Role: "assistant", Content: "a"},
+	); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	got, err := store.Get(ctx, sess.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(got.Messages) != 2 || got.Messages[1].Content != "a" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+
+	// The returned session is a copy.
+	got.Messages[0].Content = "changed"
+	again, _ := store.Get(ctx, sess.ID)
+	if again.Messages[0].Content != "q" {
+		t.Error("mutating a returned session changed the stored one")
+	}
+
+	if err := store.Delete(ctx, sess.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := store.Get(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Minute, 0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	sess, err := store.Create(ctx, "docs")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Activity refreshes the expiry.
+	now = now.Add(50 * time.Second)
+	if err := store.Append(ctx, sess.ID, Message{Role: "user", Content: "q"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+	now = now.Add(50 * time.Second)
+	if _, err := store.Get(ctx, sess.ID); err != nil {
+		t.Fatalf("expected session to be live, got %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := store.Get(ctx, sess.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected expired session to be gone, got %v", err)
+	}
+	if err := store.Append(ctx, sess.ID, Message{Role: "user", Content: "q"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound appending to expired session, got %v", err)
+	}
+}
+
+func TestMemoryStore_ZeroTTLNeverExpires(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(0, 0)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	sess, _ := store.Create(ctx, "docs")
+	now = now.Add(365 * 24 * time.Hour)
+	if _, err := store.Get(ctx, sess.ID); err != nil {
+		t.Errorf("expected session to survive with zero TTL, got %v", err)
+	}
+}
+
+func TestMemoryStore_MaxSessions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(time.Hour, 2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	first, _ := store.Create(ctx, "docs")
+	now = now.Add(time.Second)
+	second, _ := store.Create(ctx, "docs")
+	now = now.Add(time.Second)
+	if err := store.Append(ctx, first.ID, Message{Role: "user", Content: "q"}); err != nil {
+		t.Fatalf("Append failed: %v", err)
+	}
+
+	now = now.Add(time.Second)
+	third, _ := store.Create(ctx, "docs")
+	if _, err := store.Get(ctx, second.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the least recently active session evicted, got %v", err)
+	}
+	for _, id := range []string{first.ID, third.ID} {
+		if _, err := store.Get(ctx, id); err != nil {
+			t.Errorf("expected session %s kept, got %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	"github.com/pgEdge/pgedge-rag-server/internal/database"
+)
+
+// PostgresStore keeps sessions in a Postgres table, so they survive
+// restarts and are shared by every server instance using the same
+// database. Expired rows are filtered out on read and removed
+// opportunistically when new sessions are created.
+type PostgresStore struct {
+	db    *database.Pool
+	table string // sanitized identifier
+	ttl   time.Duration
+}
+
+// NewPostgresStore connects to the database and creates the sessions
+// table if it does not already exist. table may be schema-qualified.
+func NewPostgresStore(
+	ctx context.Context,
+	dbCfg config.DatabaseConfig,
+	table string,
+	ttl time.Duration,
+) (*PostgresStore, error) {
+	db, err := database.NewPool(ctx, dbCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &PostgresStore{
+		db:    db,
+		table: pgx.Identifier(strings.Split(table, ".")).Sanitize(),
+		ttl:   ttl,
+	}
+
+	_, err = s.pool().Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id text PRIMARY KEY,
+		pipeline text NOT NULL,
+		messages jsonb NOT NULL DEFAULT '[]',
+		created_at timestamptz NOT NULL DEFAULT now(),
+		updated_at timestamptz NOT NULL DEFAULT now()
+	)`, s.table))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sessions table: %w", err)
+	}
+
+	return s, nil
+}
+
+func (s *PostgresStore) pool() *pgxpool.Pool {
+	return s.db.Pool()
+}
+
+// ttlSeconds returns the TTL for use as a query parameter; zero means
+// sessions never expire.
+func (s *PostgresStore) ttlSeconds() float64 {
+	return s.ttl.Seconds()
+}
+
+// Create implements Store.
+func (s *PostgresStore) Create(ctx context.Context, pipeline string) (*Session, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.ttl > 0 {
+		if _, err := s.pool().Exec(ctx, fmt.Sprintf(
+			`DELETE FROM %s WHERE updated_at < now() - make_interval(secs => $1::float8)`,
+			s.table), s.ttlSeconds()); err != nil {
+			return nil, fmt.Errorf("failed to expire sessions: %w", err)
+		}
+	}
+
+	sess := &Session{ID: id, Pipeline: pipeline, Messages: []Message{}}
+	err = s.pool().QueryRow(ctx, fmt.Sprintf(
+		`INSERT INTO %s (id, pipeline) VALUES ($1, $2) RETURNING created_at, updated_at`,
+		s.table), id, pipeline).Scan(&sess.CreatedAt, &sess.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return sess, nil
+}
+
+// Get implements Store.
+func (s *PostgresStore) Get(ctx context.Context, id string) (*Session, error) {
+	sess := &Session{ID: id}
+	var raw []byte
+
+	err := s.pool().QueryRow(ctx, fmt.Sprintf(
+		`SELECT pipeline, messages, created_at, updated_at FROM %s
+		 WHERE id = $1 AND ($2::float8 = 0 OR updated_at >= now() - make_interval(secs => $2::float8))`,
+		s.table), id, s.ttlSeconds()).Scan(&sess.Pipeline, &raw, &sess.CreatedAt, &sess.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if err := json.Unmarshal(raw, &sess.Messages); err != nil {
+		return nil, fmt.Errorf("failed to decode session messages: %w", err)
+	}
+	return sess, nil
+}
+
+// Append implements Store.
+func (s *PostgresStore) Append(ctx context.Context, id string, msgs ...Message) error {
+	encoded, err := json.Marshal(msgs)
+	if err != nil {
+		return fmt.Errorf("failed to encode session messages: %w", err)
+	}
+
+	tag, err := s.pool().Exec(ctx, fmt.Sprintf(
+		`UPDATE %s SET messages = messages || $2::jsonb, updated_at = now()
+		 WHERE id = $1 AND ($3::float8 = 0 OR updated_at >= now() - make_interval(secs => $3::float8))`,
+		s.table), id, string(encoded), s.ttlSeconds())
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (s *PostgresStore) Delete(ctx context.Context, id string) error {
+	tag, err := s.pool().Exec(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, s.table), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close implements Store.
+func (s *PostgresStore) Close() error {
+	s.db.Close()
+	return nil
+}
@@ -0,0 +1,111 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package session stores server-side conversation history so clients
+// can reference a session ID instead of resending every prior turn.
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+// ErrNotFound is returned when a session does not exist or has expired.
+var ErrNotFound = errors.New("session not found")
+
+// Message is a single conversation turn.
+type Message struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// Session is a conversation bound to a single pipeline.
+type Session struct {
+	ID        string    `json:"id"`
+	Pipeline  string    `json:"pipeline"`
+	Messages  []Message `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Store persists sessions. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Create starts an empty session bound to the named pipeline.
+	Create(ctx context.Context, pipeline string) (*Session, error)
+
+	// Get returns the session with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Session, error)
+
+	// Append adds messages to the end of a session's history and
+	// refreshes its expiry. It returns ErrNotFound if the session does
+	// not exist.
+	Append(ctx context.Context, id string, msgs ...Message) error
+
+	// Delete removes a session. Deleting a missing session returns
+	// ErrNotFound.
+	Delete(ctx context.Context, id string) error
+
+	// Close releases any resources held by the store.
+	Close() error
+}
+
+// NewStore creates the store selected by cfg.Store.
+func NewStore(ctx context.Context, cfg config.SessionsConfig) (Store, error) {
+	switch cfg.Store {
+	case config.SessionStoreMemory, "":
+		return NewMemoryStore(cfg.TTL.Std(), cfg.MaxSessions), nil
+	case config.SessionStorePostgres:
+		return NewPostgresStore(ctx, cfg.Database, cfg.Table, cfg.TTL.Std())
+	default:
+		return nil, fmt.Errorf("unknown session store: %s", cfg.Store)
+	}
+}
+
+// newID returns a random 128-bit session ID in hex.
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// TruncateHistory returns the most recent messages whose combined
+// estimated size fits in maxTokens, estimating a token as four bytes,
+// since a session is not tied to one model's tokenizer. The result
+// never starts with an assistant message, since some providers require
+// the conversation to open with a user turn. A maxTokens of zero or
+// less disables truncation.
+func TruncateHistory(msgs []Message, maxTokens int) []Message {
+	if maxTokens <= 0 {
+		return msgs
+	}
+
+	start := len(msgs)
+	total := 0
+	for start > 0 {
+		tokens := len(msgs[start-1].Content) / 4
+		if total+tokens > maxTokens {
+			break
+		}
+		total += tokens
+		start--
+	}
+	for start < len(msgs) && msgs[start].Role != "user" {
+		start++
+	}
+	return msgs[start:]
+}
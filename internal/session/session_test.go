@@ -0,0 +1,66 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package session
+
+import (
+	"strings"
+	"testing"
+)
+
+func turns(contents ...string) []Message {
+	msgs := make([]Message, len(contents))
+	for i, c := range contents {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		msgs[i] = Message{Role: role, Content: c}
+	}
+	return msgs
+}
+
+func TestTruncateHistory_Unlimited(t *testing.T) {
+	msgs := turns("a", "b", "c")
+	if got := TruncateHistory(msgs, 0); len(got) != 3 {
+		t.Errorf("expected all 3 messages with no limit, got %d", len(got))
+	}
+}
+
+func TestTruncateHistory_KeepsMostRecent(t *testing.T) {
+	long := strings.Repeat("x", 400) // ~100 tokens
+	msgs := turns(long, long, long, long)
+
+	got := TruncateHistory(msgs, 250)
+	if len(got) != 2 {
+		t.Fatalf("expected the last 2 messages to fit, got %d", len(got))
+	}
+	if got[0].Role != "user" {
+		t.Errorf("expected history to start with a user turn, got %q", got[0].Role)
+	}
+}
+
+func TestTruncateHistory_DropsLeadingAssistant(t *testing.T) {
+	long := strings.Repeat("x", 400)
+	msgs := turns(long, long, long, long)
+
+	// 350 tokens fits three messages, but the oldest of those is an
+	// assistant reply, so only the final pair survives.
+	got := TruncateHistory(msgs, 350)
+	if len(got) != 2 || got[0].Role != "user" {
+		t.Errorf("expected final user/assistant pair, got %+v", got)
+	}
+}
+
+func TestTruncateHistory_NothingFits(t *testing.T) {
+	msgs := turns(strings.Repeat("x", 4000), strings.Repeat("y", 4000))
+	if got := TruncateHistory(msgs, 10); len(got) != 0 {
+		t.Errorf("expected empty history, got %d messages", len(got))
+	}
+}
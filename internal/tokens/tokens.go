@@ -0,0 +1,180 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+// Package tokens counts text in the tokens of the model it is sent to,
+// so a token budget holds for the model rather than for an estimate
+// of its tokenizer.
+package tokens
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tiktoken-go/tokenizer"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+	ragllm "github.com/pgEdge/pgedge-rag-server/internal/llm"
+)
+
+// anthropicPercent is how many tokens Claude's tokenizer is taken to
+// produce for the same text, as a percentage of cl100k_base's.
+// Anthropic does not publish the tokenizer, so its counts are
+// approximate.
+const anthropicPercent = 110
+
+// Counter counts text in a model's tokens. Counters are safe for
+// concurrent use.
+type Counter interface {
+	// Count returns the number of tokens in text.
+	Count(text string) int
+
+	// Truncate returns the longest prefix of text of at most max
+	// tokens that does not split a character.
+	Truncate(text string, max int) string
+}
+
+// ForModel returns the counter for a completion provider's model: the
+// model's own encoding for OpenAI models, an approximation for Claude,
+// and cl100k_base, which Llama 3's tokenizer extends, for any other.
+func ForModel(provider, model string) Counter {
+	provider, model = strings.ToLower(provider), strings.ToLower(model)
+	switch {
+	case provider == ragllm.ProviderOpenAI:
+		if codec, err := tokenizer.ForModel(tokenizer.Model(model)); err == nil {
+			return bpe{codec}
+		}
+		if openAIO200k(model) {
+			return Get(config.TokenizerO200kBase)
+		}
+		return Get(config.TokenizerCl100kBase)
+	case provider == ragllm.ProviderAnthropic,
+		provider == ragllm.ProviderBedrock && strings.Contains(model, "anthropic."):
+		return Get(config.TokenizerAnthropic)
+	}
+	return Get(config.TokenizerCl100kBase)
+}
+
+// openAIO200k reports whether an OpenAI model the tokenizer library
+// does not list, such as a dated snapshot, uses o200k_base, as every
+// model since GPT-4o does.
+func openAIO200k(model string) bool {
+	for _, prefix := range []string{"gpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "chatgpt-4o", "o1", "o3", "o4"} {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the counter a pipeline's tokenizer setting names, or the
+// estimate for a name it does not know.
+func Get(name string) Counter {
+	switch name {
+	case config.TokenizerO200kBase:
+		return codec(tokenizer.O200kBase)
+	case config.TokenizerCl100kBase:
+		return codec(tokenizer.Cl100kBase)
+	case config.TokenizerAnthropic:
+		return scaled{codec(tokenizer.Cl100kBase), anthropicPercent}
+	}
+	return Estimate{}
+}
+
+var (
+	codecsMu sync.Mutex
+	codecs   = make(map[tokenizer.Encoding]Counter)
+)
+
+// codec returns the counter for an encoding, loading its vocabulary on
+// first use.
+func codec(enc tokenizer.Encoding) Counter {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if c, ok := codecs[enc]; ok {
+		return c
+	}
+	var c Counter = Estimate{}
+	if tc, err := tokenizer.Get(enc); err == nil {
+		c = bpe{tc}
+	}
+	codecs[enc] = c
+	return c
+}
+
+// bpe counts with a byte-pair encoding. Text the encoding fails on is
+// estimated instead.
+type bpe struct {
+	codec tokenizer.Codec
+}
+
+func (b bpe) Count(text string) int {
+	n, err := b.codec.Count(text)
+	if err != nil {
+		return Estimate{}.Count(text)
+	}
+	return n
+}
+
+func (b bpe) Truncate(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	_, pieces, err := b.codec.Encode(text)
+	if err != nil {
+		return Estimate{}.Truncate(text, max)
+	}
+	if len(pieces) <= max {
+		return text
+	}
+	n := 0
+	for _, piece := range pieces[:max] {
+		n += len(piece)
+	}
+	return text[:runeStart(text, n)]
+}
+
+// scaled approximates a tokenizer that produces a percentage of the
+// tokens of another, rounding up.
+type scaled struct {
+	base    Counter
+	percent int
+}
+
+func (s scaled) Count(text string) int {
+	return (s.base.Count(text)*s.percent + 99) / 100
+}
+
+func (s scaled) Truncate(text string, max int) string {
+	return s.base.Truncate(text, max*100/s.percent)
+}
+
+// Estimate counts a token for every four bytes of text, the estimate
+// used where no tokenizer applies.
+type Estimate struct{}
+
+func (Estimate) Count(text string) int {
+	return len(text) / 4
+}
+
+func (Estimate) Truncate(text string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	return text[:runeStart(text, min(len(text), max*4))]
+}
+
+// runeStart returns the offset of the character n falls in, so text
+// cut there does not end with part of one.
+func runeStart(text string, n int) int {
+	for n > 0 && n < len(text) && !utf8.RuneStart(text[n]) {
+		n--
+	}
+	return n
+}
@@ -0,0 +1,97 @@
+//-------------------------------------------------------------------------
+//
+// pgEdge RAG Server
+//
+// Copyright (c) 2025 - 2026, pgEdge, Inc.
+// This software is released under The PostgreSQL License
+//
+//-------------------------------------------------------------------------
+
+package tokens
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pgEdge/pgedge-rag-server/internal/config"
+)
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		provider string
+		model    string
+		want     Counter
+	}{
+		{"openai", "gpt-4o", Get(config.TokenizerO200kBase)},
+		{"OpenAI", "gpt-4o-2024-08-06", Get(config.TokenizerO200kBase)},
+		{"openai", "gpt-5.1", Get(config.TokenizerO200kBase)},
+		{"openai", "gpt-4-turbo", Get(config.TokenizerCl100kBase)},
+		{"openai", "my-fine-tune", Get(config.TokenizerCl100kBase)},
+		{"anthropic", "claude-sonnet-4-5", Get(config.TokenizerAnthropic)},
+		{"bedrock", "anthropic.claude-3-haiku-20240307-v1:0", Get(config.TokenizerAnthropic)},
+		{"ollama", "llama3.1", Get(config.TokenizerCl100kBase)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider+"/"+tt.model, func(t *testing.T) {
+			if got := ForModel(tt.provider, tt.model); name(got) != name(tt.want) {
+				t.Errorf("got %s, want %s", name(got), name(tt.want))
+			}
+		})
+	}
+}
+
+// name identifies a counter by its encoding.
+func name(c Counter) string {
+	switch c := c.(type) {
+	case bpe:
+		return c.codec.GetName()
+	case scaled:
+		return "scaled " + name(c.base)
+	}
+	return "estimate"
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		tokenizer string
+		text      string
+		want      int
+	}{
+		{config.TokenizerCl100kBase, "hello world", 2},
+		{config.TokenizerO200kBase, "hello world", 2},
+		{config.TokenizerCl100kBase, "", 0},
+		{config.TokenizerAnthropic, strings.Repeat("hello world ", 49) + "hello world", 110},
+		{config.TokenizerEstimate, "hello world!", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tokenizer, func(t *testing.T) {
+			if got := Get(tt.tokenizer).Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	text := strings.Repeat("Die Größe der WAL-Segmente ist konfigurierbar. ", 20)
+
+	for _, tokenizer := range []string{config.TokenizerO200kBase, config.TokenizerCl100kBase,
+		config.TokenizerAnthropic, config.TokenizerEstimate} {
+		t.Run(tokenizer, func(t *testing.T) {
+			c := Get(tokenizer)
+			got := c.Truncate(text, 25)
+			if got == "" || !strings.HasPrefix(text, got) || !utf8.ValidString(got) {
+				t.Fatalf("expected a valid prefix of the text, got %q", got)
+			}
+			if n := c.Count(got); n > 25 {
+				t.Errorf("truncated text is %d tokens, over 25", n)
+			}
+			if c.Truncate(text, 0) != "" || c.Truncate("short", 25) != "short" {
+				t.Error("expected nothing for no tokens and short text unchanged")
+			}
+		})
+	}
+}
@@ -20,12 +20,21 @@
 // nothing. Watching the file's parent directory instead, and reacting to
 // any change there rather than filtering by which name changed, catches
 // this correctly. See issue #30.
+//
+// Reacting to any change in a directory would also reload on changes to
+// unrelated files there, so the Watcher compares the watched files'
+// contents before invoking its callback, and skips changes that leave
+// them as they were.
 package watch
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -40,13 +49,18 @@ const DefaultDebounce = 500 * time.Millisecond
 
 // Watcher watches the parent directories of a set of files and invokes
 // onChange, debounced, whenever anything changes in one of those
-// directories.
+// directories that changes the content of one of the files.
 type Watcher struct {
 	fsw      *fsnotify.Watcher
 	debounce time.Duration
 	onChange func()
 	logger   *slog.Logger
 
+	mu     sync.Mutex
+	paths  []string        // the watched files; guarded by mu
+	dirs   map[string]bool // their parent directories, each watched once; guarded by mu
+	digest string          // digest of the files' contents at the last onChange; guarded by mu
+
 	// reloadTrigger hands off debounced change notifications to a
 	// separate worker goroutine (see reloadWorker) so that a slow
 	// onChange (e.g. rebuilding pipelines) never blocks this package's
@@ -76,24 +90,83 @@ func New(paths []string, debounce time.Duration, onChange func(), logger *slog.L
 		return nil, err
 	}
 
+	w := &Watcher{
+		fsw:           fsw,
+		debounce:      debounce,
+		onChange:      onChange,
+		logger:        logger,
+		dirs:          make(map[string]bool),
+		reloadTrigger: make(chan struct{}, 1),
+	}
+	if err := w.SetPaths(paths); err != nil {
+		_ = fsw.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetPaths replaces the set of watched files, such as when a reloaded
+// configuration refers to a different API key file, and takes their
+// current contents as the baseline later changes are compared against.
+// Directories no longer needed stop being watched.
+func (w *Watcher) SetPaths(paths []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
 	dirs := make(map[string]bool)
 	for _, p := range paths {
 		dirs[filepath.Dir(p)] = true
 	}
 	for dir := range dirs {
-		if err := fsw.Add(dir); err != nil {
-			_ = fsw.Close()
-			return nil, err
+		if !w.dirs[dir] {
+			if err := w.fsw.Add(dir); err != nil {
+				return err
+			}
+			w.dirs[dir] = true
+		}
+	}
+	for dir := range w.dirs {
+		if !dirs[dir] {
+			_ = w.fsw.Remove(dir)
+			delete(w.dirs, dir)
 		}
 	}
 
-	return &Watcher{
-		fsw:           fsw,
-		debounce:      debounce,
-		onChange:      onChange,
-		logger:        logger,
-		reloadTrigger: make(chan struct{}, 1),
-	}, nil
+	w.paths = append([]string(nil), paths...)
+	w.digest = digestFiles(w.paths)
+	return nil
+}
+
+// changed reports whether the watched files' contents differ from when
+// it last reported a change, or from the baseline SetPaths took.
+func (w *Watcher) changed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	digest := digestFiles(w.paths)
+	if digest == w.digest {
+		return false
+	}
+	w.digest = digest
+	return true
+}
+
+// digestFiles returns a digest of the contents of the files at paths,
+// following symlinks. A file that cannot be read counts as missing, so
+// its appearing or disappearing is a change too.
+func digestFiles(paths []string) string {
+	h := sha256.New()
+	for _, p := range paths {
+		h.Write([]byte(p))
+		data, err := os.ReadFile(p)
+		if err != nil {
+			h.Write([]byte{0})
+			continue
+		}
+		sum := sha256.Sum256(data)
+		h.Write([]byte{1})
+		h.Write(sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // Start runs the watch loop until ctx is canceled. Intended to be run in
@@ -177,6 +250,10 @@ func (w *Watcher) reloadWorker(ctx context.Context) {
 			if ctx.Err() != nil {
 				return
 			}
+			if !w.changed() {
+				w.logger.Debug("watched files unchanged; not reloading")
+				continue
+			}
 			w.onChange()
 		}
 	}
@@ -309,3 +309,73 @@ func TestWatcher_NoReloadAfterCancel(t *testing.T) {
 		t.Errorf("expected 0 onChange calls once the context is cancelled, got %d", got)
 	}
 }
+
+// TestWatcher_UnchangedContentIgnored checks that changes in a watched
+// directory that leave the watched file's content as it was, such as an
+// unrelated file being written or the file being rewritten unchanged,
+// do not invoke onChange.
+func TestWatcher_UnchangedContentIgnored(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	mustWriteFile(t, path, "v1")
+
+	var callCount atomic.Int32
+	w, err := New([]string{path}, 50*time.Millisecond, func() { callCount.Add(1) }, nil)
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx)
+
+	time.Sleep(50 * time.Millisecond)
+	mustWriteFile(t, filepath.Join(dir, ".config.yaml.swp"), "scratch")
+	mustWriteFile(t, path, "v1")
+	time.Sleep(300 * time.Millisecond)
+	if got := callCount.Load(); got != 0 {
+		t.Fatalf("expected no onChange calls for unchanged content, got %d", got)
+	}
+
+	mustWriteFile(t, path, "v2")
+	time.Sleep(300 * time.Millisecond)
+	if got := callCount.Load(); got != 1 {
+		t.Errorf("expected 1 onChange call once the content changed, got %d", got)
+	}
+}
+
+// TestWatcher_SetPaths checks that files added by SetPaths are watched,
+// including in a directory that was not watched before, and that
+// directories no longer needed are not.
+func TestWatcher_SetPaths(t *testing.T) {
+	oldDir, newDir := t.TempDir(), t.TempDir()
+	oldPath, newPath := filepath.Join(oldDir, "apikey"), filepath.Join(newDir, "apikey")
+	mustWriteFile(t, oldPath, "old-secret")
+	mustWriteFile(t, newPath, "new-secret")
+
+	var changed atomic.Bool
+	w, err := New([]string{oldPath}, 50*time.Millisecond, func() { changed.Store(true) }, nil)
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go w.Start(ctx)
+
+	if err := w.SetPaths([]string{newPath}); err != nil {
+		t.Fatalf("failed to set paths: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	mustWriteFile(t, oldPath, "rotated-old-secret")
+	time.Sleep(300 * time.Millisecond)
+	if changed.Load() {
+		t.Fatal("expected a file no longer watched to be ignored")
+	}
+
+	mustWriteFile(t, newPath, "rotated-new-secret")
+	waitForChange(t, &changed, 2*time.Second)
+}